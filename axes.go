@@ -0,0 +1,47 @@
+package lassloot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpAxis names the axis an exported model treats as vertical.  LAS coordinates are always Z up, but many mesh and
+// point viewers expect Y up.
+type UpAxis int
+
+const (
+	// UpZ leaves coordinates as they are.
+	UpZ UpAxis = iota
+
+	// UpY rotates coordinates a quarter turn about the X axis, so that elevation runs along Y and north along -Z.
+	// The rotation keeps the coordinate system right handed, as glTF, OBJ and PLY viewers assume.
+	UpY
+)
+
+// ParseUpAxis returns the UpAxis named by name, "z" or "y".
+func ParseUpAxis(name string) (error, UpAxis) {
+	switch strings.ToLower(name) {
+	case "z", "+z":
+		return nil, UpZ
+	case "y", "+y":
+		return nil, UpY
+	default:
+		return fmt.Errorf("unknown up axis %q: want z or y", name), UpZ
+	}
+}
+
+func (a UpAxis) String() string {
+	if a == UpY {
+		return "y"
+	}
+	return "z"
+}
+
+// Apply converts x, y, z from LAS axes to the axes of a.  It applies equally to positions and to directions such as
+// normals.
+func (a UpAxis) Apply(x, y, z float64) (float64, float64, float64) {
+	if a == UpY {
+		return x, z, -y
+	}
+	return x, y, z
+}
@@ -0,0 +1,207 @@
+package lassloot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// GeneratingSoftware is recorded in the header of the files this library creates.
+const GeneratingSoftware = "lassloot"
+
+// Builder assembles a new PointCloud one point at a time, for importers that read points from other formats.  Each
+// call to Add begins a point, whose remaining dimensions are then filled in by Set.
+type Builder struct {
+	header las14.PublicHeaderBlock
+	vlrs   []las14.VariableLengthRecord
+	schema *las14.Schema
+	length int
+
+	data    []byte
+	x, y, z *las14.Dimension
+}
+
+// NewBuilder returns a Builder for points of the given format, whose coordinates are quantized with the scale factors
+// and offsets of p.  The header is dated today and names this library as the generating software.
+func NewBuilder(format las14.PointDataFormat, p Precision) (error, *Builder) {
+	if !format.IsValid() {
+		return fmt.Errorf("unrecognized point data record format: %d", format), nil
+	}
+	for _, scale := range [...]float64{p.XScaleFactor, p.YScaleFactor, p.ZScaleFactor} {
+		if scale == 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+			return fmt.Errorf("invalid scale factor %v", scale), nil
+		}
+	}
+
+	b := &Builder{}
+	h := &b.header
+	h.VersionMajor, h.VersionMinor = 1, 4
+	h.HeaderSize = las14.Las14HeaderSize
+	h.PointDataRecordFormat = format
+	h.PointDataRecordLength = format.StandardLength()
+	h.XScaleFactor, h.YScaleFactor, h.ZScaleFactor = p.XScaleFactor, p.YScaleFactor, p.ZScaleFactor
+	h.XOffset, h.YOffset, h.ZOffset = p.XOffset, p.YOffset, p.ZOffset
+	copy(h.GeneratingSoftware[:], GeneratingSoftware)
+
+	now := time.Now().UTC()
+	h.FileCreationDayOfYear = (uint16)(now.YearDay())
+	h.FileCreationYear = (uint16)(now.Year())
+
+	err, schema := las14.NewSchema(h, nil)
+	if err != nil {
+		return err, nil
+	}
+	b.setSchema(schema)
+	return nil, b
+}
+
+func (b *Builder) setSchema(schema *las14.Schema) {
+	b.schema = schema
+	b.length = (int)(b.header.PointDataRecordLength)
+	b.x, _ = schema.Dimension(las14.DimX)
+	b.y, _ = schema.Dimension(las14.DimY)
+	b.z, _ = schema.Dimension(las14.DimZ)
+}
+
+// Header returns the header of the pointcloud being built, so that fields such as the global encoding, system id and
+// creation date may be changed.  Its format, record length, scales and offsets must be left as they are, and its
+// counts and bounds are computed by Build.
+func (b *Builder) Header() *las14.PublicHeaderBlock {
+	return &b.header
+}
+
+// Schema returns the description of the dimensions stored in each point.
+func (b *Builder) Schema() *las14.Schema {
+	return b.schema
+}
+
+// AddVLR appends vlr to the records written before the points.  Extra bytes descriptors may not be added once points
+// have been.
+func (b *Builder) AddVLR(vlr las14.VariableLengthRecord) error {
+	if !vlr.IsExtraBytes() {
+		b.vlrs = append(b.vlrs, vlr)
+		return nil
+	}
+
+	if len(b.data) > 0 {
+		return fmt.Errorf("extra bytes must be described before points are added")
+	}
+	err, descriptors := las14.DecodeExtraBytesDescriptors(vlr.Data)
+	if err != nil {
+		return fmt.Errorf("invalid extra bytes vlr: %w", err)
+	}
+
+	extra := 0
+	for _, ebd := range descriptors {
+		for _, d := range ebd.Dimensions(0) {
+			extra += d.Size
+		}
+	}
+
+	header := b.header
+	header.PointDataRecordLength = header.PointDataRecordFormat.StandardLength() + (uint16)(extra)
+	vlrs := append(b.vlrs[:len(b.vlrs):len(b.vlrs)], vlr)
+	err, schema := las14.NewSchema(&header, vlrs)
+	if err != nil {
+		return err
+	}
+
+	b.header, b.vlrs = header, vlrs
+	b.setSchema(schema)
+	return nil
+}
+
+// Len returns the number of points added so far.
+func (b *Builder) Len() uint64 {
+	return (uint64)(len(b.data) / b.length)
+}
+
+// Add begins a new point at x, y, z, with every other dimension zero.  A coordinate that cannot be represented at
+// the builder's precision is an error, and the point is not added.
+func (b *Builder) Add(x, y, z float64) error {
+	b.data = append(b.data, make([]byte, b.length)...)
+	record := b.data[len(b.data)-b.length:]
+
+	for _, c := range [...]struct {
+		d *las14.Dimension
+		v float64
+	}{{b.x, x}, {b.y, y}, {b.z, z}} {
+		err := c.d.SetFloat64(record, c.v)
+		if err != nil {
+			b.data = b.data[:len(b.data)-b.length]
+			return fmt.Errorf("coordinate cannot be represented at the builder's precision: %w", err)
+		}
+	}
+	return nil
+}
+
+// Set stores v, with the dimension's scale and offset removed, as the named dimension of the point most recently
+// begun by Add.
+func (b *Builder) Set(name string, v float64) error {
+	d, ok := b.schema.Dimension(name)
+	if !ok {
+		return fmt.Errorf("format %d has no %s dimension", b.header.PointDataRecordFormat, name)
+	}
+	return b.SetDimension(d, v)
+}
+
+// SetDimension is Set for a dimension already found within the builder's schema.
+func (b *Builder) SetDimension(d *las14.Dimension, v float64) error {
+	if len(b.data) == 0 {
+		return fmt.Errorf("no point has been added")
+	}
+	return d.SetFloat64(b.data[len(b.data)-b.length:], v)
+}
+
+// Build returns the pointcloud holding every point added so far, with its header's counts and bounds computed from
+// them.  The builder should not be used afterwards.
+func (b *Builder) Build() (error, *PointCloud) {
+	err, fr := las14.NewFullResult(b.header, b.vlrs, nil, b.data)
+	if err != nil {
+		return err, nil
+	}
+	return newPointCloud(fr)
+}
+
+// PointFormatFor returns the simplest point data record format that stores GPS times, RGB color and near infrared as
+// asked, for importers choosing a format to hold what they have read.  The LAS 1.4 formats are chosen for points
+// with times, which the spec prefers for new data, and when extended asks for values only they hold, such as
+// classifications above 31 or more than 7 returns.  Formats with waveforms are never chosen.
+func PointFormatFor(gpsTime, rgb, nir, extended bool) las14.PointDataFormat {
+	switch {
+	case nir:
+		return 8
+	case gpsTime || extended:
+		if rgb {
+			return 7
+		}
+		return 6
+	case rgb:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// WriteLAS writes the pointcloud to w as a LAS 1.4 file.
+func (pc *PointCloud) WriteLAS(w io.Writer) error {
+	return las14.NewEncoder(w).Encode(pc.fr)
+}
+
+// WriteFile writes the pointcloud to a LAS 1.4 file at path.
+func (pc *PointCloud) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = pc.WriteLAS(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package lassloot
+
+import "fmt"
+
+// Standard ASPRS classification values.  Values 0 through 31 are shared by every point format, while values above
+// 18 up to 63 are reserved and 64 through 255 are user definable in formats 6 through 10.
+const (
+	ClassCreatedNeverClassified = 0
+	ClassUnclassified           = 1
+	ClassGround                 = 2
+	ClassLowVegetation          = 3
+	ClassMediumVegetation       = 4
+	ClassHighVegetation         = 5
+	ClassBuilding               = 6
+	ClassLowPoint               = 7
+	ClassWater                  = 9
+	ClassRail                   = 10
+	ClassRoadSurface            = 11
+	ClassWireGuard              = 13
+	ClassWireConductor          = 14
+	ClassTransmissionTower      = 15
+	ClassWireStructureConnector = 16
+	ClassBridgeDeck             = 17
+	ClassHighNoise              = 18
+)
+
+var classificationNames = map[uint8]string{
+	ClassCreatedNeverClassified: "Created, Never Classified",
+	ClassUnclassified:           "Unclassified",
+	ClassGround:                 "Ground",
+	ClassLowVegetation:          "Low Vegetation",
+	ClassMediumVegetation:       "Medium Vegetation",
+	ClassHighVegetation:         "High Vegetation",
+	ClassBuilding:               "Building",
+	ClassLowPoint:               "Low Point (Noise)",
+	ClassWater:                  "Water",
+	ClassRail:                   "Rail",
+	ClassRoadSurface:            "Road Surface",
+	ClassWireGuard:              "Wire - Guard (Shield)",
+	ClassWireConductor:          "Wire - Conductor (Phase)",
+	ClassTransmissionTower:      "Transmission Tower",
+	ClassWireStructureConnector: "Wire-Structure Connector",
+	ClassBridgeDeck:             "Bridge Deck",
+	ClassHighNoise:              "High Noise",
+}
+
+// ClassificationName returns the ASPRS name of classification c, or a generic description for reserved and user
+// definable values.
+func ClassificationName(c uint8) string {
+	if name, ok := classificationNames[c]; ok {
+		return name
+	}
+	if c < 64 {
+		return fmt.Sprintf("Reserved %d", c)
+	}
+	return fmt.Sprintf("User Defined %d", c)
+}
+
+// classificationColors holds the colors of the standard classes, matching the browser viewer.
+var classificationColors = map[uint8][3]uint8{
+	0: {160, 160, 160}, 1: {200, 200, 200}, 2: {166, 118, 60}, 3: {120, 200, 90}, 4: {60, 170, 60},
+	5: {20, 110, 30}, 6: {220, 70, 60}, 7: {255, 0, 255}, 9: {50, 110, 230}, 10: {120, 80, 40},
+	11: {90, 90, 90}, 13: {250, 220, 0}, 14: {250, 170, 0}, 15: {200, 120, 255}, 16: {120, 240, 255},
+	17: {240, 160, 120}, 18: {255, 0, 128},
+}
+
+// ClassificationColor returns the color that lassloot's viewers and exports draw classification c in, gray for
+// reserved and user definable values.
+func ClassificationColor(c uint8) [3]uint8 {
+	if color, ok := classificationColors[c]; ok {
+		return color
+	}
+	return [3]uint8{128, 128, 128}
+}
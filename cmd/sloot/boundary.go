@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readBoundary reads a polygon from the file at path, in the pointcloud's coordinates.  The file may hold GeoJSON (a
+// Polygon or MultiPolygon, or a Feature or FeatureCollection of them), WKT (POLYGON or MULTIPOLYGON), or a vertex
+// per line as "x y" or "x,y".  Only the outer ring of the first polygon is read.
+func readBoundary(path string) (error, [][2]float64) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err, nil
+	}
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		err, ring := geoJSONRing(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err), nil
+		}
+		return nil, ring
+	case bytes.IndexByte(data, '(') >= 0:
+		// the first ring of a wkt polygon sits after the last of the opening parentheses that lead to it
+		text := string(data)
+		start := strings.IndexByte(text, '(')
+		for start+1 < len(text) && (text[start+1] == '(' || text[start+1] == ' ') {
+			start++
+		}
+		end := strings.IndexByte(text[start:], ')')
+		if end < 0 {
+			return fmt.Errorf("%s: unclosed polygon", path), nil
+		}
+		return parseVertices(path, strings.Split(text[start+1:start+end], ","))
+	default:
+		return parseVertices(path, strings.Split(string(data), "\n"))
+	}
+}
+
+func parseVertices(path string, vertices []string) (error, [][2]float64) {
+	var ret [][2]float64
+	for i, v := range vertices {
+		fields := strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\r' })
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return fmt.Errorf("%s: vertex %d: want x and y", path, i+1), nil
+		}
+		var p [2]float64
+		for k := range p {
+			f, err := strconv.ParseFloat(fields[k], 64)
+			if err != nil {
+				return fmt.Errorf("%s: vertex %d: %w", path, i+1, err), nil
+			}
+			p[k] = f
+		}
+		ret = append(ret, p)
+	}
+	return nil, ret
+}
+
+// geoJSON holds the parts of a GeoJSON object that lead to a polygon's coordinates.
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Features    []geoJSON       `json:"features"`
+}
+
+func geoJSONRing(data []byte) (error, [][2]float64) {
+	var g geoJSON
+	if err := json.Unmarshal(data, &g); err != nil {
+		return err, nil
+	}
+	return g.ring()
+}
+
+func (g *geoJSON) ring() (error, [][2]float64) {
+	switch g.Type {
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+			return err, nil
+		}
+		return outerRing(rings)
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return err, nil
+		}
+		if len(polygons) == 0 {
+			return fmt.Errorf("empty multipolygon"), nil
+		}
+		return outerRing(polygons[0])
+	case "Feature":
+		if g.Geometry == nil {
+			return fmt.Errorf("feature has no geometry"), nil
+		}
+		return g.Geometry.ring()
+	case "FeatureCollection":
+		for i := range g.Features {
+			if err, ring := g.Features[i].ring(); err == nil {
+				return nil, ring
+			}
+		}
+		return fmt.Errorf("no polygon among %d features", len(g.Features)), nil
+	default:
+		return fmt.Errorf("geojson %q is not a polygon", g.Type), nil
+	}
+}
+
+func outerRing(rings [][][]float64) (error, [][2]float64) {
+	if len(rings) == 0 {
+		return fmt.Errorf("polygon has no rings"), nil
+	}
+	var ret [][2]float64
+	for _, p := range rings[0] {
+		if len(p) < 2 {
+			return fmt.Errorf("position %v has no x and y", p), nil
+		}
+		ret = append(ret, [2]float64{p[0], p[1]})
+	}
+	return nil, ret
+}
+
+// readLines reads polylines from the file at path, in the pointcloud's coordinates, such as breaklines.  The file may
+// hold GeoJSON (LineStrings, MultiLineStrings and the rings of Polygons and MultiPolygons, bare or in Features), WKT,
+// each of whose innermost parenthesized lists is a line, or a vertex per line as "x y" or "x y z", with a blank line
+// between polylines.  Vertices without an elevation have a NaN z.
+func readLines(path string) (error, [][][3]float64) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err, nil
+	}
+	data = bytes.TrimSpace(data)
+
+	var groups [][]string
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		var g geoJSON
+		if err := json.Unmarshal(data, &g); err != nil {
+			return fmt.Errorf("%s: %w", path, err), nil
+		}
+		err, lines := g.lines()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err), nil
+		}
+		return nil, lines
+	case bytes.IndexByte(data, '(') >= 0:
+		text := string(data)
+		for {
+			end := strings.IndexByte(text, ')')
+			if end < 0 {
+				break
+			}
+			if start := strings.LastIndexByte(text[:end], '('); start >= 0 {
+				groups = append(groups, strings.Split(text[start+1:end], ","))
+			}
+			text = text[end+1:]
+		}
+	default:
+		var group []string
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) == "" {
+				if len(group) > 0 {
+					groups = append(groups, group)
+				}
+				group = nil
+				continue
+			}
+			group = append(group, line)
+		}
+		groups = append(groups, group)
+	}
+
+	var ret [][][3]float64
+	for _, g := range groups {
+		var line [][3]float64
+		for i, v := range g {
+			fields := strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\r' })
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) < 2 {
+				return fmt.Errorf("%s: line %d vertex %d: want x and y", path, len(ret)+1, i+1), nil
+			}
+			p := [3]float64{0, 0, math.NaN()}
+			for k := 0; k < len(fields) && k < 3; k++ {
+				f, err := strconv.ParseFloat(fields[k], 64)
+				if err != nil {
+					return fmt.Errorf("%s: line %d vertex %d: %w", path, len(ret)+1, i+1, err), nil
+				}
+				p[k] = f
+			}
+			line = append(line, p)
+		}
+		if len(line) < 2 {
+			return fmt.Errorf("%s: line %d has %d vertices", path, len(ret)+1, len(line)), nil
+		}
+		ret = append(ret, line)
+	}
+	return nil, ret
+}
+
+func (g *geoJSON) lines() (error, [][][3]float64) {
+	var positions [][][]float64
+	switch g.Type {
+	case "LineString":
+		var line [][]float64
+		if err := json.Unmarshal(g.Coordinates, &line); err != nil {
+			return err, nil
+		}
+		positions = [][][]float64{line}
+	case "MultiLineString", "Polygon":
+		if err := json.Unmarshal(g.Coordinates, &positions); err != nil {
+			return err, nil
+		}
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return err, nil
+		}
+		for _, rings := range polygons {
+			positions = append(positions, rings...)
+		}
+	case "Feature":
+		if g.Geometry == nil {
+			return fmt.Errorf("feature has no geometry"), nil
+		}
+		return g.Geometry.lines()
+	case "FeatureCollection":
+		var ret [][][3]float64
+		for i := range g.Features {
+			err, lines := g.Features[i].lines()
+			if err != nil {
+				return err, nil
+			}
+			ret = append(ret, lines...)
+		}
+		return nil, ret
+	default:
+		return fmt.Errorf("geojson %q holds no lines", g.Type), nil
+	}
+
+	var ret [][][3]float64
+	for _, line := range positions {
+		var l [][3]float64
+		for _, p := range line {
+			if len(p) < 2 {
+				return fmt.Errorf("position %v has no x and y", p), nil
+			}
+			v := [3]float64{p[0], p[1], math.NaN()}
+			if len(p) > 2 {
+				v[2] = p[2]
+			}
+			l = append(l, v)
+		}
+		if len(l) < 2 {
+			return fmt.Errorf("line of %d positions", len(l)), nil
+		}
+		ret = append(ret, l)
+	}
+	return nil, ret
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/contour"
+	"github.com/nullstyle/lassloot/encoding/dxf"
+	"github.com/nullstyle/lassloot/grid"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+func init() {
+	register(&command{
+		name:    "contours",
+		summary: "trace contour lines of the terrain the points triangulate, as geojson or dxf for cad",
+		usage:   "[flags] input",
+		setup:   setupContours,
+	})
+}
+
+// contourFlags holds the flags of the commands that trace contours.
+type contourFlags struct {
+	grid       float64
+	duplicates string
+
+	dups tin.Duplicates
+	o    contour.Options
+}
+
+func addContourFlags(fs *flag.FlagSet) *contourFlags {
+	c := &contourFlags{}
+	fs.Float64Var(&c.o.Interval, "interval", 1, "elevation between contours")
+	fs.Float64Var(&c.o.Base, "base", 0, "elevation of a contour the others are spaced from")
+	fs.IntVar(&c.o.IndexEvery, "index", 5, "mark every nth contour from the base as an index contour; 0 marks none")
+	fs.IntVar(&c.o.Smoothing, "smooth", 0, "rounds of corner cutting to smooth each line")
+	fs.Float64Var(&c.o.MinLength, "min-length", 0, "drop lines shorter than this")
+	fs.Float64Var(&c.grid, "grid", 0, "trace a grid of cells this wide, sampled from the triangulation, "+
+		"rather than the triangulation itself")
+	fs.StringVar(&c.duplicates, "duplicates", "mean", "elevation where points share a position: mean, lowest, highest or first")
+	return c
+}
+
+// parse checks the flags, before any input is read.
+func (c *contourFlags) parse() error {
+	err, d := tin.ParseDuplicates(c.duplicates)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	if !(c.o.Interval > 0) {
+		return usagef("interval must be positive")
+	}
+	if c.o.IndexEvery < 0 || c.o.Smoothing < 0 || c.o.MinLength < 0 || c.grid < 0 {
+		return usagef("index, smooth, min-length and grid must not be negative")
+	}
+	c.dups = d
+	return nil
+}
+
+// triangulate triangulates the terrain of pc that contours are traced across.
+func (c *contourFlags) triangulate(pc *lassloot.PointCloud) (error, *tin.Mesh) {
+	return tin.New(pc, tin.Options{Duplicates: c.dups})
+}
+
+// contours traces the contours of m, as the flags describe.
+func (c *contourFlags) contours(m *tin.Mesh) (error, []contour.Line) {
+	if c.grid == 0 {
+		return contour.FromMesh(m, c.o)
+	}
+	err, g := grid.FromMesh(m, c.grid)
+	if err != nil {
+		return err, nil
+	}
+	return contour.FromGrid(g, c.o)
+}
+
+// format formats a contour elevation with as many decimals as the interval and base need, so that 0.1 apart
+// contours read 0.3 rather than 0.30000000000000004.
+func (c *contourFlags) format(v float64) string {
+	decimals := 0
+	for _, step := range []float64{c.o.Interval, c.o.Base} {
+		for d := decimals; d < 6; d++ {
+			scaled := step * math.Pow(10, (float64)(d))
+			if math.Abs(scaled-math.Round(scaled)) < 1e-6 {
+				break
+			}
+			decimals = d + 1
+		}
+	}
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// dxfFlags holds the flags of the drawings sloot contours writes as dxf.
+type dxfFlags struct {
+	polyline3D bool
+	labels     bool
+	textHeight float64
+	spots      float64
+	faces      bool
+}
+
+func setupContours(fs *flag.FlagSet, opts *options) func(args []string) error {
+	c := addContourFlags(fs)
+	format := fs.String("format", "", "geojson or dxf; dxf when the output ends .dxf, else geojson")
+	var d dxfFlags
+	fs.BoolVar(&d.polyline3D, "3d", false, "dxf: draw contours as 3d polylines rather than lightweight polylines")
+	fs.BoolVar(&d.labels, "labels", true, "dxf: label each index contour with its elevation")
+	fs.Float64Var(&d.textHeight, "text-height", 0, "dxf: height of labels; 0 suits it to the drawing's size")
+	fs.Float64Var(&d.spots, "spots", 0, "dxf: mark spot elevations at the centers of cells this wide; 0 marks none")
+	fs.BoolVar(&d.faces, "faces", false, "dxf: draw the triangulation as 3d faces")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		if err := c.parse(); err != nil {
+			return err
+		}
+		if *format == "" {
+			*format = "geojson"
+			if strings.EqualFold(filepath.Ext(opts.output), ".dxf") {
+				*format = "dxf"
+			}
+		}
+		if *format != "geojson" && *format != "dxf" {
+			return usagef("unknown format %q: want geojson or dxf", *format)
+		}
+		if d.textHeight < 0 || d.spots < 0 {
+			return usagef("text-height and spots must not be negative")
+		}
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("contours takes a single input, got %d", len(paths))
+		}
+
+		return opts.eachInput(paths, func(path string, pc *lassloot.PointCloud) error {
+			err, m := c.triangulate(pc)
+			if err != nil {
+				return err
+			}
+			err, lines := c.contours(m)
+			if err != nil {
+				return err
+			}
+			_, crs := pc.CRS()
+
+			var drawing *dxf.Drawing
+			if *format == "dxf" {
+				if err, drawing = d.draw(m, lines, c, crs); err != nil {
+					return err
+				}
+			}
+
+			err, out, done := opts.create()
+			if err != nil {
+				return err
+			}
+			if drawing != nil {
+				err = drawing.Write(out)
+			} else {
+				err = writeContoursGeoJSON(out, lines, c, crs)
+			}
+			if derr := done(); err == nil {
+				err = derr
+			}
+			return err
+		})
+	}
+}
+
+// writeContoursGeoJSON writes lines as a feature collection of 3D line strings, closed lines ending where they start.
+// Each feature's properties hold its elevation, and whether it is an index contour and closed.  The collection names
+// crs, as GeoJSON did before RFC 7946 restricted it to longitude and latitude, so GIS tools place projected contours.
+func writeContoursGeoJSON(w *bufio.Writer, lines []contour.Line, c *contourFlags, crs *lassloot.CRS) error {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	w.WriteString(`{"type":"FeatureCollection"`)
+	if crs != nil && crs.EPSG != 0 {
+		fmt.Fprintf(w, `,"crs":{"type":"name","properties":{"name":"urn:ogc:def:crs:EPSG::%d"}}`, crs.EPSG)
+	}
+	w.WriteString(`,"features":[`)
+	for i, l := range lines {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		z := c.format(l.Elevation)
+		fmt.Fprintf(w, "\n"+`{"type":"Feature","properties":{"elevation":%s,"index":%t,"closed":%t},`, z, l.Index, l.Closed)
+		w.WriteString(`"geometry":{"type":"LineString","coordinates":[`)
+		points := l.Points
+		if l.Closed {
+			points = append(points[:len(points):len(points)], points[0])
+		}
+		for j, p := range points {
+			if j > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString("[" + format(p[0]) + "," + format(p[1]) + "," + z + "]")
+		}
+		w.WriteString("]}}")
+	}
+	_, err := w.WriteString("\n]}\n")
+	return err
+}
+
+// Layers of the drawings sloot contours writes, named as the United States National CAD Standard names topography.
+const (
+	layerIndex        = "C-TOPO-MAJR"
+	layerIntermediate = "C-TOPO-MINR"
+	layerLabels       = "C-TOPO-TEXT"
+	layerSpots        = "C-TOPO-SPOT"
+	layerFaces        = "C-TOPO-TIN"
+)
+
+// draw draws lines traced across m, with the labels, spot elevations and faces the flags ask for, in the coordinates
+// and units of crs.
+func (d *dxfFlags) draw(m *tin.Mesh, lines []contour.Line, c *contourFlags, crs *lassloot.CRS) (error, *dxf.Drawing) {
+	units := dxf.Unitless
+	if crs != nil {
+		units = dxf.UnitsOf(crs.MetersPerUnit)
+	}
+	drawing := dxf.New(units)
+	for _, l := range []dxf.Layer{
+		{Name: layerIndex, Color: 34, Lineweight: 35},
+		{Name: layerIntermediate, Color: 34, Lineweight: 13},
+		{Name: layerLabels, Color: dxf.White},
+		{Name: layerSpots, Color: dxf.Red},
+		{Name: layerFaces, Color: dxf.Gray},
+	} {
+		if err := drawing.AddLayer(l); err != nil {
+			return err, nil
+		}
+	}
+
+	height := d.textHeight
+	if height == 0 {
+		min, max := m.Bounds()
+		height = math.Max(math.Max(max[0]-min[0], max[1]-min[1])/200, 0.01)
+	}
+
+	for _, l := range lines {
+		layer := layerIntermediate
+		if l.Index {
+			layer = layerIndex
+		}
+		var err error
+		if d.polyline3D {
+			points := make([][3]float64, len(l.Points))
+			for i, p := range l.Points {
+				points[i] = [3]float64{p[0], p[1], l.Elevation}
+			}
+			err = drawing.Polyline(layer, points, l.Closed)
+		} else {
+			err = drawing.LWPolyline(layer, l.Points, l.Elevation, l.Closed)
+		}
+		if err != nil {
+			return err, nil
+		}
+		if d.labels && l.Index {
+			p, angle := l.Label()
+			label := c.format(l.Elevation)
+			if err := drawing.Text(layerLabels, [3]float64{p[0], p[1], l.Elevation}, height, angle, label); err != nil {
+				return err, nil
+			}
+		}
+	}
+
+	if d.spots > 0 {
+		err, g := grid.FromMesh(m, d.spots)
+		if err != nil {
+			return err, nil
+		}
+		for row := 0; row < g.Rows; row++ {
+			for col := 0; col < g.Cols; col++ {
+				z := g.At(col, row)
+				if math.IsNaN(z) {
+					continue
+				}
+				x, y := g.Center(col, row)
+				if err := drawing.Point(layerSpots, [3]float64{x, y, z}); err != nil {
+					return err, nil
+				}
+				// the label sits just above its point
+				label := strconv.FormatFloat(z, 'f', 2, 64)
+				if err := drawing.Text(layerSpots, [3]float64{x, y + height, z}, height, 0, label); err != nil {
+					return err, nil
+				}
+			}
+		}
+	}
+
+	if d.faces {
+		for t := range m.Triangles {
+			a, b, c := m.Triangle(t)
+			if err := drawing.Face(layerFaces, a, b, c); err != nil {
+				return err, nil
+			}
+		}
+	}
+	return nil, drawing
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+func init() {
+	register(&command{
+		name:    "csv",
+		summary: "write selected point dimensions as delimited text",
+		usage:   "[flags] input ...",
+		setup:   setupCSV,
+	})
+}
+
+// csvAliases maps convenient column names to the dimensions they select.
+var csvAliases = map[string]string{
+	"class":     las14.DimClassification,
+	"return":    las14.DimReturnNumber,
+	"returns":   las14.DimNumberOfReturns,
+	"gps_time":  las14.DimGPSTime,
+	"source_id": las14.DimPointSourceID,
+	"r":         las14.DimRed,
+	"g":         las14.DimGreen,
+	"b":         las14.DimBlue,
+}
+
+// timeColumn names the pseudo column holding each point's GPS time as a UTC timestamp.
+const timeColumn = "time"
+
+type csvSettings struct {
+	unscaled  bool
+	unoffset  bool
+	precision int
+}
+
+// csvColumn formats a single column of each point.
+type csvColumn struct {
+	name   string
+	format func(p *lassloot.Point) (error, string)
+}
+
+func setupCSV(fs *flag.FlagSet, opts *options) func(args []string) error {
+	columns := fs.String("columns", "x,y,z", "comma separated dimensions to write, e.g. x,y,z,intensity,classification,gpstime,red,green,blue, "+
+		"any extra bytes dimension, or time for the gps time as a utc timestamp")
+	delimiter := fs.String("delimiter", ",", "field delimiter; 'tab' and 'space' name those characters")
+	precision := fs.Int("precision", -1, "decimal places of floating point values; -1 uses each dimension's scale factor")
+	header := fs.Bool("header", true, "write a header line naming the columns")
+	list := fs.Bool("list", false, "list the dimensions of each input instead of writing points")
+
+	settings := &csvSettings{}
+	fs.BoolVar(&settings.unscaled, "unscaled", false, "output coordinates unscaled by file's scale factors")
+	fs.BoolVar(&settings.unoffset, "unoffset", false, "output coordinates scaled, but not offset by file's scale factors")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		if settings.unscaled && settings.unoffset {
+			return usagef("-unscaled and -unoffset are mutually exclusive")
+		}
+		settings.precision = *precision
+
+		names := splitList(*columns)
+		if len(names) == 0 {
+			return usagef("no columns selected")
+		}
+
+		err, comma := parseDelimiter(*delimiter)
+		if err != nil {
+			return err
+		}
+
+		// the output is opened, and the header written, only once the first input has decoded and its columns resolve,
+		// so a mistyped input or column leaves an existing file alone
+		var (
+			out  *bufio.Writer
+			w    *csv.Writer
+			done func() error
+		)
+		begin := func() error {
+			if w != nil {
+				return nil
+			}
+
+			err, o, d := opts.create()
+			if err != nil {
+				return err
+			}
+			out, done = o, d
+			w = csv.NewWriter(out)
+			w.Comma = comma
+
+			if *header && !*list {
+				err = w.Write(names)
+				if err != nil {
+					return fmt.Errorf("failed to write csv header: %w", err)
+				}
+			}
+			return nil
+		}
+
+		err = opts.eachStream(args, func(path string, ps *lassloot.PointStream) error {
+			if *list {
+				if err := begin(); err != nil {
+					return err
+				}
+				return listDimensions(out, path, ps)
+			}
+
+			err, cols := resolveColumns(names, ps, settings)
+			if err != nil {
+				return err
+			}
+			err = begin()
+			if err != nil {
+				return err
+			}
+
+			record := make([]string, len(cols))
+			for {
+				err, p := ps.Next()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				for i, c := range cols {
+					err, record[i] = c.format(p)
+					if err != nil {
+						return fmt.Errorf("column %s: %w", c.name, err)
+					}
+				}
+
+				err = w.Write(record)
+				if err != nil {
+					return err
+				}
+			}
+		})
+		if w == nil {
+			return err
+		}
+
+		w.Flush()
+		if werr := w.Error(); err == nil && werr != nil {
+			err = fmt.Errorf("error writing csv: %w", werr)
+		}
+		if derr := done(); err == nil {
+			err = derr
+		}
+		return err
+	}
+}
+
+func parseDelimiter(s string) (error, rune) {
+	switch strings.ToLower(s) {
+	case "tab", `\t`:
+		return nil, '\t'
+	case "space":
+		return nil, ' '
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return usagef("invalid delimiter %q: want a single character", s), 0
+	}
+	return nil, r
+}
+
+func listDimensions(w io.Writer, path string, ps *lassloot.PointStream) error {
+	fmt.Fprintf(w, "%s:\n", displayPath(path))
+	for _, d := range ps.Schema().Dimensions {
+		desc := d.Description
+		if d.Extra {
+			desc = strings.TrimSpace("extra bytes " + desc)
+		}
+		fmt.Fprintf(w, "\t%-28s %-8s %s\n", d.Name, d.Type, desc)
+	}
+	return nil
+}
+
+// resolveColumns finds the dimension behind each column name within the stream's schema, ignoring case.
+func resolveColumns(names []string, ps *lassloot.PointStream, settings *csvSettings) (error, []csvColumn) {
+	schema := ps.Schema()
+	xd, yd, zd := ps.Precision().Digits()
+
+	cols := make([]csvColumn, len(names))
+	for i, name := range names {
+		cols[i].name = name
+
+		if strings.EqualFold(name, timeColumn) {
+			cols[i].format = func(p *lassloot.Point) (error, string) {
+				err, t := p.Time()
+				if err != nil {
+					return err, ""
+				}
+				return nil, t.Format(time.RFC3339Nano)
+			}
+			continue
+		}
+
+		dimName := name
+		if alias, ok := csvAliases[strings.ToLower(name)]; ok {
+			dimName = alias
+		}
+		d, ok := schema.Dimension(dimName)
+		if !ok {
+			return usagef("unknown column %q; run with -list to see the available dimensions", name), nil
+		}
+		cols[i].name = name
+
+		switch d.Name {
+		case las14.DimX, las14.DimY, las14.DimZ:
+			axis := map[string]int{las14.DimX: 0, las14.DimY: 1, las14.DimZ: 2}[d.Name]
+			digits := [3]int{xd, yd, zd}[axis]
+			cols[i].format = coordinateFormatter(axis, digits, settings)
+		default:
+			cols[i].format = dimensionFormatter(d, settings.precision)
+		}
+	}
+
+	return nil, cols
+}
+
+func coordinateFormatter(axis int, digits int, settings *csvSettings) func(p *lassloot.Point) (error, string) {
+	if settings.precision >= 0 {
+		digits = settings.precision
+	}
+
+	return func(p *lassloot.Point) (error, string) {
+		var v [3]float64
+		switch {
+		case settings.unscaled:
+			x, y, z := p.UnscaledXYZ()
+			return nil, strconv.FormatInt([3]int64{x, y, z}[axis], 10)
+		case settings.unoffset:
+			v[0], v[1], v[2] = p.UnoffsetXYZ()
+		default:
+			v[0], v[1], v[2] = p.XYZ()
+		}
+		return nil, strconv.FormatFloat(v[axis], 'f', digits, 64)
+	}
+}
+
+func dimensionFormatter(d *las14.Dimension, precision int) func(p *lassloot.Point) (error, string) {
+	if d.Bits == 0 && d.Type == las14.TypeUndocumented {
+		return func(p *lassloot.Point) (error, string) {
+			return nil, fmt.Sprintf("%x", d.Raw(p.PDR.Raw))
+		}
+	}
+
+	if !d.Scaled && !d.Type.IsFloat() {
+		return func(p *lassloot.Point) (error, string) {
+			if d.IsNoData(p.PDR.Raw) {
+				return nil, ""
+			}
+			return nil, strconv.FormatFloat(d.RawFloat64(p.PDR.Raw), 'f', 0, 64)
+		}
+	}
+
+	// floats print in full unless asked otherwise; scaled integers print the digits their scale provides
+	digits := precision
+	if digits < 0 && d.Scaled {
+		digits = lassloot.ScaleDigits(d.Scale)
+	}
+
+	return func(p *lassloot.Point) (error, string) {
+		if d.IsNoData(p.PDR.Raw) {
+			return nil, ""
+		}
+		return nil, strconv.FormatFloat(d.Float64(p.PDR.Raw), 'f', digits, 64)
+	}
+}
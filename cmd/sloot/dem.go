@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/geotiff"
+	"github.com/nullstyle/lassloot/grid"
+	"github.com/nullstyle/lassloot/interpolate"
+)
+
+func init() {
+	register(&command{
+		name:    "dem",
+		summary: "bin points into a gridded elevation model, a dtm of the ground or a dsm of first returns, as geotiff",
+		usage:   "[flags] input",
+		setup:   setupDEM,
+	})
+}
+
+func setupDEM(fs *flag.FlagSet, opts *options) func(args []string) error {
+	resolution := fs.Float64("resolution", 1, "width of the grid's cells")
+	method := fs.String("method", "mean", "value of each cell from the points in it: "+
+		"mean, min, max, idw, count or percentile")
+	percentile := fs.Float64("percentile", 50, "percentile, 0 to 100, that -method percentile takes")
+	power := fs.Float64("power", 2, "power of the distance that -method idw and -interpolate idw weight by")
+	interpolation := fs.String("interpolate", "", "estimate the surface between the points, rather than binning "+
+		"them, by idw, linear (tin), natural (sibson's natural neighbors) or nearest")
+	fill := fs.Bool("fill", false, "bin the points, and interpolate only the cells without any")
+	radius := fs.Float64("radius", 0, "ignore points farther than this when interpolating by idw or nearest; 0 for any")
+	minPoints := fs.Int("min-points", 1, "fewest points within -radius that -interpolate idw estimates from")
+	maxPoints := fs.Int("max-points", 0, "nearest points -interpolate idw takes; 12 without it or -radius")
+	extent := fs.String("extent", "", "area to grid as minx,miny,maxx,maxy; the bbox, or the input's bounds, if empty")
+	surface := fs.String("surface", "", "dtm keeps ground points; dsm keeps first returns and takes the highest. "+
+		"-classes, -returns and -method override either")
+	nodata := fs.Float64("nodata", -9999, "value of cells left without one")
+	cog := fs.Bool("cog", false, "write a cloud optimized geotiff, tiled with overviews, for web maps and qgis")
+	tileSize := fs.Int("tile-size", 0, "cut the raster into tiles this wide, a multiple of 16; 256 with -cog if 0")
+	compress := fs.String("compress", "none", "compression: none, deflate or lzw")
+	predictor := fs.Bool("predictor", false, "store each pixel as its difference from its neighbor, "+
+		"which compresses terrain better")
+	sampleType := fs.String("type", "float32", "pixel type: float32, or int16 scaled by -scale and offset by -offset")
+	scale := fs.Float64("scale", 0.01, "elevation of one step of an int16 pixel")
+	offset := fs.Float64("offset", 0, "elevation of an int16 pixel of zero")
+	metadata := fs.String("metadata", "", "comma separated name=value items recorded in the raster's gdal metadata")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		set := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		switch strings.ToLower(*surface) {
+		case "":
+		case "dtm":
+			if !set["classes"] {
+				opts.classes = "2"
+			}
+		case "dsm":
+			if !set["returns"] {
+				opts.returns = "first"
+			}
+			if !set["method"] {
+				*method = "max"
+			}
+		default:
+			return usagef("unknown surface %q: want dtm or dsm", *surface)
+		}
+
+		err, m := grid.ParseMethod(*method)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		o := grid.Options{Resolution: *resolution, Method: m, Percentile: *percentile, Power: *power}
+		if !(o.Resolution > 0) || math.IsInf(o.Resolution, 0) {
+			return usagef("resolution must be positive")
+		}
+		if !(o.Percentile >= 0 && o.Percentile <= 100) {
+			return usagef("percentile must be from 0 to 100")
+		}
+		if !(o.Power > 0) {
+			return usagef("power must be positive")
+		}
+		if *interpolation != "" {
+			err, im := interpolate.ParseMethod(*interpolation)
+			if err != nil {
+				return usagef("%v", err)
+			}
+			iopts := interpolate.Options{Power: *power, Radius: *radius, MinPoints: *minPoints, MaxPoints: *maxPoints}
+			if !(iopts.Radius >= 0) || iopts.MinPoints < 0 || iopts.MaxPoints < 0 ||
+				iopts.MaxPoints > 0 && iopts.MinPoints > iopts.MaxPoints {
+				return usagef("radius, min-points and max-points must not be negative, nor min-points exceed max-points")
+			}
+			if *fill && m == grid.Count {
+				return usagef("-method count leaves no cells to fill")
+			}
+			o.Interpolate = func(points [][3]float64) (error, grid.Interpolator) {
+				return interpolate.New(im, points, iopts)
+			}
+			o.Fill = *fill
+		} else if *fill {
+			return usagef("-fill needs -interpolate")
+		}
+
+		t := geotiff.Options{NoData: *nodata, COG: *cog, TileSize: *tileSize, Predictor: *predictor, Scale: *scale,
+			Offset: *offset}
+		if err, t.Compression = geotiff.ParseCompression(*compress); err != nil {
+			return usagef("%v", err)
+		}
+		if err, t.Type = geotiff.ParseSampleType(*sampleType); err != nil {
+			return usagef("%v", err)
+		}
+		if t.TileSize < 0 || t.TileSize%16 != 0 {
+			return usagef("tile size must be a multiple of 16")
+		}
+		if t.Type == geotiff.Int16 && (!(t.Scale > 0) || t.NoData != math.Trunc(t.NoData) ||
+			t.NoData < math.MinInt16 || t.NoData > math.MaxInt16) {
+			return usagef("int16 needs a positive scale and a whole nodata from -32768 to 32767")
+		}
+		for _, item := range splitList(*metadata) {
+			parts := strings.SplitN(item, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+				return usagef("invalid metadata item %q: want name=value", item)
+			}
+			if t.Metadata == nil {
+				t.Metadata = make(map[string]string)
+			}
+			t.Metadata[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+
+		bounds := *extent
+		if bounds == "" {
+			bounds = opts.bbox
+		}
+		if bounds != "" {
+			err, v := parseFloats(bounds, 4)
+			if err != nil {
+				return usagef("invalid extent %q: %v", bounds, err)
+			}
+			if !(v[2] > v[0]) || !(v[3] > v[1]) {
+				return usagef("invalid extent %q: want minx,miny,maxx,maxy", bounds)
+			}
+			o.Bounds = &lassloot.Rect{MinX: v[0], MinY: v[1], MaxX: v[2], MaxY: v[3]}
+		}
+
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("dem takes a single input, got %d", len(paths))
+		}
+
+		return opts.eachStream(paths, func(path string, ps *lassloot.PointStream) error {
+			err, g := grid.Rasterize(ps, o)
+			if err != nil {
+				return err
+			}
+			_, t.CRS = ps.CRS()
+
+			err, out, done := opts.create()
+			if err != nil {
+				return err
+			}
+			err = geotiff.Write(out, g, t)
+			if derr := done(); err == nil {
+				err = derr
+			}
+			return err
+		})
+	}
+}
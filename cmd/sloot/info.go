@@ -0,0 +1,528 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+func init() {
+	register(&command{
+		name:    "info",
+		summary: "report a file's header, records, coordinate system and point statistics",
+		usage:   "[flags] input ...",
+		setup:   setupInfo,
+	})
+}
+
+func setupInfo(fs *flag.FlagSet, opts *options) func(args []string) error {
+	jsonFlag := fs.Bool("json", false, "output the report as json; several inputs produce an array")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		var reports []*infoReport
+		ierr := opts.eachInput(args, func(path string, pc *lassloot.PointCloud) error {
+			reports = append(reports, newInfoReport(displayPath(path), pc))
+			return nil
+		})
+		// the output is only opened once there is something to report, so a mistyped input leaves it alone
+		if len(reports) == 0 {
+			return ierr
+		}
+
+		err, out, done := opts.create()
+		if err != nil {
+			return err
+		}
+		err = ierr
+
+		var werr error
+		if *jsonFlag {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if len(reports) == 1 {
+				werr = enc.Encode(reports[0])
+			} else {
+				werr = enc.Encode(reports)
+			}
+		} else {
+			for i, r := range reports {
+				if i > 0 {
+					fmt.Fprintln(out)
+				}
+				r.WriteText(out)
+			}
+		}
+		if err == nil {
+			err = werr
+		}
+
+		if derr := done(); err == nil {
+			err = derr
+		}
+		return err
+	}
+}
+
+// infoReport is everything sloot info knows about a file.  It is encoded as is for -json and rendered by WriteText
+// otherwise.
+type infoReport struct {
+	Path            string            `json:"path"`
+	Header          headerReport      `json:"header"`
+	VLRs            []vlrReport       `json:"vlrs"`
+	EVLRs           []vlrReport       `json:"evlrs"`
+	CRS             *crsReport        `json:"crs"`
+	Dimensions      []dimensionReport `json:"dimensions"`
+	Classifications []histogramBin    `json:"classifications"`
+	ReturnNumbers   []histogramBin    `json:"return_numbers"`
+	Time            *timeReport       `json:"time"`
+	Density         *densityReport    `json:"density"`
+	Mismatches      []string          `json:"mismatches"`
+
+	header las14.PublicHeaderBlock
+}
+
+type headerReport struct {
+	FileSourceID                             uint16     `json:"file_source_id"`
+	GlobalEncoding                           uint16     `json:"global_encoding"`
+	ProjectID                                string     `json:"project_id"`
+	Version                                  string     `json:"version"`
+	SystemID                                 string     `json:"system_id"`
+	GeneratingSoftware                       string     `json:"generating_software"`
+	FileCreationDayOfYear                    uint16     `json:"file_creation_day_of_year"`
+	FileCreationYear                         uint16     `json:"file_creation_year"`
+	HeaderSize                               uint16     `json:"header_size"`
+	OffsetToPointData                        uint32     `json:"offset_to_point_data"`
+	NumberOfVariableLengthRecords            uint32     `json:"number_of_vlrs"`
+	PointDataRecordFormat                    byte       `json:"point_data_record_format"`
+	PointDataRecordLength                    uint16     `json:"point_data_record_length"`
+	LegacyNumberOfPointRecords               uint32     `json:"legacy_number_of_point_records"`
+	LegacyNumberOfPointsByReturn             [5]uint32  `json:"legacy_number_of_points_by_return"`
+	ScaleFactor                              [3]finite  `json:"scale_factor"`
+	Offset                                   [3]finite  `json:"offset"`
+	Min                                      [3]finite  `json:"min"`
+	Max                                      [3]finite  `json:"max"`
+	StartOfWaveformDataPacketRecord          uint64     `json:"start_of_waveform_data_packet_record"`
+	StartOfFirstExtendedVariableLengthRecord uint64     `json:"start_of_first_evlr"`
+	NumberOfExtendedVariableLengthRecords    uint32     `json:"number_of_evlrs"`
+	NumberOfPointRecords                     uint64     `json:"number_of_point_records"`
+	NumberOfPointsByReturn                   [15]uint64 `json:"number_of_points_by_return"`
+}
+
+type vlrReport struct {
+	UserID      string `json:"user_id"`
+	RecordID    uint16 `json:"record_id"`
+	Description string `json:"description"`
+	Length      uint64 `json:"length"`
+	Kind        string `json:"kind"`
+}
+
+type crsReport struct {
+	Summary               string  `json:"summary"`
+	Name                  string  `json:"name,omitempty"`
+	EPSG                  int     `json:"epsg,omitempty"`
+	Geographic            bool    `json:"geographic"`
+	VerticalName          string  `json:"vertical_name,omitempty"`
+	VerticalEPSG          int     `json:"vertical_epsg,omitempty"`
+	Units                 string  `json:"units,omitempty"`
+	MetersPerUnit         float64 `json:"meters_per_unit,omitempty"`
+	VerticalUnits         string  `json:"vertical_units,omitempty"`
+	VerticalMetersPerUnit float64 `json:"vertical_meters_per_unit,omitempty"`
+	WKT                   string  `json:"wkt,omitempty"`
+	Error                 string  `json:"error,omitempty"`
+}
+
+type dimensionReport struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Count  uint64 `json:"count"`
+	Min    finite `json:"min"`
+	Max    finite `json:"max"`
+	Mean   finite `json:"mean"`
+	StdDev finite `json:"stddev"`
+}
+
+// finite is a float64 that encodes as JSON null when it is NaN or infinite, which JSON has no numbers for, so that a
+// float extra bytes dimension holding them doesn't lose the whole report.
+type finite float64
+
+func (f finite) MarshalJSON() ([]byte, error) {
+	if math.IsNaN((float64)(f)) || math.IsInf((float64)(f), 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal((float64)(f))
+}
+
+func finites(x, y, z float64) [3]finite {
+	return [3]finite{(finite)(x), (finite)(y), (finite)(z)}
+}
+
+type histogramBin struct {
+	Value uint8  `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count uint64 `json:"count"`
+}
+
+type timeReport struct {
+	Type        string     `json:"type"`
+	MinGPSTime  finite     `json:"min_gps_time"`
+	MaxGPSTime  finite     `json:"max_gps_time"`
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"`
+	Span        string     `json:"span,omitempty"`
+	SpanSeconds finite     `json:"span_seconds"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type densityReport struct {
+	Area                 float64 `json:"area"`
+	PointsPerArea        float64 `json:"points_per_area"`
+	AverageSpacing       float64 `json:"average_spacing"`
+	Units                string  `json:"units,omitempty"`
+	PointsPerSquareMeter float64 `json:"points_per_square_meter,omitempty"`
+}
+
+func newInfoReport(path string, pc *lassloot.PointCloud) *infoReport {
+	h := pc.Header().RawHeader
+	r := &infoReport{
+		Path:       path,
+		Header:     newHeaderReport(&h),
+		VLRs:       []vlrReport{},
+		EVLRs:      []vlrReport{},
+		Mismatches: []string{},
+		header:     h,
+	}
+
+	for _, vlr := range pc.VariableLengthRecords() {
+		r.VLRs = append(r.VLRs, vlrReport{
+			UserID:      vlr.UserIDString(),
+			RecordID:    vlr.RecordID,
+			Description: vlr.DescriptionString(),
+			Length:      (uint64)(len(vlr.Data)),
+			Kind:        recordKind(vlr.UserIDString(), vlr.RecordID, vlr.Data),
+		})
+	}
+	for _, evlr := range pc.ExtendedVariableLengthRecords() {
+		r.EVLRs = append(r.EVLRs, vlrReport{
+			UserID:      evlr.UserIDString(),
+			RecordID:    evlr.RecordID,
+			Description: evlr.DescriptionString(),
+			Length:      (uint64)(len(evlr.Data)),
+			Kind:        recordKind(evlr.UserIDString(), evlr.RecordID, evlr.Data),
+		})
+	}
+
+	err, crs := pc.CRS()
+	switch {
+	case err != nil:
+		r.CRS = &crsReport{Summary: "invalid", Error: err.Error()}
+	case crs != nil:
+		r.CRS = &crsReport{
+			Summary:               crs.String(),
+			Name:                  crs.Name,
+			EPSG:                  crs.EPSG,
+			Geographic:            crs.Geographic,
+			VerticalName:          crs.VerticalName,
+			VerticalEPSG:          crs.VerticalEPSG,
+			Units:                 crs.Units,
+			MetersPerUnit:         crs.MetersPerUnit,
+			VerticalUnits:         crs.VerticalUnits,
+			VerticalMetersPerUnit: crs.VerticalMetersPerUnit,
+			WKT:                   crs.WKT,
+		}
+	}
+
+	stats := pc.Stats()
+	schema := pc.Schema()
+	for _, ds := range stats.Dimensions {
+		d, _ := schema.Dimension(ds.Name)
+		r.Dimensions = append(r.Dimensions, dimensionReport{
+			Name:   ds.Name,
+			Type:   d.Type.String(),
+			Count:  ds.Count,
+			Min:    (finite)(ds.Min),
+			Max:    (finite)(ds.Max),
+			Mean:   (finite)(ds.Mean),
+			StdDev: (finite)(ds.StdDev),
+		})
+	}
+
+	r.Classifications = histogram(stats.Classifications, lassloot.ClassificationName)
+	r.ReturnNumbers = histogram(stats.ReturnNumbers, nil)
+	r.Time = newTimeReport(pc)
+	r.Density = newDensityReport(stats, crs)
+	r.Mismatches = append(r.Mismatches, mismatches(&h, stats)...)
+
+	return r
+}
+
+func newHeaderReport(h *las14.PublicHeaderBlock) headerReport {
+	return headerReport{
+		FileSourceID:                             h.FileSourceID,
+		GlobalEncoding:                           (uint16)(h.GlobalEncoding),
+		ProjectID:                                h.ProjectID.String(),
+		Version:                                  fmt.Sprintf("%d.%d", h.VersionMajor, h.VersionMinor),
+		SystemID:                                 h.SystemIDString(),
+		GeneratingSoftware:                       h.GeneratingSoftwareString(),
+		FileCreationDayOfYear:                    h.FileCreationDayOfYear,
+		FileCreationYear:                         h.FileCreationYear,
+		HeaderSize:                               h.HeaderSize,
+		OffsetToPointData:                        h.OffsetToPointData,
+		NumberOfVariableLengthRecords:            h.NumberOfVariableLengthRecords,
+		PointDataRecordFormat:                    (byte)(h.PointDataRecordFormat),
+		PointDataRecordLength:                    h.PointDataRecordLength,
+		LegacyNumberOfPointRecords:               h.LegacyNumberOfPointRecords,
+		LegacyNumberOfPointsByReturn:             h.LegacyNumberOfPointsByReturn,
+		ScaleFactor:                              finites(h.XScaleFactor, h.YScaleFactor, h.ZScaleFactor),
+		Offset:                                   finites(h.XOffset, h.YOffset, h.ZOffset),
+		Min:                                      finites(h.MinX, h.MinY, h.MinZ),
+		Max:                                      finites(h.MaxX, h.MaxY, h.MaxZ),
+		StartOfWaveformDataPacketRecord:          h.StartOfWaveformDataPacketRecord,
+		StartOfFirstExtendedVariableLengthRecord: h.StartOfFirstExtendedVariableLengthRecord,
+		NumberOfExtendedVariableLengthRecords:    h.NumberOfExtendedVariableLengthRecords,
+		NumberOfPointRecords:                     h.NumberOfPointRecords,
+		NumberOfPointsByReturn:                   h.NumberOfPointsByReturn,
+	}
+}
+
+// recordKind describes the contents of the well known VLRs and EVLRs defined by the LAS specification.
+func recordKind(userID string, recordID uint16, data []byte) string {
+	switch userID {
+	case las14.UserIDSpec:
+		switch {
+		case recordID == 0:
+			return "classification lookup"
+		case recordID == 3:
+			return "text area description"
+		case recordID == las14.RecordIDExtraBytes:
+			err, descriptors := las14.DecodeExtraBytesDescriptors(data)
+			if err != nil {
+				return fmt.Sprintf("extra bytes (invalid: %v)", err)
+			}
+			var names []string
+			for i := range descriptors {
+				names = append(names, descriptors[i].NameString())
+			}
+			return fmt.Sprintf("extra bytes %v", names)
+		case recordID == 7:
+			return "superseded"
+		case recordID >= 100 && recordID < 355:
+			return "waveform packet descriptor"
+		case recordID == 65535:
+			return "waveform data packets"
+		}
+	case las14.UserIDProjection:
+		switch recordID {
+		case las14.RecordIDGeoKeyDirectory:
+			err, gkd := las14.DecodeGeoKeyDirectory(data, nil, nil)
+			if err != nil {
+				return fmt.Sprintf("geotiff key directory (invalid: %v)", err)
+			}
+			return fmt.Sprintf("geotiff key directory (%d keys)", len(gkd.Keys))
+		case las14.RecordIDGeoDoubleParams:
+			return "geotiff double params"
+		case las14.RecordIDGeoASCIIParams:
+			return "geotiff ascii params"
+		case las14.RecordIDMathTransform:
+			return "ogc math transform wkt"
+		case las14.RecordIDCoordinateWKT:
+			return "ogc coordinate system wkt"
+		}
+	case "laszip encoded":
+		return "laszip compression"
+	}
+	return "unknown"
+}
+
+func histogram(counts map[uint8]uint64, name func(uint8) string) []histogramBin {
+	ret := []histogramBin{}
+	for v, n := range counts {
+		bin := histogramBin{Value: v, Count: n}
+		if name != nil {
+			bin.Name = name(v)
+		}
+		ret = append(ret, bin)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Value < ret[j].Value })
+	return ret
+}
+
+func newTimeReport(pc *lassloot.PointCloud) *timeReport {
+	err, min, max := pc.GPSTimeRange()
+	if err != nil {
+		return nil
+	}
+
+	tr := &timeReport{Type: "gps week time", MinGPSTime: (finite)(min), MaxGPSTime: (finite)(max),
+		SpanSeconds: (finite)(max - min)}
+	if pc.IsAdjustedStandardGPSTime() {
+		tr.Type = "adjusted standard gps time"
+	}
+
+	err, start, end := pc.TimeRange()
+	if err != nil {
+		tr.Error = err.Error()
+		return tr
+	}
+
+	tr.Start, tr.End, tr.Span = &start, &end, end.Sub(start).String()
+	return tr
+}
+
+func newDensityReport(stats *lassloot.Stats, crs *lassloot.CRS) *densityReport {
+	x, okx := stats.Dimension(las14.DimX)
+	y, oky := stats.Dimension(las14.DimY)
+	if !okx || !oky || stats.Count == 0 {
+		return nil
+	}
+
+	area := (x.Max - x.Min) * (y.Max - y.Min)
+	if area <= 0 {
+		return nil
+	}
+
+	dr := &densityReport{Area: area, PointsPerArea: (float64)(stats.Count) / area}
+	dr.AverageSpacing = math.Sqrt(1 / dr.PointsPerArea)
+	if crs != nil && !crs.Geographic && crs.MetersPerUnit != 0 {
+		dr.Units = crs.Units
+		dr.PointsPerSquareMeter = dr.PointsPerArea / (crs.MetersPerUnit * crs.MetersPerUnit)
+	}
+	return dr
+}
+
+// mismatches compares the summary values recorded in the header against those computed from the points.  Bounds may
+// differ by up to half a scale step, the rounding a writer is allowed when recording them.
+func mismatches(h *las14.PublicHeaderBlock, stats *lassloot.Stats) []string {
+	var ret []string
+
+	bounds := []struct {
+		name     string
+		min, max float64
+		scale    float64
+	}{
+		{las14.DimX, h.MinX, h.MaxX, h.XScaleFactor},
+		{las14.DimY, h.MinY, h.MaxY, h.YScaleFactor},
+		{las14.DimZ, h.MinZ, h.MaxZ, h.ZScaleFactor},
+	}
+	for _, b := range bounds {
+		ds, ok := stats.Dimension(b.name)
+		if !ok || ds.Count == 0 {
+			continue
+		}
+
+		tolerance := math.Abs(b.scale)/2 + 1e-9*math.Max(math.Abs(ds.Max), 1)
+		if math.Abs(ds.Min-b.min) > tolerance {
+			ret = append(ret, fmt.Sprintf("header min %s is %v but points reach %v", b.name, b.min, ds.Min))
+		}
+		if math.Abs(ds.Max-b.max) > tolerance {
+			ret = append(ret, fmt.Sprintf("header max %s is %v but points reach %v", b.name, b.max, ds.Max))
+		}
+	}
+
+	byReturn := h.NumberOfPointsByReturn[:]
+	if h.PointDataRecordFormat < 6 && h.NumberOfPointRecords == 0 {
+		byReturn = make([]uint64, len(h.LegacyNumberOfPointsByReturn))
+		for i, n := range h.LegacyNumberOfPointsByReturn {
+			byReturn[i] = (uint64)(n)
+		}
+	}
+	for i, want := range byReturn {
+		if got := stats.ReturnNumbers[(uint8)(i+1)]; got != want {
+			ret = append(ret, fmt.Sprintf("header counts %d points with return number %d but found %d", want, i+1, got))
+		}
+	}
+
+	if h.LegacyNumberOfPointRecords != 0 && h.NumberOfPointRecords != 0 &&
+		(uint64)(h.LegacyNumberOfPointRecords) != h.NumberOfPointRecords {
+		ret = append(ret, fmt.Sprintf("legacy point count %d disagrees with point count %d",
+			h.LegacyNumberOfPointRecords, h.NumberOfPointRecords))
+	}
+
+	return ret
+}
+
+// WriteText renders the report in the aligned "name = value" layout of the header summary.
+func (r *infoReport) WriteText(w io.Writer) {
+	line := func(name string, format string, args ...interface{}) {
+		fmt.Fprintf(w, "\t%-40s = %s\n", name, fmt.Sprintf(format, args...))
+	}
+
+	fmt.Fprintf(w, "File: %s\n", r.Path)
+	fmt.Fprintf(w, "\nHeader:\n%s", r.header.String())
+
+	fmt.Fprintf(w, "\nVariable Length Records:\n")
+	for i, v := range r.VLRs {
+		line(fmt.Sprintf("[%d] %s/%d", i, v.UserID, v.RecordID), "%s, %d bytes, %q", v.Kind, v.Length, v.Description)
+	}
+	if len(r.EVLRs) > 0 {
+		fmt.Fprintf(w, "\nExtended Variable Length Records:\n")
+		for i, v := range r.EVLRs {
+			line(fmt.Sprintf("[%d] %s/%d", i, v.UserID, v.RecordID), "%s, %d bytes, %q", v.Kind, v.Length, v.Description)
+		}
+	}
+
+	fmt.Fprintf(w, "\nCoordinate Reference System:\n")
+	if r.CRS == nil {
+		line("CRS", "none")
+	} else {
+		line("CRS", "%s", r.CRS.Summary)
+		if r.CRS.Error != "" {
+			line("Error", "%s", r.CRS.Error)
+		}
+	}
+
+	fmt.Fprintf(w, "\nDimensions:\n")
+	fmt.Fprintf(w, "\t%-24s %-8s %14s %20s %20s %20s %16s\n", "name", "type", "count", "min", "max", "mean", "stddev")
+	for _, d := range r.Dimensions {
+		fmt.Fprintf(w, "\t%-24s %-8s %14d %20.12g %20.12g %20.12g %16.6g\n", d.Name, d.Type, d.Count, d.Min, d.Max, d.Mean, d.StdDev)
+	}
+
+	fmt.Fprintf(w, "\nClassifications:\n")
+	for _, b := range r.Classifications {
+		line(fmt.Sprintf("%3d %s", b.Value, b.Name), "%d", b.Count)
+	}
+
+	fmt.Fprintf(w, "\nReturn Numbers:\n")
+	for _, b := range r.ReturnNumbers {
+		line(fmt.Sprintf("%3d", b.Value), "%d", b.Count)
+	}
+
+	if r.Time != nil {
+		fmt.Fprintf(w, "\nTime:\n")
+		line("GPSTimeType", "%s", r.Time.Type)
+		line("GPSTimeRange", "%f .. %f", r.Time.MinGPSTime, r.Time.MaxGPSTime)
+		if r.Time.Start != nil {
+			line("FirstPointTime", "%s", r.Time.Start.Format(time.RFC3339Nano))
+			line("LastPointTime", "%s", r.Time.End.Format(time.RFC3339Nano))
+			line("TimeSpan", "%s", r.Time.Span)
+		} else if r.Time.Error != "" {
+			line("TimeSpan", "%gs (%s)", r.Time.SpanSeconds, r.Time.Error)
+		}
+	}
+
+	if r.Density != nil {
+		fmt.Fprintf(w, "\nDensity:\n")
+		line("Area", "%g", r.Density.Area)
+		line("PointsPerArea", "%g", r.Density.PointsPerArea)
+		line("AverageSpacing", "%g", r.Density.AverageSpacing)
+		if r.Density.PointsPerSquareMeter != 0 {
+			line("PointsPerSquareMeter", "%g", r.Density.PointsPerSquareMeter)
+		}
+	}
+
+	fmt.Fprintf(w, "\nMismatches:\n")
+	if len(r.Mismatches) == 0 {
+		line("Mismatches", "none")
+	}
+	for _, m := range r.Mismatches {
+		fmt.Fprintf(w, "\t%s\n", m)
+	}
+}
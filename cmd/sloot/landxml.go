@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/landxml"
+	"github.com/nullstyle/lassloot/kdtree"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+func init() {
+	register(&command{
+		name:    "landxml",
+		summary: "triangulate ground points into a landxml 1.2 tin surface for civil design software",
+		usage:   "[flags] input",
+		setup:   setupLandXML,
+	})
+}
+
+func setupLandXML(fs *flag.FlagSet, opts *options) func(args []string) error {
+	name := fs.String("name", "", "name of the surface; the input's name if empty")
+	boundary := fs.String("boundary", "", "record the polygon in this geojson, wkt or x y text file "+
+		"as the surface's boundary")
+	breaklines := fs.String("breaklines", "", "record the lines in this geojson, wkt or x y z text file, "+
+		"one per paragraph, as breaklines")
+	duplicates := fs.String("duplicates", "mean", "elevation where points share a position: mean, lowest, highest or first")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+	// surfaces are bare earth unless asked otherwise
+	classes := fs.Lookup("classes")
+	classes.DefValue = "2"
+	classes.Value.Set("2")
+
+	return func(args []string) error {
+		err, d := tin.ParseDuplicates(*duplicates)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		var boundaries, breaks [][][3]float64
+		if *boundary != "" {
+			err, ring := readBoundary(*boundary)
+			if err != nil {
+				return err
+			}
+			b := make([][3]float64, len(ring))
+			for i, p := range ring {
+				b[i] = [3]float64{p[0], p[1], math.NaN()}
+			}
+			boundaries = [][][3]float64{b}
+		}
+		if *breaklines != "" {
+			if err, breaks = readLines(*breaklines); err != nil {
+				return err
+			}
+		}
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("landxml takes a single input, got %d", len(paths))
+		}
+
+		return opts.eachInput(paths, func(path string, pc *lassloot.PointCloud) error {
+			err, m := tin.New(pc, tin.Options{Duplicates: d})
+			if err != nil {
+				return err
+			}
+
+			o := landxml.Options{Name: *name, Boundaries: boundaries, Breaklines: breaks}
+			if o.Name == "" {
+				o.Name = "ground"
+				if path != stdinPath {
+					o.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+				}
+			}
+			o.Description = fmt.Sprintf("tin of %d points generated by %s", len(m.Vertices), lassloot.GeneratingSoftware)
+			if err, crs := pc.CRS(); err == nil && crs != nil {
+				o.Unit = landxml.UnitOf(crs.MetersPerUnit)
+				o.CoordinateSystem = &landxml.CoordinateSystem{Name: crs.Name, EPSG: crs.EPSG, WKT: crs.WKT,
+					VerticalDatum: crs.VerticalName}
+			}
+			drape(m, o.Boundaries)
+			drape(m, o.Breaklines)
+
+			err, out, done := opts.create()
+			if err != nil {
+				return err
+			}
+			err = landxml.Write(out, m.Vertices, m.Triangles, o)
+			if derr := done(); err == nil {
+				err = derr
+			}
+			return err
+		})
+	}
+}
+
+// drape sets the elevation of each vertex of lines that has none to that of the surface m beneath it, or of the
+// nearest vertex of m where the line strays off the surface.
+func drape(m *tin.Mesh, lines [][][3]float64) {
+	var tree *kdtree.Tree
+	hint := -1
+	for _, l := range lines {
+		for i, p := range l {
+			if !math.IsNaN(p[2]) {
+				continue
+			}
+			z, tri, ok := m.Interpolate(p[0], p[1], hint)
+			if ok {
+				l[i][2], hint = z, tri
+				continue
+			}
+			if tree == nil {
+				tree = kdtree.New(m.Vertices, 2)
+			}
+			l[i][2] = m.Vertices[tree.Nearest(p, 1)[0].Index][2]
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Command sloot inspects and converts LAS 1.4 point clouds.
+//
+// Usage:
+//
+//	sloot <command> [flags] [input ...]
+//
+// Run "sloot help" for the list of commands and "sloot help <command>" for a command's flags.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// A command is a single sloot subcommand.  Its flags are registered on a fresh FlagSet for each invocation.
+type command struct {
+	name    string
+	summary string
+	usage   string
+
+	// setup registers the command's own flags and returns the func that runs it once they are parsed.
+	setup func(fs *flag.FlagSet, opts *options) func(args []string) error
+}
+
+var commands = map[string]*command{}
+
+func register(c *command) {
+	commands[c.name] = c
+}
+
+// usageError marks errors caused by how sloot was invoked rather than by the files it processed.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command named by args[0] and returns the process's exit code.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) > 1 && name == "help" {
+			c, ok := commands[args[1]]
+			if !ok {
+				fmt.Fprintf(stderr, "sloot: unknown command %q\n", args[1])
+				return exitUsage
+			}
+			fs, _ := newFlagSet(c, stdin, stdout, stderr)
+			fs.SetOutput(stdout)
+			fs.Usage()
+			return exitOK
+		}
+		printUsage(stdout)
+		return exitOK
+	}
+
+	c, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "sloot: unknown command %q\n\n", name)
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	fs, runner := newFlagSet(c, stdin, stdout, stderr)
+	err := fs.Parse(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+
+	err = runner(fs.Args())
+	var ue *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ue):
+		fmt.Fprintf(stderr, "sloot %s: %v\n", name, err)
+		fmt.Fprintf(stderr, "run 'sloot help %s' for usage\n", name)
+		return exitUsage
+	default:
+		fmt.Fprintf(stderr, "sloot %s: %v\n", name, err)
+		return exitError
+	}
+}
+
+func newFlagSet(c *command, stdin io.Reader, stdout io.Writer, stderr io.Writer) (*flag.FlagSet, func([]string) error) {
+	fs := flag.NewFlagSet("sloot "+c.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	opts := &options{stdin: stdin, stdout: stdout, stderr: stderr}
+	runner := c.setup(fs, opts)
+
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "usage: sloot %s %s\n\n%s\n\nflags:\n", c.name, c.usage, c.summary)
+		fs.PrintDefaults()
+	}
+
+	return fs, runner
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: sloot <command> [flags] [input ...]\n\n")
+	fmt.Fprintf(w, "Inputs are LAS files or glob patterns; '-' reads a file from standard input.\n\ncommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintf(w, "\nRun 'sloot help <command>' for a command's flags.\n")
+}
+
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var ret []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			ret = append(ret, part)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,832 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/e57test"
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func writeTestFile(t *testing.T, dir string, name string, points []lastest.Point) string {
+	t.Helper()
+
+	file := lastest.Generate(lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 6}, Points: points})
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, file.Bytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runSloot(stdin []byte, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, bytes.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsageAndExitCodes(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{nil, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"help", "csv"}, exitOK},
+		{[]string{"help", "nope"}, exitUsage},
+		{[]string{"nope"}, exitUsage},
+		{[]string{"csv"}, exitUsage},
+		{[]string{"csv", "-h"}, exitOK},
+		{[]string{"csv", "-bogus", "x.las"}, exitUsage},
+		{[]string{"csv", "-classes", "ground", "x.las"}, exitUsage},
+		{[]string{"info", filepath.Join(t.TempDir(), "missing.las")}, exitError},
+	}
+
+	for _, tt := range tests {
+		if code, _, stderr := runSloot(nil, tt.args...); code != tt.code {
+			t.Errorf("sloot %v exited %d, want %d: %s", tt.args, code, tt.code, stderr)
+		}
+	}
+}
+
+func TestCSVGlobsAndFilters(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "a.las", []lastest.Point{{X: 100, Classification: 2}, {X: 200, Classification: 5}})
+	writeTestFile(t, dir, "b.las", []lastest.Point{{X: 300, Classification: 2}})
+
+	code, stdout, stderr := runSloot(nil, "csv", "-classes", "2", filepath.Join(dir, "*.las"))
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+
+	want := "x,y,z\n1.00,0.00,0.00\n3.00,0.00,0.00\n"
+	if stdout != want {
+		t.Errorf("csv = %q, want %q", stdout, want)
+	}
+}
+
+func TestInfoFromStdin(t *testing.T) {
+	file := lastest.Generate(lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 6}, Points: []lastest.Point{{X: 1}}})
+
+	code, stdout, stderr := runSloot(file.Bytes, "info", "-json", "-")
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+
+	var report struct {
+		Path   string `json:"path"`
+		Header struct {
+			NumberOfPointRecords uint64 `json:"number_of_point_records"`
+		} `json:"header"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("invalid json %q: %v", stdout, err)
+	}
+	if report.Path != "<stdin>" || report.Header.NumberOfPointRecords != 1 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestInfoNonFinite(t *testing.T) {
+	// a float extra bytes dimension holding NaN, whose statistics json has no numbers for
+	nan := make([]byte, 8)
+	binary.LittleEndian.PutUint64(nan, math.Float64bits(math.NaN()))
+	file := lastest.Generate(lastest.Spec{
+		Header:     lastest.Header{PointDataRecordFormat: 6},
+		Points:     []lastest.Point{{X: 1, Extra: nan}, {X: 2, Extra: nan}},
+		ExtraBytes: 8,
+		VLRs:       []lastest.VLR{lastest.ExtraBytesVLR(lastest.ExtraBytes{Name: "reflectance", DataType: (byte)(las14.TypeFloat64)})},
+	})
+
+	code, stdout, stderr := runSloot(file.Bytes, "info", "-json", "-")
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var report struct {
+		Dimensions []struct {
+			Name string   `json:"name"`
+			Min  *float64 `json:"min"`
+			Mean *float64 `json:"mean"`
+		} `json:"dimensions"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("invalid json %q: %v", stdout, err)
+	}
+	found := false
+	for _, d := range report.Dimensions {
+		if d.Name == "reflectance" {
+			found = true
+			if d.Min != nil || d.Mean != nil {
+				t.Errorf("reflectance statistics are not null in %s", stdout)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no reflectance dimension in %s", stdout)
+	}
+}
+
+func TestPartialFailureContinues(t *testing.T) {
+	dir := t.TempDir()
+	good := writeTestFile(t, dir, "good.las", []lastest.Point{{X: 1}})
+	bad := filepath.Join(dir, "bad.las")
+	if err := os.WriteFile(bad, []byte("not a las file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := runSloot(nil, "csv", bad, good)
+	if code != exitError {
+		t.Errorf("exit %d, want %d", code, exitError)
+	}
+	if !strings.Contains(stdout, "0.01,") || !strings.Contains(stderr, "bad.las") {
+		t.Errorf("stdout %q stderr %q", stdout, stderr)
+	}
+}
+
+func TestInfoMissingInputKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "report.txt")
+	if err := os.WriteFile(out, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, _ := runSloot(nil, "info", "-o", out, filepath.Join(dir, "missing.las"))
+	if code != exitError {
+		t.Errorf("exit %d, want %d", code, exitError)
+	}
+	if b, err := os.ReadFile(out); err != nil || string(b) != "keep" {
+		t.Errorf("output %q, %v; want it left alone", b, err)
+	}
+}
+
+func TestCSVUnknownColumnKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "a.las", []lastest.Point{{X: 1}})
+	out := filepath.Join(dir, "a.csv")
+	if err := os.WriteFile(out, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, _, stderr := runSloot(nil, "csv", "-columns", "x,nope", "-o", out, path)
+	if code != exitUsage || !strings.Contains(stderr, "nope") {
+		t.Errorf("exit %d, want %d; stderr %q", code, exitUsage, stderr)
+	}
+	if b, err := os.ReadFile(out); err != nil || string(b) != "keep" {
+		t.Errorf("output %q, %v; want it left alone", b, err)
+	}
+}
+
+func TestCSVColumns(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "a.las", []lastest.Point{
+		{X: 123, Y: 456, Z: 789, Intensity: 300, Classification: 2, ReturnNumber: 1, NumberOfReturns: 1, GPSTime: 1.5},
+	})
+
+	code, stdout, stderr := runSloot(nil, "csv", "-columns", "X,class,Intensity,gpstime,return", "-delimiter", "tab",
+		"-precision", "1", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if want := "X\tclass\tIntensity\tgpstime\treturn\n1.2\t2\t300\t1.5\t1\n"; stdout != want {
+		t.Errorf("csv = %q, want %q", stdout, want)
+	}
+
+	code, stdout, _ = runSloot(nil, "csv", "-header=false", "-columns", "z,y", "-unscaled", path)
+	if code != exitOK || stdout != "789,456\n" {
+		t.Errorf("exit %d csv = %q", code, stdout)
+	}
+
+	if code, _, _ := runSloot(nil, "csv", "-columns", "nope", path); code != exitUsage {
+		t.Errorf("unknown column exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestTxt2Las(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.las")
+	text := "x\ty\tz\tclass\n1.5\t2.5\t3.25\t2\n"
+
+	code, _, stderr := runSloot([]byte(text), "txt2las", "-scale", "0.001", "-o", out, "-")
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+
+	code, stdout, stderr := runSloot(nil, "csv", "-columns", "x,y,z,class", out)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if want := "x,y,z,class\n1.500,2.500,3.250,2\n"; stdout != want {
+		t.Errorf("csv = %q, want %q", stdout, want)
+	}
+
+	if code, _, _ := runSloot([]byte(text), "txt2las", "-scale", "0,1", "-"); code != exitUsage {
+		t.Errorf("invalid scale exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestPLY(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "a.las", []lastest.Point{{X: 100, Y: 200, Z: 300, Classification: 2}, {X: 300, Classification: 5}})
+
+	code, stdout, stderr := runSloot(nil, "ply", "-format", "ascii", "-normals", "0", "-origin", "none", "-classes", "2", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "element vertex 1\n") || !strings.HasSuffix(stdout, "end_header\n1.00 2.00 3.00 0 2\n") {
+		t.Errorf("ply = %q", stdout)
+	}
+
+	if code, _, _ := runSloot(nil, "ply", "-origin", "middle", path); code != exitUsage {
+		t.Errorf("invalid origin exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestPCD(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "a.las", []lastest.Point{{X: 100, Y: 200, Z: 300, Classification: 2}, {X: 300, Classification: 5}})
+	out := filepath.Join(dir, "a.pcd")
+
+	code, _, stderr := runSloot(nil, "pcd", "-data", "binary_compressed", "-o", out, path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+
+	back := filepath.Join(dir, "b.las")
+	code, _, stderr = runSloot(nil, "pcd2las", "-o", back, out)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+
+	code, stdout, stderr := runSloot(nil, "csv", "-columns", "x,y,z,class", back)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if want := "x,y,z,class\n1.00,2.00,3.00,2\n3.00,0.00,0.00,5\n"; stdout != want {
+		t.Errorf("csv = %q, want %q", stdout, want)
+	}
+
+	if code, _, _ := runSloot(nil, "pcd", "-data", "zip", path); code != exitUsage {
+		t.Errorf("invalid data exited %d, want %d", code, exitUsage)
+	}
+	if code, _, _ := runSloot(nil, "pcd", path, path); code != exitUsage {
+		t.Errorf("two inputs exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestParquet(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "a.las", []lastest.Point{{X: 100, Y: 200, Z: 300, Classification: 2}, {X: 300, Classification: 5}})
+
+	code, stdout, stderr := runSloot(nil, "parquet", "-classes", "2", "-compression", "gzip", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "PAR1") || !strings.HasSuffix(stdout, "PAR1") || !strings.Contains(stdout, `"primary_column":"geometry"`) {
+		t.Errorf("output is not geoparquet")
+	}
+
+	code, stdout, stderr = runSloot(nil, "parquet", "-arrow", "-geometry=false", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "\xff\xff\xff\xff") || !strings.HasSuffix(stdout, "\xff\xff\xff\xff\x00\x00\x00\x00") {
+		t.Errorf("output is not an arrow stream")
+	}
+	if strings.Contains(stdout, "geoarrow") {
+		t.Errorf("geometry written")
+	}
+
+	if code, _, _ := runSloot(nil, "parquet", "-compression", "snappy", path); code != exitUsage {
+		t.Errorf("invalid compression exited %d, want %d", code, exitUsage)
+	}
+	if code, _, _ := runSloot(nil, "parquet", "-row-group", "0", path); code != exitUsage {
+		t.Errorf("invalid row group exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestSTL(t *testing.T) {
+	dir := t.TempDir()
+	var points []lastest.Point
+	for i := 0; i < 100; i++ {
+		points = append(points, lastest.Point{X: (int32)(i%10) * 100, Y: (int32)(i/10) * 100, Z: (int32)(i * 10), Classification: 2})
+	}
+	points = append(points, lastest.Point{X: 450, Y: 450, Z: 9999, Classification: 5})
+	path := writeTestFile(t, dir, "a.las", points)
+
+	// a square, with a wkt boundary whose corners fall between the points
+	boundary := filepath.Join(dir, "lot.wkt")
+	if err := os.WriteFile(boundary, []byte("POLYGON ((1.5 1.5, 7.5 1.5, 7.5 7.5, 1.5 7.5, 1.5 1.5))"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	facets := func(stdout string) int {
+		if len(stdout) < 84 || strings.HasPrefix(stdout, "solid") {
+			t.Fatalf("output is not binary stl")
+		}
+		n := (int)(binary.LittleEndian.Uint32([]byte(stdout[80:84])))
+		if len(stdout) != 84+50*n {
+			t.Fatalf("%d bytes for %d facets", len(stdout), n)
+		}
+		return n
+	}
+
+	code, stdout, stderr := runSloot(nil, "stl", "-classes", "2", "-size", "100", "-exaggeration", "2", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	// the surface's 162 triangles, two for each of the 36 wall panels, and a floor of 34
+	if n := facets(stdout); n != 162+72+34 {
+		t.Errorf("%d facets", n)
+	}
+
+	code, stdout, stderr = runSloot(nil, "stl", "-classes", "2", "-boundary", boundary, path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	facets(stdout)
+
+	// a slanted quad over a scattered tile, whose densified edges run through nearly collinear points
+	rng := rand.New(rand.NewSource(1))
+	var tile []lastest.Point
+	for i := 0; i < 4000; i++ {
+		x, y := rng.Int31n(10000), rng.Int31n(10000)
+		tile = append(tile, lastest.Point{X: x, Y: y, Z: 10000 + x/10 + y/20, Classification: 2})
+	}
+	tilePath := writeTestFile(t, dir, "tile.las", tile)
+	quad := filepath.Join(dir, "quad.wkt")
+	if err := os.WriteFile(quad, []byte("POLYGON ((10 10, 90 20, 80 90, 20 85, 10 10))"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr = runSloot(nil, "stl", "-boundary", quad, tilePath)
+	if code != exitOK {
+		t.Fatalf("slanted boundary exit %d: %s", code, stderr)
+	}
+	facets(stdout)
+
+	if code, _, _ := runSloot(nil, "stl", "-base", "0", path); code != exitUsage {
+		t.Errorf("zero base exited %d, want %d", code, exitUsage)
+	}
+	if code, _, _ := runSloot(nil, "stl", "-duplicates", "median", path); code != exitUsage {
+		t.Errorf("invalid duplicates exited %d, want %d", code, exitUsage)
+	}
+}
+
+func Test3MF(t *testing.T) {
+	dir := t.TempDir()
+	var points []lastest.Point
+	for i := 0; i < 100; i++ {
+		points = append(points, lastest.Point{X: (int32)(i%10) * 100, Y: (int32)(i/10) * 100, Z: (int32)(i * 10), Classification: 2})
+	}
+	path := writeTestFile(t, dir, "hill.las", points)
+
+	code, stdout, stderr := runSloot(nil, "3mf", "-exaggeration", "3", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	zr, err := zip.NewReader(strings.NewReader(stdout), (int64)(len(stdout)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var model []byte
+	for _, f := range zr.File {
+		if f.Name == "3D/3dmodel.model" {
+			r, _ := f.Open()
+			model, _ = io.ReadAll(r)
+		}
+	}
+	for _, want := range []string{
+		`unit="millimeter"`, `<metadata name="Title">hill</metadata>`, `<metadata name="lassloot:Coloring">elevation</metadata>`,
+		`<metadata name="lassloot:VerticalExaggeration">3</metadata>`, `<m:colorgroup id="1">`, `p3="`,
+	} {
+		if !strings.Contains(string(model), want) {
+			t.Errorf("model lacks %s", want)
+		}
+	}
+
+	if code, _, _ := runSloot(nil, "3mf", "-color", "rgb", path); code != exitError {
+		t.Errorf("rgb of uncolored points exited %d, want %d", code, exitError)
+	}
+	if code, _, _ := runSloot(nil, "3mf", "-color", "intensity", path); code != exitUsage {
+		t.Errorf("unknown coloring exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestMesh(t *testing.T) {
+	dir := t.TempDir()
+	var points []lastest.Point
+	for i := 0; i < 100; i++ {
+		points = append(points, lastest.Point{X: (int32)(i%10) * 100, Y: (int32)(i/10) * 100, Z: (int32)(i * 10), Classification: 2})
+	}
+	path := writeTestFile(t, dir, "hill.las", points)
+
+	out := filepath.Join(dir, "earthworks.obj")
+	if code, _, stderr := runSloot(nil, "obj", "-texture", "64", "-o", out, path); code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	for name, want := range map[string]string{
+		"earthworks.obj": "mtllib earthworks.mtl\n",
+		"earthworks.mtl": "map_Kd earthworks.png\n",
+		"earthworks.png": "\x89PNG",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(b), want) {
+			t.Errorf("%s lacks %q", name, want)
+		}
+	}
+	// the grid runs 0 to 9 each way, recentered on 4.5
+	b, _ := os.ReadFile(out)
+	if !strings.Contains(string(b), "\nv -4.5 -4.95 4.5\n") || strings.Count(string(b), "\nf ") != 162 {
+		t.Errorf("obj is\n%s", b)
+	}
+
+	code, stdout, stderr := runSloot(nil, "glb", "-up", "z", "-texture", "0", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "glTF") || !strings.Contains(stdout, `"up":"z"`) || strings.Contains(stdout, "image/png") {
+		t.Errorf("glb begins %q", stdout[:64])
+	}
+
+	if code, _, _ := runSloot(nil, "glb", "-up", "x", path); code != exitUsage {
+		t.Errorf("unknown up axis exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestReadBoundary(t *testing.T) {
+	dir := t.TempDir()
+	want := [][2]float64{{1, 2}, {3, 2}, {3, 4}, {1, 2}}
+	for name, text := range map[string]string{
+		"text":    "1 2\n3,2\n\n3\t4\n1 2\n",
+		"wkt":     "POLYGON ((1 2, 3 2, 3 4, 1 2), (1.5 2.5, 2 2.5, 2 3, 1.5 2.5))",
+		"multi":   "MULTIPOLYGON (((1 2, 3 2, 3 4, 1 2)))",
+		"geojson": `{"type":"Polygon","coordinates":[[[1,2],[3,2],[3,4],[1,2]]]}`,
+		"collection": `{"type":"FeatureCollection","features":[` +
+			`{"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]}},` +
+			`{"type":"Feature","geometry":{"type":"MultiPolygon","coordinates":[[[[1,2,9],[3,2,9],[3,4,9],[1,2,9]]]]}}]}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err, got := readBoundary(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestE57Input(t *testing.T) {
+	data := e57test.Generate(e57test.Spec{Scans: []e57test.Scan{{
+		Name: "scan",
+		Fields: []e57test.Field{
+			{Name: "cartesianX", Type: "ScaledInteger", Min: 0, Max: 10000, Scale: 0.01, Values: []float64{150, 250}},
+			{Name: "cartesianY", Type: "ScaledInteger", Min: 0, Max: 10000, Scale: 0.01, Values: []float64{50, 75}},
+			{Name: "cartesianZ", Type: "ScaledInteger", Min: 0, Max: 10000, Scale: 0.01, Values: []float64{1000, 0}},
+		},
+	}}})
+	path := filepath.Join(t.TempDir(), "scan.e57")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"csv", "-columns", "x,y,z", path},
+		{"csv", "-columns", "x,y,z", "-"},
+		{"ply", "-format", "ascii", "-normals", "0", "-origin", "none", "-zrange", "5,", "-"},
+	} {
+		code, stdout, stderr := runSloot(data, args...)
+		if code != exitOK {
+			t.Fatalf("%v: exit %d: %s", args, code, stderr)
+		}
+		want := "x,y,z\n1.50,0.50,10.00\n2.50,0.75,0.00\n"
+		if args[0] == "ply" {
+			want = "end_header\n1.50 0.50 10.00 0 0\n"
+		}
+		if !strings.HasSuffix(stdout, want) {
+			t.Errorf("%v = %q, want %q", args, stdout, want)
+		}
+	}
+}
+
+func TestContours(t *testing.T) {
+	dir := t.TempDir()
+	// a cone 5 high in the middle of a 10 by 10 square
+	var points []lastest.Point
+	for x := 0; x <= 20; x++ {
+		for y := 0; y <= 20; y++ {
+			d := math.Hypot((float64)(x-10), (float64)(y-10)) / 2
+			z := math.Max(0, 5-d)
+			points = append(points, lastest.Point{X: (int32)(x * 50), Y: (int32)(y * 50), Z: (int32)(z * 100), Classification: 2})
+		}
+	}
+	path := writeTestFile(t, dir, "cone.las", points)
+
+	for _, args := range [][]string{{}, {"-grid", "0.5", "-smooth", "1"}} {
+		args = append(append([]string{"contours", "-interval", "1", "-base", "0.5", "-index", "2"}, args...), path)
+		code, stdout, stderr := runSloot(nil, args...)
+		if code != exitOK {
+			t.Fatalf("exit %d: %s", code, stderr)
+		}
+		var fc struct {
+			Features []struct {
+				Properties struct {
+					Elevation     float64
+					Index, Closed bool
+				}
+				Geometry struct {
+					Type        string
+					Coordinates [][3]float64
+				}
+			}
+		}
+		if err := json.Unmarshal([]byte(stdout), &fc); err != nil {
+			t.Fatalf("%v in %s", err, stdout)
+		}
+		if len(fc.Features) != 5 {
+			t.Fatalf("%v: %d features, want 5", args, len(fc.Features))
+		}
+		for i, f := range fc.Features {
+			c := f.Geometry.Coordinates
+			if p := f.Properties; p.Elevation != (float64)(i)+0.5 || p.Index != (i%2 == 0) || !p.Closed {
+				t.Errorf("%v: feature %d has %+v", args, i, p)
+			}
+			if f.Geometry.Type != "LineString" || len(c) < 4 || c[0] != c[len(c)-1] || c[0][2] != f.Properties.Elevation {
+				t.Errorf("%v: feature %d is %v", args, i, f.Geometry)
+			}
+		}
+	}
+
+	if code, _, _ := runSloot(nil, "contours", "-interval", "0", path); code != exitUsage {
+		t.Errorf("zero interval exited %d, want %d", code, exitUsage)
+	}
+
+	code, stdout, stderr := runSloot(nil, "contours", "-interval", "0.1", "-index", "10", "-min-length", "1", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"elevation":0.3,`) || strings.Contains(stdout, `"elevation":0.30000`) {
+		t.Error("elevations are not rounded to the interval")
+	}
+
+	out := filepath.Join(dir, "cone.dxf")
+	if code, _, stderr := runSloot(nil, "contours", "-index", "2", "-spots", "2", "-faces", "-o", out, path); code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drawing := string(b)
+	for _, want := range []string{
+		"$ACADVER\n  1\nAC1015\n", "LWPOLYLINE\n", "  8\nC-TOPO-MAJR\n", "  8\nC-TOPO-MINR\n", "  1\n4\n",
+		"POINT\n", "  8\nC-TOPO-SPOT\n", "3DFACE\n",
+	} {
+		if !strings.Contains(drawing, want) {
+			t.Errorf("drawing lacks %q", want)
+		}
+	}
+	if strings.Count(drawing, "\n  0\nTEXT\n") < 2 || !strings.HasSuffix(drawing, "  0\nEOF\n") {
+		t.Error("drawing lacks labels or its end")
+	}
+
+	code, stdout, _ = runSloot(nil, "contours", "-format", "dxf", "-3d", path)
+	if code != exitOK || !strings.Contains(stdout, "AcDb3dPolyline") || strings.Contains(stdout, "LWPOLYLINE") {
+		t.Errorf("3d polylines exited %d", code)
+	}
+	if code, _, _ := runSloot(nil, "contours", "-format", "shp", path); code != exitUsage {
+		t.Errorf("unknown format exited %d, want %d", code, exitUsage)
+	}
+}
+
+func TestLandXML(t *testing.T) {
+	dir := t.TempDir()
+	var points []lastest.Point
+	for i := 0; i < 100; i++ {
+		points = append(points, lastest.Point{X: (int32)(i%10) * 100, Y: (int32)(i/10) * 100, Z: (int32)(i * 10), Classification: 2})
+	}
+	// a tree, which a ground surface leaves out
+	points = append(points, lastest.Point{X: 450, Y: 450, Z: 5000, Classification: 5})
+	path := writeTestFile(t, dir, "site.las", points)
+	boundary := filepath.Join(dir, "lot.wkt")
+	breaklines := filepath.Join(dir, "breaks.txt")
+	for name, text := range map[string]string{
+		boundary:   "POLYGON ((1 1, 8 1, 8 8, 1 1))",
+		breaklines: "2 2 1.5\n3 3 2.5\n\n4 4\n5 5\n6 6\n",
+	} {
+		if err := os.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, stdout, stderr := runSloot(nil, "landxml", "-boundary", boundary, "-breaklines", breaklines, path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	for _, want := range []string{
+		`<Surface name="site"`, `linearUnit="meter"`, `elevMax="9.9"`, `<Faces>`, `<F>`,
+		`<Boundary name="boundary 1" bndType="outer" edgeTrim="true"><PntList3D>1 1 1.1 1 8 1.8 8 8 8.8 1 1 1.1</PntList3D>`,
+		`<PntList3D>2 2 1.5 3 3 2.5</PntList3D>`, `<PntList3D>4 4 4.4 5 5 5.5 6 6 6.6`,
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("surface lacks %s", want)
+		}
+	}
+	if n := strings.Count(stdout, "<P id="); n != 100 {
+		t.Errorf("%d points, want the 100 on the ground", n)
+	}
+
+	code, stdout, _ = runSloot(nil, "landxml", "-classes", "", path)
+	if code != exitOK || !strings.Contains(stdout, `elevMax="50"`) || strings.Contains(stdout, "SourceData") {
+		t.Errorf("surface of every point exited %d", code)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"text":    "1 2\n3,4,5\n\n\n6 7\n8 9\n",
+		"wkt":     "MULTILINESTRING ((1 2, 3 4 5), (6 7, 8 9))",
+		"geojson": `{"type":"MultiLineString","coordinates":[[[1,2],[3,4,5]],[[6,7],[8,9]]]}`,
+		"collection": `{"type":"FeatureCollection","features":[` +
+			`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[1,2],[3,4,5]]}},` +
+			`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[6,7],[8,9]]}}]}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err, lines := readLines(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		got := fmt.Sprint(lines)
+		if want := "[[[1 2 NaN] [3 4 5]] [[6 7 NaN] [8 9 NaN]]]"; got != want {
+			t.Errorf("%s: read %s, want %s", name, got, want)
+		}
+	}
+
+	path := filepath.Join(dir, "short")
+	os.WriteFile(path, []byte("1 2\n\n3 4\n5 6\n"), 0o644)
+	if err, _ := readLines(path); err == nil {
+		t.Error("read a line of one vertex")
+	}
+}
+
+func TestDEM(t *testing.T) {
+	dir := t.TempDir()
+	// ground one meter down across a 4 by 4 meter square, with a tree over one cell
+	var points []lastest.Point
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			p := lastest.Point{X: (int32)(x*100 + 50), Y: (int32)(y*100 + 50), Z: 100, Classification: 2,
+				ReturnNumber: 1, NumberOfReturns: 1}
+			if x == 1 && y == 2 {
+				p.ReturnNumber, p.NumberOfReturns = 2, 2
+				points = append(points, lastest.Point{X: p.X, Y: p.Y, Z: 900, Classification: 5, ReturnNumber: 1,
+					NumberOfReturns: 2})
+			}
+			points = append(points, p)
+		}
+	}
+	path := writeTestFile(t, dir, "site.las", points)
+
+	// pixels reads the raster's pixels, which a single image writes last
+	pixels := func(tif string, n int) []float32 {
+		b := []byte(tif)
+		if len(b) < 4*n || !strings.HasPrefix(tif, "II*\x00") {
+			t.Fatalf("%d bytes of raster", len(b))
+		}
+		ret := make([]float32, n)
+		for i := range ret {
+			ret[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[len(b)-4*n+4*i:]))
+		}
+		return ret
+	}
+
+	tests := []struct {
+		args []string
+		want float32
+	}{
+		{[]string{"-surface", "dtm"}, 1},
+		{[]string{"-surface", "dsm"}, 9},
+		{[]string{"-surface", "dsm", "-method", "min"}, 9},
+		{nil, 5},
+		{[]string{"-method", "count"}, 2},
+	}
+	for _, test := range tests {
+		args := append(append([]string{"dem", "-extent", "0,0,4,4"}, test.args...), path)
+		code, stdout, stderr := runSloot(nil, args...)
+		if code != exitOK {
+			t.Fatalf("%v exited %d: %s", test.args, code, stderr)
+		}
+		// the tree's cell is the second from the west and the second from the north
+		if v := pixels(stdout, 16); v[5] != test.want || v[0] != v[15] {
+			t.Errorf("%v: tree's cell %v, want %v; corners %v and %v", test.args, v[5], test.want, v[0], v[15])
+		}
+	}
+
+	code, stdout, stderr := runSloot(nil, "dem", "-extent", "-2,0,4,4", "-nodata", "-1", "-resolution", "2", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if v := pixels(stdout, 6); v[0] != -1 || v[3] != -1 || v[4] != 1 || math.Abs((float64)(v[1])-2.6) > 1e-6 {
+		t.Errorf("pixels %v", v)
+	}
+
+	code, stdout, stderr = runSloot(nil, "dem", "-cog", "-tile-size", "16", "-compress", "lzw", "-predictor", "-type",
+		"int16", "-metadata", "site=north lot", path)
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "II*\x00") || !strings.HasPrefix(stdout[8:], "GDAL_STRUCTURAL_METADATA_SIZE=") ||
+		!strings.Contains(stdout, `<Item name="site">north lot</Item>`) ||
+		!strings.Contains(stdout, `<Item name="SCALE" sample="0" role="scale">0.01</Item>`) {
+		t.Errorf("cog of %d bytes lacks its structural or gdal metadata", len(stdout))
+	}
+
+	// at half meter cells, ground points fall in one cell in four; interpolation fills those between them, within the
+	// points' hull or their reach
+	for _, test := range []struct {
+		args   []string
+		filled int
+	}{
+		{nil, 16},
+		{[]string{"-interpolate", "linear"}, 36},
+		// the binned cells along the east and south edges lie outside the hull
+		{[]string{"-interpolate", "natural", "-fill"}, 36 + 7},
+		// every cell's center is 0.35 from a point
+		{[]string{"-interpolate", "idw", "-radius", "0.4"}, 64},
+		{[]string{"-interpolate", "idw", "-radius", "0.3", "-fill"}, 16},
+		{[]string{"-interpolate", "nearest"}, 64},
+	} {
+		args := append(append([]string{"dem", "-surface", "dtm", "-resolution", "0.5", "-extent", "0,0,4,4"},
+			test.args...), path)
+		code, stdout, stderr := runSloot(nil, args...)
+		if code != exitOK {
+			t.Fatalf("%v exited %d: %s", test.args, code, stderr)
+		}
+		filled := 0
+		for _, v := range pixels(stdout, 64) {
+			if v != -9999 {
+				filled++
+				if v != 1 {
+					t.Errorf("%v: pixel %v on ground of 1", test.args, v)
+				}
+			}
+		}
+		if filled != test.filled {
+			t.Errorf("%v: %d pixels filled, want %d", test.args, filled, test.filled)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"dem", "-interpolate", "kriging", path},
+		{"dem", "-fill", path},
+		{"dem", "-interpolate", "idw", "-min-points", "5", "-max-points", "2", path},
+		{"dem", "-compress", "zip", path},
+		{"dem", "-type", "int8", path},
+		{"dem", "-tile-size", "100", path},
+		{"dem", "-type", "int16", "-nodata", "-99999", path},
+		{"dem", "-metadata", "site", path},
+		{"dem", "-surface", "chm", path},
+		{"dem", "-method", "median", path},
+		{"dem", "-resolution", "0", path},
+		{"dem", "-extent", "4,4,0,0", path},
+	} {
+		if code, _, _ := runSloot(nil, args...); code != exitUsage {
+			t.Errorf("%v exited %d", args, code)
+		}
+	}
+
+	// filters or an extent that leave no points would make a raster of nothing but nodata
+	for _, args := range [][]string{
+		{"dem", "-classes", "9", path},
+		{"dem", "-extent", "10,10,14,14", path},
+		{"dem", "-classes", "9", "-interpolate", "idw", path},
+		{"dem", "-classes", "9", "-interpolate", "linear", "-fill", path},
+	} {
+		code, stdout, stderr := runSloot(nil, args...)
+		if code != exitError || stdout != "" || !strings.Contains(stderr, "no points") {
+			t.Errorf("%v exited %d with %d bytes: %s", args, code, len(stdout), stderr)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/gltf"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/encoding/obj"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+func init() {
+	register(&command{
+		name:    "obj",
+		summary: "triangulate points into a terrain mesh as wavefront obj, with an mtl and ortho texture beside it",
+		usage:   "[flags] input",
+		setup: func(fs *flag.FlagSet, opts *options) func(args []string) error {
+			return setupMesh(fs, opts, "obj")
+		},
+	})
+	register(&command{
+		name:    "glb",
+		summary: "triangulate points into a terrain mesh as binary gltf 2.0, with an embedded ortho texture",
+		usage:   "[flags] input",
+		setup: func(fs *flag.FlagSet, opts *options) func(args []string) error {
+			return setupMesh(fs, opts, "glb")
+		},
+	})
+}
+
+// colorFlag holds the -color flag of the commands that color meshes.
+type colorFlag struct {
+	name     string
+	coloring tin.Coloring
+}
+
+func addColorFlag(fs *flag.FlagSet) *colorFlag {
+	c := &colorFlag{}
+	fs.StringVar(&c.name, "color", "", "color by rgb, classification or elevation; rgb when the points have it, else elevation")
+	return c
+}
+
+func (c *colorFlag) parse() error {
+	if c.name == "" {
+		return nil
+	}
+	err, coloring := tin.ParseColoring(c.name)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	c.coloring = coloring
+	return nil
+}
+
+// resolve returns the coloring the flag chose for pc.
+func (c *colorFlag) resolve(pc *lassloot.PointCloud) tin.Coloring {
+	if c.name != "" {
+		return c.coloring
+	}
+	if _, ok := pc.Schema().Dimension(las14.DimRed); ok {
+		return tin.RGB
+	}
+	return tin.Hypsometric
+}
+
+func setupMesh(fs *flag.FlagSet, opts *options, format string) func(args []string) error {
+	up := fs.String("up", "y", "axis elevation is written along: y, as blender's importers expect, or z")
+	origin := fs.String("origin", "center", "point subtracted from every position: center or min of the bounds, "+
+		"offset from the file header, none, or x,y,z")
+	textureSize := fs.Int("texture", 2048, "pixels along the longer side of the ortho texture; 0 writes none")
+	duplicates := fs.String("duplicates", "mean", "elevation where points share a position: mean, lowest, highest or first")
+	color := addColorFlag(fs)
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		err, upAxis := lassloot.ParseUpAxis(*up)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		err, d := tin.ParseDuplicates(*duplicates)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		if err := color.parse(); err != nil {
+			return err
+		}
+		if *textureSize < 0 {
+			return usagef("invalid texture size %d", *textureSize)
+		}
+		switch *origin {
+		case "none", "offset", "center", "min":
+		default:
+			if err, _ := parseFloats(*origin, 3); err != nil {
+				return usagef("invalid origin %q: want center, min, offset, none or x,y,z", *origin)
+			}
+		}
+
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("%s takes a single input, got %d", format, len(paths))
+		}
+
+		return opts.eachInput(paths, func(path string, pc *lassloot.PointCloud) error {
+			err, m := tin.New(pc, tin.Options{Duplicates: d})
+			if err != nil {
+				return err
+			}
+
+			name := "terrain"
+			if opts.output != stdinPath {
+				name = strings.TrimSuffix(filepath.Base(opts.output), filepath.Ext(opts.output))
+			} else if path != stdinPath {
+				name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			}
+
+			var texture []byte
+			if *textureSize > 0 && (format == "glb" || opts.output != stdinPath) {
+				err, colors := m.Colors(pc, color.resolve(pc))
+				if err != nil {
+					return err
+				}
+				var buf bytes.Buffer
+				if err := png.Encode(&buf, m.Render(colors, *textureSize)); err != nil {
+					return err
+				}
+				texture = buf.Bytes()
+			}
+
+			o := resolveOrigin(*origin, []*lassloot.PointCloud{pc})
+			var crs string
+			if err, c := pc.CRS(); err == nil && c != nil {
+				crs = c.String()
+			}
+
+			if format == "glb" {
+				err, out, done := opts.create()
+				if err != nil {
+					return err
+				}
+				g := gltf.Options{Up: upAxis, Origin: o, Normals: m.Normals(), Name: name, Texture: texture}
+				if texture != nil {
+					g.UVs = m.UVs()
+				}
+				if crs != "" {
+					g.Extras = map[string]interface{}{"crs": crs}
+				}
+				err = gltf.WriteGLB(out, m.Vertices, m.Triangles, g)
+				if derr := done(); err == nil {
+					err = derr
+				}
+				return err
+			}
+
+			// the material and texture go beside the obj file, so standard output gets the bare mesh
+			w := obj.Options{Up: upAxis, Origin: o, Normals: m.Normals()}
+			if crs != "" {
+				w.Comments = []string{"crs " + crs}
+			}
+			if opts.output != stdinPath {
+				dir := filepath.Dir(opts.output)
+				textureName := ""
+				if texture != nil {
+					textureName = name + ".png"
+					if err := os.WriteFile(filepath.Join(dir, textureName), texture, 0o644); err != nil {
+						return err
+					}
+					w.UVs = m.UVs()
+				}
+				var mtl bytes.Buffer
+				obj.WriteMaterial(&mtl, name, textureName)
+				w.MaterialLibrary, w.Material = name+".mtl", name
+				if err := os.WriteFile(filepath.Join(dir, w.MaterialLibrary), mtl.Bytes(), 0o644); err != nil {
+					return err
+				}
+			}
+
+			err, out, done := opts.create()
+			if err != nil {
+				return err
+			}
+			err = obj.Write(out, m.Vertices, m.Triangles, w)
+			if derr := done(); err == nil {
+				err = derr
+			}
+			return err
+		})
+	}
+}
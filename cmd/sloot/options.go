@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/e57"
+)
+
+// stdinPath names standard input among a command's inputs, and standard output as its output.
+const stdinPath = "-"
+
+// options holds the flags shared by every command: where output goes and which points are read.
+type options struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	output string
+
+	classes      string
+	returns      string
+	bbox         string
+	zrange       string
+	dropWithheld bool
+}
+
+// addOutputFlag registers -o, the path output is written to.
+func (o *options) addOutputFlag(fs *flag.FlagSet) {
+	fs.StringVar(&o.output, "o", stdinPath, "output path, or - for standard output")
+}
+
+// addFilterFlags registers the flags that select which points are read from each input.
+func (o *options) addFilterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.classes, "classes", "", "comma separated classifications to keep, e.g. 2,9")
+	fs.StringVar(&o.returns, "returns", "", "comma separated returns to keep: first, last, single or return numbers")
+	fs.StringVar(&o.bbox, "bbox", "", "horizontal bounds to keep as minx,miny,maxx,maxy")
+	fs.StringVar(&o.zrange, "zrange", "", "elevations to keep as min,max; either may be empty")
+	fs.BoolVar(&o.dropWithheld, "drop-withheld", false, "drop points flagged as withheld")
+}
+
+// filter builds the point filter described by the filter flags.
+func (o *options) filter() (error, *lassloot.Filter) {
+	f := &lassloot.Filter{DropWithheld: o.dropWithheld}
+
+	for _, c := range splitList(o.classes) {
+		v, err := strconv.ParseUint(c, 10, 8)
+		if err != nil {
+			return usagef("invalid classification %q", c), nil
+		}
+		f.Classes = append(f.Classes, (uint8)(v))
+	}
+
+	for _, r := range splitList(o.returns) {
+		switch strings.ToLower(r) {
+		case "first":
+			f.FirstReturns = true
+		case "last":
+			f.LastReturns = true
+		case "single", "only":
+			f.SingleReturns = true
+		default:
+			v, err := strconv.ParseUint(r, 10, 8)
+			if err != nil || v == 0 || v > 15 {
+				return usagef("invalid return %q", r), nil
+			}
+			f.ReturnNumbers = append(f.ReturnNumbers, (uint8)(v))
+		}
+	}
+
+	if o.bbox != "" {
+		err, v := parseFloats(o.bbox, 4)
+		if err != nil {
+			return usagef("invalid bbox %q: %v", o.bbox, err), nil
+		}
+		f.Bounds = &lassloot.Rect{MinX: v[0], MinY: v[1], MaxX: v[2], MaxY: v[3]}
+	}
+
+	if o.zrange != "" {
+		parts := strings.Split(o.zrange, ",")
+		if len(parts) != 2 {
+			return usagef("invalid zrange %q: want min,max", o.zrange), nil
+		}
+		for i, p := range parts {
+			if p = strings.TrimSpace(p); p == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return usagef("invalid zrange %q: %v", o.zrange, err), nil
+			}
+			if i == 0 {
+				f.MinZ = &v
+			} else {
+				f.MaxZ = &v
+			}
+		}
+	}
+
+	return nil, f
+}
+
+func parseFloats(s string, n int) (error, []float64) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return fmt.Errorf("want %d comma separated numbers", n), nil
+	}
+
+	ret := make([]float64, n)
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return err, nil
+		}
+		ret[i] = v
+	}
+	return nil, ret
+}
+
+// inputs expands the command's arguments into the paths to read, matching glob patterns against the filesystem.
+// Standard input may be named once with "-".  A pattern that matches nothing is an error, as is having no inputs.
+func (o *options) inputs(args []string) (error, []string) {
+	if len(args) == 0 {
+		return usagef("no input files"), nil
+	}
+
+	var paths []string
+	sawStdin := false
+	for _, arg := range args {
+		if arg == stdinPath {
+			if sawStdin {
+				return usagef("standard input named more than once"), nil
+			}
+			sawStdin = true
+			paths = append(paths, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return usagef("invalid pattern %q: %v", arg, err), nil
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("%s: no such file", arg), nil
+		}
+		paths = append(paths, matches...)
+	}
+
+	return nil, paths
+}
+
+// open reads the pointcloud at path, or from standard input for "-", keeping the points selected by the filter
+// flags.  ASTM E57 files are recognized by their signature and read with their scans merged.
+func (o *options) open(path string, f *lassloot.Filter) (error, *lassloot.PointCloud) {
+	var err error
+	var pc *lassloot.PointCloud
+	if path == stdinPath {
+		in := bufio.NewReader(o.stdin)
+		if sig, _ := in.Peek(len(e57.Signature)); string(sig) == e57.Signature {
+			data, rerr := io.ReadAll(in)
+			if rerr != nil {
+				return rerr, nil
+			}
+			err, pc = e57.Read(bytes.NewReader(data), (int64)(len(data)))
+		} else {
+			err, pc = lassloot.NewPointCloudFromReader(in)
+		}
+	} else if isE57(path) {
+		err, pc = e57.ReadFile(path)
+	} else {
+		err, pc = lassloot.NewPointCloudFromPath(path)
+	}
+	if err != nil {
+		return err, nil
+	}
+
+	return pc.Filter(f)
+}
+
+// isE57 reports whether the file at path begins with the E57 signature.
+func isE57(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	sig := make([]byte, len(e57.Signature))
+	_, err = io.ReadFull(file, sig)
+	return err == nil && string(sig) == e57.Signature
+}
+
+// eachInput opens every input in turn and passes it to fn.  A failure on one input is reported and the remaining
+// inputs are still processed; the returned error notes how many failed.
+func (o *options) eachInput(args []string, fn func(path string, pc *lassloot.PointCloud) error) error {
+	return o.eachPath(args, func(path string, f *lassloot.Filter) error {
+		err, pc := o.open(path, f)
+		if err != nil {
+			return err
+		}
+		return fn(path, pc)
+	})
+}
+
+// eachStream is eachInput for commands that visit each point once: rather than decoding whole files it passes fn a
+// PointStream over each input, with the filter flags applied.  Standard input is read into memory first, since
+// streaming requires random access.
+func (o *options) eachStream(args []string, fn func(path string, ps *lassloot.PointStream) error) error {
+	return o.eachPath(args, func(path string, f *lassloot.Filter) error {
+		return o.stream(path, f, fn)
+	})
+}
+
+// eachPath expands args into input paths and calls fn with each along with the filter flags' filter.
+func (o *options) eachPath(args []string, fn func(path string, f *lassloot.Filter) error) error {
+	err, f := o.filter()
+	if err != nil {
+		return err
+	}
+
+	err, paths := o.inputs(args)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, path := range paths {
+		err := fn(path, f)
+		if err != nil {
+			if len(paths) == 1 {
+				return fmt.Errorf("%s: %w", displayPath(path), err)
+			}
+			fmt.Fprintf(o.stderr, "sloot: %s: %v\n", displayPath(path), err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d inputs failed", failed, len(paths))
+	}
+	return nil
+}
+
+func (o *options) stream(path string, f *lassloot.Filter, fn func(path string, ps *lassloot.PointStream) error) error {
+	var err error
+	var ps *lassloot.PointStream
+	if path == stdinPath || isE57(path) {
+		err, data := o.readLAS(path)
+		if err != nil {
+			return err
+		}
+		err, ps = lassloot.NewPointStream(bytes.NewReader(data), (int64)(len(data)))
+	} else {
+		err, ps = lassloot.OpenPointStream(path)
+	}
+	if err != nil {
+		return err
+	}
+	defer ps.Close()
+
+	ps.SetFilter(f)
+	return fn(path, ps)
+}
+
+// readLAS reads standard input or an E57 file into memory as LAS, for streaming.  E57 files are converted.
+func (o *options) readLAS(path string) (error, []byte) {
+	var data []byte
+	if path == stdinPath {
+		var err error
+		data, err = io.ReadAll(o.stdin)
+		if err != nil {
+			return err, nil
+		}
+		if !bytes.HasPrefix(data, []byte(e57.Signature)) {
+			return nil, data
+		}
+	}
+
+	var err error
+	var pc *lassloot.PointCloud
+	if data != nil {
+		err, pc = e57.Read(bytes.NewReader(data), (int64)(len(data)))
+	} else {
+		err, pc = e57.ReadFile(path)
+	}
+	if err != nil {
+		return err, nil
+	}
+
+	var buf bytes.Buffer
+	err = pc.WriteLAS(&buf)
+	return err, buf.Bytes()
+}
+
+// create opens the output named by -o, returning a buffered writer and a func that flushes and closes it.  It
+// truncates an existing file, so commands call it only once their inputs have decoded.
+func (o *options) create() (error, *bufio.Writer, func() error) {
+	if o.output == stdinPath {
+		w := bufio.NewWriter(o.stdout)
+		return nil, w, w.Flush
+	}
+
+	f, err := os.Create(o.output)
+	if err != nil {
+		return err, nil, nil
+	}
+
+	w := bufio.NewWriter(f)
+	return nil, w, func() error {
+		err := w.Flush()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+}
+
+func displayPath(path string) string {
+	if path == stdinPath {
+		return "<stdin>"
+	}
+	return path
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/parquet"
+)
+
+func init() {
+	register(&command{
+		name:    "parquet",
+		summary: "write points with every dimension as geoparquet, or as an arrow ipc stream",
+		usage:   "[flags] input",
+		setup:   setupParquet,
+	})
+}
+
+func setupParquet(fs *flag.FlagSet, opts *options) func(args []string) error {
+	arrow := fs.Bool("arrow", false, "write an arrow ipc stream instead of a parquet file, e.g. to pipe into pyarrow")
+	rowGroup := fs.Int("row-group", parquet.DefaultRowGroupSize, "points in each row group, or record batch of a stream")
+	compression := fs.String("compression", "none", "parquet page compression: none or gzip")
+	geometry := fs.Bool("geometry", true, "write a wkb geometry column, with geoparquet metadata carrying the crs")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		var o parquet.Options
+		err, c := parquet.ParseCompression(*compression)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		if *rowGroup < 1 {
+			return usagef("invalid row group size %d", *rowGroup)
+		}
+		o.Compression, o.RowGroupSize, o.OmitGeometry = c, *rowGroup, !*geometry
+
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("parquet takes a single input, got %d", len(paths))
+		}
+
+		return opts.eachInput(paths, func(path string, pc *lassloot.PointCloud) error {
+			err, out, done := opts.create()
+			if err != nil {
+				return err
+			}
+
+			if *arrow {
+				err = parquet.WriteArrowStream(out, pc, o)
+			} else {
+				err = parquet.Write(out, pc, o)
+			}
+			if derr := done(); err == nil {
+				err = derr
+			}
+			return err
+		})
+	}
+}
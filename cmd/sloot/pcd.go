@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/encoding/pcd"
+)
+
+func init() {
+	register(&command{
+		name:    "pcd",
+		summary: "write points with every dimension as a pcd file for the point cloud library",
+		usage:   "[flags] input",
+		setup:   setupPCD,
+	})
+	register(&command{
+		name:    "pcd2las",
+		summary: "convert a pcd file into a LAS 1.4 file",
+		usage:   "[flags] -o output.las input",
+		setup:   setupPCD2Las,
+	})
+}
+
+func setupPCD(fs *flag.FlagSet, opts *options) func(args []string) error {
+	data := fs.String("data", "binary", "point encoding: ascii, binary or binary_compressed")
+	origin := fs.String("origin", "center", "point subtracted from every position: center or min of the bounds, "+
+		"offset from the file header, none, or x,y,z")
+	double := fs.Bool("double", false, "write positions as 64-bit floats")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		var o pcd.Options
+		err, d := pcd.ParseDataFormat(*data)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		o.Data = d
+		o.Double = *double
+
+		switch *origin {
+		case "none", "offset", "center", "min":
+		default:
+			if err, _ := parseFloats(*origin, 3); err != nil {
+				return usagef("invalid origin %q: want center, min, offset, none or x,y,z", *origin)
+			}
+		}
+
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("pcd takes a single input, got %d", len(paths))
+		}
+
+		return opts.eachInput(paths, func(path string, pc *lassloot.PointCloud) error {
+			o.Origin = resolveOrigin(*origin, []*lassloot.PointCloud{pc})
+			err, out, done := opts.create()
+			if err != nil {
+				return err
+			}
+
+			err = pcd.Write(out, pc, o)
+			if derr := done(); err == nil {
+				err = derr
+			}
+			return err
+		})
+	}
+}
+
+func setupPCD2Las(fs *flag.FlagSet, opts *options) func(args []string) error {
+	format := fs.Int("format", -1, "point data record format to write; -1 picks the smallest that holds the fields read")
+	scale := fs.String("scale", "", "scale factor of every axis, or x,y,z; by default that recorded by sloot pcd, "+
+		"or about the precision of the position fields")
+	offset := fs.String("offset", "", "offset of every axis, or x,y,z; by default each axis' minimum rounded down to 1000")
+	opts.addOutputFlag(fs)
+
+	return func(args []string) error {
+		var o pcd.ImportOptions
+		if *format >= 0 {
+			f := (las14.PointDataFormat)(*format)
+			if *format > 255 || !f.IsValid() {
+				return usagef("invalid point format %d", *format)
+			}
+			o.Format = &f
+		}
+
+		err, _ := overridePrecision(lassloot.Precision{XScaleFactor: 1, YScaleFactor: 1, ZScaleFactor: 1}, *scale, *offset)
+		if err != nil {
+			return err
+		}
+
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("pcd2las takes a single input, got %d", len(paths))
+		}
+
+		err, f := decodePCD(opts, paths[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayPath(paths[0]), err)
+		}
+
+		if *scale != "" || *offset != "" {
+			err, p := overridePrecision(f.Precision(), *scale, *offset)
+			if err != nil {
+				return err
+			}
+			o.Precision = &p
+		}
+
+		err, pc := f.PointCloud(o)
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayPath(paths[0]), err)
+		}
+
+		err, out, done := opts.create()
+		if err != nil {
+			return err
+		}
+
+		err = pc.WriteLAS(out)
+		if derr := done(); err == nil {
+			err = derr
+		}
+		return err
+	}
+}
+
+func decodePCD(opts *options, path string) (error, *pcd.File) {
+	if path == stdinPath {
+		return pcd.Decode(opts.stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err, nil
+	}
+	defer f.Close()
+
+	return pcd.Decode(f)
+}
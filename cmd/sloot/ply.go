@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/ply"
+)
+
+func init() {
+	register(&command{
+		name:    "ply",
+		summary: "write points with their color, intensity, classification and normals as a ply file",
+		usage:   "[flags] input ...",
+		setup:   setupPLY,
+	})
+}
+
+func setupPLY(fs *flag.FlagSet, opts *options) func(args []string) error {
+	format := fs.String("format", "binary", "vertex encoding: binary (little endian) or ascii")
+	up := fs.String("up", "z", "axis elevation is written along: z, or y for meshlab")
+	origin := fs.String("origin", "center", "point subtracted from every position: center or min of the bounds, "+
+		"offset from the file header, none, or x,y,z")
+	double := fs.Bool("double", false, "write positions as 64-bit floats")
+	normals := fs.Int("normals", lassloot.DefaultNormalNeighbors, "neighbours to estimate normals from when the points "+
+		"don't store them; 0 writes no normals")
+
+	o := ply.DefaultOptions
+	fs.BoolVar(&o.Color, "color", true, "write red, green and blue when the points have them")
+	fs.BoolVar(&o.Intensity, "intensity", true, "write intensity")
+	fs.BoolVar(&o.Classification, "classification", true, "write classification")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		err, f := ply.ParseFormat(*format)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		o.Format = f
+
+		err, o.Up = lassloot.ParseUpAxis(*up)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		if *normals < 0 {
+			return usagef("invalid normals %d", *normals)
+		}
+		o.Normals = *normals
+		o.Double = *double
+
+		switch *origin {
+		case "none", "offset", "center", "min":
+		default:
+			if err, _ := parseFloats(*origin, 3); err != nil {
+				return usagef("invalid origin %q: want center, min, offset, none or x,y,z", *origin)
+			}
+		}
+
+		var clouds []*lassloot.PointCloud
+		err = opts.eachInput(args, func(path string, pc *lassloot.PointCloud) error {
+			clouds = append(clouds, pc)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		o.Origin = resolveOrigin(*origin, clouds)
+		err, out, done := opts.create()
+		if err != nil {
+			return err
+		}
+
+		err = ply.Write(out, o, clouds...)
+		if derr := done(); err == nil {
+			err = derr
+		}
+		return err
+	}
+}
+
+// resolveOrigin resolves an already validated -origin flag against the bounds or header offset of the clouds being
+// written.
+func resolveOrigin(s string, clouds []*lassloot.PointCloud) [3]float64 {
+	switch s {
+	case "none":
+		return [3]float64{}
+	case "offset":
+		p := clouds[0].Precision()
+		return [3]float64{p.XOffset, p.YOffset, p.ZOffset}
+	case "center", "min":
+		min, max := cloudBounds(clouds)
+		if s == "min" {
+			return min
+		}
+		return [3]float64{(min[0] + max[0]) / 2, (min[1] + max[1]) / 2, (min[2] + max[2]) / 2}
+	}
+
+	_, v := parseFloats(s, 3)
+	return [3]float64{v[0], v[1], v[2]}
+}
+
+// cloudBounds returns the corners of the box enclosing every cloud, from their headers.
+func cloudBounds(clouds []*lassloot.PointCloud) ([3]float64, [3]float64) {
+	var min, max [3]float64
+	first := true
+	for _, pc := range clouds {
+		if pc.Len() == 0 {
+			continue
+		}
+
+		h := pc.Header().RawHeader
+		lo := [3]float64{h.MinX, h.MinY, h.MinZ}
+		hi := [3]float64{h.MaxX, h.MaxY, h.MaxZ}
+		for a := 0; a < 3; a++ {
+			if first || lo[a] < min[a] {
+				min[a] = lo[a]
+			}
+			if first || hi[a] > max[a] {
+				max[a] = hi[a]
+			}
+		}
+		first = false
+	}
+	return min, max
+}
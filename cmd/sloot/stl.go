@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/stl"
+	"github.com/nullstyle/lassloot/solid"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+func init() {
+	register(&command{
+		name:    "stl",
+		summary: "triangulate points into a terrain solid for 3d printing, as binary stl",
+		usage:   "[flags] input",
+		setup:   setupSTL,
+	})
+}
+
+// printFlags holds the flags shared by the commands that write printable solids.
+type printFlags struct {
+	size, exaggeration, base float64
+	boundary                 string
+	duplicates               string
+
+	dups tin.Duplicates
+	o    solid.Options
+}
+
+func addPrintFlags(fs *flag.FlagSet) *printFlags {
+	p := &printFlags{}
+	fs.Float64Var(&p.size, "size", 150, "print length in millimeters of the longer horizontal side")
+	fs.Float64Var(&p.exaggeration, "exaggeration", 1, "vertical exaggeration")
+	fs.Float64Var(&p.base, "base", 5, "thickness in millimeters beneath the lowest point")
+	fs.StringVar(&p.boundary, "boundary", "", "clip to the polygon in this geojson, wkt or x y text file")
+	fs.StringVar(&p.duplicates, "duplicates", "mean", "elevation where points share a position: mean, lowest, highest or first")
+	return p
+}
+
+// parse checks the flags and reads the boundary, before any input is read.
+func (p *printFlags) parse() error {
+	err, d := tin.ParseDuplicates(p.duplicates)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	if !(p.size > 0) || !(p.base > 0) || !(p.exaggeration > 0) {
+		return usagef("size, base and exaggeration must be positive")
+	}
+	p.dups = d
+	p.o = solid.Options{Size: p.size, Exaggeration: p.exaggeration, Base: p.base}
+	if p.boundary != "" {
+		err, p.o.Boundary = readBoundary(p.boundary)
+	}
+	return err
+}
+
+// solid triangulates pc and closes the surface into a solid as the flags describe.
+func (p *printFlags) solid(pc *lassloot.PointCloud) (error, *solid.Solid) {
+	err, m := tin.New(pc, tin.Options{Duplicates: p.dups})
+	if err != nil {
+		return err, nil
+	}
+	return solid.New(m, p.o)
+}
+
+func setupSTL(fs *flag.FlagSet, opts *options) func(args []string) error {
+	p := addPrintFlags(fs)
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		if err := p.parse(); err != nil {
+			return err
+		}
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("stl takes a single input, got %d", len(paths))
+		}
+
+		return opts.eachInput(paths, func(path string, pc *lassloot.PointCloud) error {
+			err, s := p.solid(pc)
+			if err != nil {
+				return err
+			}
+
+			err, out, done := opts.create()
+			if err != nil {
+				return err
+			}
+			err = stl.Write(out, s.Vertices, s.Triangles)
+			if derr := done(); err == nil {
+				err = derr
+			}
+			return err
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/threemf"
+	"github.com/nullstyle/lassloot/solid"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+func init() {
+	register(&command{
+		name:    "3mf",
+		summary: "triangulate points into a terrain solid for color 3d printing, as 3mf with per-vertex color",
+		usage:   "[flags] input",
+		setup:   setup3MF,
+	})
+}
+
+func setup3MF(fs *flag.FlagSet, opts *options) func(args []string) error {
+	p := addPrintFlags(fs)
+	color := addColorFlag(fs)
+	title := fs.String("title", "", "title recorded in the model; the input's name if empty")
+	opts.addOutputFlag(fs)
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		if err := p.parse(); err != nil {
+			return err
+		}
+		if err := color.parse(); err != nil {
+			return err
+		}
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("3mf takes a single input, got %d", len(paths))
+		}
+
+		return opts.eachInput(paths, func(path string, pc *lassloot.PointCloud) error {
+			err, m := tin.New(pc, tin.Options{Duplicates: p.dups})
+			if err != nil {
+				return err
+			}
+			err, s := solid.New(m, p.o)
+			if err != nil {
+				return err
+			}
+
+			c := color.resolve(pc)
+			err, colors := s.Colors(m, pc, c)
+			if err != nil {
+				return err
+			}
+
+			name := *title
+			if name == "" {
+				name = "terrain"
+				if path != stdinPath {
+					name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+				}
+			}
+			o := threemf.Options{Unit: threemf.Millimeter, Colors: colors, Metadata: printMetadata(pc, s, p, name)}
+			o.Metadata["lassloot:Coloring"] = c.String()
+
+			err, out, done := opts.create()
+			if err != nil {
+				return err
+			}
+			err = threemf.Write(out, s.Vertices, s.Triangles, o)
+			if derr := done(); err == nil {
+				err = derr
+			}
+			return err
+		})
+	}
+}
+
+// printMetadata describes a solid printed from pc: its title, and how to get back from the print to the ground.
+func printMetadata(pc *lassloot.PointCloud, s *solid.Solid, p *printFlags, title string) map[string]string {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	description := fmt.Sprintf("terrain printed %smm across", format(p.size))
+	if p.exaggeration != 1 {
+		description += fmt.Sprintf(" with %sx vertical exaggeration", format(p.exaggeration))
+	}
+	ret := map[string]string{
+		"Title":                         title,
+		"Description":                   description,
+		"lassloot:Origin":               format(s.Origin[0]) + " " + format(s.Origin[1]) + " " + format(s.Origin[2]),
+		"lassloot:MillimetersPerUnit":   format(s.Scale),
+		"lassloot:VerticalExaggeration": format(p.exaggeration),
+		"lassloot:BaseThickness":        format(p.base),
+	}
+	if err, crs := pc.CRS(); err == nil && crs != nil {
+		ret["lassloot:CRS"] = crs.String()
+		if crs.MetersPerUnit > 0 {
+			ret["lassloot:MapScale"] = fmt.Sprintf("1:%.0f", crs.MetersPerUnit*1000/s.Scale)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/encoding/txt"
+)
+
+func init() {
+	register(&command{
+		name:    "txt2las",
+		summary: "convert delimited text points, such as xyz or csv exports, into a LAS 1.4 file",
+		usage:   "[flags] -o output.las input ...",
+		setup:   setupTxt2Las,
+	})
+}
+
+func setupTxt2Las(fs *flag.FlagSet, opts *options) func(args []string) error {
+	columns := fs.String("columns", "", "comma separated dimension of each field, e.g. x,y,z,intensity,class,r,g,b,time; "+
+		"'-' skips a field.  By default a header line names the columns, or else the fields are x,y,z")
+	delimiter := fs.String("delimiter", "", "field delimiter; 'tab' and 'space' name those characters.  By default it is detected")
+	skip := fs.Int("skip", 0, "lines to ignore at the start of each input")
+	format := fs.Int("format", -1, "point data record format to write; -1 picks the smallest that holds the columns read")
+	scale := fs.String("scale", "", "scale factor of every axis, or x,y,z; by default the decimal places of the input are kept")
+	offset := fs.String("offset", "", "offset of every axis, or x,y,z; by default each axis' minimum rounded down to 1000")
+	timeFormat := fs.String("time", "auto", "how numeric times count: auto, adjusted, gps, week or unix")
+	opts.addOutputFlag(fs)
+
+	return func(args []string) error {
+		var o txt.Options
+		o.Columns = splitList(*columns)
+		o.SkipLines = *skip
+
+		if *delimiter != "" {
+			err, comma := parseDelimiter(*delimiter)
+			if err != nil {
+				return err
+			}
+			o.Delimiter = comma
+		}
+
+		if *format >= 0 {
+			f := (las14.PointDataFormat)(*format)
+			if *format > 255 || !f.IsValid() {
+				return usagef("invalid point format %d", *format)
+			}
+			o.Format = &f
+		}
+
+		err, tf := txt.ParseTimeFormat(*timeFormat)
+		if err != nil {
+			return usagef("%v", err)
+		}
+		o.TimeFormat = tf
+
+		// check the flags before reading any input; the axes they leave unset are filled in once it has been read
+		err, _ = overridePrecision(lassloot.Precision{XScaleFactor: 1, YScaleFactor: 1, ZScaleFactor: 1}, *scale, *offset)
+		if err != nil {
+			return err
+		}
+
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+
+		imp := txt.NewImporter(o)
+		for _, path := range paths {
+			err := readText(imp, opts, path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", displayPath(path), err)
+			}
+		}
+
+		if *scale != "" || *offset != "" {
+			err, p := overridePrecision(imp.Precision(), *scale, *offset)
+			if err != nil {
+				return err
+			}
+			imp.SetPrecision(p)
+		}
+
+		err, pc := imp.PointCloud()
+		if err != nil {
+			return err
+		}
+
+		err, out, done := opts.create()
+		if err != nil {
+			return err
+		}
+
+		err = pc.WriteLAS(out)
+		if derr := done(); err == nil {
+			err = derr
+		}
+		return err
+	}
+}
+
+func readText(imp *txt.Importer, opts *options, path string) error {
+	if path == stdinPath {
+		return imp.Read(opts.stdin)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return imp.Read(f)
+}
+
+// overridePrecision replaces the scale factors and offsets of p with those given by the -scale and -offset flags.
+func overridePrecision(p lassloot.Precision, scale string, offset string) (error, lassloot.Precision) {
+	axes := func(name string, s string, x, y, z *float64) error {
+		if s == "" {
+			return nil
+		}
+
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			*x, *y, *z = v, v, v
+			return nil
+		}
+
+		err, v := parseFloats(s, 3)
+		if err != nil {
+			return usagef("invalid %s %q: want a number or x,y,z", name, s)
+		}
+		*x, *y, *z = v[0], v[1], v[2]
+		return nil
+	}
+
+	err := axes("scale", scale, &p.XScaleFactor, &p.YScaleFactor, &p.ZScaleFactor)
+	if err != nil {
+		return err, p
+	}
+	err = axes("offset", offset, &p.XOffset, &p.YOffset, &p.ZOffset)
+	if err != nil {
+		return err, p
+	}
+
+	for _, v := range [...]float64{p.XScaleFactor, p.YScaleFactor, p.ZScaleFactor} {
+		if v <= 0 {
+			return usagef("invalid scale %q: scale factors must be positive", scale), p
+		}
+	}
+	return nil, p
+}
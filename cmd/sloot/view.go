@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/viewer"
+)
+
+func init() {
+	register(&command{
+		name:    "view",
+		summary: "view a point cloud in the browser or, with -terminal, from above in the terminal",
+		usage:   "[flags] input",
+		setup:   setupView,
+	})
+}
+
+func setupView(fs *flag.FlagSet, opts *options) func(args []string) error {
+	addr := fs.String("addr", "127.0.0.1:0", "address to serve the viewer on; port 0 picks a free port")
+	terminal := fs.Bool("terminal", false, "preview the cloud from above in the terminal instead of serving the browser viewer")
+	color := fs.String("color", "elevation", "terminal coloring: elevation, intensity, classification or rgb")
+	trueColor := fs.Bool("truecolor", os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit",
+		"use 24-bit color in the terminal rather than the 256 color palette")
+	opts.addFilterFlags(fs)
+
+	return func(args []string) error {
+		err, paths := opts.inputs(args)
+		if err != nil {
+			return err
+		}
+		if len(paths) != 1 {
+			return usagef("view takes a single input, got %d", len(paths))
+		}
+
+		err, mode := viewer.ParseColorMode(*color)
+		if err != nil {
+			return usagef("%v", err)
+		}
+
+		return opts.eachInput(paths, func(path string, pc *lassloot.PointCloud) error {
+			name := filepath.Base(displayPath(path))
+
+			if *terminal {
+				// keys are read from the terminal itself, which stays available when the file arrives on stdin
+				tty, err := os.Open("/dev/tty")
+				if err != nil {
+					return viewer.RunTerminal(name, pc, os.Stdin, opts.stdout, mode, *trueColor)
+				}
+				defer tty.Close()
+				return viewer.RunTerminal(name, pc, tty, opts.stdout, mode, *trueColor)
+			}
+
+			fmt.Fprintf(opts.stderr, "ordering %d points by level of detail\n", pc.Len())
+			err, server := viewer.NewServer(name, pc)
+			if err != nil {
+				return fmt.Errorf("failed to prepare viewer: %w", err)
+			}
+
+			l, err := net.Listen("tcp", *addr)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(opts.stdout, "viewing %s at http://%s/\n", displayPath(path), l.Addr())
+			return http.Serve(l, server)
+		})
+	}
+}
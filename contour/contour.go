@@ -0,0 +1,332 @@
+// Package contour traces lines of equal elevation across terrain surfaces, either triangulated irregular networks or
+// gridded elevation models.
+//
+// Contours are traced by linear interpolation along the edges of triangles, so they never cross one another and meet
+// the edges of the surface exactly.  Lines that reach an edge of the surface, or of a hole in a grid, end there; all
+// others close into loops.
+package contour
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nullstyle/lassloot/grid"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+// maxLevels bounds the number of elevations contoured, to catch intervals given in the wrong units.
+const maxLevels = 100000
+
+// Options control which contours are traced and how they are shaped.
+type Options struct {
+	// Interval is the elevation between neighbouring contours.  It must be positive.
+	Interval float64
+
+	// Base is an elevation with a contour, which others are a multiple of Interval from.  Zero usually suits.
+	Base float64
+
+	// IndexEvery marks every Nth contour, counting from Base, as an index contour: the bold, labelled lines of a
+	// topographic map.  Zero marks none.
+	IndexEvery int
+
+	// Smoothing is the number of rounds of corner cutting applied to each line.  Each round doubles a line's points
+	// and draws it closer to a smooth curve.  Smoothed lines no longer pass exactly through the surface, and lines
+	// very close together may cross.
+	Smoothing int
+
+	// MinLength drops lines shorter than it, horizontally, which removes the tiny loops that noise in the ground
+	// leaves.
+	MinLength float64
+}
+
+// Line is a contour line.
+type Line struct {
+	Elevation float64
+
+	// Index is set on index contours.
+	Index bool
+
+	// Closed is set on lines that loop back to their start.  Their first point is not repeated at their end.
+	Closed bool
+
+	// Points holds the horizontal positions along the line, which runs with higher ground on its left: closed lines run
+	// counterclockwise around hills and clockwise around hollows.
+	Points [][2]float64
+}
+
+// Length returns the horizontal length of l, including the segment closing it.
+func (l *Line) Length() float64 {
+	var ret float64
+	for i := 1; i < len(l.Points); i++ {
+		ret += math.Hypot(l.Points[i][0]-l.Points[i-1][0], l.Points[i][1]-l.Points[i-1][1])
+	}
+	if l.Closed && len(l.Points) > 1 {
+		first, last := l.Points[0], l.Points[len(l.Points)-1]
+		ret += math.Hypot(first[0]-last[0], first[1]-last[1])
+	}
+	return ret
+}
+
+// Label returns where to label l, halfway along it, and the angle in degrees counterclockwise from east that its label
+// runs there, turned so that it reads upright.
+func (l *Line) Label() ([2]float64, float64) {
+	half := l.Length() / 2
+	n := len(l.Points)
+	for i := 0; i < n; i++ {
+		if !l.Closed && i == n-1 {
+			break
+		}
+		a, b := l.Points[i], l.Points[(i+1)%n]
+		d := math.Hypot(b[0]-a[0], b[1]-a[1])
+		if d < half || d == 0 {
+			half -= d
+			continue
+		}
+		t := half / d
+		angle := math.Atan2(b[1]-a[1], b[0]-a[0]) * 180 / math.Pi
+		if angle > 90 {
+			angle -= 180
+		} else if angle <= -90 {
+			angle += 180
+		}
+		return [2]float64{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}, angle
+	}
+	return l.Points[0], 0
+}
+
+// FromMesh traces the contours of m, lowest first.
+func FromMesh(m *tin.Mesh, opts Options) (error, []Line) {
+	return trace(m.Vertices, m.Triangles, opts)
+}
+
+// FromGrid traces the contours of g, lowest first, treating the value of each cell as the elevation at its center.
+// Each square between four cell centers is split into four triangles about its middle, at their mean elevation, which
+// settles saddles the way the surrounding cells suggest.  Where one of the four cells has no value, the square is the
+// triangle of the other three; where more lack one, it is left out.
+func FromGrid(g *grid.Grid, opts Options) (error, []Line) {
+	var vertices [][3]float64
+	index := make([]int32, len(g.Values))
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			i := row*g.Cols + col
+			index[i] = -1
+			if z := g.Values[i]; !math.IsNaN(z) && !math.IsInf(z, 0) {
+				x, y := g.Center(col, row)
+				index[i] = (int32)(len(vertices))
+				vertices = append(vertices, [3]float64{x, y, z})
+			}
+		}
+	}
+
+	var triangles [][3]int32
+	for row := 0; row+1 < g.Rows; row++ {
+		for col := 0; col+1 < g.Cols; col++ {
+			// the corners of the square counterclockwise seen from above, from the bottom left
+			corners := [4]int32{
+				index[(row+1)*g.Cols+col], index[(row+1)*g.Cols+col+1], index[row*g.Cols+col+1], index[row*g.Cols+col],
+			}
+			var valid []int32
+			for _, c := range corners {
+				if c >= 0 {
+					valid = append(valid, c)
+				}
+			}
+			switch len(valid) {
+			case 3:
+				triangles = append(triangles, [3]int32{valid[0], valid[1], valid[2]})
+			case 4:
+				var mid [3]float64
+				for _, c := range corners {
+					for k := range mid {
+						mid[k] += vertices[c][k] / 4
+					}
+				}
+				m := (int32)(len(vertices))
+				vertices = append(vertices, mid)
+				for k := range corners {
+					triangles = append(triangles, [3]int32{corners[k], corners[(k+1)%4], m})
+				}
+			}
+		}
+	}
+	if len(vertices) > math.MaxInt32 {
+		return fmt.Errorf("grid of %d by %d cells is too large to contour", g.Cols, g.Rows), nil
+	}
+	return trace(vertices, triangles, opts)
+}
+
+// segment crosses a triangle at one elevation, from where it crosses one edge to where it crosses another.  Edges are
+// keyed by their vertices, so the triangles either side of an edge agree on its key.
+type segment struct {
+	from, to uint64
+}
+
+func edgeKey(a, b int32) uint64 {
+	if a > b {
+		a, b = b, a
+	}
+	return (uint64)(a)<<32 | (uint64)(b)
+}
+
+func trace(vertices [][3]float64, triangles [][3]int32, opts Options) (error, []Line) {
+	if !(opts.Interval > 0) || math.IsInf(opts.Interval, 0) {
+		return fmt.Errorf("invalid contour interval %v", opts.Interval), nil
+	}
+	if math.IsNaN(opts.Base) || math.IsInf(opts.Base, 0) {
+		return fmt.Errorf("invalid contour base %v", opts.Base), nil
+	}
+	if opts.IndexEvery < 0 {
+		return fmt.Errorf("invalid index contour count %d", opts.IndexEvery), nil
+	}
+	if opts.Smoothing < 0 {
+		return fmt.Errorf("invalid smoothing %d", opts.Smoothing), nil
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, tri := range triangles {
+		for _, v := range tri {
+			lo, hi = math.Min(lo, vertices[v][2]), math.Max(hi, vertices[v][2])
+		}
+	}
+	if n := (hi - lo) / opts.Interval; n > maxLevels {
+		return fmt.Errorf("interval %v makes %.0f contours between %v and %v", opts.Interval, n, lo, hi), nil
+	}
+
+	level := func(k int) float64 { return opts.Base + (float64)(k)*opts.Interval }
+
+	// a vertex at a contour's elevation counts as above it, so the contour passes through the vertex without
+	// doubling back, and where the surface is flat at that elevation the contour follows its lower edge
+	segments := make(map[int][]segment)
+	for _, tri := range triangles {
+		z := [3]float64{vertices[tri[0]][2], vertices[tri[1]][2], vertices[tri[2]][2]}
+		tlo, thi := math.Min(z[0], math.Min(z[1], z[2])), math.Max(z[0], math.Max(z[1], z[2]))
+		for k := (int)(math.Floor((tlo - opts.Base) / opts.Interval)); level(k) <= thi; k++ {
+			l := level(k)
+			if l <= tlo {
+				continue
+			}
+			var s segment
+			for e := 0; e < 3; e++ {
+				above, nextAbove := z[e] >= l, z[(e+1)%3] >= l
+				if above && !nextAbove {
+					s.from = edgeKey(tri[e], tri[(e+1)%3])
+				} else if !above && nextAbove {
+					s.to = edgeKey(tri[e], tri[(e+1)%3])
+				}
+			}
+			segments[k] = append(segments[k], s)
+		}
+	}
+
+	levels := make([]int, 0, len(segments))
+	for k := range segments {
+		levels = append(levels, k)
+	}
+	sort.Ints(levels)
+
+	var ret []Line
+	for _, k := range levels {
+		l := level(k)
+		cross := func(key uint64) [2]float64 {
+			a, b := vertices[key>>32], vertices[key&math.MaxUint32]
+			t := (l - a[2]) / (b[2] - a[2])
+			return [2]float64{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}
+		}
+
+		for _, keys := range link(segments[k]) {
+			line := Line{Elevation: l, Index: opts.IndexEvery > 0 && k%opts.IndexEvery == 0}
+			if len(keys) > 1 && keys[0] == keys[len(keys)-1] {
+				line.Closed = true
+				keys = keys[:len(keys)-1]
+			}
+			// a contour through a vertex crosses each edge meeting there at the same point
+			for _, key := range keys {
+				p := cross(key)
+				if n := len(line.Points); n == 0 || p != line.Points[n-1] {
+					line.Points = append(line.Points, p)
+				}
+			}
+			if n := len(line.Points); line.Closed && n > 1 && line.Points[0] == line.Points[n-1] {
+				line.Points = line.Points[:n-1]
+			}
+			if len(line.Points) < 2 || (line.Closed && len(line.Points) < 3) {
+				continue
+			}
+
+			for i := 0; i < opts.Smoothing; i++ {
+				line.Points = smooth(line.Points, line.Closed)
+			}
+			if line.Length() < opts.MinLength {
+				continue
+			}
+			ret = append(ret, line)
+		}
+	}
+	return nil, ret
+}
+
+// link joins the segments of one elevation end to start into lines, returning the edges each line crosses in order.
+// Closed lines end with the edge they start with.
+func link(segments []segment) [][]uint64 {
+	next := make(map[uint64]int, len(segments))
+	ends := make(map[uint64]bool, len(segments))
+	for i, s := range segments {
+		next[s.from] = i
+		ends[s.to] = true
+	}
+
+	used := make([]bool, len(segments))
+	var ret [][]uint64
+	follow := func(i int) {
+		keys := []uint64{segments[i].from}
+		for {
+			used[i] = true
+			keys = append(keys, segments[i].to)
+			j, ok := next[segments[i].to]
+			if !ok || used[j] {
+				break
+			}
+			i = j
+		}
+		ret = append(ret, keys)
+	}
+
+	// open lines start on an edge of the surface, which no segment ends on; every line left after them is closed
+	for i, s := range segments {
+		if !used[i] && !ends[s.from] {
+			follow(i)
+		}
+	}
+	for i := range segments {
+		if !used[i] {
+			follow(i)
+		}
+	}
+	return ret
+}
+
+// smooth cuts each corner of a line, replacing each segment with points a quarter and three quarters along it.  Open
+// lines keep their ends, so they still meet the edge of the surface.
+func smooth(points [][2]float64, closed bool) [][2]float64 {
+	n := len(points)
+	segments := n - 1
+	if closed {
+		segments = n
+	}
+
+	ret := make([][2]float64, 0, 2*n+2)
+	if !closed {
+		ret = append(ret, points[0])
+	}
+	for i := 0; i < segments; i++ {
+		a, b := points[i], points[(i+1)%n]
+		ret = append(ret,
+			[2]float64{0.75*a[0] + 0.25*b[0], 0.75*a[1] + 0.25*b[1]},
+			[2]float64{0.25*a[0] + 0.75*b[0], 0.25*a[1] + 0.75*b[1]})
+	}
+	if !closed {
+		ret = append(ret, points[n-1])
+	}
+	return ret
+}
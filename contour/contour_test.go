@@ -0,0 +1,209 @@
+package contour
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/grid"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+// signedArea returns the area of a closed line, positive when it runs counterclockwise.
+func signedArea(points [][2]float64) float64 {
+	var ret float64
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		ret += a[0]*b[1] - b[0]*a[1]
+	}
+	return ret / 2
+}
+
+func TestFromMeshPlane(t *testing.T) {
+	// a plane rising to the east, with vertices on every contour
+	var points [][3]float64
+	for x := 0; x <= 10; x++ {
+		for y := 0; y <= 5; y++ {
+			points = append(points, [3]float64{(float64)(x), (float64)(y), (float64)(x)})
+		}
+	}
+	err, m := tin.Triangulate(points, tin.Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err, lines := FromMesh(m, Options{Interval: 2, Base: 1, IndexEvery: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 5 {
+		t.Fatalf("%d lines, want 5", len(lines))
+	}
+	for i, l := range lines {
+		if want := (float64)(2*i + 1); l.Elevation != want || l.Index != (i%2 == 0) || l.Closed {
+			t.Errorf("line %d at %v, index %v, closed %v", i, l.Elevation, l.Index, l.Closed)
+		}
+		// straight across from the north edge to the south, with the rise to the east on its left
+		if len(l.Points) != 6 || l.Points[0][1] != 5 || l.Points[5][1] != 0 {
+			t.Errorf("line %d is %v", i, l.Points)
+		}
+		for _, p := range l.Points {
+			if p[0] != l.Elevation {
+				t.Errorf("line %d strays to %v", i, p)
+			}
+		}
+	}
+}
+
+// cone returns a cone 10 high over the square from -10, -10 to 10, 10, or a hollow of that depth.
+func cone(x, y float64, hollow bool) float64 {
+	z := math.Max(0, 10-math.Hypot(x, y))
+	if hollow {
+		return -z
+	}
+	return z
+}
+
+func TestFromMeshCone(t *testing.T) {
+	for _, hollow := range []bool{false, true} {
+		var points [][3]float64
+		for x := -10.0; x <= 10; x += 0.5 {
+			for y := -10.0; y <= 10; y += 0.5 {
+				points = append(points, [3]float64{x + 0.01*y, y, cone(x+0.01*y, y, hollow)})
+			}
+		}
+		err, m := tin.Triangulate(points, tin.Mean)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err, lines := FromMesh(m, Options{Interval: 1, Base: 0.5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 10 {
+			t.Fatalf("%d lines, want 10", len(lines))
+		}
+		for _, l := range lines {
+			if !l.Closed {
+				t.Fatalf("line at %v is open", l.Elevation)
+			}
+			radius := 10 - math.Abs(l.Elevation)
+			for _, p := range l.Points {
+				if d := math.Hypot(p[0], p[1]); math.Abs(d-radius) > 0.05 {
+					t.Fatalf("line at %v passes %v from the peak, want %v", l.Elevation, d, radius)
+				}
+			}
+			if area := signedArea(l.Points); (area > 0) == hollow || math.Abs(math.Abs(area)-math.Pi*radius*radius) > 0.5 {
+				t.Errorf("line at %v, hollow %v, encloses %v", l.Elevation, hollow, area)
+			}
+		}
+	}
+}
+
+func TestFromGrid(t *testing.T) {
+	err, g := grid.New(-10.5, 10.5, 1, 21, 21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			x, y := g.Center(col, row)
+			g.Set(col, row, cone(x, y, false))
+		}
+	}
+
+	err, lines := FromGrid(g, Options{Interval: 2, Base: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 5 {
+		t.Fatalf("%d lines, want 5", len(lines))
+	}
+	for _, l := range lines {
+		if radius := 10 - l.Elevation; !l.Closed || math.Abs(signedArea(l.Points)/(math.Pi*radius*radius)-1) > 0.25 {
+			t.Errorf("line at %v, closed %v, encloses %v", l.Elevation, l.Closed, signedArea(l.Points))
+		}
+	}
+
+	// a hole east of the peak opens the loops crossing it into arcs either side, and each end meets the hole
+	for row := 0; row < g.Rows; row++ {
+		g.Set(15, row, math.NaN())
+	}
+	err, lines = FromGrid(g, Options{Interval: 2, Base: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := 0
+	for _, l := range lines {
+		if l.Closed {
+			continue
+		}
+		open++
+		for _, p := range []([2]float64){l.Points[0], l.Points[len(l.Points)-1]} {
+			if p[0] != 4 && p[0] != 6 {
+				t.Errorf("line at %v ends at %v, away from the hole", l.Elevation, p)
+			}
+		}
+	}
+	if open != 5 {
+		// both arcs of the 2 loops reaching beyond the hole, and one of the loop reaching into it
+		t.Errorf("%d open lines, want 5", open)
+	}
+}
+
+func TestSmoothingAndLength(t *testing.T) {
+	err, g := grid.New(0, 3, 1, 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range g.Values {
+		g.Values[i] = 0
+	}
+	g.Set(1, 1, 1)
+
+	err, lines := FromGrid(g, Options{Interval: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || !lines[0].Closed {
+		t.Fatalf("lines %v", lines)
+	}
+	n, length := len(lines[0].Points), lines[0].Length()
+
+	err, lines = FromGrid(g, Options{Interval: 0.5, Smoothing: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || len(lines[0].Points) != 4*n || !lines[0].Closed || !(lines[0].Length() < length) {
+		t.Errorf("smoothed %d points into %v", n, lines)
+	}
+
+	if err, lines = FromGrid(g, Options{Interval: 0.5, MinLength: length + 0.01}); err != nil || len(lines) != 0 {
+		t.Errorf("lines shorter than the minimum kept: %v", lines)
+	}
+	if err, _ := FromGrid(g, Options{}); err == nil {
+		t.Error("contoured without an interval")
+	}
+	if err, _ := FromGrid(g, Options{Interval: 1e-9}); err == nil {
+		t.Error("contoured a billion levels")
+	}
+}
+
+func TestLabel(t *testing.T) {
+	for _, c := range []struct {
+		l     Line
+		at    [2]float64
+		angle float64
+	}{
+		{Line{Points: [][2]float64{{0, 0}, {4, 0}, {4, 2}}}, [2]float64{3, 0}, 0},
+		{Line{Points: [][2]float64{{4, 2}, {4, 0}, {0, 0}}}, [2]float64{3, 0}, 0},
+		{Line{Points: [][2]float64{{0, 0}, {0, 2}, {0, 4}}}, [2]float64{0, 2}, 90},
+		{Line{Points: [][2]float64{{0, 4}, {0, 0}}}, [2]float64{0, 2}, 90},
+		{Line{Closed: true, Points: [][2]float64{{0, 0}, {2, 0}, {2, 2}, {0, 2}}}, [2]float64{2, 2}, 90},
+	} {
+		at, angle := c.l.Label()
+		if math.Abs(at[0]-c.at[0]) > 1e-12 || math.Abs(at[1]-c.at[1]) > 1e-12 || angle != c.angle {
+			t.Errorf("%v labelled at %v, %v, want %v, %v", c.l.Points, at, angle, c.at, c.angle)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package lassloot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// CRS summarizes the coordinate reference system recorded in a LAS file's VLRs, either as GeoTIFF keys or as OGC WKT.
+type CRS struct {
+	// Name and EPSG identify the horizontal (projected or geographic) system.  EPSG is 0 when no code is recorded.
+	Name       string
+	EPSG       int
+	Geographic bool
+
+	VerticalName string
+	VerticalEPSG int
+
+	// Units names the horizontal linear unit and MetersPerUnit converts it to meters.  Both are empty when the
+	// file doesn't record a unit, and geographic systems are measured in degrees with no MetersPerUnit.
+	Units         string
+	MetersPerUnit float64
+
+	VerticalUnits         string
+	VerticalMetersPerUnit float64
+
+	// WKT and GeoKeys hold the raw definitions the summary was drawn from, when present.
+	WKT     string
+	GeoKeys *las14.GeoKeyDirectory
+}
+
+// linearUnits maps EPSG unit of measure codes to their names and length in meters.
+var linearUnits = map[int]struct {
+	name   string
+	meters float64
+}{
+	9001: {"metre", 1},
+	9002: {"foot", 0.3048},
+	9003: {"US survey foot", 1200.0 / 3937.0},
+	9036: {"kilometre", 1000},
+}
+
+// CRS returns the pointcloud's coordinate reference system, or nil when the file doesn't record one.  When a file
+// holds both WKT and GeoTIFF keys, the representation selected by the header's WKT flag takes precedence and the
+// other fills in anything it lacks.
+func (pc *PointCloud) CRS() (error, *CRS) {
+	var wkt string
+	var directory, doubles, ascii []byte
+
+	visit := func(userID string, recordID uint16, data []byte) {
+		if userID != las14.UserIDProjection {
+			return
+		}
+		switch recordID {
+		case las14.RecordIDCoordinateWKT:
+			wkt = strings.TrimRight(string(data), "\x00")
+		case las14.RecordIDGeoKeyDirectory:
+			directory = data
+		case las14.RecordIDGeoDoubleParams:
+			doubles = data
+		case las14.RecordIDGeoASCIIParams:
+			ascii = data
+		}
+	}
+	for _, vlr := range pc.fr.VariableLengthRecords {
+		visit(vlr.UserIDString(), vlr.RecordID, vlr.Data)
+	}
+	for _, evlr := range pc.fr.ExtendedVariableLengthRecords {
+		visit(evlr.UserIDString(), evlr.RecordID, evlr.Data)
+	}
+
+	if wkt == "" && directory == nil {
+		return nil, nil
+	}
+
+	var fromWKT, fromKeys *CRS
+	if wkt != "" {
+		err, c := crsFromWKT(wkt)
+		if err != nil {
+			return fmt.Errorf("invalid wkt: %w", err), nil
+		}
+		fromWKT = c
+	}
+	if directory != nil {
+		err, gkd := las14.DecodeGeoKeyDirectory(directory, doubles, ascii)
+		if err != nil {
+			return fmt.Errorf("invalid geo keys: %w", err), nil
+		}
+		fromKeys = crsFromGeoKeys(gkd)
+	}
+
+	switch {
+	case fromKeys == nil:
+		return nil, fromWKT
+	case fromWKT == nil:
+		return nil, fromKeys
+	case pc.fr.Header.GlobalEncoding.UseWKTForCRS():
+		return nil, fromWKT.merge(fromKeys)
+	default:
+		return nil, fromKeys.merge(fromWKT)
+	}
+}
+
+// merge fills in any fields of c that other knows and c doesn't.
+func (c *CRS) merge(other *CRS) *CRS {
+	merged := *c
+	if merged.Name == "" {
+		merged.Name = other.Name
+	}
+	if merged.EPSG == 0 {
+		merged.EPSG, merged.Geographic = other.EPSG, other.Geographic
+	}
+	if merged.VerticalName == "" {
+		merged.VerticalName = other.VerticalName
+	}
+	if merged.VerticalEPSG == 0 {
+		merged.VerticalEPSG = other.VerticalEPSG
+	}
+	if merged.Units == "" {
+		merged.Units, merged.MetersPerUnit = other.Units, other.MetersPerUnit
+	}
+	if merged.VerticalUnits == "" {
+		merged.VerticalUnits, merged.VerticalMetersPerUnit = other.VerticalUnits, other.VerticalMetersPerUnit
+	}
+	if merged.WKT == "" {
+		merged.WKT = other.WKT
+	}
+	if merged.GeoKeys == nil {
+		merged.GeoKeys = other.GeoKeys
+	}
+	return &merged
+}
+
+// String summarizes the crs on a single line, e.g. "EPSG:26910 NAD83 / UTM zone 10N (metre) + EPSG:5703 (metre)".
+func (c *CRS) String() string {
+	describe := func(epsg int, name string, units string) string {
+		var parts []string
+		if epsg != 0 {
+			parts = append(parts, fmt.Sprintf("EPSG:%d", epsg))
+		}
+		if name != "" {
+			parts = append(parts, name)
+		}
+		if units != "" {
+			parts = append(parts, "("+units+")")
+		}
+		return strings.Join(parts, " ")
+	}
+
+	ret := describe(c.EPSG, c.Name, c.Units)
+	if c.Geographic && c.Units == "" {
+		ret = describe(c.EPSG, c.Name, "degree")
+	}
+	if ret == "" {
+		ret = "unknown"
+	}
+
+	if vertical := describe(c.VerticalEPSG, c.VerticalName, c.VerticalUnits); vertical != "" {
+		ret += " + " + vertical
+	}
+
+	return ret
+}
+
+func crsFromGeoKeys(gkd *las14.GeoKeyDirectory) *CRS {
+	c := &CRS{GeoKeys: gkd}
+
+	if code, ok := gkd.Short(las14.ProjectedCSTypeGeoKey); ok && code != las14.GeoKeyUserDefined {
+		c.EPSG = (int)(code)
+	} else if code, ok := gkd.Short(las14.GeographicTypeGeoKey); ok && code != las14.GeoKeyUserDefined {
+		c.EPSG = (int)(code)
+		c.Geographic = true
+	}
+	if model, ok := gkd.Short(las14.GTModelTypeGeoKey); ok && model == las14.ModelTypeGeographic {
+		c.Geographic = true
+	}
+
+	for _, key := range []uint16{las14.PCSCitationGeoKey, las14.GTCitationGeoKey, las14.GeogCitationGeoKey} {
+		if name, ok := gkd.Text(key); ok && name != "" {
+			c.Name = name
+			break
+		}
+	}
+
+	if code, ok := gkd.Short(las14.ProjLinearUnitsGeoKey); ok {
+		if u, ok := linearUnits[(int)(code)]; ok {
+			c.Units, c.MetersPerUnit = u.name, u.meters
+		}
+	}
+
+	if code, ok := gkd.Short(las14.VerticalCSTypeGeoKey); ok && code != las14.GeoKeyUserDefined {
+		c.VerticalEPSG = (int)(code)
+	}
+	if name, ok := gkd.Text(las14.VerticalCitationGeoKey); ok {
+		c.VerticalName = name
+	}
+	if code, ok := gkd.Short(las14.VerticalUnitsGeoKey); ok {
+		if u, ok := linearUnits[(int)(code)]; ok {
+			c.VerticalUnits, c.VerticalMetersPerUnit = u.name, u.meters
+		}
+	}
+
+	return c
+}
+
+var (
+	wktCompound   = map[string]bool{"COMPD_CS": true, "COMPOUNDCRS": true}
+	wktGeographic = map[string]bool{"GEOGCS": true, "GEOGCRS": true, "GEOGRAPHICCRS": true, "GEODCRS": true, "GEODETICCRS": true}
+	wktProjected  = map[string]bool{"PROJCS": true, "PROJCRS": true, "PROJECTEDCRS": true}
+	wktVertical   = map[string]bool{"VERT_CS": true, "VERTCRS": true, "VERTICALCRS": true}
+	wktUnits      = map[string]bool{"UNIT": true, "LENGTHUNIT": true, "ANGLEUNIT": true}
+	wktBaseCRS    = map[string]bool{"GEOGCS": true, "BASEGEOGCRS": true, "BASEGEODCRS": true}
+)
+
+func crsFromWKT(wkt string) (error, *CRS) {
+	err, root := parseWKT(wkt)
+	if err != nil {
+		return err, nil
+	}
+
+	c := &CRS{WKT: wkt}
+
+	var horizontal, vertical *wktNode
+	switch {
+	case wktCompound[root.keyword]:
+		for _, child := range root.children() {
+			switch {
+			case horizontal == nil && (wktProjected[child.keyword] || wktGeographic[child.keyword]):
+				horizontal = child
+			case vertical == nil && wktVertical[child.keyword]:
+				vertical = child
+			}
+		}
+		if horizontal == nil {
+			c.Name = root.name()
+		}
+	case wktVertical[root.keyword]:
+		vertical = root
+	default:
+		horizontal = root
+	}
+
+	if horizontal != nil {
+		c.Name = horizontal.name()
+		c.EPSG = horizontal.epsg()
+		c.Geographic = wktGeographic[horizontal.keyword]
+		if !c.Geographic {
+			c.Units, c.MetersPerUnit = horizontal.unit()
+		}
+	}
+
+	if vertical != nil {
+		c.VerticalName = vertical.name()
+		c.VerticalEPSG = vertical.epsg()
+		c.VerticalUnits, c.VerticalMetersPerUnit = vertical.unit()
+	}
+
+	return nil, c
+}
+
+// wktNode is a single KEYWORD[...] element of a WKT string.  Values are strings for quoted text, wktNumbers for bare
+// tokens and *wktNodes for nested elements.
+type wktNode struct {
+	keyword string
+	values  []interface{}
+}
+
+type wktNumber string
+
+func (n *wktNode) children() []*wktNode {
+	var ret []*wktNode
+	for _, v := range n.values {
+		if child, ok := v.(*wktNode); ok {
+			ret = append(ret, child)
+		}
+	}
+	return ret
+}
+
+func (n *wktNode) name() string {
+	if len(n.values) > 0 {
+		if s, ok := n.values[0].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func (n *wktNode) epsg() int {
+	for _, child := range n.children() {
+		if child.keyword != "AUTHORITY" && child.keyword != "ID" {
+			continue
+		}
+		if !strings.EqualFold(child.name(), "EPSG") || len(child.values) < 2 {
+			continue
+		}
+
+		var code string
+		switch v := child.values[1].(type) {
+		case string:
+			code = v
+		case wktNumber:
+			code = (string)(v)
+		}
+		if n, err := strconv.Atoi(code); err == nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// unit returns the linear unit of the element, searching its own children before those of any nested coordinate
+// system definitions other than its base geographic system.
+func (n *wktNode) unit() (string, float64) {
+	queue := []*wktNode{n}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for _, child := range cur.children() {
+			if wktUnits[child.keyword] && child.keyword != "ANGLEUNIT" && len(child.values) >= 2 {
+				factor, _ := strconv.ParseFloat(fmt.Sprint(child.values[1]), 64)
+				return child.name(), factor
+			}
+		}
+		for _, child := range cur.children() {
+			if !wktBaseCRS[child.keyword] {
+				queue = append(queue, child)
+			}
+		}
+	}
+	return "", 0
+}
+
+func parseWKT(s string) (error, *wktNode) {
+	p := &wktParser{s: s}
+	err, node := p.node()
+	if err != nil {
+		return err, nil
+	}
+
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return fmt.Errorf("unexpected trailing text at offset %d", p.pos), nil
+	}
+	return nil, node
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *wktParser) node() (error, *wktNode) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && (isWKTIdent(p.s[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return fmt.Errorf("expected keyword at offset %d", p.pos), nil
+	}
+	n := &wktNode{keyword: strings.ToUpper(p.s[start:p.pos])}
+
+	p.skipSpace()
+	if p.pos >= len(p.s) || (p.s[p.pos] != '[' && p.s[p.pos] != '(') {
+		return fmt.Errorf("expected '[' after %s at offset %d", n.keyword, p.pos), nil
+	}
+	closer := byte(']')
+	if p.s[p.pos] == '(' {
+		closer = ')'
+	}
+	p.pos++
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return fmt.Errorf("unterminated %s", n.keyword), nil
+		}
+
+		switch c := p.s[p.pos]; {
+		case c == closer:
+			p.pos++
+			return nil, n
+		case c == ',':
+			p.pos++
+		case c == '"':
+			err, str := p.quoted()
+			if err != nil {
+				return err, nil
+			}
+			n.values = append(n.values, str)
+		default:
+			start := p.pos
+			for p.pos < len(p.s) && isWKTIdent(p.s[p.pos]) || p.pos < len(p.s) && strings.IndexByte("+-.", p.s[p.pos]) >= 0 {
+				p.pos++
+			}
+			if start == p.pos {
+				return fmt.Errorf("unexpected %q at offset %d", c, p.pos), nil
+			}
+
+			p.skipSpace()
+			if p.pos < len(p.s) && (p.s[p.pos] == '[' || p.s[p.pos] == '(') {
+				p.pos = start
+				err, child := p.node()
+				if err != nil {
+					return err, nil
+				}
+				n.values = append(n.values, child)
+			} else {
+				n.values = append(n.values, wktNumber(strings.TrimSpace(p.s[start:p.pos])))
+			}
+		}
+	}
+}
+
+func (p *wktParser) quoted() (error, string) {
+	var ret strings.Builder
+	p.pos++ // opening quote
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		if c != '"' {
+			ret.WriteByte(c)
+			continue
+		}
+		// a doubled quote is an escaped quote
+		if p.pos < len(p.s) && p.s[p.pos] == '"' {
+			ret.WriteByte('"')
+			p.pos++
+			continue
+		}
+		return nil, ret.String()
+	}
+	return fmt.Errorf("unterminated string"), ""
+}
+
+func isWKTIdent(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
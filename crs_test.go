@@ -0,0 +1,84 @@
+package lassloot
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+const testCompoundWKT = `COMPD_CS["NAD83 / UTM zone 10N + NAVD88 height",
+	PROJCS["NAD83 / UTM zone 10N",
+		GEOGCS["NAD83",DATUM["North_American_Datum_1983",SPHEROID["GRS 1980",6378137,298.257222101]],
+			PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433],AUTHORITY["EPSG","4269"]],
+		PROJECTION["Transverse_Mercator"],PARAMETER["central_meridian",-123],
+		UNIT["US survey foot",0.304800609601219,AUTHORITY["EPSG","9003"]],AUTHORITY["EPSG","26910"]],
+	VERT_CS["NAVD88 height",VERT_DATUM["North American Vertical Datum 1988",2005],
+		UNIT["metre",1],AUTHORITY["EPSG","5703"]]]`
+
+func TestCRSFromWKT(t *testing.T) {
+	pc := newTestPointCloud(t, lastest.Spec{
+		Header:     lastest.Header{PointDataRecordFormat: 6, GlobalEncoding: (uint16)(las14.FlagWKT)},
+		PointCount: 1,
+		VLRs: []lastest.VLR{{
+			UserID:   las14.UserIDProjection,
+			RecordID: las14.RecordIDCoordinateWKT,
+			Data:     append([]byte(testCompoundWKT), 0),
+		}},
+	})
+
+	err, crs := pc.CRS()
+	if err != nil || crs == nil {
+		t.Fatalf("crs = %v, %v", crs, err)
+	}
+
+	if crs.EPSG != 26910 || crs.Name != "NAD83 / UTM zone 10N" || crs.Geographic {
+		t.Errorf("horizontal = %d %q geographic %v", crs.EPSG, crs.Name, crs.Geographic)
+	}
+	if crs.Units != "US survey foot" || math.Abs(crs.MetersPerUnit-0.304800609601219) > 1e-15 {
+		t.Errorf("units = %q %v", crs.Units, crs.MetersPerUnit)
+	}
+	if crs.VerticalEPSG != 5703 || crs.VerticalUnits != "metre" || crs.VerticalMetersPerUnit != 1 {
+		t.Errorf("vertical = %d %q %v", crs.VerticalEPSG, crs.VerticalUnits, crs.VerticalMetersPerUnit)
+	}
+}
+
+func TestCRSFromGeoKeys(t *testing.T) {
+	gkd := &las14.GeoKeyDirectory{
+		KeyDirectoryVersion: 1, KeyRevision: 1,
+		Keys: []las14.GeoKey{
+			{ID: las14.GTModelTypeGeoKey, Count: 1, ValueOffset: las14.ModelTypeProjected},
+			{ID: las14.ProjectedCSTypeGeoKey, Count: 1, ValueOffset: 32610},
+			{ID: las14.PCSCitationGeoKey, Location: las14.RecordIDGeoASCIIParams, Count: 20, ValueOffset: 0},
+			{ID: las14.ProjLinearUnitsGeoKey, Count: 1, ValueOffset: 9001},
+		},
+		ASCII: "WGS 84 / UTM zone 10N|",
+	}
+	gkd.Keys[2].Count = (uint16)(len(gkd.ASCII))
+	directory, _, ascii := gkd.Encode()
+
+	pc := newTestPointCloud(t, lastest.Spec{
+		Header:     lastest.Header{PointDataRecordFormat: 1},
+		PointCount: 1,
+		VLRs: []lastest.VLR{
+			{UserID: las14.UserIDProjection, RecordID: las14.RecordIDGeoKeyDirectory, Data: directory},
+			{UserID: las14.UserIDProjection, RecordID: las14.RecordIDGeoASCIIParams, Data: ascii},
+		},
+	})
+
+	err, crs := pc.CRS()
+	if err != nil || crs == nil {
+		t.Fatalf("crs = %v, %v", crs, err)
+	}
+	if got, want := crs.String(), "EPSG:32610 WGS 84 / UTM zone 10N (metre)"; got != want {
+		t.Errorf("crs = %q, want %q", got, want)
+	}
+}
+
+func TestCRSAbsent(t *testing.T) {
+	pc := newTestPointCloud(t, lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 0}, PointCount: 1})
+	if err, crs := pc.CRS(); err != nil || crs != nil {
+		t.Errorf("crs = %v, %v, want none", crs, err)
+	}
+}
@@ -0,0 +1,565 @@
+// Package dxf writes drawings as AutoCAD DXF files, the interchange format CAD programs from AutoCAD and Civil 3D to
+// Rhino, LibreCAD and QGIS read.
+//
+// A DXF file is plain text: a sequence of pairs of lines, each a group code saying what the next line holds.  Drawings
+// are written as AutoCAD 2000 (AC1015) files, the oldest version with lightweight polylines, and the one nearly every
+// reader supports.  Every object carries a handle and the handle of its owner, as AutoCAD requires.
+package dxf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Units are the $INSUNITS codes for the length of a drawing unit.
+type Units int
+
+const (
+	Unitless     Units = 0
+	Inches       Units = 1
+	Feet         Units = 2
+	Millimeters  Units = 4
+	Centimeters  Units = 5
+	Meters       Units = 6
+	Kilometers   Units = 7
+	USSurveyFeet Units = 21
+)
+
+// UnitsOf returns the units metersPerUnit meters long, or Unitless when none are.
+func UnitsOf(metersPerUnit float64) Units {
+	for _, u := range []struct {
+		units  Units
+		meters float64
+	}{
+		{Inches, 0.0254}, {Feet, 0.3048}, {USSurveyFeet, 1200.0 / 3937.0}, {Millimeters, 0.001}, {Centimeters, 0.01},
+		{Meters, 1}, {Kilometers, 1000},
+	} {
+		if math.Abs(metersPerUnit-u.meters) <= 1e-9*u.meters {
+			return u.units
+		}
+	}
+	return Unitless
+}
+
+// metric reports whether u is a metric unit, which sets the drawing's default linetypes and hatch patterns.
+func (u Units) metric() bool {
+	return u == Millimeters || u == Centimeters || u == Meters || u == Kilometers
+}
+
+// Colors are AutoCAD Color Index (ACI) numbers.
+const (
+	Red     = 1
+	Yellow  = 2
+	Green   = 3
+	Cyan    = 4
+	Blue    = 5
+	Magenta = 6
+	White   = 7
+	Gray    = 8
+)
+
+// Handles of the objects every drawing holds.  Handles of layers and entities follow them.
+const (
+	handleVportTable = 1 + iota
+	handleLtypeTable
+	handleLayerTable
+	handleStyleTable
+	handleViewTable
+	handleUCSTable
+	handleAppIDTable
+	handleDimStyleTable
+	handleBlockRecordTable
+	handleModelSpace
+	handlePaperSpace
+	handleModelSpaceBlock
+	handleModelSpaceEnd
+	handlePaperSpaceBlock
+	handlePaperSpaceEnd
+	handleByBlock
+	handleByLayer
+	handleContinuous
+	handleStandardStyle
+	handleACAD
+	handleStandardDimStyle
+	handleActiveVport
+	handleRootDictionary
+	handleGroupDictionary
+
+	firstHandle
+)
+
+// Layer names a layer entities are drawn on and how they are drawn.
+type Layer struct {
+	Name string
+
+	// Color is the color index entities on the layer take.  Zero takes White.
+	Color int
+
+	// Lineweight is the width entities on the layer are plotted, in hundredths of a millimeter.  Zero takes the
+	// default.
+	Lineweight int
+}
+
+// Drawing collects the layers and entities of a DXF file.  Entities are encoded as they are added, so a drawing
+// holds little more than the file it writes.
+type Drawing struct {
+	units  Units
+	layers []Layer
+	layer  map[string]int
+
+	handles  []int
+	entities bytes.Buffer
+	next     int
+
+	min, max [3]float64
+	empty    bool
+}
+
+// New returns an empty drawing in units.
+func New(units Units) *Drawing {
+	return &Drawing{
+		units: units,
+		layer: make(map[string]int),
+		next:  firstHandle,
+		min:   [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)},
+		max:   [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)},
+		empty: true,
+	}
+}
+
+// AddLayer adds l to the drawing, or changes how an existing layer of its name is drawn.  Entities may be drawn on
+// layers that were never added, which are drawn white.
+func (d *Drawing) AddLayer(l Layer) error {
+	if err := checkLayerName(l.Name); err != nil {
+		return err
+	}
+	if l.Color < 0 || l.Color > 255 {
+		return fmt.Errorf("invalid color %d for layer %s", l.Color, l.Name)
+	}
+	if l.Lineweight < 0 || l.Lineweight > 211 {
+		return fmt.Errorf("invalid lineweight %d for layer %s", l.Lineweight, l.Name)
+	}
+	if i, ok := d.layer[strings.ToUpper(l.Name)]; ok {
+		d.layers[i] = l
+		return nil
+	}
+	d.layer[strings.ToUpper(l.Name)] = len(d.layers)
+	d.layers = append(d.layers, l)
+	d.handles = append(d.handles, d.handle())
+	return nil
+}
+
+func checkLayerName(name string) error {
+	if name == "" || strings.ContainsAny(name, "<>/\\\":;?*|=`\r\n") {
+		return fmt.Errorf("invalid layer name %q", name)
+	}
+	return nil
+}
+
+func (d *Drawing) handle() int {
+	d.next++
+	return d.next - 1
+}
+
+// entity begins an entity of kind on layer, owned by the object with handle owner, and returns its handle.
+func (d *Drawing) entity(kind string, layer string, owner int) (error, int) {
+	if _, ok := d.layer[strings.ToUpper(layer)]; !ok {
+		if err := d.AddLayer(Layer{Name: layer}); err != nil {
+			return err, 0
+		}
+	}
+	h := d.handle()
+	w := &d.entities
+	writeString(w, 0, kind)
+	writeHandle(w, 5, h)
+	writeHandle(w, 330, owner)
+	writeString(w, 100, "AcDbEntity")
+	writeString(w, 8, layer)
+	return nil, h
+}
+
+func (d *Drawing) extend(p [3]float64) {
+	for k := range p {
+		d.min[k], d.max[k] = math.Min(d.min[k], p[k]), math.Max(d.max[k], p[k])
+	}
+	d.empty = false
+}
+
+func checkPoints(points ...[3]float64) error {
+	for _, p := range points {
+		for _, v := range p {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("invalid point %v", p)
+			}
+		}
+	}
+	return nil
+}
+
+// LWPolyline draws a lightweight polyline through points at elevation, the way contours are usually drawn.  Closed
+// polylines return from their last point to their first.
+func (d *Drawing) LWPolyline(layer string, points [][2]float64, elevation float64, closed bool) error {
+	if len(points) < 2 {
+		return fmt.Errorf("polyline of %d points", len(points))
+	}
+	for _, p := range points {
+		if err := checkPoints([3]float64{p[0], p[1], elevation}); err != nil {
+			return err
+		}
+	}
+	err, _ := d.entity("LWPOLYLINE", layer, handleModelSpace)
+	if err != nil {
+		return err
+	}
+	w := &d.entities
+	writeString(w, 100, "AcDbPolyline")
+	writeInt(w, 90, len(points))
+	writeInt(w, 70, flag(closed, 1))
+	writeFloat(w, 38, elevation)
+	for _, p := range points {
+		writeFloat(w, 10, p[0])
+		writeFloat(w, 20, p[1])
+		d.extend([3]float64{p[0], p[1], elevation})
+	}
+	return nil
+}
+
+// Polyline draws a 3D polyline through points.  Closed polylines return from their last point to their first.
+func (d *Drawing) Polyline(layer string, points [][3]float64, closed bool) error {
+	if len(points) < 2 {
+		return fmt.Errorf("polyline of %d points", len(points))
+	}
+	if err := checkPoints(points...); err != nil {
+		return err
+	}
+	err, h := d.entity("POLYLINE", layer, handleModelSpace)
+	if err != nil {
+		return err
+	}
+	w := &d.entities
+	writeString(w, 100, "AcDb3dPolyline")
+	writeInt(w, 66, 1)
+	writePoint(w, 10, [3]float64{})
+	writeInt(w, 70, 8|flag(closed, 1))
+	for _, p := range points {
+		if err, _ := d.entity("VERTEX", layer, h); err != nil {
+			return err
+		}
+		writeString(w, 100, "AcDbVertex")
+		writeString(w, 100, "AcDb3dPolylineVertex")
+		writePoint(w, 10, p)
+		writeInt(w, 70, 32)
+		d.extend(p)
+	}
+	err, _ = d.entity("SEQEND", layer, h)
+	return err
+}
+
+// Point draws a point at p.
+func (d *Drawing) Point(layer string, p [3]float64) error {
+	if err := checkPoints(p); err != nil {
+		return err
+	}
+	err, _ := d.entity("POINT", layer, handleModelSpace)
+	if err != nil {
+		return err
+	}
+	writeString(&d.entities, 100, "AcDbPoint")
+	writePoint(&d.entities, 10, p)
+	d.extend(p)
+	return nil
+}
+
+// Text draws a line of text height units tall centered on p, turned rotation degrees counterclockwise from east.
+func (d *Drawing) Text(layer string, p [3]float64, height float64, rotation float64, text string) error {
+	if err := checkPoints(p); err != nil {
+		return err
+	}
+	if !(height > 0) || math.IsInf(height, 0) {
+		return fmt.Errorf("invalid text height %v", height)
+	}
+	err, _ := d.entity("TEXT", layer, handleModelSpace)
+	if err != nil {
+		return err
+	}
+	// text can't break across lines, and the reader would take a newline as the end of the value
+	text = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(text)
+
+	w := &d.entities
+	writeString(w, 100, "AcDbText")
+	writePoint(w, 10, p)
+	writeFloat(w, 40, height)
+	writeString(w, 1, text)
+	writeFloat(w, 50, rotation)
+	writeString(w, 7, "Standard")
+	writeInt(w, 72, 1)
+	writePoint(w, 11, p)
+	writeString(w, 100, "AcDbText")
+	writeInt(w, 73, 2)
+	d.extend(p)
+	return nil
+}
+
+// Face draws the triangle a, b, c as a 3D face.
+func (d *Drawing) Face(layer string, a, b, c [3]float64) error {
+	if err := checkPoints(a, b, c); err != nil {
+		return err
+	}
+	err, _ := d.entity("3DFACE", layer, handleModelSpace)
+	if err != nil {
+		return err
+	}
+	w := &d.entities
+	writeString(w, 100, "AcDbFace")
+	// a triangle repeats its last corner as the fourth
+	for i, p := range [4][3]float64{a, b, c, c} {
+		writePoint(w, 10+i, p)
+		d.extend(p)
+	}
+	return nil
+}
+
+// Write writes the drawing to w as a DXF file.
+func (d *Drawing) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	min, max := d.min, d.max
+	if d.empty {
+		min, max = [3]float64{}, [3]float64{}
+	}
+	seed := d.next
+	layers, handles := d.layers, d.handles
+	if _, ok := d.layer["0"]; !ok {
+		// every drawing has a layer 0
+		layers, handles = append([]Layer{{Name: "0"}}, layers...), append([]int{seed}, handles...)
+		seed++
+	}
+
+	writeString(bw, 0, "SECTION")
+	writeString(bw, 2, "HEADER")
+	writeString(bw, 9, "$ACADVER")
+	writeString(bw, 1, "AC1015")
+	writeString(bw, 9, "$HANDSEED")
+	writeHandle(bw, 5, seed)
+	writeString(bw, 9, "$INSUNITS")
+	writeInt(bw, 70, (int)(d.units))
+	writeString(bw, 9, "$MEASUREMENT")
+	writeInt(bw, 70, flag(d.units.metric(), 1))
+	writeString(bw, 9, "$EXTMIN")
+	writePoint(bw, 10, min)
+	writeString(bw, 9, "$EXTMAX")
+	writePoint(bw, 10, max)
+	writeString(bw, 0, "ENDSEC")
+
+	writeString(bw, 0, "SECTION")
+	writeString(bw, 2, "CLASSES")
+	writeString(bw, 0, "ENDSEC")
+
+	writeString(bw, 0, "SECTION")
+	writeString(bw, 2, "TABLES")
+
+	table(bw, "VPORT", handleVportTable, 1)
+	record(bw, "VPORT", handleActiveVport, handleVportTable, "AcDbViewportTableRecord", "*Active")
+	writePoint2(bw, 10, 0, 0)
+	writePoint2(bw, 11, 1, 1)
+	// open on the whole drawing
+	writePoint2(bw, 12, (min[0]+max[0])/2, (min[1]+max[1])/2)
+	writeFloat(bw, 40, math.Max(max[1]-min[1], 1)*1.05)
+	writeFloat(bw, 41, math.Max(1, (max[0]-min[0])/math.Max(max[1]-min[1], 1e-9)))
+	writeString(bw, 0, "ENDTAB")
+
+	table(bw, "LTYPE", handleLtypeTable, 3)
+	for _, lt := range []struct {
+		name   string
+		handle int
+		about  string
+	}{{"ByBlock", handleByBlock, ""}, {"ByLayer", handleByLayer, ""}, {"Continuous", handleContinuous, "Solid line"}} {
+		record(bw, "LTYPE", lt.handle, handleLtypeTable, "AcDbLinetypeTableRecord", lt.name)
+		writeString(bw, 3, lt.about)
+		writeInt(bw, 72, 65)
+		writeInt(bw, 73, 0)
+		writeFloat(bw, 40, 0)
+	}
+	writeString(bw, 0, "ENDTAB")
+
+	table(bw, "LAYER", handleLayerTable, len(layers))
+	for i, l := range layers {
+		record(bw, "LAYER", handles[i], handleLayerTable, "AcDbLayerTableRecord", l.Name)
+		color := l.Color
+		if color == 0 {
+			color = White
+		}
+		writeInt(bw, 62, color)
+		writeString(bw, 6, "Continuous")
+		lineweight := l.Lineweight
+		if lineweight == 0 {
+			lineweight = -3
+		}
+		writeInt(bw, 370, lineweight)
+	}
+	writeString(bw, 0, "ENDTAB")
+
+	table(bw, "STYLE", handleStyleTable, 1)
+	record(bw, "STYLE", handleStandardStyle, handleStyleTable, "AcDbTextStyleTableRecord", "Standard")
+	writeFloat(bw, 40, 0)
+	writeFloat(bw, 41, 1)
+	writeFloat(bw, 50, 0)
+	writeInt(bw, 71, 0)
+	writeFloat(bw, 42, 2.5)
+	writeString(bw, 3, "txt")
+	writeString(bw, 4, "")
+	writeString(bw, 0, "ENDTAB")
+
+	table(bw, "VIEW", handleViewTable, 0)
+	writeString(bw, 0, "ENDTAB")
+	table(bw, "UCS", handleUCSTable, 0)
+	writeString(bw, 0, "ENDTAB")
+
+	table(bw, "APPID", handleAppIDTable, 1)
+	record(bw, "APPID", handleACAD, handleAppIDTable, "AcDbRegAppTableRecord", "ACAD")
+	writeString(bw, 0, "ENDTAB")
+
+	// dimension styles carry their handles in group 105, not 5
+	writeString(bw, 0, "TABLE")
+	writeString(bw, 2, "DIMSTYLE")
+	writeHandle(bw, 5, handleDimStyleTable)
+	writeHandle(bw, 330, 0)
+	writeString(bw, 100, "AcDbSymbolTable")
+	writeInt(bw, 70, 1)
+	writeString(bw, 100, "AcDbDimStyleTable")
+	writeInt(bw, 71, 0)
+	writeString(bw, 0, "DIMSTYLE")
+	writeHandle(bw, 105, handleStandardDimStyle)
+	writeHandle(bw, 330, handleDimStyleTable)
+	writeString(bw, 100, "AcDbSymbolTableRecord")
+	writeString(bw, 100, "AcDbDimStyleTableRecord")
+	writeString(bw, 2, "Standard")
+	writeInt(bw, 70, 0)
+	writeString(bw, 0, "ENDTAB")
+
+	table(bw, "BLOCK_RECORD", handleBlockRecordTable, 2)
+	record(bw, "BLOCK_RECORD", handleModelSpace, handleBlockRecordTable, "AcDbBlockTableRecord", "*Model_Space")
+	record(bw, "BLOCK_RECORD", handlePaperSpace, handleBlockRecordTable, "AcDbBlockTableRecord", "*Paper_Space")
+	writeString(bw, 0, "ENDTAB")
+	writeString(bw, 0, "ENDSEC")
+
+	writeString(bw, 0, "SECTION")
+	writeString(bw, 2, "BLOCKS")
+	for _, b := range []struct {
+		name              string
+		owner, begin, end int
+		paper             bool
+	}{
+		{"*Model_Space", handleModelSpace, handleModelSpaceBlock, handleModelSpaceEnd, false},
+		{"*Paper_Space", handlePaperSpace, handlePaperSpaceBlock, handlePaperSpaceEnd, true},
+	} {
+		writeString(bw, 0, "BLOCK")
+		writeHandle(bw, 5, b.begin)
+		writeHandle(bw, 330, b.owner)
+		writeString(bw, 100, "AcDbEntity")
+		if b.paper {
+			writeInt(bw, 67, 1)
+		}
+		writeString(bw, 8, "0")
+		writeString(bw, 100, "AcDbBlockBegin")
+		writeString(bw, 2, b.name)
+		writeInt(bw, 70, 0)
+		writePoint(bw, 10, [3]float64{})
+		writeString(bw, 3, b.name)
+		writeString(bw, 1, "")
+		writeString(bw, 0, "ENDBLK")
+		writeHandle(bw, 5, b.end)
+		writeHandle(bw, 330, b.owner)
+		writeString(bw, 100, "AcDbEntity")
+		if b.paper {
+			writeInt(bw, 67, 1)
+		}
+		writeString(bw, 8, "0")
+		writeString(bw, 100, "AcDbBlockEnd")
+	}
+	writeString(bw, 0, "ENDSEC")
+
+	writeString(bw, 0, "SECTION")
+	writeString(bw, 2, "ENTITIES")
+	bw.Write(d.entities.Bytes())
+	writeString(bw, 0, "ENDSEC")
+
+	writeString(bw, 0, "SECTION")
+	writeString(bw, 2, "OBJECTS")
+	writeString(bw, 0, "DICTIONARY")
+	writeHandle(bw, 5, handleRootDictionary)
+	writeHandle(bw, 330, 0)
+	writeString(bw, 100, "AcDbDictionary")
+	writeInt(bw, 281, 1)
+	writeString(bw, 3, "ACAD_GROUP")
+	writeHandle(bw, 350, handleGroupDictionary)
+	writeString(bw, 0, "DICTIONARY")
+	writeHandle(bw, 5, handleGroupDictionary)
+	writeHandle(bw, 330, handleRootDictionary)
+	writeString(bw, 100, "AcDbDictionary")
+	writeInt(bw, 281, 1)
+	writeString(bw, 0, "ENDSEC")
+
+	writeString(bw, 0, "EOF")
+	return bw.Flush()
+}
+
+// table begins the symbol table name, holding n records.
+func table(w io.Writer, name string, handle int, n int) {
+	writeString(w, 0, "TABLE")
+	writeString(w, 2, name)
+	writeHandle(w, 5, handle)
+	writeHandle(w, 330, 0)
+	writeString(w, 100, "AcDbSymbolTable")
+	writeInt(w, 70, n)
+}
+
+// record begins a record of a symbol table.
+func record(w io.Writer, kind string, handle, owner int, subclass string, name string) {
+	writeString(w, 0, kind)
+	writeHandle(w, 5, handle)
+	writeHandle(w, 330, owner)
+	writeString(w, 100, "AcDbSymbolTableRecord")
+	writeString(w, 100, subclass)
+	writeString(w, 2, name)
+	writeInt(w, 70, 0)
+}
+
+func flag(set bool, v int) int {
+	if set {
+		return v
+	}
+	return 0
+}
+
+func writeString(w io.Writer, code int, s string) {
+	fmt.Fprintf(w, "%3d\n%s\n", code, s)
+}
+
+func writeInt(w io.Writer, code int, v int) {
+	writeString(w, code, strconv.Itoa(v))
+}
+
+func writeHandle(w io.Writer, code int, h int) {
+	writeString(w, code, strconv.FormatInt((int64)(h), 16))
+}
+
+func writeFloat(w io.Writer, code int, v float64) {
+	writeString(w, code, strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+// writePoint writes p as the groups code, code+10 and code+20, as DXF spreads coordinates.
+func writePoint(w io.Writer, code int, p [3]float64) {
+	writeFloat(w, code, p[0])
+	writeFloat(w, code+10, p[1])
+	writeFloat(w, code+20, p[2])
+}
+
+func writePoint2(w io.Writer, code int, x, y float64) {
+	writeFloat(w, code, x)
+	writeFloat(w, code+10, y)
+}
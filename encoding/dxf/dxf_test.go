@@ -0,0 +1,131 @@
+package dxf
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type pair struct {
+	code  int
+	value string
+}
+
+// parse splits a DXF file into its group code and value pairs.
+func parse(t *testing.T, s string) []pair {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if len(lines)%2 != 0 {
+		t.Fatalf("%d lines", len(lines))
+	}
+	var ret []pair
+	for i := 0; i < len(lines); i += 2 {
+		code, err := strconv.Atoi(strings.TrimSpace(lines[i]))
+		if err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		ret = append(ret, pair{code, lines[i+1]})
+	}
+	return ret
+}
+
+func TestWrite(t *testing.T) {
+	d := New(UnitsOf(0.3048))
+	if err := d.AddLayer(Layer{Name: "C-TOPO-MAJR", Color: Red, Lineweight: 50}); err != nil {
+		t.Fatal(err)
+	}
+	checks := []error{
+		d.LWPolyline("C-TOPO-MAJR", [][2]float64{{0, 0}, {10, 0}, {10, 10}}, 100, true),
+		d.Polyline("C-TOPO-MINR", [][3]float64{{0, 0, 99}, {5, 5, 99}}, false),
+		d.Point("C-TOPO-SPOT", [3]float64{2, 3, 101.5}),
+		d.Text("C-TOPO-TEXT", [3]float64{2, 3, 101.5}, 1.5, 30, "101.5\nft"),
+		d.Face("C-TOPO-TIN", [3]float64{0, 0, 99}, [3]float64{10, 0, 100}, [3]float64{0, 10, 102}),
+	}
+	for i, err := range checks {
+		if err != nil {
+			t.Fatalf("entity %d: %v", i, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := d.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	pairs := parse(t, buf.String())
+	if last := pairs[len(pairs)-1]; last != (pair{0, "EOF"}) {
+		t.Errorf("ends with %v", last)
+	}
+
+	var sections, entities, layers []string
+	handles := map[string]bool{"0": true}
+	var owners []string
+	var seed string
+	section := ""
+	for i, p := range pairs {
+		switch {
+		case p.code == 0 && p.value == "SECTION":
+			section = pairs[i+1].value
+			sections = append(sections, section)
+		case p.code == 0 && section == "ENTITIES" && p.value != "ENDSEC":
+			entities = append(entities, p.value)
+		case p.code == 0 && p.value == "LAYER":
+			layers = append(layers, pairs[i+5].value+"="+pairs[i+7].value)
+		case p.code == 9 && p.value == "$HANDSEED":
+			seed = pairs[i+1].value
+		case (p.code == 5 || p.code == 105) && section != "HEADER":
+			if handles[p.value] {
+				t.Errorf("handle %s used twice", p.value)
+			}
+			handles[p.value] = true
+		case p.code == 330 || p.code == 350:
+			owners = append(owners, p.value)
+		}
+	}
+	if got := strings.Join(sections, " "); got != "HEADER CLASSES TABLES BLOCKS ENTITIES OBJECTS" {
+		t.Errorf("sections %s", got)
+	}
+	if got := strings.Join(entities, " "); got != "LWPOLYLINE POLYLINE VERTEX VERTEX SEQEND POINT TEXT 3DFACE" {
+		t.Errorf("entities %s", got)
+	}
+	if got := strings.Join(layers, " "); got != "0=7 C-TOPO-MAJR=1 C-TOPO-MINR=7 C-TOPO-SPOT=7 C-TOPO-TEXT=7 C-TOPO-TIN=7" {
+		t.Errorf("layers %s", got)
+	}
+	for _, o := range owners {
+		if !handles[o] {
+			t.Errorf("owner %s has no object", o)
+		}
+	}
+	n, _ := strconv.ParseInt(seed, 16, 64)
+	for h := range handles {
+		if v, _ := strconv.ParseInt(h, 16, 64); v >= n {
+			t.Errorf("handle %s is beyond the seed %s", h, seed)
+		}
+	}
+
+	s := buf.String()
+	for _, want := range []string{
+		"$INSUNITS\n 70\n2\n", "$EXTMIN\n 10\n0\n 20\n0\n 30\n99\n", "$EXTMAX\n 10\n10\n 20\n10\n 30\n102\n",
+		"AcDbPolyline\n 90\n3\n 70\n1\n 38\n100\n", " 70\n8\n", "  1\n101.5 ft\n 50\n30\n",
+		" 12\n0\n 22\n10\n 32\n102\n 13\n0\n 23\n10\n 33\n102\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("file lacks %q", want)
+		}
+	}
+
+	if err := d.LWPolyline("bad:name", [][2]float64{{0, 0}, {1, 1}}, 0, false); err == nil {
+		t.Error("drew on a layer with a colon in its name")
+	}
+	if err := d.Polyline("0", [][3]float64{{0, 0, 0}}, false); err == nil {
+		t.Error("drew a polyline of one point")
+	}
+}
+
+func TestUnitsOf(t *testing.T) {
+	for meters, want := range map[float64]Units{1: Meters, 0.3048: Feet, 1200.0 / 3937.0: USSurveyFeet, 0.5: Unitless, 0: Unitless} {
+		if got := UnitsOf(meters); got != want {
+			t.Errorf("%v meters is %v, want %v", meters, got, want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package e57
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// Options control how a File becomes a pointcloud.
+type Options struct {
+	// Scans, if set, lists the indexes of the scans to read.  By default every scan is.
+	Scans []int
+
+	// Format, if set, is the point data record format to write.  By default the simplest format holding the
+	// fields read is chosen, as lassloot.PointFormatFor describes.
+	Format *las14.PointDataFormat
+
+	// Precision, if set, gives the scale factors and offsets to store coordinates with.  By default they keep the
+	// precision of scaled integer coordinates, or else millimeters.
+	Precision *lassloot.Precision
+}
+
+// defaultDigits is the decimal places kept of coordinates recorded as floats: millimeters, about the precision of a
+// terrestrial scanner.
+const defaultDigits = 3
+
+// point holds what is read of each point before the pointcloud is built.
+type point struct {
+	x, y, z          float64
+	intensity        float64
+	red, green, blue float64
+	time             float64
+	returnNumber     float64
+	numberOfReturns  float64
+	scan             int
+}
+
+// PointCloud reads the points of the file's scans, places them with each scan's pose and returns them as a single
+// pointcloud.  Points without a valid position are skipped.
+//
+// Intensities and colors are stretched from the limits the scan records, or else the range of their fields, to the
+// full 16-bit range of LAS.  Time stamps are made Adjusted Standard GPS Time when the scan records the GPS time it
+// began, and are otherwise kept as they are.  Each point's point source id is the index of its scan.
+func (f *File) PointCloud(opts Options) (error, *lassloot.PointCloud) {
+	indexes := opts.Scans
+	if indexes == nil {
+		for i := range f.Scans {
+			indexes = append(indexes, i)
+		}
+	}
+
+	var points []point
+	var hasTime, hasColor, hasReturns, absoluteTime, extended bool
+	digits := -1
+	for _, i := range indexes {
+		if i < 0 || i >= len(f.Scans) {
+			return fmt.Errorf("no scan %d: the file has %d", i, len(f.Scans)), nil
+		}
+		s := &f.Scans[i]
+		if i > math.MaxUint16 {
+			return fmt.Errorf("scan %d: too many scans for point source ids", i), nil
+		}
+
+		err, read := f.readScan(s, i)
+		if err != nil {
+			return fmt.Errorf("scan %d: %w", i, err), nil
+		}
+		points = append(points, read...)
+
+		has := func(name string) bool { return s.field(name) != nil }
+		hasTime = hasTime || has("timeStamp")
+		absoluteTime = absoluteTime || (has("timeStamp") && s.start != nil)
+		hasColor = hasColor || has("colorRed")
+		hasReturns = hasReturns || has("returnIndex")
+		for _, name := range [...]string{"cartesianX", "cartesianY", "cartesianZ", "sphericalRange"} {
+			if fd := s.field(name); fd != nil && fd.digits() > digits {
+				digits = fd.digits()
+			}
+		}
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("no points read"), nil
+	}
+	if digits < 0 {
+		digits = defaultDigits
+	}
+	for _, p := range points {
+		if p.returnNumber > 7 || p.numberOfReturns > 7 {
+			extended = true
+			break
+		}
+	}
+
+	format := lassloot.PointFormatFor(hasTime, hasColor, false, extended)
+	if opts.Format != nil {
+		format = *opts.Format
+	}
+
+	var p lassloot.Precision
+	if opts.Precision != nil {
+		p = *opts.Precision
+	} else {
+		min := [3]float64{points[0].x, points[0].y, points[0].z}
+		max := min
+		for _, pt := range points {
+			for a, v := range [...]float64{pt.x, pt.y, pt.z} {
+				min[a] = math.Min(min[a], v)
+				max[a] = math.Max(max[a], v)
+			}
+		}
+		p = lassloot.AutoPrecision(min, max, [3]int{digits, digits, digits})
+	}
+
+	err, b := lassloot.NewBuilder(format, p)
+	if err != nil {
+		return err, nil
+	}
+	if absoluteTime {
+		b.Header().GlobalEncoding |= las14.FlagGPSTime
+	}
+	if looksLikeWKT(f.CoordinateMetadata) {
+		wkt := append([]byte(f.CoordinateMetadata), 0)
+		err := b.AddVLR(las14.NewVariableLengthRecord(las14.UserIDProjection, las14.RecordIDCoordinateWKT, "OGC WKT", wkt))
+		if err != nil {
+			return err, nil
+		}
+		b.Header().GlobalEncoding |= las14.FlagWKT
+	}
+
+	schema := b.Schema()
+	dim := func(name string, wanted bool) *las14.Dimension {
+		if !wanted {
+			return nil
+		}
+		d, _ := schema.Dimension(name)
+		return d
+	}
+	intensity := dim(las14.DimIntensity, true)
+	red, green, blue := dim(las14.DimRed, hasColor), dim(las14.DimGreen, hasColor), dim(las14.DimBlue, hasColor)
+	gpsTime := dim(las14.DimGPSTime, hasTime)
+	returnNumber := dim(las14.DimReturnNumber, hasReturns)
+	numberOfReturns := dim(las14.DimNumberOfReturns, hasReturns)
+	source := dim(las14.DimPointSourceID, true)
+
+	for i, pt := range points {
+		err := b.Add(pt.x, pt.y, pt.z)
+		if err != nil {
+			return fmt.Errorf("point %d: %w", i, err), nil
+		}
+		for _, v := range [...]struct {
+			d *las14.Dimension
+			v float64
+		}{
+			{intensity, pt.intensity},
+			{red, pt.red},
+			{green, pt.green},
+			{blue, pt.blue},
+			{gpsTime, pt.time},
+			{returnNumber, pt.returnNumber},
+			{numberOfReturns, pt.numberOfReturns},
+			{source, (float64)(pt.scan)},
+		} {
+			if v.d == nil {
+				continue
+			}
+			err := b.SetDimension(v.d, v.v)
+			if err != nil {
+				return fmt.Errorf("point %d: %s: %w", i, v.d.Name, err), nil
+			}
+		}
+	}
+
+	return b.Build()
+}
+
+// field returns the prototype field named name, or nil.
+func (s *Scan) field(name string) *field {
+	for i := range s.prototype {
+		if s.prototype[i].name == name {
+			return &s.prototype[i]
+		}
+	}
+	return nil
+}
+
+// readScan decodes the points of scan s, the index'th of the file, placed by its pose.
+func (f *File) readScan(s *Scan, index int) (error, []point) {
+	err, columns := f.pr.readVector(s.offset, s.Points, s.prototype)
+	if err != nil {
+		return err, nil
+	}
+	column := func(name string) []float64 {
+		for i := range s.prototype {
+			if s.prototype[i].name == name {
+				return columns[i]
+			}
+		}
+		return nil
+	}
+
+	cx, cy, cz := column("cartesianX"), column("cartesianY"), column("cartesianZ")
+	sr, sa, se := column("sphericalRange"), column("sphericalAzimuth"), column("sphericalElevation")
+	cartesian := cx != nil && cy != nil && cz != nil
+	if !cartesian && (sr == nil || sa == nil || se == nil) {
+		return fmt.Errorf("points have neither cartesian nor spherical coordinates"), nil
+	}
+	invalid := column("sphericalInvalidState")
+	if cartesian {
+		invalid = column("cartesianInvalidState")
+	}
+
+	// normalize intensity and color from the scan's limits, or else the range of their fields, to 16 bits
+	stretch := func(name string, limits *[2]float64) func(v float64) float64 {
+		fd := s.field(name)
+		if fd == nil {
+			return nil
+		}
+		lo, hi := fd.limits()
+		if limits != nil {
+			lo, hi = limits[0], limits[1]
+		}
+		return func(v float64) float64 {
+			if hi <= lo {
+				return math.Max(0, math.Min(v, math.MaxUint16))
+			}
+			return math.Round(math.Max(0, math.Min(1, (v-lo)/(hi-lo))) * math.MaxUint16)
+		}
+	}
+	intensity := column("intensity")
+	intensityScale := stretch("intensity", s.intensityLimits)
+	var colors [3][]float64
+	var colorScales [3]func(float64) float64
+	for c, name := range [...]string{"colorRed", "colorGreen", "colorBlue"} {
+		colors[c] = column(name)
+		var limits *[2]float64
+		if s.colorLimits != nil {
+			limits = &s.colorLimits[c]
+		}
+		colorScales[c] = stretch(name, limits)
+	}
+	intensityInvalid, colorInvalid := column("isIntensityInvalid"), column("isColorInvalid")
+	times, timeInvalid := column("timeStamp"), column("isTimeStampInvalid")
+	returnIndex, returnCount := column("returnIndex"), column("returnCount")
+
+	ret := make([]point, 0, s.Points)
+	for i := (int64)(0); i < s.Points; i++ {
+		if invalid != nil && invalid[i] != 0 {
+			continue
+		}
+
+		var pt point
+		pt.scan = index
+		if cartesian {
+			pt.x, pt.y, pt.z = cx[i], cy[i], cz[i]
+		} else {
+			r, az, el := sr[i], sa[i], se[i]
+			pt.x = r * math.Cos(el) * math.Cos(az)
+			pt.y = r * math.Cos(el) * math.Sin(az)
+			pt.z = r * math.Sin(el)
+		}
+		if math.IsNaN(pt.x) || math.IsNaN(pt.y) || math.IsNaN(pt.z) {
+			continue
+		}
+		pt.x, pt.y, pt.z = s.Pose.Apply(pt.x, pt.y, pt.z)
+
+		if intensity != nil && (intensityInvalid == nil || intensityInvalid[i] == 0) {
+			pt.intensity = intensityScale(intensity[i])
+		}
+		if colors[0] != nil && colors[1] != nil && colors[2] != nil && (colorInvalid == nil || colorInvalid[i] == 0) {
+			pt.red = colorScales[0](colors[0][i])
+			pt.green = colorScales[1](colors[1][i])
+			pt.blue = colorScales[2](colors[2][i])
+		}
+		if times != nil && (timeInvalid == nil || timeInvalid[i] == 0) {
+			pt.time = times[i]
+			if s.start != nil {
+				pt.time += *s.start - lassloot.AdjustedStandardGPSTimeOffset
+			}
+		}
+		if returnIndex != nil {
+			pt.returnNumber = returnIndex[i] + 1
+		}
+		if returnCount != nil {
+			pt.numberOfReturns = returnCount[i]
+		}
+
+		ret = append(ret, pt)
+	}
+	return nil, ret
+}
+
+// looksLikeWKT reports whether s is a coordinate system in OGC WKT, such as PROJCS["..."], rather than another
+// description E57 allows.
+func looksLikeWKT(s string) bool {
+	i := strings.IndexByte(s, '[')
+	if i <= 0 || !strings.HasSuffix(s, "]") {
+		return false
+	}
+	for _, r := range s[:i] {
+		if (r < 'A' || r > 'Z') && r != '_' && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Read reads every scan of the E57 file held by r, which is size bytes long, as a single pointcloud.
+func Read(r io.ReaderAt, size int64) (error, *lassloot.PointCloud) {
+	err, f := Decode(r, size)
+	if err != nil {
+		return err, nil
+	}
+	return f.PointCloud(Options{})
+}
+
+// ReadFile reads every scan of the E57 file at path as a single pointcloud.
+func ReadFile(path string) (error, *lassloot.PointCloud) {
+	file, err := os.Open(path)
+	if err != nil {
+		return err, nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err, nil
+	}
+	return Read(file, info.Size())
+}
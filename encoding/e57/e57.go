@@ -0,0 +1,288 @@
+// Package e57 reads ASTM E57 files, the exchange format of terrestrial laser scanners, as pointclouds.
+//
+// An E57 file describes its scans in an XML section and stores their points in binary CompressedVector sections,
+// each field of the points as its own bitpacked bytestream.  Points may be given by cartesian or spherical
+// coordinates, and each scan has a pose placing it within the file's coordinate system; PointCloud applies the poses
+// and merges the scans into a single pointcloud.
+package e57
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Signature begins every E57 file.
+const Signature = "ASTM-E57"
+
+// fileHeaderSize is the size of the header at the start of the file, which precedes the first page's checksum.
+const fileHeaderSize = 48
+
+// checksumSize is the size of the CRC-32C checksum ending every page.
+const checksumSize = 4
+
+// fileHeader is the header at the start of every E57 file.  Offsets are physical, counting the checksum ending each
+// page, while lengths are logical and do not.
+type fileHeader struct {
+	MajorVersion   uint32
+	MinorVersion   uint32
+	PhysicalLength uint64
+	XMLOffset      uint64
+	XMLLength      uint64
+	PageSize       uint64
+}
+
+// pagedReader reads the logical contents of a file, skipping the checksums.  Checksums are not verified.
+type pagedReader struct {
+	r        io.ReaderAt
+	size     int64
+	pageSize int64
+}
+
+// read returns n logical bytes beginning at the physical offset.
+func (pr *pagedReader) read(offset int64, n int64) (error, []byte) {
+	payload := pr.pageSize - checksumSize
+	if offset < 0 || offset%pr.pageSize >= payload || n < 0 || n > pr.size {
+		return fmt.Errorf("invalid section of %d bytes at offset %d", n, offset), nil
+	}
+
+	ret := make([]byte, n)
+	for done := (int64)(0); done < n; {
+		take := payload - offset%pr.pageSize
+		if take > n-done {
+			take = n - done
+		}
+
+		read, err := pr.r.ReadAt(ret[done:done+take], offset)
+		if (int64)(read) < take {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("failed to read %d bytes at offset %d: %w", take, offset, err), nil
+		}
+
+		done += take
+		offset += take
+		if offset%pr.pageSize == payload {
+			offset += checksumSize
+		}
+	}
+	return nil, ret
+}
+
+// logical converts a physical offset into the offset of the same byte among the file's logical bytes.
+func (pr *pagedReader) logical(offset int64) int64 {
+	return offset/pr.pageSize*(pr.pageSize-checksumSize) + offset%pr.pageSize
+}
+
+// File is a decoded E57 file, whose scans become a pointcloud with PointCloud.
+type File struct {
+	GUID string
+
+	// CoordinateMetadata describes the coordinate reference system the scans are placed in, often as OGC WKT.
+	CoordinateMetadata string
+
+	Scans []Scan
+
+	pr *pagedReader
+}
+
+// Scan is one of the point sets of a file, usually captured from a single setup of the scanner.
+type Scan struct {
+	GUID        string
+	Name        string
+	Description string
+
+	// Points is the number of points recorded, including those without a valid position.
+	Points int64
+
+	// Pose places the scan's points within the file's coordinate system.
+	Pose Pose
+
+	// Fields names the values recorded for every point, such as cartesianX or intensity.
+	Fields []string
+
+	intensityLimits *[2]float64
+	colorLimits     *[3][2]float64
+
+	// start is the GPS time of the first point, which time stamps are relative to
+	start *float64
+
+	offset    int64
+	prototype []field
+}
+
+// Pose is a rigid transformation: a rotation followed by a translation.
+type Pose struct {
+	// Rotation is a unit quaternion, w, x, y, z.
+	Rotation    [4]float64
+	Translation [3]float64
+}
+
+// Identity is the pose that leaves points where they are.
+var Identity = Pose{Rotation: [4]float64{1, 0, 0, 0}}
+
+// Apply returns x, y, z rotated and then translated by the pose.
+func (p Pose) Apply(x, y, z float64) (float64, float64, float64) {
+	w, a, b, c := p.Rotation[0], p.Rotation[1], p.Rotation[2], p.Rotation[3]
+	rx := (1-2*(b*b+c*c))*x + 2*(a*b-c*w)*y + 2*(a*c+b*w)*z
+	ry := 2*(a*b+c*w)*x + (1-2*(a*a+c*c))*y + 2*(b*c-a*w)*z
+	rz := 2*(a*c-b*w)*x + 2*(b*c+a*w)*y + (1-2*(a*a+b*b))*z
+	return rx + p.Translation[0], ry + p.Translation[1], rz + p.Translation[2]
+}
+
+// Decode reads the header and XML section of the E57 file held by r, which is size bytes long.  Points are read by
+// PointCloud.
+func Decode(r io.ReaderAt, size int64) (error, *File) {
+	var buf [fileHeaderSize]byte
+	_, err := r.ReadAt(buf[:], 0)
+	if err != nil {
+		return fmt.Errorf("failed to read e57 header: %w", err), nil
+	}
+	if string(buf[:8]) != Signature {
+		return fmt.Errorf("not an e57 file"), nil
+	}
+
+	h := fileHeader{
+		MajorVersion:   binary.LittleEndian.Uint32(buf[8:12]),
+		MinorVersion:   binary.LittleEndian.Uint32(buf[12:16]),
+		PhysicalLength: binary.LittleEndian.Uint64(buf[16:24]),
+		XMLOffset:      binary.LittleEndian.Uint64(buf[24:32]),
+		XMLLength:      binary.LittleEndian.Uint64(buf[32:40]),
+		PageSize:       binary.LittleEndian.Uint64(buf[40:48]),
+	}
+	if h.MajorVersion != 1 {
+		return fmt.Errorf("unsupported e57 version %d.%d", h.MajorVersion, h.MinorVersion), nil
+	}
+	if h.PageSize <= checksumSize+fileHeaderSize || h.PageSize > 1<<20 {
+		return fmt.Errorf("invalid e57 page size %d", h.PageSize), nil
+	}
+	if h.XMLOffset > math.MaxInt64 || h.XMLLength > math.MaxInt32 {
+		return fmt.Errorf("invalid e57 xml section at %d of %d bytes", h.XMLOffset, h.XMLLength), nil
+	}
+
+	pr := &pagedReader{r: r, size: size, pageSize: (int64)(h.PageSize)}
+	err, text := pr.read((int64)(h.XMLOffset), (int64)(h.XMLLength))
+	if err != nil {
+		return fmt.Errorf("failed to read e57 xml section: %w", err), nil
+	}
+	err, root := parseXML(text)
+	if err != nil {
+		return fmt.Errorf("failed to parse e57 xml section: %w", err), nil
+	}
+	if root.name != "e57Root" {
+		return fmt.Errorf("e57 xml section has root %s, want e57Root", root.name), nil
+	}
+
+	f := &File{pr: pr}
+	f.GUID = root.childText("guid")
+	f.CoordinateMetadata = strings.TrimSpace(root.childText("coordinateMetadata"))
+
+	if data3D := root.child("data3D"); data3D != nil {
+		for i, n := range data3D.children {
+			err, scan := decodeScan(n)
+			if err != nil {
+				return fmt.Errorf("scan %d: %w", i, err), nil
+			}
+			f.Scans = append(f.Scans, scan)
+		}
+	}
+	return nil, f
+}
+
+func decodeScan(n *node) (error, Scan) {
+	s := Scan{
+		GUID:        n.childText("guid"),
+		Name:        n.childText("name"),
+		Description: n.childText("description"),
+		Pose:        Identity,
+	}
+
+	if pose := n.child("pose"); pose != nil {
+		if rotation := pose.child("rotation"); rotation != nil {
+			for i, name := range [...]string{"w", "x", "y", "z"} {
+				s.Pose.Rotation[i], _ = rotation.childNumber(name)
+			}
+			// normalize, so that rounding in the file doesn't scale the points
+			w, x, y, z := s.Pose.Rotation[0], s.Pose.Rotation[1], s.Pose.Rotation[2], s.Pose.Rotation[3]
+			norm := math.Sqrt(w*w + x*x + y*y + z*z)
+			if norm == 0 {
+				return fmt.Errorf("pose rotation is zero"), s
+			}
+			for i := range s.Pose.Rotation {
+				s.Pose.Rotation[i] /= norm
+			}
+		}
+		if translation := pose.child("translation"); translation != nil {
+			for i, name := range [...]string{"x", "y", "z"} {
+				s.Pose.Translation[i], _ = translation.childNumber(name)
+			}
+		}
+	}
+
+	if limits := n.child("intensityLimits"); limits != nil {
+		lo, lok := limits.childNumber("intensityMinimum")
+		hi, hok := limits.childNumber("intensityMaximum")
+		if lok && hok {
+			s.intensityLimits = &[2]float64{lo, hi}
+		}
+	}
+	if limits := n.child("colorLimits"); limits != nil {
+		var l [3][2]float64
+		ok := true
+		for i, color := range [...]string{"Red", "Green", "Blue"} {
+			var lok, hok bool
+			l[i][0], lok = limits.childNumber("color" + color + "Minimum")
+			l[i][1], hok = limits.childNumber("color" + color + "Maximum")
+			ok = ok && lok && hok
+		}
+		if ok {
+			s.colorLimits = &l
+		}
+	}
+	if start := n.child("acquisitionStart"); start != nil {
+		if v, ok := start.childNumber("dateTimeValue"); ok {
+			s.start = &v
+		}
+	}
+
+	points := n.child("points")
+	if points == nil {
+		return fmt.Errorf("no points"), s
+	}
+	if points.attrs["type"] != "CompressedVector" {
+		return fmt.Errorf("points are a %s, want a CompressedVector", points.attrs["type"]), s
+	}
+	err, offset := points.intAttr("fileOffset")
+	if err != nil {
+		return err, s
+	}
+	err, count := points.intAttr("recordCount")
+	if err != nil {
+		return err, s
+	}
+	if offset < 0 || count < 0 {
+		return fmt.Errorf("invalid points at offset %d with %d records", offset, count), s
+	}
+	s.offset, s.Points = offset, count
+
+	if codecs := points.child("codecs"); codecs != nil && len(codecs.children) > 0 {
+		return fmt.Errorf("codecs other than bitpack are not supported"), s
+	}
+	prototype := points.child("prototype")
+	if prototype == nil {
+		return fmt.Errorf("points have no prototype"), s
+	}
+	for _, c := range prototype.children {
+		err, f := decodeField(c)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", c.name, err), s
+		}
+		s.prototype = append(s.prototype, f)
+		s.Fields = append(s.Fields, f.name)
+	}
+
+	return nil, s
+}
@@ -0,0 +1,200 @@
+package e57
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/e57test"
+)
+
+func read(t *testing.T, spec e57test.Spec) *lassloot.PointCloud {
+	t.Helper()
+
+	data := e57test.Generate(spec)
+	err, pc := Read(bytes.NewReader(data), (int64)(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestCartesianScans(t *testing.T) {
+	start := 1.3e9
+	scaled := func(name string, values ...float64) e57test.Field {
+		return e57test.Field{Name: name, Type: "ScaledInteger", Min: -100000, Max: 100000, Scale: 0.001, Values: values}
+	}
+	integer := func(name string, max int64, values ...float64) e57test.Field {
+		return e57test.Field{Name: name, Type: "Integer", Max: max, Values: values}
+	}
+
+	pc := read(t, e57test.Spec{
+		Packets: 3,
+		Scans: []e57test.Scan{
+			{
+				Name:             "first",
+				AcquisitionStart: &start,
+				Fields: []e57test.Field{
+					scaled("cartesianX", 1000, -2500, 0),
+					scaled("cartesianY", 2000, 0, 0),
+					scaled("cartesianZ", 3000, 500, 0),
+					integer("cartesianInvalidState", 2, 0, 0, 2),
+					integer("intensity", 2047, 2047, 0, 1023),
+					integer("colorRed", 255, 255, 0, 0),
+					integer("colorGreen", 255, 128, 0, 0),
+					integer("colorBlue", 255, 0, 255, 0),
+					{Name: "timeStamp", Type: "Float", Values: []float64{0.5, 1.5, 2.5}},
+				},
+			},
+			{
+				Name: "second",
+				// a quarter turn about z, then 10 metres east
+				Pose:            &[7]float64{math.Sqrt2 / 2, 0, 0, math.Sqrt2 / 2, 10, 0, 0},
+				IntensityLimits: &[2]float64{0, 1},
+				Fields: []e57test.Field{
+					{Name: "cartesianX", Type: "Float", Single: true, Values: []float64{1}},
+					{Name: "cartesianY", Type: "Float", Single: true, Values: []float64{0}},
+					{Name: "cartesianZ", Type: "Float", Single: true, Values: []float64{0.25}},
+					{Name: "intensity", Type: "Float", Single: true, Values: []float64{0.5}},
+				},
+			},
+		},
+	})
+
+	if pc.Len() != 3 {
+		t.Fatalf("%d points, want 3 without the invalid one", pc.Len())
+	}
+	h := pc.Header().RawHeader
+	if h.PointDataRecordFormat != 7 {
+		t.Errorf("format %d, want 7", h.PointDataRecordFormat)
+	}
+	if !pc.IsAdjustedStandardGPSTime() {
+		t.Error("times are not adjusted standard")
+	}
+	if p := pc.Precision(); p.XScaleFactor != 0.001 {
+		t.Errorf("scale %v, want the 0.001 of the scaled integers", p.XScaleFactor)
+	}
+
+	for i, want := range []struct {
+		x, y, z   float64
+		intensity float64
+		rgb       [3]float64
+		time      float64
+		source    float64
+	}{
+		{1, 2, 3, 65535, [3]float64{65535, 32896, 0}, 1.3e9 + 0.5 - 1e9, 0},
+		{-2.5, 0, 0.5, 0, [3]float64{0, 0, 65535}, 1.3e9 + 1.5 - 1e9, 0},
+		{10, 1, 0.25, 32768, [3]float64{}, 0, 1},
+	} {
+		_, p := pc.PointAt((uint64)(i))
+		if x, y, z := p.XYZ(); !near(x, want.x) || !near(y, want.y) || !near(z, want.z) {
+			t.Errorf("point %d at %v %v %v, want %v %v %v", i, x, y, z, want.x, want.y, want.z)
+		}
+		for name, v := range map[string]float64{
+			las14.DimIntensity:     want.intensity,
+			las14.DimRed:           want.rgb[0],
+			las14.DimGreen:         want.rgb[1],
+			las14.DimBlue:          want.rgb[2],
+			las14.DimGPSTime:       want.time,
+			las14.DimPointSourceID: want.source,
+		} {
+			_, got := p.Float64(name)
+			if !near(got, v) {
+				t.Errorf("point %d %s is %v, want %v", i, name, got, v)
+			}
+		}
+	}
+}
+
+func TestSphericalAcrossPages(t *testing.T) {
+	// enough points that the section spans several pages
+	var r, az, el, state []float64
+	for i := 0; i < 400; i++ {
+		r = append(r, 2)
+		az = append(az, (float64)(i)*math.Pi/200)
+		el = append(el, 0)
+		state = append(state, (float64)(i%2))
+	}
+	el[0] = math.Pi / 2
+
+	pc := read(t, e57test.Spec{
+		CoordinateMetadata: `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]]`,
+		Scans: []e57test.Scan{{
+			Name: "sphere",
+			Fields: []e57test.Field{
+				{Name: "sphericalRange", Type: "Float", Values: r},
+				{Name: "sphericalAzimuth", Type: "Float", Values: az},
+				{Name: "sphericalElevation", Type: "Float", Values: el},
+				{Name: "sphericalInvalidState", Type: "Integer", Max: 2, Values: state},
+			},
+		}},
+	})
+
+	if pc.Len() != 200 {
+		t.Fatalf("%d points, want 200 valid ones", pc.Len())
+	}
+	if f := pc.Header().RawHeader.PointDataRecordFormat; f != 0 {
+		t.Errorf("format %d, want 0", f)
+	}
+	if !pc.Header().RawHeader.GlobalEncoding.UseWKTForCRS() {
+		t.Error("coordinate metadata not kept as WKT")
+	}
+
+	_, p := pc.PointAt(0)
+	if x, y, z := p.XYZ(); !near(x, 0) || !near(y, 0) || !near(z, 2) {
+		t.Errorf("point 0 at %v %v %v, want straight up", x, y, z)
+	}
+	_, p = pc.PointAt(50)
+	// the 100th point, a quarter turn round
+	if x, y, z := p.XYZ(); !near(x, 0) || !near(y, 2) || !near(z, 0) {
+		t.Errorf("point 50 at %v %v %v, want 0 2 0", x, y, z)
+	}
+}
+
+func TestDecodeScans(t *testing.T) {
+	data := e57test.Generate(e57test.Spec{Scans: []e57test.Scan{
+		{Name: "a", Fields: []e57test.Field{
+			{Name: "cartesianX", Type: "Float", Values: []float64{1}},
+			{Name: "cartesianY", Type: "Float", Values: []float64{2}},
+			{Name: "cartesianZ", Type: "Float", Values: []float64{3}},
+		}},
+		{Name: "b", Fields: []e57test.Field{
+			{Name: "cartesianX", Type: "Float", Values: []float64{4, 5}},
+			{Name: "cartesianY", Type: "Float", Values: []float64{6, 7}},
+			{Name: "cartesianZ", Type: "Float", Values: []float64{8, 9}},
+		}},
+	}})
+
+	err, f := Decode(bytes.NewReader(data), (int64)(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Scans) != 2 || f.Scans[1].Name != "b" || f.Scans[1].Points != 2 {
+		t.Fatalf("scans %+v", f.Scans)
+	}
+
+	err, pc := f.PointCloud(Options{Scans: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.Len() != 2 {
+		t.Errorf("%d points, want the 2 of scan b", pc.Len())
+	}
+	if err, _ := f.PointCloud(Options{Scans: []int{2}}); err == nil {
+		t.Error("missing scan accepted")
+	}
+
+	if err, _ := Read(bytes.NewReader(data[:1100]), 1100); err == nil {
+		t.Error("truncated file accepted")
+	}
+	bad := append([]byte("ASTM-E58"), data[8:]...)
+	if err, _ := Read(bytes.NewReader(bad), (int64)(len(bad))); err == nil {
+		t.Error("bad signature accepted")
+	}
+}
@@ -0,0 +1,274 @@
+package e57
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+)
+
+// fieldKind is the E57 type of a field of a CompressedVector's records.
+type fieldKind int
+
+const (
+	kindInteger fieldKind = iota
+	kindScaledInteger
+	kindFloat
+)
+
+// field describes one value of each record, from the CompressedVector's prototype.
+type field struct {
+	name string
+	kind fieldKind
+
+	// integers are stored as value - min in just enough bits for max - min
+	min, max int64
+	bits     int
+
+	// scaled integers have a value of integer * scale + offset
+	scale, offset float64
+
+	// floats are 4 or 8 bytes, and may note the range of their values
+	size          int
+	fmin, fmax    float64
+	hasFloatLimit bool
+}
+
+func decodeField(n *node) (error, field) {
+	f := field{name: n.name, scale: 1}
+
+	integerLimits := func() error {
+		f.min, f.max = math.MinInt64, math.MaxInt64
+		if _, ok := n.attrs["minimum"]; ok {
+			err, v := n.intAttr("minimum")
+			if err != nil {
+				return err
+			}
+			f.min = v
+		}
+		if _, ok := n.attrs["maximum"]; ok {
+			err, v := n.intAttr("maximum")
+			if err != nil {
+				return err
+			}
+			f.max = v
+		}
+		if f.max < f.min {
+			return fmt.Errorf("maximum %d is less than minimum %d", f.max, f.min)
+		}
+		f.bits = bits.Len64((uint64)(f.max) - (uint64)(f.min))
+		return nil
+	}
+
+	switch n.attrs["type"] {
+	case "Integer":
+		f.kind = kindInteger
+		return integerLimits(), f
+	case "ScaledInteger":
+		f.kind = kindScaledInteger
+		err := integerLimits()
+		if err != nil {
+			return err, f
+		}
+		err, f.scale = n.floatAttr("scale", 1)
+		if err != nil {
+			return err, f
+		}
+		if f.scale == 0 {
+			return fmt.Errorf("scale is zero"), f
+		}
+		err, f.offset = n.floatAttr("offset", 0)
+		return err, f
+	case "Float":
+		f.kind = kindFloat
+		f.size = 8
+		if strings.TrimSpace(n.attrs["precision"]) == "single" {
+			f.size = 4
+		}
+		_, hasMin := n.attrs["minimum"]
+		_, hasMax := n.attrs["maximum"]
+		if hasMin && hasMax {
+			err, lo := n.floatAttr("minimum", 0)
+			if err != nil {
+				return err, f
+			}
+			err, hi := n.floatAttr("maximum", 0)
+			if err != nil {
+				return err, f
+			}
+			f.fmin, f.fmax, f.hasFloatLimit = lo, hi, true
+		}
+		return nil, f
+	default:
+		return fmt.Errorf("unsupported type %q", n.attrs["type"]), f
+	}
+}
+
+// limits returns the range of the field's values, for normalizing intensities and colors.  Floats without a range
+// are taken to be between 0 and 1.
+func (f *field) limits() (float64, float64) {
+	switch f.kind {
+	case kindFloat:
+		if f.hasFloatLimit {
+			return f.fmin, f.fmax
+		}
+		return 0, 1
+	case kindScaledInteger:
+		return (float64)(f.min)*f.scale + f.offset, (float64)(f.max)*f.scale + f.offset
+	default:
+		return (float64)(f.min), (float64)(f.max)
+	}
+}
+
+// digits returns the decimal places the field's values are recorded to, or -1 when they aren't quantized.
+func (f *field) digits() int {
+	switch f.kind {
+	case kindScaledInteger:
+		return lassloot.ScaleDigits(f.scale)
+	case kindInteger:
+		return 0
+	default:
+		return -1
+	}
+}
+
+// size of a CompressedVector section header, and of the header of a data packet before its bytestream lengths
+const (
+	sectionHeaderSize    = 32
+	dataPacketHeaderSize = 6
+)
+
+// Section and packet types.
+const (
+	compressedVectorSectionID = 1
+
+	indexPacket = 0
+	dataPacket  = 1
+	emptyPacket = 2
+)
+
+// readVector decodes the count records of the CompressedVector section at the physical offset, returning each
+// field's values in turn.
+func (pr *pagedReader) readVector(offset int64, count int64, fields []field) (error, [][]float64) {
+	err, header := pr.read(offset, sectionHeaderSize)
+	if err != nil {
+		return err, nil
+	}
+	if header[0] != compressedVectorSectionID {
+		return fmt.Errorf("section at offset %d has id %d, want %d", offset, header[0], compressedVectorSectionID), nil
+	}
+	length := binary.LittleEndian.Uint64(header[8:16])
+	dataOffset := binary.LittleEndian.Uint64(header[16:24])
+	if length < sectionHeaderSize || length > (uint64)(pr.size) || dataOffset > (uint64)(pr.size) {
+		return fmt.Errorf("invalid section of %d bytes with data at %d", length, dataOffset), nil
+	}
+
+	err, section := pr.read(offset, (int64)(length))
+	if err != nil {
+		return err, nil
+	}
+
+	// gather each field's bytestream, which is spread across the data packets
+	streams := make([][]byte, len(fields))
+	pos := pr.logical((int64)(dataOffset)) - pr.logical(offset)
+	if count > 0 && (pos < sectionHeaderSize || pos > (int64)(len(section))) {
+		return fmt.Errorf("data at offset %d lies outside its section", dataOffset), nil
+	}
+	for count > 0 && pos+4 <= (int64)(len(section)) {
+		packet := section[pos:]
+		size := (int64)(binary.LittleEndian.Uint16(packet[2:4])) + 1
+		if size > (int64)(len(packet)) {
+			return fmt.Errorf("packet of %d bytes at %d overruns its section", size, pos), nil
+		}
+		packet = packet[:size]
+
+		switch packet[0] {
+		case dataPacket:
+			if size < dataPacketHeaderSize {
+				return fmt.Errorf("data packet of %d bytes is too short", size), nil
+			}
+			n := (int)(binary.LittleEndian.Uint16(packet[4:6]))
+			if n != len(fields) {
+				return fmt.Errorf("data packet holds %d bytestreams, want %d", n, len(fields)), nil
+			}
+			at := dataPacketHeaderSize + 2*n
+			if at > len(packet) {
+				return fmt.Errorf("data packet of %d bytes is too short", size), nil
+			}
+			for i := 0; i < n; i++ {
+				l := (int)(binary.LittleEndian.Uint16(packet[dataPacketHeaderSize+2*i:]))
+				if at+l > len(packet) {
+					return fmt.Errorf("bytestream of %d bytes overruns its packet", l), nil
+				}
+				streams[i] = append(streams[i], packet[at:at+l]...)
+				at += l
+			}
+		case indexPacket, emptyPacket:
+		default:
+			return fmt.Errorf("unknown packet type %d", packet[0]), nil
+		}
+		pos += size
+	}
+
+	values := make([][]float64, len(fields))
+	for i := range fields {
+		err, v := fields[i].decode(streams[i], count)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fields[i].name, err), nil
+		}
+		values[i] = v
+	}
+	return nil, values
+}
+
+// decode unpacks count values from the field's bytestream.  Integers are bitpacked, least significant bit first,
+// and floats are stored whole in little endian order.
+func (f *field) decode(stream []byte, count int64) (error, []float64) {
+	var need int64
+	if f.kind == kindFloat {
+		need = count * (int64)(f.size)
+	} else {
+		need = (count*(int64)(f.bits) + 7) / 8
+	}
+	if (int64)(len(stream)) < need {
+		return fmt.Errorf("bytestream holds %d bytes, want %d for %d values", len(stream), need, count), nil
+	}
+
+	ret := make([]float64, count)
+	if f.kind == kindFloat {
+		for i := range ret {
+			if f.size == 4 {
+				ret[i] = (float64)(math.Float32frombits(binary.LittleEndian.Uint32(stream[i*4:])))
+			} else {
+				ret[i] = math.Float64frombits(binary.LittleEndian.Uint64(stream[i*8:]))
+			}
+		}
+		return nil, ret
+	}
+
+	pos := 0
+	for i := range ret {
+		var raw uint64
+		for got := 0; got < f.bits; {
+			shift := pos & 7
+			take := 8 - shift
+			if take > f.bits-got {
+				take = f.bits - got
+			}
+			raw |= ((uint64)(stream[pos>>3]>>shift) & (1<<take - 1)) << got
+			got += take
+			pos += take
+		}
+
+		v := (int64)(raw + (uint64)(f.min))
+		if f.kind == kindScaledInteger {
+			ret[i] = (float64)(v)*f.scale + f.offset
+		} else {
+			ret[i] = (float64)(v)
+		}
+	}
+	return nil, ret
+}
@@ -0,0 +1,156 @@
+package e57
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// node is an element of the XML section.  Each element's type attribute gives its E57 type: Structure and Vector
+// elements have children, while Integer, ScaledInteger, Float and String elements hold their value as text.
+type node struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*node
+}
+
+func parseXML(data []byte) (error, *node) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*node
+	var root *node
+
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err, nil
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return fmt.Errorf("no root element"), nil
+	}
+	return nil, root
+}
+
+// child returns the first child element named name, or nil.
+func (n *node) child(name string) *node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// childText returns the text of the child element named name, or "" when there is none.
+func (n *node) childText(name string) string {
+	c := n.child(name)
+	if c == nil {
+		return ""
+	}
+	return c.text
+}
+
+// childNumber returns the value of the numeric child element named name.
+func (n *node) childNumber(name string) (float64, bool) {
+	c := n.child(name)
+	if c == nil {
+		return 0, false
+	}
+	return c.number()
+}
+
+// number returns the value of an Integer, ScaledInteger or Float element.  Elements without text have a value of
+// zero, as E57 specifies.
+func (n *node) number() (float64, bool) {
+	text := strings.TrimSpace(n.text)
+
+	switch n.attrs["type"] {
+	case "Integer":
+		if text == "" {
+			return 0, true
+		}
+		v, err := strconv.ParseInt(text, 10, 64)
+		return (float64)(v), err == nil
+	case "ScaledInteger":
+		var raw int64
+		if text != "" {
+			var err error
+			raw, err = strconv.ParseInt(text, 10, 64)
+			if err != nil {
+				return 0, false
+			}
+		}
+		scale, offset := 1.0, 0.0
+		if err, v := n.floatAttr("scale", 1); err == nil {
+			scale = v
+		}
+		if err, v := n.floatAttr("offset", 0); err == nil {
+			offset = v
+		}
+		return (float64)(raw)*scale + offset, true
+	case "Float":
+		if text == "" {
+			return 0, true
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		return v, err == nil && !math.IsNaN(v)
+	default:
+		return 0, false
+	}
+}
+
+// intAttr returns the integer value of the attribute named name.
+func (n *node) intAttr(name string) (error, int64) {
+	s, ok := n.attrs[name]
+	if !ok {
+		return fmt.Errorf("%s has no %s", n.name, name), 0
+	}
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q of %s", name, s, n.name), 0
+	}
+	return nil, v
+}
+
+// floatAttr returns the value of the attribute named name, or def when it is absent.
+func (n *node) floatAttr(name string, def float64) (error, float64) {
+	s, ok := n.attrs[name]
+	if !ok {
+		return nil, def
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("invalid %s %q of %s", name, s, n.name), 0
+	}
+	return nil, v
+}
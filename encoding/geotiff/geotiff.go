@@ -0,0 +1,512 @@
+// Package geotiff writes grids as GeoTIFF rasters, georeferenced so that GIS software such as QGIS and GDAL places
+// them where their cells lie.
+//
+// A GeoTIFF is a TIFF image whose tags locate its top left corner and give the size of its pixels, and whose GeoKeys
+// name its coordinate reference system.  Rasters are written as single band images of 32-bit floats, or of 16-bit
+// integers scaled and offset from the grid's values, with cells without a value set to a nodata value that GDAL's
+// nodata tag records.
+//
+// A cloud optimized GeoTIFF (COG) is cut into tiles and carries overviews, copies of the image at successively halved
+// resolutions, with its directories at the start of the file and the overviews' tiles before its own.  Web maps and
+// QGIS read one over HTTP by fetching just the byte ranges of the tiles in view, at the resolution they're drawn at.
+package geotiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/grid"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// TIFF tags.
+const (
+	tagNewSubfileType  = 254
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPlanarConfig    = 284
+	tagSoftware        = 305
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSampleFormat    = 339
+
+	tagModelPixelScale = 33550
+	tagModelTiepoint   = 33922
+	tagGeoKeyDirectory = 34735
+	tagGeoDoubleParams = 34736
+	tagGeoASCIIParams  = 34737
+	tagGDALMetadata    = 42112
+	tagGDALNoData      = 42113
+)
+
+// TIFF field types.
+const (
+	typeASCII  = 2
+	typeShort  = 3
+	typeLong   = 4
+	typeDouble = 12
+)
+
+const (
+	compressionNone       = 1
+	compressionLZW        = 5
+	compressionDeflate    = 8
+	photometricMinIsBlack = 1
+	sampleFormatInt       = 2
+	sampleFormatFloat     = 3
+	predictorHorizontal   = 2
+	predictorFloat        = 3
+	subfileReduced        = 1
+
+	rasterPixelIsArea = 1
+
+	// stripSize is roughly the number of bytes in each strip of an image that isn't tiled
+	stripSize = 64 << 10
+
+	// DefaultTileSize is the width and height of a cloud optimized GeoTIFF's tiles unless set, as GDAL's
+	DefaultTileSize = 256
+)
+
+// SampleType is the type of a raster's pixels.
+type SampleType int
+
+const (
+	Float32 SampleType = iota
+
+	// Int16 stores each value v as the whole number nearest (v - Offset) / Scale, taking half the space of Float32
+	// and compressing better.  A scale of 0.01 keeps centimeters of elevations within 327 meters of the offset.
+	Int16
+)
+
+var sampleTypeNames = [...]string{"float32", "int16"}
+
+func (t SampleType) String() string {
+	if t < 0 || (int)(t) >= len(sampleTypeNames) {
+		return fmt.Sprintf("SampleType(%d)", t)
+	}
+	return sampleTypeNames[t]
+}
+
+// ParseSampleType returns the sample type named s, as String returns.
+func ParseSampleType(s string) (error, SampleType) {
+	for i, name := range sampleTypeNames {
+		if strings.EqualFold(s, name) {
+			return nil, (SampleType)(i)
+		}
+	}
+	return fmt.Errorf("unknown sample type %q: want %s", s, strings.Join(sampleTypeNames[:], ", ")), Float32
+}
+
+// Compression is how a raster's strips or tiles are compressed.
+type Compression int
+
+const (
+	Uncompressed Compression = iota
+	Deflate
+	LZW
+)
+
+var compressionNames = [...]string{"none", "deflate", "lzw"}
+
+func (c Compression) String() string {
+	if c < 0 || (int)(c) >= len(compressionNames) {
+		return fmt.Sprintf("Compression(%d)", c)
+	}
+	return compressionNames[c]
+}
+
+// ParseCompression returns the compression named s, as String returns.
+func ParseCompression(s string) (error, Compression) {
+	for i, name := range compressionNames {
+		if strings.EqualFold(s, name) {
+			return nil, (Compression)(i)
+		}
+	}
+	return fmt.Errorf("unknown compression %q: want %s", s, strings.Join(compressionNames[:], ", ")), Uncompressed
+}
+
+// Options control how a grid is written.
+type Options struct {
+	// NoData is written in place of the cells of a grid that have no value, and recorded as such.  It is stored as is,
+	// unscaled, so for Int16 it must be a whole number from -32768 to 32767.
+	NoData float64
+
+	// CRS, if set, is the coordinate reference system of the grid's coordinates.  Its GeoKeys are copied when it has
+	// them; otherwise its EPSG codes are.
+	CRS *lassloot.CRS
+
+	Type SampleType
+
+	// Scale and Offset map Int16 pixels to the grid's values, which are pixel * Scale + Offset, as GDAL's metadata
+	// records.  Zero Scale takes 1.
+	Scale, Offset float64
+
+	Compression Compression
+
+	// Predictor stores each pixel as its difference from the one west of it, which compresses smooth surfaces such as
+	// terrain much better.
+	Predictor bool
+
+	// TileSize, if set, cuts the image into square tiles this wide, a multiple of 16, rather than strips of rows.
+	TileSize int
+
+	// COG writes a cloud optimized GeoTIFF: tiled, DefaultTileSize unless TileSize is set, with overviews halving the
+	// resolution until the image fits in one tile.  Each overview's pixel is the mean of the four beneath it that
+	// have values.
+	COG bool
+
+	// Metadata holds items recorded in GDAL's metadata, which GDAL and QGIS list among the raster's properties.
+	Metadata map[string]string
+}
+
+// Write writes g to w as a GeoTIFF.
+func Write(w io.Writer, g *grid.Grid, opts Options) error {
+	if opts.Scale == 0 {
+		opts.Scale = 1
+	}
+	switch {
+	case opts.Type < 0 || (int)(opts.Type) >= len(sampleTypeNames):
+		return fmt.Errorf("unknown sample type %v", opts.Type)
+	case opts.Compression < 0 || (int)(opts.Compression) >= len(compressionNames):
+		return fmt.Errorf("unknown compression %v", opts.Compression)
+	case math.IsNaN(opts.Scale) || math.IsInf(opts.Scale, 0) || math.IsNaN(opts.Offset) || math.IsInf(opts.Offset, 0):
+		return fmt.Errorf("invalid scale %v and offset %v", opts.Scale, opts.Offset)
+	case opts.Type == Int16 && !(opts.NoData == math.Trunc(opts.NoData) && opts.NoData >= math.MinInt16 &&
+		opts.NoData <= math.MaxInt16):
+		return fmt.Errorf("nodata %v is not a 16-bit integer", opts.NoData)
+	}
+	tile := opts.TileSize
+	if tile == 0 && opts.COG {
+		tile = DefaultTileSize
+	}
+	if tile < 0 || tile%16 != 0 {
+		return fmt.Errorf("tile size %d is not a positive multiple of 16", tile)
+	}
+
+	levels := []*grid.Grid{g}
+	for l := g; opts.COG && (l.Cols > tile || l.Rows > tile); {
+		if l = overview(l); l == nil {
+			break
+		}
+		levels = append(levels, l)
+	}
+
+	images := make([]*image, len(levels))
+	for i, l := range levels {
+		err, img := encode(l, tile, &opts)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			img.fields = append(img.fields, ascii(tagSoftware, lassloot.GeneratingSoftware))
+			img.fields = append(img.fields, georeference(g, opts.CRS)...)
+			if md := metadata(&opts); md != "" {
+				img.fields = append(img.fields, ascii(tagGDALMetadata, md))
+			}
+		} else {
+			img.fields = append(img.fields, longs(tagNewSubfileType, subfileReduced))
+		}
+		images[i] = img
+	}
+
+	var ghost []byte
+	if opts.COG {
+		ghost = structuralMetadata()
+	}
+	return writeTIFF(w, ghost, images)
+}
+
+// structuralMetadata returns the block GDAL writes between a cloud optimized GeoTIFF's header and its first directory,
+// which tells readers how the file is laid out without their reading all of it.
+func structuralMetadata() []byte {
+	md := "LAYOUT=IFDS_BEFORE_DATA\nBLOCK_ORDER=ROW_MAJOR\nKNOWN_INCOMPATIBLE_EDITION=NO\n"
+	return []byte(fmt.Sprintf("GDAL_STRUCTURAL_METADATA_SIZE=%06d bytes\n%s", len(md), md))
+}
+
+// encode lays out g as an image of strips, or of tiles tile wide, with the fields describing its pixels.
+func encode(g *grid.Grid, tile int, opts *Options) (error, *image) {
+	bytesPerSample := 4
+	if opts.Type == Int16 {
+		bytesPerSample = 2
+	}
+	img := &image{fields: []field{
+		longs(tagImageWidth, (uint32)(g.Cols)),
+		longs(tagImageLength, (uint32)(g.Rows)),
+		shorts(tagBitsPerSample, (uint16)(8*bytesPerSample)),
+		shorts(tagPhotometric, photometricMinIsBlack),
+		shorts(tagSamplesPerPixel, 1),
+		shorts(tagPlanarConfig, 1),
+		ascii(tagGDALNoData, formatNoData(opts.NoData)),
+	}}
+	switch opts.Type {
+	case Float32:
+		img.fields = append(img.fields, shorts(tagSampleFormat, sampleFormatFloat))
+	case Int16:
+		img.fields = append(img.fields, shorts(tagSampleFormat, sampleFormatInt))
+	}
+	switch opts.Compression {
+	case Uncompressed:
+		img.fields = append(img.fields, shorts(tagCompression, compressionNone))
+	case Deflate:
+		img.fields = append(img.fields, shorts(tagCompression, compressionDeflate))
+	case LZW:
+		img.fields = append(img.fields, shorts(tagCompression, compressionLZW))
+	}
+	if opts.Predictor {
+		if opts.Type == Float32 {
+			img.fields = append(img.fields, shorts(tagPredictor, predictorFloat))
+		} else {
+			img.fields = append(img.fields, shorts(tagPredictor, predictorHorizontal))
+		}
+	}
+
+	// blocks of width by height pixels, from the top left; tiles past the image's edges are padded with nodata
+	width, height := g.Cols, g.Rows
+	if tile > 0 {
+		width, height = tile, tile
+		img.offsetsTag, img.countsTag = tagTileOffsets, tagTileByteCounts
+		img.fields = append(img.fields, longs(tagTileWidth, (uint32)(tile)), longs(tagTileLength, (uint32)(tile)))
+	} else {
+		height = (int)(math.Max(1, math.Floor((float64)(stripSize)/(float64)(bytesPerSample*g.Cols))))
+		if height > g.Rows {
+			height = g.Rows
+		}
+		img.offsetsTag, img.countsTag = tagStripOffsets, tagStripByteCounts
+		img.fields = append(img.fields, longs(tagRowsPerStrip, (uint32)(height)))
+	}
+
+	for row := 0; row < g.Rows; row += height {
+		rows := height
+		if tile == 0 && row+rows > g.Rows {
+			rows = g.Rows - row
+		}
+		for col := 0; col < g.Cols; col += width {
+			err, b := encodeBlock(g, col, row, width, rows, bytesPerSample, opts)
+			if err != nil {
+				return err, nil
+			}
+			img.blocks = append(img.blocks, b)
+		}
+	}
+	return nil, img
+}
+
+// encodeBlock returns the pixels of g in the block of width by height pixels from col, row, predicted and compressed.
+func encodeBlock(g *grid.Grid, col, row, width, height, bytesPerSample int, opts *Options) (error, []byte) {
+	raw := make([]byte, 0, width*height*bytesPerSample)
+	for r := row; r < row+height; r++ {
+		start := len(raw)
+		for c := col; c < col+width; c++ {
+			v := math.NaN()
+			if c < g.Cols && r < g.Rows {
+				v = g.At(c, r)
+			}
+			switch opts.Type {
+			case Float32:
+				f := (float32)(v)
+				if math.IsNaN(v) {
+					f = (float32)(opts.NoData)
+				}
+				raw = wire.AppendUint32(raw, math.Float32bits(f))
+			case Int16:
+				s := opts.NoData
+				if !math.IsNaN(v) {
+					s = math.Round((v - opts.Offset) / opts.Scale)
+					if !(s >= math.MinInt16 && s <= math.MaxInt16) {
+						return fmt.Errorf("%v at scale %v and offset %v does not fit in 16 bits", v, opts.Scale,
+							opts.Offset), nil
+					}
+				}
+				raw = wire.AppendUint16(raw, (uint16)((int16)(s)))
+			}
+		}
+		if opts.Predictor {
+			predict(raw[start:], bytesPerSample, opts.Type)
+		}
+	}
+
+	switch opts.Compression {
+	case Deflate:
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(raw)
+		if err := zw.Close(); err != nil {
+			return err, nil
+		}
+		return nil, buf.Bytes()
+	case LZW:
+		return nil, compressLZW(raw)
+	}
+	return nil, raw
+}
+
+// predict replaces a row of pixels with their differences from their western neighbors.  Integers are differenced
+// whole.  Floats are differenced as TIFF's floating point predictor does: their bytes are regrouped, the most
+// significant of every pixel first, and each byte differenced from the one before, which leaves the slowly changing
+// sign and exponent bytes mostly zero.
+func predict(row []byte, bytesPerSample int, typ SampleType) {
+	n := len(row) / bytesPerSample
+	if typ == Int16 {
+		for i := n - 1; i > 0; i-- {
+			v := (uint16)(row[2*i]) | (uint16)(row[2*i+1])<<8
+			prev := (uint16)(row[2*i-2]) | (uint16)(row[2*i-1])<<8
+			v -= prev
+			row[2*i], row[2*i+1] = (byte)(v), (byte)(v>>8)
+		}
+		return
+	}
+
+	planes := make([]byte, len(row))
+	for i := 0; i < n; i++ {
+		for b := 0; b < bytesPerSample; b++ {
+			planes[b*n+i] = row[i*bytesPerSample+bytesPerSample-1-b]
+		}
+	}
+	for i := len(planes) - 1; i > 0; i-- {
+		planes[i] -= planes[i-1]
+	}
+	copy(row, planes)
+}
+
+// overview returns g at half its resolution, each cell the mean of the up to four beneath it with values, or nil if g
+// is a single cell.
+func overview(g *grid.Grid) *grid.Grid {
+	if g.Cols == 1 && g.Rows == 1 {
+		return nil
+	}
+	err, o := grid.New(g.MinX, g.MaxY, 2*g.Resolution, (g.Cols+1)/2, (g.Rows+1)/2)
+	if err != nil {
+		return nil
+	}
+	for row := 0; row < o.Rows; row++ {
+		for col := 0; col < o.Cols; col++ {
+			var sum float64
+			var n int
+			for r := 2 * row; r < 2*row+2 && r < g.Rows; r++ {
+				for c := 2 * col; c < 2*col+2 && c < g.Cols; c++ {
+					if v := g.At(c, r); !math.IsNaN(v) {
+						sum += v
+						n++
+					}
+				}
+			}
+			if n > 0 {
+				o.Set(col, row, sum/(float64)(n))
+			}
+		}
+	}
+	return o
+}
+
+// metadata returns the GDAL metadata recording the options' items, and their scale and offset, or "" if there are
+// none.
+func metadata(opts *Options) string {
+	if len(opts.Metadata) == 0 && !(opts.Type == Int16 && (opts.Scale != 1 || opts.Offset != 0)) {
+		return ""
+	}
+	names := make([]string, 0, len(opts.Metadata))
+	for name := range opts.Metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	item := func(name, attrs, value string) {
+		sb.WriteString(`  <Item name="`)
+		xml.EscapeText(&sb, []byte(name))
+		sb.WriteString(`"` + attrs + ">")
+		xml.EscapeText(&sb, []byte(value))
+		sb.WriteString("</Item>\n")
+	}
+	sb.WriteString("<GDALMetadata>\n")
+	for _, name := range names {
+		item(name, "", opts.Metadata[name])
+	}
+	if opts.Type == Int16 && (opts.Scale != 1 || opts.Offset != 0) {
+		item("OFFSET", ` sample="0" role="offset"`, strconv.FormatFloat(opts.Offset, 'g', -1, 64))
+		item("SCALE", ` sample="0" role="scale"`, strconv.FormatFloat(opts.Scale, 'g', -1, 64))
+	}
+	sb.WriteString("</GDALMetadata>")
+	return sb.String()
+}
+
+// formatNoData formats v as GDAL reads its nodata tag.
+func formatNoData(v float64) string {
+	if math.IsNaN(v) {
+		return "nan"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 32)
+}
+
+// georeference returns the fields that place g on the ground and name its coordinate reference system.
+func georeference(g *grid.Grid, crs *lassloot.CRS) []field {
+	ret := []field{
+		doubles(tagModelPixelScale, g.Resolution, g.Resolution, 0),
+		doubles(tagModelTiepoint, 0, 0, 0, g.MinX, g.MaxY, 0),
+	}
+
+	gkd := &las14.GeoKeyDirectory{KeyDirectoryVersion: 1, KeyRevision: 1}
+	if crs != nil && crs.GeoKeys != nil {
+		gkd.Keys = append(gkd.Keys, crs.GeoKeys.Keys...)
+		gkd.Doubles, gkd.ASCII = crs.GeoKeys.Doubles, crs.GeoKeys.ASCII
+	} else if crs != nil && crs.EPSG != 0 {
+		if crs.Geographic {
+			gkd.Keys = append(gkd.Keys,
+				las14.GeoKey{ID: las14.GTModelTypeGeoKey, ValueOffset: las14.ModelTypeGeographic},
+				las14.GeoKey{ID: las14.GeographicTypeGeoKey, Count: 1, ValueOffset: (uint16)(crs.EPSG)})
+		} else {
+			gkd.Keys = append(gkd.Keys,
+				las14.GeoKey{ID: las14.GTModelTypeGeoKey, ValueOffset: las14.ModelTypeProjected},
+				las14.GeoKey{ID: las14.ProjectedCSTypeGeoKey, Count: 1, ValueOffset: (uint16)(crs.EPSG)})
+		}
+		if crs.VerticalEPSG != 0 {
+			gkd.Keys = append(gkd.Keys,
+				las14.GeoKey{ID: las14.VerticalCSTypeGeoKey, Count: 1, ValueOffset: (uint16)(crs.VerticalEPSG)})
+		}
+	}
+
+	// a raster's cells are areas, which lidar's geo keys don't say, and keys are sorted by id
+	keys := gkd.Keys[:0]
+	for _, k := range gkd.Keys {
+		if k.ID != las14.GTRasterTypeGeoKey {
+			if k.Count == 0 {
+				k.Count = 1
+			}
+			keys = append(keys, k)
+		}
+	}
+	gkd.Keys = append(keys, las14.GeoKey{ID: las14.GTRasterTypeGeoKey, Count: 1, ValueOffset: rasterPixelIsArea})
+	sort.SliceStable(gkd.Keys, func(i, j int) bool { return gkd.Keys[i].ID < gkd.Keys[j].ID })
+
+	directory, doubleParams, asciiParams := gkd.Encode()
+	f := field{tag: tagGeoKeyDirectory, typ: typeShort, count: (uint32)(len(directory) / 2), data: directory}
+	ret = append(ret, f)
+	if doubleParams != nil {
+		ret = append(ret, field{tag: tagGeoDoubleParams, typ: typeDouble, count: (uint32)(len(doubleParams) / 8),
+			data: doubleParams})
+	}
+	if asciiParams != nil {
+		ret = append(ret, field{tag: tagGeoASCIIParams, typ: typeASCII, count: (uint32)(len(asciiParams)),
+			data: asciiParams})
+	}
+	return ret
+}
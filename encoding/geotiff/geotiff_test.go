@@ -0,0 +1,365 @@
+package geotiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/grid"
+)
+
+// readIFDs returns the values of the fields of each directory of a little endian TIFF file, as uint64s for integers
+// and float64 bits for doubles, or the bytes of ASCII fields.
+func readIFDs(t *testing.T, b []byte) []map[uint16]interface{} {
+	t.Helper()
+	le := binary.LittleEndian
+	if string(b[:4]) != "II*\x00" {
+		t.Fatalf("header % x", b[:4])
+	}
+	var ret []map[uint16]interface{}
+	for off := le.Uint32(b[4:]); off != 0; {
+		n := (int)(le.Uint16(b[off:]))
+		fields := make(map[uint16]interface{})
+		last := -1
+		for i := 0; i < n; i++ {
+			e := b[(int)(off)+2+12*i:]
+			tag, typ, count := le.Uint16(e), le.Uint16(e[2:]), (int)(le.Uint32(e[4:]))
+			if (int)(tag) <= last {
+				t.Errorf("tag %d follows %d", tag, last)
+			}
+			last = (int)(tag)
+			size := map[uint16]int{typeASCII: 1, typeShort: 2, typeLong: 4, typeDouble: 8}[typ] * count
+			data := e[8:12]
+			if size > 4 {
+				at := le.Uint32(e[8:])
+				if at%2 != 0 {
+					t.Errorf("tag %d at odd offset %d", tag, at)
+				}
+				data = b[at : (int)(at)+size]
+			}
+			switch typ {
+			case typeASCII:
+				fields[tag] = string(data[:size])
+			default:
+				var v []uint64
+				for k := 0; k < count; k++ {
+					switch typ {
+					case typeShort:
+						v = append(v, (uint64)(le.Uint16(data[2*k:])))
+					case typeLong:
+						v = append(v, (uint64)(le.Uint32(data[4*k:])))
+					case typeDouble:
+						v = append(v, le.Uint64(data[8*k:]))
+					}
+				}
+				fields[tag] = v
+			}
+		}
+		ret = append(ret, fields)
+		off = le.Uint32(b[(int)(off)+2+12*n:])
+	}
+	return ret
+}
+
+func TestWrite(t *testing.T) {
+	err, g := grid.New(500000, 4100000, 2, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range g.Values {
+		g.Values[i] = (float64)(i) + 0.5
+	}
+	g.Values[4] = math.NaN()
+
+	var buf bytes.Buffer
+	crs := &lassloot.CRS{EPSG: 26910, VerticalEPSG: 5703}
+	if err := Write(&buf, g, Options{NoData: -9999, CRS: crs}); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	ifds := readIFDs(t, b)
+	if len(ifds) != 1 {
+		t.Fatalf("%d images", len(ifds))
+	}
+	f := ifds[0]
+	for tag, want := range map[uint16]uint64{
+		tagImageWidth: 3, tagImageLength: 2, tagBitsPerSample: 32, tagSampleFormat: sampleFormatFloat,
+		tagCompression: compressionNone, tagRowsPerStrip: 2,
+	} {
+		if v, ok := f[tag].([]uint64); !ok || len(v) != 1 || v[0] != want {
+			t.Errorf("tag %d is %v, want %d", tag, f[tag], want)
+		}
+	}
+	if f[tagGDALNoData] != "-9999\x00" {
+		t.Errorf("nodata %q", f[tagGDALNoData])
+	}
+	var tiepoint []float64
+	for _, v := range f[tagModelTiepoint].([]uint64) {
+		tiepoint = append(tiepoint, math.Float64frombits(v))
+	}
+	if len(tiepoint) != 6 || tiepoint[3] != 500000 || tiepoint[4] != 4100000 {
+		t.Errorf("tiepoint %v", tiepoint)
+	}
+	if scale := f[tagModelPixelScale].([]uint64); math.Float64frombits(scale[0]) != 2 {
+		t.Errorf("pixel scale %v", math.Float64frombits(scale[0]))
+	}
+
+	keys := f[tagGeoKeyDirectory].([]uint64)
+	want := []uint64{1, 1, 0, 4,
+		las14.GTModelTypeGeoKey, 0, 1, las14.ModelTypeProjected,
+		las14.GTRasterTypeGeoKey, 0, 1, rasterPixelIsArea,
+		las14.ProjectedCSTypeGeoKey, 0, 1, 26910,
+		las14.VerticalCSTypeGeoKey, 0, 1, 5703,
+	}
+	if len(keys) != len(want) {
+		t.Fatalf("geo keys %v", keys)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("geo keys %v, want %v", keys, want)
+		}
+	}
+
+	at := f[tagStripOffsets].([]uint64)[0]
+	if n := f[tagStripByteCounts].([]uint64)[0]; n != 24 || (int)(at+n) != len(b) {
+		t.Fatalf("strip of %d bytes at %d of %d", n, at, len(b))
+	}
+	for i, want := range []float32{0.5, 1.5, 2.5, 3.5, -9999, 5.5} {
+		if v := math.Float32frombits(binary.LittleEndian.Uint32(b[(int)(at)+4*i:])); v != want {
+			t.Errorf("pixel %d is %v, want %v", i, v, want)
+		}
+	}
+}
+
+// decompressLZW decodes TIFF's LZW, growing the code width one code early.
+func decompressLZW(t *testing.T, src []byte) []byte {
+	t.Helper()
+	var out []byte
+	var table [][]byte
+	reset := func() {
+		table = table[:0]
+		for i := 0; i < lzwFirst; i++ {
+			table = append(table, []byte{(byte)(i)})
+		}
+	}
+	reset()
+	width, pos, prev := (uint)(lzwMinWidth), (uint)(0), -1
+	for {
+		if pos+width > 8*(uint)(len(src)) {
+			t.Fatal("lzw data ends without an end code")
+		}
+		code := 0
+		for i := uint(0); i < width; i++ {
+			bit := (src[(pos+i)/8] >> (7 - (pos+i)%8)) & 1
+			code = code<<1 | (int)(bit)
+		}
+		pos += width
+		switch {
+		case code == lzwEOI:
+			return out
+		case code == lzwClear:
+			reset()
+			width, prev = lzwMinWidth, -1
+			continue
+		case prev < 0:
+			out = append(out, table[code]...)
+		default:
+			var entry []byte
+			if code < len(table) {
+				entry = table[code]
+			} else if code == len(table) {
+				entry = append(append([]byte{}, table[prev]...), table[prev][0])
+			} else {
+				t.Fatalf("lzw code %d past table of %d", code, len(table))
+			}
+			out = append(out, entry...)
+			table = append(table, append(append([]byte{}, table[prev]...), entry[0]))
+		}
+		prev = code
+		if len(table) >= 1<<width-1 && width < 12 {
+			width++
+		}
+	}
+}
+
+// readPixels decodes the pixels of the image described by f as float64s, undoing scale and offset.
+func readPixels(t *testing.T, b []byte, f map[uint16]interface{}, scale, offset float64) []float64 {
+	t.Helper()
+	one := func(tag uint16) int {
+		v, ok := f[tag].([]uint64)
+		if !ok {
+			return 0
+		}
+		return (int)(v[0])
+	}
+	cols, rows := one(tagImageWidth), one(tagImageLength)
+	size := one(tagBitsPerSample) / 8
+	width, height := one(tagTileWidth), one(tagTileLength)
+	offsets, counts := f[tagTileOffsets], f[tagTileByteCounts]
+	if width == 0 {
+		width, height = cols, one(tagRowsPerStrip)
+		offsets, counts = f[tagStripOffsets], f[tagStripByteCounts]
+	}
+	across := (cols + width - 1) / width
+
+	ret := make([]float64, cols*rows)
+	for k, at := range offsets.([]uint64) {
+		data := b[at : at+counts.([]uint64)[k]]
+		switch one(tagCompression) {
+		case compressionDeflate:
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data, err = io.ReadAll(r); err != nil {
+				t.Fatal(err)
+			}
+		case compressionLZW:
+			data = decompressLZW(t, data)
+		}
+		n := width * size
+		for r := 0; r*n < len(data); r++ {
+			line := data[r*n : (r+1)*n]
+			switch one(tagPredictor) {
+			case predictorHorizontal:
+				for i := 2; i < len(line); i += 2 {
+					v := binary.LittleEndian.Uint16(line[i:]) + binary.LittleEndian.Uint16(line[i-2:])
+					binary.LittleEndian.PutUint16(line[i:], v)
+				}
+			case predictorFloat:
+				for i := 1; i < len(line); i++ {
+					line[i] += line[i-1]
+				}
+				planes := append([]byte{}, line...)
+				for i := 0; i < width; i++ {
+					for j := 0; j < size; j++ {
+						line[i*size+size-1-j] = planes[j*width+i]
+					}
+				}
+			}
+			row := (k/across)*height + r
+			for i := 0; i < width; i++ {
+				col := (k%across)*width + i
+				if col >= cols || row >= rows {
+					continue
+				}
+				if size == 4 {
+					ret[row*cols+col] = (float64)(math.Float32frombits(binary.LittleEndian.Uint32(line[4*i:])))
+				} else {
+					ret[row*cols+col] = (float64)((int16)(binary.LittleEndian.Uint16(line[2*i:])))*scale + offset
+				}
+			}
+		}
+	}
+	return ret
+}
+
+func TestWriteCOG(t *testing.T) {
+	// a hill too large for one tile, with a hole
+	err, g := grid.New(0, 24, 1, 40, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			g.Set(col, row, 100+10*math.Sin((float64)(col)/7)*math.Cos((float64)(row)/5))
+		}
+	}
+	g.Set(3, 3, math.NaN())
+
+	for _, typ := range []SampleType{Float32, Int16} {
+		for _, c := range []Compression{Uncompressed, Deflate, LZW} {
+			for _, predictor := range []bool{false, true} {
+				opts := Options{NoData: -9999, Type: typ, Compression: c, Predictor: predictor, COG: true,
+					TileSize: 16, Metadata: map[string]string{"SURFACE": "dtm <ground>"}}
+				tolerance := 1e-4
+				if typ == Int16 {
+					opts.Scale, opts.Offset, tolerance = 0.01, 100, 0.005+1e-9
+				}
+				var buf bytes.Buffer
+				if err := Write(&buf, g, opts); err != nil {
+					t.Fatal(err)
+				}
+				b := buf.Bytes()
+				ifds := readIFDs(t, b)
+				if len(ifds) != 3 {
+					t.Fatalf("%v %v: %d images, want 40 by 24 and 2 overviews", typ, c, len(ifds))
+				}
+
+				pixels := readPixels(t, b, ifds[0], opts.Scale, opts.Offset)
+				for i, want := range g.Values {
+					got := pixels[i]
+					if math.IsNaN(want) {
+						want = -9999
+						if typ == Int16 {
+							got = (got - opts.Offset) / opts.Scale
+						}
+					}
+					if math.Abs(got-want) > tolerance {
+						t.Fatalf("%v %v %v: pixel %d is %v, want %v", typ, c, predictor, i, got, want)
+					}
+				}
+
+				// the smallest overview is 10 by 6, each pixel the mean of 4 by 4 beneath it
+				f := ifds[2]
+				if f[tagImageWidth].([]uint64)[0] != 10 || f[tagNewSubfileType].([]uint64)[0] != subfileReduced {
+					t.Errorf("overview %v wide, subfile type %v", f[tagImageWidth], f[tagNewSubfileType])
+				}
+				var sum float64
+				for row := 4; row < 8; row++ {
+					for col := 4; col < 8; col++ {
+						sum += g.At(col, row)
+					}
+				}
+				if v := readPixels(t, b, f, opts.Scale, opts.Offset)[11]; math.Abs(v-sum/16) > tolerance {
+					t.Errorf("%v %v: overview pixel %v, want %v", typ, c, v, sum/16)
+				}
+
+				// directories first, then tiles, the smallest overview's first
+				first := ifds[2][tagTileOffsets].([]uint64)[0]
+				for _, at := range ifds[0][tagTileOffsets].([]uint64) {
+					if at < first {
+						t.Errorf("tile at %d before the overviews' at %d", at, first)
+					}
+				}
+				if !strings.HasPrefix(string(b[8:]), "GDAL_STRUCTURAL_METADATA_SIZE=") {
+					t.Error("no structural metadata")
+				}
+				md, _ := ifds[0][tagGDALMetadata].(string)
+				if !strings.Contains(md, `<Item name="SURFACE">dtm &lt;ground&gt;</Item>`) ||
+					typ == Int16 && !strings.Contains(md, `<Item name="SCALE" sample="0" role="scale">0.01</Item>`) {
+					t.Errorf("metadata %q", md)
+				}
+			}
+		}
+	}
+
+	if err := Write(io.Discard, g, Options{TileSize: 20}); err == nil {
+		t.Error("wrote tiles 20 wide")
+	}
+	if err := Write(io.Discard, g, Options{Type: Int16, NoData: 0.5}); err == nil {
+		t.Error("wrote nodata of 0.5 as int16")
+	}
+	if err := Write(io.Discard, g, Options{Type: Int16, Scale: 0.001}); err == nil {
+		t.Error("wrote 110 at scale 0.001 as int16")
+	}
+}
+
+func TestCompressLZW(t *testing.T) {
+	// enough varied data to fill the table several times over
+	var src []byte
+	for i := 0; i < 200000; i++ {
+		src = append(src, (byte)(i*i>>7^i>>3))
+	}
+	for _, data := range [][]byte{nil, {7}, bytes.Repeat([]byte{1, 2}, 5000), src} {
+		if got := decompressLZW(t, compressLZW(data)); !bytes.Equal(got, data) {
+			t.Fatalf("%d bytes decompressed to %d", len(data), len(got))
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package geotiff
+
+// TIFF's LZW differs from GIF's, which compress/lzw implements: codes are packed most significant bit first, and the
+// code width grows one code early.
+const (
+	lzwClear    = 256
+	lzwEOI      = 257
+	lzwFirst    = 258
+	lzwMinWidth = 9
+
+	// lzwFull is the next code at which the table is cleared, as libtiff does, rather than growing past 12 bits
+	lzwFull = 4094
+)
+
+// lzwWriter packs codes most significant bit first.
+type lzwWriter struct {
+	out   []byte
+	bits  uint32
+	nbits uint
+}
+
+func (w *lzwWriter) write(code int, width uint) {
+	w.bits = w.bits<<width | (uint32)(code)
+	w.nbits += width
+	for w.nbits >= 8 {
+		w.nbits -= 8
+		w.out = append(w.out, (byte)(w.bits>>w.nbits))
+	}
+	w.bits &= 1<<w.nbits - 1
+}
+
+// compressLZW compresses src with TIFF's LZW.
+func compressLZW(src []byte) []byte {
+	w := &lzwWriter{out: make([]byte, 0, len(src)/2+16)}
+	width := (uint)(lzwMinWidth)
+	w.write(lzwClear, width)
+
+	table := make(map[uint32]int, lzwFull)
+	next := lzwFirst
+	// grow adds a code to the table, or clears it when full, as the decoder will once it reads the code just written
+	grow := func() {
+		next++
+		if next == lzwFull {
+			w.write(lzwClear, width)
+			for k := range table {
+				delete(table, k)
+			}
+			next, width = lzwFirst, lzwMinWidth
+		} else if next > 1<<width-1 {
+			width++
+		}
+	}
+
+	if len(src) > 0 {
+		prefix := (int)(src[0])
+		for _, c := range src[1:] {
+			key := (uint32)(prefix)<<8 | (uint32)(c)
+			if code, ok := table[key]; ok {
+				prefix = code
+				continue
+			}
+			w.write(prefix, width)
+			table[key] = next
+			grow()
+			prefix = (int)(c)
+		}
+		w.write(prefix, width)
+		grow()
+	}
+	w.write(lzwEOI, width)
+	if w.nbits > 0 {
+		w.out = append(w.out, (byte)(w.bits<<(8-w.nbits)))
+	}
+	return w.out
+}
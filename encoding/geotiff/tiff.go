@@ -0,0 +1,140 @@
+package geotiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// field is a TIFF directory entry.  Data holds its values, little endian.
+type field struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func shorts(tag uint16, v ...uint16) field {
+	f := field{tag: tag, typ: typeShort, count: (uint32)(len(v))}
+	for _, s := range v {
+		f.data = wire.AppendUint16(f.data, s)
+	}
+	return f
+}
+
+func longs(tag uint16, v ...uint32) field {
+	f := field{tag: tag, typ: typeLong, count: (uint32)(len(v))}
+	for _, l := range v {
+		f.data = wire.AppendUint32(f.data, l)
+	}
+	return f
+}
+
+func doubles(tag uint16, v ...float64) field {
+	f := field{tag: tag, typ: typeDouble, count: (uint32)(len(v))}
+	for _, d := range v {
+		f.data = wire.AppendUint64(f.data, math.Float64bits(d))
+	}
+	return f
+}
+
+func ascii(tag uint16, s string) field {
+	return field{tag: tag, typ: typeASCII, count: (uint32)(len(s) + 1), data: append([]byte(s), 0)}
+}
+
+// image is a TIFF image: its directory entries, and the blocks of pixel data, strips or tiles, that offsetsTag and
+// countsTag locate.
+type image struct {
+	fields                []field
+	blocks                [][]byte
+	offsetsTag, countsTag uint16
+}
+
+// writeTIFF writes images to w as a little endian TIFF file: the header, then ghost, then each image's directory, then
+// the images' pixel data, the last image's first.  That is the layout of a cloud optimized GeoTIFF, whose readers
+// find every directory in the first bytes of the file and its overviews' data, smallest first, before its own.
+func writeTIFF(w io.Writer, ghost []byte, images []*image) error {
+	// the offsets of the blocks aren't known until the directories are laid out, but their sizes are
+	for _, img := range images {
+		counts := make([]uint32, len(img.blocks))
+		for i, b := range img.blocks {
+			counts[i] = (uint32)(len(b))
+		}
+		img.fields = append(img.fields, longs(img.offsetsTag, counts...), longs(img.countsTag, counts...))
+		sort.SliceStable(img.fields, func(i, j int) bool { return img.fields[i].tag < img.fields[j].tag })
+	}
+
+	// directories, each followed by its values too large to fit in their entries, word aligned
+	offset := (int64)(8 + len(ghost) + len(ghost)%2)
+	directories := make([]int64, len(images))
+	for i, img := range images {
+		directories[i] = offset
+		offset += 2 + 12*(int64)(len(img.fields)) + 4
+		for _, f := range img.fields {
+			if len(f.data) > 4 {
+				offset += (int64)(len(f.data) + len(f.data)%2)
+			}
+		}
+	}
+	for i := len(images) - 1; i >= 0; i-- {
+		img := images[i]
+		var offsets []uint32
+		for _, b := range img.blocks {
+			offsets = append(offsets, (uint32)(offset))
+			offset += (int64)(len(b))
+		}
+		for k, f := range img.fields {
+			if f.tag == img.offsetsTag {
+				img.fields[k] = longs(f.tag, offsets...)
+			}
+		}
+	}
+	if offset > math.MaxUint32 {
+		return fmt.Errorf("%d bytes are too many for a tiff file", offset)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write([]byte{'I', 'I', 42, 0})
+	bw.Write(wire.AppendUint32(nil, (uint32)(directories[0])))
+	bw.Write(ghost)
+	if len(ghost)%2 != 0 {
+		bw.WriteByte(0)
+	}
+	for i, img := range images {
+		external := directories[i] + 2 + 12*(int64)(len(img.fields)) + 4
+		var entries, values []byte
+		entries = wire.AppendUint16(entries, (uint16)(len(img.fields)))
+		for _, f := range img.fields {
+			entries = wire.AppendUint16(entries, f.tag)
+			entries = wire.AppendUint16(entries, f.typ)
+			entries = wire.AppendUint32(entries, f.count)
+			if len(f.data) <= 4 {
+				var inline [4]byte
+				copy(inline[:], f.data)
+				entries = append(entries, inline[:]...)
+				continue
+			}
+			entries = wire.AppendUint32(entries, (uint32)(external+(int64)(len(values))))
+			values = append(values, f.data...)
+			if len(f.data)%2 != 0 {
+				values = append(values, 0)
+			}
+		}
+		next := (uint32)(0)
+		if i+1 < len(images) {
+			next = (uint32)(directories[i+1])
+		}
+		bw.Write(wire.AppendUint32(entries, next))
+		bw.Write(values)
+	}
+	for i := len(images) - 1; i >= 0; i-- {
+		for _, b := range images[i].blocks {
+			bw.Write(b)
+		}
+	}
+	return bw.Flush()
+}
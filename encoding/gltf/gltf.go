@@ -0,0 +1,291 @@
+// Package gltf writes triangle meshes as binary glTF 2.0 (GLB) files, which Blender, three.js and most game engines
+// import directly.
+//
+// A GLB file holds a JSON chunk describing the scene and a binary chunk holding the mesh's vertex attributes and
+// indices, and any texture, as the views into it that the JSON describes.  glTF coordinates are meters with Y up.
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+const (
+	magic   = 0x46546c67 // "glTF"
+	version = 2
+
+	chunkJSON = 0x4e4f534a // "JSON"
+	chunkBIN  = 0x004e4942 // "BIN\0"
+
+	componentFloat  = 5126
+	componentUint32 = 5125
+
+	targetArrayBuffer        = 34962
+	targetElementArrayBuffer = 34963
+
+	filterLinear = 9729
+	wrapClamp    = 33071
+)
+
+// Options control what is written with a mesh and where it sits.
+type Options struct {
+	// Up is the axis elevation is written along.  glTF itself is Y up, and importers convert from it.
+	Up lassloot.UpAxis
+
+	// Origin is taken off the POSITION accessor, whose components glTF only allows as 32-bit floats.  The scene's
+	// extras keep it as origin, so the mesh can be put back where it belongs.
+	Origin [3]float64
+
+	// Normals and UVs, if set, hold a normal, in the mesh's axes, and texture coordinates for each vertex.  UVs run
+	// from the bottom left of the texture, as OBJ's do; they are flipped for glTF, which runs them from the top left.
+	Normals [][3]float64
+	UVs     [][2]float64
+
+	// Texture, if set, holds a PNG image that UVs map onto the mesh.
+	Texture []byte
+
+	// Name names the mesh and its node.
+	Name string
+
+	// Extras are recorded in the scene's extras alongside the origin, e.g. the coordinate reference system.
+	Extras map[string]interface{}
+}
+
+type document struct {
+	Asset       asset        `json:"asset"`
+	Scene       int          `json:"scene"`
+	Scenes      []scene      `json:"scenes"`
+	Nodes       []node       `json:"nodes"`
+	Meshes      []mesh       `json:"meshes"`
+	Materials   []material   `json:"materials"`
+	Textures    []texture    `json:"textures,omitempty"`
+	Images      []gltfImage  `json:"images,omitempty"`
+	Samplers    []sampler    `json:"samplers,omitempty"`
+	Accessors   []accessor   `json:"accessors"`
+	BufferViews []bufferView `json:"bufferViews"`
+	Buffers     []buffer     `json:"buffers"`
+}
+
+type asset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator"`
+}
+
+type scene struct {
+	Nodes  []int                  `json:"nodes"`
+	Extras map[string]interface{} `json:"extras,omitempty"`
+}
+
+type node struct {
+	Name string `json:"name,omitempty"`
+	Mesh int    `json:"mesh"`
+}
+
+type mesh struct {
+	Name       string      `json:"name,omitempty"`
+	Primitives []primitive `json:"primitives"`
+}
+
+type primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    int            `json:"indices"`
+	Material   int            `json:"material"`
+}
+
+type material struct {
+	Name        string `json:"name,omitempty"`
+	PBR         pbr    `json:"pbrMetallicRoughness"`
+	DoubleSided bool   `json:"doubleSided"`
+}
+
+type pbr struct {
+	BaseColorFactor  []float64    `json:"baseColorFactor,omitempty"`
+	BaseColorTexture *textureInfo `json:"baseColorTexture,omitempty"`
+	MetallicFactor   float64      `json:"metallicFactor"`
+	RoughnessFactor  float64      `json:"roughnessFactor"`
+}
+
+type textureInfo struct {
+	Index int `json:"index"`
+}
+
+type texture struct {
+	Source  int `json:"source"`
+	Sampler int `json:"sampler"`
+}
+
+type gltfImage struct {
+	BufferView int    `json:"bufferView"`
+	MimeType   string `json:"mimeType"`
+}
+
+type sampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type accessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type bufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type buffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+// WriteGLB writes the triangles of a mesh over vertices to w as a GLB file with a single node.  Triangles should list
+// their vertices counterclockwise seen from the front.
+func WriteGLB(w io.Writer, vertices [][3]float64, triangles [][3]int32, opts Options) error {
+	if opts.Normals != nil && len(opts.Normals) != len(vertices) {
+		return fmt.Errorf("%d normals for %d vertices", len(opts.Normals), len(vertices))
+	}
+	if opts.UVs != nil && len(opts.UVs) != len(vertices) {
+		return fmt.Errorf("%d texture coordinates for %d vertices", len(opts.UVs), len(vertices))
+	}
+	if len(vertices) == 0 || len(triangles) == 0 {
+		return fmt.Errorf("mesh is empty")
+	}
+	for i, tri := range triangles {
+		for _, v := range tri {
+			if v < 0 || (int)(v) >= len(vertices) {
+				return fmt.Errorf("triangle %d refers to vertex %d of %d", i, v, len(vertices))
+			}
+		}
+	}
+
+	doc := &document{
+		Asset:  asset{Version: "2.0", Generator: lassloot.GeneratingSoftware},
+		Scenes: []scene{{Nodes: []int{0}, Extras: map[string]interface{}{"origin": opts.Origin, "up": opts.Up.String()}}},
+		Nodes:  []node{{Name: opts.Name, Mesh: 0}},
+	}
+	for k, v := range opts.Extras {
+		doc.Scenes[0].Extras[k] = v
+	}
+
+	var bin []byte
+	view := func(data []byte, target int) int {
+		for len(bin)%4 != 0 {
+			bin = append(bin, 0)
+		}
+		doc.BufferViews = append(doc.BufferViews, bufferView{ByteOffset: len(bin), ByteLength: len(data), Target: target})
+		bin = append(bin, data...)
+		return len(doc.BufferViews) - 1
+	}
+	floats := func(values []float32, n int, kind string, bounds bool) int {
+		data := make([]byte, 4*len(values))
+		for i, v := range values {
+			binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+		}
+		a := accessor{BufferView: view(data, targetArrayBuffer), ComponentType: componentFloat, Count: len(values) / n, Type: kind}
+		if bounds {
+			// positions must carry their bounds
+			a.Min, a.Max = append([]float32(nil), values[:n]...), append([]float32(nil), values[:n]...)
+			for i, v := range values {
+				if v < a.Min[i%n] {
+					a.Min[i%n] = v
+				}
+				if v > a.Max[i%n] {
+					a.Max[i%n] = v
+				}
+			}
+		}
+		doc.Accessors = append(doc.Accessors, a)
+		return len(doc.Accessors) - 1
+	}
+
+	p := primitive{Attributes: make(map[string]int)}
+	positions := make([]float32, 0, 3*len(vertices))
+	for _, v := range vertices {
+		x, y, z := opts.Up.Apply(v[0]-opts.Origin[0], v[1]-opts.Origin[1], v[2]-opts.Origin[2])
+		positions = append(positions, (float32)(x), (float32)(y), (float32)(z))
+	}
+	p.Attributes["POSITION"] = floats(positions, 3, "VEC3", true)
+	if opts.Normals != nil {
+		normals := make([]float32, 0, 3*len(vertices))
+		for _, n := range opts.Normals {
+			x, y, z := opts.Up.Apply(n[0], n[1], n[2])
+			normals = append(normals, (float32)(x), (float32)(y), (float32)(z))
+		}
+		p.Attributes["NORMAL"] = floats(normals, 3, "VEC3", false)
+	}
+	if opts.UVs != nil {
+		uvs := make([]float32, 0, 2*len(vertices))
+		for _, uv := range opts.UVs {
+			uvs = append(uvs, (float32)(uv[0]), (float32)(1-uv[1]))
+		}
+		p.Attributes["TEXCOORD_0"] = floats(uvs, 2, "VEC2", false)
+	}
+
+	indices := make([]byte, 0, 12*len(triangles))
+	for _, tri := range triangles {
+		for _, v := range tri {
+			indices = wire.AppendUint32(indices, (uint32)(v))
+		}
+	}
+	doc.Accessors = append(doc.Accessors, accessor{
+		BufferView: view(indices, targetElementArrayBuffer), ComponentType: componentUint32, Count: 3 * len(triangles),
+		Type: "SCALAR",
+	})
+	p.Indices = len(doc.Accessors) - 1
+
+	m := material{Name: opts.Name, DoubleSided: true, PBR: pbr{MetallicFactor: 0, RoughnessFactor: 1}}
+	if opts.Texture != nil && opts.UVs != nil {
+		doc.Images = []gltfImage{{BufferView: view(opts.Texture, 0), MimeType: "image/png"}}
+		doc.Samplers = []sampler{{MagFilter: filterLinear, MinFilter: filterLinear, WrapS: wrapClamp, WrapT: wrapClamp}}
+		doc.Textures = []texture{{Source: 0, Sampler: 0}}
+		m.PBR.BaseColorTexture = &textureInfo{Index: 0}
+	} else {
+		m.PBR.BaseColorFactor = []float64{0.8, 0.8, 0.8, 1}
+	}
+	doc.Materials = []material{m}
+	doc.Meshes = []mesh{{Name: opts.Name, Primitives: []primitive{p}}}
+
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+	doc.Buffers = []buffer{{ByteLength: len(bin)}}
+
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	// the json chunk is padded with spaces to a multiple of four bytes
+	js = append(js, bytes.Repeat([]byte{' '}, (4-len(js)%4)%4)...)
+
+	total := 12 + 8 + len(js) + 8 + len(bin)
+	if (uint64)(total) > math.MaxUint32 {
+		return fmt.Errorf("%d bytes are too many for a glb file", total)
+	}
+	header := make([]byte, 0, 20)
+	header = wire.AppendUint32(header, magic)
+	header = wire.AppendUint32(header, version)
+	header = wire.AppendUint32(header, (uint32)(total))
+	header = wire.AppendUint32(header, (uint32)(len(js)))
+	header = wire.AppendUint32(header, chunkJSON)
+	for _, b := range [][]byte{header, js, wire.AppendUint32(wire.AppendUint32(nil, (uint32)(len(bin))), chunkBIN), bin} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+)
+
+func TestWriteGLB(t *testing.T) {
+	vertices := [][3]float64{{500000, 4100000, 100}, {500010, 4100000, 101}, {500000, 4100010, 102.5}}
+	triangles := [][3]int32{{0, 1, 2}}
+	texture := []byte("\x89PNG not really")
+	opts := Options{
+		Up:      lassloot.UpY,
+		Origin:  [3]float64{500000, 4100000, 100},
+		Normals: [][3]float64{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+		UVs:     [][2]float64{{0, 0}, {1, 0}, {0, 1}},
+		Texture: texture,
+		Name:    "hill",
+		Extras:  map[string]interface{}{"crs": "EPSG:32610"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGLB(&buf, vertices, triangles, opts); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	u32 := func(off int) uint32 { return binary.LittleEndian.Uint32(b[off:]) }
+	if u32(0) != magic || u32(4) != version || (int)(u32(8)) != len(b) {
+		t.Fatalf("bad header % x", b[:12])
+	}
+	jsonLength := (int)(u32(12))
+	if u32(16) != chunkJSON || jsonLength%4 != 0 {
+		t.Fatalf("bad json chunk of %d bytes", jsonLength)
+	}
+	binOffset := 20 + jsonLength
+	if u32(binOffset+4) != chunkBIN || binOffset+8+(int)(u32(binOffset)) != len(b) {
+		t.Fatal("bad binary chunk")
+	}
+	bin := b[binOffset+8:]
+
+	var doc document
+	if err := json.Unmarshal(b[20:binOffset], &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Asset.Version != "2.0" || doc.Scenes[0].Extras["crs"] != "EPSG:32610" || doc.Scenes[0].Extras["up"] != "y" {
+		t.Errorf("scene %+v of %+v", doc.Scenes[0], doc.Asset)
+	}
+	if doc.Buffers[0].ByteLength != len(bin) {
+		t.Errorf("buffer of %d bytes in a chunk of %d", doc.Buffers[0].ByteLength, len(bin))
+	}
+
+	read := func(attribute string) []float32 {
+		a := doc.Accessors[doc.Meshes[0].Primitives[0].Attributes[attribute]]
+		v := doc.BufferViews[a.BufferView]
+		if v.ByteOffset%4 != 0 {
+			t.Errorf("%s view at %d", attribute, v.ByteOffset)
+		}
+		ret := make([]float32, v.ByteLength/4)
+		for i := range ret {
+			ret[i] = math.Float32frombits(binary.LittleEndian.Uint32(bin[v.ByteOffset+4*i:]))
+		}
+		return ret
+	}
+	for attribute, want := range map[string][]float32{
+		"POSITION":   {0, 0, 0, 10, 1, 0, 0, 2.5, -10},
+		"NORMAL":     {0, 1, 0, 0, 1, 0, 0, 1, 0},
+		"TEXCOORD_0": {0, 1, 1, 1, 0, 0},
+	} {
+		got := read(attribute)
+		if len(got) != len(want) {
+			t.Fatalf("%s is %v, want %v", attribute, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("%s is %v, want %v", attribute, got, want)
+			}
+		}
+	}
+	position := doc.Accessors[doc.Meshes[0].Primitives[0].Attributes["POSITION"]]
+	if position.Min[0] != 0 || position.Min[1] != 0 || position.Min[2] != -10 || position.Max[0] != 10 ||
+		position.Max[1] != 2.5 || position.Max[2] != 0 {
+		t.Errorf("positions bounded by %v and %v", position.Min, position.Max)
+	}
+
+	indices := doc.Accessors[doc.Meshes[0].Primitives[0].Indices]
+	if indices.Count != 3 || indices.ComponentType != componentUint32 {
+		t.Errorf("indices %+v", indices)
+	}
+	image := doc.BufferViews[doc.Images[0].BufferView]
+	if !bytes.Equal(bin[image.ByteOffset:image.ByteOffset+image.ByteLength], texture) {
+		t.Error("texture not embedded")
+	}
+	if doc.Materials[0].PBR.BaseColorTexture == nil {
+		t.Error("material is untextured")
+	}
+
+	buf.Reset()
+	if err := WriteGLB(&buf, vertices, [][3]int32{{0, 1, 3}}, Options{}); err == nil {
+		t.Error("triangle beyond the vertices written")
+	}
+	if err := WriteGLB(&buf, vertices, triangles, Options{Normals: opts.Normals[:1]}); err == nil {
+		t.Error("too few normals written")
+	}
+}
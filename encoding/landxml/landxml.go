@@ -0,0 +1,266 @@
+// Package landxml writes triangulated surfaces as LandXML 1.2 files, which civil design software such as Civil 3D,
+// OpenRoads and 12d Model imports as a TIN surface.
+//
+// A surface's definition lists its points and the faces over them.  Its source data may add the boundaries that trim
+// it and the breaklines that its faces should follow, which the importing software can use to rebuild it.  LandXML
+// writes positions northing first: as y, x and z.
+package landxml
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// Namespace is the XML namespace of LandXML 1.2.
+const Namespace = "http://www.landxml.org/schema/LandXML-1.2"
+
+// Unit is the linear unit of a surface's coordinates.
+type Unit int
+
+const (
+	Meter Unit = iota
+	Foot
+	USSurveyFoot
+)
+
+var unitNames = [...]string{"meter", "foot", "USSurveyFoot"}
+
+func (u Unit) String() string {
+	if u < 0 || (int)(u) >= len(unitNames) {
+		return fmt.Sprintf("Unit(%d)", u)
+	}
+	return unitNames[u]
+}
+
+// UnitOf returns the unit metersPerUnit meters long.  Units other than the foot and US survey foot, and unknown ones,
+// are taken as meters.
+func UnitOf(metersPerUnit float64) Unit {
+	switch {
+	case math.Abs(metersPerUnit-0.3048) < 1e-9:
+		return Foot
+	case math.Abs(metersPerUnit-1200.0/3937.0) < 1e-9:
+		return USSurveyFoot
+	}
+	return Meter
+}
+
+// CoordinateSystem identifies the coordinate reference system of a surface.
+type CoordinateSystem struct {
+	Name string
+
+	// EPSG is the EPSG code of the horizontal system, or zero.
+	EPSG int
+
+	// WKT, if set, defines the system as OGC well known text.
+	WKT string
+
+	VerticalDatum string
+}
+
+// Options describe a surface and what is written with it.
+type Options struct {
+	// Name names the surface, and Description describes it.
+	Name        string
+	Description string
+
+	Unit             Unit
+	CoordinateSystem *CoordinateSystem
+
+	// Boundaries hold closed polylines that trim the surface to their insides, e.g. a site's boundary.
+	Boundaries [][][3]float64
+
+	// Breaklines hold polylines the surface's faces should follow, e.g. the tops and toes of slopes.
+	Breaklines [][][3]float64
+
+	// Time is recorded as when the file was made.  Zero records the present.
+	Time time.Time
+}
+
+// Write writes the triangles over vertices, counterclockwise seen from above, to w as a LandXML file holding a single
+// TIN surface.  Only the vertices some triangle uses are written.
+func Write(w io.Writer, vertices [][3]float64, triangles [][3]int32, opts Options) error {
+	if len(triangles) == 0 {
+		return fmt.Errorf("surface has no faces")
+	}
+	for i, tri := range triangles {
+		for _, v := range tri {
+			if v < 0 || (int)(v) >= len(vertices) {
+				return fmt.Errorf("triangle %d refers to vertex %d of %d", i, v, len(vertices))
+			}
+		}
+	}
+	for _, lines := range [][][][3]float64{opts.Boundaries, opts.Breaklines} {
+		for i, l := range lines {
+			if len(l) < 2 {
+				return fmt.Errorf("line %d has %d points", i, len(l))
+			}
+		}
+	}
+
+	// points are numbered from 1 in the order faces first use them
+	ids := make([]int32, len(vertices))
+	var used []int32
+	for _, tri := range triangles {
+		for _, v := range tri {
+			if ids[v] == 0 {
+				used = append(used, v)
+				ids[v] = (int32)(len(used))
+			}
+		}
+	}
+
+	var area2D, area3D float64
+	elevMin, elevMax := math.Inf(1), math.Inf(-1)
+	for _, tri := range triangles {
+		a, b, c := vertices[tri[0]], vertices[tri[1]], vertices[tri[2]]
+		u := [3]float64{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+		v := [3]float64{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+		n := [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+		area2D += math.Abs(n[2]) / 2
+		area3D += math.Sqrt(n[0]*n[0]+n[1]*n[1]+n[2]*n[2]) / 2
+		for _, p := range [3][3]float64{a, b, c} {
+			elevMin, elevMax = math.Min(elevMin, p[2]), math.Max(elevMax, p[2])
+		}
+	}
+
+	t := opts.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	name := opts.Name
+	if name == "" {
+		name = "surface"
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	fmt.Fprintf(bw, `<LandXML xmlns="%s" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" `, Namespace)
+	fmt.Fprintf(bw, `xsi:schemaLocation="%s %s/LandXML-1.2.xsd" version="1.2" date="%s" time="%s">`+"\n",
+		Namespace, Namespace, t.Format("2006-01-02"), t.Format("15:04:05"))
+
+	bw.WriteString("  <Units>\n")
+	if opts.Unit == Meter {
+		bw.WriteString(`    <Metric areaUnit="squareMeter" linearUnit="meter" volumeUnit="cubicMeter" ` +
+			`temperatureUnit="celsius" pressureUnit="HPA" angularUnit="decimal degrees" ` +
+			`directionUnit="decimal degrees"/>` + "\n")
+	} else {
+		fmt.Fprintf(bw, `    <Imperial areaUnit="squareFoot" linearUnit="%s" volumeUnit="cubicFeet" `+
+			`temperatureUnit="fahrenheit" pressureUnit="inHG" angularUnit="decimal degrees" `+
+			`directionUnit="decimal degrees"/>`+"\n", opts.Unit)
+	}
+	bw.WriteString("  </Units>\n")
+
+	if cs := opts.CoordinateSystem; cs != nil {
+		bw.WriteString("  <CoordinateSystem")
+		attr(bw, "name", cs.Name)
+		if cs.EPSG != 0 {
+			attr(bw, "epsgCode", strconv.Itoa(cs.EPSG))
+		}
+		attr(bw, "ogcWktCode", cs.WKT)
+		attr(bw, "verticalDatum", cs.VerticalDatum)
+		bw.WriteString("/>\n")
+	}
+	fmt.Fprintf(bw, `  <Application name="%s" manufacturer="%s"/>`+"\n", lassloot.GeneratingSoftware,
+		lassloot.GeneratingSoftware)
+
+	bw.WriteString("  <Surfaces>\n    <Surface")
+	attr(bw, "name", name)
+	attr(bw, "desc", opts.Description)
+	bw.WriteString(">\n")
+
+	if len(opts.Boundaries) > 0 || len(opts.Breaklines) > 0 {
+		bw.WriteString("      <SourceData>\n")
+		if len(opts.Boundaries) > 0 {
+			bw.WriteString("        <Boundaries>\n")
+			for i, b := range opts.Boundaries {
+				fmt.Fprintf(bw, `          <Boundary name="boundary %d" bndType="outer" edgeTrim="true">`, i+1)
+				pntList(bw, b, true)
+				bw.WriteString("</Boundary>\n")
+			}
+			bw.WriteString("        </Boundaries>\n")
+		}
+		if len(opts.Breaklines) > 0 {
+			bw.WriteString("        <Breaklines>\n")
+			for i, b := range opts.Breaklines {
+				fmt.Fprintf(bw, `          <Breakline name="breakline %d" brkType="standard">`, i+1)
+				pntList(bw, b, false)
+				bw.WriteString("</Breakline>\n")
+			}
+			bw.WriteString("        </Breaklines>\n")
+		}
+		bw.WriteString("      </SourceData>\n")
+	}
+
+	bw.WriteString(`      <Definition surfType="TIN"`)
+	attr(bw, "area2DSurf", wire.FormatFloat(area2D))
+	attr(bw, "area3DSurf", wire.FormatFloat(area3D))
+	attr(bw, "elevMax", wire.FormatFloat(elevMax))
+	attr(bw, "elevMin", wire.FormatFloat(elevMin))
+	bw.WriteString(">\n        <Pnts>\n")
+	buf := make([]byte, 0, 128)
+	for i, v := range used {
+		p := vertices[v]
+		buf = append(buf[:0], `          <P id="`...)
+		buf = strconv.AppendInt(buf, (int64)(i+1), 10)
+		buf = append(buf, `">`...)
+		buf = appendPosition(buf, p)
+		buf = append(buf, "</P>\n"...)
+		bw.Write(buf)
+	}
+	bw.WriteString("        </Pnts>\n        <Faces>\n")
+	for _, tri := range triangles {
+		buf = append(buf[:0], "          <F>"...)
+		for k, v := range tri {
+			if k > 0 {
+				buf = append(buf, ' ')
+			}
+			buf = strconv.AppendInt(buf, (int64)(ids[v]), 10)
+		}
+		buf = append(buf, "</F>\n"...)
+		bw.Write(buf)
+	}
+	bw.WriteString("        </Faces>\n      </Definition>\n    </Surface>\n  </Surfaces>\n</LandXML>\n")
+	return bw.Flush()
+}
+
+// attr writes the attribute name if value is set.
+func attr(w *bufio.Writer, name string, value string) {
+	if value == "" {
+		return
+	}
+	w.WriteString(" " + name + `="`)
+	xml.EscapeText(w, []byte(value))
+	w.WriteString(`"`)
+}
+
+// pntList writes the points of a line as a PntList3D, closing it if closed and it doesn't already end at its start.
+func pntList(w *bufio.Writer, points [][3]float64, closed bool) {
+	if closed && points[0] != points[len(points)-1] {
+		points = append(points[:len(points):len(points)], points[0])
+	}
+	buf := append(make([]byte, 0, 64*len(points)), "<PntList3D>"...)
+	for i, p := range points {
+		if i > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = appendPosition(buf, p)
+	}
+	w.Write(append(buf, "</PntList3D>"...))
+}
+
+// appendPosition appends p northing first, as LandXML orders positions.
+func appendPosition(b []byte, p [3]float64) []byte {
+	b = strconv.AppendFloat(b, p[1], 'f', -1, 64)
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, p[0], 'f', -1, 64)
+	b = append(b, ' ')
+	return strconv.AppendFloat(b, p[2], 'f', -1, 64)
+}
@@ -0,0 +1,122 @@
+package landxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+type document struct {
+	XMLName xml.Name `xml:"LandXML"`
+	Date    string   `xml:"date,attr"`
+	Units   struct {
+		Metric *struct {
+			LinearUnit string `xml:"linearUnit,attr"`
+		}
+		Imperial *struct {
+			LinearUnit string `xml:"linearUnit,attr"`
+		}
+	}
+	CoordinateSystem struct {
+		Name     string `xml:"name,attr"`
+		EPSGCode string `xml:"epsgCode,attr"`
+	}
+	Surface struct {
+		Name       string `xml:"name,attr"`
+		Boundaries []struct {
+			Type   string `xml:"bndType,attr"`
+			Points string `xml:"PntList3D"`
+		} `xml:"SourceData>Boundaries>Boundary"`
+		Breaklines []struct {
+			Points string `xml:"PntList3D"`
+		} `xml:"SourceData>Breaklines>Breakline"`
+		Definition struct {
+			Type   string  `xml:"surfType,attr"`
+			Area2D float64 `xml:"area2DSurf,attr"`
+			Area3D float64 `xml:"area3DSurf,attr"`
+			Max    float64 `xml:"elevMax,attr"`
+			Points []struct {
+				ID   int    `xml:"id,attr"`
+				Text string `xml:",chardata"`
+			} `xml:"Pnts>P"`
+			Faces []string `xml:"Faces>F"`
+		}
+	} `xml:"Surfaces>Surface"`
+}
+
+func TestWrite(t *testing.T) {
+	// vertex 1 is used by no face, so it isn't written
+	vertices := [][3]float64{{500000, 4100000, 10}, {7, 7, 7}, {500004, 4100000, 10}, {500000, 4100003, 14}}
+	triangles := [][3]int32{{0, 2, 3}}
+	opts := Options{
+		Name:             "ground & more",
+		Unit:             UnitOf(1200.0 / 3937.0),
+		CoordinateSystem: &CoordinateSystem{Name: "NAD83 / UTM zone 10N", EPSG: 26910},
+		Boundaries:       [][][3]float64{{{500000, 4100000, 10}, {500004, 4100000, 10}, {500000, 4100003, 14}}},
+		Breaklines:       [][][3]float64{{{500001, 4100000, 10}, {500001, 4100001, 11}}},
+		Time:             time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, vertices, triangles, opts); err != nil {
+		t.Fatal(err)
+	}
+	var doc document
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("%v in\n%s", err, buf.String())
+	}
+	if doc.XMLName.Space != Namespace || doc.Date != "2026-10-19" {
+		t.Errorf("root %v dated %s", doc.XMLName, doc.Date)
+	}
+	if doc.Units.Metric != nil || doc.Units.Imperial == nil || doc.Units.Imperial.LinearUnit != "USSurveyFoot" {
+		t.Errorf("units %+v", doc.Units)
+	}
+	if doc.CoordinateSystem.EPSGCode != "26910" || doc.Surface.Name != "ground & more" {
+		t.Errorf("coordinate system %+v of %s", doc.CoordinateSystem, doc.Surface.Name)
+	}
+
+	d := doc.Surface.Definition
+	if d.Type != "TIN" || d.Area2D != 6 || d.Area3D != 10 || d.Max != 14 {
+		t.Errorf("definition %+v", d)
+	}
+	var points []string
+	for i, p := range d.Points {
+		if p.ID != i+1 {
+			t.Errorf("point %d has id %d", i, p.ID)
+		}
+		points = append(points, p.Text)
+	}
+	if got := strings.Join(points, ", "); got != "4100000 500000 10, 4100000 500004 10, 4100003 500000 14" {
+		t.Errorf("points %s", got)
+	}
+	if len(d.Faces) != 1 || d.Faces[0] != "1 2 3" {
+		t.Errorf("faces %v", d.Faces)
+	}
+
+	// the boundary closes back at its start
+	b := doc.Surface.Boundaries
+	if len(b) != 1 || b[0].Type != "outer" || !strings.HasSuffix(b[0].Points, "14 4100000 500000 10") {
+		t.Errorf("boundaries %+v", b)
+	}
+	if b := doc.Surface.Breaklines; len(b) != 1 || b[0].Points != "4100000 500001 10 4100001 500001 11" {
+		t.Errorf("breaklines %+v", b)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, vertices, triangles, Options{Time: opts.Time}); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, `linearUnit="meter"`) || strings.Contains(s, "SourceData") ||
+		strings.Contains(s, "CoordinateSystem") {
+		t.Errorf("plain surface wrote\n%s", s)
+	}
+
+	if err := Write(&buf, vertices, [][3]int32{{0, 2, 4}}, Options{}); err == nil {
+		t.Error("face beyond the vertices written")
+	}
+	if err := Write(&buf, vertices, triangles, Options{Breaklines: [][][3]float64{{{0, 0, 0}}}}); err == nil {
+		t.Error("breakline of one point written")
+	}
+}
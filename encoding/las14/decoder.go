@@ -35,6 +35,9 @@ type FirstPassResult struct {
 type FullResult struct {
 	FirstPassResult
 
+	VariableLengthRecords         []VariableLengthRecord
+	ExtendedVariableLengthRecords []ExtendedVariableLengthRecord
+
 	pointData []byte
 }
 
@@ -47,6 +50,96 @@ func (fr *FullResult) PointDataRecord(idx uint64) *PointDataRecord {
 	}
 }
 
+// Copy returns a deep copy of fr whose point data may be modified without affecting fr.
+func (fr *FullResult) Copy() *FullResult {
+	pointData := make([]byte, len(fr.pointData))
+	copy(pointData, fr.pointData)
+
+	return &FullResult{
+		FirstPassResult:               fr.FirstPassResult,
+		VariableLengthRecords:         fr.VariableLengthRecords,
+		ExtendedVariableLengthRecords: fr.ExtendedVariableLengthRecords,
+		pointData:                     pointData,
+	}
+}
+
+// Select returns a copy of fr holding only the points for which keep returns true, in their original order.  The
+// copy's header is updated to match: its point counts, counts by return and bounds describe the selected points.
+func (fr *FullResult) Select(keep func(idx uint64) bool) *FullResult {
+	length := (uint64)(fr.Header.PointDataRecordLength)
+	n := fr.Header.PointCount()
+
+	var pointData []byte
+	for i := (uint64)(0); i < n; i++ {
+		if keep(i) {
+			offset := fr.pointOffset(i)
+			pointData = append(pointData, fr.pointData[offset:offset+length]...)
+		}
+	}
+
+	ret := &FullResult{
+		FirstPassResult:               fr.FirstPassResult,
+		VariableLengthRecords:         fr.VariableLengthRecords,
+		ExtendedVariableLengthRecords: fr.ExtendedVariableLengthRecords,
+		pointData:                     pointData,
+	}
+	ret.updateSummary((uint64)(len(pointData)) / length)
+
+	return ret
+}
+
+// updateSummary recomputes the header's point counts, counts by return and bounds from the n records of point data.
+func (fr *FullResult) updateSummary(n uint64) {
+	h := &fr.Header
+	h.NumberOfPointRecords = n
+	h.NumberOfPointsByReturn = [15]uint64{}
+
+	h.LegacyNumberOfPointRecords = 0
+	h.LegacyNumberOfPointsByReturn = [5]uint32{}
+	legacy := h.PointDataRecordFormat < 6 && n <= math.MaxUint32
+	if legacy {
+		h.LegacyNumberOfPointRecords = (uint32)(n)
+	}
+
+	min := [3]int64{math.MaxInt64, math.MaxInt64, math.MaxInt64}
+	max := [3]int64{math.MinInt64, math.MinInt64, math.MinInt64}
+	for i := (uint64)(0); i < n; i++ {
+		raw := fr.PointDataRecord(i).Raw
+
+		x, y, z := decodeXYZ(raw)
+		for j, v := range [3]int64{x, y, z} {
+			if v < min[j] {
+				min[j] = v
+			}
+			if v > max[j] {
+				max[j] = v
+			}
+		}
+
+		ret := raw[14] & 0x0f
+		if h.PointDataRecordFormat < 6 {
+			ret = raw[14] & 0x07
+		}
+		if ret >= 1 && ret <= 15 {
+			h.NumberOfPointsByReturn[ret-1]++
+		}
+		if legacy && ret >= 1 && ret <= 5 {
+			h.LegacyNumberOfPointsByReturn[ret-1]++
+		}
+	}
+
+	if n == 0 {
+		h.MinX, h.MinY, h.MinZ, h.MaxX, h.MaxY, h.MaxZ = 0, 0, 0, 0, 0, 0
+		return
+	}
+	h.MinX = (float64)(min[0])*h.XScaleFactor + h.XOffset
+	h.MinY = (float64)(min[1])*h.YScaleFactor + h.YOffset
+	h.MinZ = (float64)(min[2])*h.ZScaleFactor + h.ZOffset
+	h.MaxX = (float64)(max[0])*h.XScaleFactor + h.XOffset
+	h.MaxY = (float64)(max[1])*h.YScaleFactor + h.YOffset
+	h.MaxZ = (float64)(max[2])*h.ZScaleFactor + h.ZOffset
+}
+
 func (fr *FullResult) pointOffset(i uint64) uint64 {
 	return i * (uint64)(fr.Header.PointDataRecordLength)
 }
@@ -195,6 +288,12 @@ func (las *Decoder) firstPassDecode() (error, *FirstPassResult) {
 		return fmt.Errorf("failed to read header: %w", err), nil
 	}
 	header.PointDataRecordLength = binary.LittleEndian.Uint16(pdrLength)
+	if !header.PointDataRecordFormat.IsValid() {
+		return fmt.Errorf("unrecognized point data record format: %d", header.PointDataRecordFormat), nil
+	}
+	if header.PointDataRecordLength < header.PointDataRecordFormat.StandardLength() {
+		return fmt.Errorf("point data record length %d too short for format %d", header.PointDataRecordLength, header.PointDataRecordFormat), nil
+	}
 
 	lnpr := make([]byte, 4)
 	n, err = las.safeRead(lnpr)
@@ -312,12 +411,12 @@ func (las *Decoder) firstPassDecode() (error, *FirstPassResult) {
 	}
 	header.StartOfFirstExtendedVariableLengthRecord = binary.LittleEndian.Uint64(startOfEVLR)
 
-	nEVLR := make([]byte, 8)
+	nEVLR := make([]byte, 4)
 	n, err = las.safeRead(nEVLR)
 	if err != nil {
 		return fmt.Errorf("failed to read header: %w", err), nil
 	}
-	header.NumberOfExtendedVariableLengthRecords = binary.LittleEndian.Uint64(nEVLR)
+	header.NumberOfExtendedVariableLengthRecords = binary.LittleEndian.Uint32(nEVLR)
 
 	npr := make([]byte, 8)
 	n, err = las.safeRead(npr)
@@ -331,21 +430,21 @@ func (las *Decoder) firstPassDecode() (error, *FirstPassResult) {
 	if err != nil {
 		return fmt.Errorf("failed to read header: %w", err), nil
 	}
-	header.NumberOfPointsByReturn[0] = binary.BigEndian.Uint64(npbr[0:8])
-	header.NumberOfPointsByReturn[1] = binary.BigEndian.Uint64(npbr[8:16])
-	header.NumberOfPointsByReturn[2] = binary.BigEndian.Uint64(npbr[16:24])
-	header.NumberOfPointsByReturn[3] = binary.BigEndian.Uint64(npbr[24:32])
-	header.NumberOfPointsByReturn[4] = binary.BigEndian.Uint64(npbr[32:40])
-	header.NumberOfPointsByReturn[5] = binary.BigEndian.Uint64(npbr[40:48])
-	header.NumberOfPointsByReturn[6] = binary.BigEndian.Uint64(npbr[48:56])
-	header.NumberOfPointsByReturn[7] = binary.BigEndian.Uint64(npbr[56:64])
-	header.NumberOfPointsByReturn[8] = binary.BigEndian.Uint64(npbr[64:72])
-	header.NumberOfPointsByReturn[9] = binary.BigEndian.Uint64(npbr[72:80])
-	header.NumberOfPointsByReturn[10] = binary.BigEndian.Uint64(npbr[80:88])
-	header.NumberOfPointsByReturn[11] = binary.BigEndian.Uint64(npbr[88:96])
-	header.NumberOfPointsByReturn[12] = binary.BigEndian.Uint64(npbr[96:104])
-	header.NumberOfPointsByReturn[13] = binary.BigEndian.Uint64(npbr[104:112])
-	header.NumberOfPointsByReturn[14] = binary.BigEndian.Uint64(npbr[112:120])
+	header.NumberOfPointsByReturn[0] = binary.LittleEndian.Uint64(npbr[0:8])
+	header.NumberOfPointsByReturn[1] = binary.LittleEndian.Uint64(npbr[8:16])
+	header.NumberOfPointsByReturn[2] = binary.LittleEndian.Uint64(npbr[16:24])
+	header.NumberOfPointsByReturn[3] = binary.LittleEndian.Uint64(npbr[24:32])
+	header.NumberOfPointsByReturn[4] = binary.LittleEndian.Uint64(npbr[32:40])
+	header.NumberOfPointsByReturn[5] = binary.LittleEndian.Uint64(npbr[40:48])
+	header.NumberOfPointsByReturn[6] = binary.LittleEndian.Uint64(npbr[48:56])
+	header.NumberOfPointsByReturn[7] = binary.LittleEndian.Uint64(npbr[56:64])
+	header.NumberOfPointsByReturn[8] = binary.LittleEndian.Uint64(npbr[64:72])
+	header.NumberOfPointsByReturn[9] = binary.LittleEndian.Uint64(npbr[72:80])
+	header.NumberOfPointsByReturn[10] = binary.LittleEndian.Uint64(npbr[80:88])
+	header.NumberOfPointsByReturn[11] = binary.LittleEndian.Uint64(npbr[88:96])
+	header.NumberOfPointsByReturn[12] = binary.LittleEndian.Uint64(npbr[96:104])
+	header.NumberOfPointsByReturn[13] = binary.LittleEndian.Uint64(npbr[104:112])
+	header.NumberOfPointsByReturn[14] = binary.LittleEndian.Uint64(npbr[112:120])
 
 	las.fp = &FirstPassResult{
 		Header: header,
@@ -368,12 +467,22 @@ func (las *Decoder) fullDecode(qs QuerySet) (error, *FullResult) {
 	}
 
 	// populate full result
+	err, vlrs := las.readVLRs(fp)
+	if err != nil {
+		return fmt.Errorf("failed to read vlrs: %w", err), nil
+	}
+
 	_, err = las.r.Seek((int64)(fp.Header.OffsetToPointData), io.SeekStart)
 	if err != nil {
 		return fmt.Errorf("failed to seek to start of points: %w", err), nil
 	}
 
-	pointDataSize := (uint32)(fp.Header.PointDataRecordLength) * fp.Header.LegacyNumberOfPointRecords
+	recordLength := (uint64)(fp.Header.PointDataRecordLength)
+	if fp.Header.PointCount() > (uint64)(las.budget)/recordLength {
+		return fmt.Errorf("read budget exhausted: %d points of %d bytes requested", fp.Header.PointCount(), recordLength), nil
+	}
+
+	pointDataSize := recordLength * fp.Header.PointCount()
 	pointData := make([]byte, pointDataSize)
 	n, err := las.safeRead(pointData)
 	if err != nil {
@@ -384,10 +493,97 @@ func (las *Decoder) fullDecode(qs QuerySet) (error, *FullResult) {
 		return fmt.Errorf("could not read full point data: %w", err), nil
 	}
 
+	err, evlrs := las.readEVLRs(fp)
+	if err != nil {
+		return fmt.Errorf("failed to read evlrs: %w", err), nil
+	}
+
 	return nil, &FullResult{
-		FirstPassResult: *fp,
-		pointData:       pointData,
+		FirstPassResult:               *fp,
+		VariableLengthRecords:         vlrs,
+		ExtendedVariableLengthRecords: evlrs,
+		pointData:                     pointData,
+	}
+}
+
+func (las *Decoder) readVLRs(fp *FirstPassResult) (error, []VariableLengthRecord) {
+	_, err := las.r.Seek((int64)(fp.Header.HeaderSize), io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to seek to start of vlrs: %w", err), nil
 	}
+
+	var vlrs []VariableLengthRecord
+	for i := uint32(0); i < fp.Header.NumberOfVariableLengthRecords; i++ {
+		buf := make([]byte, VLRHeaderSize)
+		err, _ = las.safeReadFull(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read vlr %d header: %w", i, err), nil
+		}
+		vlr := decodeVLRHeader(buf)
+
+		vlr.Data = make([]byte, vlr.RecordLengthAfterHeader)
+		err, _ = las.safeReadFull(vlr.Data)
+		if err != nil {
+			return fmt.Errorf("failed to read vlr %d data: %w", i, err), nil
+		}
+
+		vlrs = append(vlrs, vlr)
+	}
+
+	return nil, vlrs
+}
+
+func (las *Decoder) readEVLRs(fp *FirstPassResult) (error, []ExtendedVariableLengthRecord) {
+	var evlrs []ExtendedVariableLengthRecord
+	if fp.Header.NumberOfExtendedVariableLengthRecords == 0 {
+		return nil, evlrs
+	}
+
+	if fp.Header.StartOfFirstExtendedVariableLengthRecord > math.MaxInt64 {
+		return fmt.Errorf("invalid evlr offset: %d", fp.Header.StartOfFirstExtendedVariableLengthRecord), nil
+	}
+
+	_, err := las.r.Seek((int64)(fp.Header.StartOfFirstExtendedVariableLengthRecord), io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("failed to seek to start of evlrs: %w", err), nil
+	}
+
+	for i := uint32(0); i < fp.Header.NumberOfExtendedVariableLengthRecords; i++ {
+		buf := make([]byte, EVLRHeaderSize)
+		err, _ = las.safeReadFull(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read evlr %d header: %w", i, err), nil
+		}
+		evlr := decodeEVLRHeader(buf)
+
+		if evlr.RecordLengthAfterHeader > (uint64)(las.budget) {
+			return fmt.Errorf("read budget exhausted: %d byte evlr requested", evlr.RecordLengthAfterHeader), nil
+		}
+
+		evlr.Data = make([]byte, evlr.RecordLengthAfterHeader)
+		err, _ = las.safeReadFull(evlr.Data)
+		if err != nil {
+			return fmt.Errorf("failed to read evlr %d data: %w", i, err), nil
+		}
+
+		evlrs = append(evlrs, evlr)
+	}
+
+	return nil, evlrs
+}
+
+// safeReadFull is safeRead that treats a short read as an error.
+func (las *Decoder) safeReadFull(p []byte) (error, int) {
+	n, err := las.safeRead(p)
+	if err != nil {
+		return err, n
+	}
+
+	if n != len(p) {
+		return fmt.Errorf("short read: only %d of %d bytes read", n, len(p)), n
+	}
+
+	return nil, n
 }
 
 func (las *Decoder) safeRead(p []byte) (n int, err error) {
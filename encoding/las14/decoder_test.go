@@ -0,0 +1,299 @@
+package las14
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+// fullHeaderSpec returns a spec with every non-derived header field set to a distinctive value.
+func fullHeaderSpec(format byte, points int) lastest.Spec {
+	return lastest.Spec{
+		Header: lastest.Header{
+			FileSourceID:          0x1234,
+			GlobalEncoding:        (uint16)(FlagGPSTime | FlagWKT),
+			ProjectIDData1:        0xdeadbeef,
+			ProjectIDData2:        0xcafe,
+			ProjectIDData3:        0xf00d,
+			ProjectIDData4:        0x0102030405060708,
+			SystemID:              "lastest system",
+			GeneratingSoftware:    "lastest generator",
+			FileCreationDayOfYear: 291,
+			FileCreationYear:      2021,
+			PointDataRecordFormat: format,
+			XScaleFactor:          0.001,
+			YScaleFactor:          0.002,
+			ZScaleFactor:          0.0025,
+			XOffset:               500000,
+			YOffset:               4100000,
+			ZOffset:               -12.5,
+		},
+		PointCount: points,
+		VLRs: []lastest.VLR{
+			{UserID: "LASF_Projection", RecordID: 2112, Description: "wkt", Data: []byte("PROJCS[]\x00")},
+		},
+		EVLRs: []lastest.VLR{
+			{UserID: "lastest", RecordID: 7, Description: "trailer", Data: []byte{1, 2, 3}},
+		},
+	}
+}
+
+func fixed32(s string) [32]byte {
+	var b [32]byte
+	copy(b[:], s)
+	return b
+}
+
+func TestFirstPassDecodeHeaderFields(t *testing.T) {
+	fields := []struct {
+		name string
+		want func(h lastest.Header) interface{}
+		got  func(h PublicHeaderBlock) interface{}
+	}{
+		{"FileSourceID", func(h lastest.Header) interface{} { return h.FileSourceID }, func(h PublicHeaderBlock) interface{} { return h.FileSourceID }},
+		{"GlobalEncoding", func(h lastest.Header) interface{} { return h.GlobalEncoding }, func(h PublicHeaderBlock) interface{} { return (uint16)(h.GlobalEncoding) }},
+		{"ProjectID.Data1", func(h lastest.Header) interface{} { return h.ProjectIDData1 }, func(h PublicHeaderBlock) interface{} { return h.ProjectID.Data1 }},
+		{"ProjectID.Data2", func(h lastest.Header) interface{} { return h.ProjectIDData2 }, func(h PublicHeaderBlock) interface{} { return h.ProjectID.Data2 }},
+		{"ProjectID.Data3", func(h lastest.Header) interface{} { return h.ProjectIDData3 }, func(h PublicHeaderBlock) interface{} { return h.ProjectID.Data3 }},
+		{"ProjectID.Data4", func(h lastest.Header) interface{} { return h.ProjectIDData4 }, func(h PublicHeaderBlock) interface{} { return h.ProjectID.Data4 }},
+		{"VersionMajor", func(h lastest.Header) interface{} { return h.VersionMajor }, func(h PublicHeaderBlock) interface{} { return h.VersionMajor }},
+		{"VersionMinor", func(h lastest.Header) interface{} { return h.VersionMinor }, func(h PublicHeaderBlock) interface{} { return h.VersionMinor }},
+		{"SystemID", func(h lastest.Header) interface{} { return fixed32(h.SystemID) }, func(h PublicHeaderBlock) interface{} { return ([32]byte)(h.SystemID) }},
+		{"GeneratingSoftware", func(h lastest.Header) interface{} { return fixed32(h.GeneratingSoftware) }, func(h PublicHeaderBlock) interface{} { return h.GeneratingSoftware }},
+		{"FileCreationDayOfYear", func(h lastest.Header) interface{} { return h.FileCreationDayOfYear }, func(h PublicHeaderBlock) interface{} { return h.FileCreationDayOfYear }},
+		{"FileCreationYear", func(h lastest.Header) interface{} { return h.FileCreationYear }, func(h PublicHeaderBlock) interface{} { return h.FileCreationYear }},
+		{"HeaderSize", func(h lastest.Header) interface{} { return h.HeaderSize }, func(h PublicHeaderBlock) interface{} { return h.HeaderSize }},
+		{"OffsetToPointData", func(h lastest.Header) interface{} { return h.OffsetToPointData }, func(h PublicHeaderBlock) interface{} { return h.OffsetToPointData }},
+		{"NumberOfVariableLengthRecords", func(h lastest.Header) interface{} { return h.NumberOfVariableLengthRecords }, func(h PublicHeaderBlock) interface{} { return h.NumberOfVariableLengthRecords }},
+		{"PointDataRecordFormat", func(h lastest.Header) interface{} { return h.PointDataRecordFormat }, func(h PublicHeaderBlock) interface{} { return (byte)(h.PointDataRecordFormat) }},
+		{"PointDataRecordLength", func(h lastest.Header) interface{} { return h.PointDataRecordLength }, func(h PublicHeaderBlock) interface{} { return h.PointDataRecordLength }},
+		{"LegacyNumberOfPointRecords", func(h lastest.Header) interface{} { return h.LegacyNumberOfPointRecords }, func(h PublicHeaderBlock) interface{} { return h.LegacyNumberOfPointRecords }},
+		{"LegacyNumberOfPointsByReturn", func(h lastest.Header) interface{} { return h.LegacyNumberOfPointsByReturn }, func(h PublicHeaderBlock) interface{} { return h.LegacyNumberOfPointsByReturn }},
+		{"XScaleFactor", func(h lastest.Header) interface{} { return h.XScaleFactor }, func(h PublicHeaderBlock) interface{} { return h.XScaleFactor }},
+		{"YScaleFactor", func(h lastest.Header) interface{} { return h.YScaleFactor }, func(h PublicHeaderBlock) interface{} { return h.YScaleFactor }},
+		{"ZScaleFactor", func(h lastest.Header) interface{} { return h.ZScaleFactor }, func(h PublicHeaderBlock) interface{} { return h.ZScaleFactor }},
+		{"XOffset", func(h lastest.Header) interface{} { return h.XOffset }, func(h PublicHeaderBlock) interface{} { return h.XOffset }},
+		{"YOffset", func(h lastest.Header) interface{} { return h.YOffset }, func(h PublicHeaderBlock) interface{} { return h.YOffset }},
+		{"ZOffset", func(h lastest.Header) interface{} { return h.ZOffset }, func(h PublicHeaderBlock) interface{} { return h.ZOffset }},
+		{"MaxX", func(h lastest.Header) interface{} { return h.MaxX }, func(h PublicHeaderBlock) interface{} { return h.MaxX }},
+		{"MinX", func(h lastest.Header) interface{} { return h.MinX }, func(h PublicHeaderBlock) interface{} { return h.MinX }},
+		{"MaxY", func(h lastest.Header) interface{} { return h.MaxY }, func(h PublicHeaderBlock) interface{} { return h.MaxY }},
+		{"MinY", func(h lastest.Header) interface{} { return h.MinY }, func(h PublicHeaderBlock) interface{} { return h.MinY }},
+		{"MaxZ", func(h lastest.Header) interface{} { return h.MaxZ }, func(h PublicHeaderBlock) interface{} { return h.MaxZ }},
+		{"MinZ", func(h lastest.Header) interface{} { return h.MinZ }, func(h PublicHeaderBlock) interface{} { return h.MinZ }},
+		{"StartOfWaveformDataPacketRecord", func(h lastest.Header) interface{} { return h.StartOfWaveformDataPacketRecord }, func(h PublicHeaderBlock) interface{} { return h.StartOfWaveformDataPacketRecord }},
+		{"StartOfFirstExtendedVariableLengthRecord", func(h lastest.Header) interface{} { return h.StartOfFirstExtendedVariableLengthRecord }, func(h PublicHeaderBlock) interface{} { return h.StartOfFirstExtendedVariableLengthRecord }},
+		{"NumberOfExtendedVariableLengthRecords", func(h lastest.Header) interface{} { return h.NumberOfExtendedVariableLengthRecords }, func(h PublicHeaderBlock) interface{} { return h.NumberOfExtendedVariableLengthRecords }},
+		{"NumberOfPointRecords", func(h lastest.Header) interface{} { return h.NumberOfPointRecords }, func(h PublicHeaderBlock) interface{} { return h.NumberOfPointRecords }},
+		{"NumberOfPointsByReturn", func(h lastest.Header) interface{} { return h.NumberOfPointsByReturn }, func(h PublicHeaderBlock) interface{} { return h.NumberOfPointsByReturn }},
+	}
+
+	for format := byte(0); format <= 10; format++ {
+		spec := fullHeaderSpec(format, 37)
+		spec.Header.StartOfWaveformDataPacketRecord = 0x0a0b0c0d
+		file := lastest.Generate(spec)
+
+		err, fp := NewDecoder(bytes.NewReader(file.Bytes)).FirstPassDecode()
+		if err != nil {
+			t.Fatalf("format %d: first pass decode failed: %v", format, err)
+		}
+
+		for _, field := range fields {
+			want, got := field.want(file.Header), field.got(fp.Header)
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("format %d: %s = %v, want %v", format, field.name, got, want)
+			}
+		}
+	}
+}
+
+func TestFirstPassDecodeRejectsCorruptFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    lastest.Spec
+		wantErr string
+	}{
+		{"empty", lastest.Spec{Corruptions: []lastest.Corruption{lastest.Truncate(0)}}, "failed to read header"},
+		{"bad signature", lastest.Spec{Corruptions: []lastest.Corruption{lastest.BadSignature()}}, "invalid file signature"},
+		{"truncated header", lastest.Spec{Corruptions: []lastest.Corruption{lastest.Truncate(200)}}, "failed to read header"},
+		{"las 1.2", lastest.Spec{Header: lastest.Header{VersionMajor: 1, VersionMinor: 2}}, "unrecognized header size"},
+		{"las 1.3", lastest.Spec{Header: lastest.Header{VersionMajor: 1, VersionMinor: 3}}, "unrecognized header size"},
+		{"unknown format", lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 11}}, "unrecognized point data record format"},
+		{"compressed format", lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 0x86}}, "unrecognized point data record format"},
+		{"short record", lastest.Spec{
+			Header:      lastest.Header{PointDataRecordFormat: 6},
+			Corruptions: []lastest.Corruption{lastest.OverwriteUint16(lastest.OffsetPointDataRecordLength, 29)},
+		}, "too short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := lastest.Generate(tt.spec)
+
+			err, _ := NewDecoder(bytes.NewReader(file.Bytes)).FirstPassDecode()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("stream decoder error = %v, want %q", err, tt.wantErr)
+			}
+
+			err, _ = NewBytesDecoder(file.Bytes).FirstPassDecode()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("reader at decoder error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFullDecodeRejectsCorruptPointData(t *testing.T) {
+	tests := []struct {
+		name string
+		spec lastest.Spec
+	}{
+		{"truncated points", lastest.Spec{PointCount: 10, Corruptions: []lastest.Corruption{lastest.Truncate(lastest.HeaderSize14 + 25)}}},
+		{"inflated count", lastest.Spec{PointCount: 10, Corruptions: []lastest.Corruption{lastest.PointCount(11)}}},
+		{"absurd count", lastest.Spec{PointCount: 10, Corruptions: []lastest.Corruption{lastest.PointCount(1 << 62)}}},
+		{"offset past end", lastest.Spec{PointCount: 10, Corruptions: []lastest.Corruption{lastest.OverwriteUint32(lastest.OffsetOffsetToPointData, 1<<31)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := lastest.Generate(tt.spec)
+
+			if err, _ := NewDecoder(bytes.NewReader(file.Bytes)).FullDecode(""); err == nil {
+				t.Error("stream decoder: expected error")
+			}
+
+			if err, _ := NewBytesDecoder(file.Bytes).FullDecode(""); err == nil {
+				t.Error("reader at decoder: expected error")
+			}
+		})
+	}
+}
+
+func TestDecodersAgree(t *testing.T) {
+	for format := byte(0); format <= 10; format++ {
+		file := lastest.Generate(fullHeaderSpec(format, 100))
+
+		err, streamed := NewDecoder(bytes.NewReader(file.Bytes)).FullDecode("")
+		if err != nil {
+			t.Fatalf("format %d: stream decode failed: %v", format, err)
+		}
+
+		err, random := NewBytesDecoder(file.Bytes).FullDecode("")
+		if err != nil {
+			t.Fatalf("format %d: reader at decode failed: %v", format, err)
+		}
+
+		if !reflect.DeepEqual(streamed, random) {
+			t.Errorf("format %d: decoders disagree", format)
+		}
+	}
+}
+
+func TestVariableLengthRecords(t *testing.T) {
+	spec := fullHeaderSpec(6, 5)
+	spec.VLRs = append(spec.VLRs, lastest.VLR{UserID: "empty", RecordID: 1})
+	spec.EVLRs = append(spec.EVLRs, lastest.VLR{UserID: "big", RecordID: 2, Data: bytes.Repeat([]byte{0xab}, 70000)})
+	file := lastest.Generate(spec)
+	d := NewBytesDecoder(file.Bytes)
+
+	err, vlrs := d.VariableLengthRecords()
+	if err != nil {
+		t.Fatalf("vlr decode failed: %v", err)
+	}
+	if len(vlrs) != len(spec.VLRs) {
+		t.Fatalf("decoded %d vlrs, want %d", len(vlrs), len(spec.VLRs))
+	}
+	for i, want := range spec.VLRs {
+		got := vlrs[i]
+		if string(bytes.TrimRight(got.UserID[:], "\x00")) != want.UserID || got.RecordID != want.RecordID ||
+			string(bytes.TrimRight(got.Description[:], "\x00")) != want.Description || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("vlr %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	err, evlrs := d.ExtendedVariableLengthRecords()
+	if err != nil {
+		t.Fatalf("evlr decode failed: %v", err)
+	}
+	if len(evlrs) != len(spec.EVLRs) {
+		t.Fatalf("decoded %d evlrs, want %d", len(evlrs), len(spec.EVLRs))
+	}
+	for i, want := range spec.EVLRs {
+		got := evlrs[i]
+		if got.RecordID != want.RecordID || got.RecordLengthAfterHeader != (uint64)(len(want.Data)) || !bytes.Equal(got.Data, want.Data) {
+			t.Errorf("evlr %d: record %d with %d bytes, want record %d with %d bytes", i, got.RecordID, len(got.Data), want.RecordID, len(want.Data))
+		}
+	}
+}
+
+func TestPointRangeConcurrent(t *testing.T) {
+	const points, chunk = 1000, 64
+	file := lastest.Generate(fullHeaderSpec(6, points))
+	d := NewBytesDecoder(file.Bytes)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, points/chunk+1)
+	for start := 0; start < points; start += chunk {
+		count := chunk
+		if start+count > points {
+			count = points - start
+		}
+
+		wg.Add(1)
+		go func(start, count int) {
+			defer wg.Done()
+			err, pr := d.PointRange((uint64)(start), (uint64)(count))
+			if err != nil {
+				errs <- err
+				return
+			}
+			for i := 0; i < count; i++ {
+				want := file.Points[start+i]
+				if got := pr.PointDataRecord((uint64)(i)).Get().Intensity(); got != want.Intensity {
+					t.Errorf("point %d intensity = %d, want %d", start+i, got, want.Intensity)
+				}
+			}
+		}(start, count)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if err, _ := d.PointRange(points-1, 2); err == nil {
+		t.Error("expected out of bounds range to fail")
+	}
+}
+
+func TestReaderAtBudget(t *testing.T) {
+	const points = 100
+	spec := fullHeaderSpec(6, points)
+	spec.VLRs = append(spec.VLRs, lastest.VLR{UserID: "test", RecordID: 1, Data: make([]byte, 100)})
+	file := lastest.Generate(spec)
+	d := NewBytesDecoder(file.Bytes)
+	err, fp := d.FirstPassDecode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the budget bounds each read, so a shared decoder may read far more than it in all
+	length := (uint64)(fp.Header.PointDataRecordLength)
+	d.SetBudget(10 * length)
+	for i := 0; i < 50; i++ {
+		if err, _ := d.PointRange((uint64)(i%10)*10, 10); err != nil {
+			t.Fatalf("read %d: %v", i, err)
+		}
+		if err, _ := d.VariableLengthRecords(); err != nil {
+			t.Fatalf("read %d: %v", i, err)
+		}
+	}
+	if err, _ := d.PointRange(0, 11); err == nil {
+		t.Error("read past the budget")
+	}
+}
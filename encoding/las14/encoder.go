@@ -0,0 +1,152 @@
+package las14
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// An Encoder writes LAS 1.4 files to an output stream.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// NewFullResult assembles a FullResult from a header, its records and point data holding whole records of the
+// header's format and length.  The header's point counts, counts by return and bounds are computed from the point
+// data; the fields describing the file's layout are left for the Encoder to fill in.
+func NewFullResult(header PublicHeaderBlock, vlrs []VariableLengthRecord, evlrs []ExtendedVariableLengthRecord, pointData []byte) (error, *FullResult) {
+	if !header.PointDataRecordFormat.IsValid() {
+		return fmt.Errorf("unrecognized point data record format: %d", header.PointDataRecordFormat), nil
+	}
+	if header.PointDataRecordLength < header.PointDataRecordFormat.StandardLength() {
+		return fmt.Errorf("point data record length %d too short for format %d", header.PointDataRecordLength, header.PointDataRecordFormat), nil
+	}
+
+	length := (int)(header.PointDataRecordLength)
+	if len(pointData)%length != 0 {
+		return fmt.Errorf("point data of %d bytes is not a whole number of %d byte records", len(pointData), length), nil
+	}
+
+	fr := &FullResult{
+		FirstPassResult:               FirstPassResult{Header: header},
+		VariableLengthRecords:         vlrs,
+		ExtendedVariableLengthRecords: evlrs,
+		pointData:                     pointData,
+	}
+	fr.updateSummary((uint64)(len(pointData) / length))
+
+	return nil, fr
+}
+
+// Encode writes fr as a LAS 1.4 file.  The header is written as given apart from the fields describing the file's
+// layout: the version, header size, record counts and the offsets to the point data and extended variable length
+// records are derived from fr itself, and the record length of every VLR and EVLR from the length of its data.
+func (enc *Encoder) Encode(fr *FullResult) error {
+	for i, vlr := range fr.VariableLengthRecords {
+		if len(vlr.Data) > math.MaxUint16 {
+			return fmt.Errorf("vlr %d holds %d bytes, more than a vlr can record", i, len(vlr.Data))
+		}
+	}
+
+	h := fr.Header
+	h.VersionMajor, h.VersionMinor = 1, 4
+	h.HeaderSize = Las14HeaderSize
+	h.NumberOfVariableLengthRecords = (uint32)(len(fr.VariableLengthRecords))
+	h.NumberOfExtendedVariableLengthRecords = (uint32)(len(fr.ExtendedVariableLengthRecords))
+
+	offset := (uint64)(Las14HeaderSize)
+	for _, vlr := range fr.VariableLengthRecords {
+		offset += VLRHeaderSize + (uint64)(len(vlr.Data))
+	}
+	if offset > math.MaxUint32 {
+		return fmt.Errorf("vlrs of %d bytes leave the point data beyond a 32-bit offset", offset)
+	}
+	h.OffsetToPointData = (uint32)(offset)
+
+	offset += (uint64)(len(fr.pointData))
+	h.StartOfFirstExtendedVariableLengthRecord = 0
+	h.StartOfWaveformDataPacketRecord = 0
+	if len(fr.ExtendedVariableLengthRecords) > 0 {
+		h.StartOfFirstExtendedVariableLengthRecord = offset
+	}
+	for _, evlr := range fr.ExtendedVariableLengthRecords {
+		// NOTE: waveform data packets stored within the file begin at the data of their evlr
+		if evlr.RecordID == RecordIDWaveformData && evlr.UserIDString() == UserIDSpec && h.StartOfWaveformDataPacketRecord == 0 {
+			h.StartOfWaveformDataPacketRecord = offset + EVLRHeaderSize
+		}
+		offset += EVLRHeaderSize + (uint64)(len(evlr.Data))
+	}
+
+	w := bufio.NewWriter(enc.w)
+	w.Write(encodeHeader(&h))
+	for _, vlr := range fr.VariableLengthRecords {
+		w.Write(encodeVLRHeader(&vlr))
+		w.Write(vlr.Data)
+	}
+	w.Write(fr.pointData)
+	for _, evlr := range fr.ExtendedVariableLengthRecords {
+		w.Write(encodeEVLRHeader(&evlr))
+		w.Write(evlr.Data)
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write las file: %w", err)
+	}
+	return nil
+}
+
+// encodeHeader lays out h as the 375 bytes of a LAS 1.4 public header block.
+func encodeHeader(h *PublicHeaderBlock) []byte {
+	b := make([]byte, Las14HeaderSize)
+	le := binary.LittleEndian
+
+	copy(b[0:4], HeaderMagicBytes)
+	le.PutUint16(b[4:6], h.FileSourceID)
+	le.PutUint16(b[6:8], (uint16)(h.GlobalEncoding))
+	le.PutUint32(b[8:12], h.ProjectID.Data1)
+	le.PutUint16(b[12:14], h.ProjectID.Data2)
+	le.PutUint16(b[14:16], h.ProjectID.Data3)
+	le.PutUint64(b[16:24], h.ProjectID.Data4)
+	b[24] = h.VersionMajor
+	b[25] = h.VersionMinor
+	copy(b[26:58], h.SystemID[:])
+	copy(b[58:90], h.GeneratingSoftware[:])
+	le.PutUint16(b[90:92], h.FileCreationDayOfYear)
+	le.PutUint16(b[92:94], h.FileCreationYear)
+	le.PutUint16(b[94:96], h.HeaderSize)
+	le.PutUint32(b[96:100], h.OffsetToPointData)
+	le.PutUint32(b[100:104], h.NumberOfVariableLengthRecords)
+	b[104] = (byte)(h.PointDataRecordFormat)
+	le.PutUint16(b[105:107], h.PointDataRecordLength)
+	le.PutUint32(b[107:111], h.LegacyNumberOfPointRecords)
+	for i, n := range h.LegacyNumberOfPointsByReturn {
+		le.PutUint32(b[111+i*4:], n)
+	}
+
+	doubles := [...]float64{
+		h.XScaleFactor, h.YScaleFactor, h.ZScaleFactor,
+		h.XOffset, h.YOffset, h.ZOffset,
+		h.MaxX, h.MinX, h.MaxY, h.MinY, h.MaxZ, h.MinZ,
+	}
+	for i, v := range doubles {
+		le.PutUint64(b[131+i*8:], math.Float64bits(v))
+	}
+
+	le.PutUint64(b[227:235], h.StartOfWaveformDataPacketRecord)
+	le.PutUint64(b[235:243], h.StartOfFirstExtendedVariableLengthRecord)
+	le.PutUint32(b[243:247], h.NumberOfExtendedVariableLengthRecords)
+	le.PutUint64(b[247:255], h.NumberOfPointRecords)
+	for i, n := range h.NumberOfPointsByReturn {
+		le.PutUint64(b[255+i*8:], n)
+	}
+
+	return b
+}
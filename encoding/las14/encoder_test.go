@@ -0,0 +1,94 @@
+package las14
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for format := byte(0); format <= 10; format++ {
+		file := lastest.Generate(fullHeaderSpec(format, 37))
+
+		err, fr := NewBytesDecoder(file.Bytes).FullDecode("")
+		if err != nil {
+			t.Fatalf("format %d: decode failed: %v", format, err)
+		}
+
+		var buf bytes.Buffer
+		err = NewEncoder(&buf).Encode(fr)
+		if err != nil {
+			t.Fatalf("format %d: encode failed: %v", format, err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), file.Bytes) {
+			t.Errorf("format %d: re-encoded file differs from the original", format)
+		}
+	}
+}
+
+func TestNewFullResultSummarizesPoints(t *testing.T) {
+	header := PublicHeaderBlock{
+		PointDataRecordFormat: 6,
+		PointDataRecordLength: 30,
+		XScaleFactor:          0.01,
+		YScaleFactor:          0.01,
+		ZScaleFactor:          0.01,
+		ZOffset:               100,
+	}
+
+	err, schema := NewSchema(&header, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := schema.Dimension(DimX)
+	z, _ := schema.Dimension(DimZ)
+	ret, _ := schema.Dimension(DimReturnNumber)
+	class, _ := schema.Dimension(DimClassification)
+
+	data := make([]byte, 3*30)
+	for i, v := range []float64{-1.5, 2.25, 0} {
+		record := data[i*30 : (i+1)*30]
+		for _, set := range []struct {
+			d *Dimension
+			v float64
+		}{{x, v}, {z, 100 + v}, {ret, (float64)(i + 1)}, {class, 40}} {
+			if err := set.d.SetFloat64(record, set.v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := class.SetFloat64(data[:30], 256); err == nil {
+		t.Error("classification 256 stored without error")
+	}
+
+	err, fr := NewFullResult(header, nil, nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := fr.Header
+	if h.NumberOfPointRecords != 3 || h.NumberOfPointsByReturn[0] != 1 || h.NumberOfPointsByReturn[2] != 1 {
+		t.Errorf("counts = %d %v", h.NumberOfPointRecords, h.NumberOfPointsByReturn)
+	}
+	if h.MinX != -1.5 || h.MaxX != 2.25 || h.MinZ != 98.5 || h.MaxZ != 102.25 {
+		t.Errorf("bounds x %v..%v z %v..%v", h.MinX, h.MaxX, h.MinZ, h.MaxZ)
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(fr); err != nil {
+		t.Fatal(err)
+	}
+	err, decoded := NewBytesDecoder(buf.Bytes()).FullDecode("")
+	if err != nil {
+		t.Fatalf("encoded file does not decode: %v", err)
+	}
+	if got := class.Float64(decoded.PointDataRecord(1).Raw); got != 40 {
+		t.Errorf("classification = %v, want 40", got)
+	}
+
+	if err, _ := NewFullResult(header, nil, nil, data[:31]); err == nil {
+		t.Error("partial record accepted")
+	}
+}
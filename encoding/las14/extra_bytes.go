@@ -0,0 +1,201 @@
+package las14
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Well known VLR user ids and record ids defined by the LAS 1.4 spec.
+const (
+	UserIDSpec       = "LASF_Spec"
+	UserIDProjection = "LASF_Projection"
+
+	RecordIDExtraBytes   = 4
+	RecordIDWaveformData = 65535
+)
+
+// ExtraBytesDescriptorSize is the size in bytes of each descriptor within an extra bytes VLR.
+const ExtraBytesDescriptorSize = 192
+
+// Option bits of an extra bytes descriptor.
+const (
+	ExtraBytesNoDataValid byte = 1 << iota
+	ExtraBytesMinValid
+	ExtraBytesMaxValid
+	ExtraBytesScaleValid
+	ExtraBytesOffsetValid
+)
+
+// ExtraBytesDescriptor describes a run of extra bytes appended to each point data record.
+type ExtraBytesDescriptor struct {
+	DataType    byte
+	Options     byte
+	Name        [32]byte
+	NoData      [3][8]byte
+	Min         [3][8]byte
+	Max         [3][8]byte
+	Scale       [3]float64
+	Offset      [3]float64
+	Description [32]byte
+}
+
+// UserIDString returns the vlr's user id without its NUL padding.
+func (vlr *VariableLengthRecord) UserIDString() string {
+	return trimNUL(vlr.UserID[:])
+}
+
+// DescriptionString returns the vlr's description without its NUL padding.
+func (vlr *VariableLengthRecord) DescriptionString() string {
+	return trimNUL(vlr.Description[:])
+}
+
+// IsExtraBytes reports whether vlr holds extra bytes descriptors.
+func (vlr *VariableLengthRecord) IsExtraBytes() bool {
+	return vlr.RecordID == RecordIDExtraBytes && vlr.UserIDString() == UserIDSpec
+}
+
+// UserIDString returns the evlr's user id without its NUL padding.
+func (evlr *ExtendedVariableLengthRecord) UserIDString() string {
+	return trimNUL(evlr.UserID[:])
+}
+
+// DescriptionString returns the evlr's description without its NUL padding.
+func (evlr *ExtendedVariableLengthRecord) DescriptionString() string {
+	return trimNUL(evlr.Description[:])
+}
+
+func trimNUL(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// DecodeExtraBytesDescriptors decodes the descriptors held in the data of an extra bytes VLR.
+func DecodeExtraBytesDescriptors(data []byte) (error, []ExtraBytesDescriptor) {
+	if len(data)%ExtraBytesDescriptorSize != 0 {
+		return fmt.Errorf("extra bytes vlr length %d is not a multiple of %d", len(data), ExtraBytesDescriptorSize), nil
+	}
+
+	descriptors := make([]ExtraBytesDescriptor, len(data)/ExtraBytesDescriptorSize)
+	for i := range descriptors {
+		b := data[i*ExtraBytesDescriptorSize : (i+1)*ExtraBytesDescriptorSize]
+		ebd := &descriptors[i]
+
+		ebd.DataType = b[2]
+		ebd.Options = b[3]
+		copy(ebd.Name[:], b[4:36])
+		// bytes 36:40 are unused
+		for j := 0; j < 3; j++ {
+			copy(ebd.NoData[j][:], b[40+j*8:48+j*8])
+			copy(ebd.Min[j][:], b[64+j*8:72+j*8])
+			copy(ebd.Max[j][:], b[88+j*8:96+j*8])
+			ebd.Scale[j] = math.Float64frombits(binary.LittleEndian.Uint64(b[112+j*8 : 120+j*8]))
+			ebd.Offset[j] = math.Float64frombits(binary.LittleEndian.Uint64(b[136+j*8 : 144+j*8]))
+		}
+		copy(ebd.Description[:], b[160:192])
+
+		if ebd.DataType > 30 {
+			return fmt.Errorf("extra bytes descriptor %d has unrecognized data type %d", i, ebd.DataType), nil
+		}
+		if ebd.DataType == 0 && ebd.Options == 0 {
+			return fmt.Errorf("extra bytes descriptor %d describes zero undocumented bytes", i), nil
+		}
+	}
+
+	return nil, descriptors
+}
+
+// Encode lays out ebd as it is stored within an extra bytes VLR.
+func (ebd *ExtraBytesDescriptor) Encode() []byte {
+	b := make([]byte, ExtraBytesDescriptorSize)
+	b[2] = ebd.DataType
+	b[3] = ebd.Options
+	copy(b[4:36], ebd.Name[:])
+	for j := 0; j < 3; j++ {
+		copy(b[40+j*8:48+j*8], ebd.NoData[j][:])
+		copy(b[64+j*8:72+j*8], ebd.Min[j][:])
+		copy(b[88+j*8:96+j*8], ebd.Max[j][:])
+		binary.LittleEndian.PutUint64(b[112+j*8:120+j*8], math.Float64bits(ebd.Scale[j]))
+		binary.LittleEndian.PutUint64(b[136+j*8:144+j*8], math.Float64bits(ebd.Offset[j]))
+	}
+	copy(b[160:192], ebd.Description[:])
+	return b
+}
+
+// NameString returns the descriptor's name without its NUL padding.
+func (ebd *ExtraBytesDescriptor) NameString() string {
+	return trimNUL(ebd.Name[:])
+}
+
+// Dimensions returns the dimensions described by ebd when its bytes begin at offset within each record.  Most
+// descriptors describe a single dimension; the deprecated two and three element data types describe one dimension
+// per element, suffixed with the element index.
+func (ebd *ExtraBytesDescriptor) Dimensions(offset int) []Dimension {
+	name := ebd.NameString()
+	description := trimNUL(ebd.Description[:])
+
+	if ebd.DataType == 0 {
+		return []Dimension{{
+			Name:        name,
+			Description: description,
+			Type:        TypeUndocumented,
+			ByteOffset:  offset,
+			Size:        (int)(ebd.Options),
+			Extra:       true,
+		}}
+	}
+
+	t := (DataType)((ebd.DataType-1)%10 + 1)
+	elements := (int)((ebd.DataType-1)/10 + 1)
+
+	dims := make([]Dimension, elements)
+	for j := range dims {
+		d := Dimension{
+			Name:        name,
+			Description: description,
+			Type:        t,
+			ByteOffset:  offset + j*t.Size(),
+			Size:        t.Size(),
+			Extra:       true,
+		}
+		if elements > 1 {
+			d.Name = fmt.Sprintf("%s[%d]", name, j)
+		}
+
+		if ebd.Options&(ExtraBytesScaleValid|ExtraBytesOffsetValid) != 0 {
+			d.Scaled, d.Scale, d.Offset = true, 1, 0
+			if ebd.Options&ExtraBytesScaleValid != 0 {
+				d.Scale = ebd.Scale[j]
+			}
+			if ebd.Options&ExtraBytesOffsetValid != 0 {
+				d.Offset = ebd.Offset[j]
+			}
+		}
+
+		if ebd.Options&ExtraBytesNoDataValid != 0 {
+			d.HasNoData = true
+			d.NoData = anyTypeFloat64(t, ebd.NoData[j])
+		}
+
+		dims[j] = d
+	}
+
+	return dims
+}
+
+// anyTypeFloat64 decodes a descriptor value stored upcast to 8 bytes: unsigned types as a uint64, signed types as an
+// int64 and floating point types as a float64.
+func anyTypeFloat64(t DataType, b [8]byte) float64 {
+	v := binary.LittleEndian.Uint64(b[:])
+	switch t {
+	case TypeInt8, TypeInt16, TypeInt32, TypeInt64:
+		return (float64)((int64)(v))
+	case TypeFloat32, TypeFloat64:
+		return math.Float64frombits(v)
+	default:
+		return (float64)(v)
+	}
+}
@@ -0,0 +1,111 @@
+package las14
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+// maxFuzzPoints bounds how many records a fuzz iteration decodes so that large counts don't slow the fuzzer down.
+const maxFuzzPoints = 64
+
+func addSeedCorpus(f *testing.F) {
+	for format := byte(0); format <= 10; format++ {
+		f.Add(lastest.Generate(fullHeaderSpec(format, 3)).Bytes)
+	}
+
+	f.Add(lastest.Generate(lastest.Spec{Header: lastest.Header{VersionMajor: 1, VersionMinor: 2}, PointCount: 1}).Bytes)
+	f.Add(lastest.Generate(lastest.Spec{PointCount: 2, ExtraBytes: 7}).Bytes)
+	f.Add(lastest.Generate(lastest.Spec{PointCount: 2, Corruptions: []lastest.Corruption{lastest.PointCount(1 << 40)}}).Bytes)
+	f.Add(lastest.Generate(lastest.Spec{
+		EVLRs:       []lastest.VLR{{UserID: "x", Data: []byte{1}}},
+		Corruptions: []lastest.Corruption{lastest.OverwriteUint32(lastest.OffsetNumberOfEVLRs, 1<<30)},
+	}).Bytes)
+}
+
+func FuzzFirstPassDecode(f *testing.F) {
+	addSeedCorpus(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := NewDecoder(bytes.NewReader(data))
+		err, fp := d.firstPassDecode()
+
+		rerr, rfp := NewBytesDecoder(data).FirstPassDecode()
+		if (err == nil) != (rerr == nil) {
+			t.Fatalf("decoders disagree on validity: stream %v, reader at %v", err, rerr)
+		}
+		if err != nil {
+			return
+		}
+
+		// NOTE: headers are compared formatted because NaN fields never compare equal
+		if fmt.Sprintf("%+v", fp.Header) != fmt.Sprintf("%+v", rfp.Header) {
+			t.Fatalf("decoders disagree on header: stream %+v, reader at %+v", fp.Header, rfp.Header)
+		}
+
+		if fp.Header.HeaderSize != Las14HeaderSize {
+			t.Fatalf("accepted header size %d", fp.Header.HeaderSize)
+		}
+
+		if fp.Header.PointDataRecordLength < fp.Header.PointDataRecordFormat.StandardLength() {
+			t.Fatalf("accepted record length %d for format %d", fp.Header.PointDataRecordLength, fp.Header.PointDataRecordFormat)
+		}
+	})
+}
+
+func FuzzVariableLengthRecords(f *testing.F) {
+	addSeedCorpus(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := NewBytesDecoder(data)
+
+		err, vlrs := d.VariableLengthRecords()
+		if err == nil {
+			for i, vlr := range vlrs {
+				if len(vlr.Data) != (int)(vlr.RecordLengthAfterHeader) {
+					t.Fatalf("vlr %d: %d bytes of data, header says %d", i, len(vlr.Data), vlr.RecordLengthAfterHeader)
+				}
+			}
+		}
+
+		err, evlrs := d.ExtendedVariableLengthRecords()
+		if err == nil {
+			for i, evlr := range evlrs {
+				if (uint64)(len(evlr.Data)) != evlr.RecordLengthAfterHeader {
+					t.Fatalf("evlr %d: %d bytes of data, header says %d", i, len(evlr.Data), evlr.RecordLengthAfterHeader)
+				}
+			}
+		}
+	})
+}
+
+func FuzzPointDataRecordGet(f *testing.F) {
+	addSeedCorpus(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := NewBytesDecoder(data)
+		err, fp := d.FirstPassDecode()
+		if err != nil {
+			return
+		}
+
+		count := fp.Header.PointCount()
+		if count > maxFuzzPoints {
+			count = maxFuzzPoints
+		}
+
+		err, pr := d.PointRange(0, count)
+		if err != nil {
+			return
+		}
+
+		for i := uint64(0); i < pr.Count; i++ {
+			pd := pr.PointDataRecord(i).Get()
+			pd.XYZ()
+			pd.Intensity()
+			pd.Classification()
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package las14
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Record ids of the coordinate reference system VLRs stored under UserIDProjection.
+const (
+	RecordIDGeoKeyDirectory = 34735
+	RecordIDGeoDoubleParams = 34736
+	RecordIDGeoASCIIParams  = 34737
+	RecordIDMathTransform   = 2111
+	RecordIDCoordinateWKT   = 2112
+)
+
+// GeoTIFF key ids commonly found in LAS files.
+const (
+	GTModelTypeGeoKey      = 1024
+	GTRasterTypeGeoKey     = 1025
+	GTCitationGeoKey       = 1026
+	GeographicTypeGeoKey   = 2048
+	GeogCitationGeoKey     = 2049
+	GeogAngularUnitsGeoKey = 2054
+	ProjectedCSTypeGeoKey  = 3072
+	PCSCitationGeoKey      = 3073
+	ProjLinearUnitsGeoKey  = 3076
+	VerticalCSTypeGeoKey   = 4096
+	VerticalCitationGeoKey = 4097
+	VerticalDatumGeoKey    = 4098
+	VerticalUnitsGeoKey    = 4099
+	GeoKeyUserDefined      = 32767
+)
+
+// GeoKey locations, which name the tag holding a key's value.  Values held directly in the directory have no tag.
+const (
+	geoKeyLocationShort   = 0
+	geoKeyLocationDoubles = RecordIDGeoDoubleParams
+	geoKeyLocationASCII   = RecordIDGeoASCIIParams
+)
+
+// geoKeyDirectoryHeadSize is the number of shorts preceding the key entries of a geo key directory.
+const geoKeyDirectoryHeadSize = 4
+
+// Model types of GTModelTypeGeoKey.
+const (
+	ModelTypeProjected  = 1
+	ModelTypeGeographic = 2
+	ModelTypeGeocentric = 3
+)
+
+// GeoKey is a single entry of a GeoTIFF key directory.
+type GeoKey struct {
+	ID          uint16
+	Location    uint16
+	Count       uint16
+	ValueOffset uint16
+}
+
+// GeoKeyDirectory holds the GeoTIFF keys that describe a file's coordinate reference system, along with the double
+// and ASCII parameters they refer to.
+type GeoKeyDirectory struct {
+	KeyDirectoryVersion uint16
+	KeyRevision         uint16
+	MinorRevision       uint16
+	Keys                []GeoKey
+	Doubles             []float64
+	ASCII               string
+}
+
+// DecodeGeoKeyDirectory decodes the data of the GeoKeyDirectoryTag VLR along with the optional GeoDoubleParamsTag and
+// GeoAsciiParamsTag VLRs.
+func DecodeGeoKeyDirectory(directory []byte, doubles []byte, ascii []byte) (error, *GeoKeyDirectory) {
+	if len(directory) < 8 || len(directory)%2 != 0 {
+		return fmt.Errorf("geo key directory of %d bytes is malformed", len(directory)), nil
+	}
+
+	shorts := make([]uint16, len(directory)/2)
+	for i := range shorts {
+		shorts[i] = binary.LittleEndian.Uint16(directory[i*2:])
+	}
+
+	gkd := &GeoKeyDirectory{
+		KeyDirectoryVersion: shorts[0],
+		KeyRevision:         shorts[1],
+		MinorRevision:       shorts[2],
+	}
+
+	n := (int)(shorts[3])
+	if geoKeyDirectoryHeadSize+n*4 > len(shorts) {
+		return fmt.Errorf("geo key directory claims %d keys but holds %d", n, (len(shorts)-geoKeyDirectoryHeadSize)/4), nil
+	}
+
+	gkd.Keys = make([]GeoKey, n)
+	for i := range gkd.Keys {
+		e := shorts[geoKeyDirectoryHeadSize+i*4:]
+		gkd.Keys[i] = GeoKey{ID: e[0], Location: e[1], Count: e[2], ValueOffset: e[3]}
+	}
+
+	gkd.Doubles = make([]float64, len(doubles)/8)
+	for i := range gkd.Doubles {
+		gkd.Doubles[i] = math.Float64frombits(binary.LittleEndian.Uint64(doubles[i*8:]))
+	}
+	gkd.ASCII = string(ascii)
+
+	return nil, gkd
+}
+
+// Encode lays out the directory as the data of the GeoKeyDirectoryTag, GeoDoubleParamsTag and GeoAsciiParamsTag
+// VLRs.  The double and ASCII data are nil when the directory has none.
+func (gkd *GeoKeyDirectory) Encode() (directory []byte, doubles []byte, ascii []byte) {
+	directory = make([]byte, (geoKeyDirectoryHeadSize+len(gkd.Keys)*4)*2)
+	head := [...]uint16{gkd.KeyDirectoryVersion, gkd.KeyRevision, gkd.MinorRevision, (uint16)(len(gkd.Keys))}
+	for i, v := range head {
+		binary.LittleEndian.PutUint16(directory[i*2:], v)
+	}
+	for i, k := range gkd.Keys {
+		off := (geoKeyDirectoryHeadSize + i*4) * 2
+		binary.LittleEndian.PutUint16(directory[off:], k.ID)
+		binary.LittleEndian.PutUint16(directory[off+2:], k.Location)
+		binary.LittleEndian.PutUint16(directory[off+4:], k.Count)
+		binary.LittleEndian.PutUint16(directory[off+6:], k.ValueOffset)
+	}
+
+	if len(gkd.Doubles) > 0 {
+		doubles = make([]byte, len(gkd.Doubles)*8)
+		for i, v := range gkd.Doubles {
+			binary.LittleEndian.PutUint64(doubles[i*8:], math.Float64bits(v))
+		}
+	}
+
+	if len(gkd.ASCII) > 0 {
+		ascii = []byte(gkd.ASCII)
+	}
+
+	return directory, doubles, ascii
+}
+
+// Key returns the directory's entry for id.
+func (gkd *GeoKeyDirectory) Key(id uint16) (GeoKey, bool) {
+	for _, k := range gkd.Keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return GeoKey{}, false
+}
+
+// Short returns the value of a key stored directly in the directory.
+func (gkd *GeoKeyDirectory) Short(id uint16) (uint16, bool) {
+	k, ok := gkd.Key(id)
+	if !ok || k.Location != geoKeyLocationShort {
+		return 0, false
+	}
+	return k.ValueOffset, true
+}
+
+// Double returns the values of a key stored in the double parameters.
+func (gkd *GeoKeyDirectory) Double(id uint16) ([]float64, bool) {
+	k, ok := gkd.Key(id)
+	if !ok || k.Location != geoKeyLocationDoubles {
+		return nil, false
+	}
+
+	start, end := (int)(k.ValueOffset), (int)(k.ValueOffset)+(int)(k.Count)
+	if end > len(gkd.Doubles) {
+		return nil, false
+	}
+	return gkd.Doubles[start:end], true
+}
+
+// Text returns the value of a key stored in the ASCII parameters, without its '|' terminator.
+func (gkd *GeoKeyDirectory) Text(id uint16) (string, bool) {
+	k, ok := gkd.Key(id)
+	if !ok || k.Location != geoKeyLocationASCII {
+		return "", false
+	}
+
+	start, end := (int)(k.ValueOffset), (int)(k.ValueOffset)+(int)(k.Count)
+	if end > len(gkd.ASCII) {
+		return "", false
+	}
+	return strings.TrimRight(gkd.ASCII[start:end], "|\x00"), true
+}
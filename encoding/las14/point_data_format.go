@@ -3,21 +3,65 @@ package las14
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 )
 
 func init() {
 
 }
 
+// standardLengths holds the size in bytes of the fields the spec defines for each point data record format.
+var standardLengths = [...]uint16{20, 28, 26, 34, 57, 63, 30, 36, 38, 59, 67}
+
+// IsValid reports whether pdf is a point data record format defined by the LAS 1.4 spec.
+func (pdf PointDataFormat) IsValid() bool {
+	return (int)(pdf) < len(standardLengths)
+}
+
+// StandardLength returns the size in bytes of the fields the spec defines for pdf, excluding any extra bytes.
+// Invalid formats have a standard length of 0.
+func (pdf PointDataFormat) StandardLength() uint16 {
+	if !pdf.IsValid() {
+		return 0
+	}
+
+	return standardLengths[pdf]
+}
+
+// decodeXYZ decodes the signed 32-bit coordinates that begin every point data record format.
+func decodeXYZ(raw []byte) (x int64, y int64, z int64) {
+	x = (int64)((int32)(binary.LittleEndian.Uint32(raw[0:4])))
+	y = (int64)((int32)(binary.LittleEndian.Uint32(raw[4:8])))
+	z = (int64)((int32)(binary.LittleEndian.Uint32(raw[8:12])))
+	return
+}
+
+// pdr0 decodes the legacy point data record formats 0 through 5, which share a common 20 byte prefix.
+type pdr0 struct {
+	pdr *PointDataRecord
+}
+
+func (p *pdr0) XYZ() (x int64, y int64, z int64) {
+	return decodeXYZ(p.pdr.Raw)
+}
+
+func (p *pdr0) Intensity() uint16 {
+	return binary.LittleEndian.Uint16(p.pdr.Raw[12:14])
+}
+
+func (p *pdr0) Classification() byte {
+	return p.pdr.Raw[15] & 0x1f
+}
+
+var _ PointData = (*pdr0)(nil)
+
+// pdr6 decodes the point data record formats 6 through 10, which share a common 30 byte prefix.
 type pdr6 struct {
 	pdr *PointDataRecord
 }
 
 func (p *pdr6) XYZ() (x int64, y int64, z int64) {
-	x = (int64)(binary.LittleEndian.Uint32(p.pdr.Raw[0:4]))
-	y = (int64)(binary.LittleEndian.Uint32(p.pdr.Raw[4:8]))
-	z = (int64)(binary.LittleEndian.Uint32(p.pdr.Raw[8:12]))
-	return
+	return decodeXYZ(p.pdr.Raw)
 }
 
 func (p *pdr6) Intensity() uint16 {
@@ -28,13 +72,30 @@ func (p *pdr6) Classification() byte {
 	return p.pdr.Raw[16]
 }
 
-var _ = (*pdr6)(nil)
+var _ PointData = (*pdr6)(nil)
 
 func (pdr *PointDataRecord) Get() PointData {
 	switch pdr.Format {
-	case 6:
+	case 0, 1, 2, 3, 4, 5:
+		return &pdr0{pdr}
+	case 6, 7, 8, 9, 10:
 		return &pdr6{pdr}
 	default:
 		panic(fmt.Sprintf("unhandled format encountered: %d", pdr.Format))
 	}
 }
+
+// SetXYZ overwrites the record's unscaled coordinates, failing if any does not fit in the signed 32-bit range the
+// format stores.
+func (pdr *PointDataRecord) SetXYZ(x int64, y int64, z int64) error {
+	for _, v := range [...]int64{x, y, z} {
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return fmt.Errorf("coordinate %d out of range for a point data record", v)
+		}
+	}
+
+	binary.LittleEndian.PutUint32(pdr.Raw[0:4], (uint32)((int32)(x)))
+	binary.LittleEndian.PutUint32(pdr.Raw[4:8], (uint32)((int32)(y)))
+	binary.LittleEndian.PutUint32(pdr.Raw[8:12], (uint32)((int32)(z)))
+	return nil
+}
@@ -0,0 +1,64 @@
+package las14
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestPointDataRecordGet(t *testing.T) {
+	points := []lastest.Point{
+		{X: 0, Y: 0, Z: 0, Intensity: 1, Classification: 2},
+		{X: -1, Y: -2, Z: -3, Intensity: 65535, Classification: 31},
+		{X: math.MinInt32, Y: math.MaxInt32, Z: -1000000, Intensity: 42, Classification: 9},
+		{X: 123456, Y: -4567890, Z: 7, Intensity: 7, Classification: 17},
+	}
+
+	for format := byte(0); format <= 10; format++ {
+		file := lastest.Generate(lastest.Spec{Header: lastest.Header{PointDataRecordFormat: format}, Points: points})
+
+		err, fr := NewBytesDecoder(file.Bytes).FullDecode("")
+		if err != nil {
+			t.Fatalf("format %d: decode failed: %v", format, err)
+		}
+
+		for i, want := range points {
+			pd := fr.PointDataRecord((uint64)(i)).Get()
+
+			x, y, z := pd.XYZ()
+			if x != (int64)(want.X) || y != (int64)(want.Y) || z != (int64)(want.Z) {
+				t.Errorf("format %d point %d: xyz = (%d, %d, %d), want (%d, %d, %d)", format, i, x, y, z, want.X, want.Y, want.Z)
+			}
+
+			if got := pd.Intensity(); got != want.Intensity {
+				t.Errorf("format %d point %d: intensity = %d, want %d", format, i, got, want.Intensity)
+			}
+
+			if got := pd.Classification(); got != want.Classification {
+				t.Errorf("format %d point %d: classification = %d, want %d", format, i, got, want.Classification)
+			}
+		}
+	}
+}
+
+func TestPointDataRecordSetXYZ(t *testing.T) {
+	file := lastest.Generate(lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 1}, PointCount: 1})
+	err, fr := NewBytesDecoder(file.Bytes).FullDecode("")
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	pdr := fr.PointDataRecord(0)
+	if err := pdr.SetXYZ(-5, math.MaxInt32, math.MinInt32); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+
+	if x, y, z := pdr.Get().XYZ(); x != -5 || y != math.MaxInt32 || z != math.MinInt32 {
+		t.Errorf("xyz = (%d, %d, %d) after set", x, y, z)
+	}
+
+	if err := pdr.SetXYZ(0, math.MaxInt32+1, 0); err == nil {
+		t.Error("expected out of range coordinate to fail")
+	}
+}
@@ -12,16 +12,34 @@ func init() {
 }
 
 const SummaryTemplateSource = `
+	FileSourceID                             = {{.FileSourceID}}
+	GlobalEncoding                           = {{printf "%#04x" .GlobalEncoding}}
+	ProjectID                                = {{.ProjectID}}
+	Version                                  = {{.VersionMajor}}.{{.VersionMinor}}
+	SystemID                                 = {{.SystemIDString}}
+	GeneratingSoftware                       = {{.GeneratingSoftwareString}}
+	FileCreationDayOfYear                    = {{.FileCreationDayOfYear}}
+	FileCreationYear                         = {{.FileCreationYear}}
+
 	HeaderSize                               = {{.HeaderSize}}
 	OffsetToPointData                        = {{.OffsetToPointData}}
 	StartOfWaveformDataPacketRecord          = {{.StartOfWaveformDataPacketRecord}}
 	StartOfFirstExtendedVariableLengthRecord = {{.StartOfFirstExtendedVariableLengthRecord}}
 
+	PointDataRecordFormat                    = {{.PointDataRecordFormat}}
+	PointDataRecordLength                    = {{.PointDataRecordLength}}
 	LegacyNumberOfPointRecords               = {{.LegacyNumberOfPointRecords}}
+	LegacyNumberOfPointsByReturn             = {{.LegacyNumberOfPointsByReturn}}
 	NumberOfPointRecords                     = {{.NumberOfPointRecords}}
+	NumberOfPointsByReturn                   = {{.NumberOfPointsByReturn}}
 
 	NumberOfVariableLengthRecords            = {{.NumberOfVariableLengthRecords}}
 	NumberOfExtendedVariableLengthRecords    = {{.NumberOfExtendedVariableLengthRecords}}
+
+	ScaleFactor                              = {{.XScaleFactor}} {{.YScaleFactor}} {{.ZScaleFactor}}
+	Offset                                   = {{.XOffset}} {{.YOffset}} {{.ZOffset}}
+	Min                                      = {{.MinX}} {{.MinY}} {{.MinZ}}
+	Max                                      = {{.MaxX}} {{.MaxY}} {{.MaxZ}}
 `
 
 var (
@@ -38,3 +56,23 @@ func (phb *PublicHeaderBlock) String() string {
 
 	return ret.String()
 }
+
+// PointCount returns the number of point records in the file, preferring the 64-bit LAS 1.4 count and falling back
+// to the legacy count written by older software.
+func (phb *PublicHeaderBlock) PointCount() uint64 {
+	if phb.NumberOfPointRecords != 0 {
+		return phb.NumberOfPointRecords
+	}
+
+	return (uint64)(phb.LegacyNumberOfPointRecords)
+}
+
+// SystemIDString returns the header's system identifier without its NUL padding.
+func (phb *PublicHeaderBlock) SystemIDString() string {
+	return trimNUL(phb.SystemID[:])
+}
+
+// GeneratingSoftwareString returns the name of the software that wrote the file without its NUL padding.
+func (phb *PublicHeaderBlock) GeneratingSoftwareString() string {
+	return trimNUL(phb.GeneratingSoftware[:])
+}
@@ -0,0 +1,243 @@
+package las14
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// A ReaderAtDecoder reads and decodes LAS 1.4 files from an io.ReaderAt.  Unlike Decoder it keeps no read position,
+// so multiple goroutines may decode distinct point ranges and VLRs from the same ReaderAtDecoder concurrently.
+//
+// Unlike Decoder's, its budget bounds each read rather than all of them together: every read also lies within the
+// input's size, so a decoder shared for the life of a file can read from it as often as it is asked to.
+type ReaderAtDecoder struct {
+	// budget is first in the struct to guarantee 64-bit alignment for atomic access on 32-bit platforms
+	budget uint64
+
+	r    io.ReaderAt
+	size int64
+
+	fpOnce sync.Once
+	fp     *FirstPassResult
+	fpErr  error
+}
+
+// NewReaderAtDecoder returns a new decoder that reads the size bytes of r.
+func NewReaderAtDecoder(r io.ReaderAt, size int64) *ReaderAtDecoder {
+	return &ReaderAtDecoder{r: r, size: size, budget: (uint64)(DefaultBudget)}
+}
+
+// NewBytesDecoder returns a new decoder that reads the LAS file held in b.
+func NewBytesDecoder(b []byte) *ReaderAtDecoder {
+	return NewReaderAtDecoder(bytes.NewReader(b), (int64)(len(b)))
+}
+
+// SetBudget replaces the largest number of bytes the decoder may read at once.
+func (las *ReaderAtDecoder) SetBudget(budget uint64) {
+	atomic.StoreUint64(&las.budget, budget)
+}
+
+// PointRange is a contiguous run of point data records read from a LAS file.
+type PointRange struct {
+	Start  uint64
+	Count  uint64
+	Format PointDataFormat
+	Length uint16
+
+	pointData []byte
+}
+
+// PointDataRecord returns the record at idx, relative to the start of the range.
+func (pr *PointRange) PointDataRecord(idx uint64) *PointDataRecord {
+	offset := idx * (uint64)(pr.Length)
+
+	return &PointDataRecord{
+		Raw:    pr.pointData[offset : offset+(uint64)(pr.Length)],
+		Format: pr.Format,
+	}
+}
+
+func (las *ReaderAtDecoder) FirstPassDecode() (error, *FirstPassResult) {
+	las.fpOnce.Do(func() {
+		err := las.charge(Las14HeaderSize)
+		if err != nil {
+			las.fpErr = err
+			return
+		}
+
+		// NOTE: the header is parsed by a stream decoder over a section of the underlying reader, budgeted to exactly
+		// the header size so that the parsing code is shared between both decoders.
+		hd := &Decoder{r: io.NewSectionReader(las.r, 0, las.size), budget: Las14HeaderSize}
+		las.fpErr, las.fp = hd.firstPassDecode()
+	})
+
+	return las.fpErr, las.fp
+}
+
+func (las *ReaderAtDecoder) FullDecode(qs QuerySet) (error, *FullResult) {
+	err, fp := las.FirstPassDecode()
+	if err != nil {
+		return fmt.Errorf("full decode failed: invoked first pass decode failed with %w", err), nil
+	}
+
+	err, vlrs := las.VariableLengthRecords()
+	if err != nil {
+		return fmt.Errorf("full decode failed: %w", err), nil
+	}
+
+	err, pr := las.PointRange(0, fp.Header.PointCount())
+	if err != nil {
+		return fmt.Errorf("full decode failed: %w", err), nil
+	}
+
+	err, evlrs := las.ExtendedVariableLengthRecords()
+	if err != nil {
+		return fmt.Errorf("full decode failed: %w", err), nil
+	}
+
+	return nil, &FullResult{
+		FirstPassResult:               *fp,
+		VariableLengthRecords:         vlrs,
+		ExtendedVariableLengthRecords: evlrs,
+		pointData:                     pr.pointData,
+	}
+}
+
+// PointRange reads count point data records beginning with the record at index start.
+func (las *ReaderAtDecoder) PointRange(start uint64, count uint64) (error, *PointRange) {
+	err, fp := las.FirstPassDecode()
+	if err != nil {
+		return fmt.Errorf("point range decode failed: invoked first pass decode failed with %w", err), nil
+	}
+
+	total := fp.Header.PointCount()
+	if start > total || count > total-start {
+		return fmt.Errorf("point range [%d, %d) out of bounds: file has %d points", start, start+count, total), nil
+	}
+
+	length := (uint64)(fp.Header.PointDataRecordLength)
+	if length != 0 && (count > math.MaxInt64/length || start > math.MaxInt64/length) {
+		return fmt.Errorf("point range [%d, %d) too large", start, start+count), nil
+	}
+
+	pointData := make([]byte, 0)
+	if count > 0 {
+		err, pointData = las.readAt((int64)(fp.Header.OffsetToPointData)+(int64)(start*length), count*length)
+		if err != nil {
+			return fmt.Errorf("failed to read point data: %w", err), nil
+		}
+	}
+
+	return nil, &PointRange{
+		Start:     start,
+		Count:     count,
+		Format:    fp.Header.PointDataRecordFormat,
+		Length:    fp.Header.PointDataRecordLength,
+		pointData: pointData,
+	}
+}
+
+// VariableLengthRecords reads every variable length record that follows the public header block.
+func (las *ReaderAtDecoder) VariableLengthRecords() (error, []VariableLengthRecord) {
+	err, fp := las.FirstPassDecode()
+	if err != nil {
+		return fmt.Errorf("vlr decode failed: invoked first pass decode failed with %w", err), nil
+	}
+
+	var vlrs []VariableLengthRecord
+	offset := (int64)(fp.Header.HeaderSize)
+	for i := uint32(0); i < fp.Header.NumberOfVariableLengthRecords; i++ {
+		err, buf := las.readAt(offset, VLRHeaderSize)
+		if err != nil {
+			return fmt.Errorf("failed to read vlr %d header: %w", i, err), nil
+		}
+		vlr := decodeVLRHeader(buf)
+		offset += VLRHeaderSize
+
+		err, vlr.Data = las.readAt(offset, (uint64)(vlr.RecordLengthAfterHeader))
+		if err != nil {
+			return fmt.Errorf("failed to read vlr %d data: %w", i, err), nil
+		}
+		offset += (int64)(vlr.RecordLengthAfterHeader)
+
+		vlrs = append(vlrs, vlr)
+	}
+
+	return nil, vlrs
+}
+
+// ExtendedVariableLengthRecords reads every extended variable length record that follows the point data records.
+func (las *ReaderAtDecoder) ExtendedVariableLengthRecords() (error, []ExtendedVariableLengthRecord) {
+	err, fp := las.FirstPassDecode()
+	if err != nil {
+		return fmt.Errorf("evlr decode failed: invoked first pass decode failed with %w", err), nil
+	}
+
+	var evlrs []ExtendedVariableLengthRecord
+	if fp.Header.NumberOfExtendedVariableLengthRecords == 0 {
+		return nil, evlrs
+	}
+
+	if fp.Header.StartOfFirstExtendedVariableLengthRecord > math.MaxInt64 {
+		return fmt.Errorf("invalid evlr offset: %d", fp.Header.StartOfFirstExtendedVariableLengthRecord), nil
+	}
+
+	offset := (int64)(fp.Header.StartOfFirstExtendedVariableLengthRecord)
+	for i := uint32(0); i < fp.Header.NumberOfExtendedVariableLengthRecords; i++ {
+		err, buf := las.readAt(offset, EVLRHeaderSize)
+		if err != nil {
+			return fmt.Errorf("failed to read evlr %d header: %w", i, err), nil
+		}
+		evlr := decodeEVLRHeader(buf)
+		offset += EVLRHeaderSize
+
+		err, evlr.Data = las.readAt(offset, evlr.RecordLengthAfterHeader)
+		if err != nil {
+			return fmt.Errorf("failed to read evlr %d data: %w", i, err), nil
+		}
+		offset += (int64)(evlr.RecordLengthAfterHeader)
+
+		evlrs = append(evlrs, evlr)
+	}
+
+	return nil, evlrs
+}
+
+// readAt reads n bytes at offset off, failing rather than allocating when the read would extend past the end of the
+// underlying reader or exceed the read budget.
+func (las *ReaderAtDecoder) readAt(off int64, n uint64) (error, []byte) {
+	if off < 0 || off > las.size || n > (uint64)(las.size-off) {
+		return fmt.Errorf("read of %d bytes at offset %d extends past end of input (%d bytes)", n, off, las.size), nil
+	}
+
+	err := las.charge(n)
+	if err != nil {
+		return err, nil
+	}
+
+	p := make([]byte, n)
+	read, err := las.r.ReadAt(p, off)
+	// NOTE: ReadAt is permitted to return io.EOF alongside a complete read
+	if read == len(p) {
+		return nil, p
+	}
+
+	if err != nil {
+		return fmt.Errorf("safe read failed: %w", err), nil
+	}
+
+	return fmt.Errorf("safe read failed: only %d of %d bytes read", read, n), nil
+}
+
+// charge checks a read of requested bytes against the read budget.  See Decoder#safeRead for the single-threaded
+// equivalent, which spends its budget instead.
+func (las *ReaderAtDecoder) charge(requested uint64) error {
+	if budget := atomic.LoadUint64(&las.budget); requested > budget {
+		return fmt.Errorf("read budget exceeded: %d byte read requested of at most %d", requested, budget)
+	}
+	return nil
+}
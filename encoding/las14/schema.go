@@ -0,0 +1,454 @@
+package las14
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DataType identifies how a dimension's value is stored within a point data record.  The numeric values match the
+// data_type field of an extra bytes descriptor.
+type DataType byte
+
+const (
+	TypeUndocumented DataType = iota
+	TypeUint8
+	TypeInt8
+	TypeUint16
+	TypeInt16
+	TypeUint32
+	TypeInt32
+	TypeUint64
+	TypeInt64
+	TypeFloat32
+	TypeFloat64
+)
+
+var dataTypeSizes = [...]int{0, 1, 1, 2, 2, 4, 4, 8, 8, 4, 8}
+
+var dataTypeNames = [...]string{"bytes", "uint8", "int8", "uint16", "int16", "uint32", "int32", "uint64", "int64", "float32", "float64"}
+
+// Size returns the number of bytes a value of type dt occupies, or 0 for undocumented bytes whose size is given by
+// the dimension.
+func (dt DataType) Size() int {
+	if (int)(dt) >= len(dataTypeSizes) {
+		return 0
+	}
+	return dataTypeSizes[dt]
+}
+
+func (dt DataType) String() string {
+	if (int)(dt) >= len(dataTypeNames) {
+		return fmt.Sprintf("DataType(%d)", dt)
+	}
+	return dataTypeNames[dt]
+}
+
+// IsFloat reports whether dt is a floating point type.
+func (dt DataType) IsFloat() bool {
+	return dt == TypeFloat32 || dt == TypeFloat64
+}
+
+// Dimension describes a single attribute stored in each point data record of a file.
+type Dimension struct {
+	Name        string
+	Description string
+	Type        DataType
+
+	// ByteOffset and Size locate the dimension within the record.
+	ByteOffset int
+	Size       int
+
+	// Bits is non-zero for dimensions packed into a bit field, which occupy Bits bits of the byte at ByteOffset
+	// starting at bit Shift.
+	Bits  uint8
+	Shift uint8
+
+	// Scaled dimensions store a value of Raw * Scale + Offset.
+	Scaled bool
+	Scale  float64
+	Offset float64
+
+	// Extra is set for dimensions described by an extra bytes VLR rather than the point data record format.
+	Extra bool
+
+	// NoData, when HasNoData is set, is the raw value that marks a point as lacking this dimension.
+	HasNoData bool
+	NoData    float64
+}
+
+// Raw returns the dimension's stored value from record as its native Go type: a bool for single bit flags, the
+// matching integer or float type for numeric dimensions, and a []byte for undocumented extra bytes.
+func (d *Dimension) Raw(record []byte) interface{} {
+	if d.Bits != 0 {
+		v := (record[d.ByteOffset] >> d.Shift) & (1<<d.Bits - 1)
+		if d.Bits == 1 {
+			return v != 0
+		}
+		return v
+	}
+
+	b := record[d.ByteOffset : d.ByteOffset+d.Size]
+	switch d.Type {
+	case TypeUint8:
+		return b[0]
+	case TypeInt8:
+		return (int8)(b[0])
+	case TypeUint16:
+		return binary.LittleEndian.Uint16(b)
+	case TypeInt16:
+		return (int16)(binary.LittleEndian.Uint16(b))
+	case TypeUint32:
+		return binary.LittleEndian.Uint32(b)
+	case TypeInt32:
+		return (int32)(binary.LittleEndian.Uint32(b))
+	case TypeUint64:
+		return binary.LittleEndian.Uint64(b)
+	case TypeInt64:
+		return (int64)(binary.LittleEndian.Uint64(b))
+	case TypeFloat32:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	case TypeFloat64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	default:
+		return b
+	}
+}
+
+// RawFloat64 returns the dimension's stored value from record converted to a float64, without applying any scale or
+// offset.  Undocumented extra bytes have no numeric value and return NaN.
+func (d *Dimension) RawFloat64(record []byte) float64 {
+	switch v := d.Raw(record).(type) {
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case uint8:
+		return (float64)(v)
+	case int8:
+		return (float64)(v)
+	case uint16:
+		return (float64)(v)
+	case int16:
+		return (float64)(v)
+	case uint32:
+		return (float64)(v)
+	case int32:
+		return (float64)(v)
+	case uint64:
+		return (float64)(v)
+	case int64:
+		return (float64)(v)
+	case float32:
+		return (float64)(v)
+	case float64:
+		return v
+	default:
+		return math.NaN()
+	}
+}
+
+// Float64 returns the dimension's value from record with its scale and offset applied.
+func (d *Dimension) Float64(record []byte) float64 {
+	v := d.RawFloat64(record)
+	if d.Scaled {
+		return v*d.Scale + d.Offset
+	}
+	return v
+}
+
+// IsNoData reports whether record holds the dimension's no data value.
+func (d *Dimension) IsNoData(record []byte) bool {
+	return d.HasNoData && d.RawFloat64(record) == d.NoData
+}
+
+// SetFloat64 stores v in record, removing the dimension's scale and offset and rounding to the nearest value its type
+// can hold.  Values outside the range of the type or bit field are an error, as are undocumented extra bytes.
+func (d *Dimension) SetFloat64(record []byte, v float64) error {
+	if d.Scaled {
+		v = (v - d.Offset) / d.Scale
+	}
+	return d.SetRawFloat64(record, v)
+}
+
+// SetRawFloat64 stores v in record as is, without removing any scale or offset.  See SetFloat64.
+func (d *Dimension) SetRawFloat64(record []byte, v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%s: non-finite value %v", d.Name, v)
+	}
+
+	if d.Bits != 0 {
+		max := (float64)((int)(1)<<d.Bits - 1)
+		r := math.Round(v)
+		if r < 0 || r > max {
+			return fmt.Errorf("%s: %v out of range 0 to %v", d.Name, v, max)
+		}
+		mask := (byte)(1<<d.Bits-1) << d.Shift
+		record[d.ByteOffset] = record[d.ByteOffset]&^mask | (byte)(r)<<d.Shift
+		return nil
+	}
+
+	b := record[d.ByteOffset : d.ByteOffset+d.Size]
+	if d.Type.IsFloat() {
+		if d.Type == TypeFloat32 {
+			binary.LittleEndian.PutUint32(b, math.Float32bits((float32)(v)))
+		} else {
+			binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+		}
+		return nil
+	}
+
+	var min, max float64
+	switch d.Type {
+	case TypeUint8:
+		min, max = 0, math.MaxUint8
+	case TypeInt8:
+		min, max = math.MinInt8, math.MaxInt8
+	case TypeUint16:
+		min, max = 0, math.MaxUint16
+	case TypeInt16:
+		min, max = math.MinInt16, math.MaxInt16
+	case TypeUint32:
+		min, max = 0, math.MaxUint32
+	case TypeInt32:
+		min, max = math.MinInt32, math.MaxInt32
+	case TypeUint64:
+		// the largest float64 below 2^64 and 2^63, since the integer maximums themselves round up to those powers
+		min, max = 0, math.Nextafter(1<<64, 0)
+	case TypeInt64:
+		min, max = math.MinInt64, math.Nextafter(1<<63, 0)
+	default:
+		return fmt.Errorf("%s: undocumented bytes have no numeric value", d.Name)
+	}
+
+	r := math.Round(v)
+	if r < min || r > max {
+		return fmt.Errorf("%s: %v out of range for %s", d.Name, v, d.Type)
+	}
+
+	switch d.Type {
+	case TypeUint8, TypeInt8:
+		b[0] = (byte)((int64)(r))
+	case TypeUint16:
+		binary.LittleEndian.PutUint16(b, (uint16)(r))
+	case TypeInt16:
+		binary.LittleEndian.PutUint16(b, (uint16)((int16)(r)))
+	case TypeUint32:
+		binary.LittleEndian.PutUint32(b, (uint32)(r))
+	case TypeInt32:
+		binary.LittleEndian.PutUint32(b, (uint32)((int32)(r)))
+	case TypeUint64:
+		binary.LittleEndian.PutUint64(b, (uint64)(r))
+	case TypeInt64:
+		binary.LittleEndian.PutUint64(b, (uint64)((int64)(r)))
+	}
+	return nil
+}
+
+// Schema describes the dimensions stored in each point data record of a file.
+type Schema struct {
+	Format       PointDataFormat
+	RecordLength uint16
+	Dimensions   []Dimension
+
+	index map[string]int
+}
+
+// NewSchema returns the schema for records described by header, including any extra bytes described by an extra
+// bytes VLR within vlrs.
+func NewSchema(header *PublicHeaderBlock, vlrs []VariableLengthRecord) (error, *Schema) {
+	format := header.PointDataRecordFormat
+	if !format.IsValid() {
+		return fmt.Errorf("unrecognized point data record format: %d", format), nil
+	}
+
+	s := &Schema{
+		Format:       format,
+		RecordLength: header.PointDataRecordLength,
+	}
+
+	s.Dimensions = append(s.Dimensions, standardDimensions(header)...)
+
+	for _, vlr := range vlrs {
+		if !vlr.IsExtraBytes() {
+			continue
+		}
+
+		err, descriptors := DecodeExtraBytesDescriptors(vlr.Data)
+		if err != nil {
+			return fmt.Errorf("invalid extra bytes vlr: %w", err), nil
+		}
+
+		offset := (int)(format.StandardLength())
+		for _, ebd := range descriptors {
+			dims := ebd.Dimensions(offset)
+			for _, d := range dims {
+				offset += d.Size
+			}
+			s.Dimensions = append(s.Dimensions, dims...)
+		}
+
+		if offset > (int)(header.PointDataRecordLength) {
+			return fmt.Errorf("extra bytes describe %d byte records but records are %d bytes", offset, header.PointDataRecordLength), nil
+		}
+		break
+	}
+
+	s.index = make(map[string]int, len(s.Dimensions))
+	for i, d := range s.Dimensions {
+		key := strings.ToLower(d.Name)
+		if _, ok := s.index[key]; !ok {
+			s.index[key] = i
+		}
+	}
+
+	return nil, s
+}
+
+// Dimension returns the dimension named name, ignoring case.
+func (s *Schema) Dimension(name string) (*Dimension, bool) {
+	i, ok := s.index[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	return &s.Dimensions[i], true
+}
+
+// Names returns the names of every dimension in the schema, in record order.
+func (s *Schema) Names() []string {
+	names := make([]string, len(s.Dimensions))
+	for i, d := range s.Dimensions {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// Dimension names for the fields defined by the point data record formats.
+const (
+	DimX                           = "X"
+	DimY                           = "Y"
+	DimZ                           = "Z"
+	DimIntensity                   = "Intensity"
+	DimReturnNumber                = "ReturnNumber"
+	DimNumberOfReturns             = "NumberOfReturns"
+	DimSynthetic                   = "Synthetic"
+	DimKeyPoint                    = "KeyPoint"
+	DimWithheld                    = "Withheld"
+	DimOverlap                     = "Overlap"
+	DimScannerChannel              = "ScannerChannel"
+	DimScanDirectionFlag           = "ScanDirectionFlag"
+	DimEdgeOfFlightLine            = "EdgeOfFlightLine"
+	DimClassification              = "Classification"
+	DimScanAngle                   = "ScanAngle"
+	DimUserData                    = "UserData"
+	DimPointSourceID               = "PointSourceID"
+	DimGPSTime                     = "GPSTime"
+	DimRed                         = "Red"
+	DimGreen                       = "Green"
+	DimBlue                        = "Blue"
+	DimNIR                         = "NIR"
+	DimWavePacketDescriptorIndex   = "WavePacketDescriptorIndex"
+	DimByteOffsetToWaveformData    = "ByteOffsetToWaveformData"
+	DimWaveformPacketSize          = "WaveformPacketSize"
+	DimReturnPointWaveformLocation = "ReturnPointWaveformLocation"
+	DimXt                          = "Xt"
+	DimYt                          = "Yt"
+	DimZt                          = "Zt"
+)
+
+// scanAngleScale converts the 16-bit scan angle of formats 6 through 10 to degrees.
+const scanAngleScale = 0.006
+
+func standardDimensions(header *PublicHeaderBlock) []Dimension {
+	format := header.PointDataRecordFormat
+
+	field := func(name string, t DataType, offset int) Dimension {
+		return Dimension{Name: name, Type: t, ByteOffset: offset, Size: t.Size()}
+	}
+	bits := func(name string, offset int, shift uint8, n uint8) Dimension {
+		return Dimension{Name: name, Type: TypeUint8, ByteOffset: offset, Size: 1, Bits: n, Shift: shift}
+	}
+	scaled := func(d Dimension, scale float64, offset float64) Dimension {
+		d.Scaled, d.Scale, d.Offset = true, scale, offset
+		return d
+	}
+
+	dims := []Dimension{
+		scaled(field(DimX, TypeInt32, 0), header.XScaleFactor, header.XOffset),
+		scaled(field(DimY, TypeInt32, 4), header.YScaleFactor, header.YOffset),
+		scaled(field(DimZ, TypeInt32, 8), header.ZScaleFactor, header.ZOffset),
+		field(DimIntensity, TypeUint16, 12),
+	}
+
+	var next int
+	if format < 6 {
+		dims = append(dims,
+			bits(DimReturnNumber, 14, 0, 3),
+			bits(DimNumberOfReturns, 14, 3, 3),
+			bits(DimScanDirectionFlag, 14, 6, 1),
+			bits(DimEdgeOfFlightLine, 14, 7, 1),
+			bits(DimClassification, 15, 0, 5),
+			bits(DimSynthetic, 15, 5, 1),
+			bits(DimKeyPoint, 15, 6, 1),
+			bits(DimWithheld, 15, 7, 1),
+			scaled(field(DimScanAngle, TypeInt8, 16), 1, 0),
+			field(DimUserData, TypeUint8, 17),
+			field(DimPointSourceID, TypeUint16, 18),
+		)
+		next = 20
+		if format != 0 && format != 2 {
+			dims = append(dims, field(DimGPSTime, TypeFloat64, 20))
+			next = 28
+		}
+	} else {
+		dims = append(dims,
+			bits(DimReturnNumber, 14, 0, 4),
+			bits(DimNumberOfReturns, 14, 4, 4),
+			bits(DimSynthetic, 15, 0, 1),
+			bits(DimKeyPoint, 15, 1, 1),
+			bits(DimWithheld, 15, 2, 1),
+			bits(DimOverlap, 15, 3, 1),
+			bits(DimScannerChannel, 15, 4, 2),
+			bits(DimScanDirectionFlag, 15, 6, 1),
+			bits(DimEdgeOfFlightLine, 15, 7, 1),
+			field(DimClassification, TypeUint8, 16),
+			field(DimUserData, TypeUint8, 17),
+			scaled(field(DimScanAngle, TypeInt16, 18), scanAngleScale, 0),
+			field(DimPointSourceID, TypeUint16, 20),
+			field(DimGPSTime, TypeFloat64, 22),
+		)
+		next = 30
+	}
+
+	switch format {
+	case 2, 3, 5, 7, 8, 10:
+		dims = append(dims,
+			field(DimRed, TypeUint16, next),
+			field(DimGreen, TypeUint16, next+2),
+			field(DimBlue, TypeUint16, next+4),
+		)
+		next += 6
+	}
+
+	if format == 8 || format == 10 {
+		dims = append(dims, field(DimNIR, TypeUint16, next))
+		next += 2
+	}
+
+	switch format {
+	case 4, 5, 9, 10:
+		dims = append(dims,
+			field(DimWavePacketDescriptorIndex, TypeUint8, next),
+			field(DimByteOffsetToWaveformData, TypeUint64, next+1),
+			field(DimWaveformPacketSize, TypeUint32, next+9),
+			field(DimReturnPointWaveformLocation, TypeFloat32, next+13),
+			field(DimXt, TypeFloat32, next+17),
+			field(DimYt, TypeFloat32, next+21),
+			field(DimZt, TypeFloat32, next+25),
+		)
+	}
+
+	return dims
+}
@@ -0,0 +1,205 @@
+package las14
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+// expectedRaw returns the raw value the standard dimension named name should decode to for p.
+func expectedRaw(name string, format byte, p lastest.Point) (interface{}, bool) {
+	legacy := format < 6
+	switch name {
+	case DimX:
+		return p.X, true
+	case DimY:
+		return p.Y, true
+	case DimZ:
+		return p.Z, true
+	case DimIntensity:
+		return p.Intensity, true
+	case DimReturnNumber:
+		return p.ReturnNumber, true
+	case DimNumberOfReturns:
+		return p.NumberOfReturns, true
+	case DimScanDirectionFlag:
+		return p.ScanDirection, true
+	case DimEdgeOfFlightLine:
+		return p.EdgeOfFlight, true
+	case DimClassification:
+		return p.Classification, true
+	case DimSynthetic:
+		return p.ClassFlags&1 != 0, true
+	case DimKeyPoint:
+		return p.ClassFlags&2 != 0, true
+	case DimWithheld:
+		return p.ClassFlags&4 != 0, true
+	case DimOverlap:
+		return p.ClassFlags&8 != 0, true
+	case DimScannerChannel:
+		return p.ScannerChannel, true
+	case DimScanAngle:
+		if legacy {
+			return (int8)(p.ScanAngle), true
+		}
+		return p.ScanAngle, true
+	case DimUserData:
+		return p.UserData, true
+	case DimPointSourceID:
+		return p.PointSourceID, true
+	case DimGPSTime:
+		return p.GPSTime, true
+	case DimRed:
+		return p.Red, true
+	case DimGreen:
+		return p.Green, true
+	case DimBlue:
+		return p.Blue, true
+	case DimNIR:
+		return p.NIR, true
+	case DimWavePacketDescriptorIndex:
+		return p.WavePacket[0], true
+	case DimByteOffsetToWaveformData:
+		return binary.LittleEndian.Uint64(p.WavePacket[1:9]), true
+	}
+	return nil, false
+}
+
+func TestSchemaStandardDimensions(t *testing.T) {
+	for format := byte(0); format <= 10; format++ {
+		points := make([]lastest.Point, 40)
+		for i := range points {
+			points[i] = lastest.PatternPoint(i)
+			points[i].ClassFlags = (byte)(i % 8)
+			points[i].ScannerChannel = (byte)(i % 4)
+			points[i].WavePacket[0] = (byte)(i)
+			binary.LittleEndian.PutUint64(points[i].WavePacket[1:9], (uint64)(i)*1000)
+			if format < 6 {
+				points[i].Classification %= 32
+				points[i].ReturnNumber %= 8
+			}
+		}
+
+		file := lastest.Generate(lastest.Spec{Header: lastest.Header{PointDataRecordFormat: format}, Points: points})
+		err, fr := NewBytesDecoder(file.Bytes).FullDecode("")
+		if err != nil {
+			t.Fatalf("format %d: decode failed: %v", format, err)
+		}
+
+		err, schema := NewSchema(&fr.Header, fr.VariableLengthRecords)
+		if err != nil {
+			t.Fatalf("format %d: schema failed: %v", format, err)
+		}
+
+		end := 0
+		for _, d := range schema.Dimensions {
+			if e := d.ByteOffset + d.Size; e > end {
+				end = e
+			}
+		}
+		if end != (int)(PointDataFormat(format).StandardLength()) {
+			t.Errorf("format %d: dimensions end at byte %d, want %d", format, end, PointDataFormat(format).StandardLength())
+		}
+
+		for i, p := range points {
+			raw := fr.PointDataRecord((uint64)(i)).Raw
+			for _, d := range schema.Dimensions {
+				want, ok := expectedRaw(d.Name, format, p)
+				if !ok {
+					continue
+				}
+				if got := d.Raw(raw); got != want {
+					t.Errorf("format %d point %d: %s = %v (%T), want %v (%T)", format, i, d.Name, got, got, want, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSchemaExtraBytes(t *testing.T) {
+	vlr := lastest.ExtraBytesVLR(
+		lastest.ExtraBytes{Name: "height", DataType: (byte)(TypeInt16), Options: ExtraBytesScaleValid | ExtraBytesOffsetValid | ExtraBytesNoDataValid,
+			Scale: [3]float64{0.01}, Offset: [3]float64{100}, NoData: [3]uint64{(uint64)(math.MaxUint64)}},
+		lastest.ExtraBytes{Name: "opaque", DataType: 0, Options: 3},
+		lastest.ExtraBytes{Name: "normal", DataType: 29, Description: "surface normal"},
+	)
+
+	extra := make([]byte, 2+3+12)
+	binary.LittleEndian.PutUint16(extra[0:2], (uint16)(0xffff)) // -1 is the no data value
+	copy(extra[2:5], "abc")
+	binary.LittleEndian.PutUint32(extra[5:9], math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(extra[9:13], math.Float32bits(-0.25))
+	binary.LittleEndian.PutUint32(extra[13:17], math.Float32bits(1))
+
+	second := make([]byte, len(extra))
+	binary.LittleEndian.PutUint16(second[0:2], 250)
+
+	file := lastest.Generate(lastest.Spec{
+		Header:     lastest.Header{PointDataRecordFormat: 6},
+		Points:     []lastest.Point{{Extra: extra}, {Extra: second}},
+		ExtraBytes: len(extra),
+		VLRs:       []lastest.VLR{vlr},
+	})
+
+	err, fr := NewBytesDecoder(file.Bytes).FullDecode("")
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	err, schema := NewSchema(&fr.Header, fr.VariableLengthRecords)
+	if err != nil {
+		t.Fatalf("schema failed: %v", err)
+	}
+
+	raw := fr.PointDataRecord(0).Raw
+	height, ok := schema.Dimension("HEIGHT")
+	if !ok {
+		t.Fatal("height dimension missing")
+	}
+	if !height.Extra || !height.IsNoData(raw) || height.Raw(raw) != (int16)(-1) {
+		t.Errorf("height = %v, no data %v", height.Raw(raw), height.IsNoData(raw))
+	}
+	if got := height.Float64(fr.PointDataRecord(1).Raw); math.Abs(got-102.5) > 1e-9 {
+		t.Errorf("scaled height = %v, want 102.5", got)
+	}
+
+	opaque, ok := schema.Dimension("opaque")
+	if !ok || string(opaque.Raw(raw).([]byte)) != "abc" {
+		t.Errorf("opaque = %v", opaque)
+	}
+
+	for i, want := range []float32{0.5, -0.25, 1} {
+		names := []string{"normal[0]", "normal[1]", "normal[2]"}
+		d, ok := schema.Dimension(names[i])
+		if !ok {
+			t.Fatalf("%s dimension missing", names[i])
+		}
+		if got := d.Raw(raw); got != want || d.Description != "surface normal" {
+			t.Errorf("%s = %v, want %v", names[i], got, want)
+		}
+	}
+
+	if _, ok := schema.Dimension("GPSTime"); !ok {
+		t.Error("standard dimensions missing alongside extra bytes")
+	}
+}
+
+func TestSchemaRejectsOversizedExtraBytes(t *testing.T) {
+	file := lastest.Generate(lastest.Spec{
+		Header:     lastest.Header{PointDataRecordFormat: 6},
+		PointCount: 1,
+		ExtraBytes: 1,
+		VLRs:       []lastest.VLR{lastest.ExtraBytesVLR(lastest.ExtraBytes{Name: "wide", DataType: (byte)(TypeFloat64)})},
+	})
+
+	err, fr := NewBytesDecoder(file.Bytes).FullDecode("")
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	if err, _ := NewSchema(&fr.Header, fr.VariableLengthRecords); err == nil {
+		t.Error("expected extra bytes wider than the record to fail")
+	}
+}
@@ -1,5 +1,10 @@
 package las14
 
+import (
+	"encoding/binary"
+	"fmt"
+)
+
 // HeaderMagicBytes are the characters that occur at the beginning of a LAS header block.
 // See the "File Signature" definition on page 5 of the OGC version of the LAS 1.4 spec
 const HeaderMagicBytes = "LASF"
@@ -38,7 +43,7 @@ type PublicHeaderBlock struct {
 	MinZ                                     float64
 	StartOfWaveformDataPacketRecord          uint64
 	StartOfFirstExtendedVariableLengthRecord uint64
-	NumberOfExtendedVariableLengthRecords    uint64
+	NumberOfExtendedVariableLengthRecords    uint32
 	NumberOfPointRecords                     uint64
 	NumberOfPointsByReturn                   [15]uint64
 }
@@ -81,11 +86,20 @@ type ProjectID struct {
 
 type SystemID [32]byte
 
-type VariableLengthRecord struct{}
+// VariableLengthRecord represents a variable length record that follows the public header block.
+type VariableLengthRecord struct {
+	Reserved                uint16
+	UserID                  [16]byte
+	RecordID                uint16
+	RecordLengthAfterHeader uint16
+	Description             [32]byte
+	Data                    []byte
+}
 
 type PointData interface {
 	XYZ() (x int64, y int64, z int64)
 	Intensity() uint16
+	Classification() byte
 }
 
 type PointDataFormat byte
@@ -94,4 +108,20 @@ type PointDataRecord struct {
 	Raw    []byte
 	Format PointDataFormat
 }
-type ExtendedVariableLengthRecord struct{}
+
+// ExtendedVariableLengthRecord represents an extended variable length record that follows the point data records.
+type ExtendedVariableLengthRecord struct {
+	Reserved                uint16
+	UserID                  [16]byte
+	RecordID                uint16
+	RecordLengthAfterHeader uint64
+	Description             [32]byte
+	Data                    []byte
+}
+
+// String formats the project id as a GUID, e.g. "00000000-0000-0000-0000-000000000000".
+func (pid ProjectID) String() string {
+	var d4 [8]byte
+	binary.LittleEndian.PutUint64(d4[:], pid.Data4)
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x", pid.Data1, pid.Data2, pid.Data3, d4[:2], d4[2:])
+}
@@ -0,0 +1,64 @@
+package las14
+
+import (
+	"encoding/binary"
+)
+
+// VLRHeaderSize is the size in bytes of the header that precedes the data of each variable length record.
+const VLRHeaderSize = 54
+
+// EVLRHeaderSize is the size in bytes of the header that precedes the data of each extended variable length record.
+const EVLRHeaderSize = 60
+
+func decodeVLRHeader(buf []byte) VariableLengthRecord {
+	var vlr VariableLengthRecord
+	vlr.Reserved = binary.LittleEndian.Uint16(buf[0:2])
+	copy(vlr.UserID[:], buf[2:18])
+	vlr.RecordID = binary.LittleEndian.Uint16(buf[18:20])
+	vlr.RecordLengthAfterHeader = binary.LittleEndian.Uint16(buf[20:22])
+	copy(vlr.Description[:], buf[22:54])
+	return vlr
+}
+
+func decodeEVLRHeader(buf []byte) ExtendedVariableLengthRecord {
+	var evlr ExtendedVariableLengthRecord
+	evlr.Reserved = binary.LittleEndian.Uint16(buf[0:2])
+	copy(evlr.UserID[:], buf[2:18])
+	evlr.RecordID = binary.LittleEndian.Uint16(buf[18:20])
+	evlr.RecordLengthAfterHeader = binary.LittleEndian.Uint64(buf[20:28])
+	copy(evlr.Description[:], buf[28:60])
+	return evlr
+}
+
+func encodeVLRHeader(vlr *VariableLengthRecord) []byte {
+	buf := make([]byte, VLRHeaderSize)
+	binary.LittleEndian.PutUint16(buf[0:2], vlr.Reserved)
+	copy(buf[2:18], vlr.UserID[:])
+	binary.LittleEndian.PutUint16(buf[18:20], vlr.RecordID)
+	binary.LittleEndian.PutUint16(buf[20:22], (uint16)(len(vlr.Data)))
+	copy(buf[22:54], vlr.Description[:])
+	return buf
+}
+
+func encodeEVLRHeader(evlr *ExtendedVariableLengthRecord) []byte {
+	buf := make([]byte, EVLRHeaderSize)
+	binary.LittleEndian.PutUint16(buf[0:2], evlr.Reserved)
+	copy(buf[2:18], evlr.UserID[:])
+	binary.LittleEndian.PutUint16(buf[18:20], evlr.RecordID)
+	binary.LittleEndian.PutUint64(buf[20:28], (uint64)(len(evlr.Data)))
+	copy(buf[28:60], evlr.Description[:])
+	return buf
+}
+
+// NewVariableLengthRecord returns a record holding data under the given user id, record id and description, which
+// are truncated to the lengths the header allows.
+func NewVariableLengthRecord(userID string, recordID uint16, description string, data []byte) VariableLengthRecord {
+	vlr := VariableLengthRecord{
+		RecordID:                recordID,
+		RecordLengthAfterHeader: (uint16)(len(data)),
+		Data:                    data,
+	}
+	copy(vlr.UserID[:], userID)
+	copy(vlr.Description[:], description)
+	return vlr
+}
@@ -0,0 +1,136 @@
+// Package obj writes triangle meshes as Wavefront OBJ files, with an MTL material library for their texture, which
+// Blender and nearly every other 3D package import.
+//
+// OBJ is plain text: a line per vertex position, texture coordinate and normal, then a line per face indexing them.
+package obj
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// Options control what is written with a mesh and where it sits.
+type Options struct {
+	// Up is the axis elevation is written along.  Blender's importer expects Y up by default.
+	Up lassloot.UpAxis
+
+	// Origin is taken off the coordinates of each v line, as most importers read them into 32-bit floats.  The
+	// "# origin" comment at the top of the file records it.
+	Origin [3]float64
+
+	// Normals and UVs, if set, hold a normal, in the mesh's axes, and texture coordinates for each vertex.
+	Normals [][3]float64
+	UVs     [][2]float64
+
+	// MaterialLibrary, if set, names the MTL file the faces take Material from, relative to the OBJ file.
+	MaterialLibrary string
+	Material        string
+
+	// Comments are written at the top of the file, a line each.
+	Comments []string
+}
+
+// Write writes the triangles of a mesh over vertices to w as an OBJ file.  Triangles should list their vertices
+// counterclockwise seen from the front.
+func Write(w io.Writer, vertices [][3]float64, triangles [][3]int32, opts Options) error {
+	if opts.Normals != nil && len(opts.Normals) != len(vertices) {
+		return fmt.Errorf("%d normals for %d vertices", len(opts.Normals), len(vertices))
+	}
+	if opts.UVs != nil && len(opts.UVs) != len(vertices) {
+		return fmt.Errorf("%d texture coordinates for %d vertices", len(opts.UVs), len(vertices))
+	}
+	for i, tri := range triangles {
+		for _, v := range tri {
+			if v < 0 || (int)(v) >= len(vertices) {
+				return fmt.Errorf("triangle %d refers to vertex %d of %d", i, v, len(vertices))
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# generated by %s\n", lassloot.GeneratingSoftware)
+	fmt.Fprintf(bw, "# up axis %s\n", opts.Up)
+	fmt.Fprintf(bw, "# origin %s %s %s\n", wire.FormatFloat(opts.Origin[0]), wire.FormatFloat(opts.Origin[1]),
+		wire.FormatFloat(opts.Origin[2]))
+	for _, c := range opts.Comments {
+		fmt.Fprintf(bw, "# %s\n", c)
+	}
+	if opts.MaterialLibrary != "" {
+		fmt.Fprintf(bw, "mtllib %s\n", opts.MaterialLibrary)
+	}
+
+	buf := make([]byte, 0, 64)
+	line := func(tag string, values ...float64) {
+		buf = append(buf[:0], tag...)
+		for _, v := range values {
+			if v == 0 {
+				// flipping an axis makes negative zeros, which read oddly
+				v = 0
+			}
+			buf = append(buf, ' ')
+			buf = strconv.AppendFloat(buf, (float64)((float32)(v)), 'f', -1, 32)
+		}
+		buf = append(buf, '\n')
+		bw.Write(buf)
+	}
+	for _, v := range vertices {
+		x, y, z := opts.Up.Apply(v[0]-opts.Origin[0], v[1]-opts.Origin[1], v[2]-opts.Origin[2])
+		line("v", x, y, z)
+	}
+	for _, uv := range opts.UVs {
+		line("vt", uv[0], uv[1])
+	}
+	for _, n := range opts.Normals {
+		x, y, z := opts.Up.Apply(n[0], n[1], n[2])
+		line("vn", x, y, z)
+	}
+
+	if opts.Material != "" {
+		fmt.Fprintf(bw, "usemtl %s\n", opts.Material)
+	}
+	for _, tri := range triangles {
+		buf = append(buf[:0], 'f')
+		for _, v := range tri {
+			i := (int64)(v) + 1
+			buf = append(buf, ' ')
+			buf = strconv.AppendInt(buf, i, 10)
+			switch {
+			case opts.UVs != nil && opts.Normals != nil:
+				buf = append(buf, '/')
+				buf = strconv.AppendInt(buf, i, 10)
+				buf = append(buf, '/')
+				buf = strconv.AppendInt(buf, i, 10)
+			case opts.UVs != nil:
+				buf = append(buf, '/')
+				buf = strconv.AppendInt(buf, i, 10)
+			case opts.Normals != nil:
+				buf = append(buf, "//"...)
+				buf = strconv.AppendInt(buf, i, 10)
+			}
+		}
+		buf = append(buf, '\n')
+		bw.Write(buf)
+	}
+
+	return bw.Flush()
+}
+
+// WriteMaterial writes an MTL material library to w holding a single matte material, name, colored by the image at
+// texture, relative to the library, or plain gray when texture is empty.
+func WriteMaterial(w io.Writer, name string, texture string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# generated by %s\n", lassloot.GeneratingSoftware)
+	fmt.Fprintf(bw, "newmtl %s\n", name)
+	// the diffuse color multiplies the texture, so it is white under one
+	if texture != "" {
+		fmt.Fprintf(bw, "Ka 0 0 0\nKd 1 1 1\nKs 0 0 0\nNs 0\nd 1\nillum 1\nmap_Kd %s\n", texture)
+	} else {
+		fmt.Fprintf(bw, "Ka 0 0 0\nKd 0.8 0.8 0.8\nKs 0 0 0\nNs 0\nd 1\nillum 1\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,79 @@
+package obj
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+)
+
+func TestWrite(t *testing.T) {
+	vertices := [][3]float64{{500000, 4100000, 100}, {500010, 4100000, 101}, {500000, 4100010, 102.5}}
+	triangles := [][3]int32{{0, 1, 2}}
+	opts := Options{
+		Up:              lassloot.UpY,
+		Origin:          [3]float64{500000, 4100000, 100},
+		Normals:         [][3]float64{{0, 0, 1}, {0, 0, 1}, {0, 0, 1}},
+		UVs:             [][2]float64{{0, 0}, {1, 0}, {0, 1}},
+		MaterialLibrary: "hill.mtl",
+		Material:        "hill",
+		Comments:        []string{"crs EPSG:32610"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, vertices, triangles, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"# generated by lassloot",
+		"# up axis y",
+		"# origin 500000 4100000 100",
+		"# crs EPSG:32610",
+		"mtllib hill.mtl",
+		"v 0 0 0",
+		"v 10 1 0",
+		"v 0 2.5 -10",
+		"vt 0 0",
+		"vt 1 0",
+		"vt 0 1",
+		"vn 0 1 0",
+		"vn 0 1 0",
+		"vn 0 1 0",
+		"usemtl hill",
+		"f 1/1/1 2/2/2 3/3/3",
+	}
+	if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wrote\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	buf.Reset()
+	if err := Write(&buf, vertices, triangles, Options{Normals: opts.Normals}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "v 500010 4100000 101\n") || !strings.HasSuffix(buf.String(), "f 1//1 2//2 3//3\n") {
+		t.Errorf("z up without texture wrote\n%s", buf.String())
+	}
+
+	if err := Write(&buf, vertices, [][3]int32{{0, 1, 3}}, Options{}); err == nil {
+		t.Error("triangle beyond the vertices written")
+	}
+	if err := Write(&buf, vertices, triangles, Options{UVs: opts.UVs[:2]}); err == nil {
+		t.Error("too few texture coordinates written")
+	}
+}
+
+func TestWriteMaterial(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMaterial(&buf, "hill", "hill.png"); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, "newmtl hill\n") || !strings.Contains(s, "map_Kd hill.png\n") {
+		t.Errorf("wrote\n%s", s)
+	}
+	buf.Reset()
+	WriteMaterial(&buf, "hill", "")
+	if strings.Contains(buf.String(), "map_Kd") {
+		t.Errorf("untextured material wrote\n%s", buf.String())
+	}
+}
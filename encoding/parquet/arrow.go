@@ -0,0 +1,246 @@
+package parquet
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// Arrow IPC message header types, from the MessageHeader union of Message.fbs.
+const (
+	arrowSchema      = 1
+	arrowRecordBatch = 3
+)
+
+// Arrow field types, from the Type union of Schema.fbs.
+const (
+	arrowInt           = 2
+	arrowFloatingPoint = 3
+	arrowBinary        = 4
+	arrowBool          = 6
+)
+
+const (
+	arrowMetadataV5 = 4
+
+	arrowSingle = 1
+	arrowDouble = 2
+)
+
+// arrowContinuation precedes the size of every message in a stream.
+const arrowContinuation = 0xffffffff
+
+// WriteArrowStream writes every point of pc to w as an Arrow IPC stream: a schema message with a field for each
+// column Write would write, followed by a record batch of each RowGroupSize points and the end of stream marker.  The
+// geometry column is the GeoArrow WKB extension type, whose metadata carries the coordinate reference system.
+//
+// Buffers are uncompressed and aligned to 64 bytes, so readers such as pyarrow use them in place without copying.
+func WriteArrowStream(w io.Writer, pc *lassloot.PointCloud, opts Options) error {
+	err := writeArrow(w, pc, opts)
+	if err != nil {
+		return fmt.Errorf("failed to write arrow stream: %w", err)
+	}
+	return nil
+}
+
+func writeArrow(w io.Writer, pc *lassloot.PointCloud, opts Options) error {
+	cols := columns(pc, !opts.OmitGeometry)
+	bw := bufio.NewWriter(w)
+
+	err := writeMessage(bw, arrowSchema, arrowSchemaHeader(pc, cols), nil)
+	if err != nil {
+		return err
+	}
+
+	size := (uint64)(opts.rowGroupSize())
+	n := pc.Len()
+	for start := (uint64)(0); start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		err := writeRecordBatch(bw, pc, cols, start, end)
+		if err != nil {
+			return err
+		}
+	}
+
+	bw.Write(wire.AppendUint32(wire.AppendUint32(nil, arrowContinuation), 0))
+	return bw.Flush()
+}
+
+// arrowSchemaHeader returns a function building the Schema table of the stream's first message.
+func arrowSchemaHeader(pc *lassloot.PointCloud, cols []column) func(b *flatbuffer) int {
+	return func(b *flatbuffer) int {
+		fields := make([]int, len(cols))
+		for i := range cols {
+			fields[i] = arrowField(b, pc, &cols[i])
+		}
+		return b.table(fbScalar(0, fbInt16(0)), fbObject(1, b.objects(fields)))
+	}
+}
+
+func arrowField(b *flatbuffer, pc *lassloot.PointCloud, c *column) int {
+	var typeID byte
+	var typ int
+	switch c.kind {
+	case kindBoolean:
+		typeID, typ = arrowBool, b.table()
+	case kindInteger:
+		typeID, typ = arrowInt, b.table(fbScalar(0, fbInt32((int32)(c.bits))), fbScalar(1, fbBool(c.signed)))
+	case kindFloat:
+		typeID, typ = arrowFloatingPoint, b.table(fbScalar(0, fbInt16(arrowSingle)))
+	case kindDouble:
+		typeID, typ = arrowFloatingPoint, b.table(fbScalar(0, fbInt16(arrowDouble)))
+	default:
+		typeID, typ = arrowBinary, b.table()
+	}
+
+	fields := []fbField{
+		fbObject(0, b.string(c.name)),
+		fbScalar(1, fbBool(false)),
+		fbScalar(2, []byte{typeID}),
+		fbObject(3, typ),
+		fbObject(5, b.objects(nil)),
+	}
+	if c.kind == kindGeometry {
+		extension := struct {
+			CRS string `json:"crs,omitempty"`
+		}{crsIdentifier(pc)}
+		metadata, _ := json.Marshal(extension)
+		fields = append(fields, fbObject(6, b.objects([]int{
+			arrowKeyValue(b, "ARROW:extension:name", "geoarrow.wkb"),
+			arrowKeyValue(b, "ARROW:extension:metadata", string(metadata)),
+		})))
+	}
+	return b.table(fields...)
+}
+
+func arrowKeyValue(b *flatbuffer, key, value string) int {
+	k, v := b.string(key), b.string(value)
+	return b.table(fbObject(0, k), fbObject(1, v))
+}
+
+// writeRecordBatch writes the points from start up to end as a record batch.  Every column has an empty validity
+// buffer, as none hold nulls, followed by its values or, for the geometry, the offsets and bytes of its WKB.
+func writeRecordBatch(w io.Writer, pc *lassloot.PointCloud, cols []column, start, end uint64) error {
+	rows := (int)(end - start)
+	values := make([][]byte, len(cols))
+	var offsets []byte
+	for j := range cols {
+		if cols[j].kind == kindGeometry {
+			offsets = make([]byte, 0, 4*(rows+1))
+			offsets = wire.AppendUint32(offsets, 0)
+		}
+	}
+
+	for i := start; i < end; i++ {
+		_, pt := pc.PointAt(i)
+		row := (int)(i - start)
+		for j := range cols {
+			c := &cols[j]
+			switch c.kind {
+			case kindBoolean:
+				if row%8 == 0 {
+					values[j] = append(values[j], 0)
+				}
+				if c.integer(pt) != 0 {
+					values[j][row/8] |= 1 << (row % 8)
+				}
+			case kindInteger:
+				v := c.integer(pt)
+				switch c.bits {
+				case 8:
+					values[j] = append(values[j], (byte)(v))
+				case 16:
+					values[j] = wire.AppendUint16(values[j], (uint16)(v))
+				case 32:
+					values[j] = wire.AppendUint32(values[j], (uint32)(v))
+				default:
+					values[j] = wire.AppendUint64(values[j], v)
+				}
+			case kindFloat:
+				values[j] = wire.AppendUint32(values[j], math.Float32bits((float32)(c.float(pt))))
+			case kindDouble:
+				values[j] = wire.AppendUint64(values[j], math.Float64bits(c.float(pt)))
+			default:
+				values[j] = appendWKB(values[j], pt)
+				offsets = wire.AppendUint32(offsets, (uint32)(len(values[j])))
+			}
+		}
+	}
+
+	// lay out the body, aligning every buffer
+	var nodes, buffers [][2]int64
+	var body [][]byte
+	var length int64
+	add := func(b []byte) {
+		buffers = append(buffers, [2]int64{length, (int64)(len(b))})
+		body = append(body, b)
+		length += (int64)(len(b))
+		if pad := (arrowAlignment - length%arrowAlignment) % arrowAlignment; pad > 0 {
+			body = append(body, make([]byte, pad))
+			length += pad
+		}
+	}
+	for j := range cols {
+		nodes = append(nodes, [2]int64{(int64)(rows), 0})
+		add(nil)
+		if cols[j].kind == kindGeometry {
+			add(offsets)
+		}
+		add(values[j])
+	}
+
+	header := func(b *flatbuffer) int {
+		bufs := b.structs(buffers)
+		fieldNodes := b.structs(nodes)
+		return b.table(fbScalar(0, fbInt64((int64)(rows))), fbObject(1, fieldNodes), fbObject(2, bufs))
+	}
+	return writeMessage(w, arrowRecordBatch, header, body)
+}
+
+// arrowAlignment is the alignment of the buffers of a record batch, which Arrow recommends for SIMD.
+const arrowAlignment = 64
+
+// writeMessage writes an encapsulated message: the continuation marker, the size of the metadata, the Message
+// flatbuffer with the header built by header, and the body.
+func writeMessage(w io.Writer, headerType byte, header func(b *flatbuffer) int, body [][]byte) error {
+	var bodyLength int64
+	for _, b := range body {
+		bodyLength += (int64)(len(b))
+	}
+
+	var b flatbuffer
+	h := header(&b)
+	message := b.table(
+		fbScalar(3, fbInt64(bodyLength)),
+		fbScalar(0, fbInt16(arrowMetadataV5)),
+		fbScalar(1, []byte{headerType}),
+		fbObject(2, h),
+	)
+	metadata := b.finish(message)
+	// pad the metadata so that the body, and the message after it, begin aligned
+	if pad := (arrowAlignment - (8+len(metadata))%arrowAlignment) % arrowAlignment; pad > 0 {
+		metadata = append(metadata, make([]byte, pad)...)
+	}
+
+	prefix := wire.AppendUint32(wire.AppendUint32(nil, arrowContinuation), (uint32)(len(metadata)))
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+	if _, err := w.Write(metadata); err != nil {
+		return err
+	}
+	for _, part := range body {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
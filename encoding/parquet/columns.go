@@ -0,0 +1,271 @@
+package parquet
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// kind is the logical type of a column's values, shared by the Parquet and Arrow writers.
+type kind int
+
+const (
+	kindBoolean kind = iota
+	kindInteger
+	kindFloat
+	kindDouble
+	kindGeometry
+)
+
+// GeometryColumn names the column holding each point as WKB, the primary column of the GeoParquet metadata.
+const GeometryColumn = "geometry"
+
+// wkbPointSize is the size of a little endian WKB Point Z: byte order, geometry type and three doubles.
+const wkbPointSize = 1 + 4 + 3*8
+
+// wkbPointZ is the ISO WKB geometry type of a point with a z coordinate.
+const wkbPointZ = 1001
+
+// column is a column written for every point, with where its values come from.
+type column struct {
+	name string
+	kind kind
+
+	// bits and signed describe integers: the width of the dimension's type, or 8 for bit fields
+	bits   int
+	signed bool
+
+	// axis is 0, 1 or 2 for the position columns and -1 otherwise
+	axis int
+
+	// dim is the dimension of the other columns, or nil for the geometry
+	dim *las14.Dimension
+}
+
+// columns derives the columns written for pc from its schema: x, y and z, a column for every other dimension and,
+// unless geometry is false, the points as WKB.  Undocumented extra bytes, which have no numeric value, are left out.
+func columns(pc *lassloot.PointCloud, geometry bool) []column {
+	var ret []column
+	seen := make(map[string]bool)
+	add := func(c column) {
+		if c.name == "" || seen[c.name] {
+			return
+		}
+		seen[c.name] = true
+		ret = append(ret, c)
+	}
+
+	for a, name := range [...]string{"x", "y", "z"} {
+		add(column{name: name, kind: kindDouble, axis: a})
+	}
+
+	schema := pc.Schema()
+	for i := range schema.Dimensions {
+		d := &schema.Dimensions[i]
+		switch d.Name {
+		case las14.DimX, las14.DimY, las14.DimZ:
+			if !d.Extra {
+				continue
+			}
+		}
+		if d.Type == las14.TypeUndocumented {
+			continue
+		}
+
+		c := column{axis: -1, dim: d}
+		if d.Extra {
+			c.name = sanitizeName(d.Name)
+		} else {
+			c.name = snakeCase(d.Name)
+		}
+
+		switch {
+		case d.Bits == 1:
+			c.kind = kindBoolean
+		case d.Bits > 1:
+			c.kind, c.bits = kindInteger, 8
+		case d.Scaled || d.Type == las14.TypeFloat64:
+			c.kind = kindDouble
+		case d.Type == las14.TypeFloat32:
+			c.kind = kindFloat
+		default:
+			c.kind, c.bits = kindInteger, d.Type.Size()*8
+			switch d.Type {
+			case las14.TypeInt8, las14.TypeInt16, las14.TypeInt32, las14.TypeInt64:
+				c.signed = true
+			}
+		}
+		add(c)
+	}
+
+	if geometry {
+		add(column{name: GeometryColumn, kind: kindGeometry, axis: -1})
+	}
+	return ret
+}
+
+// float returns the value of a position, float or double column.
+func (c *column) float(pt *lassloot.Point) float64 {
+	if c.axis >= 0 {
+		x, y, z := pt.XYZ()
+		return [3]float64{x, y, z}[c.axis]
+	}
+	return c.dim.Float64(pt.PDR.Raw)
+}
+
+// integer returns the value of an integer or boolean column as the bits of a 64-bit integer, sign extended for signed
+// types.
+func (c *column) integer(pt *lassloot.Point) uint64 {
+	switch v := c.dim.Raw(pt.PDR.Raw).(type) {
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case uint8:
+		return (uint64)(v)
+	case int8:
+		return (uint64)((int64)(v))
+	case uint16:
+		return (uint64)(v)
+	case int16:
+		return (uint64)((int64)(v))
+	case uint32:
+		return (uint64)(v)
+	case int32:
+		return (uint64)((int64)(v))
+	case uint64:
+		return v
+	case int64:
+		return (uint64)(v)
+	default:
+		return 0
+	}
+}
+
+// appendWKB appends the point's position to b as a little endian WKB Point Z.
+func appendWKB(b []byte, pt *lassloot.Point) []byte {
+	x, y, z := pt.XYZ()
+	b = append(b, 1)
+	b = wire.AppendUint32(b, wkbPointZ)
+	b = wire.AppendUint64(b, math.Float64bits(x))
+	b = wire.AppendUint64(b, math.Float64bits(y))
+	return wire.AppendUint64(b, math.Float64bits(z))
+}
+
+// bounds tracks the extent of the points written, for the GeoParquet bbox.
+type bounds struct {
+	min, max [3]float64
+	empty    bool
+}
+
+func newBounds() bounds {
+	return bounds{empty: true}
+}
+
+func (b *bounds) add(pt *lassloot.Point) {
+	x, y, z := pt.XYZ()
+	for a, v := range [...]float64{x, y, z} {
+		if b.empty || v < b.min[a] {
+			b.min[a] = v
+		}
+		if b.empty || v > b.max[a] {
+			b.max[a] = v
+		}
+	}
+	b.empty = false
+}
+
+// crsIdentifier returns the coordinate reference system of pc as a string for GeoArrow metadata: the authority code
+// when one is recorded, such as "EPSG:26910" or "EPSG:26910+5703" with a vertical system, or else the WKT.  It
+// returns "" when pc doesn't record a system.
+func crsIdentifier(pc *lassloot.PointCloud) string {
+	err, crs := pc.CRS()
+	if err != nil || crs == nil {
+		return ""
+	}
+	switch {
+	case crs.EPSG != 0 && crs.VerticalEPSG != 0:
+		return fmt.Sprintf("EPSG:%d+%d", crs.EPSG, crs.VerticalEPSG)
+	case crs.EPSG != 0:
+		return fmt.Sprintf("EPSG:%d", crs.EPSG)
+	default:
+		return crs.WKT
+	}
+}
+
+// projJSON is the part of a PROJJSON coordinate reference system that identifies it.
+type projJSON struct {
+	Type       string     `json:"type"`
+	Name       string     `json:"name"`
+	Components []projJSON `json:"components,omitempty"`
+	ID         *projID    `json:"id,omitempty"`
+}
+
+type projID struct {
+	Authority string `json:"authority"`
+	Code      int    `json:"code"`
+}
+
+// crsProjJSON returns the coordinate reference system of pc as PROJJSON for GeoParquet metadata, or nil when pc records
+// no EPSG code.  Spelling out the whole definition takes a projection database, so it carries the type, name and
+// EPSG code that readers look the definition up by.
+func crsProjJSON(pc *lassloot.PointCloud) *projJSON {
+	err, crs := pc.CRS()
+	if err != nil || crs == nil || crs.EPSG == 0 {
+		return nil
+	}
+	named := func(typ, name string, code int) projJSON {
+		if name == "" {
+			name = fmt.Sprintf("EPSG:%d", code)
+		}
+		return projJSON{Type: typ, Name: name, ID: &projID{"EPSG", code}}
+	}
+
+	typ := "ProjectedCRS"
+	if crs.Geographic {
+		typ = "GeographicCRS"
+	}
+	horizontal := named(typ, crs.Name, crs.EPSG)
+	if crs.VerticalEPSG == 0 {
+		return &horizontal
+	}
+	vertical := named("VerticalCRS", crs.VerticalName, crs.VerticalEPSG)
+	return &projJSON{
+		Type:       "CompoundCRS",
+		Name:       horizontal.Name + " + " + vertical.Name,
+		Components: []projJSON{horizontal, vertical},
+	}
+}
+
+// snakeCase converts a standard dimension name to the style of column names in analytics tools: GPSTime becomes
+// gps_time and PointSourceID becomes point_source_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// sanitizeName makes an extra bytes name usable as a column name in SQL without quoting.
+func sanitizeName(name string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, name), "_")
+}
@@ -0,0 +1,145 @@
+package parquet
+
+import "github.com/nullstyle/lassloot/internal/wire"
+
+// flatbuffer builds the FlatBuffers that hold Arrow's IPC metadata.  Like the official builders it works from the end
+// of the buffer towards the front, so that objects are written before the tables referring to them, and refers to
+// each object by its distance from the end.
+type flatbuffer struct {
+	buf  []byte
+	head int
+}
+
+// fbField is a field of a table: either a scalar, stored little endian and aligned to its size, or an offset to an
+// object built earlier.
+type fbField struct {
+	id     int
+	scalar []byte
+	object int
+}
+
+func fbScalar(id int, v []byte) fbField {
+	return fbField{id: id, scalar: v}
+}
+
+func fbObject(id int, object int) fbField {
+	return fbField{id: id, object: object}
+}
+
+func (b *flatbuffer) size() int {
+	return len(b.buf) - b.head
+}
+
+func (b *flatbuffer) prepend(p []byte) {
+	if b.head < len(p) {
+		grown := make([]byte, 2*len(b.buf)+len(p)+64)
+		head := len(grown) - b.size()
+		copy(grown[head:], b.buf[b.head:])
+		b.buf, b.head = grown, head
+	}
+	b.head -= len(p)
+	copy(b.buf[b.head:], p)
+}
+
+// align pads the buffer so that an object of additional bytes prepended next ends aligned to alignment bytes.
+func (b *flatbuffer) align(alignment, additional int) {
+	if pad := (alignment - (b.size()+additional)%alignment) % alignment; pad > 0 {
+		b.prepend(make([]byte, pad))
+	}
+}
+
+// offset prepends a reference to the object at the given distance from the end.
+func (b *flatbuffer) offset(object int) {
+	b.align(4, 0)
+	b.prepend(wire.AppendUint32(nil, (uint32)(b.size()+4-object)))
+}
+
+func (b *flatbuffer) string(s string) int {
+	b.align(4, len(s)+1)
+	b.prepend([]byte{0})
+	b.prepend([]byte(s))
+	b.prepend(wire.AppendUint32(nil, (uint32)(len(s))))
+	return b.size()
+}
+
+// objects builds a vector of references to objects built earlier.
+func (b *flatbuffer) objects(objects []int) int {
+	b.align(4, 4*len(objects))
+	for i := len(objects) - 1; i >= 0; i-- {
+		b.offset(objects[i])
+	}
+	b.prepend(wire.AppendUint32(nil, (uint32)(len(objects))))
+	return b.size()
+}
+
+// structs builds a vector of structs of pairs of 64-bit integers, the layout of Arrow's FieldNode and Buffer.
+func (b *flatbuffer) structs(pairs [][2]int64) int {
+	b.align(8, 16*len(pairs))
+	for i := len(pairs) - 1; i >= 0; i-- {
+		b.prepend(wire.AppendUint64(wire.AppendUint64(nil, (uint64)(pairs[i][0])), (uint64)(pairs[i][1])))
+	}
+	b.prepend(wire.AppendUint32(nil, (uint32)(len(pairs))))
+	return b.size()
+}
+
+// table builds a table of the given fields, with the vtable locating them immediately before it.
+func (b *flatbuffer) table(fields ...fbField) int {
+	end := b.size()
+	positions := make(map[int]int, len(fields))
+	maxID := -1
+	for _, f := range fields {
+		if f.scalar != nil {
+			b.align(len(f.scalar), 0)
+			b.prepend(f.scalar)
+		} else {
+			b.offset(f.object)
+		}
+		positions[f.id] = b.size()
+		if f.id > maxID {
+			maxID = f.id
+		}
+	}
+
+	vtableSize := 4 + 2*(maxID+1)
+	b.align(4, 0)
+	b.prepend(wire.AppendUint32(nil, (uint32)(vtableSize)))
+	start := b.size()
+
+	vtable := wire.AppendUint16(nil, (uint16)(vtableSize))
+	vtable = wire.AppendUint16(vtable, (uint16)(start-end))
+	for id := 0; id <= maxID; id++ {
+		var at uint16
+		if pos, ok := positions[id]; ok {
+			at = (uint16)(start - pos)
+		}
+		vtable = wire.AppendUint16(vtable, at)
+	}
+	b.prepend(vtable)
+	return start
+}
+
+// finish completes the buffer with root as its root table, padded to a multiple of eight bytes.
+func (b *flatbuffer) finish(root int) []byte {
+	b.align(8, 4)
+	b.offset(root)
+	return b.buf[b.head:]
+}
+
+func fbInt16(v int16) []byte {
+	return wire.AppendUint16(nil, (uint16)(v))
+}
+
+func fbInt32(v int32) []byte {
+	return wire.AppendUint32(nil, (uint32)(v))
+}
+
+func fbInt64(v int64) []byte {
+	return wire.AppendUint64(nil, (uint64)(v))
+}
+
+func fbBool(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,502 @@
+// Package parquet writes pointclouds as Apache Parquet files, with GeoParquet metadata, and as Apache Arrow IPC
+// streams, for analytics in tools such as DuckDB and pandas.  Both have a column for every dimension of the point
+// schema, beside a WKB geometry column carrying the coordinate reference system.
+package parquet
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// Compression selects how the pages of a Parquet file are compressed.
+type Compression int
+
+const (
+	Uncompressed Compression = iota
+	Gzip
+)
+
+var compressionNames = [...]string{"none", "gzip"}
+
+func (c Compression) String() string {
+	if c < 0 || (int)(c) >= len(compressionNames) {
+		return fmt.Sprintf("Compression(%d)", c)
+	}
+	return compressionNames[c]
+}
+
+// ParseCompression returns the compression named s, as String returns.
+func ParseCompression(s string) (error, Compression) {
+	for i, name := range compressionNames {
+		if s == name {
+			return nil, (Compression)(i)
+		}
+	}
+	return fmt.Errorf("unknown compression %q: want none or gzip", s), Uncompressed
+}
+
+// DefaultRowGroupSize is the number of points in each row group or record batch when Options doesn't say.  It keeps
+// each column's chunk to a few megabytes, which scan in parallel and are skipped whole by their statistics.
+const DefaultRowGroupSize = 1 << 18
+
+// targetPageSize is the size of the uncompressed pages column chunks are divided into.
+const targetPageSize = 1 << 20
+
+// Options control how a pointcloud is written.
+type Options struct {
+	// RowGroupSize is the number of points in each row group of a Parquet file, or record batch of an Arrow stream.
+	// It defaults to DefaultRowGroupSize.
+	RowGroupSize int
+
+	// Compression applies to Parquet files.  Arrow streams are written uncompressed, to be read without copying.
+	Compression Compression
+
+	// OmitGeometry leaves out the WKB geometry column, and with it the GeoParquet metadata.
+	OmitGeometry bool
+}
+
+func (opts Options) rowGroupSize() int {
+	if opts.RowGroupSize > 0 {
+		return opts.RowGroupSize
+	}
+	return DefaultRowGroupSize
+}
+
+// Parquet physical types.
+const (
+	typeBoolean   = 0
+	typeInt32     = 1
+	typeInt64     = 2
+	typeFloat     = 4
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// Parquet converted types of integers, which readers predating logical types understand.
+var (
+	convertedSigned   = map[int]int32{8: 15, 16: 16, 32: 17, 64: 18}
+	convertedUnsigned = map[int]int32{8: 11, 16: 12, 32: 13, 64: 14}
+)
+
+const (
+	pageTypeData    = 0
+	encodingPlain   = 0
+	encodingRLE     = 3
+	repetitionFixed = 0
+)
+
+var magic = []byte("PAR1")
+
+// physical returns the Parquet type of the column's values.
+func (c *column) physical() int32 {
+	switch c.kind {
+	case kindBoolean:
+		return typeBoolean
+	case kindInteger:
+		if c.bits > 32 {
+			return typeInt64
+		}
+		return typeInt32
+	case kindFloat:
+		return typeFloat
+	case kindDouble:
+		return typeDouble
+	default:
+		return typeByteArray
+	}
+}
+
+// width returns the size of each of the column's values as they are gathered: booleans take a byte until a page
+// packs them into bits, and geometries are prefixed by their length.
+func (c *column) width() int {
+	switch c.physical() {
+	case typeBoolean:
+		return 1
+	case typeInt32, typeFloat:
+		return 4
+	case typeInt64, typeDouble:
+		return 8
+	default:
+		return 4 + wkbPointSize
+	}
+}
+
+// chunk gathers a column's values for a row group, with their statistics.
+type chunk struct {
+	data []byte
+
+	// lo and hi bound every value but 64-bit integers, which ilo and ihi bound
+	lo, hi   float64
+	ilo, ihi uint64
+	has      bool
+}
+
+func (ch *chunk) add(c *column, pt *lassloot.Point, b *bounds) {
+	switch c.physical() {
+	case typeBoolean:
+		v := c.integer(pt)
+		ch.data = append(ch.data, (byte)(v))
+		ch.observe((float64)(v))
+	case typeInt32:
+		v := c.integer(pt)
+		ch.data = wire.AppendUint32(ch.data, (uint32)(v))
+		if c.signed {
+			ch.observe((float64)((int64)(v)))
+		} else {
+			ch.observe((float64)(v))
+		}
+	case typeInt64:
+		v := c.integer(pt)
+		ch.data = wire.AppendUint64(ch.data, v)
+		less := func(a, b uint64) bool { return a < b }
+		if c.signed {
+			less = func(a, b uint64) bool { return (int64)(a) < (int64)(b) }
+		}
+		if !ch.has || less(v, ch.ilo) {
+			ch.ilo = v
+		}
+		if !ch.has || less(ch.ihi, v) {
+			ch.ihi = v
+		}
+		ch.has = true
+	case typeFloat:
+		v := (float32)(c.float(pt))
+		ch.data = wire.AppendUint32(ch.data, math.Float32bits(v))
+		ch.observe((float64)(v))
+	case typeDouble:
+		v := c.float(pt)
+		ch.data = wire.AppendUint64(ch.data, math.Float64bits(v))
+		ch.observe(v)
+	default:
+		ch.data = wire.AppendUint32(ch.data, wkbPointSize)
+		ch.data = appendWKB(ch.data, pt)
+		b.add(pt)
+	}
+}
+
+// observe updates the bounds of the chunk's values with v.  NaNs are left out, as Parquet requires.
+func (ch *chunk) observe(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if !ch.has || v < ch.lo {
+		ch.lo = v
+	}
+	if !ch.has || v > ch.hi {
+		ch.hi = v
+	}
+	ch.has = true
+}
+
+// statistics returns the plain encoded bounds of the chunk's values, or nils when it has none.
+func (ch *chunk) statistics(c *column) ([]byte, []byte) {
+	if !ch.has {
+		return nil, nil
+	}
+
+	// a zero bound must include both zeros, which compare equal
+	lo, hi := ch.lo, ch.hi
+	if lo == 0 {
+		lo = math.Copysign(0, -1)
+	}
+	if hi == 0 {
+		hi = math.Copysign(0, 1)
+	}
+
+	switch c.physical() {
+	case typeBoolean:
+		return []byte{(byte)(lo)}, []byte{(byte)(hi)}
+	case typeInt32:
+		return wire.AppendUint32(nil, (uint32)((int64)(lo))), wire.AppendUint32(nil, (uint32)((int64)(hi)))
+	case typeInt64:
+		return wire.AppendUint64(nil, ch.ilo), wire.AppendUint64(nil, ch.ihi)
+	case typeFloat:
+		return wire.AppendUint32(nil, math.Float32bits((float32)(lo))),
+			wire.AppendUint32(nil, math.Float32bits((float32)(hi)))
+	case typeDouble:
+		return wire.AppendUint64(nil, math.Float64bits(lo)), wire.AppendUint64(nil, math.Float64bits(hi))
+	default:
+		return nil, nil
+	}
+}
+
+// chunkMeta records where a column chunk was written.
+type chunkMeta struct {
+	offset                   int64
+	compressed, uncompressed int64
+	min, max                 []byte
+}
+
+type rowGroupMeta struct {
+	rows   int64
+	chunks []chunkMeta
+}
+
+// countingWriter tracks the offset in the file of what is written next.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += (int64)(n)
+	return n, err
+}
+
+// Write writes every point of pc to w as a Parquet file, with a column for each dimension of its schema and a
+// GeoParquet geometry column.  Values are plain encoded in pages of about a megabyte, and each column chunk records
+// the minimum and maximum of its values for readers to skip row groups by.
+func Write(w io.Writer, pc *lassloot.PointCloud, opts Options) error {
+	err := write(w, pc, opts)
+	if err != nil {
+		return fmt.Errorf("failed to write parquet: %w", err)
+	}
+	return nil
+}
+
+func write(w io.Writer, pc *lassloot.PointCloud, opts Options) error {
+	if opts.Compression != Uncompressed && opts.Compression != Gzip {
+		return fmt.Errorf("unknown compression %d", opts.Compression)
+	}
+	cols := columns(pc, !opts.OmitGeometry)
+	size := (uint64)(opts.rowGroupSize())
+	n := pc.Len()
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	cw.Write(magic)
+
+	var groups []rowGroupMeta
+	extent := newBounds()
+	for start := (uint64)(0); start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+
+		chunks := make([]chunk, len(cols))
+		for j := range cols {
+			chunks[j].data = make([]byte, 0, (int)(end-start)*cols[j].width())
+		}
+		for i := start; i < end; i++ {
+			_, pt := pc.PointAt(i)
+			for j := range cols {
+				chunks[j].add(&cols[j], pt, &extent)
+			}
+		}
+
+		group := rowGroupMeta{rows: (int64)(end - start)}
+		for j := range cols {
+			meta := chunkMeta{offset: cw.n}
+			meta.min, meta.max = chunks[j].statistics(&cols[j])
+			err := writePages(cw, &cols[j], chunks[j].data, opts.Compression, &meta)
+			if err != nil {
+				return fmt.Errorf("%s: %w", cols[j].name, err)
+			}
+			chunks[j].data = nil
+			group.chunks = append(group.chunks, meta)
+		}
+		groups = append(groups, group)
+	}
+
+	var footer compact
+	footer.body(func() { fileMetadata(&footer, pc, cols, groups, extent, opts) })
+	cw.Write(footer.b)
+	cw.Write(wire.AppendUint32(nil, (uint32)(len(footer.b))))
+	if _, err := cw.Write(magic); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writePages writes a column chunk's values as data pages, adding their sizes to meta.
+func writePages(w io.Writer, c *column, data []byte, compression Compression, meta *chunkMeta) error {
+	width := c.width()
+	perPage := targetPageSize / width
+	if perPage < 1 {
+		perPage = 1
+	}
+
+	for start := 0; start < len(data); start += perPage * width {
+		end := start + perPage*width
+		if end > len(data) {
+			end = len(data)
+		}
+		values := (end - start) / width
+
+		page := data[start:end]
+		if c.physical() == typeBoolean {
+			page = packBits(page)
+		}
+		stored := page
+		if compression == Gzip {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write(page)
+			if err := zw.Close(); err != nil {
+				return err
+			}
+			stored = buf.Bytes()
+		}
+
+		var header compact
+		header.body(func() {
+			header.i32(1, pageTypeData)
+			header.i32(2, (int32)(len(page)))
+			header.i32(3, (int32)(len(stored)))
+			header.structure(5, func() {
+				header.i32(1, (int32)(values))
+				header.i32(2, encodingPlain)
+				header.i32(3, encodingRLE)
+				header.i32(4, encodingRLE)
+			})
+		})
+
+		if _, err := w.Write(header.b); err != nil {
+			return err
+		}
+		if _, err := w.Write(stored); err != nil {
+			return err
+		}
+		meta.compressed += (int64)(len(header.b) + len(stored))
+		meta.uncompressed += (int64)(len(header.b) + len(page))
+	}
+	return nil
+}
+
+// packBits packs boolean bytes into bits, least significant first, as the plain encoding of booleans.
+func packBits(values []byte) []byte {
+	ret := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v != 0 {
+			ret[i/8] |= 1 << (i % 8)
+		}
+	}
+	return ret
+}
+
+// fileMetadata writes the fields of the footer's FileMetaData.
+func fileMetadata(c *compact, pc *lassloot.PointCloud, cols []column, groups []rowGroupMeta, extent bounds, opts Options) {
+	c.i32(1, 1)
+	c.structs(2, len(cols)+1, func(i int) {
+		if i == 0 {
+			c.string(4, "schema")
+			c.i32(5, (int32)(len(cols)))
+			return
+		}
+		col := &cols[i-1]
+		c.i32(1, col.physical())
+		c.i32(3, repetitionFixed)
+		c.string(4, col.name)
+		if col.kind == kindInteger {
+			converted := convertedUnsigned[col.bits]
+			if col.signed {
+				converted = convertedSigned[col.bits]
+			}
+			c.i32(6, converted)
+			c.structure(10, func() {
+				c.structure(10, func() {
+					c.i8(1, (int8)(col.bits))
+					c.boolean(2, col.signed)
+				})
+			})
+		}
+	})
+
+	var rows int64
+	for _, g := range groups {
+		rows += g.rows
+	}
+	c.i64(3, rows)
+
+	codec := (int32)(0)
+	if opts.Compression == Gzip {
+		codec = 2
+	}
+	c.structs(4, len(groups), func(g int) {
+		group := &groups[g]
+		var compressed, uncompressed int64
+		c.structs(1, len(cols), func(j int) {
+			meta := &group.chunks[j]
+			compressed += meta.compressed
+			uncompressed += meta.uncompressed
+
+			c.i64(2, meta.offset)
+			c.structure(3, func() {
+				c.i32(1, cols[j].physical())
+				c.i32s(2, []int32{encodingPlain, encodingRLE})
+				c.strings(3, []string{cols[j].name})
+				c.i32(4, codec)
+				c.i64(5, group.rows)
+				c.i64(6, meta.uncompressed)
+				c.i64(7, meta.compressed)
+				c.i64(9, meta.offset)
+				if meta.min != nil {
+					c.structure(12, func() {
+						c.i64(3, 0)
+						c.binary(5, meta.max)
+						c.binary(6, meta.min)
+					})
+				}
+			})
+		})
+		c.i64(2, uncompressed)
+		c.i64(3, group.rows)
+		c.i64(5, group.chunks[0].offset)
+		c.i64(6, compressed)
+		c.i16(7, (int16)(g))
+	})
+
+	var metadata [][2]string
+	if !opts.OmitGeometry {
+		metadata = append(metadata, [2]string{"geo", geoMetadata(pc, extent)})
+	}
+	if len(metadata) > 0 {
+		c.structs(5, len(metadata), func(i int) {
+			c.string(1, metadata[i][0])
+			c.string(2, metadata[i][1])
+		})
+	}
+	c.string(6, lassloot.GeneratingSoftware)
+
+	// every column's statistics are ordered by its logical type
+	c.structs(7, len(cols), func(int) {
+		c.structure(1, func() {})
+	})
+}
+
+// geoMetadata returns the GeoParquet metadata of the file, describing its geometry column.
+func geoMetadata(pc *lassloot.PointCloud, extent bounds) string {
+	type geoColumn struct {
+		Encoding      string    `json:"encoding"`
+		GeometryTypes []string  `json:"geometry_types"`
+		CRS           *projJSON `json:"crs"`
+		CRSWKT        string    `json:"lassloot_crs_wkt,omitempty"`
+		BBox          []float64 `json:"bbox,omitempty"`
+	}
+	// an explicit null says the system is unknown, where leaving it out would claim longitude and latitude.  A system
+	// recorded only as WKT is kept beside it, under a key outside the spec.
+	column := geoColumn{Encoding: "WKB", GeometryTypes: []string{"Point Z"}, CRS: crsProjJSON(pc)}
+	if column.CRS == nil {
+		column.CRSWKT = crsIdentifier(pc)
+	}
+	if !extent.empty {
+		column.BBox = []float64{extent.min[0], extent.min[1], extent.min[2], extent.max[0], extent.max[1], extent.max[2]}
+	}
+
+	b, _ := json.Marshal(struct {
+		Version       string               `json:"version"`
+		PrimaryColumn string               `json:"primary_column"`
+		Columns       map[string]geoColumn `json:"columns"`
+	}{"1.0.0", GeometryColumn, map[string]geoColumn{GeometryColumn: column}})
+	return string(b)
+}
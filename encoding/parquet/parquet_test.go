@@ -0,0 +1,544 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/cloudtest"
+	"github.com/nullstyle/lassloot/internal/lastest"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+const testWKT = `PROJCS["NAD83 / UTM zone 10N",GEOGCS["NAD83",DATUM["North_American_Datum_1983",` +
+	`SPHEROID["GRS 1980",6378137,298.257222101]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],` +
+	`PROJECTION["Transverse_Mercator"],UNIT["metre",1],AUTHORITY["EPSG","26910"]]`
+
+// testCloud returns a format 7 cloud of n points, with a wkt crs and a uint32 extra bytes dimension.
+func testCloud(t *testing.T, n int) *lassloot.PointCloud {
+	t.Helper()
+
+	desc := las14.ExtraBytesDescriptor{DataType: (byte)(las14.TypeUint32)}
+	copy(desc.Name[:], "pulse width")
+	var points []lastest.Point
+	for i := 0; i < n; i++ {
+		points = append(points, lastest.Point{
+			X: 50000000 + (int32)(i)*3, Y: 400000000 + (int32)(i%17), Z: (int32)(i%29) - 10,
+			Intensity: (uint16)(i * 7), ReturnNumber: 1, NumberOfReturns: 1, Classification: (byte)(i % 10),
+			ClassFlags: (byte)(i % 2 * 4), ScanAngle: (int16)(i%100 - 50), GPSTime: 1e8 + (float64)(i)/10,
+			Red: (uint16)(i), Green: 65535, Blue: 0, Extra: wire.AppendUint32(nil, 4000000000+(uint32)(i)),
+		})
+	}
+	return cloudtest.Generate(t, lastest.Spec{
+		Header:     lastest.Header{PointDataRecordFormat: 7, GlobalEncoding: (uint16)(las14.FlagWKT)},
+		Points:     points,
+		ExtraBytes: 4,
+		VLRs: []lastest.VLR{
+			{UserID: las14.UserIDProjection, RecordID: las14.RecordIDCoordinateWKT, Data: append([]byte(testWKT), 0)},
+			{UserID: las14.UserIDSpec, RecordID: las14.RecordIDExtraBytes, Data: desc.Encode()},
+		},
+	})
+}
+
+// thrift decodes a compact protocol struct into a map from field id to value: int64, bool, []byte, a nested map or a
+// []interface{} list.
+func thrift(t *testing.T, b []byte, pos *int) map[int16]interface{} {
+	t.Helper()
+
+	varint := func() uint64 {
+		v, n := binary.Uvarint(b[*pos:])
+		if n <= 0 {
+			t.Fatalf("bad varint at %d", *pos)
+		}
+		*pos += n
+		return v
+	}
+	zigzag := func() int64 {
+		v := varint()
+		return (int64)(v>>1) ^ -(int64)(v&1)
+	}
+
+	var value func(typ byte) interface{}
+	value = func(typ byte) interface{} {
+		switch typ {
+		case thriftBooleanTrue:
+			return true
+		case thriftBooleanFalse:
+			return false
+		case thriftByte:
+			*pos++
+			return (int64)((int8)(b[*pos-1]))
+		case thriftI16, thriftI32, thriftI64:
+			return zigzag()
+		case thriftBinary:
+			n := (int)(varint())
+			*pos += n
+			return b[*pos-n : *pos]
+		case thriftList:
+			h := b[*pos]
+			*pos++
+			n := (int)(h >> 4)
+			if n == 15 {
+				n = (int)(varint())
+			}
+			list := make([]interface{}, n)
+			for i := range list {
+				list[i] = value(h & 0xf)
+			}
+			return list
+		case thriftStruct:
+			return thrift(t, b, pos)
+		default:
+			t.Fatalf("unexpected thrift type %d", typ)
+			return nil
+		}
+	}
+
+	ret := make(map[int16]interface{})
+	var last int16
+	for {
+		h := b[*pos]
+		*pos++
+		if h == 0 {
+			return ret
+		}
+		id := last + (int16)(h>>4)
+		if h>>4 == 0 {
+			id = (int16)(zigzag())
+		}
+		ret[id] = value(h & 0xf)
+		last = id
+	}
+}
+
+type footer struct {
+	rows      int64
+	names     []string
+	types     map[string]int64
+	groups    []map[int16]interface{}
+	metadata  map[string]string
+	createdBy string
+}
+
+func readFooter(t *testing.T, file []byte) footer {
+	t.Helper()
+
+	if !bytes.HasPrefix(file, magic) || !bytes.HasSuffix(file, magic) {
+		t.Fatal("missing magic")
+	}
+	size := (int)(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	pos := len(file) - 8 - size
+	meta := thrift(t, file, &pos)
+	if pos != len(file)-8 {
+		t.Fatalf("footer ends at %d, want %d", pos, len(file)-8)
+	}
+
+	f := footer{rows: meta[3].(int64), types: make(map[string]int64), metadata: make(map[string]string)}
+	for i, e := range meta[2].([]interface{}) {
+		element := e.(map[int16]interface{})
+		if i == 0 {
+			continue
+		}
+		name := string(element[4].([]byte))
+		f.names = append(f.names, name)
+		f.types[name] = element[1].(int64)
+	}
+	for _, g := range meta[4].([]interface{}) {
+		f.groups = append(f.groups, g.(map[int16]interface{}))
+	}
+	if kv, ok := meta[5].([]interface{}); ok {
+		for _, e := range kv {
+			pair := e.(map[int16]interface{})
+			f.metadata[string(pair[1].([]byte))] = string(pair[2].([]byte))
+		}
+	}
+	f.createdBy = string(meta[6].([]byte))
+	if orders := meta[7].([]interface{}); len(orders) != len(f.names) {
+		t.Errorf("%d column orders for %d columns", len(orders), len(f.names))
+	}
+	return f
+}
+
+// readColumn returns the plain encoded values of the named column in every row group, unpacking booleans to a byte
+// each.
+func readColumn(t *testing.T, file []byte, f footer, name string) ([]byte, []map[int16]interface{}) {
+	t.Helper()
+
+	var values []byte
+	var stats []map[int16]interface{}
+	for _, g := range f.groups {
+		for _, c := range g[1].([]interface{}) {
+			meta := c.(map[int16]interface{})[3].(map[int16]interface{})
+			if string(meta[3].([]interface{})[0].([]byte)) != name {
+				continue
+			}
+			if s, ok := meta[12].(map[int16]interface{}); ok {
+				stats = append(stats, s)
+			}
+
+			pos := (int)(meta[9].(int64))
+			end := pos + (int)(meta[7].(int64))
+			for remaining := meta[5].(int64); remaining > 0; {
+				header := thrift(t, file, &pos)
+				page := file[pos : pos+(int)(header[3].(int64))]
+				pos += len(page)
+				if meta[4].(int64) == 2 {
+					zr, err := gzip.NewReader(bytes.NewReader(page))
+					if err != nil {
+						t.Fatal(err)
+					}
+					page, err = ioutil.ReadAll(zr)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+				if (int64)(len(page)) != header[2].(int64) {
+					t.Fatalf("%s: page of %d bytes, header says %d", name, len(page), header[2])
+				}
+				n := header[5].(map[int16]interface{})[1].(int64)
+				if meta[1].(int64) == typeBoolean {
+					for i := 0; i < (int)(n); i++ {
+						values = append(values, page[i/8]>>(i%8)&1)
+					}
+				} else {
+					values = append(values, page...)
+				}
+				remaining -= n
+			}
+			if pos != end {
+				t.Errorf("%s: chunk ends at %d, metadata says %d", name, pos, end)
+			}
+		}
+	}
+	return values, stats
+}
+
+func TestWrite(t *testing.T) {
+	pc := testCloud(t, 1000)
+
+	for _, compression := range []Compression{Uncompressed, Gzip} {
+		var buf bytes.Buffer
+		err := Write(&buf, pc, Options{RowGroupSize: 300, Compression: compression})
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := buf.Bytes()
+		f := readFooter(t, file)
+
+		if f.rows != 1000 || len(f.groups) != 4 {
+			t.Fatalf("%s: %d rows in %d groups, want 1000 in 4", compression, f.rows, len(f.groups))
+		}
+		if f.createdBy != lassloot.GeneratingSoftware {
+			t.Errorf("created by %q", f.createdBy)
+		}
+		for name, typ := range map[string]int64{
+			"x": typeDouble, "intensity": typeInt32, "withheld": typeBoolean, "scan_angle": typeDouble,
+			"gps_time": typeDouble, "red": typeInt32, "pulse_width": typeInt32, "geometry": typeByteArray,
+		} {
+			if got, ok := f.types[name]; !ok || got != typ {
+				t.Errorf("%s: column %s has type %d, want %d among %v", compression, name, got, typ, f.names)
+			}
+		}
+		if f.names[0] != "x" || f.names[len(f.names)-1] != GeometryColumn {
+			t.Errorf("columns %v", f.names)
+		}
+
+		var geo struct {
+			PrimaryColumn string `json:"primary_column"`
+			Columns       map[string]struct {
+				Encoding      string
+				GeometryTypes []string `json:"geometry_types"`
+				CRS           struct {
+					Type string
+					Name string
+					ID   struct {
+						Authority string
+						Code      int
+					}
+				}
+				BBox []float64
+			}
+		}
+		if err := json.Unmarshal([]byte(f.metadata["geo"]), &geo); err != nil {
+			t.Fatalf("geo metadata %q: %v", f.metadata["geo"], err)
+		}
+		g := geo.Columns[geo.PrimaryColumn]
+		if g.Encoding != "WKB" || g.CRS.Type != "ProjectedCRS" || g.CRS.Name != "NAD83 / UTM zone 10N" ||
+			g.CRS.ID.Authority != "EPSG" || g.CRS.ID.Code != 26910 || len(g.GeometryTypes) != 1 ||
+			g.GeometryTypes[0] != "Point Z" {
+			t.Errorf("geo metadata %s", f.metadata["geo"])
+		}
+		if len(g.BBox) != 6 || g.BBox[0] != 500000 || math.Abs(g.BBox[3]-(500000+999*0.03)) > 1e-6 || g.BBox[2] != -0.1 {
+			t.Errorf("bbox %v", g.BBox)
+		}
+
+		x, _ := readColumn(t, file, f, "x")
+		intensity, stats := readColumn(t, file, f, "intensity")
+		flags, _ := readColumn(t, file, f, "withheld")
+		pulse, _ := readColumn(t, file, f, "pulse_width")
+		geometry, _ := readColumn(t, file, f, "geometry")
+		if len(x) != 8000 || len(intensity) != 4000 || len(flags) != 1000 || len(pulse) != 4000 || len(geometry) != 33000 {
+			t.Fatalf("%s: column sizes %d %d %d %d %d", compression, len(x), len(intensity), len(flags), len(pulse), len(geometry))
+		}
+		for i := 0; i < 1000; i++ {
+			_, pt := pc.PointAt((uint64)(i))
+			px, py, pz := pt.XYZ()
+			if v := math.Float64frombits(binary.LittleEndian.Uint64(x[i*8:])); v != px {
+				t.Fatalf("point %d x %v, want %v", i, v, px)
+			}
+			if v := binary.LittleEndian.Uint32(intensity[i*4:]); v != (uint32)(i*7) {
+				t.Fatalf("point %d intensity %v", i, v)
+			}
+			if v := flags[i]; v != (byte)(i%2) {
+				t.Fatalf("point %d withheld %v", i, v)
+			}
+			if v := binary.LittleEndian.Uint32(pulse[i*4:]); v != 4000000000+(uint32)(i) {
+				t.Fatalf("point %d pulse width %v", i, v)
+			}
+			wkb := geometry[i*33:]
+			if binary.LittleEndian.Uint32(wkb) != 29 || wkb[4] != 1 || binary.LittleEndian.Uint32(wkb[5:]) != 1001 ||
+				math.Float64frombits(binary.LittleEndian.Uint64(wkb[17:])) != py ||
+				math.Float64frombits(binary.LittleEndian.Uint64(wkb[25:])) != pz {
+				t.Fatalf("point %d geometry % x", i, wkb[:33])
+			}
+		}
+
+		if len(stats) != 4 {
+			t.Fatalf("%d statistics of intensity", len(stats))
+		}
+		min := binary.LittleEndian.Uint32(stats[1][6].([]byte))
+		max := binary.LittleEndian.Uint32(stats[1][5].([]byte))
+		if min != 300*7 || max != 599*7 || stats[1][3].(int64) != 0 {
+			t.Errorf("second row group's intensity from %d to %d", min, max)
+		}
+	}
+}
+
+func TestWriteWithoutGeometry(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, testCloud(t, 3), Options{OmitGeometry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := readFooter(t, buf.Bytes())
+	if _, ok := f.types[GeometryColumn]; ok {
+		t.Error("geometry written")
+	}
+	if _, ok := f.metadata["geo"]; ok {
+		t.Error("geo metadata written")
+	}
+	if len(f.groups) != 1 {
+		t.Errorf("%d row groups, want 1", len(f.groups))
+	}
+}
+
+// table reads the fields of the flatbuffer table at pos.
+type table struct {
+	b   []byte
+	pos int
+}
+
+func root(b []byte) table {
+	return table{b, (int)(binary.LittleEndian.Uint32(b))}
+}
+
+// field returns the position of field id, or 0 when it is absent.
+func (tb table) field(id int) int {
+	vtable := tb.pos - (int)((int32)(binary.LittleEndian.Uint32(tb.b[tb.pos:])))
+	if 4+2*id >= (int)(binary.LittleEndian.Uint16(tb.b[vtable:])) {
+		return 0
+	}
+	at := (int)(binary.LittleEndian.Uint16(tb.b[vtable+4+2*id:]))
+	if at == 0 {
+		return 0
+	}
+	return tb.pos + at
+}
+
+func (tb table) deref(pos int) int {
+	return pos + (int)(binary.LittleEndian.Uint32(tb.b[pos:]))
+}
+
+func (tb table) table(id int) table {
+	return table{tb.b, tb.deref(tb.field(id))}
+}
+
+func (tb table) string(id int) string {
+	at := tb.deref(tb.field(id))
+	n := (int)(binary.LittleEndian.Uint32(tb.b[at:]))
+	return string(tb.b[at+4 : at+4+n])
+}
+
+func (tb table) vector(id int) (int, int) {
+	at := tb.deref(tb.field(id))
+	return at + 4, (int)(binary.LittleEndian.Uint32(tb.b[at:]))
+}
+
+func (tb table) tables(id int) []table {
+	at, n := tb.vector(id)
+	var ret []table
+	for i := 0; i < n; i++ {
+		ret = append(ret, table{tb.b, tb.deref(at + 4*i)})
+	}
+	return ret
+}
+
+func (tb table) scalar(id int, size int) uint64 {
+	at := tb.field(id)
+	if at == 0 {
+		return 0
+	}
+	var tmp [8]byte
+	copy(tmp[:], tb.b[at:at+size])
+	return binary.LittleEndian.Uint64(tmp[:])
+}
+
+func TestWriteArrowStream(t *testing.T) {
+	pc := testCloud(t, 700)
+	var buf bytes.Buffer
+	err := WriteArrowStream(&buf, pc, Options{RowGroupSize: 256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+
+	// read each message in turn
+	type message struct {
+		header table
+		kind   uint64
+		body   []byte
+	}
+	var messages []message
+	for pos := 0; ; {
+		if pos%arrowAlignment != 0 {
+			t.Fatalf("message at %d is not aligned", pos)
+		}
+		if binary.LittleEndian.Uint32(stream[pos:]) != arrowContinuation {
+			t.Fatalf("no continuation at %d", pos)
+		}
+		size := (int)(binary.LittleEndian.Uint32(stream[pos+4:]))
+		pos += 8
+		if size == 0 {
+			if pos != len(stream) {
+				t.Errorf("%d bytes after the end of stream", len(stream)-pos)
+			}
+			break
+		}
+		m := root(stream[pos : pos+size])
+		if v := m.scalar(0, 2); v != arrowMetadataV5 {
+			t.Errorf("metadata version %d", v)
+		}
+		pos += size
+		bodyLength := (int)(m.scalar(3, 8))
+		messages = append(messages, message{m.table(2), m.scalar(1, 1), stream[pos : pos+bodyLength]})
+		pos += bodyLength
+	}
+
+	if len(messages) != 4 || messages[0].kind != arrowSchema {
+		t.Fatalf("%d messages, want a schema and 3 record batches", len(messages))
+	}
+	fields := messages[0].header.tables(1)
+	names := map[string]int{}
+	for i, f := range fields {
+		names[f.string(0)] = i
+	}
+	intensity, ok := names["intensity"]
+	if !ok {
+		t.Fatalf("no intensity among %v", names)
+	}
+	if f := fields[intensity]; f.scalar(2, 1) != arrowInt || f.table(3).scalar(0, 4) != 16 || f.table(3).scalar(1, 1) != 0 {
+		t.Errorf("intensity is not uint16")
+	}
+	geometry := fields[len(fields)-1]
+	if geometry.string(0) != GeometryColumn || geometry.scalar(2, 1) != arrowBinary {
+		t.Fatalf("last field %s is not binary geometry", geometry.string(0))
+	}
+	metadata := map[string]string{}
+	for _, kv := range geometry.tables(6) {
+		metadata[kv.string(0)] = kv.string(1)
+	}
+	if metadata["ARROW:extension:name"] != "geoarrow.wkb" || metadata["ARROW:extension:metadata"] != `{"crs":"EPSG:26910"}` {
+		t.Errorf("geometry metadata %v", metadata)
+	}
+
+	// buffers of each column: validity then values, with geometry offsets between
+	row := 0
+	for _, m := range messages[1:] {
+		if m.kind != arrowRecordBatch {
+			t.Fatalf("message kind %d", m.kind)
+		}
+		rows := (int)(m.header.scalar(0, 8))
+		nodes, n := m.header.vector(1)
+		if n != len(fields) {
+			t.Fatalf("%d nodes for %d fields", n, len(fields))
+		}
+		if l := binary.LittleEndian.Uint64(m.header.b[nodes:]); (int)(l) != rows {
+			t.Errorf("node length %d, want %d", l, rows)
+		}
+		buffers, nb := m.header.vector(2)
+		if nb != 2*len(fields)+1 {
+			t.Fatalf("%d buffers for %d fields", nb, len(fields))
+		}
+		buffer := func(i int) []byte {
+			offset := binary.LittleEndian.Uint64(m.header.b[buffers+16*i:])
+			length := binary.LittleEndian.Uint64(m.header.b[buffers+16*i+8:])
+			if offset%arrowAlignment != 0 {
+				t.Errorf("buffer %d at %d is not aligned", i, offset)
+			}
+			return m.body[offset : offset+length]
+		}
+
+		values := buffer(2*intensity + 1)
+		offsets, wkb := buffer(nb-2), buffer(nb-1)
+		for i := 0; i < rows; i++ {
+			if v := binary.LittleEndian.Uint16(values[2*i:]); (int)(v) != (row+i)*7 {
+				t.Fatalf("point %d intensity %d", row+i, v)
+			}
+			_, pt := pc.PointAt((uint64)(row + i))
+			x, _, _ := pt.XYZ()
+			at := binary.LittleEndian.Uint32(offsets[4*i:])
+			if math.Float64frombits(binary.LittleEndian.Uint64(wkb[at+5:])) != x {
+				t.Fatalf("point %d geometry x", row+i)
+			}
+		}
+		row += rows
+	}
+	if row != 700 {
+		t.Errorf("%d rows, want 700", row)
+	}
+}
+
+func TestGeoMetadataCRS(t *testing.T) {
+	points := []lastest.Point{{X: 100, Y: 200, Z: 300}}
+	local := `LOCAL_CS["site grid",LOCAL_DATUM["arbitrary",0],UNIT["metre",1]]`
+	for _, test := range []struct {
+		vlrs []lastest.VLR
+		want string
+	}{
+		// without a system, the crs is null rather than left out, which would claim longitude and latitude
+		{nil, `"crs":null}`},
+		// without an EPSG code there is no PROJJSON to give, so the wkt is kept outside the spec
+		{[]lastest.VLR{{UserID: las14.UserIDProjection, RecordID: las14.RecordIDCoordinateWKT,
+			Data: append([]byte(local), 0)}}, `"crs":null,"lassloot_crs_wkt":` + strconv.Quote(local)},
+		{[]lastest.VLR{{UserID: las14.UserIDProjection, RecordID: las14.RecordIDCoordinateWKT,
+			Data: append([]byte(testWKT), 0)}},
+			`"crs":{"type":"ProjectedCRS","name":"NAD83 / UTM zone 10N","id":{"authority":"EPSG","code":26910}}`},
+	} {
+		pc := cloudtest.Generate(t, lastest.Spec{
+			Header: lastest.Header{PointDataRecordFormat: 6, GlobalEncoding: (uint16)(las14.FlagWKT)},
+			Points: points,
+			VLRs:   test.vlrs,
+		})
+		if got := geoMetadata(pc, bounds{empty: true}); !strings.Contains(got, test.want) {
+			t.Errorf("geo metadata %s lacks %s", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package parquet
+
+// Thrift compact protocol types, as they appear in field and list headers.
+const (
+	thriftBooleanTrue  = 1
+	thriftBooleanFalse = 2
+	thriftByte         = 3
+	thriftI16          = 4
+	thriftI32          = 5
+	thriftI64          = 6
+	thriftBinary       = 8
+	thriftList         = 9
+	thriftStruct       = 12
+)
+
+// compact encodes the Thrift structures of Parquet's page headers and footer with the compact protocol.  Fields must
+// be written in increasing order of id within each struct.
+type compact struct {
+	b []byte
+
+	// last is the id of the previous field of the struct being written, from which the next field's id is a delta
+	last  int16
+	stack []int16
+}
+
+func (c *compact) varint(v uint64) {
+	for v >= 0x80 {
+		c.b = append(c.b, (byte)(v)|0x80)
+		v >>= 7
+	}
+	c.b = append(c.b, (byte)(v))
+}
+
+func (c *compact) zigzag(v int64) {
+	c.varint((uint64)(v<<1) ^ (uint64)(v>>63))
+}
+
+func (c *compact) field(id int16, t byte) {
+	if delta := id - c.last; delta > 0 && delta <= 15 {
+		c.b = append(c.b, (byte)(delta)<<4|t)
+	} else {
+		c.b = append(c.b, t)
+		c.zigzag((int64)(id))
+	}
+	c.last = id
+}
+
+func (c *compact) i8(id int16, v int8) {
+	c.field(id, thriftByte)
+	c.b = append(c.b, (byte)(v))
+}
+
+func (c *compact) i16(id int16, v int16) {
+	c.field(id, thriftI16)
+	c.zigzag((int64)(v))
+}
+
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, thriftI32)
+	c.zigzag((int64)(v))
+}
+
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, thriftI64)
+	c.zigzag(v)
+}
+
+func (c *compact) boolean(id int16, v bool) {
+	if v {
+		c.field(id, thriftBooleanTrue)
+	} else {
+		c.field(id, thriftBooleanFalse)
+	}
+}
+
+func (c *compact) binary(id int16, v []byte) {
+	c.field(id, thriftBinary)
+	c.varint((uint64)(len(v)))
+	c.b = append(c.b, v...)
+}
+
+func (c *compact) string(id int16, v string) {
+	c.binary(id, []byte(v))
+}
+
+// structure writes field id as a struct whose fields are written by fields.
+func (c *compact) structure(id int16, fields func()) {
+	c.field(id, thriftStruct)
+	c.body(fields)
+}
+
+// body writes the fields of a struct, followed by its stop byte.
+func (c *compact) body(fields func()) {
+	c.stack = append(c.stack, c.last)
+	c.last = 0
+	fields()
+	c.b = append(c.b, 0)
+	c.last = c.stack[len(c.stack)-1]
+	c.stack = c.stack[:len(c.stack)-1]
+}
+
+func (c *compact) listHeader(id int16, t byte, n int) {
+	c.field(id, thriftList)
+	if n < 15 {
+		c.b = append(c.b, (byte)(n)<<4|t)
+	} else {
+		c.b = append(c.b, 0xf0|t)
+		c.varint((uint64)(n))
+	}
+}
+
+// structs writes field id as a list of n structs, whose fields are written by element.
+func (c *compact) structs(id int16, n int, element func(i int)) {
+	c.listHeader(id, thriftStruct, n)
+	for i := 0; i < n; i++ {
+		c.body(func() { element(i) })
+	}
+}
+
+func (c *compact) i32s(id int16, v []int32) {
+	c.listHeader(id, thriftI32, len(v))
+	for _, x := range v {
+		c.zigzag((int64)(x))
+	}
+}
+
+func (c *compact) strings(id int16, v []string) {
+	c.listHeader(id, thriftBinary, len(v))
+	for _, s := range v {
+		c.varint((uint64)(len(s)))
+		c.b = append(c.b, s...)
+	}
+}
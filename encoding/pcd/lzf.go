@@ -0,0 +1,182 @@
+package pcd
+
+import (
+	"fmt"
+)
+
+// LZF, as used by PCL's binary_compressed data and originally by liblzf, is a byte oriented LZ77 variant.  Each
+// control byte either introduces a run of 1 to 32 literal bytes (values below 32) or a back reference: the top three
+// bits give the match length less two, extended by a further byte when all three are set, and the low five bits with
+// the following byte give the distance back less one.
+
+const (
+	lzfHashBits  = 14
+	lzfMaxLit    = 1 << 5
+	lzfMaxOffset = 1 << 13
+	lzfMaxRef    = (1 << 8) + (1 << 3)
+)
+
+// lzfCompress compresses in, returning nil when the result would be no smaller.
+func lzfCompress(in []byte) []byte {
+	if len(in) < 4 {
+		return nil
+	}
+
+	out := make([]byte, 0, len(in))
+	var table [1 << lzfHashBits]int32
+	for i := range table {
+		table[i] = -1
+	}
+	hash := func(p int) int {
+		v := (uint32)(in[p])<<16 | (uint32)(in[p+1])<<8 | (uint32)(in[p+2])
+		return (int)((v * 2654435761) >> (32 - lzfHashBits))
+	}
+
+	lit := 0 // length of the pending literal run, whose control byte is at out[litStart]
+	litStart := len(out)
+	out = append(out, 0)
+
+	p := 0
+	for p+2 < len(in) {
+		h := hash(p)
+		ref := (int)(table[h])
+		table[h] = (int32)(p)
+
+		off := p - ref - 1
+		if ref >= 0 && off < lzfMaxOffset && in[ref] == in[p] && in[ref+1] == in[p+1] && in[ref+2] == in[p+2] {
+			// extend the match
+			length := 3
+			maxLen := len(in) - p
+			if maxLen > lzfMaxRef {
+				maxLen = lzfMaxRef
+			}
+			for length < maxLen && in[ref+length] == in[p+length] {
+				length++
+			}
+
+			// close the literal run, dropping its control byte if empty
+			if lit == 0 {
+				out = out[:len(out)-1]
+			} else {
+				out[litStart] = (byte)(lit - 1)
+			}
+
+			l := length - 2
+			if l < 7 {
+				out = append(out, (byte)(l<<5|off>>8))
+			} else {
+				out = append(out, (byte)(7<<5|off>>8), (byte)(l-7))
+			}
+			out = append(out, (byte)(off))
+
+			// index the positions within the match so later data can refer to them
+			end := p + length
+			for p++; p < end && p+2 < len(in); p++ {
+				table[hash(p)] = (int32)(p)
+			}
+			p = end
+
+			lit = 0
+			litStart = len(out)
+			out = append(out, 0)
+		} else {
+			out = append(out, in[p])
+			p++
+			lit++
+			if lit == lzfMaxLit {
+				out[litStart] = lzfMaxLit - 1
+				lit = 0
+				litStart = len(out)
+				out = append(out, 0)
+			}
+		}
+
+		if len(out) >= len(in) {
+			return nil
+		}
+	}
+
+	for ; p < len(in); p++ {
+		out = append(out, in[p])
+		lit++
+		if lit == lzfMaxLit {
+			out[litStart] = lzfMaxLit - 1
+			lit = 0
+			litStart = len(out)
+			out = append(out, 0)
+		}
+	}
+	if lit == 0 {
+		out = out[:len(out)-1]
+	} else {
+		out[litStart] = (byte)(lit - 1)
+	}
+
+	if len(out) >= len(in) {
+		return nil
+	}
+	return out
+}
+
+// lzfDecompress decompresses in, which must expand to exactly size bytes.
+func lzfDecompress(in []byte, size int) (error, []byte) {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(in); {
+		ctrl := (int)(in[i])
+		i++
+
+		if ctrl < lzfMaxLit {
+			n := ctrl + 1
+			if i+n > len(in) || len(out)+n > size {
+				return fmt.Errorf("lzf literal run overflows"), nil
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		length := ctrl >> 5
+		if length == 7 {
+			if i >= len(in) {
+				return fmt.Errorf("lzf back reference truncated"), nil
+			}
+			length += (int)(in[i])
+			i++
+		}
+		length += 2
+
+		if i >= len(in) {
+			return fmt.Errorf("lzf back reference truncated"), nil
+		}
+		ref := len(out) - ((ctrl&0x1f)<<8 | (int)(in[i])) - 1
+		i++
+		if ref < 0 || len(out)+length > size {
+			return fmt.Errorf("lzf back reference out of range"), nil
+		}
+
+		// byte by byte, since a reference may overlap the bytes it produces
+		for j := 0; j < length; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+
+	if len(out) != size {
+		return fmt.Errorf("lzf data expands to %d bytes, want %d", len(out), size), nil
+	}
+	return nil, out
+}
+
+// lzfLiterals encodes in as literal runs alone, for data that doesn't compress.
+func lzfLiterals(in []byte) []byte {
+	out := make([]byte, 0, len(in)+len(in)/lzfMaxLit+1)
+	for len(in) > 0 {
+		n := len(in)
+		if n > lzfMaxLit {
+			n = lzfMaxLit
+		}
+		out = append(out, (byte)(n-1))
+		out = append(out, in[:n]...)
+		in = in[n:]
+	}
+	return out
+}
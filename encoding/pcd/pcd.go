@@ -0,0 +1,110 @@
+// Package pcd reads and writes pointclouds as PCD files, the Point Cloud Data format of the Point Cloud Library.
+//
+// Files are written as PCD v0.7 with a field for every dimension of the point schema, named in PCL's snake case
+// style: x, y and z as floats, intensity, classification, gps_time and so on in their native types, and colors packed
+// into a single rgb field as PCL expects.  Reading maps those fields, and PCL's own such as label, back onto LAS
+// dimensions, keeping any others as extra bytes.
+package pcd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DataFormat selects how point data follows the header.
+type DataFormat int
+
+const (
+	// Binary stores each point as its packed field values.
+	Binary DataFormat = iota
+
+	// ASCII stores each point as a line of text.
+	ASCII
+
+	// BinaryCompressed stores the values of each field in turn, compressed together with LZF.
+	BinaryCompressed
+)
+
+// ParseDataFormat returns the DataFormat named by name: "binary", "ascii" or "binary_compressed".
+func ParseDataFormat(name string) (error, DataFormat) {
+	switch strings.ToLower(name) {
+	case "binary":
+		return nil, Binary
+	case "ascii":
+		return nil, ASCII
+	case "binary_compressed", "compressed":
+		return nil, BinaryCompressed
+	default:
+		return fmt.Errorf("unknown pcd data format %q: want ascii, binary or binary_compressed", name), 0
+	}
+}
+
+func (f DataFormat) String() string {
+	switch f {
+	case ASCII:
+		return "ascii"
+	case BinaryCompressed:
+		return "binary_compressed"
+	default:
+		return "binary"
+	}
+}
+
+// Field describes one field of each point, as listed by the FIELDS, SIZE, TYPE and COUNT lines of a header.
+type Field struct {
+	Name string
+
+	// Type is 'I' for signed integers, 'U' for unsigned integers and 'F' for floating point values, each Size bytes.
+	Type byte
+	Size int
+
+	// Count is the number of values the field holds, usually 1.
+	Count int
+}
+
+// Header describes a PCD file.
+type Header struct {
+	Version string
+	Fields  []Field
+
+	// Width and Height give the layout of organized clouds, which have a point per pixel of a depth image.  Other
+	// clouds have a Height of 1.
+	Width  int
+	Height int
+
+	// Viewpoint is the position and orientation quaternion of the sensor: tx ty tz qw qx qy qz.
+	Viewpoint [7]float64
+
+	Points int
+	Data   DataFormat
+
+	// Comments holds the text of the header's comment lines, without their '#'.
+	Comments []string
+}
+
+// stride returns the bytes each point occupies.
+func (h *Header) stride() int {
+	n := 0
+	for _, f := range h.Fields {
+		n += f.Size * f.Count
+	}
+	return n
+}
+
+// validType reports whether values of type t and the given size can be read and written.
+func validType(t byte, size int) bool {
+	switch t {
+	case 'I', 'U':
+		return size == 1 || size == 2 || size == 4 || size == 8
+	case 'F':
+		return size == 4 || size == 8
+	default:
+		return false
+	}
+}
+
+// isPackedColor reports whether a field holds colors packed into four bytes, which PCL stores as a float whose bits
+// are 0x00RRGGBB or 0xAARRGGBB.
+func isPackedColor(f Field) bool {
+	return (f.Name == "rgb" || f.Name == "rgba") && f.Size == 4 && f.Count == 1
+}
@@ -0,0 +1,170 @@
+package pcd
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/cloudtest"
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestLZF(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 5000)
+	rng.Read(random)
+	repetitive := bytes.Repeat([]byte("lassloot pcd "), 1000)
+	mixed := append(append([]byte{}, repetitive[:3000]...), random[:2000]...)
+	mixed = append(mixed, make([]byte, 9000)...)
+
+	for name, in := range map[string][]byte{"random": random, "repetitive": repetitive, "mixed": mixed} {
+		compressed := lzfCompress(in)
+		if compressed == nil {
+			if name != "random" {
+				t.Errorf("%s: did not compress", name)
+			}
+			compressed = lzfLiterals(in)
+		}
+
+		err, out := lzfDecompress(compressed, len(in))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(out, in) {
+			t.Errorf("%s: round trip differs", name)
+		}
+	}
+
+	if err, _ := lzfDecompress([]byte{0x20, 0x05}, 10); err == nil {
+		t.Error("back reference before the start accepted")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var points []lastest.Point
+	for i := 0; i < 200; i++ {
+		points = append(points, lastest.Point{
+			X: 100000 + (int32)(i%20)*7, Y: 200000 + (int32)(i/20)*11, Z: (int32)(i % 13),
+			Intensity: (uint16)(i * 300), Classification: (uint8)(i % 40), ReturnNumber: 1, NumberOfReturns: 2,
+			Red: (uint16)(i) * 257, Green: 0, Blue: 65535, GPSTime: 1e9 + (float64)(i),
+		})
+	}
+	pc := cloudtest.PointCloud(t, 7, points)
+
+	for _, data := range []DataFormat{ASCII, Binary, BinaryCompressed} {
+		var buf bytes.Buffer
+		err := Write(&buf, pc, Options{Data: data, Origin: [3]float64{1000, 2000, 0}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "FIELDS x y z rgb intensity return_number number_of_returns synthetic key_point withheld overlap " +
+			"scanner_channel scan_direction_flag edge_of_flight_line classification user_data scan_angle " +
+			"point_source_id gps_time\n"; !strings.Contains(buf.String(), want) {
+			t.Fatalf("%s: header lacks %q", data, want)
+		}
+
+		err, got := Read(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		if got.Header().RawHeader.PointDataRecordFormat != 7 {
+			t.Errorf("%s: format %d, want 7", data, got.Header().RawHeader.PointDataRecordFormat)
+		}
+		if gp, wp := got.Precision(), pc.Precision(); gp.XScaleFactor != wp.XScaleFactor || gp.ZScaleFactor != wp.ZScaleFactor {
+			t.Errorf("%s: precision %+v, want the scales of %+v", data, gp, wp)
+		}
+		if got.Len() != pc.Len() {
+			t.Fatalf("%s: %d points, want %d", data, got.Len(), pc.Len())
+		}
+
+		for i := (uint64)(0); i < pc.Len(); i++ {
+			_, want := pc.PointAt(i)
+			_, p := got.PointAt(i)
+			x, y, z := p.XYZ()
+			wx, wy, wz := want.XYZ()
+			if math.Abs(x-wx) > 1e-6 || math.Abs(y-wy) > 1e-6 || math.Abs(z-wz) > 1e-6 {
+				t.Fatalf("%s: point %d at %v %v %v, want %v %v %v", data, i, x, y, z, wx, wy, wz)
+			}
+			// everything after the coordinates is stored unchanged
+			if !bytes.Equal(p.PDR.Raw[12:], want.PDR.Raw[12:]) {
+				t.Fatalf("%s: point %d is %x, want %x", data, i, p.PDR.Raw[12:], want.PDR.Raw[12:])
+			}
+		}
+	}
+}
+
+func TestReadPCL(t *testing.T) {
+	// an organized cloud in the style of PCL, with a missing point, float intensity, a label and normals
+	in := "# .PCD v0.7 - Point Cloud Data file format\n" +
+		"VERSION 0.7\n" +
+		"FIELDS x y z intensity label normal_x normal_y normal_z curvature rgb\n" +
+		"SIZE 4 4 4 4 4 4 4 4 4 4\n" +
+		"TYPE F F F F U F F F F F\n" +
+		"COUNT 1 1 1 1 1 1 1 1 1 1\n" +
+		"WIDTH 2\n" +
+		"HEIGHT 2\n" +
+		"VIEWPOINT 0 0 0 1 0 0 0\n" +
+		"POINTS 4\n" +
+		"DATA ascii\n" +
+		"1.5 2.25 3.125 0.5 2 0 0 1 0.01 16711680\n" +
+		"nan nan nan 0 0 0 0 1 0 0\n" +
+		"-1 0 10 1 6 0 1 0 0.02 255\n" +
+		"2 2 2 0 9 1 0 0 0 65280\n"
+
+	err, pc := Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.Len() != 3 {
+		t.Fatalf("%d points, want 3 without the missing one", pc.Len())
+	}
+	if f := pc.Header().RawHeader.PointDataRecordFormat; f != 2 {
+		t.Errorf("format %d, want 2", f)
+	}
+	if !pc.HasNormals() {
+		t.Error("normals not kept as extra bytes")
+	}
+
+	_, p := pc.PointAt(0)
+	if x, y, z := p.XYZ(); x != 1.5 || y != 2.25 || z != 3.125 {
+		t.Errorf("position %v %v %v, want 1.5 2.25 3.125", x, y, z)
+	}
+	for name, want := range map[string]float64{
+		las14.DimIntensity:      32768,
+		las14.DimClassification: 2,
+		las14.DimRed:            65535,
+		las14.DimGreen:          0,
+		"curvature":             0.01,
+	} {
+		_, v := p.Float64(name)
+		if (float32)(v) != (float32)(want) {
+			t.Errorf("%s is %v, want %v", name, v, want)
+		}
+	}
+
+	_, p = pc.PointAt(1)
+	if _, v := p.Float64(las14.DimBlue); v != 65535 {
+		t.Errorf("blue is %v, want 65535", v)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	header := "VERSION 0.7\nFIELDS x y z\nSIZE 4 4 4\nTYPE F F F\nCOUNT 1 1 1\nWIDTH 2\nHEIGHT 1\nPOINTS 2\n"
+	for name, in := range map[string]string{
+		"no data line":  header,
+		"no z":          "FIELDS x y\nSIZE 4 4\nTYPE F F\nCOUNT 1 1\nWIDTH 1\nPOINTS 1\nDATA ascii\n1 2\n",
+		"short size":    "FIELDS x y z\nSIZE 4 4\nWIDTH 1\nPOINTS 1\nDATA ascii\n1 2 3\n",
+		"bad type":      "FIELDS x y z\nSIZE 4 4 3\nTYPE F F F\nWIDTH 1\nPOINTS 1\nDATA ascii\n1 2 3\n",
+		"missing point": header + "DATA ascii\n1 2 3\n",
+		"short binary":  header + "DATA binary\n\x00\x00\x00\x00",
+		"bad data":      header + "DATA zip\n",
+	} {
+		if err, _ := Read(strings.NewReader(in)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,725 @@
+package pcd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// File is a decoded PCD file, whose points become a pointcloud with PointCloud.
+type File struct {
+	Header Header
+
+	// origin is added back to every position, from the comment Write leaves
+	origin [3]float64
+	digits [3]int
+	scaled bool
+
+	// values holds each field's value for every point, or nil for fields not kept
+	values [][]float64
+	n      int
+}
+
+// Decode reads a PCD file from r.  Points whose position is NaN, which mark the missing pixels of organized clouds,
+// are dropped, as are fields holding more than one value.
+func Decode(r io.Reader) (error, *File) {
+	br := bufio.NewReader(r)
+	err, f := decodeHeader(br)
+	if err != nil {
+		return err, nil
+	}
+
+	h := &f.Header
+	stride := h.stride()
+	f.values = make([][]float64, len(h.Fields))
+	for i, field := range h.Fields {
+		if field.Count == 1 && field.Name != "_" {
+			f.values[i] = make([]float64, 0, h.Points)
+		}
+	}
+
+	switch h.Data {
+	case ASCII:
+		err = f.decodeASCII(br)
+
+	case Binary:
+		data := make([]byte, h.Points*stride)
+		_, err = io.ReadFull(br, data)
+		if err != nil {
+			return fmt.Errorf("failed to read point data: %w", err), nil
+		}
+		for i := 0; i < h.Points; i++ {
+			offset := i * stride
+			for j, field := range h.Fields {
+				if f.values[j] != nil {
+					f.values[j] = append(f.values[j], getValue(data[offset:], field))
+				}
+				offset += field.Size * field.Count
+			}
+		}
+
+	case BinaryCompressed:
+		var sizes [8]byte
+		_, err = io.ReadFull(br, sizes[:])
+		if err != nil {
+			return fmt.Errorf("failed to read point data: %w", err), nil
+		}
+		compressed := make([]byte, binary.LittleEndian.Uint32(sizes[0:4]))
+		size := (int)(binary.LittleEndian.Uint32(sizes[4:8]))
+		if size != h.Points*stride {
+			return fmt.Errorf("compressed data holds %d bytes, want %d for %d points", size, h.Points*stride, h.Points), nil
+		}
+		_, err = io.ReadFull(br, compressed)
+		if err != nil {
+			return fmt.Errorf("failed to read point data: %w", err), nil
+		}
+		err, data := lzfDecompress(compressed, size)
+		if err != nil {
+			return fmt.Errorf("failed to decompress point data: %w", err), nil
+		}
+
+		offset := 0
+		for j, field := range h.Fields {
+			width := field.Size * field.Count
+			if f.values[j] != nil {
+				for i := 0; i < h.Points; i++ {
+					f.values[j] = append(f.values[j], getValue(data[offset+i*width:], field))
+				}
+			}
+			offset += h.Points * width
+		}
+	}
+	if err != nil {
+		return err, nil
+	}
+
+	f.dropMissing()
+	return nil, f
+}
+
+// Read reads a PCD file from r as a pointcloud, with its format and precision chosen as PointCloud does.
+func Read(r io.Reader) (error, *lassloot.PointCloud) {
+	err, f := Decode(r)
+	if err != nil {
+		return err, nil
+	}
+	return f.PointCloud(ImportOptions{})
+}
+
+func decodeHeader(br *bufio.Reader) (error, *File) {
+	f := &File{}
+	h := &f.Header
+	h.Height = 1
+	h.Viewpoint = [7]float64{0, 0, 0, 1, 0, 0, 0}
+	h.Points = -1
+
+	var sizes, types, counts []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return fmt.Errorf("pcd header has no DATA line"), nil
+			}
+			return err, nil
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' {
+			f.comment(strings.TrimSpace(line[1:]))
+			continue
+		}
+
+		parts := strings.Fields(line)
+		key, values := strings.ToUpper(parts[0]), parts[1:]
+		switch key {
+		case "VERSION":
+			h.Version = strings.Join(values, " ")
+		case "FIELDS", "COLUMNS":
+			for _, name := range values {
+				h.Fields = append(h.Fields, Field{Name: name, Type: 'F', Size: 4, Count: 1})
+			}
+		case "SIZE":
+			sizes = values
+		case "TYPE":
+			types = values
+		case "COUNT":
+			counts = values
+		case "WIDTH", "HEIGHT", "POINTS":
+			if len(values) != 1 {
+				return fmt.Errorf("invalid pcd header line %q", line), nil
+			}
+			v, err := strconv.Atoi(values[0])
+			if err != nil || v < 0 {
+				return fmt.Errorf("invalid pcd header line %q", line), nil
+			}
+			switch key {
+			case "WIDTH":
+				h.Width = v
+			case "HEIGHT":
+				h.Height = v
+			default:
+				h.Points = v
+			}
+		case "VIEWPOINT":
+			if len(values) != 7 {
+				return fmt.Errorf("invalid pcd header line %q", line), nil
+			}
+			for i, s := range values {
+				v, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return fmt.Errorf("invalid pcd header line %q", line), nil
+				}
+				h.Viewpoint[i] = v
+			}
+		case "DATA":
+			if len(values) != 1 {
+				return fmt.Errorf("invalid pcd header line %q", line), nil
+			}
+			err, data := ParseDataFormat(values[0])
+			if err != nil {
+				return err, nil
+			}
+			h.Data = data
+			return f.finishHeader(sizes, types, counts)
+		default:
+			return fmt.Errorf("unknown pcd header line %q", line), nil
+		}
+	}
+}
+
+// finishHeader applies the SIZE, TYPE and COUNT lines to the fields and checks that the header is complete.
+func (f *File) finishHeader(sizes, types, counts []string) (error, *File) {
+	h := &f.Header
+	if len(h.Fields) == 0 {
+		return fmt.Errorf("pcd header has no FIELDS line"), nil
+	}
+	for _, list := range [...]struct {
+		key    string
+		values []string
+	}{{"SIZE", sizes}, {"TYPE", types}, {"COUNT", counts}} {
+		if list.values != nil && len(list.values) != len(h.Fields) {
+			return fmt.Errorf("pcd header lists %d fields but %d %s values", len(h.Fields), len(list.values), list.key), nil
+		}
+	}
+
+	for i := range h.Fields {
+		field := &h.Fields[i]
+		if sizes != nil {
+			v, err := strconv.Atoi(sizes[i])
+			if err != nil {
+				return fmt.Errorf("invalid size %q of field %s", sizes[i], field.Name), nil
+			}
+			field.Size = v
+		}
+		if types != nil {
+			if len(types[i]) != 1 {
+				return fmt.Errorf("invalid type %q of field %s", types[i], field.Name), nil
+			}
+			field.Type = strings.ToUpper(types[i])[0]
+		}
+		if counts != nil {
+			v, err := strconv.Atoi(counts[i])
+			if err != nil || v < 1 {
+				return fmt.Errorf("invalid count %q of field %s", counts[i], field.Name), nil
+			}
+			field.Count = v
+		}
+		if !validType(field.Type, field.Size) {
+			return fmt.Errorf("field %s has unsupported type %c of %d bytes", field.Name, field.Type, field.Size), nil
+		}
+	}
+
+	for a, name := range [...]string{"x", "y", "z"} {
+		i := f.fieldIndex(name)
+		if i < 0 || h.Fields[i].Count != 1 {
+			return fmt.Errorf("pcd file has no %s field", name), nil
+		}
+
+		// without the scale Write records, keep about what the field's type can resolve
+		if !f.scaled && h.Fields[i].Type == 'F' {
+			f.digits[a] = 3
+			if h.Fields[i].Size == 8 {
+				f.digits[a] = 6
+			}
+		}
+	}
+
+	if h.Points < 0 {
+		h.Points = h.Width * h.Height
+	}
+	return nil, f
+}
+
+// comment notes what Write records in the header's comments.
+func (f *File) comment(text string) {
+	f.Header.Comments = append(f.Header.Comments, text)
+
+	parts := strings.Fields(text)
+	if len(parts) != 4 || (parts[0] != "origin" && parts[0] != "scale") {
+		return
+	}
+	var v [3]float64
+	for i, s := range parts[1:] {
+		var err error
+		v[i], err = strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(v[i]) || math.IsInf(v[i], 0) {
+			return
+		}
+	}
+
+	if parts[0] == "origin" {
+		f.origin = v
+		return
+	}
+	for a := range v {
+		if v[a] <= 0 {
+			return
+		}
+	}
+	for a := range v {
+		f.digits[a] = lassloot.ScaleDigits(v[a])
+	}
+	f.scaled = true
+}
+
+func (f *File) fieldIndex(name string) int {
+	for i, field := range f.Header.Fields {
+		if field.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *File) decodeASCII(br *bufio.Reader) error {
+	h := &f.Header
+	values := 0
+	for _, field := range h.Fields {
+		values += field.Count
+	}
+
+	for i := 0; i < h.Points; {
+		line, err := br.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return fmt.Errorf("pcd file ends after %d of %d points", i, h.Points)
+			}
+			return err
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) == 0 || parts[0][0] == '#' {
+			continue
+		}
+		if len(parts) != values {
+			return fmt.Errorf("point %d: %d values, want %d", i, len(parts), values)
+		}
+
+		k := 0
+		for j, field := range h.Fields {
+			if f.values[j] != nil {
+				err, v := parseValue(parts[k], field)
+				if err != nil {
+					return fmt.Errorf("point %d: invalid %s %q", i, field.Name, parts[k])
+				}
+				f.values[j] = append(f.values[j], v)
+			}
+			k += field.Count
+		}
+		i++
+	}
+	return nil
+}
+
+// parseValue parses a value of field from an ascii file.  Packed colors may be written either as the integer PCL
+// writes or as the float whose bits hold the color.
+func parseValue(s string, field Field) (error, float64) {
+	if isPackedColor(field) {
+		if v, err := strconv.ParseUint(s, 10, 32); err == nil {
+			return nil, (float64)(v)
+		}
+		v, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return err, 0
+		}
+		return nil, (float64)(math.Float32bits((float32)(v)))
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	return err, v
+}
+
+// getValue decodes a value of field from the start of b.  Packed colors are returned as the integer holding them.
+func getValue(b []byte, field Field) float64 {
+	if isPackedColor(field) {
+		return (float64)(binary.LittleEndian.Uint32(b))
+	}
+
+	var u uint64
+	switch field.Size {
+	case 1:
+		u = (uint64)(b[0])
+	case 2:
+		u = (uint64)(binary.LittleEndian.Uint16(b))
+	case 4:
+		u = (uint64)(binary.LittleEndian.Uint32(b))
+	default:
+		u = binary.LittleEndian.Uint64(b)
+	}
+
+	switch field.Type {
+	case 'F':
+		if field.Size == 4 {
+			return (float64)(math.Float32frombits((uint32)(u)))
+		}
+		return math.Float64frombits(u)
+	case 'I':
+		// sign extend
+		shift := (uint)(64 - field.Size*8)
+		return (float64)((int64)(u<<shift) >> shift)
+	default:
+		return (float64)(u)
+	}
+}
+
+// dropMissing removes the points without a position.
+func (f *File) dropMissing() {
+	x, y, z := f.values[f.fieldIndex("x")], f.values[f.fieldIndex("y")], f.values[f.fieldIndex("z")]
+	n := 0
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) || math.IsNaN(z[i]) {
+			continue
+		}
+		for _, values := range f.values {
+			if values != nil {
+				values[n] = values[i]
+			}
+		}
+		n++
+	}
+
+	for j := range f.values {
+		if f.values[j] != nil {
+			f.values[j] = f.values[j][:n]
+		}
+	}
+	f.n = n
+}
+
+// Len returns the number of points read, not counting those without a position.
+func (f *File) Len() int {
+	return f.n
+}
+
+// position returns the coordinates of point i, with the origin recorded by Write added back.
+func (f *File) position(i int, x, y, z []float64) (float64, float64, float64) {
+	return x[i] + f.origin[0], y[i] + f.origin[1], z[i] + f.origin[2]
+}
+
+// Precision returns scale factors and offsets that hold every position read: those Write recorded, or else ones
+// keeping about the precision of the position fields' type.
+func (f *File) Precision() lassloot.Precision {
+	x, y, z := f.values[f.fieldIndex("x")], f.values[f.fieldIndex("y")], f.values[f.fieldIndex("z")]
+	var min, max [3]float64
+	for i := 0; i < f.n; i++ {
+		px, py, pz := f.position(i, x, y, z)
+		for a, v := range [...]float64{px, py, pz} {
+			if i == 0 || v < min[a] {
+				min[a] = v
+			}
+			if i == 0 || v > max[a] {
+				max[a] = v
+			}
+		}
+	}
+	return lassloot.AutoPrecision(min, max, f.digits)
+}
+
+// ImportOptions control how a File becomes a pointcloud.
+type ImportOptions struct {
+	// Format, if set, is the point data record format to write.  By default the simplest format holding every
+	// field read is chosen, as lassloot.PointFormatFor describes.
+	Format *las14.PointDataFormat
+
+	// Precision, if set, gives the scale factors and offsets to store coordinates with.  By default they are those
+	// of File.Precision.
+	Precision *lassloot.Precision
+}
+
+// fieldDimensions maps normalized field names to the dimensions they are read into: the snake case names Write
+// gives every standard dimension, and PCL's own names.
+var fieldDimensions = map[string]string{
+	"label":     las14.DimClassification,
+	"class":     las14.DimClassification,
+	"timestamp": las14.DimGPSTime,
+	"time":      las14.DimGPSTime,
+	"r":         las14.DimRed,
+	"g":         las14.DimGreen,
+	"b":         las14.DimBlue,
+}
+
+func init() {
+	for _, name := range [...]string{
+		las14.DimIntensity, las14.DimReturnNumber, las14.DimNumberOfReturns, las14.DimSynthetic, las14.DimKeyPoint,
+		las14.DimWithheld, las14.DimOverlap, las14.DimScannerChannel, las14.DimScanDirectionFlag,
+		las14.DimEdgeOfFlightLine, las14.DimClassification, las14.DimScanAngle, las14.DimUserData,
+		las14.DimPointSourceID, las14.DimGPSTime, las14.DimRed, las14.DimGreen, las14.DimBlue, las14.DimNIR,
+	} {
+		fieldDimensions[normalizeName(name)] = name
+	}
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Replace(name, "_", "", -1))
+}
+
+// target is where the values of a field go.
+type target struct {
+	field  int
+	dims   []*las14.Dimension
+	scale  float64
+	packed bool
+}
+
+// PointCloud returns a pointcloud holding every point read.  Fields matching a dimension of the point format are
+// stored in it, a packed rgb or rgba field as the red, green and blue dimensions, and any other numeric field as
+// extra bytes.  Packed colors are dropped when the format has no color.  Intensities and colors written as fractions between 0 and 1 are stretched to the full 16-bit range,
+// as are colors with 8-bit channels.  Times are taken to be GPS week time if all are less than a week, and Adjusted
+// Standard GPS Time otherwise.
+func (f *File) PointCloud(opts ImportOptions) (error, *lassloot.PointCloud) {
+	if f.n == 0 {
+		return fmt.Errorf("no points read"), nil
+	}
+	h := &f.Header
+
+	// sort the fields into those read into standard dimensions and the rest
+	standard := make(map[string]int)
+	var extra []int
+	packed := -1
+	for i, field := range h.Fields {
+		switch {
+		case f.values[i] == nil || field.Name == "x" || field.Name == "y" || field.Name == "z":
+		case isPackedColor(field):
+			if packed < 0 {
+				packed = i
+			}
+		default:
+			name, ok := fieldDimensions[normalizeName(field.Name)]
+			if _, dup := standard[name]; ok && !dup {
+				standard[name] = i
+			} else {
+				extra = append(extra, i)
+			}
+		}
+	}
+
+	format := las14.PointDataFormat(0)
+	if opts.Format != nil {
+		format = *opts.Format
+	} else {
+		_, hasTime := standard[las14.DimGPSTime]
+		_, hasNIR := standard[las14.DimNIR]
+		_, hasRed := standard[las14.DimRed]
+		format = lassloot.PointFormatFor(hasTime, hasRed || packed >= 0, hasNIR, f.needsExtendedFormat(standard))
+	}
+
+	p := f.Precision()
+	if opts.Precision != nil {
+		p = *opts.Precision
+	}
+	err, b := lassloot.NewBuilder(format, p)
+	if err != nil {
+		return err, nil
+	}
+
+	// fields the format lacks are kept as extra bytes too
+	for name, i := range standard {
+		if _, ok := b.Schema().Dimension(name); !ok {
+			delete(standard, name)
+			extra = append(extra, i)
+		}
+	}
+	if len(extra) > 0 {
+		err := b.AddVLR(f.extraBytesVLR(extra))
+		if err != nil {
+			return err, nil
+		}
+	}
+
+	dimensions := make(map[int]string, len(standard))
+	for name, i := range standard {
+		dimensions[i] = name
+	}
+	isExtra := make(map[int]bool, len(extra))
+	for _, i := range extra {
+		isExtra[i] = true
+	}
+
+	var targets []target
+	for i, field := range h.Fields {
+		switch name, ok := dimensions[i]; {
+		case i == packed:
+			t := target{field: i, scale: 257, packed: true}
+			for _, name := range [...]string{las14.DimRed, las14.DimGreen, las14.DimBlue} {
+				if d, ok := b.Schema().Dimension(name); ok {
+					t.dims = append(t.dims, d)
+				}
+			}
+			if len(t.dims) == 3 {
+				targets = append(targets, t)
+			}
+		case ok:
+			if packed >= 0 && (name == las14.DimRed || name == las14.DimGreen || name == las14.DimBlue) {
+				continue
+			}
+			d, _ := b.Schema().Dimension(name)
+			targets = append(targets, target{field: i, dims: []*las14.Dimension{d}, scale: f.stretch(i, name)})
+		case isExtra[i]:
+			d := extraDimension(b.Schema(), extraBytesName(field.Name))
+			if d != nil {
+				targets = append(targets, target{field: i, dims: []*las14.Dimension{d}, scale: 1})
+			}
+		}
+	}
+
+	if i, ok := standard[las14.DimGPSTime]; ok && !f.below(i, lassloot.SecondsPerGPSWeek) {
+		b.Header().GlobalEncoding |= las14.FlagGPSTime
+	}
+
+	x, y, z := f.values[f.fieldIndex("x")], f.values[f.fieldIndex("y")], f.values[f.fieldIndex("z")]
+	for i := 0; i < f.n; i++ {
+		err := b.Add(f.position(i, x, y, z))
+		if err != nil {
+			return fmt.Errorf("point %d: %w", i, err), nil
+		}
+
+		for _, t := range targets {
+			v := f.values[t.field][i]
+			if math.IsNaN(v) {
+				continue
+			}
+
+			if t.packed {
+				rgb := (uint32)(v)
+				for c, d := range t.dims {
+					err = b.SetDimension(d, (float64)((rgb>>(16-8*c))&0xff)*t.scale)
+					if err != nil {
+						break
+					}
+				}
+			} else {
+				err = b.SetDimension(t.dims[0], v*t.scale)
+			}
+			if err != nil {
+				return fmt.Errorf("point %d: %s: %w", i, h.Fields[t.field].Name, err), nil
+			}
+		}
+	}
+
+	return b.Build()
+}
+
+// needsExtendedFormat reports whether the fields read hold anything only the LAS 1.4 formats can store.
+func (f *File) needsExtendedFormat(standard map[string]int) bool {
+	if _, ok := standard[las14.DimOverlap]; ok {
+		return true
+	}
+	if _, ok := standard[las14.DimScannerChannel]; ok {
+		return true
+	}
+	for name, limit := range map[string]float64{
+		las14.DimClassification:  31,
+		las14.DimReturnNumber:    7,
+		las14.DimNumberOfReturns: 7,
+	} {
+		if i, ok := standard[name]; ok && !f.below(i, limit+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// below reports whether every value of field i is less than limit.
+func (f *File) below(i int, limit float64) bool {
+	for _, v := range f.values[i] {
+		if v >= limit {
+			return false
+		}
+	}
+	return true
+}
+
+// stretch returns what the values of field i, read into the named dimension, are multiplied by: 65535 for
+// intensities and colors written as fractions, 257 for 8-bit colors and otherwise 1.
+func (f *File) stretch(i int, name string) float64 {
+	switch name {
+	case las14.DimIntensity, las14.DimRed, las14.DimGreen, las14.DimBlue, las14.DimNIR:
+	default:
+		return 1
+	}
+
+	if f.Header.Fields[i].Type == 'F' && f.below(i, 1+1e-9) {
+		return math.MaxUint16
+	}
+	if name != las14.DimIntensity && f.below(i, 256) {
+		return 257
+	}
+	return 1
+}
+
+// extraBytesVLR describes the fields at indexes as extra bytes of their own type.
+func (f *File) extraBytesVLR(indexes []int) las14.VariableLengthRecord {
+	var data []byte
+	for _, i := range indexes {
+		field := f.Header.Fields[i]
+		var ebd las14.ExtraBytesDescriptor
+		ebd.DataType = (byte)(extraBytesType(field))
+		copy(ebd.Name[:], extraBytesName(field.Name))
+		data = append(data, ebd.Encode()...)
+	}
+	return las14.NewVariableLengthRecord(las14.UserIDSpec, las14.RecordIDExtraBytes, "Extra Bytes", data)
+}
+
+// extraDimension returns the first extra bytes dimension named name, which may share its name with a standard
+// dimension.
+func extraDimension(schema *las14.Schema, name string) *las14.Dimension {
+	for i := range schema.Dimensions {
+		d := &schema.Dimensions[i]
+		if d.Extra && d.Name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// extraBytesName returns the name of the extra bytes holding a field, which is limited to 32 bytes.
+func extraBytesName(name string) string {
+	if len(name) > 32 {
+		return name[:32]
+	}
+	return name
+}
+
+func extraBytesType(field Field) las14.DataType {
+	switch field.Type {
+	case 'F':
+		if field.Size == 4 {
+			return las14.TypeFloat32
+		}
+		return las14.TypeFloat64
+	case 'I':
+		return map[int]las14.DataType{1: las14.TypeInt8, 2: las14.TypeInt16, 4: las14.TypeInt32, 8: las14.TypeInt64}[field.Size]
+	default:
+		return map[int]las14.DataType{1: las14.TypeUint8, 2: las14.TypeUint16, 4: las14.TypeUint32, 8: las14.TypeUint64}[field.Size]
+	}
+}
@@ -0,0 +1,309 @@
+package pcd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// Options control how a pointcloud is written.
+type Options struct {
+	Data DataFormat
+
+	// Origin shifts the cloud towards zero before it is written, since PCL's float fields cannot hold projected
+	// coordinates to the millimeter.  A "# origin" comment records it, and Decode adds it back.
+	Origin [3]float64
+
+	// Double writes positions as 64-bit floats rather than the 32-bit ones most PCL point types use.
+	Double bool
+}
+
+// column is a field written for every point, with where its values come from.
+type column struct {
+	Field
+
+	// axis is 0, 1 or 2 for the position fields and -1 otherwise
+	axis   int
+	digits int
+
+	// dim is the dimension of other fields, or nil for packed colors
+	dim *las14.Dimension
+}
+
+// Write writes every point of pc to w as a PCD v0.7 file, with a field for each dimension of its schema.  Undocumented
+// extra bytes, which have no numeric value, are left out.
+func Write(w io.Writer, pc *lassloot.PointCloud, opts Options) error {
+	columns := columns(pc, opts)
+	n := pc.Len()
+	if n > math.MaxInt32 {
+		return fmt.Errorf("pcd files hold at most %d points, not %d", math.MaxInt32, n)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# .PCD v0.7 - Point Cloud Data file format\n")
+	fmt.Fprintf(bw, "# generated by %s\n", lassloot.GeneratingSoftware)
+	fmt.Fprintf(bw, "# origin %s %s %s\n", wire.FormatFloat(opts.Origin[0]), wire.FormatFloat(opts.Origin[1]),
+		wire.FormatFloat(opts.Origin[2]))
+	p := pc.Precision()
+	fmt.Fprintf(bw, "# scale %s %s %s\n", wire.FormatFloat(p.XScaleFactor), wire.FormatFloat(p.YScaleFactor),
+		wire.FormatFloat(p.ZScaleFactor))
+	if err, crs := pc.CRS(); err == nil && crs != nil {
+		fmt.Fprintf(bw, "# crs %s\n", crs)
+	}
+
+	fmt.Fprintf(bw, "VERSION 0.7\n")
+	line := func(key string, value func(c *column) string) {
+		parts := make([]string, len(columns))
+		for i := range columns {
+			parts[i] = value(&columns[i])
+		}
+		fmt.Fprintf(bw, "%s %s\n", key, strings.Join(parts, " "))
+	}
+	line("FIELDS", func(c *column) string { return c.Name })
+	line("SIZE", func(c *column) string { return strconv.Itoa(c.Size) })
+	line("TYPE", func(c *column) string { return string(c.Type) })
+	line("COUNT", func(c *column) string { return "1" })
+	fmt.Fprintf(bw, "WIDTH %d\nHEIGHT 1\nVIEWPOINT 0 0 0 1 0 0 0\nPOINTS %d\nDATA %s\n", n, n, opts.Data)
+
+	colorShift := 0
+	if pc.ColorDepth() > 8 {
+		colorShift = 8
+	}
+	var red, green, blue *las14.Dimension
+	red, _ = pc.Schema().Dimension(las14.DimRed)
+	green, _ = pc.Schema().Dimension(las14.DimGreen)
+	blue, _ = pc.Schema().Dimension(las14.DimBlue)
+
+	value := func(c *column, pt *lassloot.Point) float64 {
+		raw := pt.PDR.Raw
+		switch {
+		case c.axis >= 0:
+			x, y, z := pt.XYZ()
+			return [3]float64{x, y, z}[c.axis] - opts.Origin[c.axis]
+		case c.dim == nil:
+			r := (uint32)(red.RawFloat64(raw)) >> colorShift
+			g := (uint32)(green.RawFloat64(raw)) >> colorShift
+			b := (uint32)(blue.RawFloat64(raw)) >> colorShift
+			return (float64)((r&0xff)<<16 | (g&0xff)<<8 | b&0xff)
+		default:
+			return c.dim.Float64(raw)
+		}
+	}
+
+	var err error
+	switch opts.Data {
+	case ASCII:
+		fields := make([]string, len(columns))
+		for i := (uint64)(0); i < n && err == nil; i++ {
+			_, pt := pc.PointAt(i)
+			for j := range columns {
+				fields[j] = formatValue(&columns[j], value(&columns[j], pt))
+			}
+			_, err = bw.WriteString(strings.Join(fields, " ") + "\n")
+		}
+
+	case BinaryCompressed:
+		// the values of each field are stored together, which compresses far better than whole points
+		data := make([]byte, (int)(n)*stride(columns))
+		offset := 0
+		for j := range columns {
+			c := &columns[j]
+			for i := (uint64)(0); i < n; i++ {
+				_, pt := pc.PointAt(i)
+				putValue(data[offset:], c.Field, value(c, pt))
+				offset += c.Size
+			}
+		}
+
+		compressed := lzfCompress(data)
+		if compressed == nil {
+			compressed = lzfLiterals(data)
+		}
+		var sizes [8]byte
+		binary.LittleEndian.PutUint32(sizes[0:4], (uint32)(len(compressed)))
+		binary.LittleEndian.PutUint32(sizes[4:8], (uint32)(len(data)))
+		bw.Write(sizes[:])
+		_, err = bw.Write(compressed)
+
+	default:
+		record := make([]byte, stride(columns))
+		for i := (uint64)(0); i < n && err == nil; i++ {
+			_, pt := pc.PointAt(i)
+			offset := 0
+			for j := range columns {
+				putValue(record[offset:], columns[j].Field, value(&columns[j], pt))
+				offset += columns[j].Size
+			}
+			_, err = bw.Write(record)
+		}
+	}
+
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write pcd: %w", err)
+	}
+	return nil
+}
+
+// columns derives the fields written for pc from its schema.
+func columns(pc *lassloot.PointCloud, opts Options) []column {
+	var ret []column
+	seen := make(map[string]bool)
+	add := func(c column) {
+		if c.Name == "" || seen[c.Name] {
+			return
+		}
+		seen[c.Name] = true
+		ret = append(ret, c)
+	}
+
+	position := Field{Type: 'F', Size: 4, Count: 1}
+	if opts.Double {
+		position.Size = 8
+	}
+	xd, yd, zd := pc.Precision().Digits()
+	for a, name := range [...]string{"x", "y", "z"} {
+		f := position
+		f.Name = name
+		add(column{Field: f, axis: a, digits: [...]int{xd, yd, zd}[a]})
+	}
+
+	if pc.ColorDepth() > 0 {
+		add(column{Field: Field{Name: "rgb", Type: 'F', Size: 4, Count: 1}, axis: -1})
+	}
+
+	schema := pc.Schema()
+	for i := range schema.Dimensions {
+		d := &schema.Dimensions[i]
+		switch d.Name {
+		case las14.DimX, las14.DimY, las14.DimZ, las14.DimRed, las14.DimGreen, las14.DimBlue:
+			if !d.Extra {
+				continue
+			}
+		}
+		if d.Type == las14.TypeUndocumented {
+			continue
+		}
+
+		c := column{axis: -1, dim: d}
+		c.Count = 1
+		if d.Extra {
+			c.Name = sanitizeName(d.Name)
+		} else {
+			c.Name = snakeCase(d.Name)
+		}
+
+		switch {
+		case d.Scaled:
+			// the scaled value is written, as a float wide enough for the raw value's precision
+			c.Type, c.Size = 'F', 4
+			if d.Type.Size() > 2 {
+				c.Size = 8
+			}
+		case d.Type.IsFloat():
+			c.Type, c.Size = 'F', d.Type.Size()
+		case d.Type == las14.TypeInt8 || d.Type == las14.TypeInt16 || d.Type == las14.TypeInt32 || d.Type == las14.TypeInt64:
+			c.Type, c.Size = 'I', d.Type.Size()
+		default:
+			c.Type, c.Size = 'U', d.Type.Size()
+		}
+		add(c)
+	}
+
+	return ret
+}
+
+func stride(columns []column) int {
+	n := 0
+	for _, c := range columns {
+		n += c.Size
+	}
+	return n
+}
+
+// putValue stores v at the start of b as a value of field f.
+func putValue(b []byte, f Field, v float64) {
+	if isPackedColor(f) {
+		binary.LittleEndian.PutUint32(b, (uint32)(v))
+		return
+	}
+
+	switch f.Type {
+	case 'F':
+		if f.Size == 4 {
+			binary.LittleEndian.PutUint32(b, math.Float32bits((float32)(v)))
+		} else {
+			binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+		}
+	case 'I':
+		putInteger(b, f.Size, (uint64)((int64)(v)))
+	default:
+		putInteger(b, f.Size, (uint64)(v))
+	}
+}
+
+func putInteger(b []byte, size int, v uint64) {
+	switch size {
+	case 1:
+		b[0] = (byte)(v)
+	case 2:
+		binary.LittleEndian.PutUint16(b, (uint16)(v))
+	case 4:
+		binary.LittleEndian.PutUint32(b, (uint32)(v))
+	default:
+		binary.LittleEndian.PutUint64(b, v)
+	}
+}
+
+// formatValue formats v as a value of c in an ascii file.  Positions keep the decimal places of the pointcloud's
+// precision, and packed colors are written as integers, as PCL does.
+func formatValue(c *column, v float64) string {
+	switch {
+	case c.axis >= 0:
+		return strconv.FormatFloat(v, 'f', c.digits, 64)
+	case isPackedColor(c.Field) || c.Type == 'U':
+		return strconv.FormatUint((uint64)(v), 10)
+	case c.Type == 'I':
+		return strconv.FormatInt((int64)(v), 10)
+	default:
+		return strconv.FormatFloat(v, 'g', -1, c.Size*8)
+	}
+}
+
+// snakeCase converts a standard dimension name to the style of PCL's field names: GPSTime becomes gps_time and
+// PointSourceID becomes point_source_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// sanitizeName makes an extra bytes name usable as a field name, which may not contain spaces.
+func sanitizeName(name string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, name), "_")
+}
@@ -0,0 +1,309 @@
+// Package ply writes pointclouds as Stanford PLY files, which MeshLab, CloudCompare and Blender import natively.
+//
+// Each point becomes a vertex carrying its position and, when the cloud has them, its color, intensity,
+// classification and normal as further vertex properties.
+package ply
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// Format selects how vertices are stored.
+type Format int
+
+const (
+	// BinaryLittleEndian stores vertices as packed binary values, which is compact and quick to load.
+	BinaryLittleEndian Format = iota
+
+	// ASCII stores each vertex as a line of text.
+	ASCII
+)
+
+// ParseFormat returns the Format named by name, "binary" or "ascii".
+func ParseFormat(name string) (error, Format) {
+	switch strings.ToLower(name) {
+	case "binary", "binary_little_endian":
+		return nil, BinaryLittleEndian
+	case "ascii":
+		return nil, ASCII
+	default:
+		return fmt.Errorf("unknown ply format %q: want binary or ascii", name), 0
+	}
+}
+
+func (f Format) String() string {
+	if f == ASCII {
+		return "ascii"
+	}
+	return "binary_little_endian"
+}
+
+// Options control which properties are written and how.
+type Options struct {
+	Format Format
+
+	// Up is the axis elevation is written along.
+	Up lassloot.UpAxis
+
+	// Origin is taken off each point as it is written, and recorded in a "comment origin" line of the header.  Without
+	// it a projected CRS's eastings and northings lose their centimeters to 32-bit float properties.
+	Origin [3]float64
+
+	// Double writes positions as 64-bit floats rather than 32-bit ones.
+	Double bool
+
+	// Color, Intensity and Classification write those dimensions as the red, green and blue, intensity and
+	// classification properties of clouds that have them.  Colors are reduced to 8 bits per channel.
+	Color          bool
+	Intensity      bool
+	Classification bool
+
+	// Normals writes nx, ny and nz properties: those stored with the points, or else estimated from the given
+	// number of neighbours of each point.  Zero writes no normals.
+	Normals int
+}
+
+// DefaultOptions writes every property a cloud has as a binary file, with Z up.
+var DefaultOptions = Options{
+	Format:         BinaryLittleEndian,
+	Color:          true,
+	Intensity:      true,
+	Classification: true,
+	Normals:        lassloot.DefaultNormalNeighbors,
+}
+
+// source holds what's needed to write the vertices of one cloud.
+type source struct {
+	pc      *lassloot.PointCloud
+	digits  [3]int
+	normals [][3]float32
+
+	red, green, blue *las14.Dimension
+	intensity        *las14.Dimension
+	classification   *las14.Dimension
+
+	// colorShift reduces the cloud's colors to 8 bits.  Each cloud has its own, since merged clouds may differ.
+	colorShift uint
+}
+
+// Write writes the points of every cloud, in order, to w as a single PLY file.  A property is written when any of
+// the clouds has it, with zero for the vertices of clouds that don't.
+func Write(w io.Writer, opts Options, clouds ...*lassloot.PointCloud) error {
+	sources := make([]source, len(clouds))
+	var count uint64
+	var hasColor, hasIntensity, hasClassification bool
+
+	for i, pc := range clouds {
+		s := &sources[i]
+		s.pc = pc
+		count += pc.Len()
+
+		xd, yd, zd := pc.Precision().Digits()
+		s.digits = [3]int{xd, yd, zd}
+
+		schema := pc.Schema()
+		if opts.Color {
+			s.red, _ = schema.Dimension(las14.DimRed)
+			s.green, _ = schema.Dimension(las14.DimGreen)
+			s.blue, _ = schema.Dimension(las14.DimBlue)
+			if s.red != nil {
+				hasColor = true
+				if pc.ColorDepth() > 8 {
+					s.colorShift = 8
+				}
+			}
+		}
+		if opts.Intensity {
+			s.intensity, _ = schema.Dimension(las14.DimIntensity)
+			hasIntensity = hasIntensity || s.intensity != nil
+		}
+		if opts.Classification {
+			s.classification, _ = schema.Dimension(las14.DimClassification)
+			hasClassification = hasClassification || s.classification != nil
+		}
+		if opts.Normals > 0 {
+			err, normals := pc.Normals(opts.Normals)
+			if err != nil {
+				return fmt.Errorf("failed to estimate normals: %w", err)
+			}
+			s.normals = normals
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "ply\nformat %s 1.0\n", opts.Format)
+	fmt.Fprintf(bw, "comment generated by %s\n", lassloot.GeneratingSoftware)
+	fmt.Fprintf(bw, "comment up axis %s\n", opts.Up)
+	fmt.Fprintf(bw, "comment origin %s %s %s\n", wire.FormatFloat(opts.Origin[0]), wire.FormatFloat(opts.Origin[1]),
+		wire.FormatFloat(opts.Origin[2]))
+	if len(clouds) == 1 {
+		err, crs := clouds[0].CRS()
+		if err == nil && crs != nil {
+			fmt.Fprintf(bw, "comment crs %s\n", crs)
+		}
+	}
+	fmt.Fprintf(bw, "element vertex %d\n", count)
+
+	position := "float"
+	if opts.Double {
+		position = "double"
+	}
+	for _, axis := range [...]string{"x", "y", "z"} {
+		fmt.Fprintf(bw, "property %s %s\n", position, axis)
+	}
+	if opts.Normals > 0 {
+		fmt.Fprintf(bw, "property float nx\nproperty float ny\nproperty float nz\n")
+	}
+	if hasColor {
+		fmt.Fprintf(bw, "property uchar red\nproperty uchar green\nproperty uchar blue\n")
+	}
+	if hasIntensity {
+		fmt.Fprintf(bw, "property ushort intensity\n")
+	}
+	if hasClassification {
+		fmt.Fprintf(bw, "property uchar classification\n")
+	}
+	fmt.Fprintf(bw, "end_header\n")
+
+	vw := &vertexWriter{
+		w:      bw,
+		opts:   opts,
+		buf:    make([]byte, 0, 64),
+		fields: make([]string, 0, 12),
+	}
+	for i := range sources {
+		s := &sources[i]
+		n := s.pc.Len()
+		for j := (uint64)(0); j < n; j++ {
+			_, p := s.pc.PointAt(j)
+			raw := p.PDR.Raw
+
+			x, y, z := p.XYZ()
+			vw.position(x-opts.Origin[0], y-opts.Origin[1], z-opts.Origin[2], s.digits)
+
+			if opts.Normals > 0 {
+				nm := s.normals[j]
+				nx, ny, nz := opts.Up.Apply((float64)(nm[0]), (float64)(nm[1]), (float64)(nm[2]))
+				vw.float32s((float32)(nx), (float32)(ny), (float32)(nz))
+			}
+			if hasColor {
+				var rgb [3]uint8
+				if s.red != nil {
+					rgb[0] = (uint8)((uint16)(s.red.RawFloat64(raw)) >> s.colorShift)
+					rgb[1] = (uint8)((uint16)(s.green.RawFloat64(raw)) >> s.colorShift)
+					rgb[2] = (uint8)((uint16)(s.blue.RawFloat64(raw)) >> s.colorShift)
+				}
+				vw.uint8s(rgb[:]...)
+			}
+			if hasIntensity {
+				var v uint16
+				if s.intensity != nil {
+					v = (uint16)(s.intensity.RawFloat64(raw))
+				}
+				vw.uint16(v)
+			}
+			if hasClassification {
+				var v uint8
+				if s.classification != nil {
+					v = (uint8)(s.classification.RawFloat64(raw))
+				}
+				vw.uint8s(v)
+			}
+
+			err := vw.end()
+			if err != nil {
+				return fmt.Errorf("failed to write ply: %w", err)
+			}
+		}
+	}
+
+	err := bw.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write ply: %w", err)
+	}
+	return nil
+}
+
+// vertexWriter writes the properties of one vertex at a time, as text or packed binary.
+type vertexWriter struct {
+	w    *bufio.Writer
+	opts Options
+
+	buf    []byte
+	fields []string
+}
+
+func (vw *vertexWriter) position(x, y, z float64, digits [3]int) {
+	// digits follow the LAS axes, which UpY reorders
+	if vw.opts.Up == lassloot.UpY {
+		digits = [3]int{digits[0], digits[2], digits[1]}
+	}
+	x, y, z = vw.opts.Up.Apply(x, y, z)
+
+	if vw.opts.Format == ASCII {
+		for i, v := range [...]float64{x, y, z} {
+			vw.fields = append(vw.fields, strconv.FormatFloat(v, 'f', digits[i], 64))
+		}
+		return
+	}
+
+	if vw.opts.Double {
+		for _, v := range [...]float64{x, y, z} {
+			vw.buf = wire.AppendUint64(vw.buf, math.Float64bits(v))
+		}
+		return
+	}
+	vw.float32s((float32)(x), (float32)(y), (float32)(z))
+}
+
+func (vw *vertexWriter) float32s(values ...float32) {
+	for _, v := range values {
+		if vw.opts.Format == ASCII {
+			vw.fields = append(vw.fields, strconv.FormatFloat((float64)(v), 'g', -1, 32))
+			continue
+		}
+		vw.buf = wire.AppendUint32(vw.buf, math.Float32bits(v))
+	}
+}
+
+func (vw *vertexWriter) uint8s(values ...uint8) {
+	for _, v := range values {
+		if vw.opts.Format == ASCII {
+			vw.fields = append(vw.fields, strconv.Itoa((int)(v)))
+			continue
+		}
+		vw.buf = append(vw.buf, v)
+	}
+}
+
+func (vw *vertexWriter) uint16(v uint16) {
+	if vw.opts.Format == ASCII {
+		vw.fields = append(vw.fields, strconv.Itoa((int)(v)))
+		return
+	}
+	vw.buf = wire.AppendUint16(vw.buf, v)
+}
+
+// end writes the vertex and readies the writer for the next.
+func (vw *vertexWriter) end() error {
+	var err error
+	if vw.opts.Format == ASCII {
+		_, err = vw.w.WriteString(strings.Join(vw.fields, " ") + "\n")
+	} else {
+		_, err = vw.w.Write(vw.buf)
+	}
+
+	vw.buf = vw.buf[:0]
+	vw.fields = vw.fields[:0]
+	return err
+}
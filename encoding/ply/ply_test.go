@@ -0,0 +1,102 @@
+package ply
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/cloudtest"
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestWriteASCII(t *testing.T) {
+	pc := cloudtest.PointCloud(t, 7, []lastest.Point{
+		{X: 150, Y: 250, Z: 1000, Intensity: 7, Classification: 2, Red: 65535, Green: 32768, Blue: 0},
+	})
+
+	opts := DefaultOptions
+	opts.Format = ASCII
+	opts.Up = lassloot.UpY
+	opts.Origin = [3]float64{1, 2, 3}
+	opts.Normals = 0
+
+	var buf bytes.Buffer
+	if err := Write(&buf, opts, pc); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"format ascii 1.0\n",
+		"comment up axis y\n",
+		"comment origin 1 2 3\n",
+		"element vertex 1\n",
+		"property float x\n",
+		"property uchar red\n",
+		"property ushort intensity\n",
+		"property uchar classification\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("header lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "property float nx") {
+		t.Error("normals written when disabled")
+	}
+
+	// (0.5, 0.5, 7) with y up is (0.5, 7, -0.5)
+	if want := "end_header\n0.50 7.00 -0.50 255 128 0 7 2\n"; !strings.HasSuffix(out, want) {
+		t.Errorf("output ends %q, want %q", out[strings.Index(out, "end_header"):], want)
+	}
+}
+
+func TestWriteBinary(t *testing.T) {
+	var points []lastest.Point
+	for i := 0; i < 30; i++ {
+		points = append(points, lastest.Point{X: (int32)(i%6) * 100, Y: (int32)(i/6) * 100, Z: (int32)(i % 2)})
+	}
+	a := cloudtest.PointCloud(t, 0, points)
+	b := cloudtest.PointCloud(t, 2, points[:5])
+
+	opts := DefaultOptions
+	opts.Double = true
+
+	var buf bytes.Buffer
+	if err := Write(&buf, opts, a, b); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.Bytes()
+	end := bytes.Index(out, []byte("end_header\n")) + len("end_header\n")
+	header := (string)(out[:end])
+	if !strings.Contains(header, "format binary_little_endian 1.0\n") || !strings.Contains(header, "element vertex 35\n") ||
+		!strings.Contains(header, "property double z\n") || !strings.Contains(header, "property float nz\n") {
+		t.Errorf("unexpected header:\n%s", header)
+	}
+
+	// doubles, float normals, rgb, intensity and classification
+	stride := 3*8 + 3*4 + 3 + 2 + 1
+	if got := len(out) - end; got != 35*stride {
+		t.Errorf("%d bytes of vertices, want %d", got, 35*stride)
+	}
+}
+
+func TestWriteMixedColorDepths(t *testing.T) {
+	// one cloud of 16-bit colors and one of 8-bit colors stored unscaled, each reduced to 8 bits on its own
+	deep := cloudtest.PointCloud(t, 2, []lastest.Point{{Red: 0xff00, Green: 0x8000, Blue: 0x1234}})
+	shallow := cloudtest.PointCloud(t, 2, []lastest.Point{{Red: 200, Green: 100, Blue: 50}})
+
+	opts := DefaultOptions
+	opts.Format = ASCII
+	opts.Normals = 0
+	opts.Intensity, opts.Classification = false, false
+
+	var buf bytes.Buffer
+	if err := Write(&buf, opts, deep, shallow); err != nil {
+		t.Fatal(err)
+	}
+	if want := "end_header\n0.00 0.00 0.00 255 128 18\n0.00 0.00 0.00 200 100 50\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("output ends %q, want %q", buf.String()[strings.Index(buf.String(), "end_header"):], want)
+	}
+}
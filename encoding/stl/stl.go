@@ -0,0 +1,78 @@
+// Package stl writes triangle meshes as binary STL files, the format every 3D printing slicer reads.
+//
+// STL stores each triangle on its own, as a facet of three 32-bit float vertices and a normal, with no units; slicers
+// take them as millimeters.
+package stl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// HeaderSize is the length of the free text header that begins a binary STL file.
+const HeaderSize = 80
+
+// FacetSize is the length of each facet: a normal and three vertices as 32-bit floats, and a 16-bit attribute count.
+const FacetSize = 50
+
+// Write writes the triangles of a mesh over vertices to w as a binary STL file.  Triangles should list their vertices
+// counterclockwise seen from outside; each facet's normal is computed from that winding.
+func Write(w io.Writer, vertices [][3]float64, triangles [][3]int32) error {
+	if (uint64)(len(triangles)) > math.MaxUint32 {
+		return fmt.Errorf("%d triangles are too many for an stl file", len(triangles))
+	}
+	for i, tri := range triangles {
+		for _, v := range tri {
+			if v < 0 || (int)(v) >= len(vertices) {
+				return fmt.Errorf("triangle %d refers to vertex %d of %d", i, v, len(vertices))
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	// a header beginning "solid" would be mistaken for an ascii stl
+	header := make([]byte, HeaderSize)
+	copy(header, "binary stl generated by "+lassloot.GeneratingSoftware)
+	bw.Write(header)
+
+	buf := make([]byte, 0, FacetSize)
+	buf = wire.AppendUint32(buf, (uint32)(len(triangles)))
+	bw.Write(buf)
+
+	for _, tri := range triangles {
+		p := [3][3]float64{vertices[tri[0]], vertices[tri[1]], vertices[tri[2]]}
+		n := normal(p[0], p[1], p[2])
+
+		buf = buf[:0]
+		for _, v := range [...][3]float64{n, p[0], p[1], p[2]} {
+			for _, c := range v {
+				buf = wire.AppendUint32(buf, math.Float32bits((float32)(c)))
+			}
+		}
+		buf = append(buf, 0, 0)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// normal returns the unit normal of the triangle a, b, c, facing the side from which it winds counterclockwise, or zero
+// for a degenerate triangle.
+func normal(a, b, c [3]float64) [3]float64 {
+	u := [3]float64{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+	v := [3]float64{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+	n := [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+	l := math.Sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])
+	if l == 0 {
+		return [3]float64{}
+	}
+	return [3]float64{n[0] / l, n[1] / l, n[2] / l}
+}
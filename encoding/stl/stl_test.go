@@ -0,0 +1,54 @@
+package stl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	// a tetrahedron, each face counterclockwise seen from outside
+	vertices := [][3]float64{{0, 0, 0}, {10, 0, 0}, {0, 10, 0}, {0, 0, 10}}
+	triangles := [][3]int32{{0, 2, 1}, {0, 1, 3}, {0, 3, 2}, {1, 2, 3}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, vertices, triangles); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if len(b) != HeaderSize+4+FacetSize*len(triangles) {
+		t.Fatalf("%d bytes", len(b))
+	}
+	if strings.HasPrefix(string(b), "solid") {
+		t.Error("header would be read as ascii stl")
+	}
+	if n := binary.LittleEndian.Uint32(b[HeaderSize:]); n != 4 {
+		t.Fatalf("%d facets", n)
+	}
+
+	f32 := func(facet, i int) float64 {
+		off := HeaderSize + 4 + facet*FacetSize + 4*i
+		return (float64)(math.Float32frombits(binary.LittleEndian.Uint32(b[off:])))
+	}
+	s := 1 / math.Sqrt(3)
+	for facet, want := range [][3]float64{{0, 0, -1}, {0, -1, 0}, {-1, 0, 0}, {s, s, s}} {
+		for k := 0; k < 3; k++ {
+			if math.Abs(f32(facet, k)-want[k]) > 1e-6 {
+				t.Errorf("facet %d normal component %d is %v, want %v", facet, k, f32(facet, k), want[k])
+			}
+		}
+		for v := 0; v < 3; v++ {
+			for k := 0; k < 3; k++ {
+				if got := f32(facet, 3+3*v+k); got != vertices[triangles[facet][v]][k] {
+					t.Errorf("facet %d vertex %d is off at %d: %v", facet, v, k, got)
+				}
+			}
+		}
+	}
+
+	if err := Write(&buf, vertices, [][3]int32{{0, 1, 4}}); err == nil {
+		t.Error("triangle beyond the vertices written")
+	}
+}
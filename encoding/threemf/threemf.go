@@ -0,0 +1,225 @@
+// Package threemf writes triangle meshes as 3MF files, the 3D Manufacturing Format that color printers and current
+// slicers read.
+//
+// A 3MF file is a zip package holding an XML model.  Unlike STL, the model records its units and metadata such as a
+// title, shares vertices between triangles, and can color each corner of each triangle through the materials
+// extension's color groups.
+package threemf
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+)
+
+// Unit is the length of one unit of a model's coordinates.
+type Unit string
+
+const (
+	Micron     Unit = "micron"
+	Millimeter Unit = "millimeter"
+	Centimeter Unit = "centimeter"
+	Inch       Unit = "inch"
+	Foot       Unit = "foot"
+	Meter      Unit = "meter"
+)
+
+// ParseUnit returns the unit named s.
+func ParseUnit(s string) (error, Unit) {
+	for _, u := range [...]Unit{Micron, Millimeter, Centimeter, Inch, Foot, Meter} {
+		if strings.EqualFold(s, (string)(u)) {
+			return nil, u
+		}
+	}
+	return fmt.Errorf("unknown unit %q: want micron, millimeter, centimeter, inch, foot or meter", s), ""
+}
+
+// Namespace is the XML namespace of the metadata lassloot adds beyond the names the 3MF core specification defines,
+// recorded with the prefix lassloot.
+const Namespace = "http://github.com/nullstyle/lassloot/3mf"
+
+// ModelPath is the path within the package of the model.
+const ModelPath = "3D/3dmodel.model"
+
+const (
+	coreNamespace      = "http://schemas.microsoft.com/3dmanufacturing/core/2015/02"
+	materialsNamespace = "http://schemas.microsoft.com/3dmanufacturing/material/2015/02"
+	modelRelationship  = "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"
+)
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+</Types>
+`
+
+const relationships = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Target="/` + ModelPath + `" Id="rel0" Type="` + modelRelationship + `"/>
+</Relationships>
+`
+
+// Options describe a model beyond its geometry.
+type Options struct {
+	// Unit is the unit of the coordinates, millimeters if empty.
+	Unit Unit
+
+	// Colors, if set, holds a color for each vertex, which the corners of the triangles sharing it take.
+	Colors [][3]uint8
+
+	// Metadata names and values are recorded in the model.  The core specification names Title, Designer,
+	// Description, Copyright, LicenseTerms, Rating, CreationDate and ModificationDate; other names must carry a
+	// namespace prefix, such as lassloot.  Application defaults to lassloot.
+	Metadata map[string]string
+}
+
+// Write writes the triangles of a mesh over vertices to w as a 3MF package with a single object.  Triangles should list
+// their vertices counterclockwise seen from outside, and the mesh should be closed for printing.
+func Write(w io.Writer, vertices [][3]float64, triangles [][3]int32, opts Options) error {
+	if opts.Colors != nil && len(opts.Colors) != len(vertices) {
+		return fmt.Errorf("%d colors for %d vertices", len(opts.Colors), len(vertices))
+	}
+	for i, tri := range triangles {
+		for _, v := range tri {
+			if v < 0 || (int)(v) >= len(vertices) {
+				return fmt.Errorf("triangle %d refers to vertex %d of %d", i, v, len(vertices))
+			}
+		}
+	}
+	unit := opts.Unit
+	if unit == "" {
+		unit = Millimeter
+	}
+	if err, _ := ParseUnit((string)(unit)); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, part := range [...]struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", relationships},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create(ModelPath)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	writeModel(bw, vertices, triangles, unit, opts)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeModel(bw *bufio.Writer, vertices [][3]float64, triangles [][3]int32, unit Unit, opts Options) {
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(bw, `<model unit="%s" xml:lang="en-US" xmlns="%s" xmlns:m="%s" xmlns:lassloot="%s">`+"\n",
+		unit, coreNamespace, materialsNamespace, Namespace)
+
+	metadata := map[string]string{"Application": lassloot.GeneratingSoftware}
+	for name, value := range opts.Metadata {
+		metadata[name] = value
+	}
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(bw, `  <metadata name="%s">%s</metadata>`+"\n", escape(name), escape(metadata[name]))
+	}
+
+	bw.WriteString("  <resources>\n")
+
+	// the color group holds each distinct color once, with the vertices indexing into it
+	const groupID, objectID = 1, 2
+	var index []int32
+	if opts.Colors != nil {
+		index = make([]int32, len(opts.Colors))
+		seen := make(map[[3]uint8]int32)
+		fmt.Fprintf(bw, `    <m:colorgroup id="%d">`+"\n", groupID)
+		for i, c := range opts.Colors {
+			j, ok := seen[c]
+			if !ok {
+				j = (int32)(len(seen))
+				seen[c] = j
+				fmt.Fprintf(bw, `      <m:color color="#%02X%02X%02X"/>`+"\n", c[0], c[1], c[2])
+			}
+			index[i] = j
+		}
+		bw.WriteString("    </m:colorgroup>\n")
+		fmt.Fprintf(bw, `    <object id="%d" type="model" pid="%d" pindex="0">`+"\n", objectID, groupID)
+	} else {
+		fmt.Fprintf(bw, `    <object id="%d" type="model">`+"\n", objectID)
+	}
+
+	bw.WriteString("      <mesh>\n        <vertices>\n")
+	buf := make([]byte, 0, 128)
+	for _, v := range vertices {
+		buf = append(buf[:0], `          <vertex x="`...)
+		buf = appendCoordinate(buf, v[0])
+		buf = append(buf, `" y="`...)
+		buf = appendCoordinate(buf, v[1])
+		buf = append(buf, `" z="`...)
+		buf = appendCoordinate(buf, v[2])
+		buf = append(buf, "\"/>\n"...)
+		bw.Write(buf)
+	}
+	bw.WriteString("        </vertices>\n        <triangles>\n")
+	for _, tri := range triangles {
+		buf = append(buf[:0], `          <triangle v1="`...)
+		buf = strconv.AppendInt(buf, (int64)(tri[0]), 10)
+		buf = append(buf, `" v2="`...)
+		buf = strconv.AppendInt(buf, (int64)(tri[1]), 10)
+		buf = append(buf, `" v3="`...)
+		buf = strconv.AppendInt(buf, (int64)(tri[2]), 10)
+		if index != nil {
+			buf = append(buf, `" pid="`...)
+			buf = strconv.AppendInt(buf, groupID, 10)
+			buf = append(buf, `" p1="`...)
+			buf = strconv.AppendInt(buf, (int64)(index[tri[0]]), 10)
+			buf = append(buf, `" p2="`...)
+			buf = strconv.AppendInt(buf, (int64)(index[tri[1]]), 10)
+			buf = append(buf, `" p3="`...)
+			buf = strconv.AppendInt(buf, (int64)(index[tri[2]]), 10)
+		}
+		buf = append(buf, "\"/>\n"...)
+		bw.Write(buf)
+	}
+	bw.WriteString("        </triangles>\n      </mesh>\n    </object>\n  </resources>\n")
+	fmt.Fprintf(bw, "  <build>\n    <item objectid=\"%d\"/>\n  </build>\n</model>\n", objectID)
+}
+
+// appendCoordinate appends v to four decimal places, a tenth of a micron in millimeters and far finer than any printer,
+// without trailing zeros.
+func appendCoordinate(b []byte, v float64) []byte {
+	v = math.Round(v*1e4) / 1e4
+	if v == 0 {
+		v = 0 // no negative zero
+	}
+	return strconv.AppendFloat(b, v, 'f', -1, 64)
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
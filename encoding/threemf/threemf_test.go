@@ -0,0 +1,125 @@
+package threemf
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+type model struct {
+	Unit     string `xml:"unit,attr"`
+	Metadata []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"metadata"`
+	Colors []struct {
+		Color string `xml:"color,attr"`
+	} `xml:"resources>colorgroup>color"`
+	Object struct {
+		ID       int `xml:"id,attr"`
+		Vertices []struct {
+			X string `xml:"x,attr"`
+			Y string `xml:"y,attr"`
+			Z string `xml:"z,attr"`
+		} `xml:"mesh>vertices>vertex"`
+		Triangles []struct {
+			V1  int `xml:"v1,attr"`
+			V2  int `xml:"v2,attr"`
+			V3  int `xml:"v3,attr"`
+			PID int `xml:"pid,attr"`
+			P1  int `xml:"p1,attr"`
+			P2  int `xml:"p2,attr"`
+			P3  int `xml:"p3,attr"`
+		} `xml:"mesh>triangles>triangle"`
+	} `xml:"resources>object"`
+	Item struct {
+		ObjectID int `xml:"objectid,attr"`
+	} `xml:"build>item"`
+}
+
+func read(t *testing.T, data []byte) (map[string][]byte, *model) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), (int64)(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name], _ = io.ReadAll(r)
+		r.Close()
+	}
+	m := &model{}
+	if err := xml.Unmarshal(parts[ModelPath], m); err != nil {
+		t.Fatal(err)
+	}
+	return parts, m
+}
+
+func TestWrite(t *testing.T) {
+	vertices := [][3]float64{{0, 0, 0}, {10.25, 0, 0}, {0, 10.00004, 0}, {0, 0, -0.00001}}
+	triangles := [][3]int32{{0, 2, 1}, {0, 1, 3}, {0, 3, 2}, {1, 2, 3}}
+	colors := [][3]uint8{{255, 0, 0}, {0, 255, 0}, {255, 0, 0}, {1, 2, 171}}
+
+	var buf bytes.Buffer
+	err := Write(&buf, vertices, triangles, Options{Colors: colors, Metadata: map[string]string{"Title": "a & b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, m := read(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels"} {
+		if len(parts[name]) == 0 {
+			t.Errorf("no %s", name)
+		}
+	}
+	if !bytes.Contains(parts["_rels/.rels"], []byte(`Target="/3D/3dmodel.model"`)) {
+		t.Errorf("relationship does not target the model")
+	}
+
+	if m.Unit != "millimeter" {
+		t.Errorf("unit %q", m.Unit)
+	}
+	meta := make(map[string]string)
+	for _, md := range m.Metadata {
+		meta[md.Name] = md.Value
+	}
+	if meta["Title"] != "a & b" || meta["Application"] != "lassloot" {
+		t.Errorf("metadata %v", meta)
+	}
+
+	if len(m.Colors) != 3 || m.Colors[0].Color != "#FF0000" || m.Colors[2].Color != "#0102AB" {
+		t.Errorf("colors %v", m.Colors)
+	}
+	if len(m.Object.Vertices) != 4 || m.Object.Vertices[1].X != "10.25" || m.Object.Vertices[2].Y != "10" ||
+		m.Object.Vertices[3].Z != "0" {
+		t.Errorf("vertices %v", m.Object.Vertices)
+	}
+	last := m.Object.Triangles[3]
+	if len(m.Object.Triangles) != 4 || last.V1 != 1 || last.V3 != 3 || last.PID != 1 || last.P1 != 1 || last.P2 != 0 ||
+		last.P3 != 2 {
+		t.Errorf("triangles %v", m.Object.Triangles)
+	}
+	if m.Item.ObjectID != m.Object.ID {
+		t.Errorf("build item %d for object %d", m.Item.ObjectID, m.Object.ID)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, vertices, triangles, Options{Unit: Inch}); err != nil {
+		t.Fatal(err)
+	}
+	if _, m := read(t, buf.Bytes()); m.Unit != "inch" || len(m.Colors) != 0 || m.Object.Triangles[0].PID != 0 {
+		t.Errorf("uncolored inch model %v", m)
+	}
+
+	if err := Write(&buf, vertices, triangles, Options{Colors: colors[:2]}); err == nil {
+		t.Error("colors missing for vertices written")
+	}
+	if err := Write(&buf, vertices, triangles, Options{Unit: "furlong"}); err == nil {
+		t.Error("unknown unit written")
+	}
+}
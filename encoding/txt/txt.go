@@ -0,0 +1,618 @@
+// Package txt imports points from delimited text, such as the XYZ and CSV exports of total stations, scanners and
+// other point cloud software, including the output of "sloot csv".
+//
+// Each line holds one point, its fields separated by commas, tabs, semicolons, pipes or runs of spaces.  Blank lines
+// and lines beginning with '#' or "//" are skipped.  Which field holds which dimension is given by a column mapping,
+// either provided up front or read from a header line naming the columns.
+package txt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// field identifies a point attribute the importer understands.
+type field int
+
+const (
+	fieldX field = iota
+	fieldY
+	fieldZ
+	fieldIntensity
+	fieldReturnNumber
+	fieldNumberOfReturns
+	fieldClassification
+	fieldScanAngle
+	fieldUserData
+	fieldPointSourceID
+	fieldGPSTime
+	fieldRed
+	fieldGreen
+	fieldBlue
+	fieldNIR
+	numFields
+)
+
+// fieldDimensions names the dimension each field is stored as.
+var fieldDimensions = [numFields]string{
+	las14.DimX, las14.DimY, las14.DimZ, las14.DimIntensity, las14.DimReturnNumber, las14.DimNumberOfReturns,
+	las14.DimClassification, las14.DimScanAngle, las14.DimUserData, las14.DimPointSourceID, las14.DimGPSTime,
+	las14.DimRed, las14.DimGreen, las14.DimBlue, las14.DimNIR,
+}
+
+// fieldAliases maps normalized column names, beyond the dimension names themselves, to the fields they hold.
+var fieldAliases = map[string]field{
+	"e":             fieldX,
+	"east":          fieldX,
+	"easting":       fieldX,
+	"lon":           fieldX,
+	"long":          fieldX,
+	"longitude":     fieldX,
+	"n":             fieldY,
+	"north":         fieldY,
+	"northing":      fieldY,
+	"lat":           fieldY,
+	"latitude":      fieldY,
+	"h":             fieldZ,
+	"elev":          fieldZ,
+	"elevation":     fieldZ,
+	"height":        fieldZ,
+	"i":             fieldIntensity,
+	"return":        fieldReturnNumber,
+	"returns":       fieldNumberOfReturns,
+	"c":             fieldClassification,
+	"class":         fieldClassification,
+	"scanangle":     fieldScanAngle,
+	"scananglerank": fieldScanAngle,
+	"sourceid":      fieldPointSourceID,
+	"t":             fieldGPSTime,
+	"time":          fieldGPSTime,
+	"gpstime":       fieldGPSTime,
+	"r":             fieldRed,
+	"g":             fieldGreen,
+	"b":             fieldBlue,
+	"infrared":      fieldNIR,
+	"nearinfrared":  fieldNIR,
+}
+
+// skipColumns are the column names that mark a field to be ignored.
+var skipColumns = map[string]bool{"": true, "-": true, "skip": true, "ignore": true}
+
+// normalizeColumn lowercases name and drops the spaces, underscores and hyphens that separate words, so that
+// "Return Number", "return_number" and "ReturnNumber" all name the same column.
+func normalizeColumn(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// lookupColumn returns the field a column name refers to.
+func lookupColumn(name string) (field, bool) {
+	key := normalizeColumn(name)
+	if f, ok := fieldAliases[key]; ok {
+		return f, true
+	}
+	for f, dim := range fieldDimensions {
+		if key == strings.ToLower(dim) {
+			return (field)(f), true
+		}
+	}
+	return 0, false
+}
+
+// TimeFormat describes how numeric values of a time column count time.
+type TimeFormat int
+
+const (
+	// TimeAuto treats times within the span of a single GPS week as GPS week time, and any other times as Adjusted
+	// Standard GPS Time.
+	TimeAuto TimeFormat = iota
+
+	// TimeAdjustedStandard is GPS seconds since the GPS epoch, less one billion: the time LAS files store when
+	// FlagGPSTime is set.
+	TimeAdjustedStandard
+
+	// TimeStandard is GPS seconds since the GPS epoch.
+	TimeStandard
+
+	// TimeWeek is GPS seconds since the start of the GPS week.
+	TimeWeek
+
+	// TimeUnix is seconds since the Unix epoch, as UTC.
+	TimeUnix
+)
+
+var timeFormatNames = map[string]TimeFormat{
+	"auto":     TimeAuto,
+	"adjusted": TimeAdjustedStandard,
+	"gps":      TimeStandard,
+	"week":     TimeWeek,
+	"unix":     TimeUnix,
+}
+
+// ParseTimeFormat returns the TimeFormat named by name: "auto", "adjusted", "gps", "week" or "unix".
+func ParseTimeFormat(name string) (error, TimeFormat) {
+	tf, ok := timeFormatNames[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown time format %q: want auto, adjusted, gps, week or unix", name), 0
+	}
+	return nil, tf
+}
+
+// Options control how text is read and which LAS file is produced from it.  The zero Options detect everything.
+type Options struct {
+	// Columns names the dimension held by each field, in order.  Names are matched ignoring case, spaces and
+	// underscores against the dimension names of the LAS point formats (X, Intensity, ReturnNumber, GPSTime, Red,
+	// ...) and common aliases such as class, time, easting, northing, lat, lon and r, g, b.  Fields named "", "-",
+	// "skip" or "ignore", or beyond the last named column, are ignored.
+	//
+	// When Columns is empty, a header line naming the columns is used if the input has one, ignoring names not
+	// understood; otherwise the first three fields are taken as x, y and z.
+	Columns []string
+
+	// Delimiter separates fields.  Zero detects a comma, tab, semicolon or pipe from the first line, falling back to
+	// runs of whitespace.
+	Delimiter rune
+
+	// SkipLines is the number of lines at the start of each input to ignore, such as a preamble before the header.
+	SkipLines int
+
+	// Format, if set, is the point data record format to write.  By default the smallest format holding every
+	// column read is chosen: formats 0 or 2 for points without times and formats 6, 7 or 8 for points with them or
+	// with values only LAS 1.4 formats can hold.
+	Format *las14.PointDataFormat
+
+	// Precision, if set, gives the scale factors and offsets to store coordinates with.  By default each axis keeps
+	// as many decimal places as its input was written with.  See lassloot.AutoPrecision.
+	Precision *lassloot.Precision
+
+	// TimeFormat describes numeric values of the time column.  Values written as RFC 3339 timestamps are always
+	// understood as such.
+	TimeFormat TimeFormat
+}
+
+// An Importer accumulates the points of one or more text inputs into a single pointcloud.
+type Importer struct {
+	opts Options
+
+	// rows holds each point's fields in field order, NaN where the input has no value
+	rows    [][numFields]float64
+	present [numFields]bool
+	digits  [3]int
+
+	sawTimestamps bool
+}
+
+// NewImporter returns an Importer reading text as described by opts.
+func NewImporter(opts Options) *Importer {
+	return &Importer{opts: opts}
+}
+
+// Read reads every point of the text in r.  Each input may have its own header line, and so its own column order.
+func (imp *Importer) Read(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var columns []field
+	var skip []bool
+	delim := imp.opts.Delimiter
+	line := 0
+	for s.Scan() {
+		line++
+		if line <= imp.opts.SkipLines {
+			continue
+		}
+
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "//") {
+			continue
+		}
+
+		if columns == nil {
+			if delim == 0 {
+				delim = detectDelimiter(text)
+			}
+
+			fields := splitFields(text, delim)
+			var err error
+			if isHeader(fields) {
+				err, columns, skip = imp.mapColumns(fields)
+				if err != nil {
+					return fmt.Errorf("line %d: %w", line, err)
+				}
+				continue
+			}
+			err, columns, skip = imp.mapColumns(nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := imp.readRow(splitFields(text, delim), columns, skip)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	err := s.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read text: %w", err)
+	}
+	return nil
+}
+
+// mapColumns resolves the field held by each column, from Options.Columns or else the header line when one was read.
+// Columns marked in skip are ignored.
+func (imp *Importer) mapColumns(header []string) (error, []field, []bool) {
+	names := imp.opts.Columns
+	strict := true
+	if len(names) == 0 {
+		names = header
+		strict = false
+	}
+	if len(names) == 0 {
+		names = []string{"x", "y", "z"}
+	}
+
+	columns := make([]field, len(names))
+	skip := make([]bool, len(names))
+	var seen [numFields]bool
+	for i, name := range names {
+		if skipColumns[normalizeColumn(name)] {
+			skip[i] = true
+			continue
+		}
+
+		f, ok := lookupColumn(name)
+		if !ok {
+			if strict {
+				return fmt.Errorf("unknown column %q", name), nil, nil
+			}
+			skip[i] = true
+			continue
+		}
+		if seen[f] {
+			return fmt.Errorf("column %q repeats %s", name, fieldDimensions[f]), nil, nil
+		}
+		seen[f] = true
+		columns[i] = f
+	}
+
+	for _, f := range [...]field{fieldX, fieldY, fieldZ} {
+		if !seen[f] {
+			return fmt.Errorf("no column holds %s", strings.ToLower(fieldDimensions[f])), nil, nil
+		}
+	}
+
+	return nil, columns, skip
+}
+
+func (imp *Importer) readRow(values []string, columns []field, skip []bool) error {
+	var row [numFields]float64
+	for i := range row {
+		row[i] = math.NaN()
+	}
+
+	for i, f := range columns {
+		if skip[i] {
+			continue
+		}
+		if i >= len(values) {
+			if f <= fieldZ {
+				return fmt.Errorf("missing %s", strings.ToLower(fieldDimensions[f]))
+			}
+			continue
+		}
+
+		s := values[i]
+		if s == "" {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil && f == fieldGPSTime {
+			t, terr := time.Parse(time.RFC3339Nano, s)
+			if terr != nil {
+				return fmt.Errorf("invalid time %q", s)
+			}
+			v, err = lassloot.GPSSecondsFromTime(t)-lassloot.AdjustedStandardGPSTimeOffset, nil
+			imp.sawTimestamps = true
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", strings.ToLower(fieldDimensions[f]), s)
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("invalid %s %q", strings.ToLower(fieldDimensions[f]), s)
+		}
+
+		if f <= fieldZ {
+			if d := decimalPlaces(s); d > imp.digits[f] {
+				imp.digits[f] = d
+			}
+		}
+		row[f] = v
+		imp.present[f] = true
+	}
+
+	imp.rows = append(imp.rows, row)
+	return nil
+}
+
+// Len returns the number of points read so far.
+func (imp *Importer) Len() int {
+	return len(imp.rows)
+}
+
+// PointCloud returns a pointcloud holding every point read so far.  Intensities written as fractions between 0 and 1
+// are stretched to the full 16-bit range, as are colors written with 8-bit channels, as the LAS spec asks.
+func (imp *Importer) PointCloud() (error, *lassloot.PointCloud) {
+	if len(imp.rows) == 0 {
+		return fmt.Errorf("no points read"), nil
+	}
+
+	err, format := imp.format()
+	if err != nil {
+		return err, nil
+	}
+
+	err, b := lassloot.NewBuilder(format, imp.Precision())
+	if err != nil {
+		return err, nil
+	}
+
+	// points read without returns are each a single return, not return 0 of 0, which first and last return filters
+	// would drop
+	var single [numFields]bool
+	single[fieldReturnNumber] = !imp.present[fieldReturnNumber]
+	single[fieldNumberOfReturns] = !imp.present[fieldNumberOfReturns]
+
+	var dims [numFields]*las14.Dimension
+	for f := fieldIntensity; f < numFields; f++ {
+		if !imp.present[f] && !single[f] {
+			continue
+		}
+		d, ok := b.Schema().Dimension(fieldDimensions[f])
+		if !ok {
+			return fmt.Errorf("point format %d has no %s dimension", format, fieldDimensions[f]), nil
+		}
+		dims[f] = d
+	}
+
+	var scale [numFields]float64
+	for f := range scale {
+		scale[f] = 1
+	}
+	if imp.fractional(fieldIntensity) {
+		scale[fieldIntensity] = math.MaxUint16
+	}
+	if imp.eightBitColor() {
+		scale[fieldRed], scale[fieldGreen], scale[fieldBlue] = 257, 257, 257
+	}
+
+	err, tf := imp.timeFormat()
+	if err != nil {
+		return err, nil
+	}
+	if imp.present[fieldGPSTime] && tf != TimeWeek {
+		b.Header().GlobalEncoding |= las14.FlagGPSTime
+	}
+
+	for i, row := range imp.rows {
+		err := b.Add(row[fieldX], row[fieldY], row[fieldZ])
+		if err != nil {
+			return fmt.Errorf("point %d: %w", i, err), nil
+		}
+
+		for f := fieldIntensity; f < numFields; f++ {
+			v := row[f]
+			if single[f] {
+				v = 1
+			}
+			if dims[f] == nil || math.IsNaN(v) {
+				continue
+			}
+
+			if f == fieldGPSTime {
+				v = toAdjustedStandard(v, tf)
+			}
+			err := b.SetDimension(dims[f], v*scale[f])
+			if err != nil {
+				return fmt.Errorf("point %d: %w", i, err), nil
+			}
+		}
+	}
+
+	return b.Build()
+}
+
+// format returns the point data record format requested by the options, or else the smallest able to hold every
+// field read.
+func (imp *Importer) format() (error, las14.PointDataFormat) {
+	if imp.opts.Format != nil {
+		format := *imp.opts.Format
+		if !format.IsValid() {
+			return fmt.Errorf("unrecognized point data record format: %d", format), 0
+		}
+		return nil, format
+	}
+
+	rgb := imp.present[fieldRed] || imp.present[fieldGreen] || imp.present[fieldBlue]
+	return nil, lassloot.PointFormatFor(imp.present[fieldGPSTime], rgb, imp.present[fieldNIR], imp.needsExtendedFormat())
+}
+
+// needsExtendedFormat reports whether any point holds a value only the LAS 1.4 formats can store: a classification
+// above 31 or more than 7 returns.
+func (imp *Importer) needsExtendedFormat() bool {
+	for _, row := range imp.rows {
+		if row[fieldClassification] > 31 || row[fieldReturnNumber] > 7 || row[fieldNumberOfReturns] > 7 {
+			return true
+		}
+	}
+	return false
+}
+
+// Precision returns the scale factors and offsets coordinates will be stored with: those of Options.Precision or
+// SetPrecision, or else ones chosen to keep every coordinate read so far as written.
+func (imp *Importer) Precision() lassloot.Precision {
+	if imp.opts.Precision != nil {
+		return *imp.opts.Precision
+	}
+
+	min := [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	max := [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, row := range imp.rows {
+		for i := 0; i < 3; i++ {
+			min[i] = math.Min(min[i], row[i])
+			max[i] = math.Max(max[i], row[i])
+		}
+	}
+	return lassloot.AutoPrecision(min, max, imp.digits)
+}
+
+// SetPrecision sets the scale factors and offsets coordinates will be stored with.
+func (imp *Importer) SetPrecision(p lassloot.Precision) {
+	imp.opts.Precision = &p
+}
+
+// fractional reports whether every value of f lies between 0 and 1 and some are not whole numbers.
+func (imp *Importer) fractional(f field) bool {
+	fraction := false
+	for _, row := range imp.rows {
+		v := row[f]
+		if math.IsNaN(v) {
+			continue
+		}
+		if v < 0 || v > 1 {
+			return false
+		}
+		if v != math.Trunc(v) {
+			fraction = true
+		}
+	}
+	return fraction
+}
+
+// eightBitColor reports whether every color channel lies within 0 to 255, as written by software that stores 8-bit
+// color.
+func (imp *Importer) eightBitColor() bool {
+	seen := false
+	for _, row := range imp.rows {
+		for _, f := range [...]field{fieldRed, fieldGreen, fieldBlue} {
+			v := row[f]
+			if math.IsNaN(v) {
+				continue
+			}
+			if v > 255 {
+				return false
+			}
+			seen = true
+		}
+	}
+	return seen
+}
+
+// timeFormat resolves TimeAuto against the times read.
+func (imp *Importer) timeFormat() (error, TimeFormat) {
+	tf := imp.opts.TimeFormat
+	if imp.sawTimestamps {
+		if tf == TimeWeek {
+			return fmt.Errorf("time column holds timestamps, which cannot be stored as gps week time"), 0
+		}
+		// timestamps have already been converted
+		return nil, TimeAdjustedStandard
+	}
+	if tf != TimeAuto {
+		return nil, tf
+	}
+
+	for _, row := range imp.rows {
+		v := row[fieldGPSTime]
+		if !math.IsNaN(v) && (v < 0 || v > lassloot.SecondsPerGPSWeek) {
+			return nil, TimeAdjustedStandard
+		}
+	}
+	return nil, TimeWeek
+}
+
+// toAdjustedStandard converts a time in format tf to the time stored in the points: Adjusted Standard GPS Time, or
+// GPS week time when tf is TimeWeek.
+func toAdjustedStandard(v float64, tf TimeFormat) float64 {
+	switch tf {
+	case TimeStandard:
+		return v - lassloot.AdjustedStandardGPSTimeOffset
+	case TimeUnix:
+		sec, frac := math.Modf(v)
+		t := time.Unix((int64)(sec), (int64)(frac*1e9)).UTC()
+		return lassloot.GPSSecondsFromTime(t) - lassloot.AdjustedStandardGPSTimeOffset
+	default:
+		return v
+	}
+}
+
+// detectDelimiter guesses the delimiter of line from the separators it contains.
+func detectDelimiter(line string) rune {
+	for _, r := range [...]rune{',', '\t', ';', '|'} {
+		if strings.ContainsRune(line, r) {
+			return r
+		}
+	}
+	return ' '
+}
+
+// splitFields splits line at delim, treating a space delimiter as any run of whitespace and trimming the spaces and
+// quotes around each field.
+func splitFields(line string, delim rune) []string {
+	var fields []string
+	if delim == ' ' {
+		fields = strings.Fields(line)
+	} else {
+		fields = strings.Split(line, string(delim))
+	}
+
+	for i, f := range fields {
+		fields[i] = strings.Trim(strings.TrimSpace(f), `"'`)
+	}
+	return fields
+}
+
+// isHeader reports whether fields are column names rather than values: none of them is a number.
+func isHeader(fields []string) bool {
+	for _, f := range fields {
+		if _, err := strconv.ParseFloat(f, 64); err == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// decimalPlaces returns the number of digits s was written with after its decimal point.
+func decimalPlaces(s string) int {
+	mantissa := s
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		exp, _ = strconv.Atoi(s[i+1:])
+	}
+
+	d := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		d = len(mantissa) - i - 1
+	}
+	d -= exp
+	if d < 0 {
+		return 0
+	}
+	return d
+}
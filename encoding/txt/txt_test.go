@@ -0,0 +1,161 @@
+package txt
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+func importText(t *testing.T, opts Options, text string) *lassloot.PointCloud {
+	t.Helper()
+
+	imp := NewImporter(opts)
+	if err := imp.Read(strings.NewReader(text)); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	err, pc := imp.PointCloud()
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	// every import must survive a trip through a LAS file
+	var buf bytes.Buffer
+	if err := pc.WriteLAS(&buf); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	err, pc = lassloot.NewPointCloudFromReader(&buf)
+	if err != nil {
+		t.Fatalf("written file does not decode: %v", err)
+	}
+	return pc
+}
+
+func value(t *testing.T, pc *lassloot.PointCloud, idx uint64, name string) float64 {
+	t.Helper()
+
+	err, p := pc.PointAt(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, v := p.Float64(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestImportHeaderAndFormat(t *testing.T) {
+	text := "# exported by a total station\n" +
+		"Easting,Northing,Elevation,Class,Intensity,R,G,B,Code\n" +
+		"500000.125,4100000.5,12.25,2,0.5,255,128,0,TREE\n" +
+		"500010.000,4100020.0,13.00,6,1,0,0,255,BLDG\n"
+	pc := importText(t, Options{}, text)
+
+	if pc.Len() != 2 {
+		t.Fatalf("len = %d, want 2", pc.Len())
+	}
+	if f := pc.Header().RawHeader.PointDataRecordFormat; f != 2 {
+		t.Errorf("format = %d, want 2 for color without time", f)
+	}
+
+	p := pc.Precision()
+	if p.XScaleFactor != 0.001 || p.YScaleFactor != 0.1 || p.ZScaleFactor != 0.01 || p.XOffset != 500000 || p.YOffset != 4100000 {
+		t.Errorf("precision = %+v", p)
+	}
+
+	err, pt := pc.PointAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y, z := pt.XYZ()
+	if math.Abs(x-500000.125) > 1e-9 || math.Abs(y-4100000.5) > 1e-9 || math.Abs(z-12.25) > 1e-9 {
+		t.Errorf("xyz = %v %v %v", x, y, z)
+	}
+
+	if v := value(t, pc, 1, las14.DimClassification); v != 6 {
+		t.Errorf("classification = %v, want 6", v)
+	}
+	if v := value(t, pc, 0, las14.DimIntensity); v != 32768 {
+		t.Errorf("fractional intensity = %v, want 32768", v)
+	}
+	if v := value(t, pc, 0, las14.DimRed); v != 65535 {
+		t.Errorf("8-bit red = %v, want 65535", v)
+	}
+	// without return columns, each point is a single return
+	for _, name := range []string{las14.DimReturnNumber, las14.DimNumberOfReturns} {
+		if v := value(t, pc, 1, name); v != 1 {
+			t.Errorf("%s = %v, want 1 without the column", name, v)
+		}
+	}
+
+	pc = importText(t, Options{Columns: []string{"x", "y", "z", "return", "returns"}}, "1,2,3,2,3\n")
+	if r, n := value(t, pc, 0, las14.DimReturnNumber), value(t, pc, 0, las14.DimNumberOfReturns); r != 2 || n != 3 {
+		t.Errorf("return %v of %v, want 2 of 3 as read", r, n)
+	}
+}
+
+func TestImportColumnsAndTime(t *testing.T) {
+	text := "1 2 3 skipped 1.5\n4 5 6 skipped 604000\n"
+	pc := importText(t, Options{Columns: []string{"x", "y", "z", "-", "gps_time"}}, text)
+
+	h := pc.Header().RawHeader
+	if h.PointDataRecordFormat != 6 {
+		t.Errorf("format = %d, want 6 for points with times", h.PointDataRecordFormat)
+	}
+	if h.GlobalEncoding.IsGPSTimeStandard() {
+		t.Error("times within a week were marked as adjusted standard gps time")
+	}
+	if v := value(t, pc, 1, las14.DimGPSTime); v != 604000 {
+		t.Errorf("gps time = %v", v)
+	}
+
+	pc = importText(t, Options{Columns: []string{"x", "y", "z", "time"}}, "1,2,3,2021-01-01T00:00:00Z\n")
+	if !pc.IsAdjustedStandardGPSTime() {
+		t.Error("timestamps not stored as adjusted standard gps time")
+	}
+	err, p := pc.PointAt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, ts := p.Time()
+	if err != nil || ts.Format("2006-01-02T15:04:05Z07:00") != "2021-01-01T00:00:00Z" {
+		t.Errorf("time = %v, %v", ts, err)
+	}
+
+	if v := value(t, importText(t, Options{Columns: []string{"x", "y", "z", "class"}}, "1,2,3,40\n"), 0, las14.DimClassification); v != 40 {
+		t.Errorf("classification = %v, want 40 in an extended format", v)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		text string
+	}{
+		{"unknown column", Options{Columns: []string{"x", "y", "z", "bogus"}}, "1,2,3,4\n"},
+		{"missing z", Options{Columns: []string{"x", "y"}}, "1,2\n"},
+		{"bad value", Options{}, "1,2,three\n"},
+		{"short line", Options{}, "1,2,3\n4,5\n"},
+	}
+
+	for _, tt := range tests {
+		imp := NewImporter(tt.opts)
+		if err := imp.Read(strings.NewReader(tt.text)); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+
+	format := (las14.PointDataFormat)(0)
+	imp := NewImporter(Options{Format: &format, Columns: []string{"x", "y", "z", "red"}})
+	if err := imp.Read(strings.NewReader("1,2,3,4\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err, _ := imp.PointCloud(); err == nil {
+		t.Error("format 0 accepted a color column")
+	}
+}
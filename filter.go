@@ -0,0 +1,126 @@
+package lassloot
+
+import (
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// Rect is an axis aligned rectangle in the horizontal plane.
+type Rect struct {
+	MinX float64
+	MinY float64
+	MaxX float64
+	MaxY float64
+}
+
+// Contains reports whether x, y lies within the rectangle, including its edges.
+func (r Rect) Contains(x, y float64) bool {
+	return x >= r.MinX && x <= r.MaxX && y >= r.MinY && y <= r.MaxY
+}
+
+// Filter selects points by their attributes.  Each field left at its zero value places no restriction, so the zero
+// Filter matches every point.
+type Filter struct {
+	// Classes, when non-empty, restricts points to those with one of the listed classifications.
+	Classes []uint8
+
+	// ReturnNumbers, when non-empty, restricts points to those with one of the listed return numbers.  FirstReturns,
+	// LastReturns and SingleReturns restrict points to the first, last and only returns of their pulses.
+	ReturnNumbers []uint8
+	FirstReturns  bool
+	LastReturns   bool
+	SingleReturns bool
+
+	// Bounds restricts points to those within a rectangle, and MinZ and MaxZ to those within a range of elevations.
+	Bounds *Rect
+	MinZ   *float64
+	MaxZ   *float64
+
+	// DropWithheld removes points flagged as withheld, which the specification asks software to treat as deleted.
+	DropWithheld bool
+}
+
+// IsEmpty reports whether f matches every point.
+func (f *Filter) IsEmpty() bool {
+	return len(f.Classes) == 0 && len(f.ReturnNumbers) == 0 && !f.FirstReturns && !f.LastReturns &&
+		!f.SingleReturns && f.Bounds == nil && f.MinZ == nil && f.MaxZ == nil && !f.DropWithheld
+}
+
+// Match reports whether p satisfies every restriction of f.
+func (f *Filter) Match(p *Point) bool {
+	schema := p.pc.schema
+	raw := p.PDR.Raw
+
+	if len(f.Classes) > 0 && !containsByte(f.Classes, p.PDR.Get().Classification()) {
+		return false
+	}
+
+	if len(f.ReturnNumbers) > 0 || f.FirstReturns || f.LastReturns || f.SingleReturns {
+		rd, _ := schema.Dimension(las14.DimReturnNumber)
+		nd, _ := schema.Dimension(las14.DimNumberOfReturns)
+		ret, n := (uint8)(rd.RawFloat64(raw)), (uint8)(nd.RawFloat64(raw))
+
+		if len(f.ReturnNumbers) > 0 && !containsByte(f.ReturnNumbers, ret) {
+			return false
+		}
+		if f.FirstReturns && ret != 1 {
+			return false
+		}
+		if f.LastReturns && ret != n {
+			return false
+		}
+		if f.SingleReturns && n != 1 {
+			return false
+		}
+	}
+
+	if f.DropWithheld {
+		d, _ := schema.Dimension(las14.DimWithheld)
+		if d.RawFloat64(raw) != 0 {
+			return false
+		}
+	}
+
+	if f.Bounds != nil || f.MinZ != nil || f.MaxZ != nil {
+		x, y, z := p.XYZ()
+		if f.Bounds != nil && !f.Bounds.Contains(x, y) {
+			return false
+		}
+		if f.MinZ != nil && z < *f.MinZ {
+			return false
+		}
+		if f.MaxZ != nil && z > *f.MaxZ {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filter returns a pointcloud holding only the points that match f.  Its header's point counts and bounds describe
+// the selected points.  An empty filter returns pc itself.
+func (pc *PointCloud) Filter(f *Filter) (error, *PointCloud) {
+	if f == nil || f.IsEmpty() {
+		return nil, pc
+	}
+
+	fr := pc.fr.Select(func(idx uint64) bool {
+		return f.Match(&Point{PDR: pc.fr.PointDataRecord(idx), pc: pc})
+	})
+
+	err, filtered := newPointCloud(fr)
+	if err != nil {
+		return err, nil
+	}
+
+	filtered.gpsWeek, filtered.gpsWeekSet = pc.gpsWeek, pc.gpsWeekSet
+	return nil, filtered
+}
+
+func containsByte(values []uint8, v uint8) bool {
+	for _, c := range values {
+		if c == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package lassloot
+
+import (
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestFilter(t *testing.T) {
+	points := []lastest.Point{
+		{X: 0, Y: 0, Z: 100, Classification: 2, ReturnNumber: 1, NumberOfReturns: 1},
+		{X: 500, Y: 500, Z: 900, Classification: 5, ReturnNumber: 1, NumberOfReturns: 2},
+		{X: 500, Y: 500, Z: 200, Classification: 2, ReturnNumber: 2, NumberOfReturns: 2},
+		{X: 900, Y: 100, Z: 300, Classification: 2, ReturnNumber: 1, NumberOfReturns: 1, ClassFlags: 4},
+	}
+
+	for _, format := range []byte{1, 6} {
+		pc := newTestPointCloud(t, lastest.Spec{Header: lastest.Header{PointDataRecordFormat: format}, Points: points})
+
+		minZ := 1.5
+		tests := []struct {
+			name   string
+			filter Filter
+			want   []float64
+		}{
+			{"empty", Filter{}, []float64{1, 9, 2, 3}},
+			{"ground", Filter{Classes: []uint8{2}}, []float64{1, 2, 3}},
+			{"last returns", Filter{LastReturns: true}, []float64{1, 2, 3}},
+			{"first returns", Filter{FirstReturns: true}, []float64{1, 9, 3}},
+			{"single returns", Filter{SingleReturns: true}, []float64{1, 3}},
+			{"return two", Filter{ReturnNumbers: []uint8{2}}, []float64{2}},
+			{"withheld", Filter{DropWithheld: true}, []float64{1, 9, 2}},
+			{"bounds", Filter{Bounds: &Rect{MinX: 4, MinY: 0, MaxX: 10, MaxY: 2}}, []float64{3}},
+			{"ground above", Filter{Classes: []uint8{2}, MinZ: &minZ}, []float64{2, 3}},
+		}
+
+		for _, tt := range tests {
+			err, filtered := pc.Filter(&tt.filter)
+			if err != nil {
+				t.Fatalf("format %d %s: %v", format, tt.name, err)
+			}
+
+			if filtered.Len() != (uint64)(len(tt.want)) {
+				t.Errorf("format %d %s: %d points, want %d", format, tt.name, filtered.Len(), len(tt.want))
+				continue
+			}
+			for i, z := range tt.want {
+				_, p := filtered.PointAt((uint64)(i))
+				if _, _, got := p.XYZ(); got != z {
+					t.Errorf("format %d %s: point %d z = %v, want %v", format, tt.name, i, got, z)
+				}
+			}
+
+			h := filtered.Header().RawHeader
+			if len(tt.want) > 0 && (h.MinZ != minFloat(tt.want) || h.MaxZ != maxFloat(tt.want)) {
+				t.Errorf("format %d %s: header z range %v..%v", format, tt.name, h.MinZ, h.MaxZ)
+			}
+		}
+	}
+}
+
+func minFloat(v []float64) float64 {
+	ret := v[0]
+	for _, f := range v {
+		if f < ret {
+			ret = f
+		}
+	}
+	return ret
+}
+
+func maxFloat(v []float64) float64 {
+	ret := v[0]
+	for _, f := range v {
+		if f > ret {
+			ret = f
+		}
+	}
+	return ret
+}
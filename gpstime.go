@@ -0,0 +1,178 @@
+package lassloot
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// GPSEpoch is the origin of GPS time.
+var GPSEpoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// AdjustedStandardGPSTimeOffset is subtracted from standard GPS time to produce the Adjusted Standard GPS Time stored
+// by files whose global encoding sets FlagGPSTime.
+const AdjustedStandardGPSTimeOffset = 1e9
+
+// SecondsPerGPSWeek is the length of the GPS week that GPS week time counts from.
+const SecondsPerGPSWeek = 7 * 24 * 60 * 60
+
+// leapSeconds lists the UTC instants at which GPS time gained a second on UTC since the GPS epoch.  The offset
+// between GPS time and UTC after the nth entry is n+1 seconds.
+var leapSeconds = []time.Time{
+	time.Date(1981, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1982, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1983, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1985, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1988, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1991, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1992, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1993, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1994, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1996, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1997, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2006, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2009, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2012, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2015, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// gpsLeapSeconds returns the number of leap seconds between GPS time and UTC at gps seconds since the GPS epoch.
+func gpsLeapSeconds(gps float64) int {
+	n := 0
+	for i, leap := range leapSeconds {
+		// a leap second takes effect once GPS time, which already counts the new second, passes the UTC instant
+		if gps < leap.Sub(GPSEpoch).Seconds()+(float64)(i+1) {
+			break
+		}
+		n = i + 1
+	}
+	return n
+}
+
+// utcLeapSeconds returns the number of leap seconds between GPS time and UTC at t.
+func utcLeapSeconds(t time.Time) int {
+	n := 0
+	for i, leap := range leapSeconds {
+		if t.Before(leap) {
+			break
+		}
+		n = i + 1
+	}
+	return n
+}
+
+// TimeFromGPSSeconds converts seconds since the GPS epoch into a UTC time.Time, removing the leap seconds GPS time
+// does not observe.
+func TimeFromGPSSeconds(gps float64) time.Time {
+	gps -= (float64)(gpsLeapSeconds(gps))
+	sec := math.Floor(gps)
+	nsec := math.Round((gps - sec) * 1e9)
+
+	return time.Unix(GPSEpoch.Unix()+(int64)(sec), (int64)(nsec)).UTC()
+}
+
+// GPSSecondsFromTime converts t into seconds since the GPS epoch, the inverse of TimeFromGPSSeconds.
+func GPSSecondsFromTime(t time.Time) float64 {
+	return t.Sub(GPSEpoch).Seconds() + (float64)(utcLeapSeconds(t))
+}
+
+// GPSWeekOf returns the GPS week containing t.
+func GPSWeekOf(t time.Time) int {
+	return (int)(math.Floor(GPSSecondsFromTime(t) / SecondsPerGPSWeek))
+}
+
+// SetGPSWeek sets the GPS week that the GPS week time of a file without FlagGPSTime counts from, overriding the week
+// derived from the file's creation date.
+func (pc *PointCloud) SetGPSWeek(week int) {
+	pc.gpsWeek = week
+	pc.gpsWeekSet = true
+}
+
+// GPSWeek returns the GPS week that GPS week time counts from: the week provided to SetGPSWeek, or else the week of
+// the file's creation date.
+func (pc *PointCloud) GPSWeek() (error, int) {
+	if pc.gpsWeekSet {
+		return nil, pc.gpsWeek
+	}
+
+	h := pc.fr.Header
+	if h.FileCreationYear == 0 || h.FileCreationDayOfYear == 0 {
+		return fmt.Errorf("gps week unknown: file has no creation date, use SetGPSWeek"), 0
+	}
+
+	created := time.Date((int)(h.FileCreationYear), time.January, (int)(h.FileCreationDayOfYear), 0, 0, 0, 0, time.UTC)
+	return nil, GPSWeekOf(created)
+}
+
+// IsAdjustedStandardGPSTime reports whether the pointcloud's GPS times are Adjusted Standard GPS Time rather than
+// GPS week time.
+func (pc *PointCloud) IsAdjustedStandardGPSTime() bool {
+	return pc.fr.Header.GlobalEncoding.IsGPSTimeStandard()
+}
+
+// TimeFromGPSTime converts a GPS time as stored in the pointcloud's points into a UTC time.Time.
+func (pc *PointCloud) TimeFromGPSTime(gpsTime float64) (error, time.Time) {
+	if pc.IsAdjustedStandardGPSTime() {
+		return nil, TimeFromGPSSeconds(gpsTime + AdjustedStandardGPSTimeOffset)
+	}
+
+	err, week := pc.GPSWeek()
+	if err != nil {
+		return err, time.Time{}
+	}
+
+	return nil, TimeFromGPSSeconds((float64)(week)*SecondsPerGPSWeek + gpsTime)
+}
+
+// Time returns the UTC time at which the point was captured.  Point formats 0 and 2 carry no GPS time.
+func (p *Point) Time() (error, time.Time) {
+	d, ok := p.pc.schema.Dimension(las14.DimGPSTime)
+	if !ok {
+		return fmt.Errorf("point format %d has no gps time", p.PDR.Format), time.Time{}
+	}
+
+	return p.pc.TimeFromGPSTime(d.Float64(p.PDR.Raw))
+}
+
+// GPSTimeRange returns the smallest and largest GPS time stored in the pointcloud's points.
+func (pc *PointCloud) GPSTimeRange() (error, float64, float64) {
+	d, ok := pc.schema.Dimension(las14.DimGPSTime)
+	if !ok {
+		return fmt.Errorf("point format %d has no gps time", pc.fr.Header.PointDataRecordFormat), 0, 0
+	}
+
+	l := pc.Len()
+	if l == 0 {
+		return fmt.Errorf("pointcloud has no points"), 0, 0
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for i := (uint64)(0); i < l; i++ {
+		t := d.Float64(pc.fr.PointDataRecord(i).Raw)
+		min = math.Min(min, t)
+		max = math.Max(max, t)
+	}
+
+	return nil, min, max
+}
+
+// TimeRange returns the UTC times of the earliest and latest points in the pointcloud.
+func (pc *PointCloud) TimeRange() (error, time.Time, time.Time) {
+	err, min, max := pc.GPSTimeRange()
+	if err != nil {
+		return err, time.Time{}, time.Time{}
+	}
+
+	err, start := pc.TimeFromGPSTime(min)
+	if err != nil {
+		return err, time.Time{}, time.Time{}
+	}
+
+	_, end := pc.TimeFromGPSTime(max)
+	return nil, start, end
+}
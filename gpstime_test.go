@@ -0,0 +1,89 @@
+package lassloot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestTimeFromGPSSeconds(t *testing.T) {
+	tests := []struct {
+		name string
+		gps  float64
+		want time.Time
+	}{
+		{"epoch", 0, GPSEpoch},
+		{"before first leap second", 46828799, time.Date(1981, time.June, 30, 23, 59, 59, 0, time.UTC)},
+		{"after first leap second", 46828801, time.Date(1981, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"adjusted standard origin", AdjustedStandardGPSTimeOffset, time.Date(2011, time.September, 14, 1, 46, 25, 0, time.UTC)},
+		{"after 2017 leap second", 1167264018, time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"fractional", 1167264018.25, time.Date(2017, time.January, 1, 0, 0, 0, 250000000, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TimeFromGPSSeconds(tt.gps)
+			if !got.Equal(tt.want) {
+				t.Errorf("TimeFromGPSSeconds(%f) = %s, want %s", tt.gps, got, tt.want)
+			}
+
+			if back := GPSSecondsFromTime(got); back != tt.gps {
+				t.Errorf("GPSSecondsFromTime(%s) = %f, want %f", got, back, tt.gps)
+			}
+		})
+	}
+
+	if week := GPSWeekOf(time.Date(2021, time.October, 19, 12, 0, 0, 0, time.UTC)); week != 2180 {
+		t.Errorf("GPSWeekOf = %d, want 2180", week)
+	}
+}
+
+func TestPointTime(t *testing.T) {
+	adjusted := newTestPointCloud(t, lastest.Spec{
+		Header: lastest.Header{PointDataRecordFormat: 6, GlobalEncoding: 1},
+		Points: []lastest.Point{{GPSTime: 320000000.5}, {GPSTime: 320000010}},
+	})
+
+	_, p := adjusted.PointAt(0)
+	err, got := p.Time()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2021, time.November, 3, 18, 39, 42, 500000000, time.UTC); !got.Equal(want) {
+		t.Errorf("adjusted standard time = %s, want %s", got, want)
+	}
+
+	summary := adjusted.Header().String()
+	if !strings.Contains(summary, "2021-11-03T18:39:42.5Z") || !strings.Contains(summary, "9.5s") {
+		t.Errorf("header summary lacks time range:\n%s", summary)
+	}
+
+	// 2021 day 292 is Tuesday October 19th, within GPS week 2180
+	week := newTestPointCloud(t, lastest.Spec{
+		Header: lastest.Header{PointDataRecordFormat: 1, FileCreationYear: 2021, FileCreationDayOfYear: 292},
+		Points: []lastest.Point{{GPSTime: 2 * 86400}},
+	})
+
+	_, p = week.PointAt(0)
+	err, got = p.Time()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2021, time.October, 18, 23, 59, 42, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("week time = %s, want %s", got, want)
+	}
+
+	week.SetGPSWeek(2000)
+	_, got = p.Time()
+	if want := time.Date(2018, time.May, 7, 23, 59, 42, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("week time with explicit week = %s, want %s", got, want)
+	}
+
+	noTime := newTestPointCloud(t, lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 2}, PointCount: 1})
+	_, p = noTime.PointAt(0)
+	if err, _ := p.Time(); err == nil {
+		t.Error("expected format 2 point to have no time")
+	}
+}
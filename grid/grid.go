@@ -0,0 +1,128 @@
+// Package grid holds rasters of values over regular horizontal lattices, such as the gridded elevation models made
+// from a pointcloud's ground points.
+//
+// Grids run north up, as GeoTIFF rasters do: the first row is the northernmost and the first column the westernmost.
+// Cells without a value hold NaN.
+package grid
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nullstyle/lassloot/tin"
+)
+
+// Grid is a raster of values over square cells.
+type Grid struct {
+	// MinX and MaxY locate the top left (north west) corner of the grid, and Resolution is the width of its cells.
+	MinX, MaxY float64
+	Resolution float64
+
+	Cols, Rows int
+
+	// Values holds the value of each cell, a row at a time from the north, or NaN where the cell has none.
+	Values []float64
+}
+
+// New returns a grid of cols by rows cells of resolution width, with its top left corner at minX, maxY, and no values.
+func New(minX, maxY, resolution float64, cols, rows int) (error, *Grid) {
+	if !(resolution > 0) || math.IsInf(resolution, 0) {
+		return fmt.Errorf("invalid resolution %v", resolution), nil
+	}
+	if cols < 1 || rows < 1 || (int64)(cols)*(int64)(rows) > math.MaxInt32 {
+		return fmt.Errorf("invalid grid of %d by %d cells", cols, rows), nil
+	}
+
+	g := &Grid{MinX: minX, MaxY: maxY, Resolution: resolution, Cols: cols, Rows: rows, Values: make([]float64, cols*rows)}
+	for i := range g.Values {
+		g.Values[i] = math.NaN()
+	}
+	return nil, g
+}
+
+// Covering returns a grid of resolution width cells, with no values, that covers the box from min to max.  Its edges
+// lie on multiples of resolution, so grids of the same resolution over neighbouring areas line up.
+func Covering(min, max [2]float64, resolution float64) (error, *Grid) {
+	if !(resolution > 0) || math.IsInf(resolution, 0) {
+		return fmt.Errorf("invalid resolution %v", resolution), nil
+	}
+	minX, maxX := math.Floor(min[0]/resolution)*resolution, math.Ceil(max[0]/resolution)*resolution
+	minY, maxY := math.Floor(min[1]/resolution)*resolution, math.Ceil(max[1]/resolution)*resolution
+	cols := (int)(math.Max(1, math.Round((maxX-minX)/resolution)))
+	rows := (int)(math.Max(1, math.Round((maxY-minY)/resolution)))
+	if (float64)(cols)*(float64)(rows) > math.MaxInt32 {
+		return fmt.Errorf("resolution %v makes %.0f cells", resolution, (float64)(cols)*(float64)(rows)), nil
+	}
+	return New(minX, maxY, resolution, cols, rows)
+}
+
+// At returns the value of the cell at col, row.
+func (g *Grid) At(col, row int) float64 {
+	return g.Values[row*g.Cols+col]
+}
+
+// Set sets the value of the cell at col, row.
+func (g *Grid) Set(col, row int, v float64) {
+	g.Values[row*g.Cols+col] = v
+}
+
+// Center returns the horizontal position of the center of the cell at col, row.
+func (g *Grid) Center(col, row int) (float64, float64) {
+	return g.MinX + ((float64)(col)+0.5)*g.Resolution, g.MaxY - ((float64)(row)+0.5)*g.Resolution
+}
+
+// Cell returns the column and row of the cell holding x, y, which may lie outside the grid.
+func (g *Grid) Cell(x, y float64) (int, int) {
+	return (int)(math.Floor((x - g.MinX) / g.Resolution)), (int)(math.Floor((g.MaxY - y) / g.Resolution))
+}
+
+// Interpolator estimates the value of a surface at any horizontal position from the points it was made from.
+type Interpolator interface {
+	// At returns the value at x, y, or false where there is none, such as beyond the reach of the points.
+	At(x, y float64) (float64, bool)
+}
+
+// Fill sets each cell of g without a value to the value in interpolates at its center.  Filling a grid fresh from New
+// interpolates all of it; filling one of binned points fills the gaps between them, such as ground under trees.  Fill
+// returns the number of cells it set.
+func (g *Grid) Fill(in Interpolator) int {
+	filled := 0
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			if !math.IsNaN(g.At(col, row)) {
+				continue
+			}
+			if z, ok := in.At(g.Center(col, row)); ok {
+				g.Set(col, row, z)
+				filled++
+			}
+		}
+	}
+	return filled
+}
+
+// FromMesh returns a grid of resolution width cells over the bounds of m, holding the elevation of m at the center of
+// each cell, or NaN where the center lies outside it.
+func FromMesh(m *tin.Mesh, resolution float64) (error, *Grid) {
+	if len(m.Triangles) == 0 {
+		return fmt.Errorf("mesh is empty"), nil
+	}
+	min, max := m.Bounds()
+	err, g := Covering(min, max, resolution)
+	if err != nil {
+		return err, nil
+	}
+
+	hint := -1
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			x, y := g.Center(col, row)
+			z, tri, ok := m.Interpolate(x, y, hint)
+			if ok {
+				g.Set(col, row, z)
+				hint = tri
+			}
+		}
+	}
+	return nil, g
+}
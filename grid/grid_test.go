@@ -0,0 +1,59 @@
+package grid
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/tin"
+)
+
+func TestCovering(t *testing.T) {
+	err, g := Covering([2]float64{10.2, 20.7}, [2]float64{14.9, 23}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.MinX != 10 || g.MaxY != 23 || g.Cols != 10 || g.Rows != 5 || len(g.Values) != 50 || !math.IsNaN(g.Values[0]) {
+		t.Errorf("grid %v, %v of %d by %d", g.MinX, g.MaxY, g.Cols, g.Rows)
+	}
+	if x, y := g.Center(2, 1); x != 11.25 || y != 22.25 {
+		t.Errorf("center at %v, %v", x, y)
+	}
+	if col, row := g.Cell(11.25, 22.25); col != 2 || row != 1 {
+		t.Errorf("cell %d, %d", col, row)
+	}
+
+	if err, _ := Covering([2]float64{0, 0}, [2]float64{1, 1}, 0); err == nil {
+		t.Error("grid of zero resolution")
+	}
+	if err, _ := Covering([2]float64{0, 0}, [2]float64{1e6, 1e6}, 1e-3); err == nil {
+		t.Error("grid of 10^18 cells")
+	}
+}
+
+func TestFromMesh(t *testing.T) {
+	points := [][3]float64{{0, 0, 1}, {4, 0, 5}, {0, 4, -3}}
+	err, m := tin.Triangulate(points, tin.Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, g := FromMesh(m, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Cols != 4 || g.Rows != 4 {
+		t.Fatalf("grid of %d by %d", g.Cols, g.Rows)
+	}
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			x, y := g.Center(col, row)
+			z := g.At(col, row)
+			if x+y > 4 {
+				if !math.IsNaN(z) {
+					t.Errorf("%v, %v outside the mesh at %v", x, y, z)
+				}
+			} else if want := 1 + x - y; math.Abs(z-want) > 1e-12 {
+				t.Errorf("%v, %v at %v, want %v", x, y, z, want)
+			}
+		}
+	}
+}
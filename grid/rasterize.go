@@ -0,0 +1,298 @@
+package grid
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+)
+
+// Method chooses how the points falling in a cell make its value.
+type Method int
+
+const (
+	// Mean takes their mean elevation, Min and Max the lowest and highest: a bare earth DTM of ground points usually
+	// takes Min or Mean, and a DSM of first returns Max.
+	Mean Method = iota
+	Min
+	Max
+
+	// IDW weights each elevation by the inverse of the point's horizontal distance from the cell's center, raised to
+	// a power.
+	IDW
+
+	// Count counts the points, so cells without any hold zero rather than NaN.
+	Count
+
+	// Percentile takes a percentile of their elevations, interpolating between the nearest two.
+	Percentile
+)
+
+var methodNames = [...]string{"mean", "min", "max", "idw", "count", "percentile"}
+
+func (m Method) String() string {
+	if m < 0 || (int)(m) >= len(methodNames) {
+		return fmt.Sprintf("Method(%d)", m)
+	}
+	return methodNames[m]
+}
+
+// ParseMethod returns the method named s, as String returns.
+func ParseMethod(s string) (error, Method) {
+	for i, name := range methodNames {
+		if strings.EqualFold(s, name) {
+			return nil, (Method)(i)
+		}
+	}
+	return fmt.Errorf("unknown method %q: want %s", s, strings.Join(methodNames[:], ", ")), Mean
+}
+
+// Options control how points are binned into a grid.
+type Options struct {
+	// Resolution is the width of the grid's cells.
+	Resolution float64
+
+	// Bounds, if set, is the area the grid covers, extended east and south to a whole number of cells.  Without it,
+	// Rasterize covers the bounds in the file's header, with the grid's edges on multiples of Resolution.
+	Bounds *lassloot.Rect
+
+	Method Method
+
+	// Percentile is the percentile, from 0 to 100, that the Percentile method takes.
+	Percentile float64
+
+	// Power is the power of the distance that IDW weights by.  Zero takes 2.
+	Power float64
+
+	// Interpolate, if set, makes an interpolator from the points read, which Rasterize fills the grid with in place of
+	// binning them: a surface without holes or stair steps.
+	Interpolate func(points [][3]float64) (error, Interpolator)
+
+	// Fill bins the points and interpolates only the cells without any.
+	Fill bool
+}
+
+// Binner bins points into the cells of a grid, one at a time, and makes the value of each cell from those in it.
+type Binner struct {
+	g    *Grid
+	opts Options
+
+	// count holds the points in each cell; sum their sum of elevations, or of weighted elevations for IDW, and
+	// weights the sum of IDW's weights
+	count   []uint32
+	sum     []float64
+	weights []float64
+
+	// exact counts the points at the centers of cells, which IDW takes alone
+	exact []uint32
+
+	// values holds each point's cell and elevation, for percentiles
+	values []binned
+}
+
+type binned struct {
+	cell int32
+	z    float64
+}
+
+// NewBinner returns a binner over the cells of g, whose values it replaces.
+func NewBinner(g *Grid, opts Options) (error, *Binner) {
+	if opts.Method < 0 || (int)(opts.Method) >= len(methodNames) {
+		return fmt.Errorf("unknown method %v", opts.Method), nil
+	}
+	if opts.Method == Percentile && !(opts.Percentile >= 0 && opts.Percentile <= 100) {
+		return fmt.Errorf("invalid percentile %v: want 0 to 100", opts.Percentile), nil
+	}
+	if opts.Power == 0 {
+		opts.Power = 2
+	}
+	if !(opts.Power > 0) || math.IsInf(opts.Power, 0) {
+		return fmt.Errorf("invalid power %v", opts.Power), nil
+	}
+
+	b := &Binner{g: g, opts: opts, count: make([]uint32, len(g.Values))}
+	switch opts.Method {
+	case Mean:
+		b.sum = make([]float64, len(g.Values))
+	case Min:
+		b.sum = make([]float64, len(g.Values))
+		for i := range b.sum {
+			b.sum[i] = math.Inf(1)
+		}
+	case Max:
+		b.sum = make([]float64, len(g.Values))
+		for i := range b.sum {
+			b.sum[i] = math.Inf(-1)
+		}
+	case IDW:
+		b.sum = make([]float64, len(g.Values))
+		b.weights = make([]float64, len(g.Values))
+		b.exact = make([]uint32, len(g.Values))
+	}
+	return nil, b
+}
+
+// Add bins a point at x, y, z.  Points outside the grid are ignored, and those on its east and south edges fall in
+// the cells beside them.
+func (b *Binner) Add(x, y, z float64) {
+	if math.IsNaN(z) {
+		return
+	}
+	g := b.g
+	col, row := g.Cell(x, y)
+	if col == g.Cols && x <= g.MinX+(float64)(g.Cols)*g.Resolution {
+		col--
+	}
+	if row == g.Rows && y >= g.MaxY-(float64)(g.Rows)*g.Resolution {
+		row--
+	}
+	if col < 0 || row < 0 || col >= g.Cols || row >= g.Rows {
+		return
+	}
+
+	i := row*g.Cols + col
+	b.count[i]++
+	switch b.opts.Method {
+	case Mean:
+		b.sum[i] += z
+	case Min:
+		b.sum[i] = math.Min(b.sum[i], z)
+	case Max:
+		b.sum[i] = math.Max(b.sum[i], z)
+	case IDW:
+		cx, cy := g.Center(col, row)
+		d := math.Hypot(x-cx, y-cy)
+		if d < 1e-9*g.Resolution {
+			if b.exact[i] == 0 {
+				b.sum[i], b.weights[i] = 0, 0
+			}
+			b.exact[i]++
+			b.sum[i] += z
+			b.weights[i]++
+		} else if b.exact[i] == 0 {
+			w := math.Pow(d, -b.opts.Power)
+			b.sum[i] += w * z
+			b.weights[i] += w
+		}
+	case Percentile:
+		b.values = append(b.values, binned{(int32)(i), z})
+	}
+}
+
+// Grid sets the value of each cell of the grid from the points binned in it, and returns the grid.  Cells without
+// points hold NaN, except when counting.
+func (b *Binner) Grid() *Grid {
+	g := b.g
+	for i, n := range b.count {
+		if n == 0 && b.opts.Method != Count {
+			g.Values[i] = math.NaN()
+			continue
+		}
+		switch b.opts.Method {
+		case Mean:
+			g.Values[i] = b.sum[i] / (float64)(n)
+		case Min, Max:
+			g.Values[i] = b.sum[i]
+		case IDW:
+			g.Values[i] = b.sum[i] / b.weights[i]
+		case Count:
+			g.Values[i] = (float64)(n)
+		}
+	}
+
+	if b.opts.Method == Percentile {
+		sort.Slice(b.values, func(i, j int) bool {
+			if b.values[i].cell != b.values[j].cell {
+				return b.values[i].cell < b.values[j].cell
+			}
+			return b.values[i].z < b.values[j].z
+		})
+		for start := 0; start < len(b.values); {
+			end := start
+			for end < len(b.values) && b.values[end].cell == b.values[start].cell {
+				end++
+			}
+			rank := b.opts.Percentile / 100 * (float64)(end-start-1)
+			lo := start + (int)(math.Floor(rank))
+			hi := start + (int)(math.Ceil(rank))
+			t := rank - math.Floor(rank)
+			g.Values[b.values[start].cell] = b.values[lo].z + t*(b.values[hi].z-b.values[lo].z)
+			start = end
+		}
+	}
+	return g
+}
+
+// Rasterize bins the points of ps, after any filter set on it, into a grid: a DTM of ground points, or a DSM of first
+// returns.  See Options.Interpolate for estimating the surface between them instead.  A grid without a single value,
+// when the filters or bounds leave no points, is an error.
+func Rasterize(ps *lassloot.PointStream, opts Options) (error, *Grid) {
+	var err error
+	var g *Grid
+	if r := opts.Bounds; r != nil {
+		if !(r.MaxX >= r.MinX) || !(r.MaxY >= r.MinY) {
+			return fmt.Errorf("invalid bounds %v", *r), nil
+		}
+		if !(opts.Resolution > 0) || math.IsInf(opts.Resolution, 0) {
+			return fmt.Errorf("invalid resolution %v", opts.Resolution), nil
+		}
+		cols := math.Max(1, math.Ceil((r.MaxX-r.MinX)/opts.Resolution))
+		rows := math.Max(1, math.Ceil((r.MaxY-r.MinY)/opts.Resolution))
+		if cols*rows > math.MaxInt32 {
+			return fmt.Errorf("resolution %v makes %.0f cells", opts.Resolution, cols*rows), nil
+		}
+		err, g = New(r.MinX, r.MaxY, opts.Resolution, (int)(cols), (int)(rows))
+	} else {
+		h := ps.Header().RawHeader
+		err, g = Covering([2]float64{h.MinX, h.MinY}, [2]float64{h.MaxX, h.MaxY}, opts.Resolution)
+	}
+	if err != nil {
+		return err, nil
+	}
+
+	err, b := NewBinner(g, opts)
+	if err != nil {
+		return err, nil
+	}
+	var points [][3]float64
+	for {
+		err, p := ps.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err, nil
+		}
+		x, y, z := p.XYZ()
+		if opts.Interpolate != nil {
+			points = append(points, [3]float64{x, y, z})
+		}
+		if opts.Interpolate == nil || opts.Fill {
+			b.Add(x, y, z)
+		}
+	}
+	if opts.Interpolate == nil || opts.Fill {
+		b.Grid()
+	}
+	filled := 0
+	if opts.Interpolate != nil && len(points) > 0 {
+		err, in := opts.Interpolate(points)
+		if err != nil {
+			return err, nil
+		}
+		filled = g.Fill(in)
+	}
+
+	for _, n := range b.count {
+		if n > 0 {
+			return nil, g
+		}
+	}
+	if filled == 0 {
+		return fmt.Errorf("no points within the grid"), nil
+	}
+	return nil, g
+}
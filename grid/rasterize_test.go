@@ -0,0 +1,73 @@
+package grid
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBinner(t *testing.T) {
+	// a 2 by 2 grid of unit cells; the top left cell gets four points, the bottom right one, and the others none
+	points := [][3]float64{{0.5, 1.5, 1}, {0.25, 1.75, 4}, {0.75, 1.25, 2}, {0.2, 1.2, 3}, {2, 0, 7}}
+	tests := []struct {
+		opts        Options
+		left, right float64
+	}{
+		{Options{Method: Mean}, 2.5, 7},
+		{Options{Method: Min}, 1, 7},
+		{Options{Method: Max}, 4, 7},
+		{Options{Method: Count}, 4, 1},
+		{Options{Method: Percentile, Percentile: 50}, 2.5, 7},
+		{Options{Method: Percentile, Percentile: 100}, 4, 7},
+		{Options{Method: Percentile, Percentile: 0}, 1, 7},
+		// the point at the cell's center is taken alone
+		{Options{Method: IDW}, 1, 7},
+	}
+	for _, test := range tests {
+		err, g := New(0, 2, 1, 2, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err, b := NewBinner(g, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range points {
+			b.Add(p[0], p[1], p[2])
+		}
+		b.Add(5, 5, 100)
+		g = b.Grid()
+		if g.At(0, 0) != test.left || g.At(1, 1) != test.right {
+			t.Errorf("%v: cells %v and %v, want %v and %v", test.opts.Method, g.At(0, 0), g.At(1, 1), test.left,
+				test.right)
+		}
+		empty := g.At(1, 0)
+		if test.opts.Method == Count && empty != 0 || test.opts.Method != Count && !math.IsNaN(empty) {
+			t.Errorf("%v: empty cell %v", test.opts.Method, empty)
+		}
+	}
+
+	// without a point at its center, nearer points weigh more
+	err, g := New(0, 1, 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, b := NewBinner(g, Options{Method: IDW, Power: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Add(0.6, 0.5, 10)
+	b.Add(0.8, 0.5, 40)
+	if v := b.Grid().At(0, 0); math.Abs(v-17.5) > 1e-9 {
+		t.Errorf("idw %v, want 17.5", v)
+	}
+
+	if err, _ := NewBinner(g, Options{Method: Percentile, Percentile: 101}); err == nil {
+		t.Error("binner of 101st percentile")
+	}
+	if err, m := ParseMethod("IDW"); err != nil || m != IDW {
+		t.Errorf("parsed %v, %v", m, err)
+	}
+	if err, _ := ParseMethod("median"); err == nil {
+		t.Error("parsed median")
+	}
+}
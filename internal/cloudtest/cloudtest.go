@@ -0,0 +1,42 @@
+// Package cloudtest decodes the synthetic files lastest generates into point clouds, for the tests of the encoders that
+// write them out again.  It lives apart from lastest, which lassloot's own tests import, because it imports lassloot.
+package cloudtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+// PointCloud returns a cloud of points in a point data record format, failing t if it does not decode.
+func PointCloud(t testing.TB, format byte, points []lastest.Point) *lassloot.PointCloud {
+	t.Helper()
+
+	return Generate(t, lastest.Spec{Header: lastest.Header{PointDataRecordFormat: format}, Points: points})
+}
+
+// Generate returns the cloud of the file spec describes, failing t if it does not decode.
+func Generate(t testing.TB, spec lastest.Spec) *lassloot.PointCloud {
+	t.Helper()
+
+	file := lastest.Generate(spec)
+	err, pc := lassloot.NewPointCloudFromReader(bytes.NewReader(file.Bytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
+
+// PointCloudAt is PointCloud, but decodes the file by random access, as a cloud read from disk would be.
+func PointCloudAt(t testing.TB, format byte, points []lastest.Point) *lassloot.PointCloud {
+	t.Helper()
+
+	file := lastest.Generate(lastest.Spec{Header: lastest.Header{PointDataRecordFormat: format}, Points: points})
+	err, pc := lassloot.NewPointCloudFromReaderAt(bytes.NewReader(file.Bytes), (int64)(len(file.Bytes)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pc
+}
@@ -0,0 +1,244 @@
+// Package e57test generates synthetic ASTM E57 files for use in tests.  Like lastest, it shares no code with the
+// decoder it exercises: the pages, sections, packets and bitpacking are laid out here from the standard.
+package e57test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/nullstyle/lassloot/internal/wire"
+)
+
+// PageSize is the size of the pages files are written in, each ending with a 4 byte checksum.
+const PageSize = 1024
+
+// Field is one value recorded for every point of a scan, such as cartesianX or intensity.
+type Field struct {
+	Name string
+
+	// Type is "Integer", "ScaledInteger" or "Float".
+	Type string
+
+	// Min and Max bound integers, whose stored value is the integer less Min.  Scaled integers have a value of
+	// integer * Scale + Offset.
+	Min, Max      int64
+	Scale, Offset float64
+
+	// Single stores floats as 32 bits rather than 64.
+	Single bool
+
+	// Values holds the value of each point: the integer of Integer and ScaledInteger fields.
+	Values []float64
+}
+
+// Scan is a set of points with its pose and metadata.
+type Scan struct {
+	Name string
+
+	// Pose, if set, is the rotation quaternion w, x, y, z followed by the translation x, y, z.
+	Pose *[7]float64
+
+	IntensityLimits *[2]float64
+	ColorLimits     *[2]float64
+
+	// AcquisitionStart, if set, is the GPS time the scan began.
+	AcquisitionStart *float64
+
+	Fields []Field
+}
+
+// Spec describes a file to generate.
+type Spec struct {
+	Scans              []Scan
+	CoordinateMetadata string
+
+	// Packets is the number of data packets each scan's bytestreams are spread across, at least 1.
+	Packets int
+}
+
+// Generate lays out the file described by spec.  Checksums are left zero.
+func Generate(spec Spec) []byte {
+	packets := spec.Packets
+	if packets < 1 {
+		packets = 1
+	}
+
+	// logical holds the file's contents without checksums; offsets written into it are physical
+	logical := make([]byte, 48)
+	var xmlScans bytes.Buffer
+	for _, scan := range spec.Scans {
+		for len(logical)%4 != 0 {
+			logical = append(logical, 0)
+		}
+		sectionStart := len(logical)
+		logical = append(logical, section(scan, packets, physical(sectionStart+32))...)
+		binary.LittleEndian.PutUint64(logical[sectionStart+8:], (uint64)(len(logical)-sectionStart))
+
+		writeScanXML(&xmlScans, scan, physical(sectionStart))
+	}
+
+	xml := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<e57Root type="Structure" xmlns="http://www.astm.org/COMMIT/E57/2010-e57-v1.0">
+<formatName type="String"><![CDATA[ASTM E57 3D Imaging Data File]]></formatName>
+<guid type="String"><![CDATA[{test-file}]]></guid>
+<versionMajor type="Integer">1</versionMajor>
+<versionMinor type="Integer"/>
+<coordinateMetadata type="String"><![CDATA[%s]]></coordinateMetadata>
+<data3D type="Vector" allowHeterogeneousChildren="1">
+%s</data3D>
+<images2D type="Vector" allowHeterogeneousChildren="1"/>
+</e57Root>
+`, spec.CoordinateMetadata, xmlScans.String())
+	xmlStart := len(logical)
+	logical = append(logical, xml...)
+
+	// paginate, padding the last page
+	payload := PageSize - 4
+	pages := (len(logical) + payload - 1) / payload
+	out := make([]byte, 0, pages*PageSize)
+	for p := 0; p < pages; p++ {
+		end := (p + 1) * payload
+		if end > len(logical) {
+			end = len(logical)
+		}
+		page := make([]byte, PageSize)
+		copy(page, logical[p*payload:end])
+		out = append(out, page...)
+	}
+
+	copy(out[0:8], "ASTM-E57")
+	binary.LittleEndian.PutUint32(out[8:], 1)
+	binary.LittleEndian.PutUint32(out[12:], 0)
+	binary.LittleEndian.PutUint64(out[16:], (uint64)(len(out)))
+	binary.LittleEndian.PutUint64(out[24:], (uint64)(physical(xmlStart)))
+	binary.LittleEndian.PutUint64(out[32:], (uint64)(len(xml)))
+	binary.LittleEndian.PutUint64(out[40:], PageSize)
+	return out
+}
+
+// physical converts a logical offset into a physical one.
+func physical(logical int) int {
+	payload := PageSize - 4
+	return logical/payload*PageSize + logical%payload
+}
+
+// section returns a CompressedVector section holding the scan's points, whose data packets begin at dataOffset.
+// The section length is left for the caller to fill in.
+func section(scan Scan, packets int, dataOffset int) []byte {
+	ret := make([]byte, 32)
+	ret[0] = 1
+	binary.LittleEndian.PutUint64(ret[16:], (uint64)(dataOffset))
+
+	streams := make([][]byte, len(scan.Fields))
+	for i, f := range scan.Fields {
+		streams[i] = encode(f)
+	}
+
+	for p := 0; p < packets; p++ {
+		packet := []byte{1, 0, 0, 0}
+		packet = wire.AppendUint16(packet, (uint16)(len(streams)))
+		var buffers [][]byte
+		for _, s := range streams {
+			lo, hi := len(s)*p/packets, len(s)*(p+1)/packets
+			buffers = append(buffers, s[lo:hi])
+			packet = wire.AppendUint16(packet, (uint16)(hi-lo))
+		}
+		for _, b := range buffers {
+			packet = append(packet, b...)
+		}
+		for len(packet)%4 != 0 {
+			packet = append(packet, 0)
+		}
+		binary.LittleEndian.PutUint16(packet[2:], (uint16)(len(packet)-1))
+		ret = append(ret, packet...)
+	}
+	return ret
+}
+
+// encode returns the bytestream of a field: integers bitpacked least significant bit first, floats little endian.
+func encode(f Field) []byte {
+	var ret []byte
+	if f.Type == "Float" {
+		for _, v := range f.Values {
+			if f.Single {
+				ret = wire.AppendUint32(ret, math.Float32bits((float32)(v)))
+			} else {
+				ret = wire.AppendUint64(ret, math.Float64bits(v))
+			}
+		}
+		return ret
+	}
+
+	width := bits.Len64((uint64)(f.Max - f.Min))
+	var acc uint64
+	n := 0
+	for _, v := range f.Values {
+		raw := (uint64)((int64)(v) - f.Min)
+		for b := 0; b < width; b++ {
+			acc |= ((raw >> b) & 1) << n
+			n++
+			if n == 8 {
+				ret = append(ret, (byte)(acc))
+				acc, n = 0, 0
+			}
+		}
+	}
+	if n > 0 {
+		ret = append(ret, (byte)(acc))
+	}
+	return ret
+}
+
+func writeScanXML(w *bytes.Buffer, scan Scan, offset int) {
+	fmt.Fprintf(w, "<vectorChild type=\"Structure\">\n")
+	fmt.Fprintf(w, "<guid type=\"String\"><![CDATA[{%s}]]></guid>\n", scan.Name)
+	fmt.Fprintf(w, "<name type=\"String\"><![CDATA[%s]]></name>\n", scan.Name)
+	if p := scan.Pose; p != nil {
+		fmt.Fprintf(w, "<pose type=\"Structure\"><rotation type=\"Structure\">"+
+			"<w type=\"Float\">%v</w><x type=\"Float\">%v</x><y type=\"Float\">%v</y><z type=\"Float\">%v</z></rotation>"+
+			"<translation type=\"Structure\"><x type=\"Float\">%v</x><y type=\"Float\">%v</y><z type=\"Float\">%v</z>"+
+			"</translation></pose>\n", p[0], p[1], p[2], p[3], p[4], p[5], p[6])
+	}
+	if l := scan.IntensityLimits; l != nil {
+		fmt.Fprintf(w, "<intensityLimits type=\"Structure\"><intensityMinimum type=\"Float\">%v</intensityMinimum>"+
+			"<intensityMaximum type=\"Float\">%v</intensityMaximum></intensityLimits>\n", l[0], l[1])
+	}
+	if l := scan.ColorLimits; l != nil {
+		fmt.Fprintf(w, "<colorLimits type=\"Structure\">")
+		for _, c := range [...]string{"Red", "Green", "Blue"} {
+			fmt.Fprintf(w, "<color%sMinimum type=\"Integer\">%v</color%sMinimum>", c, l[0], c)
+			fmt.Fprintf(w, "<color%sMaximum type=\"Integer\">%v</color%sMaximum>", c, l[1], c)
+		}
+		fmt.Fprintf(w, "</colorLimits>\n")
+	}
+	if t := scan.AcquisitionStart; t != nil {
+		fmt.Fprintf(w, "<acquisitionStart type=\"Structure\"><dateTimeValue type=\"Float\">%v</dateTimeValue>"+
+			"<isAtomicClockReferenced type=\"Integer\">1</isAtomicClockReferenced></acquisitionStart>\n", *t)
+	}
+
+	count := 0
+	if len(scan.Fields) > 0 {
+		count = len(scan.Fields[0].Values)
+	}
+	fmt.Fprintf(w, "<points type=\"CompressedVector\" fileOffset=\"%d\" recordCount=\"%d\">\n", offset, count)
+	fmt.Fprintf(w, "<prototype type=\"Structure\">\n")
+	for _, f := range scan.Fields {
+		switch f.Type {
+		case "Float":
+			precision := "double"
+			if f.Single {
+				precision = "single"
+			}
+			fmt.Fprintf(w, "<%s type=\"Float\" precision=\"%s\"/>\n", f.Name, precision)
+		case "ScaledInteger":
+			fmt.Fprintf(w, "<%s type=\"ScaledInteger\" minimum=\"%d\" maximum=\"%d\" scale=\"%v\" offset=\"%v\"/>\n",
+				f.Name, f.Min, f.Max, f.Scale, f.Offset)
+		default:
+			fmt.Fprintf(w, "<%s type=\"Integer\" minimum=\"%d\" maximum=\"%d\"/>\n", f.Name, f.Min, f.Max)
+		}
+	}
+	fmt.Fprintf(w, "</prototype>\n<codecs type=\"Vector\" allowHeterogeneousChildren=\"1\"/>\n</points>\n</vectorChild>\n")
+}
@@ -0,0 +1,73 @@
+package lastest
+
+import (
+	"encoding/binary"
+)
+
+// Truncate cuts the file down to n bytes.
+func Truncate(n int) Corruption {
+	return func(b []byte) []byte {
+		if n > len(b) {
+			return b
+		}
+		return b[:n]
+	}
+}
+
+// Overwrite replaces the bytes at offset with data, growing the file if needed.
+func Overwrite(offset int, data []byte) Corruption {
+	return func(b []byte) []byte {
+		if end := offset + len(data); end > len(b) {
+			b = append(b, make([]byte, end-len(b))...)
+		}
+		copy(b[offset:], data)
+		return b
+	}
+}
+
+// OverwriteUint16 replaces the little endian uint16 at offset.
+func OverwriteUint16(offset int, v uint16) Corruption {
+	var data [2]byte
+	binary.LittleEndian.PutUint16(data[:], v)
+	return Overwrite(offset, data[:])
+}
+
+// OverwriteUint32 replaces the little endian uint32 at offset.
+func OverwriteUint32(offset int, v uint32) Corruption {
+	var data [4]byte
+	binary.LittleEndian.PutUint32(data[:], v)
+	return Overwrite(offset, data[:])
+}
+
+// OverwriteUint64 replaces the little endian uint64 at offset.
+func OverwriteUint64(offset int, v uint64) Corruption {
+	var data [8]byte
+	binary.LittleEndian.PutUint64(data[:], v)
+	return Overwrite(offset, data[:])
+}
+
+// Offsets of header fields that corruptions commonly target.
+const (
+	OffsetHeaderSize                    = 94
+	OffsetOffsetToPointData             = 96
+	OffsetNumberOfVariableLengthRecords = 100
+	OffsetPointDataRecordFormat         = 104
+	OffsetPointDataRecordLength         = 105
+	OffsetLegacyNumberOfPointRecords    = 107
+	OffsetStartOfFirstEVLR              = 235
+	OffsetNumberOfEVLRs                 = 243
+	OffsetNumberOfPointRecords          = 247
+)
+
+// BadSignature replaces the "LASF" file signature.
+func BadSignature() Corruption {
+	return Overwrite(0, []byte("LAZF"))
+}
+
+// PointCount overwrites both the legacy and 1.4 point counts without changing the point data.
+func PointCount(n uint64) Corruption {
+	return func(b []byte) []byte {
+		b = OverwriteUint32(OffsetLegacyNumberOfPointRecords, (uint32)(n))(b)
+		return OverwriteUint64(OffsetNumberOfPointRecords, n)(b)
+	}
+}
@@ -0,0 +1,496 @@
+// Package lastest generates synthetic LAS files for use in tests.  It deliberately shares no code with the decoders
+// it exercises: every byte is laid out here from the spec so that a decoding bug cannot hide behind a matching
+// encoding bug.
+package lastest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// Header sizes by LAS minor version.
+const (
+	HeaderSize12 = 227
+	HeaderSize13 = 235
+	HeaderSize14 = 375
+)
+
+// WavePacketSize is the size of the wave packet fields carried by point formats 4, 5, 9 and 10.
+const WavePacketSize = 29
+
+// Header holds the public header block fields written into a synthetic file.  Fields derived from the rest of the
+// Spec (sizes, offsets, counts and bounds) are filled in by Generate unless the Spec asks for them to be overridden.
+type Header struct {
+	FileSourceID                             uint16
+	GlobalEncoding                           uint16
+	ProjectIDData1                           uint32
+	ProjectIDData2                           uint16
+	ProjectIDData3                           uint16
+	ProjectIDData4                           uint64
+	VersionMajor                             byte
+	VersionMinor                             byte
+	SystemID                                 string
+	GeneratingSoftware                       string
+	FileCreationDayOfYear                    uint16
+	FileCreationYear                         uint16
+	HeaderSize                               uint16
+	OffsetToPointData                        uint32
+	NumberOfVariableLengthRecords            uint32
+	PointDataRecordFormat                    byte
+	PointDataRecordLength                    uint16
+	LegacyNumberOfPointRecords               uint32
+	LegacyNumberOfPointsByReturn             [5]uint32
+	XScaleFactor                             float64
+	YScaleFactor                             float64
+	ZScaleFactor                             float64
+	XOffset                                  float64
+	YOffset                                  float64
+	ZOffset                                  float64
+	MaxX                                     float64
+	MinX                                     float64
+	MaxY                                     float64
+	MinY                                     float64
+	MaxZ                                     float64
+	MinZ                                     float64
+	StartOfWaveformDataPacketRecord          uint64
+	StartOfFirstExtendedVariableLengthRecord uint64
+	NumberOfExtendedVariableLengthRecords    uint32
+	NumberOfPointRecords                     uint64
+	NumberOfPointsByReturn                   [15]uint64
+}
+
+// Point holds every field any point format can carry.  Fields a format does not carry are ignored when encoding.
+type Point struct {
+	X, Y, Z         int32
+	Intensity       uint16
+	ReturnNumber    byte
+	NumberOfReturns byte
+	ScanDirection   bool
+	EdgeOfFlight    bool
+	Classification  byte
+	ClassFlags      byte
+	ScannerChannel  byte
+	ScanAngle       int16
+	UserData        byte
+	PointSourceID   uint16
+	GPSTime         float64
+	Red             uint16
+	Green           uint16
+	Blue            uint16
+	NIR             uint16
+	WavePacket      [WavePacketSize]byte
+	Extra           []byte
+}
+
+// VLR is a variable length record, or an extended variable length record when placed in Spec.EVLRs.
+type VLR struct {
+	UserID      string
+	RecordID    uint16
+	Description string
+	Data        []byte
+}
+
+// A Corruption mutates an otherwise well-formed file.
+type Corruption func(b []byte) []byte
+
+// Spec describes a synthetic LAS file.
+type Spec struct {
+	// Header seeds the header.  A zero VersionMajor/VersionMinor yields a LAS 1.4 file, and zero scale factors
+	// default to 0.01.
+	Header Header
+
+	// Points are written verbatim.  When nil, PointCount points are generated by PatternPoint.
+	Points     []Point
+	PointCount int
+
+	// ExtraBytes is the number of extra bytes appended to each point record beyond its format's standard fields.
+	ExtraBytes int
+
+	VLRs  []VLR
+	EVLRs []VLR
+
+	// KeepHeader disables derivation of sizes, offsets, counts and bounds, writing Header exactly as given.
+	KeepHeader bool
+
+	Corruptions []Corruption
+}
+
+// File is the result of generating a synthetic LAS file.
+type File struct {
+	Bytes  []byte
+	Header Header
+	Points []Point
+}
+
+// StandardLength returns the size of the fields defined by the spec for point format f.
+func StandardLength(f byte) int {
+	switch f {
+	case 0:
+		return 20
+	case 1:
+		return 28
+	case 2:
+		return 26
+	case 3:
+		return 34
+	case 4:
+		return 57
+	case 5:
+		return 63
+	case 6:
+		return 30
+	case 7:
+		return 36
+	case 8:
+		return 38
+	case 9:
+		return 59
+	case 10:
+		return 67
+	default:
+		return 0
+	}
+}
+
+// HasGPSTime reports whether point format f carries a GPS time.
+func HasGPSTime(f byte) bool {
+	return f != 0 && f != 2
+}
+
+// HasRGB reports whether point format f carries red, green and blue channels.
+func HasRGB(f byte) bool {
+	switch f {
+	case 2, 3, 5, 7, 8, 10:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasNIR reports whether point format f carries a near infrared channel.
+func HasNIR(f byte) bool {
+	return f == 8 || f == 10
+}
+
+// HasWavePacket reports whether point format f carries wave packet fields.
+func HasWavePacket(f byte) bool {
+	switch f {
+	case 4, 5, 9, 10:
+		return true
+	default:
+		return false
+	}
+}
+
+// PatternPoint returns a deterministic point for index i whose fields all vary with i.
+func PatternPoint(i int) Point {
+	returns := (byte)(i%5) + 1
+	return Point{
+		X:               (int32)(i * 7),
+		Y:               (int32)(i * 11),
+		Z:               (int32)(i * 13),
+		Intensity:       (uint16)(i * 17),
+		ReturnNumber:    (byte)(i%(int)(returns)) + 1,
+		NumberOfReturns: returns,
+		ScanDirection:   i%2 == 0,
+		EdgeOfFlight:    i%3 == 0,
+		Classification:  (byte)(i % 19),
+		ScanAngle:       (int16)(i%61 - 30),
+		UserData:        (byte)(i),
+		PointSourceID:   (uint16)(i * 3),
+		GPSTime:         1e8 + (float64)(i)*0.25,
+		Red:             (uint16)(i * 101),
+		Green:           (uint16)(i * 103),
+		Blue:            (uint16)(i * 107),
+		NIR:             (uint16)(i * 109),
+	}
+}
+
+// Generate lays out the file described by spec.
+func Generate(spec Spec) File {
+	h := spec.Header
+	points := spec.Points
+	if points == nil {
+		points = make([]Point, spec.PointCount)
+		for i := range points {
+			points[i] = PatternPoint(i)
+		}
+	}
+
+	if !spec.KeepHeader {
+		deriveHeader(&h, spec, points)
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, &h)
+
+	for _, vlr := range spec.VLRs {
+		writeVLR(&buf, vlr, false)
+	}
+
+	for _, p := range points {
+		writePoint(&buf, h.PointDataRecordFormat, spec.ExtraBytes, p)
+	}
+
+	for _, evlr := range spec.EVLRs {
+		writeVLR(&buf, evlr, true)
+	}
+
+	b := buf.Bytes()
+	for _, c := range spec.Corruptions {
+		b = c(b)
+	}
+
+	return File{Bytes: b, Header: h, Points: points}
+}
+
+func deriveHeader(h *Header, spec Spec, points []Point) {
+	if h.VersionMajor == 0 && h.VersionMinor == 0 {
+		h.VersionMajor, h.VersionMinor = 1, 4
+	}
+
+	switch {
+	case h.VersionMinor >= 4:
+		h.HeaderSize = HeaderSize14
+	case h.VersionMinor == 3:
+		h.HeaderSize = HeaderSize13
+	default:
+		h.HeaderSize = HeaderSize12
+	}
+
+	if h.XScaleFactor == 0 {
+		h.XScaleFactor = 0.01
+	}
+	if h.YScaleFactor == 0 {
+		h.YScaleFactor = 0.01
+	}
+	if h.ZScaleFactor == 0 {
+		h.ZScaleFactor = 0.01
+	}
+
+	h.NumberOfVariableLengthRecords = (uint32)(len(spec.VLRs))
+	offset := (int)(h.HeaderSize)
+	for _, vlr := range spec.VLRs {
+		offset += 54 + len(vlr.Data)
+	}
+	h.OffsetToPointData = (uint32)(offset)
+	h.PointDataRecordLength = (uint16)(StandardLength(h.PointDataRecordFormat) + spec.ExtraBytes)
+
+	n := (uint64)(len(points))
+	h.NumberOfPointRecords = n
+	h.NumberOfPointsByReturn = [15]uint64{}
+	h.LegacyNumberOfPointRecords = 0
+	h.LegacyNumberOfPointsByReturn = [5]uint32{}
+	for _, p := range points {
+		if p.ReturnNumber >= 1 && p.ReturnNumber <= 15 {
+			h.NumberOfPointsByReturn[p.ReturnNumber-1]++
+		}
+	}
+	if h.PointDataRecordFormat < 6 && n <= math.MaxUint32 {
+		h.LegacyNumberOfPointRecords = (uint32)(n)
+		for i := 0; i < 5; i++ {
+			h.LegacyNumberOfPointsByReturn[i] = (uint32)(h.NumberOfPointsByReturn[i])
+		}
+	}
+
+	h.MinX, h.MinY, h.MinZ, h.MaxX, h.MaxY, h.MaxZ = 0, 0, 0, 0, 0, 0
+	for i, p := range points {
+		x := (float64)(p.X)*h.XScaleFactor + h.XOffset
+		y := (float64)(p.Y)*h.YScaleFactor + h.YOffset
+		z := (float64)(p.Z)*h.ZScaleFactor + h.ZOffset
+		if i == 0 {
+			h.MinX, h.MinY, h.MinZ, h.MaxX, h.MaxY, h.MaxZ = x, y, z, x, y, z
+			continue
+		}
+		h.MinX, h.MaxX = math.Min(h.MinX, x), math.Max(h.MaxX, x)
+		h.MinY, h.MaxY = math.Min(h.MinY, y), math.Max(h.MaxY, y)
+		h.MinZ, h.MaxZ = math.Min(h.MinZ, z), math.Max(h.MaxZ, z)
+	}
+
+	h.NumberOfExtendedVariableLengthRecords = (uint32)(len(spec.EVLRs))
+	h.StartOfFirstExtendedVariableLengthRecord = 0
+	if len(spec.EVLRs) > 0 {
+		h.StartOfFirstExtendedVariableLengthRecord = (uint64)(offset) + n*(uint64)(h.PointDataRecordLength)
+	}
+}
+
+func writeHeader(buf *bytes.Buffer, h *Header) {
+	le := binary.LittleEndian
+	var scratch [8]byte
+	u16 := func(v uint16) { le.PutUint16(scratch[:2], v); buf.Write(scratch[:2]) }
+	u32 := func(v uint32) { le.PutUint32(scratch[:4], v); buf.Write(scratch[:4]) }
+	u64 := func(v uint64) { le.PutUint64(scratch[:8], v); buf.Write(scratch[:8]) }
+	f64 := func(v float64) { u64(math.Float64bits(v)) }
+
+	buf.WriteString("LASF")
+	u16(h.FileSourceID)
+	u16(h.GlobalEncoding)
+	u32(h.ProjectIDData1)
+	u16(h.ProjectIDData2)
+	u16(h.ProjectIDData3)
+	u64(h.ProjectIDData4)
+	buf.WriteByte(h.VersionMajor)
+	buf.WriteByte(h.VersionMinor)
+	buf.Write(fixed(h.SystemID, 32))
+	buf.Write(fixed(h.GeneratingSoftware, 32))
+	u16(h.FileCreationDayOfYear)
+	u16(h.FileCreationYear)
+	u16(h.HeaderSize)
+	u32(h.OffsetToPointData)
+	u32(h.NumberOfVariableLengthRecords)
+	buf.WriteByte(h.PointDataRecordFormat)
+	u16(h.PointDataRecordLength)
+	u32(h.LegacyNumberOfPointRecords)
+	for _, v := range h.LegacyNumberOfPointsByReturn {
+		u32(v)
+	}
+	f64(h.XScaleFactor)
+	f64(h.YScaleFactor)
+	f64(h.ZScaleFactor)
+	f64(h.XOffset)
+	f64(h.YOffset)
+	f64(h.ZOffset)
+	f64(h.MaxX)
+	f64(h.MinX)
+	f64(h.MaxY)
+	f64(h.MinY)
+	f64(h.MaxZ)
+	f64(h.MinZ)
+
+	if h.VersionMajor == 1 && h.VersionMinor < 3 {
+		return
+	}
+	u64(h.StartOfWaveformDataPacketRecord)
+
+	if h.VersionMajor == 1 && h.VersionMinor < 4 {
+		return
+	}
+	u64(h.StartOfFirstExtendedVariableLengthRecord)
+	u32(h.NumberOfExtendedVariableLengthRecords)
+	u64(h.NumberOfPointRecords)
+	for _, v := range h.NumberOfPointsByReturn {
+		u64(v)
+	}
+}
+
+func writeVLR(buf *bytes.Buffer, vlr VLR, extended bool) {
+	le := binary.LittleEndian
+	var scratch [8]byte
+
+	buf.Write(scratch[:2])
+	buf.Write(fixed(vlr.UserID, 16))
+	le.PutUint16(scratch[:2], vlr.RecordID)
+	buf.Write(scratch[:2])
+	if extended {
+		le.PutUint64(scratch[:8], (uint64)(len(vlr.Data)))
+		buf.Write(scratch[:8])
+	} else {
+		le.PutUint16(scratch[:2], (uint16)(len(vlr.Data)))
+		buf.Write(scratch[:2])
+	}
+	buf.Write(fixed(vlr.Description, 32))
+	buf.Write(vlr.Data)
+}
+
+func writePoint(buf *bytes.Buffer, format byte, extraBytes int, p Point) {
+	le := binary.LittleEndian
+	rec := make([]byte, StandardLength(format)+extraBytes)
+	if StandardLength(format) == 0 {
+		buf.Write(rec)
+		return
+	}
+
+	le.PutUint32(rec[0:4], (uint32)(p.X))
+	le.PutUint32(rec[4:8], (uint32)(p.Y))
+	le.PutUint32(rec[8:12], (uint32)(p.Z))
+	le.PutUint16(rec[12:14], p.Intensity)
+
+	var off int
+	if format < 6 {
+		rec[14] = (p.ReturnNumber & 0x07) | (p.NumberOfReturns&0x07)<<3 | bit(p.ScanDirection)<<6 | bit(p.EdgeOfFlight)<<7
+		rec[15] = (p.Classification & 0x1f) | (p.ClassFlags&0x07)<<5
+		rec[16] = (byte)((int8)(p.ScanAngle))
+		rec[17] = p.UserData
+		le.PutUint16(rec[18:20], p.PointSourceID)
+		off = 20
+		if HasGPSTime(format) {
+			le.PutUint64(rec[20:28], math.Float64bits(p.GPSTime))
+			off = 28
+		}
+	} else {
+		rec[14] = (p.ReturnNumber & 0x0f) | (p.NumberOfReturns&0x0f)<<4
+		rec[15] = (p.ClassFlags & 0x0f) | (p.ScannerChannel&0x03)<<4 | bit(p.ScanDirection)<<6 | bit(p.EdgeOfFlight)<<7
+		rec[16] = p.Classification
+		rec[17] = p.UserData
+		le.PutUint16(rec[18:20], (uint16)(p.ScanAngle))
+		le.PutUint16(rec[20:22], p.PointSourceID)
+		le.PutUint64(rec[22:30], math.Float64bits(p.GPSTime))
+		off = 30
+	}
+
+	if HasRGB(format) {
+		le.PutUint16(rec[off:off+2], p.Red)
+		le.PutUint16(rec[off+2:off+4], p.Green)
+		le.PutUint16(rec[off+4:off+6], p.Blue)
+		off += 6
+	}
+
+	if HasNIR(format) {
+		le.PutUint16(rec[off:off+2], p.NIR)
+		off += 2
+	}
+
+	if HasWavePacket(format) {
+		copy(rec[off:off+WavePacketSize], p.WavePacket[:])
+		off += WavePacketSize
+	}
+
+	copy(rec[off:], p.Extra)
+	buf.Write(rec)
+}
+
+func fixed(s string, n int) []byte {
+	b := make([]byte, n)
+	copy(b, s)
+	return b
+}
+
+func bit(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ExtraBytes describes a single extra bytes descriptor.
+type ExtraBytes struct {
+	Name        string
+	Description string
+	DataType    byte
+	Options     byte
+	NoData      [3]uint64
+	Scale       [3]float64
+	Offset      [3]float64
+}
+
+// ExtraBytesVLR lays out an extra bytes VLR holding the provided descriptors.
+func ExtraBytesVLR(descriptors ...ExtraBytes) VLR {
+	le := binary.LittleEndian
+	var data []byte
+	for _, eb := range descriptors {
+		b := make([]byte, 192)
+		b[2] = eb.DataType
+		b[3] = eb.Options
+		copy(b[4:36], eb.Name)
+		for j := 0; j < 3; j++ {
+			le.PutUint64(b[40+j*8:], eb.NoData[j])
+			le.PutUint64(b[112+j*8:], math.Float64bits(eb.Scale[j]))
+			le.PutUint64(b[136+j*8:], math.Float64bits(eb.Offset[j]))
+		}
+		copy(b[160:192], eb.Description)
+		data = append(data, b...)
+	}
+
+	return VLR{UserID: "LASF_Spec", RecordID: 4, Description: "extra bytes", Data: data}
+}
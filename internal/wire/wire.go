@@ -0,0 +1,34 @@
+// Package wire holds the helpers the encoding packages share for laying values out as little-endian binary and as
+// decimal text.
+package wire
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// AppendUint16 appends v to b in little-endian order.
+func AppendUint16(b []byte, v uint16) []byte {
+	var tmp [2]byte
+	binary.LittleEndian.PutUint16(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+// AppendUint32 appends v to b in little-endian order.
+func AppendUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.LittleEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+// AppendUint64 appends v to b in little-endian order.
+func AppendUint64(b []byte, v uint64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+// FormatFloat formats v in the fewest decimal digits that read back exactly, never in exponent form.
+func FormatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,231 @@
+// Package interpolate estimates surfaces between scattered points, for gridding elevation models where binning points
+// into cells leaves holes where there are none, such as in ground under trees, and stair steps where there are few.
+//
+// Each interpolator implements grid.Interpolator, so filling a grid with one grids the surface it estimates.  IDW
+// weights the points near a position by the inverse of their distance, raised to a power.  Linear takes the plane of
+// the triangle of a Delaunay triangulation of the points beneath a position, and NaturalNeighbor weights the points
+// around it by Sibson's natural neighbor coordinates, a smooth surface through every point.  Nearest takes the nearest
+// point, a surface of flat Voronoi cells.
+//
+// The triangulated interpolators only estimate within the points' convex hull; IDW and Nearest reach as far as their
+// radius allows.
+package interpolate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nullstyle/lassloot/grid"
+	"github.com/nullstyle/lassloot/kdtree"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+// Method chooses an interpolator.
+type Method int
+
+const (
+	IDW Method = iota
+	Linear
+	NaturalNeighbor
+	Nearest
+)
+
+var methodNames = [...]string{"idw", "linear", "natural", "nearest"}
+
+func (m Method) String() string {
+	if m < 0 || (int)(m) >= len(methodNames) {
+		return fmt.Sprintf("Method(%d)", m)
+	}
+	return methodNames[m]
+}
+
+// ParseMethod returns the method named s, as String returns.  "tin" names Linear too.
+func ParseMethod(s string) (error, Method) {
+	if strings.EqualFold(s, "tin") {
+		return nil, Linear
+	}
+	for i, name := range methodNames {
+		if strings.EqualFold(s, name) {
+			return nil, (Method)(i)
+		}
+	}
+	return fmt.Errorf("unknown interpolation %q: want %s", s, strings.Join(methodNames[:], ", ")), IDW
+}
+
+// Options control IDW and Nearest.
+type Options struct {
+	// Power is the power of the distance that IDW weights by.  Zero takes 2; higher powers favor nearer points.
+	Power float64
+
+	// Radius, if set, ignores points farther than it from a position.
+	Radius float64
+
+	// MinPoints is the fewest points IDW estimates from: positions with fewer within Radius have no value.
+	MinPoints int
+
+	// MaxPoints, if set, limits IDW to the nearest points.  Without it or Radius, IDW takes the nearest 12.
+	MaxPoints int
+}
+
+// New returns an interpolator of the method over points.  The triangulated methods triangulate them, taking the mean
+// elevation where points share a position.
+func New(method Method, points [][3]float64, opts Options) (error, grid.Interpolator) {
+	switch method {
+	case IDW:
+		err, in := NewIDW(points, opts)
+		if err != nil {
+			return err, nil
+		}
+		return nil, in
+	case Nearest:
+		err, in := NewNearest(points, opts.Radius)
+		if err != nil {
+			return err, nil
+		}
+		return nil, in
+	case Linear, NaturalNeighbor:
+		err, m := tin.Triangulate(points, tin.Mean)
+		if err != nil {
+			return err, nil
+		}
+		if method == Linear {
+			return nil, NewLinear(m)
+		}
+		return nil, NewNaturalNeighbor(m)
+	}
+	return fmt.Errorf("unknown interpolation %v", method), nil
+}
+
+// IDWInterpolator interpolates by inverse distance weighting.
+type IDWInterpolator struct {
+	tree *kdtree.Tree
+	opts Options
+}
+
+// NewIDW returns an inverse distance weighting interpolator over points, which it refers to.
+func NewIDW(points [][3]float64, opts Options) (error, *IDWInterpolator) {
+	if opts.Power == 0 {
+		opts.Power = 2
+	}
+	switch {
+	case !(opts.Power > 0) || math.IsInf(opts.Power, 0):
+		return fmt.Errorf("invalid power %v", opts.Power), nil
+	case !(opts.Radius >= 0):
+		return fmt.Errorf("invalid radius %v", opts.Radius), nil
+	case opts.MinPoints < 0 || opts.MaxPoints < 0:
+		return fmt.Errorf("invalid point counts %d and %d", opts.MinPoints, opts.MaxPoints), nil
+	case opts.MaxPoints > 0 && opts.MinPoints > opts.MaxPoints:
+		return fmt.Errorf("min points %d exceeds max points %d", opts.MinPoints, opts.MaxPoints), nil
+	}
+	if opts.MinPoints == 0 {
+		opts.MinPoints = 1
+	}
+	if opts.MaxPoints == 0 && opts.Radius == 0 {
+		opts.MaxPoints = 12
+	}
+	return nil, &IDWInterpolator{tree: kdtree.New(points, 2), opts: opts}
+}
+
+// At returns the weighted mean elevation of the points near x, y, or of those exactly there if any are.
+func (in *IDWInterpolator) At(x, y float64) (float64, bool) {
+	q := [3]float64{x, y}
+	var neighbors []kdtree.Neighbor
+	if in.opts.MaxPoints > 0 {
+		neighbors = in.tree.Nearest(q, in.opts.MaxPoints)
+		if r := in.opts.Radius; r > 0 {
+			for len(neighbors) > 0 && neighbors[len(neighbors)-1].Distance2 > r*r {
+				neighbors = neighbors[:len(neighbors)-1]
+			}
+		}
+	} else {
+		in.tree.Within(q, in.opts.Radius, func(n kdtree.Neighbor) { neighbors = append(neighbors, n) })
+	}
+	if len(neighbors) < in.opts.MinPoints {
+		return 0, false
+	}
+
+	var sum, weights, exact float64
+	var exacts int
+	for _, n := range neighbors {
+		z := in.tree.Point(n.Index)[2]
+		if n.Distance2 == 0 {
+			exact += z
+			exacts++
+			continue
+		}
+		w := math.Pow(n.Distance2, -in.opts.Power/2)
+		sum += w * z
+		weights += w
+	}
+	if exacts > 0 {
+		return exact / (float64)(exacts), true
+	}
+	return sum / weights, true
+}
+
+// NearestInterpolator takes the elevation of the nearest point.
+type NearestInterpolator struct {
+	tree   *kdtree.Tree
+	radius float64
+}
+
+// NewNearest returns a nearest neighbor interpolator over points, which it refers to, ignoring points farther than
+// radius if it is set.
+func NewNearest(points [][3]float64, radius float64) (error, *NearestInterpolator) {
+	if !(radius >= 0) {
+		return fmt.Errorf("invalid radius %v", radius), nil
+	}
+	return nil, &NearestInterpolator{tree: kdtree.New(points, 2), radius: radius}
+}
+
+// At returns the elevation of the point nearest x, y.
+func (in *NearestInterpolator) At(x, y float64) (float64, bool) {
+	n := in.tree.Nearest([3]float64{x, y}, 1)
+	if len(n) == 0 || in.radius > 0 && n[0].Distance2 > in.radius*in.radius {
+		return 0, false
+	}
+	return in.tree.Point(n[0].Index)[2], true
+}
+
+// LinearInterpolator takes the plane of the triangle of a TIN beneath a position.  It remembers the last triangle it
+// found to start its next search from, so it isn't safe for concurrent use but is quick over positions in order.
+type LinearInterpolator struct {
+	m    *tin.Mesh
+	hint int
+}
+
+// NewLinear returns a linear interpolator over m.
+func NewLinear(m *tin.Mesh) *LinearInterpolator {
+	return &LinearInterpolator{m: m, hint: -1}
+}
+
+// At returns the elevation of the surface at x, y, or false outside it.
+func (in *LinearInterpolator) At(x, y float64) (float64, bool) {
+	z, tri, ok := in.m.Interpolate(x, y, in.hint)
+	if ok {
+		in.hint = tri
+	}
+	return z, ok
+}
+
+// NaturalNeighborInterpolator interpolates across a TIN by Sibson's natural neighbor interpolation.  Like
+// LinearInterpolator it isn't safe for concurrent use.
+type NaturalNeighborInterpolator struct {
+	m    *tin.Mesh
+	hint int
+}
+
+// NewNaturalNeighbor returns a natural neighbor interpolator over m, which must be a Delaunay triangulation.
+func NewNaturalNeighbor(m *tin.Mesh) *NaturalNeighborInterpolator {
+	return &NaturalNeighborInterpolator{m: m, hint: -1}
+}
+
+// At returns the elevation of the surface at x, y, or false outside it.
+func (in *NaturalNeighborInterpolator) At(x, y float64) (float64, bool) {
+	z, tri, ok := in.m.NaturalNeighbor(x, y, in.hint)
+	if ok {
+		in.hint = tri
+	}
+	return z, ok
+}
@@ -0,0 +1,118 @@
+package interpolate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/grid"
+)
+
+func TestMethods(t *testing.T) {
+	// a sloping plane sampled on a 1 meter lattice, with a clearing of no points in the middle
+	var points [][3]float64
+	for x := 0; x <= 10; x++ {
+		for y := 0; y <= 10; y++ {
+			if x >= 4 && x <= 6 && y >= 4 && y <= 6 {
+				continue
+			}
+			points = append(points, [3]float64{(float64)(x), (float64)(y), (float64)(2*x + y)})
+		}
+	}
+
+	tests := []struct {
+		method    Method
+		opts      Options
+		tolerance float64
+	}{
+		// planes reproduce planes exactly; idw and nearest only roughly
+		{Linear, Options{}, 1e-9},
+		{NaturalNeighbor, Options{}, 1e-9},
+		{IDW, Options{}, 1.5},
+		{IDW, Options{Power: 1, Radius: 3, MinPoints: 3}, 1.5},
+		{Nearest, Options{}, 4},
+	}
+	for _, test := range tests {
+		err, in := New(test.method, points, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, q := range [][2]float64{{5, 5}, {4.5, 5.2}, {1.3, 8.7}, {7, 2}} {
+			z, ok := in.At(q[0], q[1])
+			if want := 2*q[0] + q[1]; !ok || math.Abs(z-want) > test.tolerance {
+				t.Errorf("%v %+v: %v at %v, want %v", test.method, test.opts, z, q, want)
+			}
+		}
+		// at a point, every method takes its elevation
+		if z, ok := in.At(2, 3); !ok || math.Abs(z-7) > 1e-9 {
+			t.Errorf("%v: %v at a point of 7", test.method, z)
+		}
+	}
+
+	// beyond the points, only the methods without a radius reach
+	for _, test := range []struct {
+		method Method
+		opts   Options
+		ok     bool
+	}{
+		{Linear, Options{}, false},
+		{NaturalNeighbor, Options{}, false},
+		{IDW, Options{}, true},
+		{IDW, Options{Radius: 2}, false},
+		{Nearest, Options{}, true},
+		{Nearest, Options{Radius: 2}, false},
+	} {
+		_, in := New(test.method, points, test.opts)
+		if _, ok := in.At(15, 5); ok != test.ok {
+			t.Errorf("%v %+v: value 5 beyond the points is %v", test.method, test.opts, ok)
+		}
+	}
+
+	// the clearing's middle is farther than 1.5 from any point
+	_, in := New(IDW, points, Options{Radius: 1.5, MinPoints: 1})
+	if _, ok := in.At(5, 5); ok {
+		t.Error("idw reached across the clearing")
+	}
+
+	for _, opts := range []Options{{Power: -1}, {Radius: -1}, {MinPoints: 5, MaxPoints: 2}} {
+		if err, _ := New(IDW, points, opts); err == nil {
+			t.Errorf("idw with %+v", opts)
+		}
+	}
+	if err, _ := New(Linear, points[:2], Options{}); err == nil {
+		t.Error("triangulated 2 points")
+	}
+	if err, m := ParseMethod("TIN"); err != nil || m != Linear {
+		t.Errorf("parsed tin as %v, %v", m, err)
+	}
+	if err, _ := ParseMethod("kriging"); err == nil {
+		t.Error("parsed kriging")
+	}
+}
+
+func TestFill(t *testing.T) {
+	// the binned grid has a hole in its middle that filling closes, leaving its binned cells as they were
+	err, g := grid.New(0, 3, 1, 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var points [][3]float64
+	for i := range g.Values {
+		if i == 4 {
+			continue
+		}
+		col, row := i%3, i/3
+		x, y := g.Center(col, row)
+		g.Values[i] = 100
+		points = append(points, [3]float64{x, y, x + y})
+	}
+	err, in := New(Linear, points, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := g.Fill(in); n != 1 {
+		t.Errorf("filled %d cells, want 1", n)
+	}
+	if g.Values[4] != 3 || g.Values[0] != 100 {
+		t.Errorf("filled %v", g.Values)
+	}
+}
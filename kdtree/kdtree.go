@@ -0,0 +1,230 @@
+// Package kdtree finds the points nearest a location among a fixed set of points, in two or three dimensions.  The
+// tree is balanced and stored implicitly as a permutation of the point indexes, so it needs only four bytes per point
+// beyond the points themselves.
+package kdtree
+
+import (
+	"math"
+	"sort"
+)
+
+// Tree is a static k-d tree over a set of points.  It is safe for concurrent queries.
+type Tree struct {
+	points [][3]float64
+	dims   int
+
+	// idx holds the point indexes such that the median of each range is the node splitting it, along the axis
+	// given by the range's depth
+	idx []int32
+}
+
+// New builds a tree over points, considering only their first dims coordinates: 2 for horizontal neighbours, 3 for
+// neighbours in space.  The tree refers to points, which must not be modified while it is in use.
+func New(points [][3]float64, dims int) *Tree {
+	if dims < 1 || dims > 3 {
+		panic("kdtree: dims must be 1, 2 or 3")
+	}
+
+	t := &Tree{points: points, dims: dims, idx: make([]int32, len(points))}
+	for i := range t.idx {
+		t.idx[i] = (int32)(i)
+	}
+	t.build(0, len(t.idx), 0)
+	return t
+}
+
+// Len returns the number of points in the tree.
+func (t *Tree) Len() int {
+	return len(t.idx)
+}
+
+// Point returns the coordinates of point i.
+func (t *Tree) Point(i int) [3]float64 {
+	return t.points[i]
+}
+
+func (t *Tree) build(lo, hi, depth int) {
+	for hi-lo > 1 {
+		axis := depth % t.dims
+		mid := (lo + hi) / 2
+		t.selectNth(lo, hi, mid, axis)
+
+		t.build(lo, mid, depth+1)
+		lo, depth = mid+1, depth+1
+	}
+}
+
+// selectNth reorders idx[lo:hi] so that idx[n] holds the point that would be there were the range sorted along axis,
+// with no point before it greater and none after it less.
+func (t *Tree) selectNth(lo, hi, n, axis int) {
+	key := func(i int) float64 { return t.points[t.idx[i]][axis] }
+	swap := func(i, j int) { t.idx[i], t.idx[j] = t.idx[j], t.idx[i] }
+
+	for hi-lo > 1 {
+		// median of three pivot
+		a, b, c := key(lo), key((lo+hi)/2), key(hi-1)
+		pivot := math.Max(math.Min(a, b), math.Min(math.Max(a, b), c))
+
+		// three way partition into [lo, lt) less than, [lt, gt) equal to and [gt, hi) greater than the pivot, so
+		// that runs of equal coordinates, common in gridded and quantized data, don't degrade to quadratic time
+		lt, i, gt := lo, lo, hi
+		for i < gt {
+			switch k := key(i); {
+			case k < pivot:
+				swap(i, lt)
+				lt++
+				i++
+			case k > pivot:
+				gt--
+				swap(i, gt)
+			default:
+				i++
+			}
+		}
+
+		switch {
+		case n < lt:
+			hi = lt
+		case n >= gt:
+			lo = gt
+		default:
+			return
+		}
+	}
+}
+
+func (t *Tree) distance2(q [3]float64, i int32) float64 {
+	p := t.points[i]
+	d := 0.0
+	for a := 0; a < t.dims; a++ {
+		v := p[a] - q[a]
+		d += v * v
+	}
+	return d
+}
+
+// Neighbor is a point found by a query, with its squared distance from the query location.
+type Neighbor struct {
+	Index     int
+	Distance2 float64
+}
+
+// Nearest returns the k points nearest q, closest first.  Fewer are returned when the tree holds fewer than k points.
+func (t *Tree) Nearest(q [3]float64, k int) []Neighbor {
+	if k <= 0 || len(t.idx) == 0 {
+		return nil
+	}
+
+	h := &neighborHeap{k: k}
+	t.nearest(q, 0, len(t.idx), 0, h)
+
+	ret := h.items
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Distance2 < ret[j].Distance2 })
+	return ret
+}
+
+func (t *Tree) nearest(q [3]float64, lo, hi, depth int, h *neighborHeap) {
+	if lo >= hi {
+		return
+	}
+
+	mid := (lo + hi) / 2
+	i := t.idx[mid]
+	h.offer(Neighbor{Index: (int)(i), Distance2: t.distance2(q, i)})
+
+	axis := depth % t.dims
+	diff := q[axis] - t.points[i][axis]
+	if diff < 0 {
+		t.nearest(q, lo, mid, depth+1, h)
+		if diff*diff < h.bound() {
+			t.nearest(q, mid+1, hi, depth+1, h)
+		}
+	} else {
+		t.nearest(q, mid+1, hi, depth+1, h)
+		if diff*diff < h.bound() {
+			t.nearest(q, lo, mid, depth+1, h)
+		}
+	}
+}
+
+// Within calls fn with every point no farther than r from q, in no particular order.
+func (t *Tree) Within(q [3]float64, r float64, fn func(n Neighbor)) {
+	t.within(q, r*r, 0, len(t.idx), 0, fn)
+}
+
+func (t *Tree) within(q [3]float64, r2 float64, lo, hi, depth int, fn func(n Neighbor)) {
+	for lo < hi {
+		mid := (lo + hi) / 2
+		i := t.idx[mid]
+		if d := t.distance2(q, i); d <= r2 {
+			fn(Neighbor{Index: (int)(i), Distance2: d})
+		}
+
+		axis := depth % t.dims
+		diff := q[axis] - t.points[i][axis]
+		depth++
+		if diff < 0 {
+			if diff*diff <= r2 {
+				t.within(q, r2, mid+1, hi, depth, fn)
+			}
+			hi = mid
+		} else {
+			if diff*diff <= r2 {
+				t.within(q, r2, lo, mid, depth, fn)
+			}
+			lo = mid + 1
+		}
+	}
+}
+
+// neighborHeap keeps the k nearest neighbours seen so far as a max heap on distance.
+type neighborHeap struct {
+	k     int
+	items []Neighbor
+}
+
+// bound returns the squared distance a point must beat to be among the k nearest.
+func (h *neighborHeap) bound() float64 {
+	if len(h.items) < h.k {
+		return math.Inf(1)
+	}
+	return h.items[0].Distance2
+}
+
+func (h *neighborHeap) offer(n Neighbor) {
+	if len(h.items) < h.k {
+		h.items = append(h.items, n)
+		// sift up
+		i := len(h.items) - 1
+		for i > 0 {
+			parent := (i - 1) / 2
+			if h.items[parent].Distance2 >= h.items[i].Distance2 {
+				break
+			}
+			h.items[parent], h.items[i] = h.items[i], h.items[parent]
+			i = parent
+		}
+		return
+	}
+
+	if n.Distance2 >= h.items[0].Distance2 {
+		return
+	}
+
+	// replace the farthest and sift down
+	h.items[0] = n
+	i := 0
+	for {
+		largest := i
+		for _, c := range [...]int{2*i + 1, 2*i + 2} {
+			if c < len(h.items) && h.items[c].Distance2 > h.items[largest].Distance2 {
+				largest = c
+			}
+		}
+		if largest == i {
+			return
+		}
+		h.items[i], h.items[largest] = h.items[largest], h.items[i]
+		i = largest
+	}
+}
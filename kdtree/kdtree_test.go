@@ -0,0 +1,71 @@
+package kdtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomPoints(n int) [][3]float64 {
+	r := rand.New(rand.NewSource(1))
+	points := make([][3]float64, n)
+	for i := range points {
+		// coarse coordinates produce plenty of duplicates along every axis
+		points[i] = [3]float64{(float64)(r.Intn(50)), (float64)(r.Intn(50)), (float64)(r.Intn(5))}
+	}
+	return points
+}
+
+func bruteDistances(points [][3]float64, q [3]float64, dims int) []float64 {
+	d := make([]float64, len(points))
+	for i, p := range points {
+		for a := 0; a < dims; a++ {
+			d[i] += (p[a] - q[a]) * (p[a] - q[a])
+		}
+	}
+	sort.Float64s(d)
+	return d
+}
+
+func TestNearestMatchesBruteForce(t *testing.T) {
+	points := randomPoints(2000)
+	for _, dims := range []int{2, 3} {
+		tree := New(points, dims)
+		for _, q := range [][3]float64{{0, 0, 0}, {25.5, 10.2, 2}, {-10, 60, 9}, points[17]} {
+			want := bruteDistances(points, q, dims)
+
+			got := tree.Nearest(q, 12)
+			if len(got) != 12 {
+				t.Fatalf("dims %d: %d neighbours, want 12", dims, len(got))
+			}
+			for i, n := range got {
+				if n.Distance2 != want[i] {
+					t.Errorf("dims %d q %v: neighbour %d at %v, want %v", dims, q, i, n.Distance2, want[i])
+				}
+			}
+
+			count := 0
+			tree.Within(q, 4, func(n Neighbor) {
+				count++
+				if n.Distance2 > 16 {
+					t.Errorf("dims %d: point at %v returned within 4", dims, n.Distance2)
+				}
+			})
+			wantCount := sort.SearchFloat64s(want, 16.0000001)
+			if count != wantCount {
+				t.Errorf("dims %d q %v: %d points within 4, want %d", dims, q, count, wantCount)
+			}
+		}
+	}
+}
+
+func TestSmallTrees(t *testing.T) {
+	if got := New(nil, 2).Nearest([3]float64{}, 3); len(got) != 0 {
+		t.Errorf("empty tree returned %v", got)
+	}
+
+	tree := New([][3]float64{{1, 1, 0}, {1, 1, 0}}, 2)
+	if got := tree.Nearest([3]float64{}, 5); len(got) != 2 {
+		t.Errorf("two point tree returned %d neighbours", len(got))
+	}
+}
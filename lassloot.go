@@ -1,17 +1,34 @@
 package lassloot
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/nullstyle/lassloot/encoding/las14"
+	"io"
 	"log"
 	"os"
+	"strings"
+	"time"
 )
 
 // PointCloud is the primary API for interacting with LAS files provided by this library.
 // It represents a high level interface that wraps a lower level parse result,
 // providing caching and higher level algorithms.
 type PointCloud struct {
-	fr *las14.FullResult
+	fr     *las14.FullResult
+	schema *las14.Schema
+
+	gpsWeek    int
+	gpsWeekSet bool
+}
+
+func newPointCloud(fr *las14.FullResult) (error, *PointCloud) {
+	err, schema := las14.NewSchema(&fr.Header, fr.VariableLengthRecords)
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, &PointCloud{fr: fr, schema: schema}
 }
 
 func NewPointCloudFromPath(path string) (error, *PointCloud) {
@@ -22,24 +39,51 @@ func NewPointCloudFromPath(path string) (error, *PointCloud) {
 	defer func() {
 		err := f.Close()
 		if err != nil {
-			log.Printf("error occurred closing las file: %w", err)
+			log.Printf("error occurred closing las file: %v", err)
 		}
 	}()
 
 	d := las14.NewDecoder(f)
 	err, fr := d.FullDecode("")
+	if err != nil {
+		return err, nil
+	}
+
+	return newPointCloud(fr)
+}
+
+// NewPointCloudFromReaderAt decodes a PointCloud from the size bytes of r, such as an in-memory blob or a file within
+// an archive.
+func NewPointCloudFromReaderAt(r io.ReaderAt, size int64) (error, *PointCloud) {
+	d := las14.NewReaderAtDecoder(r, size)
+	err, fr := d.FullDecode("")
+	if err != nil {
+		return err, nil
+	}
+
+	return newPointCloud(fr)
+}
+
+// NewPointCloudFromReader decodes a PointCloud from everything r produces, such as a file piped to standard input.
+// The whole stream is read into memory, since decoding requires seeking.
+func NewPointCloudFromReader(r io.Reader) (error, *PointCloud) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err, nil
+	}
 
-	return nil, &PointCloud{fr}
+	return NewPointCloudFromReaderAt(bytes.NewReader(data), (int64)(len(data)))
 }
 
 func (pc *PointCloud) Header() *Header {
 	return &Header{
 		RawHeader: pc.fr.Header,
+		pc:        pc,
 	}
 }
 
 func (pc *PointCloud) Len() uint64 {
-	return (uint64)(pc.fr.Header.LegacyNumberOfPointRecords)
+	return pc.fr.Header.PointCount()
 }
 
 func (pc *PointCloud) PointSize() int {
@@ -48,7 +92,7 @@ func (pc *PointCloud) PointSize() int {
 
 func (pc *PointCloud) PointAt(idx uint64) (error, *Point) {
 
-	if idx > pc.Len() {
+	if idx >= pc.Len() {
 		return fmt.Errorf("index %d too high", idx), nil
 	}
 
@@ -83,6 +127,34 @@ type Header struct {
 	pc        *PointCloud
 }
 
+// String summarizes the header, followed by the time span of the pointcloud's points when they carry GPS times.
+func (h *Header) String() string {
+	var ret strings.Builder
+	ret.WriteString(h.RawHeader.String())
+
+	if h.pc == nil {
+		return ret.String()
+	}
+
+	err, start, end := h.pc.TimeRange()
+	if err != nil {
+		return ret.String()
+	}
+
+	timeType := "gps week time"
+	if h.pc.IsAdjustedStandardGPSTime() {
+		timeType = "adjusted standard gps time"
+	}
+
+	ret.WriteString("\n")
+	fmt.Fprintf(&ret, "\t%-40s = %s\n", "GPSTimeType", timeType)
+	fmt.Fprintf(&ret, "\t%-40s = %s\n", "FirstPointTime", start.Format(time.RFC3339Nano))
+	fmt.Fprintf(&ret, "\t%-40s = %s\n", "LastPointTime", end.Format(time.RFC3339Nano))
+	fmt.Fprintf(&ret, "\t%-40s = %s\n", "TimeSpan", end.Sub(start))
+
+	return ret.String()
+}
+
 type Point struct {
 	PDR *las14.PointDataRecord
 	pc  *PointCloud
@@ -118,3 +190,49 @@ func (p *Point) UnoffsetXYZ() (x float64, y float64, z float64) {
 
 	return
 }
+
+// VariableLengthRecords returns the VLRs that follow the pointcloud's header.
+func (pc *PointCloud) VariableLengthRecords() []las14.VariableLengthRecord {
+	return pc.fr.VariableLengthRecords
+}
+
+// ExtendedVariableLengthRecords returns the EVLRs that follow the pointcloud's point data.
+func (pc *PointCloud) ExtendedVariableLengthRecords() []las14.ExtendedVariableLengthRecord {
+	return pc.fr.ExtendedVariableLengthRecords
+}
+
+// Coordinates returns the scaled and offset coordinates of every point, for algorithms that work on positions alone.
+func (pc *PointCloud) Coordinates() [][3]float64 {
+	n := pc.Len()
+	ret := make([][3]float64, n)
+	for i := (uint64)(0); i < n; i++ {
+		x, y, z := pc.LocalizeXYZ(pc.fr.PointDataRecord(i).Get().XYZ())
+		ret[i] = [3]float64{x, y, z}
+	}
+	return ret
+}
+
+// ColorDepth returns the bits per channel of the pointcloud's colors: 16 when any channel of any point exceeds 255,
+// 8 otherwise, and 0 when its points have no color.  The LAS spec asks for 16-bit colors, but many writers store
+// 8-bit values unscaled.
+func (pc *PointCloud) ColorDepth() int {
+	var channels [3]*las14.Dimension
+	for i, name := range [...]string{las14.DimRed, las14.DimGreen, las14.DimBlue} {
+		d, ok := pc.schema.Dimension(name)
+		if !ok {
+			return 0
+		}
+		channels[i] = d
+	}
+
+	n := pc.Len()
+	for i := (uint64)(0); i < n; i++ {
+		raw := pc.fr.PointDataRecord(i).Raw
+		for _, d := range channels {
+			if d.RawFloat64(raw) > 255 {
+				return 16
+			}
+		}
+	}
+	return 8
+}
@@ -0,0 +1,185 @@
+package lassloot
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/nullstyle/lassloot/kdtree"
+)
+
+// DefaultNormalNeighbors is the number of neighbouring points a normal is usually estimated from: enough to smooth
+// over scan noise without rounding off corners.
+const DefaultNormalNeighbors = 12
+
+// normalDimensions lists the names under which other software stores normals as extra bytes.
+var normalDimensions = [][3]string{
+	{"nx", "ny", "nz"},
+	{"normal_x", "normal_y", "normal_z"},
+	{"NormalX", "NormalY", "NormalZ"},
+}
+
+// HasNormals reports whether the pointcloud's points store normals as extra bytes, under names such as nx, ny, nz
+// or normal_x, normal_y, normal_z.
+func (pc *PointCloud) HasNormals() bool {
+	_, ok := pc.normalDimensions()
+	return ok
+}
+
+func (pc *PointCloud) normalDimensions() ([3]string, bool) {
+	for _, names := range normalDimensions {
+		found := true
+		for _, name := range names {
+			if _, ok := pc.schema.Dimension(name); !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return names, true
+		}
+	}
+	return [3]string{}, false
+}
+
+// Normals returns a unit surface normal for every point: those stored in its extra bytes when the pointcloud has
+// them (see HasNormals), and otherwise normals estimated from each point's k nearest neighbours.
+//
+// Estimated normals are the direction of least variance among the neighbours, found by principal component analysis
+// of their covariance, and are turned to point upwards since airborne and terrestrial scanners see surfaces from
+// above or from the side.  Points whose neighbours are all coincident have a normal of straight up.
+func (pc *PointCloud) Normals(k int) (error, [][3]float32) {
+	if names, ok := pc.normalDimensions(); ok {
+		n := pc.Len()
+		ret := make([][3]float32, n)
+		for i := (uint64)(0); i < n; i++ {
+			_, p := pc.PointAt(i)
+			for a, name := range names {
+				_, v := p.Float64(name)
+				ret[i][a] = (float32)(v)
+			}
+		}
+		return nil, ret
+	}
+
+	if k < 3 {
+		return fmt.Errorf("estimating normals needs at least 3 neighbours, not %d", k), nil
+	}
+	return nil, EstimateNormals(pc.Coordinates(), k)
+}
+
+// EstimateNormals estimates a unit normal for each of points from its k nearest neighbours, as described by
+// PointCloud.Normals.  The work is spread across every available CPU.
+func EstimateNormals(points [][3]float64, k int) [][3]float32 {
+	tree := kdtree.New(points, 3)
+	ret := make([][3]float32, len(points))
+
+	workers := runtime.GOMAXPROCS(0)
+	chunk := (len(points) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(points); lo += chunk {
+		hi := lo + chunk
+		if hi > len(points) {
+			hi = len(points)
+		}
+
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				ret[i] = estimateNormal(points, tree.Nearest(points[i], k))
+			}
+		}(lo, hi)
+	}
+	wg.Wait()
+
+	return ret
+}
+
+func estimateNormal(points [][3]float64, neighbors []kdtree.Neighbor) [3]float32 {
+	var mean [3]float64
+	for _, n := range neighbors {
+		for a := 0; a < 3; a++ {
+			mean[a] += points[n.Index][a]
+		}
+	}
+	for a := range mean {
+		mean[a] /= (float64)(len(neighbors))
+	}
+
+	var cov [3][3]float64
+	for _, n := range neighbors {
+		p := points[n.Index]
+		d := [3]float64{p[0] - mean[0], p[1] - mean[1], p[2] - mean[2]}
+		for r := 0; r < 3; r++ {
+			for c := r; c < 3; c++ {
+				cov[r][c] += d[r] * d[c]
+			}
+		}
+	}
+	cov[1][0], cov[2][0], cov[2][1] = cov[0][1], cov[0][2], cov[1][2]
+	if cov[0][0]+cov[1][1]+cov[2][2] == 0 {
+		return [3]float32{0, 0, 1}
+	}
+
+	v := smallestEigenvector(cov)
+	length := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+	if v[2] < 0 {
+		length = -length
+	}
+	return [3]float32{(float32)(v[0] / length), (float32)(v[1] / length), (float32)(v[2] / length)}
+}
+
+// smallestEigenvector returns the eigenvector of the symmetric matrix m with the smallest eigenvalue, found by Jacobi
+// rotations.
+func smallestEigenvector(m [3][3]float64) [3]float64 {
+	v := [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+	for sweep := 0; sweep < 50; sweep++ {
+		off := m[0][1]*m[0][1] + m[0][2]*m[0][2] + m[1][2]*m[1][2]
+		if off < 1e-30*(m[0][0]*m[0][0]+m[1][1]*m[1][1]+m[2][2]*m[2][2]) || off == 0 {
+			break
+		}
+
+		for _, pq := range [...][2]int{{0, 1}, {0, 2}, {1, 2}} {
+			p, q := pq[0], pq[1]
+			if m[p][q] == 0 {
+				continue
+			}
+
+			theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+			t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+			if theta < 0 {
+				t = -t
+			}
+			c := 1 / math.Sqrt(t*t+1)
+			s := t * c
+
+			// m = Jᵀ m J for the rotation J in the p, q plane
+			for k := 0; k < 3; k++ {
+				mkp, mkq := m[k][p], m[k][q]
+				m[k][p] = c*mkp - s*mkq
+				m[k][q] = s*mkp + c*mkq
+			}
+			for k := 0; k < 3; k++ {
+				mpk, mqk := m[p][k], m[q][k]
+				m[p][k] = c*mpk - s*mqk
+				m[q][k] = s*mpk + c*mqk
+			}
+			for k := 0; k < 3; k++ {
+				vkp, vkq := v[k][p], v[k][q]
+				v[k][p] = c*vkp - s*vkq
+				v[k][q] = s*vkp + c*vkq
+			}
+		}
+	}
+
+	min := 0
+	for i := 1; i < 3; i++ {
+		if m[i][i] < m[min][min] {
+			min = i
+		}
+	}
+	return [3]float64{v[0][min], v[1][min], v[2][min]}
+}
@@ -0,0 +1,43 @@
+package lassloot
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateNormals(t *testing.T) {
+	// a tilted plane z = x/2 sampled on a grid; its upward normal is (-1, 0, 2)/√5
+	var points [][3]float64
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 20; j++ {
+			x, y := (float64)(i), (float64)(j)
+			points = append(points, [3]float64{x, y, x / 2})
+		}
+	}
+
+	want := [3]float64{-1 / math.Sqrt(5), 0, 2 / math.Sqrt(5)}
+	for i, n := range EstimateNormals(points, DefaultNormalNeighbors) {
+		for a := 0; a < 3; a++ {
+			if math.Abs((float64)(n[a])-want[a]) > 1e-5 {
+				t.Fatalf("point %d normal = %v, want %v", i, n, want)
+			}
+		}
+	}
+
+	if n := EstimateNormals([][3]float64{{1, 1, 1}, {1, 1, 1}, {1, 1, 1}}, 3)[0]; n != [3]float32{0, 0, 1} {
+		t.Errorf("coincident points normal = %v, want straight up", n)
+	}
+}
+
+func TestUpAxis(t *testing.T) {
+	err, a := ParseUpAxis("Y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, y, z := a.Apply(1, 2, 3); x != 1 || y != 3 || z != -2 {
+		t.Errorf("y up (1, 2, 3) = (%v, %v, %v)", x, y, z)
+	}
+	if err, _ := ParseUpAxis("x"); err == nil {
+		t.Error("x accepted as an up axis")
+	}
+}
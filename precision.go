@@ -0,0 +1,239 @@
+package lassloot
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Precision describes the scale factors and offsets with which a PointCloud's coordinates are quantized into the
+// signed 32-bit integers stored in each point record.
+type Precision struct {
+	XScaleFactor float64
+	YScaleFactor float64
+	ZScaleFactor float64
+	XOffset      float64
+	YOffset      float64
+	ZOffset      float64
+}
+
+// Precision returns the scale factors and offsets recorded in the pointcloud's header.
+func (pc *PointCloud) Precision() Precision {
+	h := pc.fr.Header
+	return Precision{
+		XScaleFactor: h.XScaleFactor,
+		YScaleFactor: h.YScaleFactor,
+		ZScaleFactor: h.ZScaleFactor,
+		XOffset:      h.XOffset,
+		YOffset:      h.YOffset,
+		ZOffset:      h.ZOffset,
+	}
+}
+
+// Resolution returns the smallest distinguishable step along each axis, in the file's units.
+func (p Precision) Resolution() (x float64, y float64, z float64) {
+	return math.Abs(p.XScaleFactor), math.Abs(p.YScaleFactor), math.Abs(p.ZScaleFactor)
+}
+
+// Digits returns the number of decimal places needed to print coordinates along each axis without losing precision.
+func (p Precision) Digits() (x int, y int, z int) {
+	return ScaleDigits(p.XScaleFactor), ScaleDigits(p.YScaleFactor), ScaleDigits(p.ZScaleFactor)
+}
+
+// ScaleDigits returns the number of decimal places needed to print values quantized with the scale factor scale.
+func ScaleDigits(scale float64) int {
+	const maxDigits = 15
+
+	scale = math.Abs(scale)
+	for d := 0; d < maxDigits; d++ {
+		v := scale * math.Pow10(d)
+		if math.Abs(v-math.Round(v)) <= 1e-9*math.Max(1, v) {
+			return d
+		}
+	}
+
+	return maxDigits
+}
+
+// Requantize returns a copy of the pointcloud whose coordinates are stored with the scale factors and offsets of p.
+// Each coordinate is mapped to the nearest value representable at the new precision.  Scale factors and offsets are
+// interpreted as the decimals they print as, so conversions between decimal precisions (such as centimeters to
+// millimeters, or shifting the offset by a whole number of steps) are exact rather than accumulating floating point
+// drift.
+func (pc *PointCloud) Requantize(p Precision) (error, *PointCloud) {
+	old := pc.Precision()
+
+	err, qx := newRequantizer(old.XScaleFactor, old.XOffset, p.XScaleFactor, p.XOffset)
+	if err != nil {
+		return fmt.Errorf("invalid x precision: %w", err), nil
+	}
+	err, qy := newRequantizer(old.YScaleFactor, old.YOffset, p.YScaleFactor, p.YOffset)
+	if err != nil {
+		return fmt.Errorf("invalid y precision: %w", err), nil
+	}
+	err, qz := newRequantizer(old.ZScaleFactor, old.ZOffset, p.ZScaleFactor, p.ZOffset)
+	if err != nil {
+		return fmt.Errorf("invalid z precision: %w", err), nil
+	}
+
+	fr := pc.fr.Copy()
+	l := pc.Len()
+	for i := (uint64)(0); i < l; i++ {
+		pdr := fr.PointDataRecord(i)
+		x, y, z := pdr.Get().XYZ()
+
+		err := pdr.SetXYZ(qx.apply(x), qy.apply(y), qz.apply(z))
+		if err != nil {
+			return fmt.Errorf("point %d cannot be represented at the requested precision: %w", i, err), nil
+		}
+	}
+
+	fr.Header.XScaleFactor = p.XScaleFactor
+	fr.Header.YScaleFactor = p.YScaleFactor
+	fr.Header.ZScaleFactor = p.ZScaleFactor
+	fr.Header.XOffset = p.XOffset
+	fr.Header.YOffset = p.YOffset
+	fr.Header.ZOffset = p.ZOffset
+
+	err, requantized := newPointCloud(fr)
+	if err != nil {
+		return err, nil
+	}
+
+	requantized.gpsWeek, requantized.gpsWeekSet = pc.gpsWeek, pc.gpsWeekSet
+	return nil, requantized
+}
+
+// requantizer maps an unscaled coordinate at one precision to another.  The mapping is v*num/den + shift/den, which
+// is evaluated exactly in integers whenever the terms are small enough to avoid overflow and in floating point
+// otherwise.
+type requantizer struct {
+	exact bool
+	num   int64
+	shift int64
+	den   int64
+
+	ratio  float64
+	offset float64
+}
+
+const (
+	maxExactNum   = 1 << 30
+	maxExactShift = 1 << 60
+)
+
+func newRequantizer(oldScale, oldOffset, newScale, newOffset float64) (error, requantizer) {
+	for _, v := range [...]float64{oldScale, oldOffset, newScale, newOffset} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("non-finite scale or offset"), requantizer{}
+		}
+	}
+	if newScale == 0 || oldScale == 0 {
+		return fmt.Errorf("zero scale factor"), requantizer{}
+	}
+
+	ratio := new(big.Rat).Quo(decimalRat(oldScale), decimalRat(newScale))
+	offset := new(big.Rat).Sub(decimalRat(oldOffset), decimalRat(newOffset))
+	offset.Quo(offset, decimalRat(newScale))
+
+	q := requantizer{}
+	q.ratio, _ = ratio.Float64()
+	q.offset, _ = offset.Float64()
+
+	// bring both terms over a common positive denominator
+	den := new(big.Int).Mul(ratio.Denom(), offset.Denom())
+	den.Quo(den, new(big.Int).GCD(nil, nil, ratio.Denom(), offset.Denom()))
+	num := new(big.Int).Mul(ratio.Num(), new(big.Int).Quo(den, ratio.Denom()))
+	shift := new(big.Int).Mul(offset.Num(), new(big.Int).Quo(den, offset.Denom()))
+
+	if num.IsInt64() && shift.IsInt64() && den.IsInt64() {
+		q.num, q.shift, q.den = num.Int64(), shift.Int64(), den.Int64()
+		q.exact = abs64(q.num) <= maxExactNum && abs64(q.shift) <= maxExactShift && q.den <= maxExactShift
+	}
+
+	return nil, q
+}
+
+func (q requantizer) apply(v int64) int64 {
+	if q.exact {
+		// round half up: floor((2n + d) / 2d)
+		n := v*q.num + q.shift
+		return floorDiv(2*n+q.den, 2*q.den)
+	}
+
+	f := math.Round((float64)(v)*q.ratio + q.offset)
+	if math.Abs(f) > math.MaxInt32*2.0 {
+		// out of range for any point record; let SetXYZ report it
+		return math.MaxInt64
+	}
+	return (int64)(f)
+}
+
+// decimalRat returns v as the exact decimal fraction it prints as, such that 0.01 is 1/100 rather than the nearest
+// binary fraction.
+func decimalRat(v float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, 64))
+	if !ok {
+		return new(big.Rat).SetFloat64(v)
+	}
+	return r
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// MaxAutoDigits is the most decimal places AutoPrecision will keep.
+const MaxAutoDigits = 9
+
+// AutoPrecision chooses scale factors and offsets for coordinates spanning min to max along each axis.  Each axis
+// keeps the requested number of decimal places, if the span then fits in the signed 32-bit range of a point record,
+// and otherwise as many as do fit.  Offsets are the minimum rounded down to a multiple of 1000, which keeps them
+// recognizable and leaves coordinates near the offset small.
+func AutoPrecision(min, max [3]float64, digits [3]int) Precision {
+	var scale, offset [3]float64
+	for i := range scale {
+		offset[i] = math.Floor(min[i]/1000) * 1000
+		if math.IsNaN(offset[i]) || math.IsInf(offset[i], 0) {
+			offset[i] = 0
+		}
+
+		d := digits[i]
+		if d > MaxAutoDigits {
+			d = MaxAutoDigits
+		}
+		span := math.Max(math.Abs(max[i]-offset[i]), math.Abs(min[i]-offset[i]))
+		for d > -MaxAutoDigits && span*math.Pow10(d) > math.MaxInt32 {
+			d--
+		}
+		scale[i] = decimalScale(d)
+	}
+
+	return Precision{
+		XScaleFactor: scale[0],
+		YScaleFactor: scale[1],
+		ZScaleFactor: scale[2],
+		XOffset:      offset[0],
+		YOffset:      offset[1],
+		ZOffset:      offset[2],
+	}
+}
+
+// decimalScale returns the scale factor that keeps d decimal places, parsed from its decimal form so that 0.01 is
+// the float64 nearest one hundredth rather than 1/100 computed in floating point.
+func decimalScale(d int) float64 {
+	v, _ := strconv.ParseFloat("1e"+strconv.Itoa(-d), 64)
+	return v
+}
@@ -0,0 +1,140 @@
+package lassloot
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func newTestPointCloud(t *testing.T, spec lastest.Spec) *PointCloud {
+	t.Helper()
+
+	file := lastest.Generate(spec)
+	err, pc := NewPointCloudFromReaderAt(bytes.NewReader(file.Bytes), (int64)(len(file.Bytes)))
+	if err != nil {
+		t.Fatalf("failed to decode synthetic pointcloud: %v", err)
+	}
+
+	return pc
+}
+
+func TestSignedCoordinates(t *testing.T) {
+	for format := byte(0); format <= 10; format++ {
+		pc := newTestPointCloud(t, lastest.Spec{
+			Header: lastest.Header{PointDataRecordFormat: format, XOffset: 500000, YOffset: 4100000, ZOffset: 100},
+			Points: []lastest.Point{{X: 12, Y: -5, Z: -250000}},
+		})
+
+		err, p := pc.PointAt(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		x, y, z := p.XYZ()
+		if math.Abs(x-500000.12) > 1e-6 || math.Abs(y-4099999.95) > 1e-6 || math.Abs(z-(-2400)) > 1e-6 {
+			t.Errorf("format %d: xyz = (%f, %f, %f)", format, x, y, z)
+		}
+	}
+}
+
+func TestPrecisionDigits(t *testing.T) {
+	tests := []struct {
+		scale float64
+		want  int
+	}{
+		{1, 0},
+		{0.1, 1},
+		{0.01, 2},
+		{0.001, 3},
+		{0.025, 3},
+		{0.0001, 4},
+		{10, 0},
+	}
+
+	for _, tt := range tests {
+		x, _, _ := Precision{XScaleFactor: tt.scale}.Digits()
+		if x != tt.want {
+			t.Errorf("Digits() for scale %v = %d, want %d", tt.scale, x, tt.want)
+		}
+	}
+}
+
+func TestRequantize(t *testing.T) {
+	points := []lastest.Point{
+		{X: 0, Y: 0, Z: 0},
+		{X: -1, Y: 123456789, Z: -98765},
+		{X: 1000001, Y: -7, Z: 31},
+	}
+	pc := newTestPointCloud(t, lastest.Spec{
+		Header: lastest.Header{
+			PointDataRecordFormat: 6,
+			XScaleFactor:          0.01, YScaleFactor: 0.01, ZScaleFactor: 0.01,
+			XOffset: 500000, YOffset: 4100000, ZOffset: 0,
+		},
+		Points: points,
+	})
+
+	// centimeters to millimeters, moving the x offset by a whole number of centimeters
+	fine := Precision{
+		XScaleFactor: 0.001, YScaleFactor: 0.01, ZScaleFactor: 0.001,
+		XOffset: 499876.54, YOffset: 4100000, ZOffset: 0,
+	}
+	err, mm := pc.Requantize(fine)
+	if err != nil {
+		t.Fatalf("requantize failed: %v", err)
+	}
+
+	if mm.Precision() != fine {
+		t.Errorf("precision = %+v, want %+v", mm.Precision(), fine)
+	}
+
+	for i, want := range points {
+		_, p := mm.PointAt((uint64)(i))
+		x, y, z := p.UnscaledXYZ()
+		if x != (int64)(want.X)*10+123460 || y != (int64)(want.Y) || z != (int64)(want.Z)*10 {
+			t.Errorf("point %d: unscaled xyz = (%d, %d, %d)", i, x, y, z)
+		}
+	}
+
+	// and back again, which must reproduce the original records exactly
+	err, cm := mm.Requantize(pc.Precision())
+	if err != nil {
+		t.Fatalf("requantize failed: %v", err)
+	}
+
+	for i, want := range points {
+		_, p := cm.PointAt((uint64)(i))
+		x, y, z := p.UnscaledXYZ()
+		if x != (int64)(want.X) || y != (int64)(want.Y) || z != (int64)(want.Z) {
+			t.Errorf("point %d: round trip unscaled xyz = (%d, %d, %d)", i, x, y, z)
+		}
+	}
+
+	// the original must be untouched
+	_, p := pc.PointAt(1)
+	if x, _, _ := p.UnscaledXYZ(); x != -1 {
+		t.Errorf("requantize modified its receiver: x = %d", x)
+	}
+
+	// 123456789 cm doesn't fit in 32 bits as micrometers
+	overflow := pc.Precision()
+	overflow.YScaleFactor = 0.00001
+	if err, _ := pc.Requantize(overflow); err == nil {
+		t.Error("expected requantize to overflow")
+	}
+
+	if err, _ := pc.Requantize(Precision{}); err == nil {
+		t.Error("expected zero scale factors to fail")
+	}
+}
+
+func TestAutoPrecision(t *testing.T) {
+	p := AutoPrecision([3]float64{500123.5, -12.25, 0}, [3]float64{501000, 40, 1e7}, [3]int{3, 2, 4})
+
+	want := Precision{XScaleFactor: 0.001, YScaleFactor: 0.01, ZScaleFactor: 0.01, XOffset: 500000, YOffset: -1000}
+	if p != want {
+		t.Errorf("precision = %+v, want %+v", p, want)
+	}
+}
@@ -0,0 +1,176 @@
+package lassloot
+
+import (
+	"fmt"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// Schema returns the description of the dimensions stored in each of the pointcloud's points, including any extra
+// bytes.
+func (pc *PointCloud) Schema() *las14.Schema {
+	return pc.schema
+}
+
+// Get returns the value of the dimension named name, ignoring case.  Scaled dimensions (X, Y, Z, ScanAngle and scaled
+// extra bytes) are returned as a float64 with their scale and offset applied.  Single bit flags are returned as a
+// bool, and every other dimension as its native type, e.g. a uint16 for Intensity.
+func (p *Point) Get(name string) (error, interface{}) {
+	d, ok := p.pc.schema.Dimension(name)
+	if !ok {
+		return fmt.Errorf("unknown dimension: %s", name), nil
+	}
+
+	return nil, dimensionValue(d, p.PDR.Raw)
+}
+
+// Float64 returns the value of the dimension named name converted to a float64, with any scale and offset applied.
+func (p *Point) Float64(name string) (error, float64) {
+	d, ok := p.pc.schema.Dimension(name)
+	if !ok {
+		return fmt.Errorf("unknown dimension: %s", name), 0
+	}
+
+	return nil, d.Float64(p.PDR.Raw)
+}
+
+func dimensionValue(d *las14.Dimension, record []byte) interface{} {
+	if d.Scaled {
+		return d.Float64(record)
+	}
+	return d.Raw(record)
+}
+
+// Column holds a single dimension's values for every point in a PointCloud.  Values is a slice of the type Point.Get
+// returns for the dimension: a []float64 for scaled dimensions, a []bool for flags, a []uint16 for Intensity and so
+// on.  Undocumented extra bytes are held as a [][]byte.
+type Column struct {
+	Dimension las14.Dimension
+	Values    interface{}
+}
+
+// Column extracts the values of the dimension named name, ignoring case, from every point.
+func (pc *PointCloud) Column(name string) (error, *Column) {
+	d, ok := pc.schema.Dimension(name)
+	if !ok {
+		return fmt.Errorf("unknown dimension: %s", name), nil
+	}
+
+	n := (int)(pc.Len())
+	record := func(i int) []byte {
+		return pc.fr.PointDataRecord((uint64)(i)).Raw
+	}
+
+	// the column's type depends only on the dimension, so it is sampled from a zeroed record
+	var values interface{}
+	switch sample := dimensionValue(d, make([]byte, d.ByteOffset+d.Size)).(type) {
+	case float64:
+		vs := make([]float64, n)
+		for i := range vs {
+			vs[i] = d.Float64(record(i))
+		}
+		values = vs
+	case bool:
+		vs := make([]bool, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(bool)
+		}
+		values = vs
+	case uint8:
+		vs := make([]uint8, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(uint8)
+		}
+		values = vs
+	case int8:
+		vs := make([]int8, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(int8)
+		}
+		values = vs
+	case uint16:
+		vs := make([]uint16, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(uint16)
+		}
+		values = vs
+	case int16:
+		vs := make([]int16, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(int16)
+		}
+		values = vs
+	case uint32:
+		vs := make([]uint32, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(uint32)
+		}
+		values = vs
+	case int32:
+		vs := make([]int32, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(int32)
+		}
+		values = vs
+	case uint64:
+		vs := make([]uint64, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(uint64)
+		}
+		values = vs
+	case int64:
+		vs := make([]int64, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(int64)
+		}
+		values = vs
+	case float32:
+		vs := make([]float32, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).(float32)
+		}
+		values = vs
+	case []byte:
+		vs := make([][]byte, n)
+		for i := range vs {
+			vs[i] = d.Raw(record(i)).([]byte)
+		}
+		values = vs
+	default:
+		return fmt.Errorf("unhandled value type %T for dimension %s", sample, d.Name), nil
+	}
+
+	return nil, &Column{Dimension: *d, Values: values}
+}
+
+// Len returns the number of values in the column.
+func (c *Column) Len() int {
+	switch vs := c.Values.(type) {
+	case []float64:
+		return len(vs)
+	case []bool:
+		return len(vs)
+	case []uint8:
+		return len(vs)
+	case []int8:
+		return len(vs)
+	case []uint16:
+		return len(vs)
+	case []int16:
+		return len(vs)
+	case []uint32:
+		return len(vs)
+	case []int32:
+		return len(vs)
+	case []uint64:
+		return len(vs)
+	case []int64:
+		return len(vs)
+	case []float32:
+		return len(vs)
+	case [][]byte:
+		return len(vs)
+	default:
+		return 0
+	}
+}
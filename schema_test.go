@@ -0,0 +1,79 @@
+package lassloot
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestPointGet(t *testing.T) {
+	pc := newTestPointCloud(t, lastest.Spec{
+		Header: lastest.Header{PointDataRecordFormat: 7, XOffset: 1000},
+		Points: []lastest.Point{{X: -150, Intensity: 900, Classification: 2, ScanAngle: 500, Red: 65535, EdgeOfFlight: true}},
+	})
+
+	_, p := pc.PointAt(0)
+	tests := []struct {
+		name string
+		want interface{}
+	}{
+		{"x", 998.5},
+		{"Intensity", (uint16)(900)},
+		{"classification", (uint8)(2)},
+		{"ScanAngle", 3.0},
+		{"red", (uint16)(65535)},
+		{"EdgeOfFlightLine", true},
+	}
+
+	for _, tt := range tests {
+		err, got := p.Get(tt.name)
+		if err != nil {
+			t.Errorf("Get(%q) failed: %v", tt.name, err)
+			continue
+		}
+
+		if f, ok := got.(float64); ok {
+			if math.Abs(f-tt.want.(float64)) > 1e-9 {
+				t.Errorf("Get(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		} else if got != tt.want {
+			t.Errorf("Get(%q) = %v (%T), want %v (%T)", tt.name, got, got, tt.want, tt.want)
+		}
+	}
+
+	if err, _ := p.Get("NIR"); err == nil {
+		t.Error("expected format 7 to have no NIR dimension")
+	}
+}
+
+func TestPointCloudColumn(t *testing.T) {
+	pc := newTestPointCloud(t, lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 1}, PointCount: 25})
+
+	err, intensity := pc.Column("intensity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, ok := intensity.Values.([]uint16)
+	if !ok || intensity.Len() != 25 {
+		t.Fatalf("intensity column is %T with %d values", intensity.Values, intensity.Len())
+	}
+	for i, v := range values {
+		if want := lastest.PatternPoint(i).Intensity; v != want {
+			t.Errorf("intensity[%d] = %d, want %d", i, v, want)
+		}
+	}
+
+	err, z := pc.Column("Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if zs, ok := z.Values.([]float64); !ok || math.Abs(zs[3]-(float64)(lastest.PatternPoint(3).Z)*0.01) > 1e-9 {
+		t.Errorf("z column = %v", z.Values)
+	}
+
+	err, empty := newTestPointCloud(t, lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 6}}).Column("GPSTime")
+	if err != nil || empty.Len() != 0 {
+		t.Errorf("empty column = %v, %v", empty, err)
+	}
+}
@@ -0,0 +1,37 @@
+package solid
+
+import (
+	"math"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+// Colors returns a color for each vertex of s, taken from its parent in m, the mesh s was made from, and pc, the
+// pointcloud m was made from.  The walls and base take the colors of the surface's edge above them.  Hypsometric
+// tints span the elevations of the solid's own surface, which may be a small part of m.
+func (s *Solid) Colors(m *tin.Mesh, pc *lassloot.PointCloud, c tin.Coloring) (error, [][3]uint8) {
+	ret := make([][3]uint8, len(s.Vertices))
+
+	if c == tin.Hypsometric {
+		minZ, maxZ := math.Inf(1), math.Inf(-1)
+		for _, p := range s.Parents {
+			z := m.Vertices[p][2]
+			minZ, maxZ = math.Min(minZ, z), math.Max(maxZ, z)
+		}
+		span := math.Max(maxZ-minZ, 1e-9)
+		for i, p := range s.Parents {
+			ret[i] = tin.HypsometricColor((m.Vertices[p][2] - minZ) / span)
+		}
+		return nil, ret
+	}
+
+	err, colors := m.Colors(pc, c)
+	if err != nil {
+		return err, nil
+	}
+	for i, p := range s.Parents {
+		ret[i] = colors[p]
+	}
+	return nil, ret
+}
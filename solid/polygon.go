@@ -0,0 +1,130 @@
+package solid
+
+import (
+	"fmt"
+	"math"
+)
+
+// polygon answers point in polygon queries against a ring quickly: its edges are bucketed into horizontal bands, so a
+// query only tests the edges of the band it falls in.
+type polygon struct {
+	points     [][2]float64
+	minY, step float64
+	bands      [][]int32
+}
+
+func newPolygon(points [][2]float64) *polygon {
+	p := &polygon{points: points}
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, pt := range points {
+		minY, maxY = math.Min(minY, pt[1]), math.Max(maxY, pt[1])
+	}
+	p.minY = minY
+	p.bands = make([][]int32, len(points))
+	p.step = (maxY - minY) / (float64)(len(p.bands))
+	for i := range points {
+		a, b := points[i], points[(i+1)%len(points)]
+		lo, hi := p.band(math.Min(a[1], b[1])), p.band(math.Max(a[1], b[1]))
+		for j := lo; j <= hi; j++ {
+			p.bands[j] = append(p.bands[j], (int32)(i))
+		}
+	}
+	return p
+}
+
+func (p *polygon) band(y float64) int {
+	if p.step == 0 {
+		return 0
+	}
+	i := (int)((y - p.minY) / p.step)
+	if i < 0 {
+		return 0
+	}
+	if i >= len(p.bands) {
+		return len(p.bands) - 1
+	}
+	return i
+}
+
+// contains reports whether x, y lies inside the ring, by the even-odd rule.  Points on its edges may go either way.
+func (p *polygon) contains(x, y float64) bool {
+	if y < p.minY || y > p.minY+p.step*(float64)(len(p.bands)) {
+		return false
+	}
+	in := false
+	for _, i := range p.bands[p.band(y)] {
+		a, b := p.points[i], p.points[((int)(i)+1)%len(p.points)]
+		if (a[1] > y) != (b[1] > y) && x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// ring tidies a boundary into a counterclockwise ring: a repeated closing point and consecutive duplicates are
+// dropped.  It fails for rings with fewer than three points, no area, or edges that cross.
+func ring(boundary [][2]float64) (error, [][2]float64) {
+	var ret [][2]float64
+	for _, p := range boundary {
+		if math.IsNaN(p[0]) || math.IsNaN(p[1]) || math.IsInf(p[0], 0) || math.IsInf(p[1], 0) {
+			return fmt.Errorf("boundary point %v is not a position", p), nil
+		}
+		if len(ret) == 0 || ret[len(ret)-1] != p {
+			ret = append(ret, p)
+		}
+	}
+	for len(ret) > 1 && ret[len(ret)-1] == ret[0] {
+		ret = ret[:len(ret)-1]
+	}
+	if len(ret) < 3 {
+		return fmt.Errorf("boundary of %d distinct points is not a polygon", len(ret)), nil
+	}
+
+	area := 0.0
+	for i, a := range ret {
+		b := ret[(i+1)%len(ret)]
+		area += (a[0]-ret[0][0])*(b[1]-ret[0][1]) - (b[0]-ret[0][0])*(a[1]-ret[0][1])
+	}
+	if area == 0 {
+		return fmt.Errorf("boundary encloses no area"), nil
+	}
+	if area < 0 {
+		for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+			ret[i], ret[j] = ret[j], ret[i]
+		}
+	}
+
+	n := len(ret)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if j == i+1 || (i == 0 && j == n-1) {
+				continue
+			}
+			if crosses(ret[i], ret[(i+1)%n], ret[j], ret[(j+1)%n]) {
+				return fmt.Errorf("boundary crosses itself between points %d and %d", i, j), nil
+			}
+		}
+	}
+	return nil, ret
+}
+
+// crosses reports whether the segments ab and cd meet.
+func crosses(a, b, c, d [2]float64) bool {
+	d1, d2 := orient(c, d, a), orient(c, d, b)
+	d3, d4 := orient(a, b, c), orient(a, b, d)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && between(c, d, a)) || (d2 == 0 && between(c, d, b)) ||
+		(d3 == 0 && between(a, b, c)) || (d4 == 0 && between(a, b, d))
+}
+
+func orient(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
+
+// between reports whether p, collinear with ab, lies within its extent.
+func between(a, b, p [2]float64) bool {
+	return math.Min(a[0], b[0]) <= p[0] && p[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= p[1] && p[1] <= math.Max(a[1], b[1])
+}
@@ -0,0 +1,413 @@
+// Package solid closes terrain surfaces into watertight solids for 3D printing.  The surface is scaled to print size
+// and optionally clipped to a boundary.  Walls then run from its edges down to a flat base.
+//
+// Every edge of a solid is shared by exactly two of its triangles, which run it in opposite directions, so slicers
+// accept the solid without repair.
+package solid
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nullstyle/lassloot/kdtree"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+// Options control the size and shape of a solid.
+type Options struct {
+	// Size is the length in millimeters at which the longer horizontal side of the surface is printed.
+	Size float64
+
+	// Exaggeration multiplies heights above the lowest point of the surface.  Zero leaves them true to scale.
+	Exaggeration float64
+
+	// Base is the thickness in millimeters of the solid beneath the lowest point of the surface.  It must be positive,
+	// so that every wall has height.
+	Base float64
+
+	// Boundary, if set, is a simple polygon in the mesh's coordinates that the surface is clipped to, e.g. a property
+	// line.  It must lie within the mesh.  Without one, the solid covers the mesh's convex hull.
+	Boundary [][2]float64
+}
+
+// Solid is a closed triangle mesh in millimeters.  The corner of its base is at the origin, and its bottom lies on the
+// plane z = 0.
+type Solid struct {
+	Vertices [][3]float64
+
+	// Triangles lists the vertices of each triangle, counterclockwise seen from outside.
+	Triangles [][3]int32
+
+	// Parents holds, for each vertex, the mesh vertex nearest it on the surface, which gives it attributes such as
+	// color.
+	Parents []int32
+
+	// Surface counts the leading triangles that make up the top surface.  The walls and the base follow them.
+	Surface int
+
+	// Scale is the number of millimeters per unit of the mesh's coordinates, before exaggeration.
+	Scale float64
+
+	// Origin is the position in the mesh's coordinates that the origin's horizontal position and the lowest point of the
+	// surface come from.
+	Origin [3]float64
+}
+
+// New closes m, or the part of it within opts.Boundary, into a solid.
+func New(m *tin.Mesh, opts Options) (error, *Solid) {
+	if !(opts.Size > 0) {
+		return fmt.Errorf("invalid print size %v", opts.Size), nil
+	}
+	if !(opts.Base > 0) {
+		return fmt.Errorf("invalid base thickness %v", opts.Base), nil
+	}
+	if opts.Exaggeration < 0 || math.IsNaN(opts.Exaggeration) {
+		return fmt.Errorf("invalid exaggeration %v", opts.Exaggeration), nil
+	}
+	if len(m.Triangles) == 0 {
+		return fmt.Errorf("mesh has no triangles"), nil
+	}
+
+	surface := m
+	parents := make([]int32, len(m.Vertices))
+	for i := range parents {
+		parents[i] = (int32)(i)
+	}
+	if len(opts.Boundary) > 0 {
+		var err error
+		err, surface, parents = clip(m, opts.Boundary)
+		if err != nil {
+			return err, nil
+		}
+	}
+
+	err, loops := boundaries(surface)
+	if err != nil {
+		return err, nil
+	}
+
+	// the scale fits the surface's extent to the print size
+	minX, minY, minZ := math.Inf(1), math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, tri := range surface.Triangles {
+		for _, v := range tri {
+			p := surface.Vertices[v]
+			minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+			minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+			minZ = math.Min(minZ, p[2])
+		}
+	}
+	exaggeration := opts.Exaggeration
+	if exaggeration == 0 {
+		exaggeration = 1
+	}
+	s := &Solid{Scale: opts.Size / math.Max(maxX-minX, maxY-minY), Origin: [3]float64{minX, minY, minZ}}
+
+	// top and bottom hold the solid vertex of each surface vertex, added as triangles first use them
+	top := make([]int32, len(surface.Vertices))
+	bottom := make([]int32, len(surface.Vertices))
+	for i := range top {
+		top[i], bottom[i] = -1, -1
+	}
+	vertex := func(v int32, base bool) int32 {
+		index := top
+		if base {
+			index = bottom
+		}
+		if index[v] < 0 {
+			p := surface.Vertices[v]
+			z := 0.0
+			if !base {
+				z = (p[2]-minZ)*s.Scale*exaggeration + opts.Base
+			}
+			index[v] = (int32)(len(s.Vertices))
+			s.Vertices = append(s.Vertices, [3]float64{(p[0] - minX) * s.Scale, (p[1] - minY) * s.Scale, z})
+			s.Parents = append(s.Parents, parents[v])
+		}
+		return index[v]
+	}
+
+	for _, tri := range surface.Triangles {
+		s.Triangles = append(s.Triangles, [3]int32{vertex(tri[0], false), vertex(tri[1], false), vertex(tri[2], false)})
+	}
+	s.Surface = len(s.Triangles)
+
+	// each edge of a boundary runs with the surface on its left, so the outside of its wall is on its right
+	for _, loop := range loops {
+		for i, a := range loop {
+			b := loop[(i+1)%len(loop)]
+			ta, tb := vertex(a, false), vertex(b, false)
+			ba, bb := vertex(a, true), vertex(b, true)
+			s.Triangles = append(s.Triangles, [3]int32{ba, bb, tb}, [3]int32{ba, tb, ta})
+		}
+	}
+
+	// the base faces down, so its triangles are reversed
+	floor := base(surface, loops)
+	for _, tri := range floor {
+		s.Triangles = append(s.Triangles, [3]int32{vertex(tri[0], true), vertex(tri[2], true), vertex(tri[1], true)})
+	}
+	return nil, s
+}
+
+// boundaries returns the loops of vertices around the edges of m that have no neighbouring triangle, each running
+// with the surface on its left.  A vertex where two loops meet has no consistent closing, so it is an error.
+func boundaries(m *tin.Mesh) (error, [][]int32) {
+	next := make(map[int32]int32)
+	for e, h := range m.Halfedges {
+		if h >= 0 {
+			continue
+		}
+		from, to := m.Triangles[e/3][e%3], m.Triangles[e/3][(e+1)%3]
+		if _, ok := next[from]; ok {
+			return fmt.Errorf("surface is pinched at %v", m.Vertices[from]), nil
+		}
+		next[from] = to
+	}
+
+	var loops [][]int32
+	seen := make(map[int32]bool, len(next))
+	for e, h := range m.Halfedges {
+		start := m.Triangles[e/3][e%3]
+		if h >= 0 || seen[start] {
+			continue
+		}
+		var loop []int32
+		for v := start; !seen[v]; v = next[v] {
+			seen[v] = true
+			loop = append(loop, v)
+		}
+		loops = append(loops, loop)
+	}
+	return nil, loops
+}
+
+// base triangulates the floor beneath m.  A single boundary loop is triangulated on its own, which takes far fewer
+// triangles than the surface.  Otherwise, or when the loop's own triangulation misses one of its edges, the floor
+// repeats the surface's triangles.
+func base(m *tin.Mesh, loops [][]int32) [][3]int32 {
+	if len(loops) != 1 {
+		return m.Triangles
+	}
+	loop := loops[0]
+
+	points := make([][3]float64, len(loop))
+	for i, v := range loop {
+		p := m.Vertices[v]
+		points[i] = [3]float64{p[0], p[1], 0}
+	}
+	err, floor := tin.Triangulate(points, tin.First)
+	if err != nil || len(floor.Vertices) != len(loop) {
+		return m.Triangles
+	}
+
+	if !hasRing(floor, len(loop)) {
+		return m.Triangles
+	}
+	var ret [][3]int32
+	for t, in := range within(floor, len(loop)) {
+		if in {
+			tri := floor.Triangles[t]
+			ret = append(ret, [3]int32{loop[tri[0]], loop[tri[1]], loop[tri[2]]})
+		}
+	}
+	return ret
+}
+
+// hasRing reports whether each edge of the ring of the first n vertices of m, from each vertex to the next, runs along
+// a triangle of m.
+func hasRing(m *tin.Mesh, n int) bool {
+	edges := make(map[[2]int32]bool)
+	for _, tri := range m.Triangles {
+		for k := 0; k < 3; k++ {
+			if a, b := tri[k], tri[(k+1)%3]; (int)(a) < n && (int)(b) < n {
+				edges[[2]int32{a, b}] = true
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !edges[[2]int32{(int32)(i), (int32)((i + 1) % n)}] {
+			return false
+		}
+	}
+	return true
+}
+
+// within marks the triangles of m inside the counterclockwise ring of its first n vertices, whose edges hasRing
+// finds.  They are those reached from the triangles on the ring's left without crossing it.  Testing their centroids
+// instead misjudges the slivers between nearly collinear points of the ring, whose centroids lie on it to within
+// rounding, and pinches the surface.
+func within(m *tin.Mesh, n int) []bool {
+	ring := func(a, b int32) bool {
+		return (int)(a) < n && (int)(b) < n && ((int)(a+1)%n == (int)(b) || (int)(b+1)%n == (int)(a))
+	}
+	in := make([]bool, len(m.Triangles))
+	var queue []int
+	for t, tri := range m.Triangles {
+		for k := 0; k < 3; k++ {
+			if a, b := tri[k], tri[(k+1)%3]; !in[t] && (int)(a) < n && (int)(a+1)%n == (int)(b) {
+				in[t] = true
+				queue = append(queue, t)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		t := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for k := 0; k < 3; k++ {
+			tri := m.Triangles[t]
+			h := m.Halfedges[3*t+k]
+			if h < 0 || in[h/3] || ring(tri[k], tri[(k+1)%3]) {
+				continue
+			}
+			in[h/3] = true
+			queue = append(queue, (int)(h/3))
+		}
+	}
+	return in
+}
+
+// maxSplits bounds the rounds of splitting boundary edges that clip makes before giving up on a boundary.
+const maxSplits = 32
+
+// clip cuts m to a boundary polygon.  The boundary, densified to the mesh's point spacing with elevations interpolated
+// from it, is triangulated with the mesh vertices inside it.  Vertices closer to the boundary than that spacing are
+// dropped, so that each boundary edge's diametral circle is empty and the edge appears in the Delaunay triangulation.
+// Edges that still do not appear, where the boundary turns sharply or narrows, are split until they do.  Triangles
+// outside the boundary, across its edges from those within it, are then removed.  clip returns the clipped mesh and
+// the parent of each of its vertices in m.
+func clip(m *tin.Mesh, boundary [][2]float64) (error, *tin.Mesh, []int32) {
+	err, poly := ring(boundary)
+	if err != nil {
+		return err, nil, nil
+	}
+
+	area := 0.0
+	o := m.Vertices[m.Hull[0]]
+	for i, a := range m.Hull {
+		p, q := m.Vertices[a], m.Vertices[m.Hull[(i+1)%len(m.Hull)]]
+		area += (p[0]-o[0])*(q[1]-o[1]) - (q[0]-o[0])*(p[1]-o[1])
+	}
+	spacing := math.Sqrt(area / 2 / (float64)(len(m.Vertices)))
+
+	var points [][3]float64
+	var parents []int32
+	hint := 0
+	add := func(at int, x, y float64) error {
+		z, t, ok := m.Interpolate(x, y, hint)
+		if !ok {
+			return fmt.Errorf("boundary passes outside the surface at %v, %v", x, y)
+		}
+		hint = t
+		parent, best := (int32)(-1), math.Inf(1)
+		for _, v := range m.Triangles[t] {
+			p := m.Vertices[v]
+			if d := (p[0]-x)*(p[0]-x) + (p[1]-y)*(p[1]-y); d < best {
+				parent, best = v, d
+			}
+		}
+		points = append(points, [3]float64{})
+		parents = append(parents, 0)
+		copy(points[at+1:], points[at:])
+		copy(parents[at+1:], parents[at:])
+		points[at], parents[at] = [3]float64{x, y, z}, parent
+		return nil
+	}
+
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		n := math.Max(1, math.Ceil(math.Hypot(b[0]-a[0], b[1]-a[1])/spacing))
+		for k := 0.0; k < n; k++ {
+			if err := add(len(points), a[0]+(b[0]-a[0])*k/n, a[1]+(b[1]-a[1])*k/n); err != nil {
+				return err, nil, nil
+			}
+		}
+	}
+
+	// the vertices inside the boundary and clear of it
+	near := make([]bool, len(m.Vertices))
+	tree := kdtree.New(m.Vertices, 2)
+	for _, p := range points {
+		tree.Within(p, spacing, func(n kdtree.Neighbor) { near[n.Index] = true })
+	}
+	inside := newPolygon(poly)
+	var interior [][3]float64
+	var interiorParents []int32
+	for i, p := range m.Vertices {
+		if !near[i] && inside.contains(p[0], p[1]) {
+			interior = append(interior, p)
+			interiorParents = append(interiorParents, (int32)(i))
+		}
+	}
+
+	for round := 0; ; round++ {
+		nb := len(points)
+		err, c := tin.Triangulate(append(points[:nb:nb], interior...), tin.First)
+		if err != nil {
+			return fmt.Errorf("clipping to boundary: %w", err), nil, nil
+		}
+		if len(c.Vertices) != nb+len(interior) {
+			return fmt.Errorf("boundary touches itself"), nil, nil
+		}
+
+		edges := make(map[[2]int32]bool)
+		for _, tri := range c.Triangles {
+			for k := 0; k < 3; k++ {
+				if a, b := tri[k], tri[(k+1)%3]; (int)(a) < nb && (int)(b) < nb {
+					edges[[2]int32{a, b}] = true
+				}
+			}
+		}
+		var missing []int
+		for i := 0; i < nb; i++ {
+			if !edges[[2]int32{(int32)(i), (int32)((i + 1) % nb)}] {
+				missing = append(missing, i)
+			}
+		}
+
+		if len(missing) == 0 {
+			return nil, subset(c, within(c, nb)), append(parents, interiorParents...)
+		}
+		if round == maxSplits {
+			return fmt.Errorf("boundary turns too sharply to clip the surface to"), nil, nil
+		}
+
+		// split from the end, so the indices of earlier edges hold
+		for j := len(missing) - 1; j >= 0; j-- {
+			i := missing[j]
+			a, b := points[i], points[(i+1)%nb]
+			if err := add(i+1, (a[0]+b[0])/2, (a[1]+b[1])/2); err != nil {
+				return err, nil, nil
+			}
+		}
+	}
+}
+
+// subset returns the mesh of the triangles of m that keep selects, over the same vertices.  Edges shared with a
+// dropped triangle have no neighbour.
+func subset(m *tin.Mesh, keep []bool) *tin.Mesh {
+	index := make([]int32, len(m.Triangles))
+	ret := &tin.Mesh{Vertices: m.Vertices, Sources: m.Sources}
+	for t, tri := range m.Triangles {
+		index[t] = -1
+		if keep[t] {
+			index[t] = (int32)(len(ret.Triangles))
+			ret.Triangles = append(ret.Triangles, tri)
+		}
+	}
+	ret.Halfedges = make([]int32, 0, 3*len(ret.Triangles))
+	for t := range m.Triangles {
+		if !keep[t] {
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			h := m.Halfedges[3*t+k]
+			if h < 0 || index[h/3] < 0 {
+				ret.Halfedges = append(ret.Halfedges, -1)
+			} else {
+				ret.Halfedges = append(ret.Halfedges, 3*index[h/3]+h%3)
+			}
+		}
+	}
+	return ret
+}
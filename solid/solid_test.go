@@ -0,0 +1,264 @@
+package solid
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/cloudtest"
+	"github.com/nullstyle/lassloot/internal/lastest"
+	"github.com/nullstyle/lassloot/tin"
+)
+
+// checkClosed verifies that s is a closed, consistently wound manifold: every edge run once in each direction by the
+// triangles either side of it, with a positive volume.  It returns the volume.
+func checkClosed(t *testing.T, s *Solid) float64 {
+	t.Helper()
+
+	edges := make(map[[2]int32]int)
+	volume := 0.0
+	for i, tri := range s.Triangles {
+		for k := 0; k < 3; k++ {
+			a, b := tri[k], tri[(k+1)%3]
+			if a == b {
+				t.Fatalf("triangle %d %v is degenerate", i, tri)
+			}
+			edges[[2]int32{a, b}]++
+		}
+		a, b, c := s.Vertices[tri[0]], s.Vertices[tri[1]], s.Vertices[tri[2]]
+		volume += (a[0]*(b[1]*c[2]-b[2]*c[1]) - a[1]*(b[0]*c[2]-b[2]*c[0]) + a[2]*(b[0]*c[1]-b[1]*c[0])) / 6
+	}
+	for e, n := range edges {
+		if n != 1 || edges[[2]int32{e[1], e[0]}] != 1 {
+			t.Fatalf("edge %v is run %d times and its reverse %d times", e, n, edges[[2]int32{e[1], e[0]}])
+		}
+	}
+	if volume <= 0 {
+		t.Fatalf("volume %v", volume)
+	}
+	if len(s.Parents) != len(s.Vertices) {
+		t.Errorf("%d parents of %d vertices", len(s.Parents), len(s.Vertices))
+	}
+	return volume
+}
+
+func terrain(t *testing.T, n int) *tin.Mesh {
+	rng := rand.New(rand.NewSource(1))
+	var points [][3]float64
+	for i := 0; i < n; i++ {
+		x := math.Round(rng.Float64()*10000) / 100
+		y := math.Round(rng.Float64()*5000) / 100
+		points = append(points, [3]float64{400000 + x, 3000000 + y, 200 + 10*math.Sin(x/10) + y/10})
+	}
+	err, m := tin.Triangulate(points, tin.Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestNew(t *testing.T) {
+	m := terrain(t, 5000)
+	err, s := New(m, Options{Size: 200, Exaggeration: 2, Base: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkClosed(t, s)
+
+	if s.Surface != len(m.Triangles) {
+		t.Errorf("surface of %d triangles, mesh has %d", s.Surface, len(m.Triangles))
+	}
+	var maxX, maxY, minTop float64
+	minTop = math.Inf(1)
+	for i, v := range s.Vertices {
+		maxX, maxY = math.Max(maxX, v[0]), math.Max(maxY, v[1])
+		if v[2] != 0 {
+			minTop = math.Min(minTop, v[2])
+			p := m.Vertices[s.Parents[i]]
+			if want := (p[2]-s.Origin[2])*s.Scale*2 + 3; math.Abs(v[2]-want) > 1e-9 {
+				t.Fatalf("vertex %d at %v, want height %v", i, v, want)
+			}
+		}
+	}
+	if math.Abs(maxX-200) > 1e-9 || maxY > 200 {
+		t.Errorf("extent %v by %v, want 200 along x", maxX, maxY)
+	}
+	if minTop != 3 {
+		t.Errorf("lowest point of the surface at %v, want the base thickness", minTop)
+	}
+}
+
+func TestNewLattice(t *testing.T) {
+	// the hull of a lattice runs through rows of collinear points, each of which the walls must meet
+	var points [][3]float64
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			points = append(points, [3]float64{(float64)(x), (float64)(y), (float64)(x*y) / 10})
+		}
+	}
+	err, m := tin.Triangulate(points, tin.Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, s := New(m, Options{Size: 90, Base: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkClosed(t, s)
+
+	// 36 boundary vertices, each with two wall triangles, and a floor of 34
+	if want := 2*9*9 + 2*36 + 34; len(s.Triangles) != want {
+		t.Errorf("%d triangles, want %d", len(s.Triangles), want)
+	}
+}
+
+func TestNewBoundary(t *testing.T) {
+	m := terrain(t, 5000)
+	o := [2]float64{400000, 3000000}
+
+	// an L with a sliver jutting from its inner corner at a sharp angle, clockwise and closed
+	boundary := [][2]float64{{10, 10}, {10, 45}, {30, 45}, {30, 20}, {60, 21}, {60, 20}, {90, 20}, {90, 10}, {10, 10}}
+	for i := range boundary {
+		boundary[i][0] += o[0]
+		boundary[i][1] += o[1]
+	}
+	err, s := New(m, Options{Size: 100, Base: 2, Boundary: boundary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	volume := checkClosed(t, s)
+
+	// the surface covers exactly the polygon
+	area := 0.0
+	for _, tri := range s.Triangles[:s.Surface] {
+		a, b, c := s.Vertices[tri[0]], s.Vertices[tri[1]], s.Vertices[tri[2]]
+		area += ((b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])) / 2
+	}
+	want := (20*35 + 60*10 + 15) * s.Scale * s.Scale
+	if math.Abs(area-want) > 1e-6*want {
+		t.Errorf("surface covers %v square millimeters, want %v", area, want)
+	}
+	if volume < want*2 {
+		t.Errorf("volume %v is less than the base", volume)
+	}
+	if s.Scale != 100.0/80 {
+		t.Errorf("scale %v, want the boundary's 80 units to span 100mm", s.Scale)
+	}
+}
+
+func TestNewBoundaryShapes(t *testing.T) {
+	// boundaries off the axes, whose densified edges run through points nearly collinear after rounding
+	m := terrain(t, 5000)
+	for name, boundary := range map[string][][2]float64{
+		"slanted quad":  {{10, 10}, {90, 20}, {80, 45}, {20, 42.5}},
+		"rotated":       {{12.3, 7.1}, {61.7, 2.9}, {88.2, 31.4}, {40.9, 47.3}},
+		"concave":       {{5, 5}, {50, 30}, {95, 5}, {70, 45}, {50, 35}, {30, 45}},
+		"long triangle": {{3, 3}, {97, 47}, {3, 47}},
+	} {
+		want := 0.0
+		for i := range boundary {
+			a, b := boundary[i], boundary[(i+1)%len(boundary)]
+			want += (a[0]*b[1] - b[0]*a[1]) / 2
+		}
+		for i := range boundary {
+			boundary[i][0] += 400000
+			boundary[i][1] += 3000000
+		}
+		err, s := New(m, Options{Size: 100, Base: 2, Boundary: boundary})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		checkClosed(t, s)
+
+		area := 0.0
+		for _, tri := range s.Triangles[:s.Surface] {
+			a, b, c := s.Vertices[tri[0]], s.Vertices[tri[1]], s.Vertices[tri[2]]
+			area += ((b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])) / 2
+		}
+		if want *= s.Scale * s.Scale; math.Abs(math.Abs(area)-math.Abs(want)) > 1e-6*math.Abs(want) {
+			t.Errorf("%s: surface covers %v square millimeters, want %v", name, area, want)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	m := terrain(t, 500)
+	o := [2]float64{400000, 3000000}
+	ring := func(xy ...float64) [][2]float64 {
+		var ret [][2]float64
+		for i := 0; i < len(xy); i += 2 {
+			ret = append(ret, [2]float64{o[0] + xy[i], o[1] + xy[i+1]})
+		}
+		return ret
+	}
+
+	for name, opts := range map[string]Options{
+		"no base":      {Size: 100},
+		"no size":      {Base: 1},
+		"outside":      {Size: 100, Base: 1, Boundary: ring(50, 10, 150, 10, 150, 20, 50, 20)},
+		"too few":      {Size: 100, Base: 1, Boundary: ring(10, 10, 20, 10, 10, 10)},
+		"self crossed": {Size: 100, Base: 1, Boundary: ring(10, 10, 20, 20, 20, 10, 10, 20)},
+	} {
+		if err, _ := New(m, opts); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestColors(t *testing.T) {
+	var points []lastest.Point
+	for i := 0; i < 25; i++ {
+		class := (byte)(2)
+		if i == 12 {
+			class = 6
+		}
+		points = append(points, lastest.Point{
+			X: (int32)(i%5) * 100, Y: (int32)(i/5) * 100, Z: (int32)(i) * 10, Classification: class,
+			Red: (uint16)(i) << 8, Green: 0xff00, Blue: 0x1200,
+		})
+	}
+	pc := cloudtest.PointCloud(t, 3, points)
+	err, m := tin.New(pc, tin.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, s := New(m, Options{Size: 50, Base: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []tin.Coloring{tin.Hypsometric, tin.RGB, tin.Classification} {
+		err, colors := s.Colors(m, pc, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(colors) != len(s.Vertices) {
+			t.Fatalf("%s: %d colors of %d vertices", c, len(colors), len(s.Vertices))
+		}
+		for i, color := range colors {
+			point := m.Sources[s.Parents[i]]
+			var want [3]uint8
+			switch c {
+			case tin.Hypsometric:
+				want = tin.HypsometricColor((float64)(point) / 24)
+			case tin.RGB:
+				want = [3]uint8{(uint8)(point), 0xff, 0x12}
+			case tin.Classification:
+				want = lassloot.ClassificationColor(2)
+				if point == 12 {
+					want = lassloot.ClassificationColor(6)
+				}
+			}
+			if color != want {
+				t.Fatalf("%s: vertex %d from point %d is %v, want %v", c, i, point, color, want)
+			}
+		}
+	}
+
+	pc = cloudtest.PointCloud(t, 1, points)
+	if err, _ := s.Colors(m, pc, tin.RGB); err == nil {
+		t.Error("colored by rgb that the points lack")
+	}
+}
@@ -0,0 +1,110 @@
+package lassloot
+
+import (
+	"math"
+	"strings"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// DimensionStats summarizes the values of a single dimension across a pointcloud.  Points holding the dimension's
+// no data value are not counted.
+type DimensionStats struct {
+	Name   string
+	Count  uint64
+	Min    float64
+	Max    float64
+	Mean   float64
+	StdDev float64
+
+	// m2 is the running sum of squared differences from the mean.
+	m2 float64
+}
+
+// Stats summarizes every numeric dimension of a pointcloud along with histograms of its classifications and return
+// numbers.
+type Stats struct {
+	Count      uint64
+	Dimensions []DimensionStats
+
+	// Classifications and ReturnNumbers count points by classification and by return number.
+	Classifications map[uint8]uint64
+	ReturnNumbers   map[uint8]uint64
+}
+
+// Stats computes the minimum, maximum, mean and standard deviation of each numeric dimension, with scales and
+// offsets applied, in a single pass over the points.  Undocumented extra bytes are skipped.
+func (pc *PointCloud) Stats() *Stats {
+	s := &Stats{
+		Classifications: map[uint8]uint64{},
+		ReturnNumbers:   map[uint8]uint64{},
+	}
+
+	var dims []*las14.Dimension
+	for i := range pc.schema.Dimensions {
+		d := &pc.schema.Dimensions[i]
+		if d.Bits == 0 && d.Type == las14.TypeUndocumented {
+			continue
+		}
+		dims = append(dims, d)
+		s.Dimensions = append(s.Dimensions, DimensionStats{Name: d.Name, Min: math.Inf(1), Max: math.Inf(-1)})
+	}
+
+	class, _ := pc.schema.Dimension(las14.DimClassification)
+	ret, _ := pc.schema.Dimension(las14.DimReturnNumber)
+
+	l := pc.Len()
+	for i := (uint64)(0); i < l; i++ {
+		raw := pc.fr.PointDataRecord(i).Raw
+		s.Count++
+
+		for j, d := range dims {
+			if d.IsNoData(raw) {
+				continue
+			}
+			s.Dimensions[j].add(d.Float64(raw))
+		}
+
+		if class != nil {
+			s.Classifications[(uint8)(class.RawFloat64(raw))]++
+		}
+		if ret != nil {
+			s.ReturnNumbers[(uint8)(ret.RawFloat64(raw))]++
+		}
+	}
+
+	for i := range s.Dimensions {
+		s.Dimensions[i].finish()
+	}
+
+	return s
+}
+
+// Dimension returns the statistics of the dimension named name, matched case-insensitively like Schema.Dimension.
+func (s *Stats) Dimension(name string) (*DimensionStats, bool) {
+	for i := range s.Dimensions {
+		if strings.EqualFold(s.Dimensions[i].Name, name) {
+			return &s.Dimensions[i], true
+		}
+	}
+	return nil, false
+}
+
+// add accumulates v using Welford's algorithm, which stays accurate for large coordinates far from zero.
+func (ds *DimensionStats) add(v float64) {
+	ds.Count++
+	ds.Min = math.Min(ds.Min, v)
+	ds.Max = math.Max(ds.Max, v)
+
+	delta := v - ds.Mean
+	ds.Mean += delta / (float64)(ds.Count)
+	ds.m2 += delta * (v - ds.Mean)
+}
+
+func (ds *DimensionStats) finish() {
+	if ds.Count == 0 {
+		ds.Min, ds.Max = 0, 0
+		return
+	}
+	ds.StdDev = math.Sqrt(ds.m2 / (float64)(ds.Count))
+}
@@ -0,0 +1,46 @@
+package lassloot
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestStats(t *testing.T) {
+	points := []lastest.Point{
+		{X: 0, Y: 0, Z: 100, Intensity: 10, Classification: 2, ReturnNumber: 1, NumberOfReturns: 2},
+		{X: 100, Y: 200, Z: 200, Intensity: 20, Classification: 2, ReturnNumber: 2, NumberOfReturns: 2},
+		{X: 200, Y: 400, Z: 300, Intensity: 30, Classification: 5, ReturnNumber: 1, NumberOfReturns: 1},
+	}
+	pc := newTestPointCloud(t, lastest.Spec{
+		Header: lastest.Header{PointDataRecordFormat: 6, XOffset: 1000},
+		Points: points,
+	})
+
+	s := pc.Stats()
+	if s.Count != 3 {
+		t.Fatalf("count = %d", s.Count)
+	}
+
+	x, ok := s.Dimension("x")
+	if !ok {
+		t.Fatal("x stats missing")
+	}
+	if x.Min != 1000 || x.Max != 1002 || math.Abs(x.Mean-1001) > 1e-9 || math.Abs(x.StdDev-math.Sqrt(2.0/3)) > 1e-9 {
+		t.Errorf("x = %+v", x)
+	}
+
+	intensity, _ := s.Dimension(las14.DimIntensity)
+	if intensity.Min != 10 || intensity.Max != 30 || intensity.Mean != 20 {
+		t.Errorf("intensity = %+v", intensity)
+	}
+
+	if s.Classifications[2] != 2 || s.Classifications[5] != 1 {
+		t.Errorf("classifications = %v", s.Classifications)
+	}
+	if s.ReturnNumbers[1] != 2 || s.ReturnNumbers[2] != 1 {
+		t.Errorf("return numbers = %v", s.ReturnNumbers)
+	}
+}
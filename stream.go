@@ -0,0 +1,172 @@
+package lassloot
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// DefaultStreamBatchSize is the number of points a PointStream reads at a time.
+const DefaultStreamBatchSize = 1 << 16
+
+// PointStream reads a LAS file's points in batches, holding only one batch in memory at a time, for tools that visit
+// every point once and don't need the whole cloud decoded up front.
+type PointStream struct {
+	// pc holds the file's header, records and schema, but no point data
+	pc *PointCloud
+	d  *las14.ReaderAtDecoder
+
+	filter    *Filter
+	batchSize uint64
+	batch     *las14.PointRange
+	next      uint64
+	total     uint64
+
+	closer io.Closer
+}
+
+// NewPointStream prepares to stream the points of the LAS file held in the size bytes of r.  The header and records
+// are decoded immediately.
+func NewPointStream(r io.ReaderAt, size int64) (error, *PointStream) {
+	d := las14.NewReaderAtDecoder(r, size)
+
+	err, fp := d.FirstPassDecode()
+	if err != nil {
+		return err, nil
+	}
+	err, vlrs := d.VariableLengthRecords()
+	if err != nil {
+		return err, nil
+	}
+	err, evlrs := d.ExtendedVariableLengthRecords()
+	if err != nil {
+		return err, nil
+	}
+
+	fr := &las14.FullResult{
+		FirstPassResult:               *fp,
+		VariableLengthRecords:         vlrs,
+		ExtendedVariableLengthRecords: evlrs,
+	}
+	err, pc := newPointCloud(fr)
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, &PointStream{
+		pc:        pc,
+		d:         d,
+		batchSize: DefaultStreamBatchSize,
+		total:     fp.Header.PointCount(),
+	}
+}
+
+// OpenPointStream opens the LAS file at path for streaming.  Close the stream to close the file.
+func OpenPointStream(path string) (error, *PointStream) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err, nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err, nil
+	}
+
+	err, ps := NewPointStream(f, info.Size())
+	if err != nil {
+		f.Close()
+		return err, nil
+	}
+
+	ps.closer = f
+	return nil, ps
+}
+
+// Close releases the file opened by OpenPointStream.
+func (ps *PointStream) Close() error {
+	if ps.closer == nil {
+		return nil
+	}
+	return ps.closer.Close()
+}
+
+// Header returns the file's header.
+func (ps *PointStream) Header() *Header {
+	return ps.pc.Header()
+}
+
+// Schema returns the description of the dimensions stored in each point.
+func (ps *PointStream) Schema() *las14.Schema {
+	return ps.pc.Schema()
+}
+
+// Precision returns the scale factors and offsets recorded in the file's header.
+func (ps *PointStream) Precision() Precision {
+	return ps.pc.Precision()
+}
+
+// CRS returns the file's coordinate reference system, or nil when it doesn't record one.
+func (ps *PointStream) CRS() (error, *CRS) {
+	return ps.pc.CRS()
+}
+
+// SetGPSWeek sets the GPS week that the GPS week time of a file without FlagGPSTime counts from.  See
+// PointCloud.SetGPSWeek.
+func (ps *PointStream) SetGPSWeek(week int) {
+	ps.pc.SetGPSWeek(week)
+}
+
+// SetFilter restricts the points returned by Next to those matching f.
+func (ps *PointStream) SetFilter(f *Filter) {
+	if f != nil && f.IsEmpty() {
+		f = nil
+	}
+	ps.filter = f
+}
+
+// SetBatchSize sets the number of points read from the file at a time.
+func (ps *PointStream) SetBatchSize(n uint64) {
+	if n == 0 {
+		n = 1
+	}
+	ps.batchSize = n
+}
+
+// Len returns the number of points in the file, before any filter is applied.
+func (ps *PointStream) Len() uint64 {
+	return ps.total
+}
+
+// Next returns the next point of the file, or io.EOF once every point has been returned.  The point remains valid
+// after later calls to Next.
+func (ps *PointStream) Next() (error, *Point) {
+	for {
+		if ps.next >= ps.total {
+			return io.EOF, nil
+		}
+
+		if ps.batch == nil || ps.next >= ps.batch.Start+ps.batch.Count {
+			count := ps.batchSize
+			if remaining := ps.total - ps.next; count > remaining {
+				count = remaining
+			}
+
+			err, batch := ps.d.PointRange(ps.next, count)
+			if err != nil {
+				return fmt.Errorf("failed to read points: %w", err), nil
+			}
+			ps.batch = batch
+		}
+
+		p := &Point{PDR: ps.batch.PointDataRecord(ps.next - ps.batch.Start), pc: ps.pc}
+		ps.next++
+
+		if ps.filter == nil || ps.filter.Match(p) {
+			return nil, p
+		}
+	}
+}
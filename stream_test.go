@@ -0,0 +1,49 @@
+package lassloot
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func TestPointStreamMatchesPointCloud(t *testing.T) {
+	points := make([]lastest.Point, 1000)
+	for i := range points {
+		points[i] = lastest.PatternPoint(i)
+	}
+	file := lastest.Generate(lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 7}, Points: points})
+
+	pc := newTestPointCloud(t, lastest.Spec{Header: lastest.Header{PointDataRecordFormat: 7}, Points: points})
+	filter := &Filter{Classes: []uint8{2, 5}}
+	_, want := pc.Filter(filter)
+
+	err, ps := NewPointStream(bytes.NewReader(file.Bytes), (int64)(len(file.Bytes)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps.SetBatchSize(7)
+	ps.SetFilter(filter)
+
+	var i uint64
+	for {
+		err, p := ps.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, wp := want.PointAt(i)
+		if !bytes.Equal(p.PDR.Raw, wp.PDR.Raw) {
+			t.Fatalf("point %d differs", i)
+		}
+		i++
+	}
+
+	if i != want.Len() {
+		t.Errorf("streamed %d points, want %d", i, want.Len())
+	}
+}
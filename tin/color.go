@@ -0,0 +1,119 @@
+package tin
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// Coloring chooses the color of each vertex of a mesh, for color printing and textures.
+type Coloring int
+
+const (
+	// Hypsometric tints the surface by elevation, from lowland green through brown to white peaks.
+	Hypsometric Coloring = iota
+
+	// RGB takes the colors of the points, which formats 2, 3, 5, 7, 8 and 10 carry.
+	RGB
+
+	// Classification draws each point's class in the colors the viewers use.
+	Classification
+)
+
+var coloringNames = [...]string{"elevation", "rgb", "classification"}
+
+func (c Coloring) String() string {
+	if c < 0 || (int)(c) >= len(coloringNames) {
+		return fmt.Sprintf("Coloring(%d)", c)
+	}
+	return coloringNames[c]
+}
+
+// ParseColoring returns the coloring named s, as String returns.
+func ParseColoring(s string) (error, Coloring) {
+	for i, name := range coloringNames {
+		if strings.EqualFold(s, name) {
+			return nil, (Coloring)(i)
+		}
+	}
+	return fmt.Errorf("unknown coloring %q: want %s", s, strings.Join(coloringNames[:], ", ")), Hypsometric
+}
+
+// hypsometricRamp runs from the lowest elevation of a surface to its highest.
+var hypsometricRamp = [][3]float64{
+	{40, 110, 60}, {110, 160, 80}, {200, 200, 120}, {190, 150, 90}, {140, 100, 70}, {245, 245, 240},
+}
+
+// HypsometricColor returns the color of the hypsometric tint t of the way from a surface's lowest elevation to its
+// highest.
+func HypsometricColor(t float64) [3]uint8 {
+	t = math.Min(1, math.Max(0, t)) * (float64)(len(hypsometricRamp)-1)
+	i := (int)(math.Min((float64)(len(hypsometricRamp)-2), math.Floor(t)))
+	f := t - (float64)(i)
+
+	var ret [3]uint8
+	for c := range ret {
+		ret[c] = (uint8)(math.Round(hypsometricRamp[i][c] + (hypsometricRamp[i+1][c]-hypsometricRamp[i][c])*f))
+	}
+	return ret
+}
+
+// Colors returns a color for each vertex of m.  RGB and Classification look up the point each vertex was made from in
+// pc, the pointcloud m was made from.
+func (m *Mesh) Colors(pc *lassloot.PointCloud, c Coloring) (error, [][3]uint8) {
+	ret := make([][3]uint8, len(m.Vertices))
+
+	switch c {
+	case Hypsometric:
+		minZ, maxZ := math.Inf(1), math.Inf(-1)
+		for _, v := range m.Vertices {
+			minZ, maxZ = math.Min(minZ, v[2]), math.Max(maxZ, v[2])
+		}
+		span := math.Max(maxZ-minZ, 1e-9)
+		for i, v := range m.Vertices {
+			ret[i] = HypsometricColor((v[2] - minZ) / span)
+		}
+		return nil, ret
+
+	case RGB, Classification:
+		schema := pc.Schema()
+		var dims []*las14.Dimension
+		names := []string{las14.DimRed, las14.DimGreen, las14.DimBlue}
+		if c == Classification {
+			names = []string{las14.DimClassification}
+		}
+		for _, name := range names {
+			d, ok := schema.Dimension(name)
+			if !ok {
+				return fmt.Errorf("points have no %s to color by", name), nil
+			}
+			dims = append(dims, d)
+		}
+		shift := 0
+		if c == RGB && pc.ColorDepth() > 8 {
+			shift = 8
+		}
+
+		for i, s := range m.Sources {
+			err, pt := pc.PointAt(s)
+			if err != nil {
+				return err, nil
+			}
+			raw := pt.PDR.Raw
+			if c == Classification {
+				ret[i] = lassloot.ClassificationColor((uint8)(dims[0].RawFloat64(raw)))
+				continue
+			}
+			for k, d := range dims {
+				ret[i][k] = (uint8)((uint16)(d.RawFloat64(raw)) >> shift)
+			}
+		}
+		return nil, ret
+
+	default:
+		return fmt.Errorf("unknown coloring %v", c), nil
+	}
+}
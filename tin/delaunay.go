@@ -0,0 +1,328 @@
+package tin
+
+import (
+	"math"
+	"sort"
+)
+
+// delaunay triangulates points in the plane with the sweep-hull algorithm of Delaunator: points are added in order of
+// their distance from a seed triangle, each joined to the edges of the convex hull it sees, and edges are flipped until
+// every triangle's circumcircle is empty.  Triangles wind clockwise.  The points must be distinct.
+type delaunay struct {
+	xy [][2]float64
+
+	triangles []int32
+	halfedges []int32
+
+	// the convex hull, as a doubly linked list of points with a hash of their angle about the center for finding the
+	// hull edges a new point sees
+	hullPrev, hullNext, hullTri []int32
+	hullHash                    []int32
+	hullStart                   int32
+	cx, cy                      float64
+
+	edges []int32
+}
+
+// triangulate triangulates points, returning nil when there are fewer than three or they are all collinear.
+func triangulate(xy [][2]float64) *delaunay {
+	n := len(xy)
+	if n < 3 {
+		return nil
+	}
+
+	d := &delaunay{
+		xy:        xy,
+		triangles: make([]int32, 0, 3*(2*n-5)),
+		halfedges: make([]int32, 0, 3*(2*n-5)),
+		hullPrev:  make([]int32, n),
+		hullNext:  make([]int32, n),
+		hullTri:   make([]int32, n),
+		hullHash:  make([]int32, (int)(math.Ceil(math.Sqrt((float64)(n))))),
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range xy {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	cx, cy := (minX+maxX)/2, (minY+maxY)/2
+
+	// seed with the point nearest the center, the point nearest it and the point making the smallest circle with both
+	i0, i1, i2 := -1, -1, -1
+	best := math.Inf(1)
+	for i, p := range xy {
+		if dd := dist2(cx, cy, p[0], p[1]); dd < best {
+			i0, best = i, dd
+		}
+	}
+	best = math.Inf(1)
+	for i, p := range xy {
+		if i == i0 {
+			continue
+		}
+		if dd := dist2(xy[i0][0], xy[i0][1], p[0], p[1]); dd < best && dd > 0 {
+			i1, best = i, dd
+		}
+	}
+	best = math.Inf(1)
+	for i, p := range xy {
+		if i == i0 || i == i1 || orient2d(xy[i0][0], xy[i0][1], xy[i1][0], xy[i1][1], p[0], p[1]) == 0 {
+			continue
+		}
+		if r := circumradius2(xy[i0], xy[i1], p); r < best {
+			i2, best = i, r
+		}
+	}
+	if i2 < 0 {
+		return nil
+	}
+	if orient2d(xy[i0][0], xy[i0][1], xy[i1][0], xy[i1][1], xy[i2][0], xy[i2][1]) > 0 {
+		i1, i2 = i2, i1
+	}
+
+	d.cx, d.cy = circumcenter(xy[i0], xy[i1], xy[i2])
+	ids := make([]int32, n)
+	dists := make([]float64, n)
+	for i, p := range xy {
+		ids[i] = (int32)(i)
+		dists[i] = dist2(d.cx, d.cy, p[0], p[1])
+	}
+	sort.Sort(&byDistance{ids, dists})
+
+	d.hullStart = (int32)(i0)
+	d.hullNext[i0], d.hullPrev[i2] = (int32)(i1), (int32)(i1)
+	d.hullNext[i1], d.hullPrev[i0] = (int32)(i2), (int32)(i2)
+	d.hullNext[i2], d.hullPrev[i1] = (int32)(i0), (int32)(i0)
+	d.hullTri[i0], d.hullTri[i1], d.hullTri[i2] = 0, 1, 2
+	for i := range d.hullHash {
+		d.hullHash[i] = -1
+	}
+	for _, i := range [...]int{i0, i1, i2} {
+		d.hullHash[d.hashKey(xy[i][0], xy[i][1])] = (int32)(i)
+	}
+	d.addTriangle((int32)(i0), (int32)(i1), (int32)(i2), -1, -1, -1)
+
+	for _, i := range ids {
+		if (int)(i) == i0 || (int)(i) == i1 || (int)(i) == i2 {
+			continue
+		}
+		x, y := xy[i][0], xy[i][1]
+
+		// find an edge of the hull the point sees, starting near its angle
+		var start int32
+		key := d.hashKey(x, y)
+		for j := range d.hullHash {
+			start = d.hullHash[(key+j)%len(d.hullHash)]
+			if start != -1 && start != d.hullNext[start] {
+				break
+			}
+		}
+		start = d.hullPrev[start]
+		e := start
+		for {
+			q := d.hullNext[e]
+			if orient2d(x, y, xy[e][0], xy[e][1], xy[q][0], xy[q][1]) > 0 {
+				break
+			}
+			e = q
+			if e == start {
+				e = -1
+				break
+			}
+		}
+		if e == -1 {
+			// only possible were the point a duplicate
+			continue
+		}
+
+		// join the point to the edge, then walk the hull forwards and backwards adding triangles
+		t := d.addTriangle(e, i, d.hullNext[e], -1, -1, d.hullTri[e])
+		d.hullTri[i] = d.legalize(t + 2)
+		d.hullTri[e] = t
+
+		next := d.hullNext[e]
+		for {
+			q := d.hullNext[next]
+			if orient2d(x, y, xy[next][0], xy[next][1], xy[q][0], xy[q][1]) <= 0 {
+				break
+			}
+			t := d.addTriangle(next, i, q, d.hullTri[i], -1, d.hullTri[next])
+			d.hullTri[i] = d.legalize(t + 2)
+			d.hullNext[next] = next
+			next = q
+		}
+		if e == start {
+			for {
+				q := d.hullPrev[e]
+				if orient2d(x, y, xy[q][0], xy[q][1], xy[e][0], xy[e][1]) <= 0 {
+					break
+				}
+				t := d.addTriangle(q, i, e, -1, d.hullTri[e], d.hullTri[q])
+				d.legalize(t + 2)
+				d.hullTri[q] = t
+				d.hullNext[e] = e
+				e = q
+			}
+		}
+
+		d.hullStart, d.hullPrev[i] = e, e
+		d.hullNext[e], d.hullPrev[next] = i, i
+		d.hullNext[i] = next
+		d.hullHash[d.hashKey(x, y)] = i
+		d.hullHash[d.hashKey(xy[e][0], xy[e][1])] = e
+	}
+
+	return d
+}
+
+// hull returns the points of the convex hull in order.
+func (d *delaunay) hull() []int32 {
+	var ret []int32
+	e := d.hullStart
+	for {
+		ret = append(ret, e)
+		e = d.hullNext[e]
+		if e == d.hullStart {
+			return ret
+		}
+	}
+}
+
+func (d *delaunay) hashKey(x, y float64) int {
+	dx, dy := x-d.cx, y-d.cy
+	// a pseudo-angle, increasing monotonically with the angle from 0 to 1
+	p := dx / (math.Abs(dx) + math.Abs(dy))
+	var angle float64
+	if dy > 0 {
+		angle = (3 - p) / 4
+	} else {
+		angle = (1 + p) / 4
+	}
+	if math.IsNaN(angle) {
+		angle = 0
+	}
+	n := len(d.hullHash)
+	return (int)(math.Floor(angle*(float64)(n))) % n
+}
+
+func (d *delaunay) link(a, b int32) {
+	d.halfedges[a] = b
+	if b != -1 {
+		d.halfedges[b] = a
+	}
+}
+
+func (d *delaunay) addTriangle(i0, i1, i2, a, b, c int32) int32 {
+	t := (int32)(len(d.triangles))
+	d.triangles = append(d.triangles, i0, i1, i2)
+	d.halfedges = append(d.halfedges, -1, -1, -1)
+	d.link(t, a)
+	d.link(t+1, b)
+	d.link(t+2, c)
+	return t
+}
+
+// legalize flips the edge a, and those beyond it in turn, until their triangles are Delaunay.  It returns the edge
+// that ends up opposite a's triangle's third point.
+func (d *delaunay) legalize(a int32) int32 {
+	xy := d.xy
+	edges := d.edges[:0]
+	var ar int32
+	for {
+		b := d.halfedges[a]
+		a0 := a - a%3
+		ar = a0 + (a+2)%3
+
+		if b == -1 {
+			if len(edges) == 0 {
+				break
+			}
+			a, edges = edges[len(edges)-1], edges[:len(edges)-1]
+			continue
+		}
+
+		b0 := b - b%3
+		al := a0 + (a+1)%3
+		bl := b0 + (b+2)%3
+		p0, pr, pl, p1 := d.triangles[ar], d.triangles[a], d.triangles[al], d.triangles[bl]
+
+		// the triangles wind clockwise, so a point within the circle is negative
+		if incircle(xy[p0][0], xy[p0][1], xy[pr][0], xy[pr][1], xy[pl][0], xy[pl][1], xy[p1][0], xy[p1][1]) < 0 {
+			d.triangles[a] = p1
+			d.triangles[b] = p0
+
+			hbl := d.halfedges[bl]
+			if hbl == -1 {
+				// the edge flipped was on the hull on the far side; fix the hull's reference to it
+				e := d.hullStart
+				for {
+					if d.hullTri[e] == bl {
+						d.hullTri[e] = a
+						break
+					}
+					e = d.hullPrev[e]
+					if e == d.hullStart {
+						break
+					}
+				}
+			}
+			d.link(a, hbl)
+			d.link(b, d.halfedges[ar])
+			d.link(ar, bl)
+
+			edges = append(edges, b0+(b+1)%3)
+		} else {
+			if len(edges) == 0 {
+				break
+			}
+			a, edges = edges[len(edges)-1], edges[:len(edges)-1]
+		}
+	}
+	d.edges = edges
+	return ar
+}
+
+func dist2(ax, ay, bx, by float64) float64 {
+	dx, dy := ax-bx, ay-by
+	return dx*dx + dy*dy
+}
+
+// circumradius2 returns the square of the radius of the circle through a, b and c, or +Inf when they are collinear.
+func circumradius2(a, b, c [2]float64) float64 {
+	x, y := circumcenter(a, b, c)
+	r := dist2(x, y, a[0], a[1])
+	if math.IsNaN(r) {
+		return math.Inf(1)
+	}
+	return r
+}
+
+func circumcenter(a, b, c [2]float64) (float64, float64) {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	ex, ey := c[0]-a[0], c[1]-a[1]
+	bl := dx*dx + dy*dy
+	cl := ex*ex + ey*ey
+	k := 0.5 / (dx*ey - dy*ex)
+	return a[0] + (ey*bl-dy*cl)*k, a[1] + (dx*cl-ex*bl)*k
+}
+
+// byDistance sorts point ids by their distance from the seed's circumcenter.
+type byDistance struct {
+	ids   []int32
+	dists []float64
+}
+
+func (s *byDistance) Len() int {
+	return len(s.ids)
+}
+
+func (s *byDistance) Less(i, j int) bool {
+	return s.dists[i] < s.dists[j]
+}
+
+func (s *byDistance) Swap(i, j int) {
+	s.ids[i], s.ids[j] = s.ids[j], s.ids[i]
+	s.dists[i], s.dists[j] = s.dists[j], s.dists[i]
+}
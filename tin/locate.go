@@ -0,0 +1,76 @@
+package tin
+
+// Locate returns the triangle containing x, y, or -1 when it lies outside the mesh.  The search walks from triangle
+// start towards the point, so it is quickest when start is near, such as the triangle found for the previous of a run
+// of nearby points.  Points on an edge shared by two triangles may be found in either.
+func (m *Mesh) Locate(x, y float64, start int) int {
+	if len(m.Triangles) == 0 {
+		return -1
+	}
+	if start < 0 || start >= len(m.Triangles) {
+		start = 0
+	}
+
+	// a walk through a Delaunay triangulation always reaches the point; the bound guards meshes built otherwise
+	t := start
+	for steps := 0; steps <= len(m.Triangles); steps++ {
+		tri := m.Triangles[t]
+		next := -1
+		for k := 0; k < 3; k++ {
+			a, b := m.Vertices[tri[k]], m.Vertices[tri[(k+1)%3]]
+			if orient2d(a[0], a[1], b[0], b[1], x, y) < 0 {
+				h := m.Halfedges[3*t+k]
+				if h < 0 {
+					return -1
+				}
+				next = (int)(h / 3)
+				break
+			}
+		}
+		if next < 0 {
+			return t
+		}
+		t = next
+	}
+
+	for t := range m.Triangles {
+		if m.contains(t, x, y) {
+			return t
+		}
+	}
+	return -1
+}
+
+func (m *Mesh) contains(t int, x, y float64) bool {
+	tri := m.Triangles[t]
+	for k := 0; k < 3; k++ {
+		a, b := m.Vertices[tri[k]], m.Vertices[tri[(k+1)%3]]
+		if orient2d(a[0], a[1], b[0], b[1], x, y) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Interpolate returns the elevation of the surface at x, y, on the plane of the triangle containing it, and the
+// triangle.  It returns false when x, y lies outside the mesh.  See Locate for start.
+func (m *Mesh) Interpolate(x, y float64, start int) (float64, int, bool) {
+	t := m.Locate(x, y, start)
+	if t < 0 {
+		return 0, -1, false
+	}
+	a, b, c := m.Triangle(t)
+	return planeZ(a, b, c, x, y), t, true
+}
+
+// planeZ returns the elevation at x, y of the plane through a, b and c, weighting each vertex by its barycentric
+// coordinate.
+func planeZ(a, b, c [3]float64, x, y float64) float64 {
+	det := (b[1]-c[1])*(a[0]-c[0]) + (c[0]-b[0])*(a[1]-c[1])
+	if det == 0 {
+		return (a[2] + b[2] + c[2]) / 3
+	}
+	wa := ((b[1]-c[1])*(x-c[0]) + (c[0]-b[0])*(y-c[1])) / det
+	wb := ((c[1]-a[1])*(x-c[0]) + (a[0]-c[0])*(y-c[1])) / det
+	return wa*a[2] + wb*b[2] + (1-wa-wb)*c[2]
+}
@@ -0,0 +1,85 @@
+package tin
+
+import "math"
+
+// NaturalNeighbor returns the elevation of the surface at x, y by Sibson's natural neighbor interpolation, and the
+// triangle containing it.  It returns false when x, y lies outside the mesh.  See Locate for start.
+//
+// Inserting x, y into the triangulation would give it a Voronoi cell carved from those of the vertices around it, its
+// natural neighbors, and each is weighted by the area taken from its cell.  Unlike Interpolate's planes, the surface is
+// smooth everywhere except at the vertices, and it still passes through every vertex and stays within the elevations
+// of the neighbors.
+func (m *Mesh) NaturalNeighbor(x, y float64, start int) (float64, int, bool) {
+	t := m.Locate(x, y, start)
+	if t < 0 {
+		return 0, -1, false
+	}
+
+	// the triangles whose circumcircles hold x, y are those inserting it would replace, a cavity around it reached
+	// from the triangle containing it
+	cavity := []int{t}
+	in := map[int]bool{t: true}
+	for i := 0; i < len(cavity); i++ {
+		for k := 0; k < 3; k++ {
+			h := m.Halfedges[3*cavity[i]+k]
+			if h < 0 || in[(int)(h/3)] {
+				continue
+			}
+			n := (int)(h / 3)
+			tri := m.Triangles[n]
+			a, b, c := m.Vertices[tri[0]], m.Vertices[tri[1]], m.Vertices[tri[2]]
+			if incircle(a[0], a[1], b[0], b[1], c[0], c[1], x, y) > 0 {
+				in[n] = true
+				cavity = append(cavity, n)
+			}
+		}
+	}
+
+	// the area a neighbor v loses to x, y is that of the polygon from the circumcenter of x, y, v and the vertex
+	// before v around the cavity, through the circumcenters of the cavity's triangles at v, to that of x, y, v and the
+	// vertex after it; summed over the cavity's triangles, each contributes the triangle of its own circumcenter and
+	// those of x, y with each of its edges at v.  Positions are taken relative to x, y for precision.
+	weights := make(map[int32]float64)
+	var total float64
+	for _, n := range cavity {
+		tri := m.Triangles[n]
+		var p [3][2]float64
+		for k, v := range tri {
+			p[k] = [2]float64{m.Vertices[v][0] - x, m.Vertices[v][1] - y}
+			if p[k] == [2]float64{} {
+				return m.Vertices[v][2], t, true
+			}
+		}
+		// on an edge, the interpolation is linear along it
+		for k := 0; k < 3; k++ {
+			a, b := p[k], p[(k+1)%3]
+			if orient2d(a[0], a[1], b[0], b[1], 0, 0) == 0 {
+				a, b, c := m.Triangle(t)
+				return planeZ(a, b, c, x, y), t, true
+			}
+		}
+
+		cx, cy := circumcenter(p[0], p[1], p[2])
+		var edges [3][2]float64
+		for k := 0; k < 3; k++ {
+			edges[k][0], edges[k][1] = circumcenter([2]float64{}, p[k], p[(k+1)%3])
+		}
+		for k, v := range tri {
+			// vertex k's edges run to vertex k+1, and from vertex k+2
+			a, b := edges[k], edges[(k+2)%3]
+			area := ((a[0]-cx)*(b[1]-cy) - (b[0]-cx)*(a[1]-cy)) / 2
+			weights[v] += area
+			total += area
+		}
+	}
+
+	if total == 0 || math.IsNaN(total) || math.IsInf(total, 0) {
+		a, b, c := m.Triangle(t)
+		return planeZ(a, b, c, x, y), t, true
+	}
+	var z float64
+	for v, w := range weights {
+		z += w / total * m.Vertices[v][2]
+	}
+	return z, t, true
+}
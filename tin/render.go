@@ -0,0 +1,116 @@
+package tin
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Bounds returns the corners of the horizontal box enclosing the vertices of m.
+func (m *Mesh) Bounds() ([2]float64, [2]float64) {
+	min := [2]float64{math.Inf(1), math.Inf(1)}
+	max := [2]float64{math.Inf(-1), math.Inf(-1)}
+	for _, v := range m.Vertices {
+		min[0], max[0] = math.Min(min[0], v[0]), math.Max(max[0], v[0])
+		min[1], max[1] = math.Min(min[1], v[1]), math.Max(max[1], v[1])
+	}
+	return min, max
+}
+
+// UVs returns texture coordinates for each vertex of m that map Render's image onto it: u runs east across the
+// bounds, and v north, from 0 at the bottom of the image to 1 at its top.
+func (m *Mesh) UVs() [][2]float64 {
+	min, max := m.Bounds()
+	w, h := math.Max(max[0]-min[0], 1e-9), math.Max(max[1]-min[1], 1e-9)
+	ret := make([][2]float64, len(m.Vertices))
+	for i, v := range m.Vertices {
+		ret[i] = [2]float64{(v[0] - min[0]) / w, (v[1] - min[1]) / h}
+	}
+	return ret
+}
+
+// Render draws m from directly above into an orthographic image of its bounds, north up, with the longer side size
+// pixels across.  Each pixel takes the colors of the corners of the triangle beneath it, blended by its position
+// within the triangle.  Pixels outside the mesh are transparent.
+func (m *Mesh) Render(colors [][3]uint8, size int) *image.NRGBA {
+	min, max := m.Bounds()
+	w, h := max[0]-min[0], max[1]-min[1]
+	cols, rows := size, size
+	if w > h {
+		rows = (int)(math.Max(1, math.Round((float64)(size)*h/w)))
+	} else {
+		cols = (int)(math.Max(1, math.Round((float64)(size)*w/h)))
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, cols, rows))
+	if w == 0 || h == 0 {
+		return img
+	}
+
+	// pixel coordinates run right and down from the image's top left corner
+	sx, sy := (float64)(cols)/w, (float64)(rows)/h
+	for t, tri := range m.Triangles {
+		a, b, c := m.Triangle(t)
+		pa := [2]float64{(a[0] - min[0]) * sx, (max[1] - a[1]) * sy}
+		pb := [2]float64{(b[0] - min[0]) * sx, (max[1] - b[1]) * sy}
+		pc := [2]float64{(c[0] - min[0]) * sx, (max[1] - c[1]) * sy}
+		det := (pb[1]-pc[1])*(pa[0]-pc[0]) + (pc[0]-pb[0])*(pa[1]-pc[1])
+		if det == 0 {
+			continue
+		}
+
+		x0 := (int)(math.Max(0, math.Floor(math.Min(pa[0], math.Min(pb[0], pc[0])))))
+		x1 := (int)(math.Min((float64)(cols-1), math.Ceil(math.Max(pa[0], math.Max(pb[0], pc[0])))))
+		y0 := (int)(math.Max(0, math.Floor(math.Min(pa[1], math.Min(pb[1], pc[1])))))
+		y1 := (int)(math.Min((float64)(rows-1), math.Ceil(math.Max(pa[1], math.Max(pb[1], pc[1])))))
+		ca, cb, cc := colors[tri[0]], colors[tri[1]], colors[tri[2]]
+
+		// a small tolerance fills pixels whose centers fall on a shared edge, in either triangle
+		const tolerance = -1e-9
+		for y := y0; y <= y1; y++ {
+			py := (float64)(y) + 0.5
+			for x := x0; x <= x1; x++ {
+				px := (float64)(x) + 0.5
+				wa := ((pb[1]-pc[1])*(px-pc[0]) + (pc[0]-pb[0])*(py-pc[1])) / det
+				wb := ((pc[1]-pa[1])*(px-pc[0]) + (pa[0]-pc[0])*(py-pc[1])) / det
+				wc := 1 - wa - wb
+				if wa < tolerance || wb < tolerance || wc < tolerance {
+					continue
+				}
+				var rgb [3]uint8
+				for k := range rgb {
+					rgb[k] = (uint8)(math.Round(math.Min(255, math.Max(0,
+						wa*(float64)(ca[k])+wb*(float64)(cb[k])+wc*(float64)(cc[k])))))
+				}
+				img.SetNRGBA(x, y, color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255})
+			}
+		}
+	}
+	return img
+}
+
+// Normals returns a unit normal for each vertex of m, pointing up: the mean of the normals of the triangles around it,
+// weighted by their areas.
+func (m *Mesh) Normals() [][3]float64 {
+	ret := make([][3]float64, len(m.Vertices))
+	for t, tri := range m.Triangles {
+		a, b, c := m.Triangle(t)
+		u := [3]float64{b[0] - a[0], b[1] - a[1], b[2] - a[2]}
+		v := [3]float64{c[0] - a[0], c[1] - a[1], c[2] - a[2]}
+		// the cross product's length is twice the triangle's area
+		n := [3]float64{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+		for _, i := range tri {
+			for k := range n {
+				ret[i][k] += n[k]
+			}
+		}
+	}
+	for i, n := range ret {
+		l := math.Sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])
+		if l == 0 {
+			ret[i] = [3]float64{0, 0, 1}
+			continue
+		}
+		ret[i] = [3]float64{n[0] / l, n[1] / l, n[2] / l}
+	}
+	return ret
+}
@@ -0,0 +1,214 @@
+// Package tin builds triangulated irregular networks: 2.5D Delaunay triangulations of pointclouds over their
+// horizontal positions, the terrain surfaces meshes, contours and interpolation are made from.
+//
+// Triangulation runs in O(n log n) and copes with millions of points.  Its geometric predicates are exact, so
+// collinear and cocircular points, common in quantized lidar coordinates, never produce overlapping or missing
+// triangles.
+package tin
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nullstyle/lassloot"
+)
+
+// Duplicates chooses the elevation of a vertex where several points share a horizontal position, as the first and last
+// returns of a pulse straight down do.
+type Duplicates int
+
+const (
+	Mean Duplicates = iota
+	Lowest
+	Highest
+	First
+)
+
+var duplicatesNames = [...]string{"mean", "lowest", "highest", "first"}
+
+func (d Duplicates) String() string {
+	if d < 0 || (int)(d) >= len(duplicatesNames) {
+		return fmt.Sprintf("Duplicates(%d)", d)
+	}
+	return duplicatesNames[d]
+}
+
+// ParseDuplicates returns the policy named s, as String returns.
+func ParseDuplicates(s string) (error, Duplicates) {
+	for i, name := range duplicatesNames {
+		if s == name {
+			return nil, (Duplicates)(i)
+		}
+	}
+	return fmt.Errorf("unknown duplicates %q: want mean, lowest, highest or first", s), Mean
+}
+
+// Options control how a pointcloud is triangulated.
+type Options struct {
+	// Filter, if set, selects the points triangulated, e.g. only ground points for a bare earth surface.
+	Filter *lassloot.Filter
+
+	Duplicates Duplicates
+}
+
+// Mesh is an indexed triangle mesh over a set of vertices.
+type Mesh struct {
+	// Vertices holds a vertex for each distinct horizontal position triangulated.
+	Vertices [][3]float64
+
+	// Sources holds the index of the point each vertex was made from or, where several points share its position, of
+	// the first of them.
+	Sources []uint64
+
+	// Triangles lists the vertices of each triangle, counterclockwise seen from above.
+	Triangles [][3]int32
+
+	// Halfedges pairs the edges of neighbouring triangles.  Edge 3t+k runs from vertex k of triangle t to vertex
+	// (k+1)%3, and Halfedges holds the index of the same edge run the other way in the neighbouring triangle, or -1
+	// on the convex hull.
+	Halfedges []int32
+
+	// Hull lists the vertices of the convex hull, counterclockwise.
+	Hull []int32
+}
+
+// New triangulates the points of pc that opts.Filter selects.  Sources index the points of pc.
+func New(pc *lassloot.PointCloud, opts Options) (error, *Mesh) {
+	n := pc.Len()
+	if n > math.MaxInt32 {
+		return fmt.Errorf("%d points are too many to triangulate", n), nil
+	}
+
+	var points [][3]float64
+	var sources []uint64
+	for i := (uint64)(0); i < n; i++ {
+		_, pt := pc.PointAt(i)
+		if opts.Filter != nil && !opts.Filter.Match(pt) {
+			continue
+		}
+		x, y, z := pt.XYZ()
+		points = append(points, [3]float64{x, y, z})
+		sources = append(sources, i)
+	}
+
+	err, m := build(points, opts.Duplicates)
+	if err != nil {
+		return err, nil
+	}
+	for i, s := range m.Sources {
+		m.Sources[i] = sources[s]
+	}
+	return nil, m
+}
+
+// Triangulate triangulates points over x and y.  Sources index points.
+func Triangulate(points [][3]float64, duplicates Duplicates) (error, *Mesh) {
+	if len(points) > math.MaxInt32 {
+		return fmt.Errorf("%d points are too many to triangulate", len(points)), nil
+	}
+	return build(points, duplicates)
+}
+
+func build(points [][3]float64, duplicates Duplicates) (error, *Mesh) {
+	for i, p := range points {
+		if math.IsNaN(p[0]) || math.IsNaN(p[1]) || math.IsInf(p[0], 0) || math.IsInf(p[1], 0) {
+			return fmt.Errorf("point %d has no horizontal position", i), nil
+		}
+	}
+
+	m := merge(points, duplicates)
+	xy := make([][2]float64, len(m.Vertices))
+	for i, v := range m.Vertices {
+		xy[i] = [2]float64{v[0], v[1]}
+	}
+
+	d := triangulate(xy)
+	if d == nil {
+		return fmt.Errorf("%d distinct positions are collinear or too few to triangulate", len(xy)), nil
+	}
+
+	// the triangulation winds clockwise: reverse each triangle, and renumber its edges to match
+	edge := func(e int32) int32 {
+		if e < 0 {
+			return e
+		}
+		return e - e%3 + 2 - e%3
+	}
+	m.Triangles = make([][3]int32, len(d.triangles)/3)
+	for t := range m.Triangles {
+		m.Triangles[t] = [3]int32{d.triangles[3*t], d.triangles[3*t+2], d.triangles[3*t+1]}
+	}
+	m.Halfedges = make([]int32, len(d.halfedges))
+	for e, h := range d.halfedges {
+		m.Halfedges[edge((int32)(e))] = edge(h)
+	}
+	hull := d.hull()
+	for i := len(hull) - 1; i >= 0; i-- {
+		m.Hull = append(m.Hull, hull[i])
+	}
+	return nil, m
+}
+
+// merge returns a mesh without triangles whose vertices are the distinct horizontal positions of points, in the order
+// they first appear.
+func merge(points [][3]float64, duplicates Duplicates) *Mesh {
+	order := make([]int32, len(points))
+	for i := range order {
+		order[i] = (int32)(i)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := &points[order[i]], &points[order[j]]
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		return order[i] < order[j]
+	})
+
+	// the first point of each run sharing a position represents it, with the elevation the policy chooses
+	first := make([]bool, len(points))
+	z := make([]float64, len(points))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && points[order[end]][0] == points[order[start]][0] &&
+			points[order[end]][1] == points[order[start]][1] {
+			end++
+		}
+
+		rep := order[start]
+		v := points[rep][2]
+		for _, i := range order[start+1 : end] {
+			switch duplicates {
+			case Mean:
+				v += points[i][2]
+			case Lowest:
+				v = math.Min(v, points[i][2])
+			case Highest:
+				v = math.Max(v, points[i][2])
+			}
+		}
+		if duplicates == Mean {
+			v /= (float64)(end - start)
+		}
+		first[rep], z[rep] = true, v
+		start = end
+	}
+
+	m := &Mesh{}
+	for i, p := range points {
+		if first[i] {
+			m.Vertices = append(m.Vertices, [3]float64{p[0], p[1], z[i]})
+			m.Sources = append(m.Sources, (uint64)(i))
+		}
+	}
+	return m
+}
+
+// Triangle returns the positions of the vertices of triangle t.
+func (m *Mesh) Triangle(t int) ([3]float64, [3]float64, [3]float64) {
+	tri := m.Triangles[t]
+	return m.Vertices[tri[0]], m.Vertices[tri[1]], m.Vertices[tri[2]]
+}
@@ -0,0 +1,327 @@
+package tin
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/cloudtest"
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+// checkMesh verifies that m is a valid Delaunay triangulation of its vertices: every triangle counterclockwise, every
+// halfedge paired with its reverse, no vertex inside the circle of a neighbouring triangle, and Euler's count of
+// triangles.
+func checkMesh(t *testing.T, m *Mesh) {
+	t.Helper()
+
+	v := m.Vertices
+	for i, tri := range m.Triangles {
+		a, b, c := v[tri[0]], v[tri[1]], v[tri[2]]
+		if orient2d(a[0], a[1], b[0], b[1], c[0], c[1]) <= 0 {
+			t.Fatalf("triangle %d %v is not counterclockwise", i, tri)
+		}
+	}
+
+	hullEdges := 0
+	for e, h := range m.Halfedges {
+		if h == -1 {
+			hullEdges++
+			continue
+		}
+		if m.Halfedges[h] != (int32)(e) {
+			t.Fatalf("halfedge %d pairs with %d, which pairs with %d", e, h, m.Halfedges[h])
+		}
+		from, to := m.Triangles[e/3][e%3], m.Triangles[e/3][(e+1)%3]
+		hfrom, hto := m.Triangles[h/3][h%3], m.Triangles[h/3][(h+1)%3]
+		if from != hto || to != hfrom {
+			t.Fatalf("halfedge %d runs %d-%d but its pair %d runs %d-%d", e, from, to, h, hfrom, hto)
+		}
+
+		// the vertex across the edge must not be inside this triangle's circle
+		tri := m.Triangles[e/3]
+		a, b, c := v[tri[0]], v[tri[1]], v[tri[2]]
+		p := v[m.Triangles[h/3][(h+2)%3]]
+		if incircle(a[0], a[1], b[0], b[1], c[0], c[1], p[0], p[1]) > 0 {
+			t.Fatalf("triangle %d is not delaunay", e/3)
+		}
+	}
+
+	if hullEdges != len(m.Hull) {
+		t.Errorf("%d hull edges, hull of %d vertices", hullEdges, len(m.Hull))
+	}
+	if want := 2*len(v) - 2 - len(m.Hull); len(m.Triangles) != want {
+		t.Errorf("%d triangles of %d vertices with %d on the hull, want %d", len(m.Triangles), len(v), len(m.Hull), want)
+	}
+	for i := range m.Hull {
+		a, b, c := v[m.Hull[i]], v[m.Hull[(i+1)%len(m.Hull)]], v[m.Hull[(i+2)%len(m.Hull)]]
+		if orient2d(a[0], a[1], b[0], b[1], c[0], c[1]) < 0 {
+			t.Fatalf("hull turns clockwise at %d", m.Hull[(i+1)%len(m.Hull)])
+		}
+	}
+}
+
+func exactOrient(ax, ay, bx, by, cx, cy float64) int {
+	r := func(v float64) *big.Rat { return new(big.Rat).SetFloat64(v) }
+	sub := func(a, b float64) *big.Rat { return new(big.Rat).Sub(r(a), r(b)) }
+	left := new(big.Rat).Mul(sub(ax, cx), sub(by, cy))
+	right := new(big.Rat).Mul(sub(ay, cy), sub(bx, cx))
+	return left.Sub(left, right).Sign()
+}
+
+func TestPredicates(t *testing.T) {
+	// points a few ulps from the line y = x, where a floating point determinant gets the sign wrong
+	ulp := math.Nextafter(0.5, 1) - 0.5
+	for i := 0; i < 64; i++ {
+		for j := 0; j < 64; j++ {
+			px, py := 0.5+(float64)(i)*ulp, 0.5+(float64)(j)*ulp
+			got, want := orient2d(px, py, 12, 12, 24, 24), exactOrient(px, py, 12, 12, 24, 24)
+			if (got > 0) != (want > 0) || (got < 0) != (want < 0) {
+				t.Fatalf("orient2d of %v %v is %v, want sign %d", px, py, got, want)
+			}
+		}
+	}
+
+	// exactly cocircular points in projected coordinates, and ones just inside and outside
+	cx, cy := 500000.0, 4000000.0
+	a, b, c := [2]float64{cx + 3, cy + 4}, [2]float64{cx - 5, cy}, [2]float64{cx, cy - 5}
+	for _, tc := range []struct {
+		x, y float64
+		sign float64
+	}{
+		{cx + 4, cy + 3, 0},
+		{cx + 4, math.Nextafter(cy+3, 0), 1},
+		{cx + 4, math.Nextafter(cy+3, math.Inf(1)), -1},
+	} {
+		got := incircle(a[0], a[1], b[0], b[1], c[0], c[1], tc.x, tc.y)
+		if (got > 0) != (tc.sign > 0) || (got < 0) != (tc.sign < 0) {
+			t.Errorf("incircle of %v %v is %v, want sign %v", tc.x, tc.y, got, tc.sign)
+		}
+	}
+}
+
+func TestTriangulateGrid(t *testing.T) {
+	// a lattice is as cocircular as points get
+	var points [][3]float64
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			points = append(points, [3]float64{500000 + (float64)(x)*0.5, 4000000 + (float64)(y)*0.5, (float64)(x * y)})
+		}
+	}
+	err, m := Triangulate(points, Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMesh(t, m)
+	if len(m.Triangles) != 2*19*19 {
+		t.Errorf("%d triangles, want %d", len(m.Triangles), 2*19*19)
+	}
+}
+
+func TestTriangulateRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var points [][3]float64
+	for i := 0; i < 20000; i++ {
+		// quantized to centimeters, as lidar is
+		x := math.Round(rng.Float64()*10000) / 100
+		y := math.Round(rng.Float64()*10000) / 100
+		points = append(points, [3]float64{300000 + x, 5000000 + y, rng.Float64()})
+	}
+	err, m := Triangulate(points, Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMesh(t, m)
+}
+
+func TestDuplicates(t *testing.T) {
+	points := [][3]float64{{0, 0, 1}, {1, 0, 2}, {0, 1, 3}, {1, 0, 4}, {1, 1, 5}, {1, 0, 9}}
+	for policy, want := range map[Duplicates]float64{Mean: 5, Lowest: 2, Highest: 9, First: 2} {
+		err, m := Triangulate(points, policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkMesh(t, m)
+		if len(m.Vertices) != 4 || m.Vertices[1] != [3]float64{1, 0, want} || m.Sources[1] != 1 || m.Sources[3] != 4 {
+			t.Errorf("%s: vertices %v from %v", policy, m.Vertices, m.Sources)
+		}
+	}
+
+	if err, _ := Triangulate([][3]float64{{0, 0, 0}, {1, 1, 0}, {2, 2, 0}, {1, 1, 5}}, Mean); err == nil {
+		t.Error("collinear points triangulated")
+	}
+	if err, _ := Triangulate([][3]float64{{0, 0, 0}, {0, 0, 1}, {1, 0, 0}}, Mean); err == nil {
+		t.Error("two distinct positions triangulated")
+	}
+}
+
+func TestNew(t *testing.T) {
+	var points []lastest.Point
+	for i := 0; i < 100; i++ {
+		class := (byte)(2)
+		if i%3 == 0 {
+			class = 5
+		}
+		points = append(points, lastest.Point{X: (int32)(i%10) * 100, Y: (int32)(i/10) * 100, Z: (int32)(i), Classification: class})
+	}
+	pc := cloudtest.PointCloud(t, 1, points)
+
+	err, m := New(pc, Options{Filter: &lassloot.Filter{Classes: []uint8{2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMesh(t, m)
+	if len(m.Vertices) != 66 {
+		t.Fatalf("%d vertices, want the 66 ground points", len(m.Vertices))
+	}
+	for i, s := range m.Sources {
+		if s%3 == 0 {
+			t.Fatalf("vertex %d is made from unclassified point %d", i, s)
+		}
+		if math.Abs(m.Vertices[i][2]-(float64)(s)/100) > 1e-9 {
+			t.Errorf("vertex %d at %v, want point %d's elevation", i, m.Vertices[i], s)
+		}
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	var points [][3]float64
+	for i := 0; i < 2000; i++ {
+		x, y := math.Round(rng.Float64()*10000)/100, math.Round(rng.Float64()*10000)/100
+		points = append(points, [3]float64{x, y, 3*x - 2*y + 7})
+	}
+	points = append(points, [3]float64{0, 0, 7}, [3]float64{100, 0, 307}, [3]float64{0, 100, -193}, [3]float64{100, 100, 107})
+	err, m := Triangulate(points, Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every triangle lies on the plane, so interpolation recovers it anywhere in the square
+	hint := -1
+	for i := 0; i < 1000; i++ {
+		x, y := rng.Float64()*100, rng.Float64()*100
+		z, tri, ok := m.Interpolate(x, y, hint)
+		if !ok || !m.contains(tri, x, y) {
+			t.Fatalf("%v, %v not found: triangle %d", x, y, tri)
+		}
+		if want := 3*x - 2*y + 7; math.Abs(z-want) > 1e-9 {
+			t.Fatalf("%v, %v at %v, want %v", x, y, z, want)
+		}
+		hint = tri
+	}
+	if _, _, ok := m.Interpolate(100.5, 50, 0); ok {
+		t.Error("point outside the hull found")
+	}
+}
+
+func TestNaturalNeighbor(t *testing.T) {
+	// a plane, offset far from the origin as projected coordinates are, which natural neighbors reproduce exactly
+	rng := rand.New(rand.NewSource(3))
+	var points [][3]float64
+	for i := 0; i < 500; i++ {
+		x, y := math.Round(rng.Float64()*10000)/100, math.Round(rng.Float64()*10000)/100
+		points = append(points, [3]float64{500000 + x, 4100000 + y, 3*x - 2*y + 7})
+	}
+	// and a grid, whose cocircular squares and points along edges are the awkward cases
+	for i := 0; i < 100; i++ {
+		x, y := (float64)(i%10)*10, (float64)(i/10)*10
+		points = append(points, [3]float64{600000 + x, 4100000 + y, 3*x - 2*y + 7})
+	}
+	err, m := Triangulate(points, Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hint := -1
+	for i := 0; i < 2000; i++ {
+		x, y := 10+rng.Float64()*79, 10+rng.Float64()*79
+		ox := 500000.0
+		if i%2 == 1 {
+			ox = 600000
+			if i%10 == 1 {
+				x = math.Round(x)
+			}
+		}
+		z, tri, ok := m.NaturalNeighbor(ox+x, 4100000+y, hint)
+		if !ok {
+			t.Fatalf("%v, %v not found", x, y)
+		}
+		if want := 3*x - 2*y + 7; math.Abs(z-want) > 1e-6 {
+			t.Fatalf("%v, %v at %v, want %v", ox+x, y, z, want)
+		}
+		hint = tri
+	}
+	for _, v := range m.Vertices[:10] {
+		if z, _, ok := m.NaturalNeighbor(v[0], v[1], -1); !ok || z != v[2] {
+			t.Errorf("vertex %v interpolated at %v", v, z)
+		}
+	}
+	if _, _, ok := m.NaturalNeighbor(0, 0, 0); ok {
+		t.Error("point outside the hull found")
+	}
+
+	// a peak: smooth, so unlike the planes of its triangles it stays within its neighbors but bends between them
+	err, m = Triangulate([][3]float64{{0, 0, 0}, {2, 0, 0}, {2, 2, 0}, {0, 2, 0}, {1, 1, 1}}, Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, _, _ := m.NaturalNeighbor(1.6, 1.3, -1)
+	linear, _, _ := m.Interpolate(1.6, 1.3, -1)
+	if !(z > 0 && z < 1) || z == linear {
+		t.Errorf("natural neighbor %v, linear %v", z, linear)
+	}
+}
+
+func TestRender(t *testing.T) {
+	// a plane rising to the east over a 20 by 10 rectangle
+	var points [][3]float64
+	for x := 0; x <= 20; x += 2 {
+		for y := 0; y <= 10; y += 2 {
+			points = append(points, [3]float64{(float64)(x), (float64)(y), (float64)(x) / 2})
+		}
+	}
+	err, m := Triangulate(points, Mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, n := range m.Normals() {
+		want := [3]float64{-0.5 / math.Sqrt(1.25), 0, 1 / math.Sqrt(1.25)}
+		for k := range n {
+			if math.Abs(n[k]-want[k]) > 1e-9 {
+				t.Fatalf("vertex %d normal %v, want %v", i, n, want)
+			}
+		}
+	}
+
+	uvs := m.UVs()
+	for i, v := range m.Vertices {
+		if want := [2]float64{v[0] / 20, v[1] / 10}; math.Abs(uvs[i][0]-want[0]) > 1e-12 || math.Abs(uvs[i][1]-want[1]) > 1e-12 {
+			t.Fatalf("vertex %v at %v, want %v", v, uvs[i], want)
+		}
+	}
+
+	// red to the west, blue to the east
+	colors := make([][3]uint8, len(m.Vertices))
+	for i, v := range m.Vertices {
+		colors[i] = [3]uint8{(uint8)(255 - v[0]*12.75), 0, (uint8)(v[0] * 12.75)}
+	}
+	img := m.Render(colors, 40)
+	if b := img.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+		t.Fatalf("image is %dx%d", b.Dx(), b.Dy())
+	}
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := img.NRGBAAt(x, y)
+			if c.A != 255 {
+				t.Fatalf("pixel %d,%d is transparent", x, y)
+			}
+			if want := ((float64)(x) + 0.5) / 40 * 255; math.Abs((float64)(c.B)-want) > 1 || c.R+c.B < 254 {
+				t.Fatalf("pixel %d,%d is %v, want blue %v", x, y, c, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,345 @@
+// slootview's WebGL point renderer.  Points arrive from api/points in level of detail order, so drawing whatever has
+// loaded so far always shows an evenly thinned version of the whole cloud.
+(function () {
+  'use strict';
+
+  const canvas = document.getElementById('view');
+  const gl = canvas.getContext('webgl', { antialias: false });
+  const statusEl = document.getElementById('status');
+  if (!gl) {
+    statusEl.textContent = 'WebGL is not available in this browser';
+    return;
+  }
+
+  // ASPRS standard classification colors, indexed by class; unlisted classes are gray.
+  const classColors = {
+    0: [160, 160, 160], 1: [200, 200, 200], 2: [166, 118, 60], 3: [120, 200, 90], 4: [60, 170, 60],
+    5: [20, 110, 30], 6: [220, 70, 60], 7: [255, 0, 255], 9: [50, 110, 230], 10: [120, 80, 40],
+    11: [90, 90, 90], 13: [250, 220, 0], 14: [250, 170, 0], 15: [200, 120, 255], 16: [120, 240, 255],
+    17: [240, 160, 120], 18: [255, 0, 128],
+  };
+
+  // Elevation ramp from low to high.
+  const ramp = [[48, 18, 160], [30, 130, 230], [40, 200, 150], [190, 220, 60], [250, 150, 30], [220, 40, 30]];
+
+  function rampColor(t, out, o) {
+    t = Math.min(1, Math.max(0, t)) * (ramp.length - 1);
+    const i = Math.min(ramp.length - 2, Math.floor(t));
+    const f = t - i;
+    for (let c = 0; c < 3; c++) {
+      out[o + c] = ramp[i][c] + (ramp[i + 1][c] - ramp[i][c]) * f;
+    }
+  }
+
+  const vertexSource = `
+    attribute vec3 position;
+    attribute vec3 color;
+    uniform mat4 mvp;
+    uniform float size;
+    varying vec3 vColor;
+    void main() {
+      gl_Position = mvp * vec4(position, 1.0);
+      gl_PointSize = size;
+      vColor = color;
+    }`;
+  const fragmentSource = `
+    precision mediump float;
+    varying vec3 vColor;
+    void main() {
+      gl_FragColor = vec4(vColor, 1.0);
+    }`;
+
+  function compile(type, source) {
+    const shader = gl.createShader(type);
+    gl.shaderSource(shader, source);
+    gl.compileShader(shader);
+    if (!gl.getShaderParameter(shader, gl.COMPILE_STATUS)) {
+      throw new Error(gl.getShaderInfoLog(shader));
+    }
+    return shader;
+  }
+
+  const program = gl.createProgram();
+  gl.attachShader(program, compile(gl.VERTEX_SHADER, vertexSource));
+  gl.attachShader(program, compile(gl.FRAGMENT_SHADER, fragmentSource));
+  gl.linkProgram(program);
+  gl.useProgram(program);
+
+  const positionLoc = gl.getAttribLocation(program, 'position');
+  const colorLoc = gl.getAttribLocation(program, 'color');
+  const mvpLoc = gl.getUniformLocation(program, 'mvp');
+  const sizeLoc = gl.getUniformLocation(program, 'size');
+
+  const positionBuffer = gl.createBuffer();
+  const colorBuffer = gl.createBuffer();
+
+  // Column major 4x4 matrix helpers.
+  function perspective(fovy, aspect, near, far) {
+    const f = 1 / Math.tan(fovy / 2);
+    const nf = 1 / (near - far);
+    return [f / aspect, 0, 0, 0, 0, f, 0, 0, 0, 0, (far + near) * nf, -1, 0, 0, 2 * far * near * nf, 0];
+  }
+
+  function sub(a, b) { return [a[0] - b[0], a[1] - b[1], a[2] - b[2]]; }
+  function cross(a, b) { return [a[1] * b[2] - a[2] * b[1], a[2] * b[0] - a[0] * b[2], a[0] * b[1] - a[1] * b[0]]; }
+  function dot(a, b) { return a[0] * b[0] + a[1] * b[1] + a[2] * b[2]; }
+  function normalize(a) {
+    const l = Math.hypot(a[0], a[1], a[2]) || 1;
+    return [a[0] / l, a[1] / l, a[2] / l];
+  }
+
+  function lookAt(eye, target, up) {
+    const z = normalize(sub(eye, target));
+    const x = normalize(cross(up, z));
+    const y = cross(z, x);
+    return [x[0], y[0], z[0], 0, x[1], y[1], z[1], 0, x[2], y[2], z[2], 0, -dot(x, eye), -dot(y, eye), -dot(z, eye), 1];
+  }
+
+  function multiply(a, b) {
+    const out = new Array(16);
+    for (let col = 0; col < 4; col++) {
+      for (let row = 0; row < 4; row++) {
+        let s = 0;
+        for (let k = 0; k < 4; k++) {
+          s += a[k * 4 + row] * b[col * 4 + k];
+        }
+        out[col * 4 + row] = s;
+      }
+    }
+    return out;
+  }
+
+  let meta = null;
+  let capacity = 0;
+  let loaded = 0;
+  let positions, intensities, classes, rgbs, colors;
+  let loading = false;
+  let generation = 0;
+
+  const camera = { yaw: -Math.PI / 2, pitch: Math.PI / 4, distance: 1, target: [0, 0, 0] };
+  let radius = 1;
+  let dirty = true;
+
+  function resetCamera() {
+    const extent = sub(meta.max, meta.min);
+    radius = Math.max(1e-6, Math.hypot(extent[0], extent[1], extent[2]) / 2);
+    camera.yaw = -Math.PI / 2;
+    camera.pitch = Math.PI / 4;
+    camera.distance = radius * 2.2;
+    camera.target = [0, 0, 0];
+    dirty = true;
+  }
+
+  function colorPoints(from, to) {
+    const mode = document.getElementById('color').value;
+    const zmin = meta.min[2] - meta.center[2];
+    const zspan = Math.max(1e-9, meta.max[2] - meta.min[2]);
+    const [ilo, ihi] = meta.intensityRange;
+
+    for (let i = from; i < to; i++) {
+      const o = i * 3;
+      if (mode === 'intensity' && meta.hasIntensity) {
+        const v = Math.min(1, Math.max(0, (intensities[i] - ilo) / (ihi - ilo))) * 255;
+        colors[o] = colors[o + 1] = colors[o + 2] = v;
+      } else if (mode === 'classification' && meta.hasClassification) {
+        const c = classColors[classes[i]] || [128, 128, 128];
+        colors[o] = c[0]; colors[o + 1] = c[1]; colors[o + 2] = c[2];
+      } else if (mode === 'rgb' && meta.hasRGB) {
+        colors[o] = rgbs[o]; colors[o + 1] = rgbs[o + 1]; colors[o + 2] = rgbs[o + 2];
+      } else {
+        rampColor((positions[o + 2] - zmin) / zspan, colors, o);
+      }
+    }
+  }
+
+  function allocate() {
+    const budget = parseInt(document.getElementById('budget').value, 10);
+    capacity = Math.min(budget, meta.count);
+    positions = new Float32Array(capacity * 3);
+    intensities = new Uint16Array(capacity);
+    classes = new Uint8Array(capacity);
+    rgbs = new Uint8Array(capacity * 3);
+    colors = new Uint8Array(capacity * 3);
+    loaded = 0;
+    generation++;
+
+    gl.bindBuffer(gl.ARRAY_BUFFER, positionBuffer);
+    gl.bufferData(gl.ARRAY_BUFFER, positions.byteLength, gl.DYNAMIC_DRAW);
+    gl.bindBuffer(gl.ARRAY_BUFFER, colorBuffer);
+    gl.bufferData(gl.ARRAY_BUFFER, colors.byteLength, gl.DYNAMIC_DRAW);
+  }
+
+  async function load() {
+    if (loading) {
+      return;
+    }
+    loading = true;
+    const gen = generation;
+    try {
+      while (loaded < capacity) {
+        const count = Math.min(meta.chunkSize, capacity - loaded);
+        const response = await fetch(`api/points?start=${loaded}&count=${count}`);
+        if (!response.ok) {
+          throw new Error(await response.text());
+        }
+        const buf = await response.arrayBuffer();
+        if (gen !== generation) {
+          break;
+        }
+        const n = buf.byteLength / 18;
+        if (n === 0) {
+          break;
+        }
+
+        positions.set(new Float32Array(buf, 0, n * 3), loaded * 3);
+        intensities.set(new Uint16Array(buf, n * 12, n), loaded);
+        classes.set(new Uint8Array(buf, n * 14, n), loaded);
+        rgbs.set(new Uint8Array(buf, n * 15, n * 3), loaded * 3);
+        colorPoints(loaded, loaded + n);
+
+        gl.bindBuffer(gl.ARRAY_BUFFER, positionBuffer);
+        gl.bufferSubData(gl.ARRAY_BUFFER, loaded * 12, positions.subarray(loaded * 3, (loaded + n) * 3));
+        gl.bindBuffer(gl.ARRAY_BUFFER, colorBuffer);
+        gl.bufferSubData(gl.ARRAY_BUFFER, loaded * 3, colors.subarray(loaded * 3, (loaded + n) * 3));
+
+        loaded += n;
+        updateStatus();
+        dirty = true;
+      }
+    } catch (err) {
+      statusEl.textContent = `failed to load points: ${err.message}`;
+    } finally {
+      loading = false;
+    }
+  }
+
+  function updateStatus() {
+    statusEl.textContent = `${loaded.toLocaleString()} of ${meta.count.toLocaleString()} points`;
+  }
+
+  function recolor() {
+    colorPoints(0, loaded);
+    gl.bindBuffer(gl.ARRAY_BUFFER, colorBuffer);
+    gl.bufferSubData(gl.ARRAY_BUFFER, 0, colors.subarray(0, loaded * 3));
+    dirty = true;
+  }
+
+  function draw() {
+    requestAnimationFrame(draw);
+
+    const dpr = window.devicePixelRatio || 1;
+    const w = Math.floor(canvas.clientWidth * dpr);
+    const h = Math.floor(canvas.clientHeight * dpr);
+    if (canvas.width !== w || canvas.height !== h) {
+      canvas.width = w;
+      canvas.height = h;
+      dirty = true;
+    }
+    if (!dirty || !meta) {
+      return;
+    }
+    dirty = false;
+
+    gl.viewport(0, 0, w, h);
+    gl.clearColor(0.07, 0.07, 0.07, 1);
+    gl.clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT);
+    gl.enable(gl.DEPTH_TEST);
+
+    const cp = Math.cos(camera.pitch);
+    const eye = [
+      camera.target[0] + camera.distance * cp * Math.cos(camera.yaw),
+      camera.target[1] + camera.distance * cp * Math.sin(camera.yaw),
+      camera.target[2] + camera.distance * Math.sin(camera.pitch),
+    ];
+    const near = Math.max(camera.distance / 1000, radius / 1e6);
+    const far = camera.distance + radius * 4;
+    const mvp = multiply(perspective(Math.PI / 4, w / h, near, far), lookAt(eye, camera.target, [0, 0, 1]));
+
+    gl.uniformMatrix4fv(mvpLoc, false, new Float32Array(mvp));
+    gl.uniform1f(sizeLoc, parseFloat(document.getElementById('size').value) * dpr);
+
+    gl.bindBuffer(gl.ARRAY_BUFFER, positionBuffer);
+    gl.enableVertexAttribArray(positionLoc);
+    gl.vertexAttribPointer(positionLoc, 3, gl.FLOAT, false, 0, 0);
+    gl.bindBuffer(gl.ARRAY_BUFFER, colorBuffer);
+    gl.enableVertexAttribArray(colorLoc);
+    gl.vertexAttribPointer(colorLoc, 3, gl.UNSIGNED_BYTE, true, 0, 0);
+
+    gl.drawArrays(gl.POINTS, 0, loaded);
+  }
+
+  // Orbit with the primary button, pan with the secondary button or shift, zoom with the wheel.
+  let drag = null;
+  canvas.addEventListener('contextmenu', (e) => e.preventDefault());
+  canvas.addEventListener('mousedown', (e) => {
+    drag = { x: e.clientX, y: e.clientY, pan: e.button === 2 || e.shiftKey };
+  });
+  window.addEventListener('mouseup', () => { drag = null; });
+  window.addEventListener('mousemove', (e) => {
+    if (!drag) {
+      return;
+    }
+    const dx = e.clientX - drag.x;
+    const dy = e.clientY - drag.y;
+    drag.x = e.clientX;
+    drag.y = e.clientY;
+
+    if (drag.pan) {
+      const scale = camera.distance / canvas.clientHeight;
+      const right = [-Math.sin(camera.yaw), Math.cos(camera.yaw), 0];
+      const up = [
+        -Math.sin(camera.pitch) * Math.cos(camera.yaw),
+        -Math.sin(camera.pitch) * Math.sin(camera.yaw),
+        Math.cos(camera.pitch),
+      ];
+      for (let i = 0; i < 3; i++) {
+        camera.target[i] += (-dx * right[i] + dy * up[i]) * scale;
+      }
+    } else {
+      camera.yaw -= dx * 0.005;
+      camera.pitch = Math.min(Math.PI / 2 - 0.001, Math.max(-Math.PI / 2 + 0.001, camera.pitch + dy * 0.005));
+    }
+    dirty = true;
+  });
+  canvas.addEventListener('wheel', (e) => {
+    e.preventDefault();
+    camera.distance *= Math.exp(e.deltaY * 0.001);
+    camera.distance = Math.min(radius * 100, Math.max(radius / 10000, camera.distance));
+    dirty = true;
+  }, { passive: false });
+  window.addEventListener('keydown', (e) => {
+    if (e.key === 'r' && meta) {
+      resetCamera();
+    }
+  });
+
+  document.getElementById('color').addEventListener('change', recolor);
+  document.getElementById('size').addEventListener('input', () => { dirty = true; });
+  document.getElementById('budget').addEventListener('change', async () => {
+    allocate();
+    // let any load of the previous allocation notice it has been replaced
+    while (loading) {
+      await new Promise((resolve) => setTimeout(resolve, 50));
+    }
+    load();
+  });
+
+  fetch('api/meta')
+    .then((response) => response.json())
+    .then((m) => {
+      meta = m;
+      document.title = `${meta.name} - slootview`;
+      document.getElementById('name').textContent = meta.name;
+      document.getElementById('crs').textContent = meta.crs || '';
+      if (meta.hasRGB) {
+        document.getElementById('color').value = 'rgb';
+      }
+      resetCamera();
+      allocate();
+      updateStatus();
+      load();
+    })
+    .catch((err) => { statusEl.textContent = `failed to load metadata: ${err.message}`; });
+
+  requestAnimationFrame(draw);
+})();
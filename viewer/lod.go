@@ -0,0 +1,127 @@
+package viewer
+
+import (
+	"math"
+
+	"github.com/nullstyle/lassloot"
+)
+
+// maxLODLevel bounds the depth of the level of detail grid.  A level L grid divides the cloud's longest axis into
+// 2^L cells, so the finest level still holds several points per cell for any realistic scan.
+const maxLODLevel = 16
+
+// lodOrder returns the indices of the pointcloud's points ordered such that every prefix is an evenly spread
+// subsample of the whole cloud.  Points are assigned to the coarsest level of an octree-like grid whose cell they are
+// the first to claim, and levels are emitted coarsest first, so the first few thousand points outline the whole
+// scene and later points fill in detail.
+func lodOrder(pc *lassloot.PointCloud) []uint32 {
+	n := pc.Len()
+	if n == 0 {
+		return nil
+	}
+
+	min, max := bounds(pc)
+	extent := math.Max(max[0]-min[0], math.Max(max[1]-min[1], max[2]-min[2]))
+	if extent == 0 {
+		extent = 1
+	}
+
+	levels := make([]map[uint64]struct{}, maxLODLevel+1)
+	for i := range levels {
+		levels[i] = map[uint64]struct{}{}
+	}
+	assigned := make([]uint8, n)
+	counts := make([]uint64, maxLODLevel+2)
+
+	// visit points in a scattered order so the point claiming each cell isn't biased towards the start of the
+	// file's scan lines
+	step := scatterStep(n)
+	idx := (uint64)(0)
+	for i := (uint64)(0); i < n; i++ {
+		_, p := pc.PointAt(idx)
+		x, y, z := p.XYZ()
+
+		level := maxLODLevel + 1
+		for l := 0; l <= maxLODLevel; l++ {
+			cells := (float64)((uint64)(1) << l)
+			key := cellIndex(x, min[0], extent, cells) |
+				cellIndex(y, min[1], extent, cells)<<21 |
+				cellIndex(z, min[2], extent, cells)<<42
+			if _, taken := levels[l][key]; !taken {
+				levels[l][key] = struct{}{}
+				level = l
+				break
+			}
+		}
+		assigned[idx] = (uint8)(level)
+		counts[level]++
+
+		idx = (idx + step) % n
+	}
+
+	// counting sort by level, preserving the scattered visiting order within each level
+	starts := make([]uint64, len(counts))
+	for l := 1; l < len(counts); l++ {
+		starts[l] = starts[l-1] + counts[l-1]
+	}
+	order := make([]uint32, n)
+	idx = 0
+	for i := (uint64)(0); i < n; i++ {
+		l := assigned[idx]
+		order[starts[l]] = (uint32)(idx)
+		starts[l]++
+		idx = (idx + step) % n
+	}
+
+	return order
+}
+
+func cellIndex(v, min, extent, cells float64) uint64 {
+	c := (v - min) / extent * cells
+	if c >= cells {
+		c = cells - 1
+	}
+	if c < 0 {
+		c = 0
+	}
+	return (uint64)(c)
+}
+
+// scatterStep returns a stride near n times the golden ratio that is coprime with n, such that repeatedly adding it
+// modulo n visits every index exactly once.
+func scatterStep(n uint64) uint64 {
+	if n < 3 {
+		return 1
+	}
+	step := (uint64)((float64)(n)*0.6180339887) | 1
+	for gcd(step, n) != 1 {
+		step++
+	}
+	return step
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// bounds returns the smallest and largest coordinates of the pointcloud's points.
+func bounds(pc *lassloot.PointCloud) (min [3]float64, max [3]float64) {
+	for i := range min {
+		min[i], max[i] = math.Inf(1), math.Inf(-1)
+	}
+
+	l := pc.Len()
+	for i := (uint64)(0); i < l; i++ {
+		_, p := pc.PointAt(i)
+		x, y, z := p.XYZ()
+		for j, v := range [3]float64{x, y, z} {
+			min[j] = math.Min(min[j], v)
+			max[j] = math.Max(max[j], v)
+		}
+	}
+
+	return min, max
+}
@@ -0,0 +1,404 @@
+package viewer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+// ColorMode selects the attribute a view colors points by.
+type ColorMode int
+
+const (
+	ColorElevation ColorMode = iota
+	ColorIntensity
+	ColorClassification
+	ColorRGB
+)
+
+var colorModeNames = [...]string{"elevation", "intensity", "classification", "rgb"}
+
+func (cm ColorMode) String() string {
+	if (int)(cm) < len(colorModeNames) {
+		return colorModeNames[cm]
+	}
+	return fmt.Sprintf("ColorMode(%d)", cm)
+}
+
+// ParseColorMode returns the color mode named name.
+func ParseColorMode(name string) (error, ColorMode) {
+	for i, n := range colorModeNames {
+		if strings.EqualFold(n, name) {
+			return nil, (ColorMode)(i)
+		}
+	}
+	return fmt.Errorf("unknown color mode %q, want one of %s", name, strings.Join(colorModeNames[:], ", ")), 0
+}
+
+// elevationRamp runs from low to high elevations, matching the browser viewer.
+var elevationRamp = [][3]float64{{48, 18, 160}, {30, 130, 230}, {40, 200, 150}, {190, 220, 60}, {250, 150, 30}, {220, 40, 30}}
+
+func rampColor(t float64) [3]uint8 {
+	t = math.Min(1, math.Max(0, t)) * (float64)(len(elevationRamp)-1)
+	i := (int)(math.Min((float64)(len(elevationRamp)-2), math.Floor(t)))
+	f := t - (float64)(i)
+
+	var ret [3]uint8
+	for c := range ret {
+		ret[c] = (uint8)(elevationRamp[i][c] + (elevationRamp[i+1][c]-elevationRamp[i][c])*f)
+	}
+	return ret
+}
+
+// TerminalView rasterizes a pointcloud as seen from above into a grid of terminal cells.  Each cell shows two
+// vertically stacked pixels using the upper half block character, its foreground coloring the upper pixel and its
+// background the lower one, which keeps pixels roughly square.  Each pixel shows the highest point within it.
+type TerminalView struct {
+	Mode      ColorMode
+	TrueColor bool
+
+	// CenterX and CenterY are the world coordinates at the middle of the view, and Scale is the width of a pixel in
+	// world units.
+	CenterX float64
+	CenterY float64
+	Scale   float64
+
+	meta Meta
+
+	// point attributes, with coordinates relative to the cloud's center to keep them precise as float32
+	xs, ys, zs  []float32
+	intensities []uint16
+	classes     []uint8
+	rgbs        []uint8
+	rgbShift    uint
+}
+
+// NewTerminalView loads pc's points for rasterizing.  The view starts centered on the cloud; call Fit to size it to
+// a terminal.
+func NewTerminalView(name string, pc *lassloot.PointCloud) *TerminalView {
+	s := &Server{pc: pc, name: name}
+	tv := &TerminalView{meta: s.newMeta(), rgbShift: s.rgbShift}
+	tv.CenterX, tv.CenterY = tv.meta.Center[0], tv.meta.Center[1]
+	tv.Scale = 1
+
+	n := (int)(pc.Len())
+	tv.xs, tv.ys, tv.zs = make([]float32, n), make([]float32, n), make([]float32, n)
+
+	schema := pc.Schema()
+	intensity, hasIntensity := schema.Dimension(las14.DimIntensity)
+	class, hasClass := schema.Dimension(las14.DimClassification)
+	red, hasRGB := schema.Dimension(las14.DimRed)
+	green, _ := schema.Dimension(las14.DimGreen)
+	blue, _ := schema.Dimension(las14.DimBlue)
+	if hasIntensity {
+		tv.intensities = make([]uint16, n)
+	}
+	if hasClass {
+		tv.classes = make([]uint8, n)
+	}
+	if hasRGB {
+		tv.rgbs = make([]uint8, n*3)
+	}
+
+	for i := 0; i < n; i++ {
+		_, p := pc.PointAt((uint64)(i))
+		raw := p.PDR.Raw
+
+		x, y, z := p.XYZ()
+		tv.xs[i] = (float32)(x - tv.meta.Center[0])
+		tv.ys[i] = (float32)(y - tv.meta.Center[1])
+		tv.zs[i] = (float32)(z - tv.meta.Center[2])
+
+		if hasIntensity {
+			tv.intensities[i] = (uint16)(intensity.RawFloat64(raw))
+		}
+		if hasClass {
+			tv.classes[i] = (uint8)(class.RawFloat64(raw))
+		}
+		if hasRGB {
+			tv.rgbs[i*3] = (uint8)((uint16)(red.RawFloat64(raw)) >> tv.rgbShift)
+			tv.rgbs[i*3+1] = (uint8)((uint16)(green.RawFloat64(raw)) >> tv.rgbShift)
+			tv.rgbs[i*3+2] = (uint8)((uint16)(blue.RawFloat64(raw)) >> tv.rgbShift)
+		}
+	}
+
+	if hasRGB {
+		tv.Mode = ColorRGB
+	}
+
+	return tv
+}
+
+// Fit centers the view on the cloud and scales it to fill cols by rows cells.
+func (tv *TerminalView) Fit(cols, rows int) {
+	tv.CenterX, tv.CenterY = tv.meta.Center[0], tv.meta.Center[1]
+
+	w := tv.meta.Max[0] - tv.meta.Min[0]
+	h := tv.meta.Max[1] - tv.meta.Min[1]
+	tv.Scale = math.Max(w/(float64)(maxInt(cols, 1)), h/(float64)(maxInt(rows*2, 1)))
+	if tv.Scale <= 0 {
+		tv.Scale = 1
+	}
+	// leave a pixel of margin so the outermost points aren't clipped
+	tv.Scale *= 1.02
+}
+
+// Pan moves the view by dx and dy pixels, with positive y moving north.
+func (tv *TerminalView) Pan(dx, dy float64) {
+	tv.CenterX += dx * tv.Scale
+	tv.CenterY += dy * tv.Scale
+}
+
+// Zoom scales the view by factor, with factors above one zooming in.
+func (tv *TerminalView) Zoom(factor float64) {
+	tv.Scale /= factor
+}
+
+// NextMode cycles to the next color mode the cloud has the attributes for.
+func (tv *TerminalView) NextMode() {
+	for i := 0; i < len(colorModeNames); i++ {
+		tv.Mode = (tv.Mode + 1) % (ColorMode)(len(colorModeNames))
+		if tv.supports(tv.Mode) {
+			return
+		}
+	}
+}
+
+func (tv *TerminalView) supports(mode ColorMode) bool {
+	switch mode {
+	case ColorIntensity:
+		return tv.intensities != nil
+	case ColorClassification:
+		return tv.classes != nil
+	case ColorRGB:
+		return tv.rgbs != nil
+	default:
+		return true
+	}
+}
+
+// pixel is the highest point found within a pixel, or empty when the pixel holds no points.
+type pixel struct {
+	set   bool
+	z     float32
+	index int
+}
+
+// Raster returns the colors of the cols by rows*2 pixels of the view, row by row from the north, along with whether
+// each pixel holds any points.
+func (tv *TerminalView) Raster(cols, rows int) ([][3]uint8, []bool) {
+	w, h := cols, rows*2
+	pixels := make([]pixel, w*h)
+
+	// pixel coordinates of the view's origin, relative to the cloud's center
+	left := tv.CenterX - tv.meta.Center[0] - (float64)(w)/2*tv.Scale
+	top := tv.CenterY - tv.meta.Center[1] + (float64)(h)/2*tv.Scale
+
+	for i := range tv.xs {
+		px := (int)(math.Floor(((float64)(tv.xs[i]) - left) / tv.Scale))
+		py := (int)(math.Floor((top - (float64)(tv.ys[i])) / tv.Scale))
+		if px < 0 || px >= w || py < 0 || py >= h {
+			continue
+		}
+
+		p := &pixels[py*w+px]
+		if !p.set || tv.zs[i] > p.z {
+			p.set, p.z, p.index = true, tv.zs[i], i
+		}
+	}
+
+	colors := make([][3]uint8, len(pixels))
+	set := make([]bool, len(pixels))
+	for i, p := range pixels {
+		if p.set {
+			colors[i] = tv.color(p.index)
+			set[i] = true
+		}
+	}
+	return colors, set
+}
+
+func (tv *TerminalView) color(i int) [3]uint8 {
+	switch {
+	case tv.Mode == ColorIntensity && tv.intensities != nil:
+		r := tv.meta.IntensityRange
+		v := (uint8)(math.Min(1, math.Max(0, ((float64)(tv.intensities[i])-r[0])/(r[1]-r[0]))) * 255)
+		return [3]uint8{v, v, v}
+	case tv.Mode == ColorClassification && tv.classes != nil:
+		return lassloot.ClassificationColor(tv.classes[i])
+	case tv.Mode == ColorRGB && tv.rgbs != nil:
+		return [3]uint8{tv.rgbs[i*3], tv.rgbs[i*3+1], tv.rgbs[i*3+2]}
+	default:
+		zmin := tv.meta.Min[2] - tv.meta.Center[2]
+		zspan := math.Max(1e-9, tv.meta.Max[2]-tv.meta.Min[2])
+		return rampColor(((float64)(tv.zs[i]) - zmin) / zspan)
+	}
+}
+
+// Render draws the view into cols by rows cells of w, starting at the cursor's current position.  Each row ends by
+// resetting the colors and moving to the start of the next line.
+func (tv *TerminalView) Render(w io.Writer, cols, rows int) error {
+	colors, set := tv.Raster(cols, rows)
+
+	bw := bufio.NewWriter(w)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			upper, lower := (row*2)*cols+col, (row*2+1)*cols+col
+			switch {
+			case set[upper] && set[lower]:
+				bw.WriteString(tv.sgr(38, colors[upper]) + tv.sgr(48, colors[lower]) + "▀")
+			case set[upper]:
+				bw.WriteString("\x1b[49m" + tv.sgr(38, colors[upper]) + "▀")
+			case set[lower]:
+				bw.WriteString("\x1b[49m" + tv.sgr(38, colors[lower]) + "▄")
+			default:
+				bw.WriteString("\x1b[49m ")
+			}
+		}
+		bw.WriteString("\x1b[0m\r\n")
+	}
+	return bw.Flush()
+}
+
+// sgr returns the escape sequence setting the foreground (base 38) or background (base 48) color to c, in truecolor
+// or as the nearest entry of the 256 color palette's 6x6x6 cube.
+func (tv *TerminalView) sgr(base int, c [3]uint8) string {
+	if tv.TrueColor {
+		return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", base, c[0], c[1], c[2])
+	}
+
+	cube := func(v uint8) int {
+		return ((int)(v)*5 + 127) / 255
+	}
+	return fmt.Sprintf("\x1b[%d;5;%dm", base, 16+36*cube(c[0])+6*cube(c[1])+cube(c[2]))
+}
+
+// RunTerminal shows pc in the terminal attached to in and out.  When in is an interactive terminal the view fills it
+// and responds to keys until q is pressed; otherwise a single frame sized by the COLUMNS and LINES environment
+// variables is written to out.
+func RunTerminal(name string, pc *lassloot.PointCloud, in *os.File, out io.Writer, mode ColorMode, trueColor bool) error {
+	tv := NewTerminalView(name, pc)
+	if tv.supports(mode) {
+		tv.Mode = mode
+	}
+	tv.TrueColor = trueColor
+
+	err, restore := makeRaw(in)
+	if err != nil {
+		cols, rows := environmentSize()
+		tv.Fit(cols, rows)
+		return tv.Render(out, cols, rows)
+	}
+	defer restore()
+
+	// switch to the alternate screen and hide the cursor for the duration of the session
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+
+	cols, rows := viewSize(in)
+	tv.Fit(cols, rows)
+
+	keys := bufio.NewReader(in)
+	for {
+		cols, rows = viewSize(in)
+
+		fmt.Fprint(out, "\x1b[H")
+		err := tv.Render(out, cols, rows)
+		if err != nil {
+			return err
+		}
+		status := fmt.Sprintf(" %s | %s | %.3g units/px | arrows/hjkl pan  +/- zoom  c color  r reset  q quit",
+			name, tv.Mode, tv.Scale)
+		if len(status) > cols {
+			status = status[:cols]
+		}
+		fmt.Fprintf(out, "\x1b[0m\x1b[K%s", status)
+
+		err, key := readKey(keys)
+		if err != nil {
+			return err
+		}
+
+		step := (float64)(maxInt(cols, rows)) / 8
+		switch key {
+		case "q", "\x03", "\x1b":
+			return nil
+		case "left", "h", "a":
+			tv.Pan(-step, 0)
+		case "right", "l", "d":
+			tv.Pan(step, 0)
+		case "up", "k", "w":
+			tv.Pan(0, step)
+		case "down", "j", "s":
+			tv.Pan(0, -step)
+		case "+", "=":
+			tv.Zoom(1.5)
+		case "-", "_":
+			tv.Zoom(1 / 1.5)
+		case "c":
+			tv.NextMode()
+		case "r":
+			tv.Fit(cols, rows)
+		}
+	}
+}
+
+// readKey reads a single key press, naming arrow keys "up", "down", "left" and "right".
+func readKey(r *bufio.Reader) (error, string) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err, ""
+	}
+	if b != 0x1b {
+		return nil, string(b)
+	}
+
+	// an arrow key arrives as ESC [ A..D; a lone escape has nothing buffered behind it
+	if r.Buffered() < 2 {
+		return nil, "\x1b"
+	}
+	seq := make([]byte, 2)
+	if _, err := io.ReadFull(r, seq); err != nil {
+		return err, ""
+	}
+	switch string(seq) {
+	case "[A":
+		return nil, "up"
+	case "[B":
+		return nil, "down"
+	case "[C":
+		return nil, "right"
+	case "[D":
+		return nil, "left"
+	}
+	return nil, ""
+}
+
+// viewSize returns the number of cells available for the view, leaving the bottom line for the status.
+func viewSize(tty *os.File) (int, int) {
+	err, cols, rows := terminalSize(tty)
+	if err != nil {
+		cols, rows = environmentSize()
+	}
+	return maxInt(cols, 1), maxInt(rows-1, 1)
+}
+
+func environmentSize() (int, int) {
+	cols, rows := 80, 24
+	fmt.Sscan(os.Getenv("COLUMNS"), &cols)
+	fmt.Sscan(os.Getenv("LINES"), &rows)
+	return cols, rows
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
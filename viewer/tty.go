@@ -0,0 +1,54 @@
+package viewer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The terminal is driven through stty rather than ioctls, which keeps the package free of platform specific code
+// and dependencies at the cost of a process per call.
+
+// makeRaw puts tty into raw mode, returning a func that restores its previous settings.  It fails when tty isn't a
+// terminal.
+func makeRaw(tty *os.File) (error, func()) {
+	err, state := stty(tty, "-g")
+	if err != nil {
+		return err, nil
+	}
+
+	err, _ = stty(tty, "raw", "-echo")
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, func() {
+		stty(tty, state)
+	}
+}
+
+// terminalSize returns the number of columns and rows of tty.
+func terminalSize(tty *os.File) (error, int, int) {
+	err, out := stty(tty, "size")
+	if err != nil {
+		return err, 0, 0
+	}
+
+	var rows, cols int
+	_, err = fmt.Sscan(out, &rows, &cols)
+	if err != nil || rows == 0 || cols == 0 {
+		return fmt.Errorf("unexpected terminal size %q", out), 0, 0
+	}
+	return nil, cols, rows
+}
+
+func stty(tty *os.File, args ...string) (error, string) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("stty %s: %w", strings.Join(args, " "), err), ""
+	}
+	return nil, strings.TrimSpace(string(out))
+}
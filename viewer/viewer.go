@@ -0,0 +1,232 @@
+// Package viewer serves an interactive WebGL view of a PointCloud over HTTP.  The page and its script are embedded in
+// the package, and points are streamed to the browser in level of detail order so a coarse outline of the whole cloud
+// appears immediately and fills in as more chunks arrive.  For sessions without a browser, TerminalView draws the
+// cloud from above using colored half block characters.
+package viewer
+
+import (
+	"embed"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/encoding/las14"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// DefaultChunkSize is the number of points served by a single chunk request when the client doesn't ask for a size.
+const DefaultChunkSize = 1 << 16
+
+// MaxChunkSize bounds the number of points a single chunk request may ask for.
+const MaxChunkSize = 1 << 20
+
+// ChunkStride is the number of bytes each point occupies in a chunk: three float32 coordinates, a uint16 intensity, a
+// uint8 classification and three uint8 color channels.
+const ChunkStride = 12 + 2 + 1 + 3
+
+// Server is an http.Handler serving the viewer page at its root, the cloud's metadata at api/meta and chunks of
+// points at api/points.
+type Server struct {
+	pc    *lassloot.PointCloud
+	name  string
+	order []uint32
+	meta  Meta
+	mux   *http.ServeMux
+
+	// rgbShift reduces the cloud's color channels to 8 bits.
+	rgbShift uint
+}
+
+// Meta describes the cloud to the viewer's script.  Coordinates within chunks are relative to Center, which keeps
+// them precise as float32.
+type Meta struct {
+	Name      string     `json:"name"`
+	Count     uint64     `json:"count"`
+	ChunkSize int        `json:"chunkSize"`
+	Center    [3]float64 `json:"center"`
+	Min       [3]float64 `json:"min"`
+	Max       [3]float64 `json:"max"`
+	CRS       string     `json:"crs,omitempty"`
+
+	HasIntensity      bool       `json:"hasIntensity"`
+	IntensityRange    [2]float64 `json:"intensityRange"`
+	HasClassification bool       `json:"hasClassification"`
+	HasRGB            bool       `json:"hasRGB"`
+}
+
+// NewServer prepares pc for viewing, ordering its points by level of detail.  The name is shown in the page title.
+func NewServer(name string, pc *lassloot.PointCloud) (error, *Server) {
+	if pc.Len() > math.MaxUint32 {
+		return fmt.Errorf("pointcloud of %d points is too large to view", pc.Len()), nil
+	}
+
+	s := &Server{
+		pc:    pc,
+		name:  name,
+		order: lodOrder(pc),
+		mux:   http.NewServeMux(),
+	}
+	s.meta = s.newMeta()
+
+	static, err := fs.Sub(assets, "assets")
+	if err != nil {
+		return err, nil
+	}
+	s.mux.Handle("/", http.FileServer(http.FS(static)))
+	s.mux.HandleFunc("/api/meta", s.serveMeta)
+	s.mux.HandleFunc("/api/points", s.servePoints)
+
+	return nil, s
+}
+
+// Meta returns the description of the cloud served at api/meta.
+func (s *Server) Meta() Meta {
+	return s.meta
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) newMeta() Meta {
+	m := Meta{Name: s.name, Count: s.pc.Len(), ChunkSize: DefaultChunkSize}
+
+	if m.Count > 0 {
+		m.Min, m.Max = bounds(s.pc)
+		for i := range m.Center {
+			m.Center[i] = (m.Min[i] + m.Max[i]) / 2
+		}
+	}
+
+	if err, crs := s.pc.CRS(); err == nil && crs != nil {
+		m.CRS = crs.String()
+	}
+
+	schema := s.pc.Schema()
+	_, m.HasClassification = schema.Dimension(las14.DimClassification)
+	_, m.HasRGB = schema.Dimension(las14.DimRed)
+	if m.HasRGB && s.pc.ColorDepth() == 16 {
+		s.rgbShift = 8
+	}
+
+	if d, ok := schema.Dimension(las14.DimIntensity); ok {
+		m.HasIntensity = true
+		m.IntensityRange = s.intensityRange(d)
+	}
+
+	return m
+}
+
+// intensityRange returns the 2nd and 98th percentile intensities, which make a better contrast stretch than the
+// extremes since a few specular returns are usually far brighter than the rest.
+func (s *Server) intensityRange(d *las14.Dimension) [2]float64 {
+	var histogram [1 << 16]uint64
+	n := s.pc.Len()
+	for i := (uint64)(0); i < n; i++ {
+		_, p := s.pc.PointAt(i)
+		histogram[(uint16)(d.RawFloat64(p.PDR.Raw))]++
+	}
+
+	percentile := func(q float64) float64 {
+		target := (uint64)(q * (float64)(n))
+		var seen uint64
+		for v, c := range histogram {
+			seen += c
+			if seen > target {
+				return (float64)(v)
+			}
+		}
+		return math.MaxUint16
+	}
+
+	lo, hi := percentile(0.02), percentile(0.98)
+	if hi <= lo {
+		hi = lo + 1
+	}
+	return [2]float64{lo, hi}
+}
+
+func (s *Server) serveMeta(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(s.meta)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// servePoints writes count points starting at start in level of detail order.  The chunk holds the points' float32
+// coordinates relative to the cloud's center, followed by their uint16 intensities, uint8 classifications and uint8
+// RGB colors, each as a contiguous little endian array.
+func (s *Server) servePoints(w http.ResponseWriter, r *http.Request) {
+	start, err := strconv.ParseUint(r.URL.Query().Get("start"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid start", http.StatusBadRequest)
+		return
+	}
+
+	count := (uint64)(DefaultChunkSize)
+	if c := r.URL.Query().Get("count"); c != "" {
+		count, err = strconv.ParseUint(c, 10, 64)
+		if err != nil || count > MaxChunkSize {
+			http.Error(w, "invalid count", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if start > (uint64)(len(s.order)) {
+		start = (uint64)(len(s.order))
+	}
+	if start+count > (uint64)(len(s.order)) {
+		count = (uint64)(len(s.order)) - start
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	// a failed write means the client went away, which leaves nobody to report it to
+	_, _ = w.Write(s.encodeChunk(s.order[start : start+count]))
+}
+
+func (s *Server) encodeChunk(indices []uint32) []byte {
+	n := len(indices)
+	buf := make([]byte, n*ChunkStride)
+	intensities := buf[12*n : 14*n]
+	classes := buf[14*n : 15*n]
+	colors := buf[15*n:]
+
+	schema := s.pc.Schema()
+	intensity, hasIntensity := schema.Dimension(las14.DimIntensity)
+	class, hasClass := schema.Dimension(las14.DimClassification)
+	red, hasRGB := schema.Dimension(las14.DimRed)
+	green, _ := schema.Dimension(las14.DimGreen)
+	blue, _ := schema.Dimension(las14.DimBlue)
+
+	for i, idx := range indices {
+		_, p := s.pc.PointAt((uint64)(idx))
+		raw := p.PDR.Raw
+
+		x, y, z := p.XYZ()
+		binary.LittleEndian.PutUint32(buf[i*12:], math.Float32bits((float32)(x-s.meta.Center[0])))
+		binary.LittleEndian.PutUint32(buf[i*12+4:], math.Float32bits((float32)(y-s.meta.Center[1])))
+		binary.LittleEndian.PutUint32(buf[i*12+8:], math.Float32bits((float32)(z-s.meta.Center[2])))
+
+		if hasIntensity {
+			binary.LittleEndian.PutUint16(intensities[i*2:], (uint16)(intensity.RawFloat64(raw)))
+		}
+		if hasClass {
+			classes[i] = (uint8)(class.RawFloat64(raw))
+		}
+		if hasRGB {
+			colors[i*3] = (uint8)((uint16)(red.RawFloat64(raw)) >> s.rgbShift)
+			colors[i*3+1] = (uint8)((uint16)(green.RawFloat64(raw)) >> s.rgbShift)
+			colors[i*3+2] = (uint8)((uint16)(blue.RawFloat64(raw)) >> s.rgbShift)
+		}
+	}
+
+	return buf
+}
@@ -0,0 +1,118 @@
+package viewer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nullstyle/lassloot"
+	"github.com/nullstyle/lassloot/internal/cloudtest"
+	"github.com/nullstyle/lassloot/internal/lastest"
+)
+
+func newTestServer(t *testing.T, n int) *Server {
+	t.Helper()
+
+	points := make([]lastest.Point, n)
+	for i := range points {
+		points[i] = lastest.PatternPoint(i)
+		points[i].Red, points[i].Green, points[i].Blue = 0xff00, 0x8000, (uint16)(i)
+	}
+	err, s := NewServer("test.las", cloudtest.PointCloudAt(t, 7, points))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLODOrderIsPermutation(t *testing.T) {
+	s := newTestServer(t, 1000)
+
+	seen := make([]bool, 1000)
+	for _, idx := range s.order {
+		if seen[idx] {
+			t.Fatalf("point %d ordered twice", idx)
+		}
+		seen[idx] = true
+	}
+	if len(s.order) != 1000 {
+		t.Fatalf("ordered %d of 1000 points", len(s.order))
+	}
+}
+
+func TestServeMetaAndPoints(t *testing.T) {
+	s := newTestServer(t, 100)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/meta", nil))
+	var meta Meta
+	if err := json.NewDecoder(rec.Body).Decode(&meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Count != 100 || !meta.HasRGB || !meta.HasIntensity || meta.Name != "test.las" {
+		t.Errorf("meta = %+v", meta)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/api/points?start=90&count=50", nil))
+	body := rec.Body.Bytes()
+	if len(body) != 10*ChunkStride {
+		t.Fatalf("chunk of %d bytes, want %d", len(body), 10*ChunkStride)
+	}
+
+	// the first point's coordinates relative to the center must lie within the bounds
+	x := math.Float32frombits(binary.LittleEndian.Uint32(body))
+	if (float64)(x)+meta.Center[0] < meta.Min[0]-1e-3 || (float64)(x)+meta.Center[0] > meta.Max[0]+1e-3 {
+		t.Errorf("x %v outside bounds", x)
+	}
+	if red := body[15*10]; red != 0xff {
+		t.Errorf("red = %#x, want 0xff", red)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(rec.Body.String(), "viewer.js") {
+		t.Error("index page not served")
+	}
+}
+
+func TestTerminalViewRaster(t *testing.T) {
+	points := []lastest.Point{
+		{X: 0, Y: 0, Z: 0, Classification: 2},
+		{X: 1000, Y: 1000, Z: 500, Classification: 6},
+		{X: 1000, Y: 1000, Z: 100, Classification: 2},
+	}
+	tv := NewTerminalView("test.las", cloudtest.PointCloudAt(t, 1, points))
+	tv.Mode = ColorClassification
+	tv.Fit(10, 5)
+
+	colors, set := tv.Raster(10, 5)
+	n := 0
+	for _, s := range set {
+		if s {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Fatalf("%d pixels set, want 2", n)
+	}
+
+	// north is up: the north east point is in the top row, and the higher building point hides the ground beneath
+	if !set[9] || colors[9] != lassloot.ClassificationColor(6) {
+		t.Errorf("top right pixel = %v %v, want building", set[9], colors[9])
+	}
+	if !set[9*10] || colors[9*10] != lassloot.ClassificationColor(2) {
+		t.Errorf("bottom left pixel = %v %v, want ground", set[90], colors[90])
+	}
+
+	var out strings.Builder
+	if err := tv.Render(&out, 10, 5); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out.String(), "\r\n") != 5 {
+		t.Errorf("rendered %d rows, want 5", strings.Count(out.String(), "\r\n"))
+	}
+}